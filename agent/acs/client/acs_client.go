@@ -36,12 +36,13 @@ type clientServer struct {
 // New returns a client/server to bidirectionally communicate with ACS
 // The returned struct should have both 'Connect' and 'Serve' called upon it
 // before being used.
-func New(url string, region string, credentialProvider credentials.AWSCredentialProvider, acceptInvalidCert bool) wsclient.ClientServer {
+func New(url string, region string, credentialProvider credentials.AWSCredentialProvider, acceptInvalidCert bool, connConfig wsclient.ConnectionConfig) wsclient.ClientServer {
 	cs := &clientServer{}
 	cs.URL = url
 	cs.Region = region
 	cs.CredentialProvider = credentialProvider
 	cs.AcceptInvalidCert = acceptInvalidCert
+	cs.ConnectionConfig = connConfig
 	cs.ServiceError = &acsError{}
 	cs.RequestHandlers = make(map[string]wsclient.RequestHandler)
 	cs.TypeDecoder = &decoder{}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
 	"github.com/aws/amazon-ecs-agent/agent/auth"
+	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/wsclient"
 	"github.com/gorilla/websocket"
 )
@@ -60,12 +61,22 @@ func (ml *messageLogger) ReadMessage() (int, []byte, error) {
 
 func testCS() (wsclient.ClientServer, *messageLogger) {
 	testCreds := auth.TestCredentialProvider{}
-	cs := New("localhost:443", "us-east-1", testCreds, true).(*clientServer)
+	cs := New("localhost:443", "us-east-1", testCreds, true, wsclient.ConnectionConfig{}).(*clientServer)
 	ml := &messageLogger{make([][]byte, 0), make([][]byte, 0), false}
 	cs.Conn = ml
 	return cs, ml
 }
 
+func TestNewConnectionConfig(t *testing.T) {
+	cfg := &config.Config{WebsocketReadBufferSize: 65536, WebsocketWriteBufferSize: 16384, WebsocketMaxMessageSize: 1 << 20}
+	cs := New("localhost:443", "us-east-1", auth.TestCredentialProvider{}, true, wsclient.NewConnectionConfig(cfg)).(*clientServer)
+
+	expected := wsclient.ConnectionConfig{ReadBufferSize: 65536, WriteBufferSize: 16384, MaxMessageSize: 1 << 20}
+	if cs.ConnectionConfig != expected {
+		t.Errorf("Expected connection config %+v, got %+v", expected, cs.ConnectionConfig)
+	}
+}
+
 func TestMakeUnrecognizedRequest(t *testing.T) {
 	cs, _ := testCS()
 	// 'testing.T' should not be a known type ;)
@@ -215,7 +226,7 @@ func TestConnect(t *testing.T) {
 		t.Fatal(<-serverErr)
 	}()
 
-	cs := New(server.URL, "us-east-1", auth.TestCredentialProvider{}, true)
+	cs := New(server.URL, "us-east-1", auth.TestCredentialProvider{}, true, wsclient.ConnectionConfig{})
 	// Wait for up to a second for the mock server to launch
 	for i := 0; i < 100; i++ {
 		err = cs.Connect()
@@ -286,7 +297,7 @@ func TestConnectClientError(t *testing.T) {
 	}))
 	defer testServer.Close()
 
-	cs := New(testServer.URL, "us-east-1", auth.TestCredentialProvider{}, true)
+	cs := New(testServer.URL, "us-east-1", auth.TestCredentialProvider{}, true, wsclient.ConnectionConfig{})
 	err := cs.Connect()
 	if _, ok := err.(*wsclient.WSError); !ok || err.Error() != "InvalidClusterException: Invalid cluster" {
 		t.Error("Did not get correctly typed error: " + err.Error())
@@ -29,10 +29,12 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/authv4/credentials"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/eventhandler"
+	"github.com/aws/amazon-ecs-agent/agent/health"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	utilatomic "github.com/aws/amazon-ecs-agent/agent/utils/atomic"
+	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
 	"github.com/aws/amazon-ecs-agent/agent/version"
 	"github.com/aws/amazon-ecs-agent/agent/wsclient"
@@ -51,10 +53,19 @@ const payloadMessageBufferSize = 10
 // the last sequence number successfully handled.
 var SequenceNumber = utilatomic.NewIncreasingInt64(1)
 
+// ResetSessionState forgets the sequence number and handled messages, such as
+// those loaded from the saved state of a container instance which is not
+// being resumed. It must be called before they are next saved or used.
+func ResetSessionState() {
+	SequenceNumber = utilatomic.NewIncreasingInt64(1)
+	HandledMessages = NewMessageLog(handledMessagesSize)
+}
+
 // StartSession creates a session with ACS and handles requests using the passed
 // in arguments.
 func StartSession(containerInstanceArn string, credentialProvider credentials.AWSCredentialProvider, cfg *config.Config, taskEngine engine.TaskEngine, ecsclient api.ECSClient, stateManager statemanager.StateManager, acceptInvalidCert bool) error {
-	backoff := utils.NewSimpleBackoff(time.Second, 2*time.Minute, 0.2, 2)
+	backoff := retry.ACSSession.NewBackoff()
+	health.Default.Register(health.ComponentACS)
 	for {
 		acsError := func() error {
 			acsEndpoint, err := ecsclient.DiscoverPollEndpoint(containerInstanceArn)
@@ -66,10 +77,10 @@ func StartSession(containerInstanceArn string, credentialProvider credentials.AW
 
 			url := AcsWsUrl(acsEndpoint, cfg.Cluster, containerInstanceArn, taskEngine)
 
-			client := acsclient.New(url, cfg.AWSRegion, credentialProvider, acceptInvalidCert)
+			client := acsclient.New(url, cfg.AWSRegion, credentialProvider, acceptInvalidCert, wsclient.NewConnectionConfig(cfg))
 			defer client.Close()
 
-			client.AddRequestHandler(payloadMessageHandler(client, cfg.Cluster, containerInstanceArn, taskEngine, ecsclient, stateManager))
+			client.AddRequestHandler(payloadMessageHandler(client, cfg.Cluster, containerInstanceArn, taskEngine, ecsclient, stateManager, cfg.DumpACSPayloads))
 			client.AddRequestHandler(heartbeatHandler(client))
 
 			updater.AddAgentUpdateHandlers(client, cfg, stateManager, taskEngine)
@@ -79,25 +90,33 @@ func StartSession(containerInstanceArn string, credentialProvider credentials.AW
 				log.Error("Error connecting to ACS: " + err.Error())
 				return err
 			}
+			health.Default.Report(health.ComponentACS, nil)
+			updater.ConfirmUpdate(cfg)
 			return client.Serve()
 		}()
+		Liveness.disconnected()
 		if acsError == nil || acsError == io.EOF {
 			backoff.Reset()
+			backoff.Succeeded()
 		} else {
 			log.Info("Error from acs; backing off", "err", acsError)
-			ttime.Sleep(backoff.Duration())
+			health.Default.Report(health.ComponentACS, acsError)
+			ecsclient.InvalidatePollEndpoints(containerInstanceArn)
+			ttime.Sleep(backoff.DurationFor(acsError))
 		}
 	}
 }
 
-// heartbeatHandler starts a timer and listens for acs heartbeats. If there are
-// none for unexpectedly long, it closes the passed in connection.
+// heartbeatHandler starts a timer and listens for acs heartbeats, recording
+// them in Liveness. If there are none for unexpectedly long, it closes the
+// passed in connection.
 func heartbeatHandler(acsConnection io.Closer) func(*ecsacs.HeartbeatMessage) {
 	timer := time.AfterFunc(utils.AddJitter(heartbeatTimeout, heartbeatJitter), func() {
 		log.Debug("ACS Connection hasn't had a heartbeat in too long of a timeout; disconnecting")
 		acsConnection.Close()
 	})
 	return func(*ecsacs.HeartbeatMessage) {
+		Liveness.heartbeat()
 		timer.Reset(utils.AddJitter(heartbeatTimeout, heartbeatJitter))
 	}
 }
@@ -106,26 +125,40 @@ func heartbeatHandler(acsConnection io.Closer) func(*ecsacs.HeartbeatMessage) {
 // takes given payloads, converts them into the internal representation of
 // tasks, and passes them on to the task engine. If there is an issue handling a
 // task, it is moved to stopped. If a task is handled, state is saved.
-func payloadMessageHandler(cs wsclient.ClientServer, cluster, containerInstanceArn string, taskEngine engine.TaskEngine, client api.ECSClient, stateManager statemanager.Saver) func(payload *ecsacs.PayloadMessage) {
+func payloadMessageHandler(cs wsclient.ClientServer, cluster, containerInstanceArn string, taskEngine engine.TaskEngine, client api.ECSClient, stateManager statemanager.Saver, dumpPayloads bool) func(payload *ecsacs.PayloadMessage) {
 	messageBuffer := make(chan *ecsacs.PayloadMessage, payloadMessageBufferSize)
 	go func() {
 		for message := range messageBuffer {
-			handlePayloadMessage(cs, cluster, containerInstanceArn, message, taskEngine, client, stateManager)
+			if dumpPayloads {
+				dumpPayload(message)
+			}
+			handlePayloadMessage(cs, cluster, containerInstanceArn, message, taskEngine, client, stateManager, HandledMessages)
 		}
 	}()
 
 	return func(payload *ecsacs.PayloadMessage) {
+		Liveness.payload()
 		messageBuffer <- payload
 	}
 }
 
 // handlePayloadMessage attempts to add each task to the taskengine and, if it can, acks the request.
-func handlePayloadMessage(cs wsclient.ClientServer, cluster, containerInstanceArn string, payload *ecsacs.PayloadMessage, taskEngine engine.TaskEngine, client api.ECSClient, saver statemanager.Saver) {
+// A message which has already been handled, such as one redelivered after the
+// agent restarted before acking it, is acked again without re-adding its tasks.
+func handlePayloadMessage(cs wsclient.ClientServer, cluster, containerInstanceArn string, payload *ecsacs.PayloadMessage, taskEngine engine.TaskEngine, client api.ECSClient, saver statemanager.Saver, handledMessages *MessageLog) {
 	if payload.MessageId == nil {
 		log.Crit("Recieved a payload with no message id", "payload", payload)
 		return
 	}
-	allTasksHandled := addPayloadTasks(cs, client, cluster, containerInstanceArn, payload, taskEngine)
+	allTasksHandled := true
+	if handledMessages.Contains(*payload.MessageId) {
+		log.Info("Received a payload message which was already handled; acking it again", "messageId", *payload.MessageId)
+	} else {
+		allTasksHandled = addPayloadTasks(cs, client, cluster, containerInstanceArn, payload, taskEngine)
+		if allTasksHandled {
+			handledMessages.Add(*payload.MessageId)
+		}
+	}
 	// save the state of tasks we know about after passing them to the task engine
 	err := saver.Save()
 	if err != nil {
@@ -150,23 +183,23 @@ func handlePayloadMessage(cs wsclient.ClientServer, cluster, containerInstanceAr
 }
 
 // addPayloadTasks does validation on each task and, for all valid ones, adds
-// it to the task engine. It returns a bool indicating if it could add every
-// task to the taskEngine
+// it to the task engine. It returns a bool indicating if every task was
+// handled. Invalid tasks are handled by reporting them stopped, as
+// redelivering the message would not make them valid; only a task the task
+// engine could not accept leaves the message unhandled.
 func addPayloadTasks(cs wsclient.ClientServer, client api.ECSClient, cluster, containerInstanceArn string, payload *ecsacs.PayloadMessage, taskEngine engine.TaskEngine) bool {
-	// verify thatwe were able to work with all tasks in this payload so we know whether to ack the whole thing or not
+	// verify that we were able to work with all tasks in this payload so we know whether to ack the whole thing or not
 	allTasksOk := true
 
 	validTasks := make([]*api.Task, 0, len(payload.Tasks))
 	for _, task := range payload.Tasks {
 		if task == nil {
 			log.Crit("Recieved nil task", "messageId", *payload.MessageId)
-			allTasksOk = false
 			continue
 		}
 		apiTask, err := api.TaskFromACS(task, payload)
 		if err != nil {
 			handleUnrecognizedTask(cs, client, cluster, containerInstanceArn, task, err, payload)
-			allTasksOk = false
 			continue
 		}
 		validTasks = append(validTasks, apiTask)
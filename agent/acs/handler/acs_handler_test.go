@@ -69,6 +69,7 @@ func TestHandlerReconnects(t *testing.T) {
 	}
 
 	ecsclient.EXPECT().DiscoverPollEndpoint("myArn").Return(server.URL, nil).AnyTimes()
+	ecsclient.EXPECT().InvalidatePollEndpoints("myArn").AnyTimes()
 	taskEngine.EXPECT().Version().Return("Docker: 1.5.0", nil).AnyTimes()
 
 	ended := make(chan bool, 1)
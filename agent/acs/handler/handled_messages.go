@@ -0,0 +1,87 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// handledMessagesSize is the number of payload message ids remembered. ACS
+// only redelivers recent unacked messages, so a short history suffices.
+const handledMessagesSize = 100
+
+// HandledMessages is shared between all ACS clients and records the ids of the
+// payload messages most recently handled. It is saved with the agent's state
+// so that a message redelivered after a restart is recognized.
+var HandledMessages = NewMessageLog(handledMessagesSize)
+
+// MessageLog remembers a bounded number of message ids, forgetting the oldest
+// first. It is safe for concurrent use and may be saved as json.
+type MessageLog struct {
+	lock sync.Mutex
+	ids  []string
+	size int
+}
+
+// NewMessageLog returns a MessageLog remembering up to size message ids
+func NewMessageLog(size int) *MessageLog {
+	return &MessageLog{ids: make([]string, 0, size), size: size}
+}
+
+// Contains returns whether the message id has been recorded
+func (messages *MessageLog) Contains(id string) bool {
+	messages.lock.Lock()
+	defer messages.lock.Unlock()
+	for _, handled := range messages.ids {
+		if handled == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Add records the message id
+func (messages *MessageLog) Add(id string) {
+	messages.lock.Lock()
+	defer messages.lock.Unlock()
+	messages.add(id)
+}
+
+func (messages *MessageLog) add(id string) {
+	if len(messages.ids) >= messages.size {
+		messages.ids = messages.ids[len(messages.ids)-messages.size+1:]
+	}
+	messages.ids = append(messages.ids, id)
+}
+
+func (messages *MessageLog) MarshalJSON() ([]byte, error) {
+	messages.lock.Lock()
+	defer messages.lock.Unlock()
+	return json.Marshal(messages.ids)
+}
+
+func (messages *MessageLog) UnmarshalJSON(data []byte) error {
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return err
+	}
+	messages.lock.Lock()
+	defer messages.lock.Unlock()
+	messages.ids = messages.ids[:0]
+	for _, id := range ids {
+		messages.add(id)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/api/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/wsclient/mock"
+	"github.com/golang/mock/gomock"
+)
+
+func TestMessageLog(t *testing.T) {
+	messages := NewMessageLog(2)
+	messages.Add("1")
+	messages.Add("2")
+	messages.Add("3")
+	if messages.Contains("1") {
+		t.Error("Expected the oldest message to be forgotten")
+	}
+	if !messages.Contains("2") || !messages.Contains("3") {
+		t.Error("Expected the newest messages to be remembered")
+	}
+
+	data, err := json.Marshal(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewMessageLog(2)
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Contains("2") || !restored.Contains("3") {
+		t.Error("Expected messages to be restored", string(data))
+	}
+}
+
+func TestHandlePayloadMessageDuplicate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cs := mock_wsclient.NewMockClientServer(ctrl)
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	client := mock_api.NewMockECSClient(ctrl)
+
+	strptr := func(s string) *string { return &s }
+	seqNum := int64(1)
+	payload := &ecsacs.PayloadMessage{
+		MessageId: strptr("messageId"),
+		SeqNum:    &seqNum,
+		Tasks: []*ecsacs.Task{{
+			Arn:           strptr("arn"),
+			DesiredStatus: strptr("RUNNING"),
+			Family:        strptr("family"),
+			Version:       strptr("1"),
+			Containers:    []*ecsacs.Container{{Name: strptr("c1"), Image: strptr("image")}},
+		}},
+	}
+	handledMessages := NewMessageLog(10)
+
+	// The task is added once, but the message is acked each time it arrives
+	taskEngine.EXPECT().AddTask(gomock.Any()).Return(nil)
+	cs.EXPECT().MakeRequest(gomock.Any()).Return(nil).Times(2)
+
+	handlePayloadMessage(cs, "cluster", "containerInstance", payload, taskEngine, client, statemanager.NewNoopStateManager(), handledMessages)
+	handlePayloadMessage(cs, "cluster", "containerInstance", payload, taskEngine, client, statemanager.NewNoopStateManager(), handledMessages)
+}
+
+func TestHandlePayloadMessageRejectsInvalidTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cs := mock_wsclient.NewMockClientServer(ctrl)
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	client := mock_api.NewMockECSClient(ctrl)
+
+	strptr := func(s string) *string { return &s }
+	payload := &ecsacs.PayloadMessage{
+		MessageId: strptr("messageId"),
+		Tasks: []*ecsacs.Task{{
+			Arn:           strptr("valid"),
+			DesiredStatus: strptr("RUNNING"),
+			Family:        strptr("family"),
+			Version:       strptr("1"),
+			Containers:    []*ecsacs.Container{{Name: strptr("c1"), Image: strptr("image")}},
+		}, {
+			Arn:           strptr("invalid"),
+			DesiredStatus: strptr("RUNNING"),
+			Family:        strptr("family"),
+			Version:       strptr("1"),
+			Containers:    []*ecsacs.Container{{Name: strptr("c1")}},
+		}},
+	}
+	handledMessages := NewMessageLog(10)
+
+	// The valid task is still added, the invalid one is reported stopped, and
+	// the message is acked
+	taskEngine.EXPECT().AddTask(gomock.Any()).Do(func(task *api.Task) {
+		if task.Arn != "valid" {
+			t.Error("Expected only the valid task to be added", task.Arn)
+		}
+	}).Return(nil)
+	client.EXPECT().SubmitTaskStateChange(gomock.Any()).Return(nil).AnyTimes()
+	cs.EXPECT().MakeRequest(gomock.Any()).Do(func(req interface{}) {
+		if _, ok := req.(*ecsacs.AckRequest); !ok {
+			t.Error("Expected the message to be acked", req)
+		}
+	}).Return(nil)
+
+	handlePayloadMessage(cs, "cluster", "containerInstance", payload, taskEngine, client, statemanager.NewNoopStateManager(), handledMessages)
+	if !handledMessages.Contains("messageId") {
+		t.Error("Expected a message with an invalid task to be handled")
+	}
+}
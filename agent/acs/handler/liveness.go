@@ -0,0 +1,75 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+// Liveness is shared between all ACS clients and records when ACS was last
+// heard from.
+var Liveness = &ACSLiveness{}
+
+// ACSLiveness tracks whether the agent is actually hearing from ACS, rather
+// than merely holding a connection open. It is connected once a heartbeat is
+// received, and stops being connected when the session ends or when no
+// heartbeat has arrived for heartbeatTimeout.
+type ACSLiveness struct {
+	lock          sync.RWMutex
+	inSession     bool
+	lastHeartbeat time.Time
+	lastPayload   time.Time
+}
+
+// LivenessStatus is a snapshot of an ACSLiveness. Times are zero if nothing
+// has been received.
+type LivenessStatus struct {
+	Connected     bool
+	LastHeartbeat time.Time
+	LastPayload   time.Time
+}
+
+func (liveness *ACSLiveness) heartbeat() {
+	liveness.lock.Lock()
+	defer liveness.lock.Unlock()
+	liveness.inSession = true
+	liveness.lastHeartbeat = ttime.Now()
+}
+
+func (liveness *ACSLiveness) payload() {
+	liveness.lock.Lock()
+	defer liveness.lock.Unlock()
+	liveness.lastPayload = ttime.Now()
+}
+
+// disconnected records that the session with ACS ended
+func (liveness *ACSLiveness) disconnected() {
+	liveness.lock.Lock()
+	defer liveness.lock.Unlock()
+	liveness.inSession = false
+}
+
+// Status returns whether ACS is connected and when it was last heard from
+func (liveness *ACSLiveness) Status() LivenessStatus {
+	liveness.lock.RLock()
+	defer liveness.lock.RUnlock()
+	return LivenessStatus{
+		Connected:     liveness.inSession && ttime.Since(liveness.lastHeartbeat) < heartbeatTimeout,
+		LastHeartbeat: liveness.lastHeartbeat,
+		LastPayload:   liveness.lastPayload,
+	}
+}
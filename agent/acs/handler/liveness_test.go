@@ -0,0 +1,56 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handler
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+func TestLivenessFollowsHeartbeats(t *testing.T) {
+	testTime := ttime.NewTestTime()
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	liveness := &ACSLiveness{}
+	if status := liveness.Status(); status.Connected || !status.LastHeartbeat.IsZero() {
+		t.Error("Expected a new liveness to be disconnected with no heartbeat", status)
+	}
+
+	liveness.heartbeat()
+	liveness.payload()
+	status := liveness.Status()
+	if !status.Connected || status.LastHeartbeat.IsZero() || status.LastPayload.IsZero() {
+		t.Error("Expected to be connected after a heartbeat", status)
+	}
+
+	testTime.Warp(heartbeatTimeout)
+	if liveness.Status().Connected {
+		t.Error("Expected to be disconnected without a recent heartbeat")
+	}
+	liveness.heartbeat()
+	if !liveness.Status().Connected {
+		t.Error("Expected to be connected after another heartbeat")
+	}
+
+	liveness.disconnected()
+	status = liveness.Status()
+	if status.Connected {
+		t.Error("Expected to be disconnected after the session ended")
+	}
+	if status.LastHeartbeat.IsZero() {
+		t.Error("Expected the last heartbeat to be remembered after disconnecting")
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handler
+
+import (
+	"encoding/json"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/awslabs/aws-sdk-go/internal/protocol/json/jsonutil"
+)
+
+// redactedFields are the fields whose values are replaced before a payload
+// is dumped. Environment variables, commands and the container overrides,
+// which may set either, commonly hold credentials.
+var redactedFields = map[string]bool{
+	"environment": true,
+	"command":     true,
+	"overrides":   true,
+}
+
+// dumpPayload logs a payload message with sensitive values redacted
+func dumpPayload(payload *ecsacs.PayloadMessage) {
+	dump, err := redactedPayload(payload)
+	if err != nil {
+		log.Warn("Unable to dump payload", "err", err)
+		return
+	}
+	log.Info("Received payload", "payload", dump)
+}
+
+func redactedPayload(payload *ecsacs.PayloadMessage) (string, error) {
+	data, err := jsonutil.BuildJSON(payload)
+	if err != nil {
+		return "", err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "", err
+	}
+	redactedData, err := json.Marshal(utils.Redact(value, redactedFields))
+	if err != nil {
+		return "", err
+	}
+	return string(redactedData), nil
+}
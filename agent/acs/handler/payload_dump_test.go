@@ -0,0 +1,71 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/wsclient"
+)
+
+func TestRedactedPayload(t *testing.T) {
+	strptr := func(s string) *string {
+		return &s
+	}
+	payload := &ecsacs.PayloadMessage{
+		MessageId: strptr("messageId"),
+		Tasks: []*ecsacs.Task{
+			&ecsacs.Task{
+				Arn: strptr("myArn"),
+				Containers: []*ecsacs.Container{
+					&ecsacs.Container{
+						Name:        strptr("c1"),
+						Environment: &map[string]*string{"PASSWORD": strptr("hunter2")},
+						Command:     []*string{strptr("--password=hunter3"), strptr("hunter4")},
+						Overrides:   strptr(`{"command":["hunter5"]}`),
+					},
+				},
+			},
+		},
+	}
+
+	dump, err := redactedPayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(dump, "hunter") {
+		t.Error("Environment, command and overrides values should be redacted", dump)
+	}
+	for _, expected := range []string{`"PASSWORD":"[REDACTED]"`, `"command":["--password=[REDACTED]","[REDACTED]"]`, `"overrides":"[REDACTED]"`, `"arn":"myArn"`, `"messageId":"messageId"`} {
+		if !strings.Contains(dump, expected) {
+			t.Errorf("Expected %s in %s", expected, dump)
+		}
+	}
+}
+
+func TestUnknownPayloadFields(t *testing.T) {
+	message := `{"messageId":"id","newField":1,"tasks":[{"arn":"arn","containers":[{"name":"c1","gpus":2}]}]}`
+
+	unknown, err := wsclient.UnknownFields([]byte(message), reflect.TypeOf(ecsacs.PayloadMessage{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"newField", "tasks[].containers[].gpus"}
+	if !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("Expected %v, got %v", expected, unknown)
+	}
+}
@@ -58,20 +58,30 @@ type metadataCloseMessage struct {
 }
 
 type Container struct {
+	Artifacts []*ContainerArtifact `locationName:"artifacts" type:"list"`
+
 	Command []*string `locationName:"command" type:"list"`
 
 	Cpu *int64 `locationName:"cpu" type:"integer"`
 
+	DockerLabels *map[string]*string `locationName:"dockerLabels" type:"map"`
+
 	EntryPoint []*string `locationName:"entryPoint" type:"list"`
 
 	Environment *map[string]*string `locationName:"environment" type:"map"`
 
 	Essential *bool `locationName:"essential" type:"boolean"`
 
+	Hostname *string `locationName:"hostname" type:"string"`
+
 	Image *string `locationName:"image" type:"string"`
 
+	Interactive *bool `locationName:"interactive" type:"boolean"`
+
 	Links []*string `locationName:"links" type:"list"`
 
+	LinuxParameters *LinuxParameters `locationName:"linuxParameters" type:"structure"`
+
 	Memory *int64 `locationName:"memory" type:"integer"`
 
 	MountPoints []*MountPoint `locationName:"mountPoints" type:"list"`
@@ -82,8 +92,20 @@ type Container struct {
 
 	PortMappings []*PortMapping `locationName:"portMappings" type:"list"`
 
+	PseudoTerminal *bool `locationName:"pseudoTerminal" type:"boolean"`
+
+	ReadonlyRootFilesystem *bool `locationName:"readonlyRootFilesystem" type:"boolean"`
+
+	StopSignal *string `locationName:"stopSignal" type:"string"`
+
+	StopTimeout *int64 `locationName:"stopTimeout" type:"integer"`
+
+	User *string `locationName:"user" type:"string"`
+
 	VolumesFrom []*VolumeFrom `locationName:"volumesFrom" type:"list"`
 
+	WorkingDirectory *string `locationName:"workingDirectory" type:"string"`
+
 	metadataContainer `json:"-", xml:"-"`
 }
 
@@ -91,6 +113,68 @@ type metadataContainer struct {
 	SDKShapeTraits bool `type:"structure"`
 }
 
+type ContainerArtifact struct {
+	ContainerPath *string `locationName:"containerPath" type:"string"`
+
+	Mode *string `locationName:"mode" type:"string"`
+
+	Owner *string `locationName:"owner" type:"string"`
+
+	Sha256 *string `locationName:"sha256" type:"string"`
+
+	Source *string `locationName:"source" type:"string"`
+
+	metadataContainerArtifact `json:"-", xml:"-"`
+}
+
+type metadataContainerArtifact struct {
+	SDKShapeTraits bool `type:"structure"`
+}
+
+type Device struct {
+	ContainerPath *string `locationName:"containerPath" type:"string"`
+
+	HostPath *string `locationName:"hostPath" type:"string"`
+
+	Permissions []*string `locationName:"permissions" type:"list"`
+
+	metadataDevice `json:"-", xml:"-"`
+}
+
+type metadataDevice struct {
+	SDKShapeTraits bool `type:"structure"`
+}
+
+type EFSAuthorizationConfig struct {
+	AccessPointId *string `locationName:"accessPointId" type:"string"`
+
+	Iam *string `locationName:"iam" type:"string"`
+
+	metadataEFSAuthorizationConfig `json:"-", xml:"-"`
+}
+
+type metadataEFSAuthorizationConfig struct {
+	SDKShapeTraits bool `type:"structure"`
+}
+
+type EFSVolumeConfiguration struct {
+	AuthorizationConfig *EFSAuthorizationConfig `locationName:"authorizationConfig" type:"structure"`
+
+	FileSystemId *string `locationName:"fileSystemId" type:"string"`
+
+	RootDirectory *string `locationName:"rootDirectory" type:"string"`
+
+	TransitEncryption *string `locationName:"transitEncryption" type:"string"`
+
+	TransitEncryptionPort *int64 `locationName:"transitEncryptionPort" type:"integer"`
+
+	metadataEFSVolumeConfiguration `json:"-", xml:"-"`
+}
+
+type metadataEFSVolumeConfiguration struct {
+	SDKShapeTraits bool `type:"structure"`
+}
+
 type ErrorMessage struct {
 	Message *string `locationName:"message" type:"string"`
 
@@ -137,6 +221,18 @@ type metadataHostVolumeProperties struct {
 	SDKShapeTraits bool `type:"structure"`
 }
 
+type HugePageLimit struct {
+	Limit *int64 `locationName:"limit" type:"integer"`
+
+	PageSize *string `locationName:"pageSize" type:"string"`
+
+	metadataHugePageLimit `json:"-", xml:"-"`
+}
+
+type metadataHugePageLimit struct {
+	SDKShapeTraits bool `type:"structure"`
+}
+
 type InactiveInstanceException struct {
 	Message *string `locationName:"message" type:"string"`
 
@@ -167,6 +263,28 @@ type metadataInvalidInstanceException struct {
 	SDKShapeTraits bool `type:"structure"`
 }
 
+type LinuxParameters struct {
+	CpusetCpus *string `locationName:"cpusetCpus" type:"string"`
+
+	Devices []*Device `locationName:"devices" type:"list"`
+
+	HugePages []*HugePageLimit `locationName:"hugePages" type:"list"`
+
+	MaxSwap *int64 `locationName:"maxSwap" type:"integer"`
+
+	PidsLimit *int64 `locationName:"pidsLimit" type:"integer"`
+
+	Swappiness *int64 `locationName:"swappiness" type:"integer"`
+
+	Tmpfs []*Tmpfs `locationName:"tmpfs" type:"list"`
+
+	metadataLinuxParameters `json:"-", xml:"-"`
+}
+
+type metadataLinuxParameters struct {
+	SDKShapeTraits bool `type:"structure"`
+}
+
 type MountPoint struct {
 	ContainerPath *string `locationName:"containerPath" type:"string"`
 
@@ -300,6 +418,8 @@ type Task struct {
 
 	Overrides *string `locationName:"overrides" type:"string"`
 
+	Platform *string `locationName:"platform" type:"string"`
+
 	TaskDefinitionAccountId *string `locationName:"taskDefinitionAccountId" type:"string"`
 
 	Version *string `locationName:"version" type:"string"`
@@ -313,6 +433,18 @@ type metadataTask struct {
 	SDKShapeTraits bool `type:"structure"`
 }
 
+type Tmpfs struct {
+	ContainerPath *string `locationName:"containerPath" type:"string"`
+
+	Size *int64 `locationName:"size" type:"integer"`
+
+	metadataTmpfs `json:"-", xml:"-"`
+}
+
+type metadataTmpfs struct {
+	SDKShapeTraits bool `type:"structure"`
+}
+
 type UpdateFailureOutput struct {
 	metadataUpdateFailureOutput `json:"-", xml:"-"`
 }
@@ -348,6 +480,8 @@ type metadataVersionInfo struct {
 }
 
 type Volume struct {
+	EfsVolumeConfiguration *EFSVolumeConfiguration `locationName:"efsVolumeConfiguration" type:"structure"`
+
 	Host *HostVolumeProperties `locationName:"host" type:"structure"`
 
 	Name *string `locationName:"name" type:"string"`
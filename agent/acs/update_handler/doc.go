@@ -19,4 +19,19 @@
 // referenced file and validating it against the provided checksum. However,
 // the actual 'update' component is handled by signaling a watching process via
 // exit code.
+//
+// If a signing key is configured, the downloaded file's checksum must also be
+// signed with it; the signature is downloaded from beside the file, with
+// '.sig' added to the path of its location.
+//
+// The watching process starts the agent named in the 'desired-image' file of
+// the download directory. Before exiting to be updated, the agent writes the
+// image it was started from to 'previous-image', which is empty if it was the
+// one the watching process was installed with. The new agent removes that file
+// once it has started and connected to ACS. If the new agent exits while the
+// file remains, the watching process should roll back to the previous image.
+// The agent also writes the newest state format it can read to
+// 'previous-data-version'; if the new agent saves its state in a newer format,
+// it removes 'previous-image' when it starts, so that it is not rolled back to
+// an agent which can't read that state.
 package updater
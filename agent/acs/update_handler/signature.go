@@ -0,0 +1,133 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package updater
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// maxSignatureSize bounds how much of a signature is read
+const maxSignatureSize = 64 * 1024
+
+// signatureSuffix is appended to the path of an update's location to find its
+// signature
+const signatureSuffix = ".sig"
+
+// ecdsaSignature is the ASN.1 form of an ECDSA signature
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// loadSigningKey reads the PEM encoded public key updates are signed with
+func loadSigningKey(path string) (crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key in " + path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	}
+	return nil, errors.New("unsupported key type in " + path + "; expected RSA or ECDSA")
+}
+
+// verifySignature checks that the update's signature, downloaded from beside
+// it, was made of its sha256 checksum with the signing key's private key
+func (u *updater) verifySignature(location string, checksum []byte) error {
+	if u.signingKey == nil {
+		return nil
+	}
+	signatureLocation, err := signatureLocation(location)
+	if err != nil {
+		return err
+	}
+	signature, err := u.downloadSignature(signatureLocation)
+	if err != nil {
+		return errors.New("unable to download signature: " + err.Error())
+	}
+	switch key := u.signingKey.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA256, checksum, signature)
+	case *ecdsa.PublicKey:
+		err = verifyECDSA(key, checksum, signature)
+	default:
+		err = errors.New("unsupported signing key")
+	}
+	if err != nil {
+		return errors.New("Signature validation failed: " + err.Error())
+	}
+	return nil
+}
+
+// verifyECDSA checks an ASN.1 encoded ECDSA signature of hash
+func verifyECDSA(key *ecdsa.PublicKey, hash, signature []byte) error {
+	var parsed ecdsaSignature
+	rest, err := asn1.Unmarshal(signature, &parsed)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 || parsed.R == nil || parsed.S == nil {
+		return errors.New("malformed ecdsa signature")
+	}
+	if !ecdsa.Verify(key, hash, parsed.R, parsed.S) {
+		return errors.New("ecdsa verification failed")
+	}
+	return nil
+}
+
+// signatureLocation returns where the signature of the update at location is
+// found: beside it, with the suffix added to its path rather than to any
+// query string the location has
+func signatureLocation(location string) (string, error) {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return "", errors.New("invalid update location: " + err.Error())
+	}
+	parsed.Path += signatureSuffix
+	return parsed.String(), nil
+}
+
+func (u *updater) downloadSignature(location string) ([]byte, error) {
+	resp, err := u.httpclient.Get(location)
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status " + strconv.Itoa(resp.StatusCode))
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxSignatureSize))
+}
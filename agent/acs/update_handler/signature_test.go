@@ -0,0 +1,159 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package updater
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/acs/update_handler/os/mock"
+	"github.com/aws/amazon-ecs-agent/agent/httpclient/mock"
+)
+
+func signUpdate(t *testing.T, key *ecdsa.PrivateKey, data string) string {
+	checksum := sha256.Sum256([]byte(data))
+	r, s, err := ecdsa.Sign(rand.Reader, key, checksum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := asn1.Marshal(ecdsaSignature{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(signature)
+}
+
+func stageSignedUpdate(u *updater) {
+	u.stageUpdateHandler()(&ecsacs.StageUpdateMessage{
+		ClusterArn:           ptr("cluster").(*string),
+		ContainerInstanceArn: ptr("containerInstance").(*string),
+		MessageId:            ptr("mid").(*string),
+		UpdateInfo: &ecsacs.UpdateInfo{
+			Location:  ptr("https://s3.amazonaws.com/amazon-ecs-agent/update.tar").(*string),
+			Signature: ptr("6caeef375a080e3241781725b357890758d94b15d7ce63f6b2ff1cb5589f2007").(*string),
+		},
+	})
+}
+
+func TestSignedUpdate(t *testing.T) {
+	u, ctrl, cfg, mockfs, mockacs, mockhttp := mocks(t, nil)
+	defer ctrl.Finish()
+	defer os.RemoveAll(cfg.UpdateDownloadDir)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	u.signingKey = &key.PublicKey
+
+	gomock.InOrder(
+		mockhttp.EXPECT().RoundTrip(mock_http.NewHTTPSimpleMatcher("GET", "https://s3.amazonaws.com/amazon-ecs-agent/update.tar")).Return(mock_http.SuccessResponse("update-tar-data"), nil),
+		mockhttp.EXPECT().RoundTrip(mock_http.NewHTTPSimpleMatcher("GET", "https://s3.amazonaws.com/amazon-ecs-agent/update.tar.sig")).Return(mock_http.SuccessResponse(signUpdate(t, key, "update-tar-data")), nil),
+		mockfs.EXPECT().Open(filepath.Join(cfg.UpdateDownloadDir, desiredImageFile)).Return(nil, os.ErrNotExist),
+		mockfs.EXPECT().Open(cachedUpdatePath(cfg, "6caeef375a080e3241781725b357890758d94b15d7ce63f6b2ff1cb5589f2007")).Return(mock_os.NopReadWriteCloser(bytes.NewBufferString("update-tar-data")), nil),
+		mockfs.EXPECT().Create(gomock.Any()).Return(mock_os.NopReadWriteCloser(&bytes.Buffer{}), nil),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, desiredImageFile), stagedUpdateName{}, gomock.Any()).Return(nil),
+		mockacs.EXPECT().MakeRequest(gomock.Eq(&ecsacs.AckRequest{
+			Cluster:           ptr("cluster").(*string),
+			ContainerInstance: ptr("containerInstance").(*string),
+			MessageId:         ptr("mid").(*string),
+		})),
+	)
+	stageSignedUpdate(u)
+}
+
+func TestBadlySignedUpdate(t *testing.T) {
+	u, ctrl, cfg, mockfs, mockacs, mockhttp := mocks(t, nil)
+	defer ctrl.Finish()
+	defer os.RemoveAll(cfg.UpdateDownloadDir)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	u.signingKey = &key.PublicKey
+
+	gomock.InOrder(
+		mockhttp.EXPECT().RoundTrip(mock_http.NewHTTPSimpleMatcher("GET", "https://s3.amazonaws.com/amazon-ecs-agent/update.tar")).Return(mock_http.SuccessResponse("update-tar-data"), nil),
+		mockhttp.EXPECT().RoundTrip(mock_http.NewHTTPSimpleMatcher("GET", "https://s3.amazonaws.com/amazon-ecs-agent/update.tar.sig")).Return(mock_http.SuccessResponse(signUpdate(t, otherKey, "update-tar-data")), nil),
+		mockfs.EXPECT().Remove(cachedUpdatePath(cfg, "6caeef375a080e3241781725b357890758d94b15d7ce63f6b2ff1cb5589f2007")),
+		mockacs.EXPECT().MakeRequest(gomock.Any()).Do(func(req interface{}) {
+			nack, ok := req.(*ecsacs.NackRequest)
+			if !ok || !strings.Contains(*nack.Reason, "Signature validation failed") {
+				t.Error("Expected the update to be nacked for its signature", req)
+			}
+		}),
+	)
+	stageSignedUpdate(u)
+}
+
+func TestSignatureLocation(t *testing.T) {
+	for location, expected := range map[string]string{
+		"https://s3.amazonaws.com/amazon-ecs-agent/update.tar":                        "https://s3.amazonaws.com/amazon-ecs-agent/update.tar.sig",
+		"https://bucket.s3.amazonaws.com/update.tar?X-Amz-Signature=abc&X-Amz-Date=1": "https://bucket.s3.amazonaws.com/update.tar.sig?X-Amz-Signature=abc&X-Amz-Date=1",
+	} {
+		if actual, err := signatureLocation(location); err != nil || actual != expected {
+			t.Error("Unexpected signature location for", location, actual, err)
+		}
+	}
+}
+
+func TestVerifyECDSAMalformed(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	checksum := sha256.Sum256([]byte("update-tar-data"))
+	if err := verifyECDSA(&key.PublicKey, checksum[:], []byte("not asn1")); err == nil {
+		t.Error("Expected a malformed signature to be refused")
+	}
+	signature := signUpdate(t, key, "update-tar-data")
+	if err := verifyECDSA(&key.PublicKey, checksum[:], []byte(signature+"trailing")); err == nil {
+		t.Error("Expected trailing data after a signature to be refused")
+	}
+	if err := verifyECDSA(&key.PublicKey, checksum[:], []byte(signature)); err != nil {
+		t.Error("Expected the signature to verify", err)
+	}
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signingkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	keyPath := filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+	key, err := loadSigningKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := key.(*rsa.PublicKey); !ok {
+		t.Error("Expected an RSA key", key)
+	}
+
+	garbagePath := filepath.Join(dir, "garbage.pem")
+	ioutil.WriteFile(garbagePath, []byte("not a key"), 0600)
+	if _, err := loadSigningKey(garbagePath); err == nil {
+		t.Error("Expected an error loading a file without a key")
+	}
+}
@@ -14,12 +14,15 @@
 package updater
 
 import (
-	"crypto/sha256"
+	"crypto"
+	"encoding/hex"
 	"errors"
-	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	goos "os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -27,6 +30,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
 	"github.com/aws/amazon-ecs-agent/agent/acs/update_handler/os"
 	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/downloader"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/httpclient"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
@@ -40,7 +44,22 @@ import (
 
 var log = logger.ForModule("updater")
 
-const desiredImageFile = "desired-image"
+const (
+	desiredImageFile = "desired-image"
+	// updateCacheDir is the directory under the update download directory in
+	// which downloads are cached, so that an interrupted one resumes
+	updateCacheDir = "cache"
+	// previousImageFile names the agent an update replaces, for the external
+	// updating process to roll back to if the new agent fails to start. It is
+	// empty if the agent being replaced is the one it was installed with.
+	previousImageFile = "previous-image"
+	// previousDataVersionFile holds the newest state format the agent named
+	// in previousImageFile can read
+	previousDataVersionFile = "previous-data-version"
+	// unrecordedDataVersion is the newest state format agents which don't
+	// write previousDataVersionFile can read
+	unrecordedDataVersion = 3
+)
 
 // update describes metadata around an update 2-phase request
 type updater struct {
@@ -56,6 +75,15 @@ type updater struct {
 	acs        wsclient.ClientServer
 	config     *config.Config
 	httpclient *http.Client
+	// downloader caches updates under the update download directory, from
+	// which they are copied to be staged
+	downloader *downloader.Downloader
+	// signingKey, if set, is the key updates must be signed with
+	signingKey crypto.PublicKey
+	// previousImage is the image this agent was started from, read from the
+	// desired image file before the first update it stages replaces it
+	previousImage     []byte
+	previousImageRead bool
 
 	sync.Mutex
 }
@@ -71,6 +99,9 @@ const (
 const (
 	maxUpdateDuration     = 30 * time.Minute
 	updateDownloadTimeout = 15 * time.Minute
+	// updateCacheDuration is how long a cached update is kept once no update
+	// has used it
+	updateCacheDuration = 24 * time.Hour
 )
 
 // Singleton updater
@@ -80,12 +111,33 @@ var singleUpdater *updater
 // updates
 func AddAgentUpdateHandlers(cs wsclient.ClientServer, cfg *config.Config, saver statemanager.Saver, taskEngine engine.TaskEngine) {
 	if cfg.UpdatesEnabled {
-		singleUpdater = &updater{
+		var signingKey crypto.PublicKey
+		if cfg.UpdateSigningKey != "" {
+			key, err := loadSigningKey(cfg.UpdateSigningKey)
+			if err != nil {
+				log.Error("Unable to load the update signing key; updates disabled", "err", err)
+				return
+			}
+			signingKey = key
+		}
+		client := httpclient.New(updateDownloadTimeout, false)
+		newUpdater := &updater{
 			acs:        cs,
 			config:     cfg,
 			fs:         os.Default,
-			httpclient: httpclient.New(updateDownloadTimeout, false),
+			httpclient: client,
+			downloader: downloader.New(filepath.Join(cfg.UpdateDownloadDir, updateCacheDir), client, cfg.AWSRegion, nil, cfg.DownloadBandwidthLimit),
+			signingKey: signingKey,
+		}
+		if singleUpdater != nil {
+			// Handlers are added again on each connection to ACS; an update
+			// staged on an earlier one has already replaced the desired image
+			singleUpdater.Lock()
+			newUpdater.previousImage = singleUpdater.previousImage
+			newUpdater.previousImageRead = singleUpdater.previousImageRead
+			singleUpdater.Unlock()
 		}
+		singleUpdater = newUpdater
 		cs.AddRequestHandler(singleUpdater.stageUpdateHandler())
 		cs.AddRequestHandler(singleUpdater.performUpdateHandler(saver, taskEngine))
 		log.Debug("Added update handlers")
@@ -173,42 +225,71 @@ func (u *updater) download(info *ecsacs.UpdateInfo) (err error) {
 	if info.Signature == nil {
 		return errors.New("No signature given")
 	}
-	resp, err := u.httpclient.Get(*info.Location)
-	if resp != nil && resp.Body != nil {
-		defer resp.Body.Close()
-	}
+	path, err := u.downloader.Fetch(downloader.Artifact{
+		Source: *info.Location,
+		SHA256: *info.Signature,
+	})
 	if err != nil {
 		return err
 	}
+	// Fetch only returns an artifact matching its checksum
+	shasum, _ := hex.DecodeString(strings.TrimSpace(*info.Signature))
+	if err = u.verifySignature(*info.Location, shasum); err != nil {
+		u.fs.Remove(path)
+		return err
+	}
 
+	desiredImagePath := filepath.Join(u.config.UpdateDownloadDir, desiredImageFile)
+	if !u.previousImageRead {
+		u.previousImage, err = u.readFile(desiredImagePath)
+		if err != nil {
+			return err
+		}
+		u.previousImageRead = true
+	}
 	outFileBasename := utils.RandHex() + ".ecs-update.tar"
-	outFilePath := filepath.Join(u.config.UpdateDownloadDir, outFileBasename)
-	outFile, err := u.fs.Create(outFilePath)
+	if err = u.copyFile(path, filepath.Join(u.config.UpdateDownloadDir, outFileBasename)); err != nil {
+		return err
+	}
+	err = u.fs.WriteFile(desiredImagePath, []byte(outFileBasename+"\n"), 0644)
+	u.downloader.Prune(updateCacheDuration)
+	return err
+}
+
+// copyFile copies the file at source to a new file at target, which is
+// removed if the copy fails
+func (u *updater) copyFile(source, target string) (err error) {
+	in, err := u.fs.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := u.fs.Create(target)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		outFile.Close()
+		out.Close()
 		if err != nil {
-			u.fs.Remove(outFilePath)
+			u.fs.Remove(target)
 		}
 	}()
+	_, err = io.Copy(out, in)
+	return err
+}
 
-	hashsum := sha256.New()
-	bodyHashReader := io.TeeReader(resp.Body, hashsum)
-	_, err = io.Copy(outFile, bodyHashReader)
+// readFile returns the contents of the file at path, or nothing if it does
+// not exist
+func (u *updater) readFile(path string) ([]byte, error) {
+	file, err := u.fs.Open(path)
 	if err != nil {
-		return err
-	}
-	shasum := hashsum.Sum(nil)
-	shasumString := fmt.Sprintf("%x", shasum)
-
-	if shasumString != strings.TrimSpace(*info.Signature) {
-		return errors.New("Hashsum validation failed")
+		if goos.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
-
-	err = u.fs.WriteFile(filepath.Join(u.config.UpdateDownloadDir, desiredImageFile), []byte(outFileBasename+"\n"), 0644)
-	return err
+	defer file.Close()
+	return u.fs.ReadAll(file)
 }
 
 func (u *updater) performUpdateHandler(saver statemanager.Saver, taskEngine engine.TaskEngine) func(req *ecsacs.PerformUpdateMessage) {
@@ -228,13 +309,30 @@ func (u *updater) performUpdateHandler(saver statemanager.Saver, taskEngine engi
 			})
 			return
 		}
+		// Until the new agent confirms it started, the updating process can
+		// roll back to this one
+		err := u.fs.WriteFile(filepath.Join(u.config.UpdateDownloadDir, previousDataVersionFile), []byte(strconv.Itoa(statemanager.EncryptedEcsDataVersion)+"\n"), 0644)
+		if err == nil {
+			err = u.fs.WriteFile(filepath.Join(u.config.UpdateDownloadDir, previousImageFile), u.previousImage, 0644)
+		}
+		if err != nil {
+			log.Error("Unable to record the agent to roll back to", "err", err)
+			reason := "Cannot perform update; unable to record the agent to roll back to"
+			u.acs.MakeRequest(&ecsacs.NackRequest{
+				Cluster:           req.ClusterArn,
+				ContainerInstance: req.ContainerInstanceArn,
+				MessageId:         req.MessageId,
+				Reason:            &reason,
+			})
+			return
+		}
 		u.acs.MakeRequest(&ecsacs.AckRequest{
 			Cluster:           req.ClusterArn,
 			ContainerInstance: req.ContainerInstanceArn,
 			MessageId:         req.MessageId,
 		})
 
-		err := sighandlers.FinalSave(saver, taskEngine)
+		err = sighandlers.FinalSave(saver, taskEngine)
 		if err != nil {
 			log.Crit("Error saving before update exit", "err", err)
 		} else {
@@ -250,3 +348,49 @@ func (u *updater) reset() {
 	u.stage = updateNone
 	u.stageTime = time.Time{}
 }
+
+var confirmUpdateOnce sync.Once
+
+// ConfirmUpdate records that this agent started and reached the backend, so
+// that the updating process no longer rolls back to the agent it replaced.
+// Only the first call by each agent process does anything, so that an agent
+// which has since staged an update of its own doesn't confirm that.
+func ConfirmUpdate(cfg *config.Config) {
+	if !cfg.UpdatesEnabled {
+		return
+	}
+	confirmUpdateOnce.Do(func() {
+		os.Default.Remove(filepath.Join(cfg.UpdateDownloadDir, previousImageFile))
+		os.Default.Remove(filepath.Join(cfg.UpdateDownloadDir, previousDataVersionFile))
+	})
+}
+
+// CheckRollback stops the updating process from rolling back to the agent
+// this one replaced if that agent couldn't read the state this one saves, as
+// it would then fail to start too. It must be called before the state is
+// first saved.
+func CheckRollback(cfg *config.Config) {
+	if !cfg.UpdatesEnabled {
+		return
+	}
+	previousImagePath := filepath.Join(cfg.UpdateDownloadDir, previousImageFile)
+	if _, err := goos.Stat(previousImagePath); err != nil {
+		return
+	}
+	previousDataVersion := -1
+	data, err := ioutil.ReadFile(filepath.Join(cfg.UpdateDownloadDir, previousDataVersionFile))
+	if goos.IsNotExist(err) {
+		previousDataVersion = unrecordedDataVersion
+	} else if err == nil {
+		if version, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			previousDataVersion = version
+		}
+	}
+	dataVersion := statemanager.SavedDataVersion(cfg)
+	if previousDataVersion >= dataVersion {
+		return
+	}
+	log.Warn("The agent this one replaced can't read its state; it won't be rolled back to", "previousDataVersion", previousDataVersion, "dataVersion", dataVersion)
+	os.Default.Remove(previousImagePath)
+	os.Default.Remove(filepath.Join(cfg.UpdateDownloadDir, previousDataVersionFile))
+}
@@ -17,7 +17,13 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -25,6 +31,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
 	"github.com/aws/amazon-ecs-agent/agent/acs/update_handler/os/mock"
 	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/downloader"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/httpclient"
 	"github.com/aws/amazon-ecs-agent/agent/httpclient/mock"
@@ -41,9 +48,13 @@ func ptr(i interface{}) interface{} {
 
 func mocks(t *testing.T, cfg *config.Config) (*updater, *gomock.Controller, *config.Config, *mock_os.MockFileSystem, *mock_client.MockClientServer, *mock_http.MockRoundTripper) {
 	if cfg == nil {
+		dir, err := ioutil.TempDir("", "updates")
+		if err != nil {
+			t.Fatal(err)
+		}
 		cfg = &config.Config{
 			UpdatesEnabled:    true,
-			UpdateDownloadDir: "/tmp/test/",
+			UpdateDownloadDir: dir,
 		}
 	}
 	ctrl := gomock.NewController(t)
@@ -59,25 +70,55 @@ func mocks(t *testing.T, cfg *config.Config) (*updater, *gomock.Controller, *con
 		config:     cfg,
 		fs:         mockfs,
 		httpclient: httpClient,
+		downloader: downloader.New(filepath.Join(cfg.UpdateDownloadDir, updateCacheDir), httpClient, "us-east-1", nil, 0),
 	}
 
 	return u, ctrl, cfg, mockfs, mockacs, mockhttp
 }
 
+// cachedUpdatePath returns where the update with the given checksum is cached
+func cachedUpdatePath(cfg *config.Config, checksum string) string {
+	return filepath.Join(cfg.UpdateDownloadDir, updateCacheDir, "sha256-"+checksum)
+}
+
+// cachedUpdate returns the contents of the update with the given checksum in
+// the download directory's cache
+func cachedUpdate(cfg *config.Config, checksum string) string {
+	data, _ := ioutil.ReadFile(cachedUpdatePath(cfg, checksum))
+	return string(data)
+}
+
+// stagedUpdateName matches the contents of the desired image file naming a
+// staged update, <random hex>.ecs-update.tar
+type stagedUpdateName struct{}
+
+func (stagedUpdateName) Matches(x interface{}) bool {
+	data, ok := x.([]byte)
+	return ok && strings.HasSuffix(string(data), ".ecs-update.tar\n") && !strings.Contains(string(data), "/")
+}
+
+func (stagedUpdateName) String() string { return "is the name of a staged update" }
+
 func TestFullUpdateFlow(t *testing.T) {
 	u, ctrl, cfg, mockfs, mockacs, mockhttp := mocks(t, nil)
 	defer ctrl.Finish()
+	defer os.RemoveAll(cfg.UpdateDownloadDir)
 
-	var writtenFile bytes.Buffer
+	var stagedFile bytes.Buffer
 	gomock.InOrder(
 		mockhttp.EXPECT().RoundTrip(mock_http.NewHTTPSimpleMatcher("GET", "https://s3.amazonaws.com/amazon-ecs-agent/update.tar")).Return(mock_http.SuccessResponse("update-tar-data"), nil),
-		mockfs.EXPECT().Create(gomock.Any()).Return(mock_os.NopReadWriteCloser(&writtenFile), nil),
-		mockfs.EXPECT().WriteFile("/tmp/test/desired-image", gomock.Any(), gomock.Any()).Return(nil),
+		mockfs.EXPECT().Open(filepath.Join(cfg.UpdateDownloadDir, desiredImageFile)).Return(mock_os.NopReadWriteCloser(bytes.NewBufferString("old.ecs-update.tar\n")), nil),
+		mockfs.EXPECT().ReadAll(gomock.Any()).Return([]byte("old.ecs-update.tar\n"), nil),
+		mockfs.EXPECT().Open(cachedUpdatePath(cfg, "6caeef375a080e3241781725b357890758d94b15d7ce63f6b2ff1cb5589f2007")).Return(mock_os.NopReadWriteCloser(bytes.NewBufferString("update-tar-data")), nil),
+		mockfs.EXPECT().Create(gomock.Any()).Return(mock_os.NopReadWriteCloser(&stagedFile), nil),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, desiredImageFile), stagedUpdateName{}, gomock.Any()).Return(nil),
 		mockacs.EXPECT().MakeRequest(gomock.Eq(&ecsacs.AckRequest{
 			Cluster:           ptr("cluster").(*string),
 			ContainerInstance: ptr("containerInstance").(*string),
 			MessageId:         ptr("mid").(*string),
 		})),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, previousDataVersionFile), []byte(strconv.Itoa(statemanager.EncryptedEcsDataVersion)+"\n"), gomock.Any()).Return(nil),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, previousImageFile), []byte("old.ecs-update.tar\n"), gomock.Any()).Return(nil),
 		mockacs.EXPECT().MakeRequest(gomock.Eq(&ecsacs.AckRequest{
 			Cluster:           ptr("cluster").(*string),
 			ContainerInstance: ptr("containerInstance").(*string),
@@ -96,9 +137,12 @@ func TestFullUpdateFlow(t *testing.T) {
 		},
 	})
 
-	if writtenFile.String() != "update-tar-data" {
+	if cachedUpdate(cfg, "6caeef375a080e3241781725b357890758d94b15d7ce63f6b2ff1cb5589f2007") != "update-tar-data" {
 		t.Error("Incorrect data written")
 	}
+	if stagedFile.String() != "update-tar-data" {
+		t.Error("Incorrect data staged", stagedFile.String())
+	}
 
 	u.performUpdateHandler(statemanager.NewNoopStateManager(), engine.NewTaskEngine(cfg))(&ecsacs.PerformUpdateMessage{
 		ClusterArn:           ptr("cluster").(*string),
@@ -135,8 +179,9 @@ func (m *nackRequestMatcher) Matches(nack interface{}) bool {
 }
 
 func TestMissingUpdateInfo(t *testing.T) {
-	u, ctrl, _, _, mockacs, _ := mocks(t, nil)
+	u, ctrl, cfg, _, mockacs, _ := mocks(t, nil)
 	defer ctrl.Finish()
+	defer os.RemoveAll(cfg.UpdateDownloadDir)
 
 	mockacs.EXPECT().MakeRequest(&nackRequestMatcher{&ecsacs.NackRequest{
 		Cluster:           ptr("cluster").(*string),
@@ -158,6 +203,7 @@ func (m *nackRequestMatcher) String() string {
 func TestUndownloadedUpdate(t *testing.T) {
 	u, ctrl, cfg, _, mockacs, _ := mocks(t, nil)
 	defer ctrl.Finish()
+	defer os.RemoveAll(cfg.UpdateDownloadDir)
 
 	mockacs.EXPECT().MakeRequest(&nackRequestMatcher{&ecsacs.NackRequest{
 		Cluster:           ptr("cluster").(*string),
@@ -175,12 +221,14 @@ func TestUndownloadedUpdate(t *testing.T) {
 func TestDuplicateUpdateMessagesWithSuccess(t *testing.T) {
 	u, ctrl, cfg, mockfs, mockacs, mockhttp := mocks(t, nil)
 	defer ctrl.Finish()
+	defer os.RemoveAll(cfg.UpdateDownloadDir)
 
-	var writtenFile bytes.Buffer
 	gomock.InOrder(
 		mockhttp.EXPECT().RoundTrip(mock_http.NewHTTPSimpleMatcher("GET", "https://s3.amazonaws.com/amazon-ecs-agent/update.tar")).Return(mock_http.SuccessResponse("update-tar-data"), nil),
-		mockfs.EXPECT().Create(gomock.Any()).Return(mock_os.NopReadWriteCloser(&writtenFile), nil),
-		mockfs.EXPECT().WriteFile("/tmp/test/desired-image", gomock.Any(), gomock.Any()).Return(nil),
+		mockfs.EXPECT().Open(filepath.Join(cfg.UpdateDownloadDir, desiredImageFile)).Return(nil, os.ErrNotExist),
+		mockfs.EXPECT().Open(cachedUpdatePath(cfg, "6caeef375a080e3241781725b357890758d94b15d7ce63f6b2ff1cb5589f2007")).Return(mock_os.NopReadWriteCloser(bytes.NewBufferString("update-tar-data")), nil),
+		mockfs.EXPECT().Create(gomock.Any()).Return(mock_os.NopReadWriteCloser(&bytes.Buffer{}), nil),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, desiredImageFile), stagedUpdateName{}, gomock.Any()).Return(nil),
 		mockacs.EXPECT().MakeRequest(gomock.Eq(&ecsacs.AckRequest{
 			Cluster:           ptr("cluster").(*string),
 			ContainerInstance: ptr("containerInstance").(*string),
@@ -191,6 +239,8 @@ func TestDuplicateUpdateMessagesWithSuccess(t *testing.T) {
 			ContainerInstance: ptr("containerInstance").(*string),
 			MessageId:         ptr("mid2").(*string),
 		})),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, previousDataVersionFile), []byte(strconv.Itoa(statemanager.EncryptedEcsDataVersion)+"\n"), gomock.Any()).Return(nil),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, previousImageFile), []byte(nil), gomock.Any()).Return(nil),
 		mockacs.EXPECT().MakeRequest(gomock.Eq(&ecsacs.AckRequest{
 			Cluster:           ptr("cluster").(*string),
 			ContainerInstance: ptr("containerInstance").(*string),
@@ -221,7 +271,7 @@ func TestDuplicateUpdateMessagesWithSuccess(t *testing.T) {
 		},
 	})
 
-	if writtenFile.String() != "update-tar-data" {
+	if cachedUpdate(cfg, "6caeef375a080e3241781725b357890758d94b15d7ce63f6b2ff1cb5589f2007") != "update-tar-data" {
 		t.Error("Incorrect data written")
 	}
 
@@ -239,25 +289,32 @@ func TestDuplicateUpdateMessagesWithSuccess(t *testing.T) {
 func TestDuplicateUpdateMessagesWithFailure(t *testing.T) {
 	u, ctrl, cfg, mockfs, mockacs, mockhttp := mocks(t, nil)
 	defer ctrl.Finish()
+	defer os.RemoveAll(cfg.UpdateDownloadDir)
 
-	var writtenFile bytes.Buffer
 	gomock.InOrder(
-		mockhttp.EXPECT().RoundTrip(mock_http.NewHTTPSimpleMatcher("GET", "https://s3.amazonaws.com/amazon-ecs-agent/update.tar")).Return(mock_http.SuccessResponse("update-tar-data"), nil),
-		mockfs.EXPECT().Create(gomock.Any()).Return(nil, errors.New("test error")),
+		mockhttp.EXPECT().RoundTrip(mock_http.NewHTTPSimpleMatcher("GET", "https://s3.amazonaws.com/amazon-ecs-agent/update.tar")).Return(&http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Status:     "500 Internal Server Error",
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}, nil),
 		mockacs.EXPECT().MakeRequest(gomock.Eq(&ecsacs.NackRequest{
 			Cluster:           ptr("cluster").(*string),
 			ContainerInstance: ptr("containerInstance").(*string),
 			MessageId:         ptr("mid").(*string),
-			Reason:            ptr("Unable to download: test error").(*string),
+			Reason:            ptr("Unable to download: unexpected status 500 Internal Server Error downloading https://s3.amazonaws.com/amazon-ecs-agent/update.tar").(*string),
 		})),
 		mockhttp.EXPECT().RoundTrip(mock_http.NewHTTPSimpleMatcher("GET", "https://s3.amazonaws.com/amazon-ecs-agent/update.tar")).Return(mock_http.SuccessResponse("update-tar-data"), nil),
-		mockfs.EXPECT().Create(gomock.Any()).Return(mock_os.NopReadWriteCloser(&writtenFile), nil),
-		mockfs.EXPECT().WriteFile("/tmp/test/desired-image", gomock.Any(), gomock.Any()).Return(nil),
+		mockfs.EXPECT().Open(filepath.Join(cfg.UpdateDownloadDir, desiredImageFile)).Return(nil, os.ErrNotExist),
+		mockfs.EXPECT().Open(cachedUpdatePath(cfg, "6caeef375a080e3241781725b357890758d94b15d7ce63f6b2ff1cb5589f2007")).Return(mock_os.NopReadWriteCloser(bytes.NewBufferString("update-tar-data")), nil),
+		mockfs.EXPECT().Create(gomock.Any()).Return(mock_os.NopReadWriteCloser(&bytes.Buffer{}), nil),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, desiredImageFile), stagedUpdateName{}, gomock.Any()).Return(nil),
 		mockacs.EXPECT().MakeRequest(gomock.Eq(&ecsacs.AckRequest{
 			Cluster:           ptr("cluster").(*string),
 			ContainerInstance: ptr("containerInstance").(*string),
 			MessageId:         ptr("mid2").(*string),
 		})),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, previousDataVersionFile), []byte(strconv.Itoa(statemanager.EncryptedEcsDataVersion)+"\n"), gomock.Any()).Return(nil),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, previousImageFile), []byte(nil), gomock.Any()).Return(nil),
 		mockacs.EXPECT().MakeRequest(gomock.Eq(&ecsacs.AckRequest{
 			Cluster:           ptr("cluster").(*string),
 			ContainerInstance: ptr("containerInstance").(*string),
@@ -288,7 +345,7 @@ func TestDuplicateUpdateMessagesWithFailure(t *testing.T) {
 		},
 	})
 
-	if writtenFile.String() != "update-tar-data" {
+	if cachedUpdate(cfg, "6caeef375a080e3241781725b357890758d94b15d7ce63f6b2ff1cb5589f2007") != "update-tar-data" {
 		t.Error("Incorrect data written")
 	}
 
@@ -306,12 +363,14 @@ func TestDuplicateUpdateMessagesWithFailure(t *testing.T) {
 func TestNewerUpdateMessages(t *testing.T) {
 	u, ctrl, cfg, mockfs, mockacs, mockhttp := mocks(t, nil)
 	defer ctrl.Finish()
+	defer os.RemoveAll(cfg.UpdateDownloadDir)
 
-	var writtenFile bytes.Buffer
 	gomock.InOrder(
 		mockhttp.EXPECT().RoundTrip(mock_http.NewHTTPSimpleMatcher("GET", "https://s3.amazonaws.com/amazon-ecs-agent/update.tar")).Return(mock_http.SuccessResponse("update-tar-data"), nil),
-		mockfs.EXPECT().Create(gomock.Any()).Return(mock_os.NopReadWriteCloser(&writtenFile), nil),
-		mockfs.EXPECT().WriteFile("/tmp/test/desired-image", gomock.Any(), gomock.Any()).Return(nil),
+		mockfs.EXPECT().Open(filepath.Join(cfg.UpdateDownloadDir, desiredImageFile)).Return(nil, os.ErrNotExist),
+		mockfs.EXPECT().Open(cachedUpdatePath(cfg, "6caeef375a080e3241781725b357890758d94b15d7ce63f6b2ff1cb5589f2007")).Return(mock_os.NopReadWriteCloser(bytes.NewBufferString("update-tar-data")), nil),
+		mockfs.EXPECT().Create(gomock.Any()).Return(mock_os.NopReadWriteCloser(&bytes.Buffer{}), nil),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, desiredImageFile), stagedUpdateName{}, gomock.Any()).Return(nil),
 		mockacs.EXPECT().MakeRequest(gomock.Eq(&ecsacs.AckRequest{
 			Cluster:           ptr("cluster").(*string),
 			ContainerInstance: ptr("containerInstance").(*string),
@@ -324,13 +383,16 @@ func TestNewerUpdateMessages(t *testing.T) {
 			Reason:            ptr("New update arrived: StageMIDNew").(*string),
 		}}),
 		mockhttp.EXPECT().RoundTrip(mock_http.NewHTTPSimpleMatcher("GET", "https://s3.amazonaws.com/amazon-ecs-agent/new.tar")).Return(mock_http.SuccessResponse("newer-update-tar-data"), nil),
-		mockfs.EXPECT().Create(gomock.Any()).Return(mock_os.NopReadWriteCloser(&writtenFile), nil),
-		mockfs.EXPECT().WriteFile("/tmp/test/desired-image", gomock.Any(), gomock.Any()).Return(nil),
+		mockfs.EXPECT().Open(cachedUpdatePath(cfg, "9c6ea7bd7d49f95b6d516517e453b965897109bf8a1d6ff3a6e57287049eb2de")).Return(mock_os.NopReadWriteCloser(bytes.NewBufferString("newer-update-tar-data")), nil),
+		mockfs.EXPECT().Create(gomock.Any()).Return(mock_os.NopReadWriteCloser(&bytes.Buffer{}), nil),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, desiredImageFile), stagedUpdateName{}, gomock.Any()).Return(nil),
 		mockacs.EXPECT().MakeRequest(gomock.Eq(&ecsacs.AckRequest{
 			Cluster:           ptr("cluster").(*string),
 			ContainerInstance: ptr("containerInstance").(*string),
 			MessageId:         ptr("StageMIDNew").(*string),
 		})),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, previousDataVersionFile), []byte(strconv.Itoa(statemanager.EncryptedEcsDataVersion)+"\n"), gomock.Any()).Return(nil),
+		mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, previousImageFile), []byte(nil), gomock.Any()).Return(nil),
 		mockacs.EXPECT().MakeRequest(gomock.Eq(&ecsacs.AckRequest{
 			Cluster:           ptr("cluster").(*string),
 			ContainerInstance: ptr("containerInstance").(*string),
@@ -349,10 +411,9 @@ func TestNewerUpdateMessages(t *testing.T) {
 		},
 	})
 
-	if writtenFile.String() != "update-tar-data" {
+	if cachedUpdate(cfg, "6caeef375a080e3241781725b357890758d94b15d7ce63f6b2ff1cb5589f2007") != "update-tar-data" {
 		t.Error("Incorrect data written")
 	}
-	writtenFile.Reset()
 
 	// Never perform, make sure a new hash results in a new stage
 	u.stageUpdateHandler()(&ecsacs.StageUpdateMessage{
@@ -365,7 +426,7 @@ func TestNewerUpdateMessages(t *testing.T) {
 		},
 	})
 
-	if writtenFile.String() != "newer-update-tar-data" {
+	if cachedUpdate(cfg, "9c6ea7bd7d49f95b6d516517e453b965897109bf8a1d6ff3a6e57287049eb2de") != "newer-update-tar-data" {
 		t.Error("Incorrect data written")
 	}
 
@@ -380,15 +441,93 @@ func TestNewerUpdateMessages(t *testing.T) {
 	})
 }
 
+func TestPerformUpdateWithoutRollback(t *testing.T) {
+	u, ctrl, cfg, mockfs, mockacs, _ := mocks(t, nil)
+	defer ctrl.Finish()
+	defer os.RemoveAll(cfg.UpdateDownloadDir)
+	u.stage = updateDownloaded
+
+	// An update the agent couldn't roll back from isn't performed
+	mockfs.EXPECT().WriteFile(filepath.Join(cfg.UpdateDownloadDir, previousDataVersionFile), gomock.Any(), gomock.Any()).Return(errors.New("read-only filesystem"))
+	mockacs.EXPECT().MakeRequest(&nackRequestMatcher{&ecsacs.NackRequest{
+		MessageId: ptr("mid2").(*string),
+		Reason:    ptr("Cannot perform update; unable to record the agent to roll back to").(*string),
+	}})
+
+	u.performUpdateHandler(statemanager.NewNoopStateManager(), engine.NewTaskEngine(cfg))(&ecsacs.PerformUpdateMessage{
+		ClusterArn:           ptr("cluster").(*string),
+		ContainerInstanceArn: ptr("containerInstance").(*string),
+		MessageId:            ptr("mid2").(*string),
+	})
+}
+
+func TestConfirmUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "updates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	previousImagePath := filepath.Join(dir, previousImageFile)
+	ioutil.WriteFile(previousImagePath, []byte("old.ecs-update.tar\n"), 0644)
+
+	ConfirmUpdate(&config.Config{UpdatesEnabled: true, UpdateDownloadDir: dir})
+	if _, err := os.Stat(previousImagePath); !os.IsNotExist(err) {
+		t.Error("Expected confirming the update to remove the image to roll back to", err)
+	}
+}
+
+func TestCheckRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "updates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := &config.Config{UpdatesEnabled: true, UpdateDownloadDir: dir}
+	previousImagePath := filepath.Join(dir, previousImageFile)
+	previousDataVersionPath := filepath.Join(dir, previousDataVersionFile)
+
+	// The agent being replaced can read this agent's state
+	ioutil.WriteFile(previousImagePath, []byte("old.ecs-update.tar\n"), 0644)
+	ioutil.WriteFile(previousDataVersionPath, []byte(strconv.Itoa(statemanager.EcsDataVersion)+"\n"), 0644)
+	CheckRollback(cfg)
+	if _, err := os.Stat(previousImagePath); err != nil {
+		t.Error("Expected an agent which can read the state to be rolled back to", err)
+	}
+
+	// An agent from before the version was recorded reads plaintext state
+	os.Remove(previousDataVersionPath)
+	CheckRollback(cfg)
+	if _, err := os.Stat(previousImagePath); err != nil {
+		t.Error("Expected an agent which can read plaintext state to be rolled back to", err)
+	}
+
+	// It can't, or didn't say which it can
+	for _, version := range []string{strconv.Itoa(statemanager.EcsDataVersion - 1), "unknown"} {
+		ioutil.WriteFile(previousImagePath, []byte("old.ecs-update.tar\n"), 0644)
+		ioutil.WriteFile(previousDataVersionPath, []byte(version+"\n"), 0644)
+		CheckRollback(cfg)
+		if _, err := os.Stat(previousImagePath); !os.IsNotExist(err) {
+			t.Error("Expected an agent which can't read the state not to be rolled back to", version, err)
+		}
+	}
+
+	// Nor can one from before the version was recorded read encrypted state
+	cfg.StateEncryptionKeyFile = "/etc/ecs/state.key"
+	ioutil.WriteFile(previousImagePath, []byte("old.ecs-update.tar\n"), 0644)
+	os.Remove(previousDataVersionPath)
+	CheckRollback(cfg)
+	if _, err := os.Stat(previousImagePath); !os.IsNotExist(err) {
+		t.Error("Expected an agent which can't read encrypted state not to be rolled back to", err)
+	}
+}
+
 func TestValidationError(t *testing.T) {
-	u, ctrl, _, mockfs, mockacs, mockhttp := mocks(t, nil)
+	u, ctrl, cfg, _, mockacs, mockhttp := mocks(t, nil)
 	defer ctrl.Finish()
+	defer os.RemoveAll(cfg.UpdateDownloadDir)
 
-	var writtenFile bytes.Buffer
 	gomock.InOrder(
 		mockhttp.EXPECT().RoundTrip(mock_http.NewHTTPSimpleMatcher("GET", "https://s3.amazonaws.com/amazon-ecs-agent/update.tar")).Return(mock_http.SuccessResponse("update-tar-data"), nil),
-		mockfs.EXPECT().Create(gomock.Any()).Return(mock_os.NopReadWriteCloser(&writtenFile), nil),
-		mockfs.EXPECT().Remove(gomock.Any()),
 		mockacs.EXPECT().MakeRequest(&nackRequestMatcher{&ecsacs.NackRequest{
 			Cluster:           ptr("cluster").(*string),
 			ContainerInstance: ptr("containerInstance").(*string),
@@ -402,18 +541,20 @@ func TestValidationError(t *testing.T) {
 		MessageId:            ptr("StageMID").(*string),
 		UpdateInfo: &ecsacs.UpdateInfo{
 			Location:  ptr("https://s3.amazonaws.com/amazon-ecs-agent/update.tar").(*string),
-			Signature: ptr("Invalid signature").(*string),
+			Signature: ptr("9c6ea7bd7d49f95b6d516517e453b965897109bf8a1d6ff3a6e57287049eb2de").(*string),
 		},
 	})
 
-	if writtenFile.String() != "update-tar-data" {
-		t.Error("Incorrect data written")
+	files, _ := ioutil.ReadDir(filepath.Join(cfg.UpdateDownloadDir, updateCacheDir))
+	if len(files) != 0 {
+		t.Error("Expected the invalid update to be discarded", files)
 	}
 }
 
 func TestLocationBucketValidationError(t *testing.T) {
-	u, ctrl, _, _, mockacs, _ := mocks(t, nil)
+	u, ctrl, cfg, _, mockacs, _ := mocks(t, nil)
 	defer ctrl.Finish()
+	defer os.RemoveAll(cfg.UpdateDownloadDir)
 
 	mockacs.EXPECT().MakeRequest(&nackRequestMatcher{&ecsacs.NackRequest{
 		Cluster:           ptr("cluster").(*string),
@@ -433,8 +574,9 @@ func TestLocationBucketValidationError(t *testing.T) {
 }
 
 func TestLocationHostValidationError(t *testing.T) {
-	u, ctrl, _, _, mockacs, _ := mocks(t, nil)
+	u, ctrl, cfg, _, mockacs, _ := mocks(t, nil)
 	defer ctrl.Finish()
+	defer os.RemoveAll(cfg.UpdateDownloadDir)
 
 	mockacs.EXPECT().MakeRequest(&nackRequestMatcher{&ecsacs.NackRequest{
 		Cluster:           ptr("cluster").(*string),
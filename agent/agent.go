@@ -21,21 +21,30 @@ import (
 	"time"
 
 	acshandler "github.com/aws/amazon-ecs-agent/agent/acs/handler"
+	"github.com/aws/amazon-ecs-agent/agent/acs/update_handler"
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	"github.com/aws/amazon-ecs-agent/agent/auth"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/ec2"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/eventhandler"
+	"github.com/aws/amazon-ecs-agent/agent/eventstream"
 	"github.com/aws/amazon-ecs-agent/agent/handlers"
+	"github.com/aws/amazon-ecs-agent/agent/health"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/scheduledevents"
+	"github.com/aws/amazon-ecs-agent/agent/sdnotify"
 	"github.com/aws/amazon-ecs-agent/agent/sighandlers"
 	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/stats"
+	tcshandler "github.com/aws/amazon-ecs-agent/agent/tcs/handler"
+	"github.com/aws/amazon-ecs-agent/agent/tlsconfig"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	utilatomic "github.com/aws/amazon-ecs-agent/agent/utils/atomic"
 	"github.com/aws/amazon-ecs-agent/agent/version"
 	log "github.com/cihub/seelog"
+	"golang.org/x/net/context"
 )
 
 func init() {
@@ -43,6 +52,11 @@ func init() {
 	mathrand.Seed(time.Now().UnixNano())
 }
 
+// replayMain is set in agents built with the benchmark tag. It replays
+// recorded payloads instead of running the agent if asked to, returning the
+// exit code and whether it did.
+var replayMain func() (int, bool)
+
 func main() {
 	// Use a returning main instead of os.Exiting main to allow defers to run
 	// before exit
@@ -57,6 +71,12 @@ func _main() int {
 
 	logger.SetLevel(*logLevel)
 
+	if replayMain != nil {
+		if exitCode, replayed := replayMain(); replayed {
+			return exitCode
+		}
+	}
+
 	log.Infof("Starting Agent: %v", version.String())
 
 	log.Info("Loading configuration")
@@ -76,8 +96,18 @@ func _main() int {
 	}
 	log.Debug("Loaded config: " + cfg.String())
 
+	if err := tlsconfig.Configure(cfg); err != nil {
+		log.Criticalf("Invalid TLS configuration: %v", err)
+		return exitcodes.ExitTerminal
+	}
+	updater.CheckRollback(cfg)
+
 	var currentEc2InstanceID, containerInstanceArn string
 	var taskEngine engine.TaskEngine
+	// staleCluster and staleContainerInstanceArn record the container
+	// instance in the saved state if it was registered in a different
+	// cluster than the one configured
+	var staleCluster, staleContainerInstanceArn string
 
 	if cfg.Checkpoint {
 		log.Info("Checkpointing is enabled. Attempting to load state")
@@ -85,14 +115,24 @@ func _main() int {
 		previousTaskEngine := engine.NewTaskEngine(cfg)
 		// previousState is used to verify that our current runtime configuration is
 		// compatible with our past configuration as reflected by our state-file
-		previousState, err := initializeStateManager(cfg, previousTaskEngine, &previousCluster, &previousContainerInstanceArn, &previousEc2InstanceID, acshandler.SequenceNumber)
+		previousState, err := initializeStateManager(cfg, previousTaskEngine, &previousCluster, &previousContainerInstanceArn, &previousEc2InstanceID, acshandler.SequenceNumber, acshandler.HandledMessages)
 		if err != nil {
 			log.Criticalf("Error creating state manager: %v", err)
 			return exitcodes.ExitTerminal
 		}
 
 		err = previousState.Load()
-		if err != nil {
+		if _, corrupt := err.(statemanager.CorruptStateError); corrupt && cfg.RecoverCorruptState {
+			log.Errorf("Saved state is corrupt; starting over as a new container instance: %v", err)
+			if err := recoverFromCorruptState(cfg); err != nil {
+				log.Criticalf("Unable to recover from corrupt state: %v", err)
+				return exitcodes.ExitError
+			}
+			// Loading may have filled in some of the state before failing
+			previousCluster, previousEc2InstanceID, previousContainerInstanceArn = "", "", ""
+			previousTaskEngine = engine.NewTaskEngine(cfg)
+			acshandler.ResetSessionState()
+		} else if err != nil {
 			log.Criticalf("Error loading previously saved state: %v", err)
 			return exitcodes.ExitTerminal
 		}
@@ -105,11 +145,12 @@ func _main() int {
 				configuredCluster = config.DEFAULT_CLUSTER_NAME
 			}
 			if previousCluster != configuredCluster {
-				log.Criticalf("Data mismatch; saved cluster '%v' does not match configured cluster '%v'. Perhaps you want to delete the configured checkpoint file?", previousCluster, configuredCluster)
-				return exitcodes.ExitTerminal
+				log.Warnf("Data mismatch; saved cluster '%v' does not match configured cluster '%v'. Discarding saved state and registering a new container instance", previousCluster, configuredCluster)
+				staleCluster, staleContainerInstanceArn = previousCluster, previousContainerInstanceArn
+			} else {
+				cfg.Cluster = previousCluster
+				log.Infof("Restored cluster '%v'", cfg.Cluster)
 			}
-			cfg.Cluster = previousCluster
-			log.Infof("Restored cluster '%v'", cfg.Cluster)
 		}
 
 		if instanceIdentityDoc, err := ec2.GetInstanceIdentityDocument(); err == nil {
@@ -118,7 +159,19 @@ func _main() int {
 			log.Criticalf("Unable to access EC2 Metadata service to determine EC2 ID: %v", err)
 		}
 
-		if previousEc2InstanceID != "" && previousEc2InstanceID != currentEc2InstanceID {
+		if staleCluster != "" {
+			// Tasks, the sequence number and handled messages all belong to
+			// the old container instance. Its tasks' containers would be left
+			// running with nothing managing them, so they are removed first;
+			// the saved state is kept until they are, so that a failure is
+			// retried on the next start.
+			if err := removeLostContainers(cfg); err != nil {
+				log.Criticalf("Unable to remove the containers of the previous cluster's tasks: %v", err)
+				return exitcodes.ExitError
+			}
+			taskEngine = engine.NewTaskEngine(cfg)
+			acshandler.ResetSessionState()
+		} else if previousEc2InstanceID != "" && previousEc2InstanceID != currentEc2InstanceID {
 			log.Warnf("Data mismatch; saved InstanceID '%v' does not match current InstanceID '%v'. Overwriting old datafile", previousEc2InstanceID, currentEc2InstanceID)
 
 			// Reset taskEngine; all the other values are still default
@@ -133,7 +186,7 @@ func _main() int {
 		taskEngine = engine.NewTaskEngine(cfg)
 	}
 
-	stateManager, err := initializeStateManager(cfg, taskEngine, &cfg.Cluster, &containerInstanceArn, &currentEc2InstanceID, acshandler.SequenceNumber)
+	stateManager, err := initializeStateManager(cfg, taskEngine, &cfg.Cluster, &containerInstanceArn, &currentEc2InstanceID, acshandler.SequenceNumber, acshandler.HandledMessages)
 	if err != nil {
 		log.Criticalf("Error creating state manager: %v", err)
 		return exitcodes.ExitTerminal
@@ -151,6 +204,17 @@ func _main() int {
 	}
 	client := api.NewECSClient(awsCreds, cfg, *acceptInsecureCert)
 
+	if staleContainerInstanceArn != "" {
+		if cfg.DeregisterOnClusterChange {
+			log.Infof("Deregistering '%v' from previous cluster '%v'", staleContainerInstanceArn, staleCluster)
+			if err := client.DeregisterContainerInstance(staleCluster, staleContainerInstanceArn); err != nil {
+				log.Warnf("Unable to deregister from previous cluster '%v'; continuing: %v", staleCluster, err)
+			}
+		} else {
+			log.Warnf("Leaving '%v' registered in previous cluster '%v'; set ECS_DEREGISTER_ON_CLUSTER_CHANGE=true to deregister it", staleContainerInstanceArn, staleCluster)
+		}
+	}
+
 	if containerInstanceArn == "" {
 		log.Info("Registering Instance with ECS")
 		containerInstanceArn, err = client.RegisterContainerInstance()
@@ -168,18 +232,66 @@ func _main() int {
 		log.Infof("Restored from checkpoint file. I am running as '%v' in cluster '%v'", containerInstanceArn, cfg.Cluster)
 	}
 
+	// Tell containers where they are running
+	if dockerTaskEngine, ok := taskEngine.(*engine.DockerTaskEngine); ok {
+		placement, err := ec2.GetInstancePlacement()
+		if err != nil {
+			log.Warnf("Unable to determine the instance's placement for container environments: %v", err)
+		}
+		dockerTaskEngine.SetContainerInstance(containerInstanceArn, placement)
+	}
+
 	// Begin listening to the docker daemon and saving changes
 	taskEngine.SetSaver(stateManager)
 	taskEngine.MustInit()
 
 	go sighandlers.StartTerminationHandler(stateManager, taskEngine)
+	go sighandlers.StartDeregistrationHandler(client, cfg.Cluster, containerInstanceArn, stateManager, taskEngine)
+	go scheduledevents.StartHandler(ec2.DefaultClient, client, containerInstanceArn, cfg.DrainOnScheduledEvents)
+	// The stats engine is a singleton; telemetry shares this instance
+	go sighandlers.StartDebugHandler(taskEngine, stats.NewDockerStatsEngine(cfg))
 
 	// Agent introspection api
+	handlers.AddHealthChecks(health.Default, taskEngine, cfg)
 	go handlers.ServeHttp(&containerInstanceArn, taskEngine, cfg)
 
+	// Stream events to on-host integrations
+	health.Default.AddListener(eventstream.Default.HealthChanged)
+	if cfg.EventStreamSocket != "" {
+		go func() {
+			if err := eventstream.Default.Serve(cfg.EventStreamSocket); err != nil {
+				log.Errorf("Unable to stream events on '%v': %v", cfg.EventStreamSocket, err)
+			}
+		}()
+	}
+
 	// Start sending events to the backend
 	go eventhandler.HandleEngineEvents(taskEngine, client, stateManager)
 
+	// Publish metrics while telemetry is enabled; if it is configured, it can
+	// be turned off and on again through the local api
+	go tcshandler.StartMetricsSession(tcshandler.TelemetrySessionParams{
+		ContainerInstanceArn: containerInstanceArn,
+		CredentialProvider:   credentialProvider,
+		Cfg:                  cfg,
+		AcceptInvalidCert:    *acceptInsecureCert,
+		EcsClient:            client,
+		TaskEngine:           taskEngine,
+	})
+
+	// Let systemd know we're up, and keep its watchdog fed while the task
+	// engine's event loop advances. The health of ACS, TCS and the disk is
+	// left out, since restarting the agent doesn't fix a backend outage.
+	if _, err := sdnotify.Notify(sdnotify.Ready); err != nil {
+		log.Warnf("Unable to notify systemd of readiness: %v", err)
+	}
+	if dockerTaskEngine, ok := taskEngine.(*engine.DockerTaskEngine); ok {
+		watchdogInterval := sdnotify.WatchdogInterval()
+		go sdnotify.RunWatchdog(context.Background(), watchdogInterval, func() bool {
+			return dockerTaskEngine.Alive(watchdogInterval / 4)
+		})
+	}
+
 	log.Info("Beginning Polling for updates")
 	err = acshandler.StartSession(containerInstanceArn, credentialProvider, cfg, taskEngine, client, stateManager, *acceptInsecureCert)
 	if err != nil {
@@ -190,7 +302,33 @@ func _main() int {
 	return exitcodes.ExitError
 }
 
-func initializeStateManager(cfg *config.Config, taskEngine engine.TaskEngine, cluster, containerInstanceArn, savedInstanceID *string, sequenceNumber *utilatomic.IncreasingInt64) (statemanager.StateManager, error) {
+// recoverFromCorruptState removes the containers of the tasks the agent lost
+// track of with its state, then moves the corrupt state file aside so that the
+// agent starts over. The state file is only moved once the containers are
+// gone, so that a failure is retried on the next start.
+func recoverFromCorruptState(cfg *config.Config) error {
+	if err := removeLostContainers(cfg); err != nil {
+		return err
+	}
+	backup, err := statemanager.BackupStateFile(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+	log.Warnf("Moved corrupt state file to '%v'", backup)
+	return nil
+}
+
+// removeLostContainers stops and removes the containers of every task the
+// agent created, for when it is starting over without its record of them
+func removeLostContainers(cfg *config.Config) error {
+	client, err := engine.NewDockerGoClient()
+	if err != nil {
+		return err
+	}
+	return engine.RemoveManagedContainers(client, cfg.DockerStopTimeout)
+}
+
+func initializeStateManager(cfg *config.Config, taskEngine engine.TaskEngine, cluster, containerInstanceArn, savedInstanceID *string, sequenceNumber *utilatomic.IncreasingInt64, handledMessages *acshandler.MessageLog) (statemanager.StateManager, error) {
 	if !cfg.Checkpoint {
 		return statemanager.NewNoopStateManager(), nil
 	}
@@ -200,6 +338,7 @@ func initializeStateManager(cfg *config.Config, taskEngine engine.TaskEngine, cl
 		statemanager.AddSaveable("Cluster", cluster),
 		statemanager.AddSaveable("EC2InstanceID", savedInstanceID),
 		statemanager.AddSaveable("ACSSeqNum", sequenceNumber),
+		statemanager.AddSaveable("ACSHandledMessages", handledMessages),
 	)
 	if err != nil {
 		return nil, err
@@ -14,8 +14,11 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
+	"regexp"
 	"runtime"
+	"sort"
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
@@ -31,6 +34,19 @@ import (
 
 var log = logger.ForModule("api client")
 
+// Attributes describing where the container instance runs, for placement
+// constraints such as "attribute:topology.partition == 1"
+const (
+	RegionAttribute           = "topology.region"
+	AvailabilityZoneAttribute = "topology.availability-zone"
+	PlacementGroupAttribute   = "topology.placement-group"
+	PartitionAttribute        = "topology.partition"
+)
+
+// validAttributeName matches the names ECS accepts for container instance
+// attributes
+var validAttributeName = regexp.MustCompile(`^[a-zA-Z0-9_./-]{1,128}$`)
+
 // ECSClient is an interface over the ECSSDK interface which abstracts away some
 // details around constructing the request and reading the response down to the
 // parts the agent cares about.
@@ -41,6 +57,16 @@ type ECSClient interface {
 	// the default cluster if necessary, and returns the registered
 	// ContainerInstanceARN if successful.
 	RegisterContainerInstance() (string, error)
+	// DeregisterContainerInstance deregisters the given container instance
+	// from the given cluster, stopping any tasks ECS has placed on it
+	DeregisterContainerInstance(cluster, containerInstanceArn string) error
+	// DrainContainerInstance sets the given container instance in the
+	// configured cluster to DRAINING, so that no new tasks are placed on it
+	// and the tasks of services are replaced elsewhere
+	DrainContainerInstance(containerInstanceArn string) error
+	// ActivateContainerInstance sets the given container instance in the
+	// configured cluster back to ACTIVE after it was drained
+	ActivateContainerInstance(containerInstanceArn string) error
 	// SubmitTaskStateChange sends a state change and returns an error
 	// indicating if it was submitted
 	SubmitTaskStateChange(change TaskStateChange) utils.RetriableError
@@ -53,6 +79,10 @@ type ECSClient interface {
 	// DiscoverTelemetryEndpoint takes a ContainerInstanceARN and returns the
 	// endpoint at which this Agent should contact Telemetry Service
 	DiscoverTelemetryEndpoint(containerInstanceArn string) (string, error)
+	// InvalidatePollEndpoints makes the next DiscoverPollEndpoint or
+	// DiscoverTelemetryEndpoint call discover the endpoints again, after a
+	// connection to one of them failed
+	InvalidatePollEndpoints(containerInstanceArn string)
 }
 
 // ECSSDK is an interface that specifies the subset of the AWS Go SDK's ECS
@@ -61,9 +91,11 @@ type ECSClient interface {
 type ECSSDK interface {
 	CreateCluster(*ecs.CreateClusterInput) (*ecs.CreateClusterOutput, error)
 	RegisterContainerInstance(*ecs.RegisterContainerInstanceInput) (*ecs.RegisterContainerInstanceOutput, error)
+	DeregisterContainerInstance(*ecs.DeregisterContainerInstanceInput) (*ecs.DeregisterContainerInstanceOutput, error)
 	SubmitContainerStateChange(*ecs.SubmitContainerStateChangeInput) (*ecs.SubmitContainerStateChangeOutput, error)
 	SubmitTaskStateChange(*ecs.SubmitTaskStateChangeInput) (*ecs.SubmitTaskStateChangeOutput, error)
 	DiscoverPollEndpoint(*ecs.DiscoverPollEndpointInput) (*ecs.DiscoverPollEndpointOutput, error)
+	UpdateContainerInstancesState(*ecs.UpdateContainerInstancesStateInput) (*ecs.UpdateContainerInstancesStateOutput, error)
 }
 
 // ApiECSClient implements ECSClient
@@ -73,6 +105,10 @@ type ApiECSClient struct {
 	insecureSkipVerify bool
 	c                  ECSSDK
 	ec2metadata        ec2.EC2MetadataClient
+
+	// endpoints caches DiscoverPollEndpoint responses, which are shared by
+	// ACS and TCS discovery
+	endpoints endpointCache
 }
 
 // SetSDK overrides the SDK to the given one. This is useful for injecting a
@@ -129,6 +165,13 @@ func getCpuAndMemory() (int64, int64) {
 	return int64(cpu), mem
 }
 
+// RegisteredCPUAndMemory returns the cpu units and MiB of memory the instance
+// registers with, which the scheduler places tasks against
+func RegisteredCPUAndMemory(cfg *config.Config) (int64, int64) {
+	cpu, mem := getCpuAndMemory()
+	return cpu, mem - int64(cfg.ReservedMemory)
+}
+
 // CreateCluster creates a cluster from a given name and returns its arn
 func (client *ApiECSClient) CreateCluster(clusterName string) (string, error) {
 	resp, err := client.c.CreateCluster(&ecs.CreateClusterInput{ClusterName: &clusterName})
@@ -189,11 +232,15 @@ func (client *ApiECSClient) registerContainerInstance(clusterRef string) (string
 	strIidSig := string(instanceIdentitySignature)
 	registerRequest.InstanceIdentityDocumentSignature = &strIidSig
 
+	var iid ec2.InstanceIdentityDocument
+	if iidRetrieved && json.Unmarshal(instanceIdentityDoc, &iid) == nil {
+		registerRequest.Attributes = placementAttributes(ec2.ReadInstancePlacement(client.ec2metadata, &iid))
+	}
+
 	// Micro-optimization, the pointer to this is used multiple times below
 	integerStr := "INTEGER"
 
-	cpu, mem := getCpuAndMemory()
-	mem = mem - int64(client.config.ReservedMemory)
+	cpu, mem := RegisteredCPUAndMemory(client.config)
 
 	cpuResource := ecs.Resource{
 		Name:         utils.Strptr("CPU"),
@@ -219,6 +266,12 @@ func (client *ApiECSClient) registerContainerInstance(clusterRef string) (string
 	resources := []*ecs.Resource{&cpuResource, &memResource, &portResource, &udpPortResource}
 	registerRequest.TotalResources = resources
 
+	if client.config.PropagateInstanceTags && len(client.config.InstanceTags) > 0 {
+		var tagAttributes []*ecs.Attribute
+		registerRequest.Tags, tagAttributes = tagsAndAttributes(client.config.InstanceTags)
+		registerRequest.Attributes = append(registerRequest.Attributes, tagAttributes...)
+	}
+
 	resp, err := client.c.RegisterContainerInstance(&registerRequest)
 	if err != nil {
 		log.Error("Could not register", "err", err)
@@ -228,6 +281,90 @@ func (client *ApiECSClient) registerContainerInstance(clusterRef string) (string
 	return *resp.ContainerInstance.ContainerInstanceARN, nil
 }
 
+// placementAttributes converts the instance's placement to container instance
+// attributes, leaving out what it doesn't know
+func placementAttributes(placement *ec2.InstancePlacement) []*ecs.Attribute {
+	attributes := []*ecs.Attribute{}
+	for _, attribute := range []struct{ name, value string }{
+		{RegionAttribute, placement.Region},
+		{AvailabilityZoneAttribute, placement.AvailabilityZone},
+		{PlacementGroupAttribute, placement.PlacementGroup},
+		{PartitionAttribute, placement.PartitionNumber},
+	} {
+		if attribute.value != "" {
+			attributes = append(attributes, &ecs.Attribute{Name: utils.Strptr(attribute.name), Value: utils.Strptr(attribute.value)})
+		}
+	}
+	return attributes
+}
+
+// tagsAndAttributes converts instance tags, which were validated when the
+// config was loaded, to container instance tags and attributes, sorted by
+// key. Tags whose keys are not valid attribute names are copied only as tags.
+func tagsAndAttributes(tags map[string]string) ([]*ecs.Tag, []*ecs.Attribute) {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ecsTags := make([]*ecs.Tag, 0, len(keys))
+	attributes := make([]*ecs.Attribute, 0, len(keys))
+	for _, key := range keys {
+		key, value := key, tags[key]
+		ecsTags = append(ecsTags, &ecs.Tag{Key: &key, Value: &value})
+		if !validAttributeName.MatchString(key) {
+			log.Warn("Instance tag is not a valid attribute name; applying it only as a tag", "key", key)
+			continue
+		}
+		attributes = append(attributes, &ecs.Attribute{Name: &key, Value: &value})
+	}
+	return ecsTags, attributes
+}
+
+func (client *ApiECSClient) DeregisterContainerInstance(cluster, containerInstanceArn string) error {
+	_, err := client.c.DeregisterContainerInstance(&ecs.DeregisterContainerInstanceInput{
+		Cluster:           &cluster,
+		ContainerInstance: &containerInstanceArn,
+		Force:             aws.Boolean(true),
+	})
+	if err != nil {
+		log.Error("Could not deregister", "cluster", cluster, "containerInstance", containerInstanceArn, "err", err)
+		return NewAPIError(err)
+	}
+	log.Info("Deregistered", "cluster", cluster, "containerInstance", containerInstanceArn)
+	return nil
+}
+
+func (client *ApiECSClient) DrainContainerInstance(containerInstanceArn string) error {
+	return client.updateContainerInstanceState(containerInstanceArn, "DRAINING")
+}
+
+func (client *ApiECSClient) ActivateContainerInstance(containerInstanceArn string) error {
+	return client.updateContainerInstanceState(containerInstanceArn, "ACTIVE")
+}
+
+func (client *ApiECSClient) updateContainerInstanceState(containerInstanceArn, status string) error {
+	resp, err := client.c.UpdateContainerInstancesState(&ecs.UpdateContainerInstancesStateInput{
+		Cluster:            &client.config.Cluster,
+		ContainerInstances: []*string{&containerInstanceArn},
+		Status:             &status,
+	})
+	if err == nil && len(resp.Failures) > 0 {
+		reason := "unknown failure"
+		if resp.Failures[0].Reason != nil {
+			reason = *resp.Failures[0].Reason
+		}
+		err = errors.New("Unable to set " + containerInstanceArn + " to " + status + ": " + reason)
+	}
+	if err != nil {
+		log.Error("Could not update container instance state", "containerInstance", containerInstanceArn, "status", status, "err", err)
+		return NewAPIError(err)
+	}
+	log.Info("Updated container instance state", "containerInstance", containerInstanceArn, "status", status)
+	return nil
+}
+
 func (client *ApiECSClient) SubmitTaskStateChange(change TaskStateChange) utils.RetriableError {
 	if change.Status == TaskStatusNone {
 		log.Warn("SubmitTaskStateChange called with an invalid change", "change", change)
@@ -305,24 +442,18 @@ func (client *ApiECSClient) SubmitContainerStateChange(change ContainerStateChan
 }
 
 func (client *ApiECSClient) DiscoverPollEndpoint(containerInstanceArn string) (string, error) {
-	resp, err := client.c.DiscoverPollEndpoint(&ecs.DiscoverPollEndpointInput{
-		ContainerInstance: &containerInstanceArn,
-		Cluster:           &client.config.Cluster,
-	})
+	resp, err := client.discoverPollEndpoint(containerInstanceArn)
 	if err != nil {
-		return "", NewAPIError(err)
+		return "", err
 	}
 
 	return *resp.Endpoint, nil
 }
 
 func (client *ApiECSClient) DiscoverTelemetryEndpoint(containerInstanceArn string) (string, error) {
-	resp, err := client.c.DiscoverPollEndpoint(&ecs.DiscoverPollEndpointInput{
-		ContainerInstance: &containerInstanceArn,
-		Cluster:           &client.config.Cluster,
-	})
+	resp, err := client.discoverPollEndpoint(containerInstanceArn)
 	if err != nil {
-		return "", NewAPIError(err)
+		return "", err
 	}
 	if resp.TelemetryEndpoint == nil {
 		return "", &APIError{err: errors.New("No telemetry endpoint returned; nil"), Retriable: false}
@@ -330,3 +461,22 @@ func (client *ApiECSClient) DiscoverTelemetryEndpoint(containerInstanceArn strin
 
 	return *resp.TelemetryEndpoint, nil
 }
+
+func (client *ApiECSClient) InvalidatePollEndpoints(containerInstanceArn string) {
+	client.endpoints.invalidate(client.config.Cluster, containerInstanceArn)
+}
+
+// discoverPollEndpoint returns the endpoints for the container instance,
+// which are cached as described by endpointCache
+func (client *ApiECSClient) discoverPollEndpoint(containerInstanceArn string) (*ecs.DiscoverPollEndpointOutput, error) {
+	return client.endpoints.get(client.config.Cluster, containerInstanceArn, func() (*ecs.DiscoverPollEndpointOutput, error) {
+		resp, err := client.c.DiscoverPollEndpoint(&ecs.DiscoverPollEndpointInput{
+			ContainerInstance: &containerInstanceArn,
+			Cluster:           &client.config.Cluster,
+		})
+		if err != nil {
+			return nil, NewAPIError(err)
+		}
+		return resp, nil
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/ec2"
 	"github.com/aws/amazon-ecs-agent/agent/ec2/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
 	"github.com/awslabs/aws-sdk-go/aws"
 )
 
@@ -250,6 +252,61 @@ func TestRegisterContainerInstance(t *testing.T) {
 	}
 }
 
+func TestRegisterContainerInstanceWithInstanceTags(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := api.NewECSClient(aws.DetectCreds("", "", ""), &config.Config{Cluster: configuredCluster, AWSRegion: "us-east-1", PropagateInstanceTags: true, InstanceTags: map[string]string{"team": "payments", "Cost Center": "42"}}, false)
+	mc := mock_api.NewMockECSSDK(mockCtrl)
+	client.(*api.ApiECSClient).SetSDK(mc)
+	mockEC2Metadata := mock_ec2.NewMockEC2MetadataClient(mockCtrl)
+	client.(*api.ApiECSClient).SetEC2MetadataClient(mockEC2Metadata)
+
+	mockEC2Metadata.EXPECT().ReadResource(ec2.INSTANCE_IDENTITY_DOCUMENT_RESOURCE).Return([]byte("instanceIdentityDocument"), nil)
+	mockEC2Metadata.EXPECT().ReadResource(ec2.INSTANCE_IDENTITY_DOCUMENT_SIGNATURE_RESOURCE).Return([]byte("signature"), nil)
+	mc.EXPECT().RegisterContainerInstance(gomock.Any()).Do(func(req *ecs.RegisterContainerInstanceInput) {
+		if len(req.Tags) != 2 || *req.Tags[0].Key != "Cost Center" || *req.Tags[1].Key != "team" || *req.Tags[1].Value != "payments" {
+			t.Errorf("Wrong tags: %v", req.Tags)
+		}
+		if len(req.Attributes) != 1 || *req.Attributes[0].Name != "team" || *req.Attributes[0].Value != "payments" {
+			t.Errorf("Wrong attributes: %v", req.Attributes)
+		}
+	}).Return(&ecs.RegisterContainerInstanceOutput{ContainerInstance: &ecs.ContainerInstance{ContainerInstanceARN: aws.String("registerArn")}}, nil)
+
+	_, err := client.RegisterContainerInstance()
+	if err != nil {
+		t.Errorf("Should not be an error: %v", err)
+	}
+}
+
+func TestRegisterContainerInstanceWithPlacement(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := api.NewECSClient(aws.DetectCreds("", "", ""), &config.Config{Cluster: configuredCluster, AWSRegion: "us-east-1"}, false)
+	mc := mock_api.NewMockECSSDK(mockCtrl)
+	client.(*api.ApiECSClient).SetSDK(mc)
+	mockEC2Metadata := mock_ec2.NewMockEC2MetadataClient(mockCtrl)
+	client.(*api.ApiECSClient).SetEC2MetadataClient(mockEC2Metadata)
+
+	mockEC2Metadata.EXPECT().ReadResource(ec2.INSTANCE_IDENTITY_DOCUMENT_RESOURCE).Return([]byte(`{"region":"us-east-1","availabilityZone":"us-east-1b"}`), nil)
+	mockEC2Metadata.EXPECT().ReadResource(ec2.INSTANCE_IDENTITY_DOCUMENT_SIGNATURE_RESOURCE).Return([]byte("signature"), nil)
+	mockEC2Metadata.EXPECT().ReadResource(ec2.PLACEMENT_GROUP_RESOURCE).Return([]byte("analytics"), nil)
+	mockEC2Metadata.EXPECT().ReadResource(ec2.PLACEMENT_PARTITION_RESOURCE).Return(nil, errors.New("404"))
+	mc.EXPECT().RegisterContainerInstance(gomock.Any()).Do(func(req *ecs.RegisterContainerInstanceInput) {
+		attributes := make(map[string]string)
+		for _, attribute := range req.Attributes {
+			attributes[*attribute.Name] = *attribute.Value
+		}
+		if len(attributes) != 3 || attributes[api.RegionAttribute] != "us-east-1" || attributes[api.AvailabilityZoneAttribute] != "us-east-1b" || attributes[api.PlacementGroupAttribute] != "analytics" {
+			t.Errorf("Wrong attributes: %v", attributes)
+		}
+	}).Return(&ecs.RegisterContainerInstanceOutput{ContainerInstance: &ecs.ContainerInstance{ContainerInstanceARN: aws.String("registerArn")}}, nil)
+
+	_, err := client.RegisterContainerInstance()
+	if err != nil {
+		t.Errorf("Should not be an error: %v", err)
+	}
+}
+
 func findResource(resources []*ecs.Resource, name string) (*ecs.Resource, bool) {
 	for _, resource := range resources {
 		if name == *resource.Name {
@@ -299,6 +356,82 @@ func TestRegisterBlankCluster(t *testing.T) {
 	}
 }
 
+func TestDeregisterContainerInstance(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client, mc := NewMockClient(mockCtrl)
+
+	mc.EXPECT().DeregisterContainerInstance(gomock.Any()).Do(func(req *ecs.DeregisterContainerInstanceInput) {
+		if *req.Cluster != "oldCluster" {
+			t.Errorf("Wrong cluster: %v", *req.Cluster)
+		}
+		if *req.ContainerInstance != "oldArn" {
+			t.Errorf("Wrong container instance: %v", *req.ContainerInstance)
+		}
+		if req.Force == nil || !*req.Force {
+			t.Error("Expected a forced deregistration")
+		}
+	}).Return(&ecs.DeregisterContainerInstanceOutput{}, nil)
+
+	err := client.DeregisterContainerInstance("oldCluster", "oldArn")
+	if err != nil {
+		t.Errorf("Should not be an error: %v", err)
+	}
+}
+
+func TestDrainContainerInstance(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client, mc := NewMockClient(mockCtrl)
+
+	mc.EXPECT().UpdateContainerInstancesState(gomock.Any()).Do(func(req *ecs.UpdateContainerInstancesStateInput) {
+		if len(req.ContainerInstances) != 1 || *req.ContainerInstances[0] != "arn" {
+			t.Errorf("Wrong container instances: %v", req.ContainerInstances)
+		}
+		if *req.Status != "DRAINING" {
+			t.Errorf("Wrong status: %v", *req.Status)
+		}
+	}).Return(&ecs.UpdateContainerInstancesStateOutput{}, nil)
+
+	err := client.DrainContainerInstance("arn")
+	if err != nil {
+		t.Errorf("Should not be an error: %v", err)
+	}
+}
+
+func TestDrainContainerInstanceFailure(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client, mc := NewMockClient(mockCtrl)
+
+	reason := "MISSING"
+	mc.EXPECT().UpdateContainerInstancesState(gomock.Any()).Return(&ecs.UpdateContainerInstancesStateOutput{
+		Failures: []*ecs.Failure{&ecs.Failure{Reason: &reason}},
+	}, nil)
+
+	err := client.DrainContainerInstance("arn")
+	if err == nil {
+		t.Error("Expected an error when the container instance could not be drained")
+	}
+}
+
+func TestActivateContainerInstance(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client, mc := NewMockClient(mockCtrl)
+
+	mc.EXPECT().UpdateContainerInstancesState(gomock.Any()).Do(func(req *ecs.UpdateContainerInstancesStateInput) {
+		if *req.Status != "ACTIVE" {
+			t.Errorf("Wrong status: %v", *req.Status)
+		}
+	}).Return(&ecs.UpdateContainerInstancesStateOutput{}, nil)
+
+	err := client.ActivateContainerInstance("arn")
+	if err != nil {
+		t.Errorf("Should not be an error: %v", err)
+	}
+}
+
 func TestDiscoverTelemetryEndpoint(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -313,12 +446,106 @@ func TestDiscoverTelemetryEndpoint(t *testing.T) {
 		t.Errorf("Expected telemetry endpoint(%s) != endpoint(%s)", expectedEndpoint, endpoint)
 	}
 	mc.EXPECT().DiscoverPollEndpoint(gomock.Any()).Return(nil, fmt.Errorf("Error getting endpoint"))
-	endpoint, err = client.DiscoverTelemetryEndpoint("containerInstance")
+	endpoint, err = client.DiscoverTelemetryEndpoint("otherContainerInstance")
 	if err == nil {
 		t.Error("Expected error getting telemetry endpoint, didn't get any")
 	}
 }
 
+func TestDiscoverEndpointsCached(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	testTime := ttime.NewTestTime()
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+	client, mc := NewMockClient(mockCtrl)
+
+	pollEndpoint := "http://acs.127.0.0.1"
+	telemetryEndpoint := "http://tcs.127.0.0.1"
+	mc.EXPECT().DiscoverPollEndpoint(gomock.Any()).Return(&ecs.DiscoverPollEndpointOutput{Endpoint: &pollEndpoint, TelemetryEndpoint: &telemetryEndpoint}, nil)
+
+	// One call serves both endpoints until the cache expires
+	if endpoint, err := client.DiscoverPollEndpoint("containerInstance"); err != nil || endpoint != pollEndpoint {
+		t.Errorf("Expected poll endpoint %s, got %s (%v)", pollEndpoint, endpoint, err)
+	}
+	if endpoint, err := client.DiscoverTelemetryEndpoint("containerInstance"); err != nil || endpoint != telemetryEndpoint {
+		t.Errorf("Expected telemetry endpoint %s, got %s (%v)", telemetryEndpoint, endpoint, err)
+	}
+
+	// Once expired, the cached endpoints are still served while they are
+	// refreshed, and for as long as refreshing fails
+	testTime.Warp(13 * time.Hour)
+	refreshed := make(chan bool)
+	mc.EXPECT().DiscoverPollEndpoint(gomock.Any()).Do(func(interface{}) { refreshed <- true }).Return(nil, errors.New("ECS is unavailable"))
+	if endpoint, err := client.DiscoverTelemetryEndpoint("containerInstance"); err != nil || endpoint != telemetryEndpoint {
+		t.Errorf("Expected stale telemetry endpoint %s, got %s (%v)", telemetryEndpoint, endpoint, err)
+	}
+	<-refreshed
+
+	newTelemetryEndpoint := "http://tcs2.127.0.0.1"
+	mc.EXPECT().DiscoverPollEndpoint(gomock.Any()).Return(&ecs.DiscoverPollEndpointOutput{Endpoint: &pollEndpoint, TelemetryEndpoint: &newTelemetryEndpoint}, nil)
+	if endpoint, err := client.DiscoverTelemetryEndpoint("containerInstance"); err != nil || endpoint != telemetryEndpoint {
+		t.Errorf("Expected stale telemetry endpoint %s after a failed refresh, got %s (%v)", telemetryEndpoint, endpoint, err)
+	}
+	waitForTelemetryEndpoint(t, client, newTelemetryEndpoint)
+
+	// Endpoints are cached per container instance
+	otherEndpoint := "http://tcs3.127.0.0.1"
+	mc.EXPECT().DiscoverPollEndpoint(gomock.Any()).Return(&ecs.DiscoverPollEndpointOutput{TelemetryEndpoint: &otherEndpoint}, nil)
+	if endpoint, err := client.DiscoverTelemetryEndpoint("otherContainerInstance"); err != nil || endpoint != otherEndpoint {
+		t.Errorf("Expected telemetry endpoint %s, got %s (%v)", otherEndpoint, endpoint, err)
+	}
+}
+
+func TestDiscoverEndpointsInvalidated(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client, mc := NewMockClient(mockCtrl)
+
+	telemetryEndpoint := "http://tcs.127.0.0.1"
+	newTelemetryEndpoint := "http://tcs2.127.0.0.1"
+	gomock.InOrder(
+		mc.EXPECT().DiscoverPollEndpoint(gomock.Any()).Return(&ecs.DiscoverPollEndpointOutput{TelemetryEndpoint: &telemetryEndpoint}, nil),
+		mc.EXPECT().DiscoverPollEndpoint(gomock.Any()).Return(nil, errors.New("ECS is unavailable")),
+		mc.EXPECT().DiscoverPollEndpoint(gomock.Any()).Return(&ecs.DiscoverPollEndpointOutput{TelemetryEndpoint: &newTelemetryEndpoint}, nil),
+	)
+
+	if endpoint, err := client.DiscoverTelemetryEndpoint("containerInstance"); err != nil || endpoint != telemetryEndpoint {
+		t.Errorf("Expected telemetry endpoint %s, got %s (%v)", telemetryEndpoint, endpoint, err)
+	}
+	// Invalidating endpoints which were never discovered does nothing
+	client.InvalidatePollEndpoints("otherContainerInstance")
+	client.InvalidatePollEndpoints("containerInstance")
+	// If rediscovering fails, the invalidated endpoints are still served, and
+	// rediscovered again next time
+	if endpoint, err := client.DiscoverTelemetryEndpoint("containerInstance"); err != nil || endpoint != telemetryEndpoint {
+		t.Errorf("Expected invalidated telemetry endpoint %s, got %s (%v)", telemetryEndpoint, endpoint, err)
+	}
+	for i := 0; i < 2; i++ {
+		if endpoint, err := client.DiscoverTelemetryEndpoint("containerInstance"); err != nil || endpoint != newTelemetryEndpoint {
+			t.Errorf("Expected rediscovered telemetry endpoint %s, got %s (%v)", newTelemetryEndpoint, endpoint, err)
+		}
+	}
+}
+
+// waitForTelemetryEndpoint waits for a background refresh to make the client
+// discover expected
+func waitForTelemetryEndpoint(t *testing.T, client api.ECSClient, expected string) {
+	var endpoint string
+	for i := 0; i < 100; i++ {
+		var err error
+		endpoint, err = client.DiscoverTelemetryEndpoint("containerInstance")
+		if err != nil {
+			t.Fatal("Expected cached endpoints to be served", err)
+		}
+		if endpoint == expected {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Expected telemetry endpoint %s, got %s", expected, endpoint)
+}
+
 func TestDiscoverNilTelemetryEndpoint(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -0,0 +1,87 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A container's command and entryPoint are lists. Like a docker health
+// check, the first element may name the form of the rest:
+//
+//	["CMD", "echo", "a b"]         exec form; run as given
+//	["CMD-SHELL", "echo $HOME"]    shell form; the one string is run by
+//	                               /bin/sh -c, so it follows shell quoting
+//	["echo", "a b"]                without a marker, exec form as before
+//
+// The agent never splits or unquotes a string itself.
+const (
+	execFormMarker  = "CMD"
+	shellFormMarker = "CMD-SHELL"
+)
+
+// shellFormPrefix runs a shell form string
+var shellFormPrefix = []string{"/bin/sh", "-c"}
+
+// dockerCommand resolves the form of a container's command or entryPoint,
+// named by field, into the arguments docker runs. It returns whether the
+// value was in shell form, or an error if the form is ambiguous.
+func dockerCommand(field string, value []string) ([]string, bool, error) {
+	if len(value) == 0 {
+		return value, false, nil
+	}
+	switch value[0] {
+	case execFormMarker:
+		if len(value) == 1 {
+			return nil, false, commandFormError(field + " in exec form (" + execFormMarker + ") must name an executable")
+		}
+		return value[1:], false, nil
+	case shellFormMarker:
+		if len(value) != 2 {
+			return nil, false, commandFormError(field + " in shell form (" + shellFormMarker + ") must be followed by exactly one string, not " + strconv.Itoa(len(value)-1))
+		}
+		if strings.TrimSpace(value[1]) == "" {
+			return nil, false, commandFormError(field + " in shell form (" + shellFormMarker + ") must not be empty")
+		}
+		return append(append([]string{}, shellFormPrefix...), value[1]), true, nil
+	}
+	return value, false, nil
+}
+
+// dockerCommands resolves a container's entryPoint and command
+func dockerCommands(container *Container) (entryPoint []string, command []string, err error) {
+	entryPoint = []string{}
+	if container.EntryPoint != nil {
+		entryPoint = *container.EntryPoint
+	}
+	entryPoint, entryPointShell, err := dockerCommand("entryPoint", entryPoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	command, _, err = dockerCommand("command", container.Command)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A shell form entryPoint is all the shell runs; a command would only
+	// become the shell's positional parameters
+	if entryPointShell && len(command) > 0 {
+		return nil, nil, commandFormError("entryPoint in shell form (" + shellFormMarker + ") ignores command; use exec form for one of them")
+	}
+	return entryPoint, command, nil
+}
+
+func commandFormError(msg string) *DockerClientConfigError {
+	return &DockerClientConfigError{"Invalid container " + msg}
+}
@@ -13,8 +13,34 @@
 
 package api
 
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
 const DOCKER_MINIMUM_MEMORY = 4 * 1024 * 1024 // 4MB
 
+// Phases of launching a container which are timed in LaunchPhases
+const (
+	LaunchPhasePullWait = "pullWait"
+	LaunchPhasePull     = "pull"
+	LaunchPhaseVolumes  = "volumes"
+	LaunchPhaseCreate   = "create"
+	LaunchPhaseStart    = "start"
+)
+
+// Labels the agent stamps on every container it creates. All of them live
+// under AgentLabelPrefix, which task definitions may not use.
+const (
+	AgentLabelPrefix           = "com.amazonaws.ecs."
+	ClusterLabel               = AgentLabelPrefix + "cluster"
+	TaskArnLabel               = AgentLabelPrefix + "task-arn"
+	ContainerNameLabel         = AgentLabelPrefix + "container-name"
+	TaskDefinitionFamilyLabel  = AgentLabelPrefix + "task-definition-family"
+	TaskDefinitionVersionLabel = AgentLabelPrefix + "task-definition-version"
+)
+
 // Overriden returns
 func (c *Container) Overridden() *Container {
 	result := *c
@@ -27,10 +53,139 @@ func (c *Container) Overridden() *Container {
 	return &result
 }
 
+// maxHostnameLength is the longest hostname linux accepts
+const maxHostnameLength = 64
+
+// validateUser checks that user is a name or uid, optionally followed by a
+// group name or gid
+func validateUser(user string) error {
+	if user == "" {
+		return nil
+	}
+	if strings.IndexFunc(user, isSpace) != -1 {
+		return errors.New("user '" + user + "' must not contain whitespace")
+	}
+	parts := strings.Split(user, ":")
+	if len(parts) > 2 || parts[0] == "" || (len(parts) == 2 && parts[1] == "") {
+		return errors.New("user '" + user + "' must be a user, or a user and group separated by ':'")
+	}
+	return nil
+}
+
+// validateHostname checks that hostname is a valid dns name short enough
+// for linux
+func validateHostname(hostname string) error {
+	if hostname == "" {
+		return nil
+	}
+	if len(hostname) > maxHostnameLength {
+		return errors.New("hostname '" + hostname + "' is longer than 64 characters")
+	}
+	for _, label := range strings.Split(hostname, ".") {
+		if label == "" || label[0] == '-' || label[len(label)-1] == '-' {
+			return errors.New("hostname '" + hostname + "' has an empty label or one starting or ending with '-'")
+		}
+		for _, c := range label {
+			if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-') {
+				return errors.New("hostname '" + hostname + "' may only contain letters, digits, '-' and '.'")
+			}
+		}
+	}
+	return nil
+}
+
+func isSpace(c rune) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
 func (c *Container) KnownTerminal() bool {
-	return c.KnownStatus.Terminal()
+	knownStatus := c.GetKnownStatus()
+	return knownStatus.Terminal()
 }
 
 func (c *Container) DesiredTerminal() bool {
-	return c.DesiredStatus.Terminal()
+	desiredStatus := c.GetDesiredStatus()
+	return desiredStatus.Terminal()
+}
+
+// GetKnownStatus returns the container's known status
+func (c *Container) GetKnownStatus() ContainerStatus {
+	c.StatusLock.Lock()
+	defer c.StatusLock.Unlock()
+	return c.KnownStatus
+}
+
+// SetKnownStatus sets the container's known status
+func (c *Container) SetKnownStatus(status ContainerStatus) {
+	c.StatusLock.Lock()
+	defer c.StatusLock.Unlock()
+	c.KnownStatus = status
+}
+
+// GetDesiredStatus returns the container's desired status
+func (c *Container) GetDesiredStatus() ContainerStatus {
+	c.StatusLock.Lock()
+	defer c.StatusLock.Unlock()
+	return c.DesiredStatus
+}
+
+// SetDesiredStatus sets the container's desired status
+func (c *Container) SetDesiredStatus(status ContainerStatus) {
+	c.StatusLock.Lock()
+	defer c.StatusLock.Unlock()
+	c.DesiredStatus = status
+}
+
+// GetAppliedStatus returns the status the engine is transitioning the
+// container to
+func (c *Container) GetAppliedStatus() ContainerStatus {
+	c.StatusLock.Lock()
+	defer c.StatusLock.Unlock()
+	return c.AppliedStatus
+}
+
+// GetSentStatus returns the last status of the container sent to the backend
+func (c *Container) GetSentStatus() ContainerStatus {
+	c.StatusLock.Lock()
+	defer c.StatusLock.Unlock()
+	return c.SentStatus
+}
+
+// SetSentStatus records the last status of the container sent to the backend
+func (c *Container) SetSentStatus(status ContainerStatus) {
+	c.StatusLock.Lock()
+	defer c.StatusLock.Unlock()
+	c.SentStatus = status
+}
+
+// RecordLaunchPhases records how long the given phases of launching the
+// container took, replacing any earlier timing of the same phase. The map is
+// replaced rather than modified, so that the state can be saved while the
+// container launches.
+func (c *Container) RecordLaunchPhases(durations map[string]time.Duration) {
+	if len(durations) == 0 {
+		return
+	}
+	c.StatusLock.Lock()
+	defer c.StatusLock.Unlock()
+	phases := make(map[string]time.Duration, len(c.LaunchPhases)+len(durations))
+	for phase, duration := range c.LaunchPhases {
+		phases[phase] = duration
+	}
+	for phase, duration := range durations {
+		phases[phase] = duration
+	}
+	c.LaunchPhases = phases
+}
+
+// GetLaunchPhases returns a copy of how long each phase of launching the
+// container took
+func (c *Container) GetLaunchPhases() map[string]time.Duration {
+	c.StatusLock.Lock()
+	defer c.StatusLock.Unlock()
+	phases := make(map[string]time.Duration, len(c.LaunchPhases))
+	for phase, duration := range c.LaunchPhases {
+		phases[phase] = duration
+	}
+	return phases
 }
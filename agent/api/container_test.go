@@ -16,6 +16,7 @@ package api
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/fsouza/go-dockerclient"
@@ -82,3 +83,20 @@ func (pair configPair) Equal() bool {
 
 	return true
 }
+
+func TestRecordLaunchPhases(t *testing.T) {
+	container := &Container{}
+	container.RecordLaunchPhases(map[string]time.Duration{LaunchPhasePull: time.Second})
+	saved := container.LaunchPhases
+	phases := container.GetLaunchPhases()
+	phases[LaunchPhasePull] = time.Minute
+
+	container.RecordLaunchPhases(map[string]time.Duration{LaunchPhaseStart: 2 * time.Second})
+	if !reflect.DeepEqual(saved, map[string]time.Duration{LaunchPhasePull: time.Second}) {
+		t.Error("Expected recording phases to replace the map rather than modify it", saved)
+	}
+	expected := map[string]time.Duration{LaunchPhasePull: time.Second, LaunchPhaseStart: 2 * time.Second}
+	if !reflect.DeepEqual(container.GetLaunchPhases(), expected) {
+		t.Error("Wrong launch phases", container.GetLaunchPhases())
+	}
+}
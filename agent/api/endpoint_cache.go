@@ -0,0 +1,109 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+// pollEndpointCacheTTL is how long a discovered endpoint is used before it is
+// refreshed
+const pollEndpointCacheTTL = 12 * time.Hour
+
+// endpointCache holds DiscoverPollEndpoint responses per cluster and
+// container instance. Once a response expires it is still served while it is
+// refreshed in the background, and for as long as refreshing fails, so that
+// an ECS API outage doesn't keep the agent from reconnecting to ACS and TCS
+// at endpoints it already knows. An entry invalidated after a connection to
+// one of its endpoints failed is discovered again before it is next used,
+// and also still served if that fails.
+type endpointCache struct {
+	lock    sync.Mutex
+	entries map[string]*endpointCacheEntry
+}
+
+type endpointCacheEntry struct {
+	endpoints  *ecs.DiscoverPollEndpointOutput
+	expiry     time.Time
+	refreshing bool
+	invalid    bool
+}
+
+// get returns the endpoints for the cluster and container instance, calling
+// discover if none are cached
+func (cache *endpointCache) get(cluster, containerInstanceArn string, discover func() (*ecs.DiscoverPollEndpointOutput, error)) (*ecs.DiscoverPollEndpointOutput, error) {
+	key := cluster + "/" + containerInstanceArn
+
+	cache.lock.Lock()
+	if cache.entries == nil {
+		cache.entries = make(map[string]*endpointCacheEntry)
+	}
+	entry, ok := cache.entries[key]
+	if ok && !entry.invalid {
+		if !entry.refreshing && !ttime.Now().Before(entry.expiry) {
+			entry.refreshing = true
+			go cache.refresh(key, entry, discover)
+		}
+		endpoints := entry.endpoints
+		cache.lock.Unlock()
+		return endpoints, nil
+	}
+	cache.lock.Unlock()
+
+	endpoints, err := discover()
+	if err != nil {
+		if !ok {
+			return nil, err
+		}
+		// An invalidated entry is still better than none
+		log.Warn("Unable to rediscover poll endpoints; continuing to use previously discovered endpoints", "key", key, "err", err)
+		cache.lock.Lock()
+		defer cache.lock.Unlock()
+		return entry.endpoints, nil
+	}
+	cache.lock.Lock()
+	cache.entries[key] = &endpointCacheEntry{endpoints: endpoints, expiry: ttime.Now().Add(pollEndpointCacheTTL)}
+	cache.lock.Unlock()
+	return endpoints, nil
+}
+
+// invalidate makes the next get for the cluster and container instance
+// discover the endpoints again
+func (cache *endpointCache) invalidate(cluster, containerInstanceArn string) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if entry, ok := cache.entries[cluster+"/"+containerInstanceArn]; ok {
+		entry.invalid = true
+	}
+}
+
+// refresh replaces an expired entry's endpoints. If discovery fails, the
+// expired endpoints continue to be served and the next use retries.
+func (cache *endpointCache) refresh(key string, entry *endpointCacheEntry, discover func() (*ecs.DiscoverPollEndpointOutput, error)) {
+	endpoints, err := discover()
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	entry.refreshing = false
+	if err != nil {
+		log.Warn("Unable to refresh poll endpoints; continuing to use previously discovered endpoints", "key", key, "err", err)
+		return
+	}
+	entry.endpoints = endpoints
+	entry.expiry = ttime.Now().Add(pollEndpointCacheTTL)
+}
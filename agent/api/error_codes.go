@@ -0,0 +1,49 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+// Error codes classify the failures reported in state changes and the
+// introspection api so that callers can act on the kind of failure without
+// parsing its message. Every error the agent reports falls into one of these.
+const (
+	ErrorCodeCannotPullContainer    = "CannotPullContainerError"
+	ErrorCodeCannotCreateContainer  = "CannotCreateContainerError"
+	ErrorCodeCannotStartContainer   = "CannotStartContainerError"
+	ErrorCodeCannotStopContainer    = "CannotStopContainerError"
+	ErrorCodeCannotInspectContainer = "CannotInspectContainerError"
+	ErrorCodeCannotCreateVolume     = "CannotCreateVolumeError"
+	ErrorCodeCannotVerifyImage      = "CannotVerifyImageError"
+	ErrorCodeResourceInitialization = "ResourceInitializationError"
+	ErrorCodeOutOfMemory            = "OutOfMemoryError"
+	ErrorCodeInternal               = "InternalError"
+)
+
+// CodedError is implemented by errors which know their error code
+type CodedError interface {
+	ErrorCode() string
+}
+
+// ErrorCode returns the error code for err. Errors which do not declare a
+// code are internal errors.
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	if coded, ok := err.(CodedError); ok {
+		if code := coded.ErrorCode(); code != "" {
+			return code
+		}
+	}
+	return ErrorCodeInternal
+}
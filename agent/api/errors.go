@@ -16,6 +16,7 @@ package api
 import (
 	"net/http"
 
+	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	"github.com/awslabs/aws-sdk-go/aws"
 )
 
@@ -27,7 +28,11 @@ type APIError struct {
 
 func NewAPIError(err error) *APIError {
 	if apierr, ok := err.(aws.APIError); ok {
-		// ClientExceptions are not retriable
+		// ClientExceptions are not retriable, but throttling is reported
+		// with a 4xx status and should be retried
+		if retry.IsThrottle(apierr) {
+			return &APIError{err, true}
+		}
 		if apierr.Code == "ClientException" || (apierr.StatusCode >= http.StatusBadRequest && apierr.StatusCode < http.StatusInternalServerError) {
 			return &APIError{err, false}
 		}
@@ -44,6 +49,12 @@ func (apiErr *APIError) Error() string {
 	return apiErr.err.Error()
 }
 
+// Throttled returns true if the error was caused by the API throttling the
+// request
+func (apiErr *APIError) Throttled() bool {
+	return retry.IsThrottle(apiErr.err)
+}
+
 type badVolumeError struct {
 	msg string
 }
@@ -51,6 +62,7 @@ type badVolumeError struct {
 func (err *badVolumeError) Error() string     { return err.msg }
 func (err *badVolumeError) ErrorName() string { return "InvalidVolumeError" }
 func (err *badVolumeError) Retry() bool       { return false }
+func (err *badVolumeError) ErrorCode() string { return ErrorCodeCannotCreateVolume }
 
 type NamedError interface {
 	error
@@ -61,6 +73,7 @@ type NamedError interface {
 type DefaultNamedError struct {
 	Err  string `json:"error"`
 	Name string `json:"name"`
+	Code string `json:"code,omitempty"`
 }
 
 // Error implements error
@@ -76,12 +89,26 @@ func (err *DefaultNamedError) ErrorName() string {
 	return err.Name
 }
 
+// ErrorCode implements CodedError
+func (err *DefaultNamedError) ErrorCode() string {
+	return err.Code
+}
+
+// Reason formats the error for a state change reason, prefixed with its
+// error code so that the class of failure can be read from the start of it
+func (err *DefaultNamedError) Reason() string {
+	if err.Code == "" || err.Code == err.Name {
+		return err.Error()
+	}
+	return err.Code + ": " + err.Error()
+}
+
 // NewNamedError creates a NamedError.
 func NewNamedError(err error) *DefaultNamedError {
 	if namedErr, ok := err.(NamedError); ok {
-		return &DefaultNamedError{Err: namedErr.Error(), Name: namedErr.ErrorName()}
+		return &DefaultNamedError{Err: namedErr.Error(), Name: namedErr.ErrorName(), Code: ErrorCode(err)}
 	}
-	return &DefaultNamedError{Err: err.Error()}
+	return &DefaultNamedError{Err: err.Error(), Code: ErrorCode(err)}
 }
 
 type HostConfigError struct {
@@ -90,6 +117,7 @@ type HostConfigError struct {
 
 func (err *HostConfigError) Error() string     { return err.msg }
 func (err *HostConfigError) ErrorName() string { return "HostConfigError" }
+func (err *HostConfigError) ErrorCode() string { return ErrorCodeCannotCreateContainer }
 
 type DockerClientConfigError struct {
 	msg string
@@ -97,3 +125,4 @@ type DockerClientConfigError struct {
 
 func (err *DockerClientConfigError) Error() string     { return err.msg }
 func (err *DockerClientConfigError) ErrorName() string { return "DockerClientConfigError" }
+func (err *DockerClientConfigError) ErrorCode() string { return ErrorCodeCannotCreateContainer }
@@ -23,6 +23,7 @@ import (
 func TestNewAPIError(t *testing.T) {
 	retriable := []error{
 		aws.APIError{Code: "ServerException"},
+		aws.APIError{StatusCode: 400, Code: "ThrottlingException"},
 		errors.New("Error"),
 	}
 	unretriable := []error{
@@ -43,4 +44,32 @@ func TestNewAPIError(t *testing.T) {
 			t.Errorf("Expected error to be unretriable: #%v: %v", i, err)
 		}
 	}
+
+	if !NewAPIError(aws.APIError{StatusCode: 400, Code: "ThrottlingException"}).Throttled() {
+		t.Error("Expected ThrottlingException to be throttled")
+	}
+}
+
+func TestNamedErrorCode(t *testing.T) {
+	volumeErr := NewNamedError(&badVolumeError{"bad volume"})
+	if volumeErr.Code != ErrorCodeCannotCreateVolume {
+		t.Error("Expected CannotCreateVolumeError, got " + volumeErr.Code)
+	}
+	if volumeErr.Reason() != "CannotCreateVolumeError: InvalidVolumeError: bad volume" {
+		t.Error("Unexpected reason: " + volumeErr.Reason())
+	}
+
+	unknownErr := NewNamedError(errors.New("unknown"))
+	if unknownErr.Code != ErrorCodeInternal {
+		t.Error("Expected InternalError, got " + unknownErr.Code)
+	}
+
+	// Errors restored from state saved before codes were recorded have none
+	oldErr := &DefaultNamedError{Err: "msg", Name: "HostConfigError"}
+	if oldErr.Reason() != "HostConfigError: msg" {
+		t.Error("Unexpected reason: " + oldErr.Reason())
+	}
+	if ErrorCode(oldErr) != ErrorCodeInternal {
+		t.Error("Expected uncoded named error to be internal")
+	}
 }
@@ -143,7 +143,8 @@ func (overrides *ContainerOverrides) UnmarshalJSON(b []byte) error {
 // UnmarshalJSON for TaskVolume determines the name and volume type, and
 // unmarshals it into the appropriate HostVolume fulfilling interfaces
 func (tv *TaskVolume) UnmarshalJSON(b []byte) error {
-	// Format: {name: volumeName, host: emptyVolumeOrHostVolume}
+	// Format: {name: volumeName, host: emptyVolumeOrHostVolume} or
+	// {name: volumeName, efsVolumeConfiguration: efsVolume}
 	intermediate := make(map[string]json.RawMessage)
 	if err := json.Unmarshal(b, &intermediate); err != nil {
 		return err
@@ -156,6 +157,18 @@ func (tv *TaskVolume) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
+	if rawefsdata, ok := intermediate["efsVolumeConfiguration"]; ok {
+		efsVolume := &EFSVolume{}
+		if err := json.Unmarshal(rawefsdata, efsVolume); err != nil {
+			return err
+		}
+		if efsVolume.FileSystemID == "" {
+			return errors.New("invalid EFS volume; must include a fileSystemId")
+		}
+		tv.Volume = efsVolume
+		return nil
+	}
+
 	if rawhostdata, ok := intermediate["host"]; ok {
 		// Default to trying to unmarshal it as a FSHostVolume
 		var hostvolume FSHostVolume
@@ -189,6 +202,8 @@ func (tv *TaskVolume) MarshalJSON() ([]byte, error) {
 		result["host"] = v
 	case *EmptyHostVolume:
 		result["host"] = v
+	case *EFSVolume:
+		result["efsVolumeConfiguration"] = v
 	default:
 		log.Crit("Unknown task volume type in marshal")
 	}
@@ -190,6 +190,42 @@ func TestMarshalUnmarshalTaskVolumes(t *testing.T) {
 	}
 }
 
+func TestMarshalUnmarshalEFSVolume(t *testing.T) {
+	task := &Task{
+		Arn: "test",
+		Volumes: []TaskVolume{
+			TaskVolume{Name: "efs", Volume: &EFSVolume{
+				FileSystemID:        "fs-12345678",
+				TransitEncryption:   "ENABLED",
+				AuthorizationConfig: EFSAuthorizationConfig{AccessPointID: "fsap-1234", IAM: "ENABLED"},
+				HostPath:            "/mnt/efs",
+			}},
+		},
+	}
+
+	marshal, err := json.Marshal(task)
+	if err != nil {
+		t.Fatal("Could not marshal: ", err)
+	}
+
+	var out Task
+	err = json.Unmarshal(marshal, &out)
+	if err != nil {
+		t.Fatal("Could not unmarshal: ", err)
+	}
+
+	efs, ok := out.Volumes[0].Volume.(*EFSVolume)
+	if !ok {
+		t.Fatal("Expected an EFS volume")
+	}
+	if !reflect.DeepEqual(efs, task.Volumes[0].Volume) {
+		t.Error("Unmarshaled EFS volume didn't match marshalled volume", efs)
+	}
+	if !efs.TransitEncryptionEnabled() || !efs.IAMEnabled() {
+		t.Error("Expected transit encryption and IAM to be enabled")
+	}
+}
+
 func TestUnmarshalTransportProtocol_Null(t *testing.T) {
 	tp := TransportProtocolTCP
 
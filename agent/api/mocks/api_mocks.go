@@ -55,6 +55,17 @@ func (_mr *_MockECSSDKRecorder) CreateCluster(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "CreateCluster", arg0)
 }
 
+func (_m *MockECSSDK) DeregisterContainerInstance(_param0 *ecs.DeregisterContainerInstanceInput) (*ecs.DeregisterContainerInstanceOutput, error) {
+	ret := _m.ctrl.Call(_m, "DeregisterContainerInstance", _param0)
+	ret0, _ := ret[0].(*ecs.DeregisterContainerInstanceOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockECSSDKRecorder) DeregisterContainerInstance(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeregisterContainerInstance", arg0)
+}
+
 func (_m *MockECSSDK) DiscoverPollEndpoint(_param0 *ecs.DiscoverPollEndpointInput) (*ecs.DiscoverPollEndpointOutput, error) {
 	ret := _m.ctrl.Call(_m, "DiscoverPollEndpoint", _param0)
 	ret0, _ := ret[0].(*ecs.DiscoverPollEndpointOutput)
@@ -99,6 +110,17 @@ func (_mr *_MockECSSDKRecorder) SubmitTaskStateChange(arg0 interface{}) *gomock.
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "SubmitTaskStateChange", arg0)
 }
 
+func (_m *MockECSSDK) UpdateContainerInstancesState(_param0 *ecs.UpdateContainerInstancesStateInput) (*ecs.UpdateContainerInstancesStateOutput, error) {
+	ret := _m.ctrl.Call(_m, "UpdateContainerInstancesState", _param0)
+	ret0, _ := ret[0].(*ecs.UpdateContainerInstancesStateOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockECSSDKRecorder) UpdateContainerInstancesState(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "UpdateContainerInstancesState", arg0)
+}
+
 // Mock of ECSClient interface
 type MockECSClient struct {
 	ctrl     *gomock.Controller
@@ -120,6 +142,16 @@ func (_m *MockECSClient) EXPECT() *_MockECSClientRecorder {
 	return _m.recorder
 }
 
+func (_m *MockECSClient) DeregisterContainerInstance(_param0 string, _param1 string) error {
+	ret := _m.ctrl.Call(_m, "DeregisterContainerInstance", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockECSClientRecorder) DeregisterContainerInstance(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DeregisterContainerInstance", arg0, arg1)
+}
+
 func (_m *MockECSClient) DiscoverPollEndpoint(_param0 string) (string, error) {
 	ret := _m.ctrl.Call(_m, "DiscoverPollEndpoint", _param0)
 	ret0, _ := ret[0].(string)
@@ -142,6 +174,34 @@ func (_mr *_MockECSClientRecorder) DiscoverTelemetryEndpoint(arg0 interface{}) *
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DiscoverTelemetryEndpoint", arg0)
 }
 
+func (_m *MockECSClient) InvalidatePollEndpoints(_param0 string) {
+	_m.ctrl.Call(_m, "InvalidatePollEndpoints", _param0)
+}
+
+func (_mr *_MockECSClientRecorder) InvalidatePollEndpoints(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "InvalidatePollEndpoints", arg0)
+}
+
+func (_m *MockECSClient) ActivateContainerInstance(_param0 string) error {
+	ret := _m.ctrl.Call(_m, "ActivateContainerInstance", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockECSClientRecorder) ActivateContainerInstance(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ActivateContainerInstance", arg0)
+}
+
+func (_m *MockECSClient) DrainContainerInstance(_param0 string) error {
+	ret := _m.ctrl.Call(_m, "DrainContainerInstance", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockECSClientRecorder) DrainContainerInstance(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "DrainContainerInstance", arg0)
+}
+
 func (_m *MockECSClient) RegisterContainerInstance() (string, error) {
 	ret := _m.ctrl.Call(_m, "RegisterContainerInstance")
 	ret0, _ := ret[0].(string)
@@ -0,0 +1,94 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	procMeminfo       = "/proc/meminfo"
+	swapTotalKeyField = "SwapTotal:"
+)
+
+// hostSwapTotal returns the total amount of swap configured on the host in
+// kB. It is a variable so that it may be replaced in tests.
+var hostSwapTotal = readHostSwapTotal
+
+func readHostSwapTotal() (int64, error) {
+	file, err := os.Open(procMeminfo)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != swapTotalKeyField {
+			continue
+		}
+		return strconv.ParseInt(fields[1], 10, 64)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("Could not find " + swapTotalKeyField + " in " + procMeminfo)
+}
+
+// dockerMemorySwap returns the value of docker's MemorySwap (memory plus swap,
+// in bytes) for the given container. A return value of 0 leaves the docker
+// default in place.
+func (task *Task) dockerMemorySwap(container *Container, dockerMem int64) (int64, error) {
+	params := container.LinuxParameters
+	if params == nil {
+		return 0, nil
+	}
+	if params.Swappiness != nil {
+		// The docker remote API version we use has no memory swappiness
+		// setting. Rather than run the container with the kernel default,
+		// which it did not ask for, it is refused.
+		return 0, errors.New("Container " + container.Name + " in task " + task.Arn + " sets swappiness " + strconv.FormatInt(*params.Swappiness, 10) + ", which is not supported by the docker remote API in use")
+	}
+	if params.MaxSwap == nil {
+		return 0, nil
+	}
+
+	maxSwap := *params.MaxSwap
+	if maxSwap < 0 {
+		return 0, errors.New("Container " + container.Name + " in task " + task.Arn + " has invalid maxSwap " + strconv.FormatInt(maxSwap, 10))
+	}
+	if dockerMem == 0 {
+		return 0, errors.New("Container " + container.Name + " in task " + task.Arn + " sets maxSwap without a memory limit")
+	}
+	if maxSwap == 0 {
+		// Equal memory and memory-swap disables swap for the container
+		return dockerMem, nil
+	}
+
+	swapTotal, err := hostSwapTotal()
+	if err != nil {
+		return 0, errors.New("Unable to determine host swap for container " + container.Name + " in task " + task.Arn + ": " + err.Error())
+	}
+	if swapTotal == 0 {
+		return 0, errors.New("Container " + container.Name + " in task " + task.Arn + " requests swap, but swap is disabled on this host")
+	}
+
+	// Convert MB to B
+	return dockerMem + maxSwap*1024*1024, nil
+}
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
 	"github.com/aws/amazon-ecs-agent/agent/engine/emptyvolume"
@@ -28,6 +29,17 @@ import (
 
 const emptyHostVolumeName = "~internal~ecs-emptyvolume-source"
 
+// LocalTaskArnPrefix begins the arns of tasks run through the local task api
+// rather than by the backend
+const LocalTaskArnPrefix = "arn:aws:ecs:local:000000000000:task/"
+
+// IsLocalTaskArn returns whether the task was run through the local task api.
+// The backend knows nothing of such tasks, so their state changes are not
+// sent to it.
+func IsLocalTaskArn(arn string) bool {
+	return strings.HasPrefix(arn, LocalTaskArnPrefix)
+}
+
 // PostUnmarshalTask is run after a task has been unmarshalled, but before it has been
 // run. It is possible it will be subsequently called after that and should be
 // able to handle such an occurrence appropriately (e.g. behave idempotently).
@@ -134,9 +146,11 @@ func (task *Task) UpdateMountPoints(cont *Container, vols map[string]string) {
 // updateContainerDesiredStatus sets all container's desired status's to the
 // task's desired status
 func (task *Task) updateContainerDesiredStatus() {
+	taskDesiredStatus := task.GetDesiredStatus()
+	desiredStatus := taskDesiredStatus.ContainerStatus()
 	for _, c := range task.Containers {
-		if c.DesiredStatus < task.DesiredStatus.ContainerStatus() {
-			c.DesiredStatus = task.DesiredStatus.ContainerStatus()
+		if c.GetDesiredStatus() < desiredStatus {
+			c.SetDesiredStatus(desiredStatus)
 		}
 	}
 }
@@ -152,15 +166,15 @@ func (task *Task) updateTaskKnownStatus() (newStatus TaskStatus) {
 	// Set to a large 'impossible' status that can't be the min
 	earliestStatus := ContainerZombie
 	for _, cont := range task.Containers {
-		if cont.KnownStatus < earliestStatus {
-			earliestStatus = cont.KnownStatus
+		if knownStatus := cont.GetKnownStatus(); knownStatus < earliestStatus {
+			earliestStatus = knownStatus
 		}
 	}
 
 	llog.Debug("Earliest status is " + earliestStatus.String())
-	if task.KnownStatus < earliestStatus.TaskStatus() {
+	if task.GetKnownStatus() < earliestStatus.TaskStatus() {
 		task.SetKnownStatus(earliestStatus.TaskStatus())
-		return task.KnownStatus
+		return earliestStatus.TaskStatus()
 	}
 	return TaskStatusNone
 }
@@ -206,30 +220,62 @@ func (task *Task) dockerConfig(container *Container) (*docker.Config, *DockerCli
 		dockerMem = DOCKER_MINIMUM_MEMORY
 	}
 
-	entryPoint := []string{}
-	if container.EntryPoint != nil {
-		entryPoint = *container.EntryPoint
+	dockerMemSwap, err := task.dockerMemorySwap(container, dockerMem)
+	if err != nil {
+		return nil, &DockerClientConfigError{err.Error()}
+	}
+
+	entryPoint, command, err := dockerCommands(container)
+	if err != nil {
+		return nil, &DockerClientConfigError{err.Error()}
+	}
+	if err := validateUser(container.User); err != nil {
+		return nil, &DockerClientConfigError{"Invalid container " + err.Error()}
+	}
+	if err := validateHostname(container.Hostname); err != nil {
+		return nil, &DockerClientConfigError{"Invalid container " + err.Error()}
 	}
 
 	config := &docker.Config{
 		Image:        container.Image,
-		Cmd:          container.Command,
+		Cmd:          command,
 		Entrypoint:   entryPoint,
+		WorkingDir:   container.WorkingDirectory,
+		User:         container.User,
+		Hostname:     container.Hostname,
+		Tty:          container.PseudoTerminal,
+		OpenStdin:    container.Interactive,
 		ExposedPorts: task.dockerExposedPorts(container),
 		Volumes:      dockerVolumes,
 		Env:          dockerEnv,
 		Memory:       dockerMem,
+		MemorySwap:   dockerMemSwap,
 		CPUShares:    task.dockerCpuShares(container.Cpu),
-		Labels: map[string]string{
-			"com.amazonaws.ecs.task-arn":                task.Arn,
-			"com.amazonaws.ecs.container-name":          container.Name,
-			"com.amazonaws.ecs.task-definition-family":  task.Family,
-			"com.amazonaws.ecs.task-definition-version": task.Version,
-		},
+		Labels:       task.dockerLabels(container),
 	}
 	return config, nil
 }
 
+// dockerLabels merges the container's dockerLabels with the labels the agent
+// manages. Labels under AgentLabelPrefix are reserved for the agent so that
+// on-host tools can trust them; a task definition attempting to set one is
+// ignored.
+func (task *Task) dockerLabels(container *Container) map[string]string {
+	labels := make(map[string]string)
+	for key, value := range container.DockerLabels {
+		if strings.HasPrefix(key, AgentLabelPrefix) {
+			log.Warn("Ignoring docker label in the reserved agent namespace", "task", task.Arn, "container", container.Name, "label", key)
+			continue
+		}
+		labels[key] = value
+	}
+	labels[TaskArnLabel] = task.Arn
+	labels[ContainerNameLabel] = container.Name
+	labels[TaskDefinitionFamilyLabel] = task.Family
+	labels[TaskDefinitionVersionLabel] = task.Version
+	return labels
+}
+
 // Docker silently converts 0 to 1024 CPU shares, which is probably not what we want.
 // Instead, we convert 0 to 1 to be closer to expected behavior.
 func (task *Task) dockerCpuShares(containerCpu uint) int64 {
@@ -294,12 +340,19 @@ func (task *Task) dockerHostConfig(container *Container, dockerContainerMap map[
 		return nil, &HostConfigError{err.Error()}
 	}
 
+	devices, err := task.dockerDevices(container)
+	if err != nil {
+		return nil, &HostConfigError{err.Error()}
+	}
+
 	hostConfig := &docker.HostConfig{
-		Links:        dockerLinkArr,
-		Binds:        binds,
-		PortBindings: dockerPortMap,
-		VolumesFrom:  volumesFrom,
-		Privileged: true,
+		Devices:        devices,
+		Links:          dockerLinkArr,
+		Binds:          binds,
+		PortBindings:   dockerPortMap,
+		VolumesFrom:    volumesFrom,
+		Privileged:     true,
+		ReadonlyRootfs: container.ReadonlyRootFilesystem,
 	}
 	return hostConfig, nil
 }
@@ -361,6 +414,63 @@ func (task *Task) dockerVolumesFrom(container *Container, dockerContainerMap map
 	return volumesFrom, nil
 }
 
+func (task *Task) dockerDevices(container *Container) ([]docker.Device, error) {
+	if container.LinuxParameters == nil {
+		return nil, nil
+	}
+	devices := make([]docker.Device, len(container.LinuxParameters.Devices))
+	for i, device := range container.LinuxParameters.Devices {
+		if device.HostPath == "" {
+			return nil, errors.New("Device for container " + container.Name + " has no host path")
+		}
+		containerPath := device.ContainerPath
+		if containerPath == "" {
+			containerPath = device.HostPath
+		}
+		permissions, err := dockerCgroupPermissions(device.Permissions)
+		if err != nil {
+			return nil, err
+		}
+		devices[i] = docker.Device{
+			PathOnHost:        device.HostPath,
+			PathInContainer:   containerPath,
+			CgroupPermissions: permissions,
+		}
+	}
+	return devices, nil
+}
+
+// dockerCgroupPermissions converts device permissions to docker's "rwm" form
+func dockerCgroupPermissions(permissions []string) (string, error) {
+	if len(permissions) == 0 {
+		return "rwm", nil
+	}
+	var read, write, mknod bool
+	for _, permission := range permissions {
+		switch permission {
+		case "read":
+			read = true
+		case "write":
+			write = true
+		case "mknod":
+			mknod = true
+		default:
+			return "", errors.New("Invalid device permission: " + permission)
+		}
+	}
+	result := ""
+	if read {
+		result += "r"
+	}
+	if write {
+		result += "w"
+	}
+	if mknod {
+		result += "m"
+	}
+	return result, nil
+}
+
 func (task *Task) dockerHostBinds(container *Container) ([]string, error) {
 	if container.Name == emptyHostVolumeName {
 		// emptyHostVolumes are handled as a special case in config, not
@@ -387,10 +497,28 @@ func (task *Task) dockerHostBinds(container *Container) ([]string, error) {
 		binds[i] = bind
 	}
 
+	if container.LinuxParameters != nil {
+		for _, tmpfs := range container.LinuxParameters.Tmpfs {
+			if tmpfs.HostPath == "" {
+				return []string{}, errors.New("Scratch mount at " + tmpfs.ContainerPath + " was not mounted")
+			}
+			binds = append(binds, tmpfs.HostPath+":"+tmpfs.ContainerPath)
+		}
+	}
+	for _, artifact := range container.Artifacts {
+		if artifact.HostPath == "" {
+			return []string{}, errors.New("Artifact for " + artifact.ContainerPath + " was not downloaded")
+		}
+		binds = append(binds, artifact.HostPath+":"+artifact.ContainerPath+":ro")
+	}
+
 	return binds, nil
 }
 
 func TaskFromACS(acsTask *ecsacs.Task, envelope *ecsacs.PayloadMessage) (*Task, error) {
+	if err := validateACSTask(acsTask); err != nil {
+		return nil, err
+	}
 	data, err := jsonutil.BuildJSON(acsTask)
 	if err != nil {
 		return nil, err
@@ -417,9 +545,9 @@ func (task *Task) updateTaskDesiredStatus() {
 	// A task's desired status is stopped if any essential container is stopped
 	// Otherwise, the task's desired status is unchanged (typically running, but no need to change)
 	for _, cont := range task.Containers {
-		if cont.Essential && (cont.KnownStatus.Terminal() || cont.DesiredStatus.Terminal()) {
+		if cont.Essential && (cont.KnownTerminal() || cont.DesiredTerminal()) {
 			llog.Debug("Updating task desired status to stopped", "container", cont.Name)
-			task.DesiredStatus = TaskStopped
+			task.SetDesiredStatus(TaskStopped)
 		}
 	}
 }
@@ -440,6 +568,50 @@ func (t *Task) UpdateDesiredStatus() {
 }
 
 func (t *Task) SetKnownStatus(status TaskStatus) {
+	t.statusLock.Lock()
+	defer t.statusLock.Unlock()
 	t.KnownStatus = status
 	t.KnownStatusTime = ttime.Now()
 }
+
+// GetKnownStatus returns the task's known status
+func (t *Task) GetKnownStatus() TaskStatus {
+	t.statusLock.Lock()
+	defer t.statusLock.Unlock()
+	return t.KnownStatus
+}
+
+// GetKnownStatusTime returns when the task's known status last changed
+func (t *Task) GetKnownStatusTime() time.Time {
+	t.statusLock.Lock()
+	defer t.statusLock.Unlock()
+	return t.KnownStatusTime
+}
+
+// GetDesiredStatus returns the task's desired status
+func (t *Task) GetDesiredStatus() TaskStatus {
+	t.statusLock.Lock()
+	defer t.statusLock.Unlock()
+	return t.DesiredStatus
+}
+
+// SetDesiredStatus sets the task's desired status
+func (t *Task) SetDesiredStatus(status TaskStatus) {
+	t.statusLock.Lock()
+	defer t.statusLock.Unlock()
+	t.DesiredStatus = status
+}
+
+// GetSentStatus returns the last status of the task sent to the backend
+func (t *Task) GetSentStatus() TaskStatus {
+	t.statusLock.Lock()
+	defer t.statusLock.Unlock()
+	return t.SentStatus
+}
+
+// SetSentStatus records the last status of the task sent to the backend
+func (t *Task) SetSentStatus(status TaskStatus) {
+	t.statusLock.Lock()
+	defer t.statusLock.Unlock()
+	t.SentStatus = status
+}
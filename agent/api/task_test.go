@@ -15,6 +15,7 @@ package api
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
@@ -222,6 +223,40 @@ func TestDockerConfigLabels(t *testing.T) {
 	}
 }
 
+func TestDockerConfigDockerLabels(t *testing.T) {
+	testTask := &Task{
+		Arn:     "arn:aws:ecs:us-east-1:012345678910:task/c09f0188-7f87-4b0f-bfc3-16296622b6fe",
+		Family:  "myFamily",
+		Version: "1",
+		Containers: []*Container{
+			&Container{
+				Name: "c1",
+				DockerLabels: map[string]string{
+					"team":                             "payments",
+					"com.amazonaws.ecs.task-arn":       "spoofed",
+					"com.amazonaws.ecs.something-else": "spoofed",
+				},
+			},
+		},
+	}
+
+	config, err := testTask.DockerConfig(testTask.Containers[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"team":                                      "payments",
+		"com.amazonaws.ecs.task-arn":                "arn:aws:ecs:us-east-1:012345678910:task/c09f0188-7f87-4b0f-bfc3-16296622b6fe",
+		"com.amazonaws.ecs.container-name":          "c1",
+		"com.amazonaws.ecs.task-definition-family":  "myFamily",
+		"com.amazonaws.ecs.task-definition-version": "1",
+	}
+	if !reflect.DeepEqual(config.Labels, expected) {
+		t.Fatal("Expected docker labels without the reserved namespace, was: ", config.Labels)
+	}
+}
+
 func TestTaskFromACS(t *testing.T) {
 	strptr := func(s string) *string {
 		return &s
@@ -249,7 +284,11 @@ func TestTaskFromACS(t *testing.T) {
 				Essential:   boolptr(true),
 				Image:       strptr("image:tag"),
 				Links:       []*string{strptr("link1"), strptr("link2")},
-				Memory:      intptr(100),
+				LinuxParameters: &ecsacs.LinuxParameters{
+					MaxSwap:    intptr(20),
+					Swappiness: intptr(10),
+				},
+				Memory: intptr(100),
 				MountPoints: []*ecsacs.MountPoint{
 					&ecsacs.MountPoint{
 						ContainerPath: strptr("/container/path"),
@@ -298,6 +337,10 @@ func TestTaskFromACS(t *testing.T) {
 				Environment: map[string]string{"key": "value"},
 				Cpu:         10,
 				Memory:      100,
+				LinuxParameters: &LinuxParameters{
+					MaxSwap:    intptr(20),
+					Swappiness: intptr(10),
+				},
 				MountPoints: []MountPoint{
 					MountPoint{
 						ContainerPath: "/container/path",
@@ -352,3 +395,287 @@ func TestTaskFromACS(t *testing.T) {
 		t.Fatal("Should be equal")
 	}
 }
+
+func TestTaskFromACSInvalid(t *testing.T) {
+	strptr := func(s string) *string {
+		return &s
+	}
+	newTask := func() *ecsacs.Task {
+		return &ecsacs.Task{
+			Arn:           strptr("myArn"),
+			DesiredStatus: strptr("RUNNING"),
+			Family:        strptr("myFamily"),
+			Version:       strptr("1"),
+			Containers: []*ecsacs.Container{
+				&ecsacs.Container{Name: strptr("c1"), Image: strptr("image")},
+				&ecsacs.Container{Name: strptr("c2"), Image: strptr("image")},
+			},
+		}
+	}
+
+	noImage := newTask()
+	noImage.Containers[1].Image = nil
+	duplicateName := newTask()
+	duplicateName.Containers[1].Name = strptr("c1")
+	badStatus := newTask()
+	badStatus.DesiredStatus = strptr("PAUSED")
+	noArn := newTask()
+	noArn.Arn = nil
+
+	for task, reason := range map[*ecsacs.Task]string{
+		noImage:       "Task is missing required field 'containers[1].image'",
+		duplicateName: "Task has more than one container named 'c1'",
+		badStatus:     "Task has unrecognized desiredStatus 'PAUSED'",
+		noArn:         "Task is missing required field 'arn'",
+	} {
+		_, err := TaskFromACS(task, &ecsacs.PayloadMessage{})
+		if err == nil {
+			t.Errorf("Expected error %q", reason)
+			continue
+		}
+		if err.Error() != reason {
+			t.Errorf("Expected error %q, got %q", reason, err.Error())
+		}
+		if _, ok := err.(*InvalidTaskError); !ok {
+			t.Errorf("Expected InvalidTaskError, got %T", err)
+		}
+	}
+
+	if _, err := TaskFromACS(newTask(), &ecsacs.PayloadMessage{}); err != nil {
+		t.Error("Expected a valid task to be accepted", err)
+	}
+}
+
+func TestDockerConfigMaxSwap(t *testing.T) {
+	defer func() { hostSwapTotal = readHostSwapTotal }()
+	hostSwapTotal = func() (int64, error) { return 1024, nil }
+
+	maxSwap := int64(20)
+	testTask := &Task{
+		Containers: []*Container{
+			&Container{
+				Name:            "c1",
+				Memory:          100,
+				LinuxParameters: &LinuxParameters{MaxSwap: &maxSwap},
+			},
+		},
+	}
+
+	config, err := testTask.DockerConfig(testTask.Containers[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MemorySwap != 120*1024*1024 {
+		t.Error("Expected memory swap of 120MB, got", config.MemorySwap)
+	}
+}
+
+func TestDockerConfigMaxSwapZeroDisablesSwap(t *testing.T) {
+	defer func() { hostSwapTotal = readHostSwapTotal }()
+	hostSwapTotal = func() (int64, error) { return 0, nil }
+
+	maxSwap := int64(0)
+	testTask := &Task{
+		Containers: []*Container{
+			&Container{
+				Name:            "c1",
+				Memory:          100,
+				LinuxParameters: &LinuxParameters{MaxSwap: &maxSwap},
+			},
+		},
+	}
+
+	config, err := testTask.DockerConfig(testTask.Containers[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MemorySwap != config.Memory {
+		t.Error("Expected memory swap to equal memory, got", config.MemorySwap)
+	}
+}
+
+func TestDockerConfigMaxSwapHostSwapDisabled(t *testing.T) {
+	defer func() { hostSwapTotal = readHostSwapTotal }()
+	hostSwapTotal = func() (int64, error) { return 0, nil }
+
+	maxSwap := int64(20)
+	testTask := &Task{
+		Containers: []*Container{
+			&Container{
+				Name:            "c1",
+				Memory:          100,
+				LinuxParameters: &LinuxParameters{MaxSwap: &maxSwap},
+			},
+		},
+	}
+
+	_, err := testTask.DockerConfig(testTask.Containers[0])
+	if err == nil {
+		t.Fatal("Expected an error when swap is disabled on the host")
+	}
+}
+
+func TestDockerConfigSwappinessUnsupported(t *testing.T) {
+	swappiness := int64(10)
+	testTask := &Task{
+		Containers: []*Container{
+			&Container{
+				Name:            "c1",
+				Memory:          100,
+				LinuxParameters: &LinuxParameters{Swappiness: &swappiness},
+			},
+		},
+	}
+
+	_, err := testTask.DockerConfig(testTask.Containers[0])
+	if err == nil {
+		t.Fatal("Expected an error for swappiness, which is not supported")
+	}
+}
+
+func TestDockerHostConfigDevices(t *testing.T) {
+	testTask := &Task{
+		Containers: []*Container{
+			&Container{
+				Name: "c1",
+				LinuxParameters: &LinuxParameters{
+					Devices: []Device{
+						Device{HostPath: "/dev/fuse"},
+						Device{HostPath: "/dev/sda", ContainerPath: "/dev/xvda", Permissions: []string{"read", "mknod"}},
+					},
+				},
+			},
+		},
+	}
+
+	config, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Devices) != 2 {
+		t.Fatal("Expected two devices, got", config.Devices)
+	}
+	if config.Devices[0].PathInContainer != "/dev/fuse" || config.Devices[0].CgroupPermissions != "rwm" {
+		t.Error("Unexpected default device mapping", config.Devices[0])
+	}
+	if config.Devices[1].PathInContainer != "/dev/xvda" || config.Devices[1].CgroupPermissions != "rm" {
+		t.Error("Unexpected device mapping", config.Devices[1])
+	}
+}
+
+func TestDockerHostConfigInvalidDevicePermission(t *testing.T) {
+	testTask := &Task{
+		Containers: []*Container{
+			&Container{
+				Name: "c1",
+				LinuxParameters: &LinuxParameters{
+					Devices: []Device{Device{HostPath: "/dev/fuse", Permissions: []string{"execute"}}},
+				},
+			},
+		},
+	}
+
+	_, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask))
+	if err == nil {
+		t.Error("Expected an error for an invalid device permission")
+	}
+}
+
+func TestDockerConfigCommandForms(t *testing.T) {
+	strs := func(s ...string) []string { return s }
+	testCases := []struct {
+		entryPoint         []string
+		command            []string
+		expectedEntryPoint []string
+		expectedCommand    []string
+		err                string
+	}{
+		{nil, strs("echo", "a b"), []string{}, strs("echo", "a b"), ""},
+		{nil, strs("CMD", "echo", "a b"), []string{}, strs("echo", "a b"), ""},
+		{nil, strs("CMD-SHELL", "echo 'a b' && sleep 1"), []string{}, strs("/bin/sh", "-c", "echo 'a b' && sleep 1"), ""},
+		{strs("CMD-SHELL", "exec server"), nil, strs("/bin/sh", "-c", "exec server"), nil, ""},
+		{strs("CMD", "/server"), strs("CMD-SHELL", "--port 80"), strs("/server"), strs("/bin/sh", "-c", "--port 80"), ""},
+		{nil, strs("CMD"), nil, nil, "Invalid container command in exec form (CMD) must name an executable"},
+		{nil, strs("CMD-SHELL", "echo", "a"), nil, nil, "Invalid container command in shell form (CMD-SHELL) must be followed by exactly one string, not 2"},
+		{nil, strs("CMD-SHELL", " "), nil, nil, "Invalid container command in shell form (CMD-SHELL) must not be empty"},
+		{strs("CMD-SHELL", "server"), strs("--port", "80"), nil, nil, "Invalid container entryPoint in shell form (CMD-SHELL) ignores command; use exec form for one of them"},
+	}
+
+	for i, testCase := range testCases {
+		container := &Container{Name: "c1", Command: testCase.command}
+		if testCase.entryPoint != nil {
+			entryPoint := testCase.entryPoint
+			container.EntryPoint = &entryPoint
+		}
+		testTask := &Task{Containers: []*Container{container}}
+
+		config, err := testTask.DockerConfig(container)
+		if testCase.err != "" {
+			if err == nil || err.Error() != testCase.err {
+				t.Errorf("Case %d: expected error %q, got %v", i, testCase.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Case %d: unexpected error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(config.Entrypoint, testCase.expectedEntryPoint) || !reflect.DeepEqual(config.Cmd, testCase.expectedCommand) {
+			t.Errorf("Case %d: got entrypoint %#v and command %#v", i, config.Entrypoint, config.Cmd)
+		}
+	}
+}
+
+func TestDockerConfigUserAndHostname(t *testing.T) {
+	testCases := []struct {
+		user     string
+		hostname string
+		valid    bool
+	}{
+		{"", "", true},
+		{"nobody", "web-1", true},
+		{"1000:1000", "web-1.example.com", true},
+		{"app:staff", "a", true},
+		{"1000:", "", false},
+		{":1000", "", false},
+		{"a:b:c", "", false},
+		{"my user", "", false},
+		{"", "-web", false},
+		{"", "web_1", false},
+		{"", "web..example", false},
+		{"", strings.Repeat("a", 65), false},
+	}
+
+	for i, testCase := range testCases {
+		container := &Container{Name: "c1", User: testCase.user, Hostname: testCase.hostname, WorkingDirectory: "/srv"}
+		testTask := &Task{Containers: []*Container{container}}
+
+		config, err := testTask.DockerConfig(container)
+		if !testCase.valid {
+			if err == nil {
+				t.Errorf("Case %d: expected an error for user %q hostname %q", i, testCase.user, testCase.hostname)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Case %d: unexpected error %v", i, err)
+			continue
+		}
+		if config.User != testCase.user || config.Hostname != testCase.hostname || config.WorkingDir != "/srv" {
+			t.Errorf("Case %d: got user %q hostname %q working dir %q", i, config.User, config.Hostname, config.WorkingDir)
+		}
+	}
+}
+
+func TestDockerConfigTerminal(t *testing.T) {
+	container := &Container{Name: "c1", Interactive: true, PseudoTerminal: true}
+	testTask := &Task{Containers: []*Container{container}}
+
+	config, err := testTask.DockerConfig(container)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.Tty || !config.OpenStdin {
+		t.Error("Expected tty and open stdin, got", config.Tty, config.OpenStdin)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"strconv"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+)
+
+// InvalidTaskError is returned for a task from ACS which is missing a field
+// the agent requires to run it
+type InvalidTaskError struct {
+	msg string
+}
+
+func (err *InvalidTaskError) Error() string     { return err.msg }
+func (err *InvalidTaskError) ErrorName() string { return "InvalidTaskError" }
+func (err *InvalidTaskError) Retry() bool       { return false }
+
+func missingField(path string) *InvalidTaskError {
+	return &InvalidTaskError{"Task is missing required field '" + path + "'"}
+}
+
+// validateACSTask checks that a task from ACS has every field the agent needs
+// to run it. Fields the agent doesn't know about are not an error; they are
+// ignored when the task is decoded.
+func validateACSTask(acsTask *ecsacs.Task) error {
+	if acsTask.Arn == nil || *acsTask.Arn == "" {
+		return missingField("arn")
+	}
+	if acsTask.Family == nil || *acsTask.Family == "" {
+		return missingField("family")
+	}
+	if acsTask.Version == nil || *acsTask.Version == "" {
+		return missingField("version")
+	}
+	if acsTask.DesiredStatus == nil {
+		return missingField("desiredStatus")
+	}
+	if *acsTask.DesiredStatus != "RUNNING" && *acsTask.DesiredStatus != "STOPPED" {
+		return &InvalidTaskError{"Task has unrecognized desiredStatus '" + *acsTask.DesiredStatus + "'"}
+	}
+
+	names := make(map[string]bool)
+	for i, container := range acsTask.Containers {
+		path := "containers[" + strconv.Itoa(i) + "]"
+		if container == nil {
+			return missingField(path)
+		}
+		if container.Name == nil || *container.Name == "" {
+			return missingField(path + ".name")
+		}
+		if container.Image == nil || *container.Image == "" {
+			return missingField(path + ".image")
+		}
+		if names[*container.Name] {
+			return &InvalidTaskError{"Task has more than one container named '" + *container.Name + "'"}
+		}
+		names[*container.Name] = true
+	}
+
+	for i, volume := range acsTask.Volumes {
+		path := "volumes[" + strconv.Itoa(i) + "]"
+		if volume == nil {
+			return missingField(path)
+		}
+		if volume.Name == nil || *volume.Name == "" {
+			return missingField(path + ".name")
+		}
+	}
+	return nil
+}
@@ -92,6 +92,9 @@ type Task struct {
 	Version    string
 	Containers []*Container
 	Volumes    []TaskVolume `json:"volumes"`
+	// Platform is the os/architecture[/variant], such as "linux/arm64", the
+	// task's images are pulled for. The host's is used if it is not set.
+	Platform string `json:"platform"`
 
 	DesiredStatus   TaskStatus
 	KnownStatus     TaskStatus
@@ -99,11 +102,24 @@ type Task struct {
 
 	SentStatus TaskStatus
 
+	// statusLock guards the task's statuses, which are updated by the
+	// engine and the event handler and read by the introspection server
+	statusLock sync.Mutex
+
 	containersByNameLock sync.Mutex
 	containersByName     map[string]*Container
 
 	StartSequenceNumber int64
 	StopSequenceNumber  int64
+
+	// PreStartHooksRun is whether the host's task hooks were run before the
+	// task's first container was created, and PostStopHooksRun whether they
+	// were run once it stopped
+	PreStartHooksRun bool `json:"preStartHooksRun,omitempty"`
+	PostStopHooksRun bool `json:"postStopHooksRun,omitempty"`
+	// HooksLock serializes running the task hooks for the task, as its
+	// containers may be created concurrently
+	HooksLock sync.Mutex `json:"-"`
 }
 
 // TaskVolume is a definition of all the volumes available for containers to
@@ -146,6 +162,43 @@ func (e *EmptyHostVolume) SourcePath() string {
 	return e.HostPath
 }
 
+// EFSVolume is a HostVolume backed by an EFS filesystem which the agent
+// mounts on the host before the task's containers are created.
+type EFSVolume struct {
+	FileSystemID          string                 `json:"fileSystemId"`
+	RootDirectory         string                 `json:"rootDirectory"`
+	TransitEncryption     string                 `json:"transitEncryption"`
+	TransitEncryptionPort int64                  `json:"transitEncryptionPort"`
+	AuthorizationConfig   EFSAuthorizationConfig `json:"authorizationConfig"`
+
+	// HostPath is where the filesystem is mounted on the host. It is empty
+	// until the volume has been mounted.
+	HostPath string `json:"hostPath"`
+}
+
+// EFSAuthorizationConfig configures access point and IAM authorization for
+// an EFSVolume
+type EFSAuthorizationConfig struct {
+	AccessPointID string `json:"accessPointId"`
+	IAM           string `json:"iam"`
+}
+
+// SourcePath returns the location the filesystem is mounted on the host
+func (efs *EFSVolume) SourcePath() string {
+	return efs.HostPath
+}
+
+// TransitEncryptionEnabled returns true if traffic to the filesystem should
+// be encrypted with TLS
+func (efs *EFSVolume) TransitEncryptionEnabled() bool {
+	return efs.TransitEncryption == "ENABLED"
+}
+
+// IAMEnabled returns true if IAM authorization should be used when mounting
+func (efs *EFSVolume) IAMEnabled() bool {
+	return efs.AuthorizationConfig.IAM == "ENABLED"
+}
+
 type ContainerStateChange struct {
 	TaskArn       string
 	ContainerName string
@@ -155,10 +208,10 @@ type ContainerStateChange struct {
 	ExitCode     *int
 	PortBindings []PortBinding
 
-	// This bit is a little hacky; a pointer to the container's sentstatus which
-	// may be updated to indicate what status was sent. This is used to ensure
-	// the same event is handled only once.
-	SentStatus *ContainerStatus
+	// This bit is a little hacky; the container whose sent status may be
+	// updated to indicate what status was sent. This is used to ensure the
+	// same event is handled only once.
+	Container *Container
 }
 
 type TaskStateChange struct {
@@ -167,17 +220,19 @@ type TaskStateChange struct {
 	Reason  string
 
 	// As above, this is the same sort of hacky.
-	// This is a pointer to the task's sent-status that gives the event handler a
-	// hook into storing metadata about the task on the task such that it follows
-	// the lifecycle of the task and so on.
-	SentStatus *TaskStatus
+	// This is the task whose sent status gives the event handler a hook into
+	// storing metadata about the task on the task such that it follows the
+	// lifecycle of the task and so on.
+	Task *Task
 }
 
 func (t *Task) String() string {
-	res := fmt.Sprintf("%s-%s %s, Status: (%s->%s)", t.Family, t.Version, t.Arn, t.KnownStatus.String(), t.DesiredStatus.String())
+	knownStatus, desiredStatus := t.GetKnownStatus(), t.GetDesiredStatus()
+	res := fmt.Sprintf("%s-%s %s, Status: (%s->%s)", t.Family, t.Version, t.Arn, knownStatus.String(), desiredStatus.String())
 	res += " Containers: ["
 	for _, c := range t.Containers {
-		res += fmt.Sprintf("%s (%s->%s),", c.Name, c.KnownStatus.String(), c.DesiredStatus.String())
+		containerKnownStatus, containerDesiredStatus := c.GetKnownStatus(), c.GetDesiredStatus()
+		res += fmt.Sprintf("%s (%s->%s),", c.Name, containerKnownStatus.String(), containerDesiredStatus.String())
 	}
 	return res + "]"
 }
@@ -187,8 +242,9 @@ type ContainerOverrides struct {
 }
 
 type Container struct {
-	Name        string
-	Image       string
+	Name  string
+	Image string
+	// Command and EntryPoint may be in exec or shell form; see command.go
 	Command     []string
 	Cpu         uint
 	Memory      uint
@@ -201,6 +257,42 @@ type Container struct {
 	Environment map[string]string  `json:"environment"`
 	Overrides   ContainerOverrides `json:"overrides"`
 
+	LinuxParameters *LinuxParameters `json:"linuxParameters"`
+
+	// WorkingDirectory, User and Hostname override the image's defaults
+	// when set. User is a name or uid, optionally followed by ":" and a group
+	// name or gid.
+	WorkingDirectory string `json:"workingDirectory"`
+	User             string `json:"user"`
+	Hostname         string `json:"hostname"`
+
+	// DockerLabels are applied to the docker container alongside the labels
+	// the agent manages; see AgentLabelPrefix
+	DockerLabels map[string]string `json:"dockerLabels"`
+
+	// Interactive keeps stdin open and PseudoTerminal allocates a tty, for
+	// containers wrapping programs that expect to be run from a terminal
+	Interactive    bool `json:"interactive"`
+	PseudoTerminal bool `json:"pseudoTerminal"`
+
+	// ReadonlyRootFilesystem mounts the container's root filesystem read
+	// only. Paths it needs to write to can be declared as
+	// LinuxParameters.Tmpfs scratch mounts.
+	ReadonlyRootFilesystem bool `json:"readonlyRootFilesystem"`
+
+	// Artifacts are files the agent downloads, before the container is
+	// created, and binds into it
+	Artifacts []ContainerArtifact `json:"artifacts,omitempty"`
+
+	// StopSignal is the signal the container is asked to stop with, such as
+	// "SIGQUIT", instead of SIGTERM
+	StopSignal string `json:"stopSignal,omitempty"`
+
+	// StopTimeout is how many seconds the container is given to exit after
+	// being asked to stop before it is killed. If it is 0, the agent's
+	// configured default is used.
+	StopTimeout int64 `json:"stopTimeout"`
+
 	DesiredStatus ContainerStatus `json:"desiredStatus"`
 	KnownStatus   ContainerStatus
 
@@ -219,8 +311,17 @@ type Container struct {
 
 	KnownExitCode     *int
 	KnownPortBindings []PortBinding
+	// KnownReason explains why the container stopped, as reported to the
+	// backend, and KnownFinishedAt is when it stopped
+	KnownReason     string
+	KnownFinishedAt time.Time
 
-	// Not upstream; todo move this out into a wrapper type
+	// LaunchPhases records how long each phase of launching the container,
+	// such as pulling its image, took
+	LaunchPhases map[string]time.Duration
+
+	// Not upstream; todo move this out into a wrapper type. It guards the
+	// container's statuses and launch phases.
 	StatusLock sync.Mutex
 }
 
@@ -230,6 +331,63 @@ type VolumeFrom struct {
 	ReadOnly        bool   `json:"readOnly"`
 }
 
+// LinuxParameters are linux-specific options for a container. MaxSwap is the
+// total amount of swap, in MiB, the container may use; a value of 0 disables
+// swap for the container. Swappiness is not supported, and a container which
+// sets it is not created.
+// PidsLimit is the most processes and threads the container may run at once.
+type LinuxParameters struct {
+	// CPUSetCPUs pins the container to cores of the instance, given as a
+	// list of cores and ranges such as "0-1,3". No other container is
+	// pinned to or started on those cores while it runs.
+	CPUSetCPUs string          `json:"cpusetCpus"`
+	Devices    []Device        `json:"devices"`
+	HugePages  []HugePageLimit `json:"hugePages"`
+	MaxSwap    *int64          `json:"maxSwap"`
+	PidsLimit  *int64          `json:"pidsLimit"`
+	Swappiness *int64          `json:"swappiness"`
+	Tmpfs      []Tmpfs         `json:"tmpfs"`
+}
+
+// HugePageLimit limits the container to Limit MiB of hugepages of PageSize,
+// "2MB" or "1GB". Limit must be a whole number of pages.
+type HugePageLimit struct {
+	PageSize string `json:"pageSize"`
+	Limit    int64  `json:"limit"`
+}
+
+// Tmpfs is a writable scratch mount of Size MiB at ContainerPath. The agent
+// mounts a tmpfs on the host at HostPath and binds it into the container.
+type Tmpfs struct {
+	ContainerPath string `json:"containerPath"`
+	Size          int64  `json:"size"`
+	HostPath      string `json:"hostPath"`
+}
+
+// ContainerArtifact is a file downloaded from Source, an https url, which
+// must have the hex encoded sha256 digest SHA256. S3 locations, as
+// s3://bucket/key, are refused, since tasks have no credentials of their own
+// to fetch them with; a presigned url can be used instead. It is bound read only into the container at ContainerPath. Mode is
+// its permissions in octal, such as "0440", and Owner its owner as "uid" or
+// "uid:gid"; they default to "0444" and root. The agent downloads it into a
+// directory of the task's at HostPath.
+type ContainerArtifact struct {
+	Source        string `json:"source"`
+	SHA256        string `json:"sha256"`
+	ContainerPath string `json:"containerPath"`
+	Mode          string `json:"mode"`
+	Owner         string `json:"owner"`
+	HostPath      string `json:"hostPath"`
+}
+
+// Device is a host device exposed to a container. Permissions may contain
+// any of "read", "write", and "mknod"; if empty, all are granted.
+type Device struct {
+	HostPath      string   `json:"hostPath"`
+	ContainerPath string   `json:"containerPath"`
+	Permissions   []string `json:"permissions"`
+}
+
 func (c *Container) String() string {
 	ret := fmt.Sprintf("%s-%s (%s->%s)", c.Name, c.Image, c.KnownStatus.String(), c.DesiredStatus.String())
 	if c.KnownExitCode != nil {
@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/ec2"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
@@ -41,6 +42,14 @@ const (
 	AGENT_INTROSPECTION_PORT = 51678
 
 	DEFAULT_CLUSTER_NAME = "default"
+
+	// DefaultDockerGraphPath is where docker keeps its data if the daemon
+	// doesn't say otherwise
+	DefaultDockerGraphPath = "/var/lib/docker"
+
+	// DefaultDiskPressureThreshold is the percentage of a filesystem in use
+	// above which it is under pressure, unless configured otherwise
+	DefaultDiskPressureThreshold = 85
 )
 
 // Merge merges two config files, preferring the ones on the left. Any nil or
@@ -144,8 +153,21 @@ func DefaultConfig() Config {
 		ReservedPortsUDP: []uint16{},
 		DataDir:          "/data/",
 		DisableMetrics:   false,
-		DockerGraphPath:  "/var/lib/docker",
 		ReservedMemory:   0,
+
+		AllowedDevicePathPrefixes:  []string{"/dev/"},
+		EFSMountDir:                "/var/lib/ecs/volumes",
+		ImagePullConcurrency:       4,
+		TaskReconciliationInterval: 10 * time.Minute,
+		TaskCleanupWaitDuration:    3 * time.Hour,
+		DiskPressureThreshold:      DefaultDiskPressureThreshold,
+		PreStartHookTimeout:        30 * time.Second,
+		TaskHookTimeout:            30 * time.Second,
+		DockerStopTimeout:          30 * time.Second,
+
+		StatsSamplingContainerThreshold: 50,
+		StatsCPUBudget:                  5,
+		CNIPluginsPath:                  "/amazon-ecs-cni-plugins",
 	}
 }
 
@@ -183,6 +205,38 @@ func FileConfig() Config {
 // to convert them to the given type
 func EnvironmentConfig() Config {
 	endpoint := os.Getenv("ECS_BACKEND_HOST")
+	telemetryEndpoint := os.Getenv("ECS_TELEMETRY_ENDPOINT")
+	statsDAddress := os.Getenv("ECS_STATSD_ADDRESS")
+	emfLogGroup := os.Getenv("ECS_EMF_LOG_GROUP")
+	emfAddress := os.Getenv("ECS_EMF_ADDRESS")
+	otlpEndpoint := os.Getenv("ECS_OTLP_ENDPOINT")
+	cgroupDriver := os.Getenv("ECS_CGROUP_DRIVER")
+	preStartHookCommand := os.Getenv("ECS_PRESTART_HOOK_COMMAND")
+	preStartHookURL := os.Getenv("ECS_PRESTART_HOOK_URL")
+	preStartHookFailOpen := utils.ParseBool(os.Getenv("ECS_PRESTART_HOOK_FAIL_OPEN"), false)
+	taskHookCommand := os.Getenv("ECS_TASK_HOOK_COMMAND")
+	taskHookURL := os.Getenv("ECS_TASK_HOOK_URL")
+	taskHookFailOpen := utils.ParseBool(os.Getenv("ECS_TASK_HOOK_FAIL_OPEN"), false)
+	deregisterOnClusterChange := utils.ParseBool(os.Getenv("ECS_DEREGISTER_ON_CLUSTER_CHANGE"), false)
+	propagateInstanceTags := utils.ParseBool(os.Getenv("ECS_PROPAGATE_INSTANCE_TAGS"), false)
+	statsAdaptiveSampling := utils.ParseBool(os.Getenv("ECS_STATS_ADAPTIVE_SAMPLING"), false)
+	drainOnScheduledEvents := utils.ParseBool(os.Getenv("ECS_DRAIN_ON_SCHEDULED_EVENTS"), false)
+	localAPISocket := os.Getenv("ECS_LOCAL_API_SOCKET")
+	localTaskAPIEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_LOCAL_TASK_API"), false)
+	containerLogsAPIEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_CONTAINER_LOGS_API"), false)
+	networkDiagnosticsAPIEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_NETWORK_DIAGNOSTICS_API"), false)
+	containerMetadataEndpoint := os.Getenv("ECS_CONTAINER_METADATA_ENDPOINT")
+	eventStreamSocket := os.Getenv("ECS_EVENT_STREAM_SOCKET")
+	taskLogDir := os.Getenv("ECS_TASK_LOG_DIR")
+	numaPlacement := utils.ParseBool(os.Getenv("ECS_NUMA_PLACEMENT"), false)
+	stateEncryptionKeyFile := os.Getenv("ECS_STATE_ENCRYPTION_KEY_FILE")
+	imagePlatformSelectionEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_IMAGE_PLATFORM_SELECTION"), false)
+	recoverCorruptState := utils.ParseBool(os.Getenv("ECS_RECOVER_CORRUPT_STATE"), false)
+	containerNameTemplate := os.Getenv("ECS_CONTAINER_NAME_TEMPLATE")
+	tlsCABundle := os.Getenv("ECS_TLS_CA_BUNDLE")
+	tlsMinVersion := os.Getenv("ECS_TLS_MIN_VERSION")
+	imageVerifier := os.Getenv("ECS_IMAGE_VERIFIER")
+	imageVerificationKey := os.Getenv("ECS_IMAGE_VERIFICATION_KEY")
 
 	clusterRef := os.Getenv("ECS_CLUSTER")
 	awsRegion := os.Getenv("AWS_DEFAULT_REGION")
@@ -227,8 +281,12 @@ func EnvironmentConfig() Config {
 
 	updateDownloadDir := os.Getenv("ECS_UPDATE_DOWNLOAD_DIR")
 	updatesEnabled := utils.ParseBool(os.Getenv("ECS_UPDATES_ENABLED"), false)
+	updateSigningKey := os.Getenv("ECS_UPDATE_SIGNING_KEY")
 
 	disableMetrics := utils.ParseBool(os.Getenv("ECS_DISABLE_METRICS"), false)
+	telemetryEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_TELEMETRY"), false)
+	dumpACSPayloads := utils.ParseBool(os.Getenv("ECS_DUMP_ACS_PAYLOADS"), false)
+	taskTracing := utils.ParseBool(os.Getenv("ECS_ENABLE_TASK_TRACING"), false)
 	dockerGraphPath := os.Getenv("ECS_DOCKER_GRAPHPATH")
 
 	reservedMemoryEnv := os.Getenv("ECS_RESERVED_MEMORY")
@@ -246,9 +304,106 @@ func EnvironmentConfig() Config {
 		}
 	}
 
+	// Format: json array, e.g. ["/dev/fuse","/dev/nvidia"]
+	allowedDevicesEnv := os.Getenv("ECS_ALLOWED_DEVICE_PATH_PREFIXES")
+	allowedDevicesDecoder := json.NewDecoder(strings.NewReader(allowedDevicesEnv))
+	var allowedDevicePathPrefixes []string
+	err = allowedDevicesDecoder.Decode(&allowedDevicePathPrefixes)
+	if err != io.EOF && err != nil {
+		log.Warn("Invalid format for \"ECS_ALLOWED_DEVICE_PATH_PREFIXES\" environment variable; expected a JSON array of strings.", "err", err)
+	}
+
+	efsMountDir := os.Getenv("ECS_EFS_MOUNT_DIR")
+
+	var imagePullConcurrency int
+	imagePullConcurrencyEnv := os.Getenv("ECS_IMAGE_PULL_CONCURRENCY")
+	if imagePullConcurrencyEnv != "" {
+		imagePullConcurrency, err = strconv.Atoi(imagePullConcurrencyEnv)
+		if err != nil || imagePullConcurrency < 0 {
+			log.Warn("Invalid format for \"ECS_IMAGE_PULL_CONCURRENCY\" environment variable; expected a positive integer.", "err", err)
+			imagePullConcurrency = 0
+		}
+	}
+
+	var statsSamplingContainerThreshold int
+	statsSamplingContainerThresholdEnv := os.Getenv("ECS_STATS_SAMPLING_CONTAINER_THRESHOLD")
+	if statsSamplingContainerThresholdEnv != "" {
+		statsSamplingContainerThreshold, err = strconv.Atoi(statsSamplingContainerThresholdEnv)
+		if err != nil || statsSamplingContainerThreshold < 0 {
+			log.Warn("Invalid format for \"ECS_STATS_SAMPLING_CONTAINER_THRESHOLD\" environment variable; expected a positive integer.", "err", err)
+			statsSamplingContainerThreshold = 0
+		}
+	}
+
+	var taskReconciliationInterval time.Duration
+	taskReconciliationIntervalEnv := os.Getenv("ECS_TASK_RECONCILIATION_INTERVAL")
+	if taskReconciliationIntervalEnv != "" {
+		taskReconciliationInterval, err = time.ParseDuration(taskReconciliationIntervalEnv)
+		if err != nil {
+			log.Warn("Invalid format for \"ECS_TASK_RECONCILIATION_INTERVAL\" environment variable; expected a duration like 10m.", "err", err)
+			taskReconciliationInterval = 0
+		}
+	}
+
+	var taskCleanupWaitDuration time.Duration
+	taskCleanupWaitDurationEnv := os.Getenv("ECS_ENGINE_TASK_CLEANUP_WAIT_DURATION")
+	if taskCleanupWaitDurationEnv != "" {
+		taskCleanupWaitDuration, err = time.ParseDuration(taskCleanupWaitDurationEnv)
+		if err != nil {
+			log.Warn("Invalid format for \"ECS_ENGINE_TASK_CLEANUP_WAIT_DURATION\" environment variable; expected a duration like 3h.", "err", err)
+			taskCleanupWaitDuration = 0
+		}
+	}
+
+	var preStartHookTimeout time.Duration
+	preStartHookTimeoutEnv := os.Getenv("ECS_PRESTART_HOOK_TIMEOUT")
+	if preStartHookTimeoutEnv != "" {
+		preStartHookTimeout, err = time.ParseDuration(preStartHookTimeoutEnv)
+		if err != nil {
+			log.Warn("Invalid format for \"ECS_PRESTART_HOOK_TIMEOUT\" environment variable; expected a duration like 30s.", "err", err)
+			preStartHookTimeout = 0
+		}
+	}
+
+	var taskHookTimeout time.Duration
+	taskHookTimeoutEnv := os.Getenv("ECS_TASK_HOOK_TIMEOUT")
+	if taskHookTimeoutEnv != "" {
+		taskHookTimeout, err = time.ParseDuration(taskHookTimeoutEnv)
+		if err != nil {
+			log.Warn("Invalid format for \"ECS_TASK_HOOK_TIMEOUT\" environment variable; expected a duration like 30s.", "err", err)
+			taskHookTimeout = 0
+		}
+	}
+
+	var dockerStopTimeout time.Duration
+	dockerStopTimeoutEnv := os.Getenv("ECS_CONTAINER_STOP_TIMEOUT")
+	if dockerStopTimeoutEnv != "" {
+		dockerStopTimeout, err = time.ParseDuration(dockerStopTimeoutEnv)
+		if err != nil {
+			log.Warn("Invalid format for \"ECS_CONTAINER_STOP_TIMEOUT\" environment variable; expected a duration like 30s.", "err", err)
+			dockerStopTimeout = 0
+		}
+	}
+
+	diskPressureThreshold := parsePercent("ECS_DISK_PRESSURE_THRESHOLD")
+	websocketReadBufferSize := parseSize("ECS_WEBSOCKET_READ_BUFFER_SIZE")
+	websocketWriteBufferSize := parseSize("ECS_WEBSOCKET_WRITE_BUFFER_SIZE")
+	websocketMaxMessageSize := parseSize("ECS_WEBSOCKET_MAX_MESSAGE_SIZE")
+	downloadBandwidthLimit := parseSize("ECS_DOWNLOAD_BANDWIDTH_LIMIT")
+	containerDefaults := parseContainerDefaults("ECS_CONTAINER_DEFAULTS")
+	diskUnhealthyThreshold := parsePercent("ECS_DISK_UNHEALTHY_THRESHOLD")
+	statsCPUBudget := parsePercent("ECS_STATS_CPU_BUDGET")
+	statsExcludeLabels := parseStringArray("ECS_STATS_EXCLUDE_LABELS")
+	prePullImages := parseStringArray("ECS_PREPULL_IMAGES")
+	statsExcludeContainerNames := parseStringArray("ECS_STATS_EXCLUDE_CONTAINER_NAMES")
+	statsExcludeTaskFamilies := parseStringArray("ECS_STATS_EXCLUDE_TASK_FAMILIES")
+	instanceHealthChecks := parseStringArray("ECS_INSTANCE_HEALTH_CHECKS")
+	cniPluginsPath := os.Getenv("ECS_CNI_PLUGINS_PATH")
+
 	return Config{
 		Cluster:           clusterRef,
 		APIEndpoint:       endpoint,
+		TelemetryEndpoint: telemetryEndpoint,
 		AWSRegion:         awsRegion,
 		DockerEndpoint:    dockerEndpoint,
 		ReservedPorts:     reservedPorts,
@@ -259,10 +414,130 @@ func EnvironmentConfig() Config {
 		EngineAuthData:    []byte(engineAuthData),
 		UpdatesEnabled:    updatesEnabled,
 		UpdateDownloadDir: updateDownloadDir,
+		UpdateSigningKey:  updateSigningKey,
 		DisableMetrics:    disableMetrics,
+		TelemetryEnabled:  telemetryEnabled,
 		DockerGraphPath:   dockerGraphPath,
 		ReservedMemory:    reservedMemory,
+
+		AllowedDevicePathPrefixes:  allowedDevicePathPrefixes,
+		EFSMountDir:                efsMountDir,
+		ImagePullConcurrency:       imagePullConcurrency,
+		TaskReconciliationInterval: taskReconciliationInterval,
+		TaskCleanupWaitDuration:    taskCleanupWaitDuration,
+		DumpACSPayloads:            dumpACSPayloads,
+		DiskPressureThreshold:      diskPressureThreshold,
+		DiskUnhealthyThreshold:     diskUnhealthyThreshold,
+		WebsocketReadBufferSize:    int(websocketReadBufferSize),
+		WebsocketWriteBufferSize:   int(websocketWriteBufferSize),
+		WebsocketMaxMessageSize:    websocketMaxMessageSize,
+		DownloadBandwidthLimit:     downloadBandwidthLimit,
+		ContainerDefaults:          containerDefaults,
+		StatsDAddress:              statsDAddress,
+		EMFLogGroup:                emfLogGroup,
+		EMFAddress:                 emfAddress,
+		OTLPEndpoint:               otlpEndpoint,
+		TaskTracing:                taskTracing,
+		CgroupDriver:               cgroupDriver,
+		PreStartHookCommand:        preStartHookCommand,
+		PreStartHookURL:            preStartHookURL,
+		PreStartHookTimeout:        preStartHookTimeout,
+		PreStartHookFailOpen:       preStartHookFailOpen,
+		TaskHookCommand:            taskHookCommand,
+		TaskHookURL:                taskHookURL,
+		TaskHookTimeout:            taskHookTimeout,
+		TaskHookFailOpen:           taskHookFailOpen,
+		ImageVerifier:              imageVerifier,
+		ImageVerificationKey:       imageVerificationKey,
+		DockerStopTimeout:          dockerStopTimeout,
+		DeregisterOnClusterChange:  deregisterOnClusterChange,
+		PropagateInstanceTags:      propagateInstanceTags,
+
+		StatsAdaptiveSampling:           statsAdaptiveSampling,
+		StatsSamplingContainerThreshold: statsSamplingContainerThreshold,
+		StatsCPUBudget:                  statsCPUBudget,
+		StatsExcludeLabels:              statsExcludeLabels,
+		StatsExcludeContainerNames:      statsExcludeContainerNames,
+		StatsExcludeTaskFamilies:        statsExcludeTaskFamilies,
+		DrainOnScheduledEvents:          drainOnScheduledEvents,
+		InstanceHealthChecks:            instanceHealthChecks,
+		CNIPluginsPath:                  cniPluginsPath,
+		LocalAPISocket:                  localAPISocket,
+		LocalTaskAPIEnabled:             localTaskAPIEnabled,
+		ContainerLogsAPIEnabled:         containerLogsAPIEnabled,
+		NetworkDiagnosticsAPIEnabled:    networkDiagnosticsAPIEnabled,
+		ContainerMetadataEndpoint:       containerMetadataEndpoint,
+		EventStreamSocket:               eventStreamSocket,
+		TaskLogDir:                      taskLogDir,
+		NUMAPlacement:                   numaPlacement,
+		StateEncryptionKeyFile:          stateEncryptionKeyFile,
+		ImagePlatformSelectionEnabled:   imagePlatformSelectionEnabled,
+		PrePullImages:                   prePullImages,
+		RecoverCorruptState:             recoverCorruptState,
+		ContainerNameTemplate:           containerNameTemplate,
+		TLSCABundle:                     tlsCABundle,
+		TLSMinVersion:                   tlsMinVersion,
+	}
+}
+
+// parseSize parses the size in bytes in the environment variable name,
+// returning 0 if it is unset or not a positive integer
+func parseSize(name string) int64 {
+	env := os.Getenv(name)
+	if env == "" {
+		return 0
+	}
+	size, err := strconv.ParseInt(env, 10, 32)
+	if err != nil || size <= 0 {
+		log.Warn("Invalid format for \""+name+"\" environment variable; expected a positive number of bytes.", "err", err)
+		return 0
 	}
+	return size
+}
+
+// parsePercent parses the percentage in the environment variable name,
+// returning 0 if it is unset or not an integer between 1 and 100
+func parsePercent(name string) int {
+	env := os.Getenv(name)
+	if env == "" {
+		return 0
+	}
+	percent, err := strconv.Atoi(env)
+	if err != nil || percent <= 0 || percent > 100 {
+		log.Warn("Invalid format for \""+name+"\" environment variable; expected an integer percentage between 1 and 100.", "err", err)
+		return 0
+	}
+	return percent
+}
+
+// parseStringArray parses the json array of strings in the environment
+// variable name, e.g. ["a","b"], returning nil if it is unset or invalid
+func parseStringArray(name string) []string {
+	env := os.Getenv(name)
+	if env == "" {
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal([]byte(env), &values); err != nil {
+		log.Warn("Invalid format for \""+name+"\" environment variable; expected a JSON array of strings.", "err", err)
+		return nil
+	}
+	return values
+}
+
+// parseContainerDefaults parses the json container defaults in the
+// environment variable name, returning nil if it is unset or invalid
+func parseContainerDefaults(name string) *ContainerDefaults {
+	env := os.Getenv(name)
+	if env == "" {
+		return nil
+	}
+	var defaults ContainerDefaults
+	if err := json.Unmarshal([]byte(env), &defaults); err != nil {
+		log.Warn("Invalid format for \""+name+"\" environment variable; expected a JSON object of container defaults.", "err", err)
+		return nil
+	}
+	return &defaults
 }
 
 var ec2MetadataClient = ec2.DefaultClient
@@ -288,6 +563,9 @@ func NewConfig() (config *Config, err error) {
 		config.TrimWhitespace()
 		err = config.CheckMissingAndDepreciated()
 		config.Merge(DefaultConfig())
+		if config.PropagateInstanceTags && config.InstanceTags == nil {
+			config.Merge(InstanceTagsConfig())
+		}
 	}()
 
 	if config.Complete() {
@@ -17,8 +17,11 @@ import (
 	"errors"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
 	"github.com/aws/amazon-ecs-agent/agent/ec2/mocks"
 
 	"github.com/golang/mock/gomock"
@@ -81,6 +84,64 @@ func TestEnvironmentConfig(t *testing.T) {
 	os.Setenv("ECS_CLUSTER", "myCluster")
 	os.Setenv("ECS_RESERVED_PORTS_UDP", "[42,99]")
 	os.Setenv("ECS_RESERVED_MEMORY", "20")
+	os.Setenv("ECS_ALLOWED_DEVICE_PATH_PREFIXES", `["/dev/fuse","/dev/nvidia"]`)
+	os.Setenv("ECS_TASK_RECONCILIATION_INTERVAL", "30m")
+	os.Setenv("ECS_ENGINE_TASK_CLEANUP_WAIT_DURATION", "90m")
+	os.Setenv("ECS_DUMP_ACS_PAYLOADS", "true")
+	os.Setenv("ECS_TELEMETRY_ENDPOINT", "https://tcs.vpce.example.com")
+	os.Setenv("ECS_STATSD_ADDRESS", "127.0.0.1:8125")
+	os.Setenv("ECS_EMF_LOG_GROUP", "ecs-metrics")
+	os.Setenv("ECS_OTLP_ENDPOINT", "http://127.0.0.1:4318")
+	os.Setenv("ECS_ENABLE_TASK_TRACING", "true")
+	os.Setenv("ECS_WEBSOCKET_READ_BUFFER_SIZE", "65536")
+	os.Setenv("ECS_WEBSOCKET_WRITE_BUFFER_SIZE", "16384")
+	os.Setenv("ECS_WEBSOCKET_MAX_MESSAGE_SIZE", "1048576")
+	os.Setenv("ECS_DOWNLOAD_BANDWIDTH_LIMIT", "5242880")
+	os.Setenv("ECS_CONTAINER_DEFAULTS", `{"logDriver":"syslog","dnsServers":["10.0.0.2"]}`)
+	os.Setenv("ECS_DISK_PRESSURE_THRESHOLD", "70")
+	os.Setenv("ECS_DISK_UNHEALTHY_THRESHOLD", "95")
+	os.Setenv("ECS_CGROUP_DRIVER", "systemd")
+	os.Setenv("ECS_PRESTART_HOOK_COMMAND", "/usr/local/bin/scan")
+	os.Setenv("ECS_PRESTART_HOOK_URL", "https://scanner.example.com/check")
+	os.Setenv("ECS_PRESTART_HOOK_TIMEOUT", "10s")
+	os.Setenv("ECS_CONTAINER_STOP_TIMEOUT", "2m")
+	os.Setenv("ECS_DEREGISTER_ON_CLUSTER_CHANGE", "true")
+	os.Setenv("ECS_PROPAGATE_INSTANCE_TAGS", "true")
+	// Later tests load the config, which would read the instance's tags
+	defer os.Unsetenv("ECS_PROPAGATE_INSTANCE_TAGS")
+	os.Setenv("ECS_STATS_ADAPTIVE_SAMPLING", "true")
+	os.Setenv("ECS_DRAIN_ON_SCHEDULED_EVENTS", "true")
+	os.Setenv("ECS_INSTANCE_HEALTH_CHECKS", `["docker","selinux"]`)
+	os.Setenv("ECS_CNI_PLUGINS_PATH", "/opt/cni/bin")
+	os.Setenv("ECS_LOCAL_API_SOCKET", "/var/run/ecs-agent/api.sock")
+	os.Setenv("ECS_ENABLE_TELEMETRY", "true")
+	os.Setenv("ECS_ENABLE_LOCAL_TASK_API", "true")
+	os.Setenv("ECS_ENABLE_CONTAINER_LOGS_API", "true")
+	os.Setenv("ECS_ENABLE_NETWORK_DIAGNOSTICS_API", "true")
+	os.Setenv("ECS_CONTAINER_METADATA_ENDPOINT", "http://172.17.42.1:51678")
+	os.Setenv("ECS_EVENT_STREAM_SOCKET", "/var/run/ecs-agent/events.sock")
+	os.Setenv("ECS_TASK_LOG_DIR", "/var/log/ecs/tasks")
+	os.Setenv("ECS_NUMA_PLACEMENT", "true")
+	os.Setenv("ECS_STATE_ENCRYPTION_KEY_FILE", "/etc/ecs/state.key")
+	os.Setenv("ECS_ENABLE_IMAGE_PLATFORM_SELECTION", "true")
+	os.Setenv("ECS_PREPULL_IMAGES", `["busybox","nginx:1.9"]`)
+	os.Setenv("ECS_RECOVER_CORRUPT_STATE", "true")
+	os.Setenv("ECS_CONTAINER_NAME_TEMPLATE", "{family}-{name}-{taskid:8}")
+	os.Setenv("ECS_TLS_CA_BUNDLE", "/etc/ecs/proxy-ca.pem")
+	os.Setenv("ECS_TLS_MIN_VERSION", "1.2")
+	os.Setenv("ECS_UPDATE_SIGNING_KEY", "/etc/ecs/update-key.pem")
+	os.Setenv("ECS_STATS_SAMPLING_CONTAINER_THRESHOLD", "20")
+	os.Setenv("ECS_STATS_CPU_BUDGET", "10")
+	os.Setenv("ECS_STATS_EXCLUDE_LABELS", `["ecs.sidecar=true"]`)
+	os.Setenv("ECS_STATS_EXCLUDE_CONTAINER_NAMES", `["log-*","pause"]`)
+	os.Setenv("ECS_STATS_EXCLUDE_TASK_FAMILIES", `["daemon"]`)
+	os.Setenv("ECS_PRESTART_HOOK_FAIL_OPEN", "true")
+	os.Setenv("ECS_TASK_HOOK_COMMAND", "/usr/local/bin/task-hook")
+	os.Setenv("ECS_TASK_HOOK_URL", "https://hooks.example.com/task")
+	os.Setenv("ECS_TASK_HOOK_TIMEOUT", "5s")
+	os.Setenv("ECS_TASK_HOOK_FAIL_OPEN", "true")
+	os.Setenv("ECS_IMAGE_VERIFIER", "cosign")
+	os.Setenv("ECS_IMAGE_VERIFICATION_KEY", "/etc/ecs/cosign.pub")
 
 	conf := EnvironmentConfig()
 	if conf.Cluster != "myCluster" {
@@ -95,6 +156,133 @@ func TestEnvironmentConfig(t *testing.T) {
 	if conf.ReservedMemory != 20 {
 		t.Error("Wrong value for ReservedMemory", conf.ReservedMemory)
 	}
+	if len(conf.AllowedDevicePathPrefixes) != 2 || conf.AllowedDevicePathPrefixes[1] != "/dev/nvidia" {
+		t.Error("Wrong value for AllowedDevicePathPrefixes", conf.AllowedDevicePathPrefixes)
+	}
+	if conf.TaskReconciliationInterval != 30*time.Minute {
+		t.Error("Wrong value for TaskReconciliationInterval", conf.TaskReconciliationInterval)
+	}
+	if conf.TaskCleanupWaitDuration != 90*time.Minute {
+		t.Error("Wrong value for TaskCleanupWaitDuration", conf.TaskCleanupWaitDuration)
+	}
+	if conf.TelemetryEndpoint != "https://tcs.vpce.example.com" {
+		t.Error("Wrong value for TelemetryEndpoint", conf.TelemetryEndpoint)
+	}
+	if conf.WebsocketReadBufferSize != 65536 || conf.WebsocketWriteBufferSize != 16384 || conf.WebsocketMaxMessageSize != 1048576 {
+		t.Error("Wrong value for websocket sizes", conf.WebsocketReadBufferSize, conf.WebsocketWriteBufferSize, conf.WebsocketMaxMessageSize)
+	}
+	if conf.DownloadBandwidthLimit != 5242880 {
+		t.Error("Wrong value for DownloadBandwidthLimit", conf.DownloadBandwidthLimit)
+	}
+	expectedDefaults := &ContainerDefaults{
+		LogDriver:  "syslog",
+		DNSServers: []string{"10.0.0.2"},
+	}
+	if !reflect.DeepEqual(conf.ContainerDefaults, expectedDefaults) {
+		t.Error("Wrong value for ContainerDefaults", conf.ContainerDefaults)
+	}
+	if conf.StatsDAddress != "127.0.0.1:8125" || conf.EMFLogGroup != "ecs-metrics" || conf.OTLPEndpoint != "http://127.0.0.1:4318" {
+		t.Error("Wrong value for metrics publishers", conf.StatsDAddress, conf.EMFLogGroup, conf.OTLPEndpoint)
+	}
+	if !conf.TaskTracing {
+		t.Error("Wrong value for TaskTracing")
+	}
+	if conf.CgroupDriver != "systemd" {
+		t.Error("Wrong value for CgroupDriver", conf.CgroupDriver)
+	}
+	if conf.PreStartHookCommand != "/usr/local/bin/scan" || conf.PreStartHookURL != "https://scanner.example.com/check" {
+		t.Error("Wrong value for pre-start hooks", conf.PreStartHookCommand, conf.PreStartHookURL)
+	}
+	if conf.PreStartHookTimeout != 10*time.Second || !conf.PreStartHookFailOpen {
+		t.Error("Wrong value for pre-start hook policy", conf.PreStartHookTimeout, conf.PreStartHookFailOpen)
+	}
+	if conf.TaskHookCommand != "/usr/local/bin/task-hook" || conf.TaskHookURL != "https://hooks.example.com/task" {
+		t.Error("Wrong value for task hooks", conf.TaskHookCommand, conf.TaskHookURL)
+	}
+	if conf.TaskHookTimeout != 5*time.Second || !conf.TaskHookFailOpen {
+		t.Error("Wrong value for task hook policy", conf.TaskHookTimeout, conf.TaskHookFailOpen)
+	}
+	if conf.ImageVerifier != "cosign" || conf.ImageVerificationKey != "/etc/ecs/cosign.pub" {
+		t.Error("Wrong value for image verification", conf.ImageVerifier, conf.ImageVerificationKey)
+	}
+	if conf.DockerStopTimeout != 2*time.Minute {
+		t.Error("Wrong value for DockerStopTimeout", conf.DockerStopTimeout)
+	}
+	if !conf.DeregisterOnClusterChange {
+		t.Error("Wrong value for DeregisterOnClusterChange")
+	}
+	if !conf.PropagateInstanceTags {
+		t.Error("Wrong value for PropagateInstanceTags")
+	}
+	if !conf.StatsAdaptiveSampling || conf.StatsSamplingContainerThreshold != 20 || conf.StatsCPUBudget != 10 {
+		t.Error("Wrong value for adaptive stats sampling", conf.StatsAdaptiveSampling, conf.StatsSamplingContainerThreshold, conf.StatsCPUBudget)
+	}
+	if len(conf.StatsExcludeLabels) != 1 || len(conf.StatsExcludeContainerNames) != 2 || conf.StatsExcludeContainerNames[1] != "pause" || len(conf.StatsExcludeTaskFamilies) != 1 {
+		t.Error("Wrong value for stats exclusions", conf.StatsExcludeLabels, conf.StatsExcludeContainerNames, conf.StatsExcludeTaskFamilies)
+	}
+	if !conf.DrainOnScheduledEvents {
+		t.Error("Wrong value for DrainOnScheduledEvents")
+	}
+	if len(conf.InstanceHealthChecks) != 2 || conf.InstanceHealthChecks[1] != "selinux" || conf.CNIPluginsPath != "/opt/cni/bin" {
+		t.Error("Wrong value for instance health checks", conf.InstanceHealthChecks, conf.CNIPluginsPath)
+	}
+	if conf.LocalAPISocket != "/var/run/ecs-agent/api.sock" {
+		t.Error("Wrong value for LocalAPISocket", conf.LocalAPISocket)
+	}
+	if !conf.TelemetryEnabled {
+		t.Error("Wrong value for TelemetryEnabled")
+	}
+	if !conf.LocalTaskAPIEnabled {
+		t.Error("Wrong value for LocalTaskAPIEnabled")
+	}
+	if !conf.ContainerLogsAPIEnabled {
+		t.Error("Wrong value for ContainerLogsAPIEnabled")
+	}
+	if !conf.NetworkDiagnosticsAPIEnabled {
+		t.Error("Wrong value for NetworkDiagnosticsAPIEnabled")
+	}
+	if conf.ContainerMetadataEndpoint != "http://172.17.42.1:51678" {
+		t.Error("Wrong value for ContainerMetadataEndpoint", conf.ContainerMetadataEndpoint)
+	}
+	if conf.EventStreamSocket != "/var/run/ecs-agent/events.sock" {
+		t.Error("Wrong value for EventStreamSocket", conf.EventStreamSocket)
+	}
+	if conf.TaskLogDir != "/var/log/ecs/tasks" {
+		t.Error("Wrong value for TaskLogDir", conf.TaskLogDir)
+	}
+	if !conf.NUMAPlacement {
+		t.Error("Wrong value for NUMAPlacement")
+	}
+	if conf.StateEncryptionKeyFile != "/etc/ecs/state.key" {
+		t.Error("Wrong value for StateEncryptionKeyFile", conf.StateEncryptionKeyFile)
+	}
+	if !conf.ImagePlatformSelectionEnabled {
+		t.Error("Wrong value for ImagePlatformSelectionEnabled")
+	}
+	if len(conf.PrePullImages) != 2 || conf.PrePullImages[1] != "nginx:1.9" {
+		t.Error("Wrong value for PrePullImages", conf.PrePullImages)
+	}
+	if !conf.RecoverCorruptState {
+		t.Error("Wrong value for RecoverCorruptState")
+	}
+	if conf.ContainerNameTemplate != "{family}-{name}-{taskid:8}" {
+		t.Error("Wrong value for ContainerNameTemplate", conf.ContainerNameTemplate)
+	}
+	if conf.TLSCABundle != "/etc/ecs/proxy-ca.pem" || conf.TLSMinVersion != "1.2" {
+		t.Error("Wrong value for TLS settings", conf.TLSCABundle, conf.TLSMinVersion)
+	}
+	if conf.UpdateSigningKey != "/etc/ecs/update-key.pem" {
+		t.Error("Wrong value for UpdateSigningKey", conf.UpdateSigningKey)
+	}
+	if !conf.DumpACSPayloads {
+		t.Error("Wrong value for DumpACSPayloads")
+	}
+	if conf.DiskPressureThreshold != 70 {
+		t.Error("Wrong value for DiskPressureThreshold", conf.DiskPressureThreshold)
+	}
+	if conf.DiskUnhealthyThreshold != 95 {
+		t.Error("Wrong value for DiskUnhealthyThreshold", conf.DiskUnhealthyThreshold)
+	}
 }
 
 func TestTrimWhitespace(t *testing.T) {
@@ -148,10 +336,79 @@ func TestConfigDefault(t *testing.T) {
 	if len(cfg.ReservedPorts) != 4 {
 		t.Error("Default resered ports set incorrectly")
 	}
-	if cfg.DockerGraphPath != "/var/lib/docker" {
-		t.Error("Default docker graph path set incorrectly")
+	if cfg.DockerGraphPath != "" {
+		t.Error("Expected the docker graph path to be detected by default")
 	}
 	if cfg.ReservedMemory != 0 {
 		t.Error("Default reserved memory set incorrectly")
 	}
+	if len(cfg.AllowedDevicePathPrefixes) != 1 || cfg.AllowedDevicePathPrefixes[0] != "/dev/" {
+		t.Error("Default allowed device path prefixes set incorrectly")
+	}
+	if cfg.EFSMountDir != "/var/lib/ecs/volumes" {
+		t.Error("Default EFS mount dir set incorrectly")
+	}
+	if cfg.ImagePullConcurrency != 4 {
+		t.Error("Default image pull concurrency set incorrectly")
+	}
+	if cfg.TaskReconciliationInterval != 10*time.Minute {
+		t.Error("Default task reconciliation interval set incorrectly")
+	}
+	if cfg.TaskCleanupWaitDuration != 3*time.Hour {
+		t.Error("Default task cleanup wait duration set incorrectly")
+	}
+	if cfg.DiskPressureThreshold != 85 {
+		t.Error("Default disk pressure threshold set incorrectly")
+	}
+	if cfg.PreStartHookTimeout != 30*time.Second {
+		t.Error("Default pre-start hook timeout set incorrectly")
+	}
+	if cfg.TaskHookTimeout != 30*time.Second {
+		t.Error("Default task hook timeout set incorrectly")
+	}
+	if cfg.DockerStopTimeout != 30*time.Second {
+		t.Error("Default docker stop timeout set incorrectly")
+	}
+	if cfg.StatsAdaptiveSampling || cfg.StatsSamplingContainerThreshold != 50 || cfg.StatsCPUBudget != 5 {
+		t.Error("Default adaptive stats sampling set incorrectly")
+	}
+	if cfg.CNIPluginsPath != "/amazon-ecs-cni-plugins" {
+		t.Error("Default CNI plugins path set incorrectly")
+	}
+	if cfg.DiskUnhealthyThreshold != 0 {
+		t.Error("Disk unhealthy threshold should be disabled by default")
+	}
+}
+
+func TestInstanceTagsConfig(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockEc2Metadata := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	ec2MetadataClient = mockEc2Metadata
+
+	longValue := strings.Repeat("v", maxTagValueLength+1)
+	mockEc2Metadata.EXPECT().ReadResource(ec2.INSTANCE_TAGS_RESOURCE).Return([]byte("team\naws:autoscaling:groupName\nCost Center\nbad#key\nlong"), nil)
+	mockEc2Metadata.EXPECT().ReadResource(ec2.INSTANCE_TAGS_RESOURCE+"/team").Return([]byte("payments"), nil)
+	mockEc2Metadata.EXPECT().ReadResource(ec2.INSTANCE_TAGS_RESOURCE+"/aws:autoscaling:groupName").Return([]byte("asg"), nil)
+	mockEc2Metadata.EXPECT().ReadResource(ec2.INSTANCE_TAGS_RESOURCE+"/Cost Center").Return([]byte("42"), nil)
+	mockEc2Metadata.EXPECT().ReadResource(ec2.INSTANCE_TAGS_RESOURCE+"/bad#key").Return([]byte("x"), nil)
+	mockEc2Metadata.EXPECT().ReadResource(ec2.INSTANCE_TAGS_RESOURCE+"/long").Return([]byte(longValue), nil)
+
+	cfg := InstanceTagsConfig()
+	expected := map[string]string{"team": "payments", "Cost Center": "42"}
+	if !reflect.DeepEqual(cfg.InstanceTags, expected) {
+		t.Error("Expected only the valid tags", cfg.InstanceTags)
+	}
+}
+
+func TestInstanceTagsConfigUnreadable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockEc2Metadata := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	ec2MetadataClient = mockEc2Metadata
+
+	mockEc2Metadata.EXPECT().ReadResource(ec2.INSTANCE_TAGS_RESOURCE).Return(nil, errors.New("tags not allowed in metadata"))
+	if cfg := InstanceTagsConfig(); cfg.InstanceTags != nil {
+		t.Error("Expected no tags", cfg.InstanceTags)
+	}
 }
@@ -0,0 +1,86 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
+)
+
+const (
+	// reservedTagPrefix begins the keys of tags which only AWS may set
+	reservedTagPrefix = "aws:"
+
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// validTagCharacters matches the characters ECS accepts in tag keys and
+// values
+var validTagCharacters = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// InstanceTagsConfig returns a config with the instance's tags, read from the
+// instance metadata service. Tags which ECS would refuse are dropped with a
+// warning, so that they can't fail registration.
+func InstanceTagsConfig() Config {
+	tags, err := readInstanceTags()
+	if err != nil {
+		log.Warn("Unable to read instance tags; registering without them", "err", err)
+		return Config{}
+	}
+	return Config{InstanceTags: validInstanceTags(tags)}
+}
+
+func readInstanceTags() (map[string]string, error) {
+	keys, err := ec2MetadataClient.ReadResource(ec2.INSTANCE_TAGS_RESOURCE)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string)
+	for _, key := range strings.Split(string(keys), "\n") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		value, err := ec2MetadataClient.ReadResource(ec2.INSTANCE_TAGS_RESOURCE + "/" + key)
+		if err != nil {
+			return nil, err
+		}
+		tags[key] = string(value)
+	}
+	return tags, nil
+}
+
+// validInstanceTags returns the tags which may be applied to the container
+// instance. Tags in the reserved aws: namespace, and tags whose key or value
+// is too long or has characters ECS does not accept, are dropped.
+func validInstanceTags(tags map[string]string) map[string]string {
+	valid := make(map[string]string, len(tags))
+	for key, value := range tags {
+		switch {
+		case strings.HasPrefix(key, reservedTagPrefix):
+			log.Debug("Not applying reserved instance tag", "key", key)
+		case key == "" || utf8.RuneCountInString(key) > maxTagKeyLength || !validTagCharacters.MatchString(key):
+			log.Warn("Dropping instance tag with an invalid key", "key", key)
+		case utf8.RuneCountInString(value) > maxTagValueLength || !validTagCharacters.MatchString(value):
+			log.Warn("Dropping instance tag with an invalid value", "key", key)
+		default:
+			valid[key] = value
+		}
+	}
+	return valid
+}
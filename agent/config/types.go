@@ -13,7 +13,10 @@
 
 package config
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Config struct {
 	// DEPRECATED
@@ -28,6 +31,10 @@ type Config struct {
 	// make calls against. If this value is not set, it will default to the
 	// endpoint for your current AWSRegion
 	APIEndpoint string `trim:"true"`
+	// TelemetryEndpoint is the endpoint, such as a VPC endpoint, at which the
+	// agent connects to the telemetry service. If this value is not set, the
+	// endpoint is discovered through the APIEndpoint.
+	TelemetryEndpoint string `trim:"true"`
 	// DockerEndpoint is the address the agent will attempt to connect to the
 	// Docker daemon at. This should have the same value as "DOCKER_HOST"
 	// normally would to interact with the daemon. It defaults to
@@ -67,15 +74,297 @@ type Config struct {
 	// within the container in order for the external updating process to
 	// correctly handle them.
 	UpdateDownloadDir string
+	// UpdateSigningKey is a PEM encoded RSA or ECDSA public key. If set, each
+	// update must have a detached signature, made with its private key, of its
+	// sha256 checksum, which is downloaded from the update's location with
+	// ".sig" appended.
+	UpdateSigningKey string
+	// DownloadBandwidthLimit caps the rate, in bytes per second, at which
+	// artifacts, such as agent updates and container artifacts, are
+	// downloaded. It is unlimited if 0.
+	DownloadBandwidthLimit int64
+
+	// ContainerDefaults are settings applied to each container the agent
+	// creates where its task definition doesn't make them, such as a
+	// fleet-wide log driver or DNS servers
+	ContainerDefaults *ContainerDefaults
 
 	// DisableMetrics configures whether task utilization metrics should be
 	// sent to the ECS telemetry endpoint
 	DisableMetrics bool
 
-	// DockerGraphPath specifies the path for docker graph directory.
+	// TelemetryEnabled opts in to sending task utilization metrics to the
+	// ECS telemetry endpoint, unless DisableMetrics is set. Telemetry can
+	// then be turned off and on again through the local api.
+	TelemetryEnabled bool
+
+	// ImagePlatformSelectionEnabled lets the agent read each image's manifest
+	// list from its registry to pull the manifest for the task's platform,
+	// rather than leaving the docker daemon to pick
+	ImagePlatformSelectionEnabled bool
+
+	// RecoverCorruptState lets the agent start over when its checkpoint file
+	// cannot be parsed, rather than exiting. The file is kept as a backup, the
+	// containers of the agent's tasks are removed, and a new container
+	// instance is registered.
+	RecoverCorruptState bool
+
+	// ContainerNameTemplate names the containers the agent creates, such as
+	// "{family}-{name}-{taskid:8}". The placeholders are the task
+	// definition's {family} and {version}, the container's {name}, the
+	// {taskid} and a {random} suffix; {taskid:8} and the like keep only the
+	// first characters. It must have a {random} or {taskid} placeholder, and
+	// defaults to "ecs-{family}-{version}-{name}-{random}".
+	ContainerNameTemplate string
+
+	// TLSCABundle is a file of PEM encoded CA certificates trusted by the
+	// agent's connections in addition to the system's, such as that of a
+	// TLS-intercepting proxy
+	TLSCABundle string
+
+	// TLSMinVersion is the lowest TLS version the agent's connections accept.
+	// Only "1.2" is supported, as go 1.4 has no TLS 1.3.
+	TLSMinVersion string
+
+	// PrePullImages are pulled when the agent starts, ahead of the tasks
+	// which use them, and are not removed when tasks are cleaned up
+	PrePullImages []string
+
+	// DockerGraphPath specifies the path for docker graph directory. If it is
+	// not set, it is asked of the docker daemon when the agent connects.
 	DockerGraphPath string
 
 	// ReservedMemory specifies the amount of memory (in MB) to reserve for things
 	// other than containers managed by ECS
 	ReservedMemory uint16
+
+	// AllowedDevicePathPrefixes is the list of host path prefixes of devices
+	// which task definitions may map into containers. If not set, it defaults
+	// to ["/dev/"].
+	AllowedDevicePathPrefixes []string
+
+	// EFSMountDir is the directory under which EFS volumes and tmpfs scratch
+	// volumes are mounted. It must be the same path on the host and within
+	// the agent's container, with shared mount propagation. It defaults to
+	// /var/lib/ecs/volumes.
+	EFSMountDir string
+
+	// ImagePullConcurrency is the maximum number of images the agent will
+	// pull at once across all tasks. It defaults to 4.
+	ImagePullConcurrency int
+
+	// TaskReconciliationInterval is how often the agent inspects the
+	// containers of running tasks to catch any missed docker events. It
+	// defaults to 10 minutes.
+	TaskReconciliationInterval time.Duration
+
+	// TaskCleanupWaitDuration is how long a stopped task, and its metadata in
+	// the introspection api, is kept before its containers are removed. It
+	// defaults to 3 hours.
+	TaskCleanupWaitDuration time.Duration
+
+	// DumpACSPayloads logs each task payload received from ACS, with
+	// environment variable values redacted, for debugging
+	DumpACSPayloads bool
+
+	// DiskPressureThreshold is the percentage of the docker graph path's or
+	// data directory's filesystem in use above which the agent cleans up
+	// stopped tasks' containers and images without waiting for
+	// TaskCleanupWaitDuration. It is disabled if 0.
+	DiskPressureThreshold int
+
+	// DiskUnhealthyThreshold is the percentage of either filesystem in use
+	// above which the agent reports itself unhealthy. It is disabled if 0.
+	DiskUnhealthyThreshold int
+
+	// WebsocketReadBufferSize and WebsocketWriteBufferSize are the sizes, in
+	// bytes, of the buffers for the ACS and TCS websocket connections. An
+	// outbound message larger than the write buffer is sent as fragments the
+	// size of the write buffer.
+	WebsocketReadBufferSize  int
+	WebsocketWriteBufferSize int
+
+	// WebsocketMaxMessageSize is the largest message, in bytes, accepted from
+	// ACS or TCS. A larger message closes the connection. It is unlimited if
+	// 0.
+	WebsocketMaxMessageSize int64
+
+	// StatsDAddress is the udp address of a statsd daemon to which container
+	// metrics are also published, such as "127.0.0.1:8125"
+	StatsDAddress string
+
+	// EMFLogGroup is the CloudWatch log group to which container metrics are
+	// also published in embedded metric format, through the CloudWatch agent
+	// listening at EMFAddress. EMFAddress defaults to "127.0.0.1:25888". The
+	// metrics the telemetry service has no fields for, such as the share of
+	// its pids limit a container uses, are only published this way.
+	EMFLogGroup string
+	EMFAddress  string
+
+	// OTLPEndpoint is the address of an OpenTelemetry collector on the host to
+	// which container metrics are also exported over OTLP/HTTP, such as
+	// "http://127.0.0.1:4318"
+	OTLPEndpoint string
+
+	// TaskTracing exports a trace of each task launch, from receiving the
+	// task to its containers running, to the collector at OTLPEndpoint
+	TaskTracing bool
+
+	// CgroupDriver is the cgroup driver the docker daemon uses, "cgroupfs" or
+	// "systemd". It is detected from the daemon if unset.
+	CgroupDriver string
+
+	// PreStartHookCommand is the path of an executable, and PreStartHookURL
+	// the url of a webhook, which is asked whether each container may start
+	// once its image is pulled. Either or both may be set.
+	PreStartHookCommand string
+	PreStartHookURL     string
+
+	// PreStartHookTimeout is how long a pre-start hook may take to answer.
+	// It defaults to 30 seconds.
+	PreStartHookTimeout time.Duration
+
+	// PreStartHookFailOpen starts containers when a pre-start hook fails or
+	// times out rather than answering. By default they are not started.
+	PreStartHookFailOpen bool
+
+	// TaskHookCommand is the path of an executable, and TaskHookURL the url
+	// of a webhook, which is run with the task's metadata before each task's
+	// first container is created and after the task stops, such as to create
+	// and remove per-task users or firewall rules. Either or both may be set.
+	TaskHookCommand string
+	TaskHookURL     string
+
+	// TaskHookTimeout is how long a task hook may take. It defaults to 30
+	// seconds.
+	TaskHookTimeout time.Duration
+
+	// TaskHookFailOpen starts tasks when a pre-start task hook fails or times
+	// out. By default the task is stopped. Post-stop failures are only
+	// logged.
+	TaskHookFailOpen bool
+
+	// ImageVerifier, if set, is the tool used to verify the signature of
+	// each image before creating a container from it: "cosign" or
+	// "notation". Cosign verifies against the public key or key reference in
+	// ImageVerificationKey; notation uses its own trust store and policy.
+	// Neither is in the agent's image.
+	ImageVerifier        string
+	ImageVerificationKey string
+
+	// DockerStopTimeout is how long a container is given to exit after being
+	// asked to stop before it is killed, unless its task definition sets a
+	// stop timeout of its own. It defaults to 30 seconds.
+	DockerStopTimeout time.Duration
+
+	// DeregisterOnClusterChange deregisters the container instance from the
+	// cluster recorded in the checkpoint file when the agent starts configured
+	// for a different cluster. Otherwise the old container instance is left
+	// behind, disconnected, in its cluster.
+	DeregisterOnClusterChange bool
+
+	// PropagateInstanceTags reads the EC2 instance's tags from the instance
+	// metadata service when the config is loaded and applies them to the
+	// container instance when registering, both as tags and as attributes
+	// usable in placement constraints. The instance must allow tags in its
+	// metadata.
+	PropagateInstanceTags bool
+
+	// InstanceTags are the instance tags applied to the container instance.
+	// Tags which ECS would refuse have been dropped.
+	InstanceTags map[string]string
+
+	// StatsAdaptiveSampling lengthens the interval between collections of
+	// container stats when more than StatsSamplingContainerThreshold
+	// containers are running, or when collecting takes more than
+	// StatsCPUBudget percent of a core. Samples are still collected often
+	// enough for every telemetry publish.
+	StatsAdaptiveSampling           bool
+	StatsSamplingContainerThreshold int
+	StatsCPUBudget                  int
+
+	// StatsExcludeLabels, StatsExcludeContainerNames and
+	// StatsExcludeTaskFamilies exclude containers, such as log routers and
+	// other sidecars, from stats collection. Labels are docker labels from
+	// the task definition given as "key" or "key=value"; container names are
+	// shell patterns like "log-*"; task families must match exactly.
+	StatsExcludeLabels         []string
+	StatsExcludeContainerNames []string
+	StatsExcludeTaskFamilies   []string
+
+	// DrainOnScheduledEvents drains the container instance when EC2 schedules
+	// an event for the instance, such as its retirement, so that services
+	// are replaced elsewhere before it goes away
+	DrainOnScheduledEvents bool
+
+	// InstanceHealthChecks names the checks whose results make up the
+	// instance's health, as reported by the introspection healthcheck: any of
+	// "docker", "disk", "selinux" and "cni". It defaults to docker and disk.
+	InstanceHealthChecks []string
+
+	// CNIPluginsPath is the directory the "cni" health check expects CNI
+	// plugins in
+	CNIPluginsPath string
+
+	// LocalAPISocket is the path of a unix socket on which the parts of the
+	// introspection api that change the agent or expose what its tasks run
+	// are served, rather than on the introspection port, which anything that
+	// can reach the instance can use. They are not served if it is empty.
+	LocalAPISocket string
+
+	// LocalTaskAPIEnabled lets tasks be run by posting them to the local
+	// api, without the backend, for developing and debugging the agent. It
+	// must not be enabled in production.
+	LocalTaskAPIEnabled bool
+
+	// ContainerLogsAPIEnabled lets the logs of the agent's containers be read
+	// through the local api, for debugging tasks on instances without
+	// docker access
+	ContainerLogsAPIEnabled bool
+
+	// NetworkDiagnosticsAPIEnabled lets diagnostics, such as pinging a host or
+	// resolving its name, be run in task network namespaces through the local
+	// api. It requires nsenter, the diagnostics' commands and the host's pid
+	// namespace, none of which the agent's image has.
+	NetworkDiagnosticsAPIEnabled bool
+
+	// ContainerMetadataEndpoint is the address of the agent's introspection
+	// api as containers can reach it, such as "http://172.17.42.1:51678". If
+	// set, containers are given ECS_TASK_INTROSPECTION_URI, where they can
+	// find their task in the introspection api's format.
+	ContainerMetadataEndpoint string
+
+	// EventStreamSocket is the path of a unix socket on which task, container
+	// and health events are streamed as json to on-host integrations. The
+	// stream is disabled if it is empty.
+	EventStreamSocket string
+
+	// TaskLogDir is a directory on the host in which each task gets a
+	// directory, named by task id, of links to its containers' json-file
+	// logs, so that log shippers can find a task's logs. The directories are
+	// removed when their tasks are cleaned up. It is disabled if empty.
+	TaskLogDir string
+
+	// NUMAPlacement places each task on a single NUMA node of hosts with more
+	// than one, by limiting its containers to the node's cores, so that the
+	// memory they allocate is local to them. Containers pinned to cores with
+	// cpusetCpus are left where they are pinned.
+	NUMAPlacement bool
+
+	// StateEncryptionKeyFile is a file holding a base64 encoded 256 bit key
+	// the state file is encrypted with. If set, existing plaintext state is
+	// encrypted when it's next saved.
+	StateEncryptionKeyFile string
+}
+
+// ContainerDefaults are instance-level defaults for the containers the agent
+// creates. They are merged under what each container's task definition sets:
+// its own labels win over these, and the log driver and DNS settings only
+// apply to containers without their own.
+type ContainerDefaults struct {
+	LogDriver        string            `json:"logDriver"`
+	LogOptions       map[string]string `json:"logOptions"`
+	Labels           map[string]string `json:"labels"`
+	DNSServers       []string          `json:"dnsServers"`
+	DNSSearchDomains []string          `json:"dnsSearchDomains"`
 }
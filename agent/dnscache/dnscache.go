@@ -0,0 +1,143 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package dnscache provides an in-process cache of DNS lookups for the
+// connections the agent makes to AWS endpoints. The agent resolves the same
+// handful of endpoints repeatedly, and on hosts where VPC DNS is throttled
+// those repeated resolutions can fail.
+package dnscache
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+var log = logger.ForModule("dnscache")
+
+const (
+	// DefaultTTL is how long a successful lookup is cached. The go resolver
+	// does not expose record TTLs, so this acts as an upper bound on them.
+	DefaultTTL = 30 * time.Second
+	// DefaultNegativeTTL is how long a failed lookup is cached
+	DefaultNegativeTTL = 5 * time.Second
+)
+
+// Default is the resolver used by the agent's http and websocket clients
+var Default = NewResolver(DefaultTTL, DefaultNegativeTTL)
+
+type cacheEntry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// Metrics are counters describing the resolver's behavior since creation
+type Metrics struct {
+	Lookups   uint64
+	CacheHits uint64
+	Failures  uint64
+}
+
+// Resolver caches the results of host lookups, including failures
+type Resolver struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	lookupHost  func(host string) ([]string, error)
+
+	lock  sync.Mutex
+	cache map[string]*cacheEntry
+
+	lookups   uint64
+	cacheHits uint64
+	failures  uint64
+}
+
+// NewResolver returns a Resolver which caches successful lookups for ttl and
+// failed lookups for negativeTTL
+func NewResolver(ttl, negativeTTL time.Duration) *Resolver {
+	return &Resolver{
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		lookupHost:  net.LookupHost,
+		cache:       make(map[string]*cacheEntry),
+	}
+}
+
+// LookupHost returns the addresses of the given host, from the cache if a
+// fresh entry is present
+func (r *Resolver) LookupHost(host string) ([]string, error) {
+	atomic.AddUint64(&r.lookups, 1)
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	r.lock.Lock()
+	entry, ok := r.cache[host]
+	r.lock.Unlock()
+	if ok && ttime.Now().Before(entry.expires) {
+		atomic.AddUint64(&r.cacheHits, 1)
+		return entry.addrs, entry.err
+	}
+
+	addrs, err := r.lookupHost(host)
+	if err != nil {
+		atomic.AddUint64(&r.failures, 1)
+		log.Warn("Unable to resolve host", "host", host, "err", err)
+		entry = &cacheEntry{err: err, expires: ttime.Now().Add(r.negativeTTL)}
+	} else {
+		entry = &cacheEntry{addrs: addrs, expires: ttime.Now().Add(r.ttl)}
+	}
+
+	r.lock.Lock()
+	r.cache[host] = entry
+	r.lock.Unlock()
+	return addrs, err
+}
+
+// Dial returns a dial function, suitable for an http.Transport, which
+// resolves hosts through the cache before dialing with the given dialer. Each
+// resolved address is tried in turn until one connects.
+func (r *Resolver) Dial(dialer *net.Dialer) func(network, address string) (net.Conn, error) {
+	return func(network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := r.LookupHost(host)
+		if err != nil {
+			return nil, err
+		}
+		var conn net.Conn
+		for _, addr := range addrs {
+			conn, err = dialer.Dial(network, net.JoinHostPort(addr, port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// Metrics returns a snapshot of the resolver's counters
+func (r *Resolver) Metrics() Metrics {
+	return Metrics{
+		Lookups:   atomic.LoadUint64(&r.lookups),
+		CacheHits: atomic.LoadUint64(&r.cacheHits),
+		Failures:  atomic.LoadUint64(&r.failures),
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dnscache
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+func TestLookupHostCaches(t *testing.T) {
+	testTime := ttime.NewTestTime()
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	resolver := NewResolver(30*time.Second, 5*time.Second)
+	calls := 0
+	resolver.lookupHost = func(host string) ([]string, error) {
+		calls++
+		return []string{"10.0.0.1"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		addrs, err := resolver.LookupHost("ecs.us-west-2.amazonaws.com")
+		if err != nil || len(addrs) != 1 || addrs[0] != "10.0.0.1" {
+			t.Fatal("Unexpected lookup result", addrs, err)
+		}
+	}
+	if calls != 1 {
+		t.Error("Expected a single lookup, got", calls)
+	}
+
+	testTime.Warp(31 * time.Second)
+	resolver.LookupHost("ecs.us-west-2.amazonaws.com")
+	if calls != 2 {
+		t.Error("Expected the entry to expire after its ttl")
+	}
+
+	metrics := resolver.Metrics()
+	if metrics.Lookups != 4 || metrics.CacheHits != 2 || metrics.Failures != 0 {
+		t.Error("Unexpected metrics", metrics)
+	}
+}
+
+func TestLookupHostNegativeCache(t *testing.T) {
+	testTime := ttime.NewTestTime()
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	resolver := NewResolver(30*time.Second, 5*time.Second)
+	calls := 0
+	resolver.lookupHost = func(host string) ([]string, error) {
+		calls++
+		return nil, errors.New("throttled")
+	}
+
+	if _, err := resolver.LookupHost("ecs.us-west-2.amazonaws.com"); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if _, err := resolver.LookupHost("ecs.us-west-2.amazonaws.com"); err == nil {
+		t.Fatal("Expected the cached error")
+	}
+	if calls != 1 {
+		t.Error("Expected the failure to be cached")
+	}
+	testTime.Warp(6 * time.Second)
+	resolver.LookupHost("ecs.us-west-2.amazonaws.com")
+	if calls != 2 {
+		t.Error("Expected the failure to expire after the negative ttl")
+	}
+	if resolver.Metrics().Failures != 2 {
+		t.Error("Expected two failures", resolver.Metrics())
+	}
+}
+
+func TestDialUsesCache(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	resolver := NewResolver(30*time.Second, 5*time.Second)
+	resolver.lookupHost = func(host string) ([]string, error) {
+		return []string{"127.0.0.1"}, nil
+	}
+	conn, err := resolver.Dial(&net.Dialer{})("tcp", "example.invalid:"+port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,373 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package downloader fetches artifacts, such as agent updates, over HTTPS or
+// from S3. Each artifact is verified against its sha256 digest and kept in a
+// local cache keyed by that digest. A download which is interrupted resumes
+// where it left off the next time the artifact is fetched.
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/authv4"
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/authv4/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	utilsync "github.com/aws/amazon-ecs-agent/agent/utils/sync"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+var log = logger.ForModule("downloader")
+
+const (
+	s3Scheme      = "s3"
+	s3ServiceName = "s3"
+
+	// cachePrefix prefixes the digest in the name of a cached artifact
+	cachePrefix = "sha256-"
+	// partialSuffix marks an artifact which is still being downloaded
+	partialSuffix = ".partial"
+)
+
+// maxArtifactSize is the largest artifact which will be downloaded, so that a
+// misbehaving source cannot fill the disk. It is a variable for tests.
+var maxArtifactSize int64 = 1 << 30
+
+// Artifact is a file to download
+type Artifact struct {
+	// Source is an https url, or an S3 location as s3://bucket/key
+	Source string
+	// SHA256 is the hex encoded sha256 digest the artifact must have
+	SHA256 string
+}
+
+// DigestMismatchError is returned when a downloaded artifact does not have
+// the digest it was expected to
+type DigestMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (err DigestMismatchError) Error() string {
+	return "sha256 digest mismatch: expected " + err.Expected + ", got " + err.Actual
+}
+
+// Downloader downloads artifacts into a cache directory
+type Downloader struct {
+	cacheDir string
+	client   *http.Client
+	region   string
+	// s3Signer signs requests for S3 sources; it is nil if the downloader was
+	// given no credentials
+	s3Signer authv4.HttpSigner
+	// bytesPerSecond caps the rate of each download; it is unlimited if 0
+	bytesPerSecond int64
+
+	// locks holds a lock for each digest while it is fetched or pruned, so
+	// that an artifact is only downloaded once
+	locks *utilsync.KeyedMutex
+}
+
+// New returns a downloader which caches artifacts in cacheDir. S3 sources are
+// fetched from the given region with the given credentials, which may be nil
+// if only https sources are used.
+func New(cacheDir string, client *http.Client, region string, credentialProvider credentials.AWSCredentialProvider, bytesPerSecond int64) *Downloader {
+	downloader := &Downloader{
+		cacheDir:       cacheDir,
+		client:         client,
+		region:         region,
+		bytesPerSecond: bytesPerSecond,
+		locks:          utilsync.NewKeyedMutex(),
+	}
+	if credentialProvider != nil {
+		downloader.s3Signer = authv4.NewHttpSigner(region, s3ServiceName, credentialProvider, nil)
+	}
+	return downloader
+}
+
+// Path returns where an artifact with the given digest is cached
+func (d *Downloader) Path(digest string) string {
+	return filepath.Join(d.cacheDir, cachePrefix+strings.ToLower(digest))
+}
+
+// Fetch returns the path of the artifact in the cache, downloading it first
+// if it is not already cached. If the download fails, what was downloaded is
+// kept and the next Fetch of the artifact resumes from there; if the artifact
+// does not match its digest, it is discarded. Artifacts with different
+// digests are fetched concurrently.
+func (d *Downloader) Fetch(artifact Artifact) (string, error) {
+	digest := strings.ToLower(strings.TrimSpace(artifact.SHA256))
+	if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != sha256.Size {
+		return "", errors.New("invalid sha256 digest '" + artifact.SHA256 + "'")
+	}
+
+	d.locks.Lock(digest)
+	defer d.locks.Unlock(digest)
+
+	path := d.Path(digest)
+	if actual, err := fileDigest(path); err == nil {
+		if actual == digest {
+			log.Debug("Artifact already cached", "source", artifact.Source, "path", path)
+			// Its modification time is when it was last used, for Prune
+			now := ttime.Now()
+			os.Chtimes(path, now, now)
+			return path, nil
+		}
+		log.Warn("Discarding corrupt cached artifact", "path", path, "digest", actual)
+		os.Remove(path)
+	}
+
+	if err := os.MkdirAll(d.cacheDir, 0755); err != nil {
+		return "", err
+	}
+	partialPath := path + partialSuffix
+	if err := d.download(artifact.Source, partialPath); err != nil {
+		return "", err
+	}
+
+	actual, err := fileDigest(partialPath)
+	if err != nil {
+		return "", err
+	}
+	if actual != digest {
+		os.Remove(partialPath)
+		return "", DigestMismatchError{Expected: digest, Actual: actual}
+	}
+	if err := os.Rename(partialPath, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Prune removes the cached artifacts, and partial downloads, which have not
+// been fetched within maxAge
+func (d *Downloader) Prune(maxAge time.Duration) {
+	cacheDir, err := os.Open(d.cacheDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("Unable to read artifact cache", "dir", d.cacheDir, "err", err)
+		}
+		return
+	}
+	names, err := cacheDir.Readdirnames(-1)
+	cacheDir.Close()
+	if err != nil {
+		log.Warn("Unable to read artifact cache", "dir", d.cacheDir, "err", err)
+		return
+	}
+	for _, name := range names {
+		if !strings.HasPrefix(name, cachePrefix) {
+			continue
+		}
+		d.pruneArtifact(strings.TrimSuffix(strings.TrimPrefix(name, cachePrefix), partialSuffix), name, maxAge)
+	}
+}
+
+// pruneArtifact removes the cache file name, of the artifact with digest, if
+// it is older than maxAge
+func (d *Downloader) pruneArtifact(digest, name string, maxAge time.Duration) {
+	d.locks.Lock(digest)
+	defer d.locks.Unlock(digest)
+
+	path := filepath.Join(d.cacheDir, name)
+	info, err := os.Stat(path)
+	if err != nil || ttime.Since(info.ModTime()) < maxAge {
+		return
+	}
+	log.Info("Removing unused cached artifact", "path", path)
+	if err := os.Remove(path); err != nil {
+		log.Warn("Unable to remove cached artifact", "path", path, "err", err)
+	}
+}
+
+// download downloads source into the file at path, appending to what it
+// already holds if the source supports ranged requests
+func (d *Downloader) download(source, path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	offset, err := file.Seek(0, os.SEEK_END)
+	if err != nil {
+		return err
+	}
+	if offset > maxArtifactSize {
+		// Left by an earlier, larger, limit; it can never be completed
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if offset, err = file.Seek(0, os.SEEK_SET); err != nil {
+			return err
+		}
+	}
+
+	req, err := d.request(source)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The whole artifact was sent, whether or not a range was asked for
+		if offset > 0 {
+			log.Debug("Source does not support resuming; restarting download", "source", source)
+		}
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, os.SEEK_SET); err != nil {
+			return err
+		}
+		offset = 0
+	case http.StatusPartialContent:
+		start, err := contentRangeStart(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return err
+		}
+		if start != offset {
+			return errors.New("source resumed " + source + " from byte " + strconv.FormatInt(start, 10) + " rather than " + strconv.FormatInt(offset, 10))
+		}
+		log.Info("Resuming download", "source", source, "offset", offset)
+	case http.StatusRequestedRangeNotSatisfiable:
+		// What was downloaded is already the whole artifact; its digest
+		// decides whether it is usable
+		return nil
+	default:
+		return errors.New("unexpected status " + resp.Status + " downloading " + source)
+	}
+
+	remaining := maxArtifactSize - offset
+	if resp.ContentLength > remaining {
+		return tooLargeError(source)
+	}
+
+	// Read one byte more than is allowed to tell whether the body is too large
+	var body io.Reader = io.LimitReader(resp.Body, remaining+1)
+	if d.bytesPerSecond > 0 {
+		body = &throttledReader{reader: body, bytesPerSecond: d.bytesPerSecond, start: ttime.Now()}
+	}
+	written, err := io.Copy(file, body)
+	if err != nil {
+		return err
+	}
+	if written > remaining {
+		// There is nothing worth resuming
+		file.Truncate(0)
+		return tooLargeError(source)
+	}
+	return nil
+}
+
+// tooLargeError is returned when source exceeds maxArtifactSize
+func tooLargeError(source string) error {
+	return errors.New(source + " is larger than the maximum artifact size of " + strconv.FormatInt(maxArtifactSize, 10) + " bytes")
+}
+
+// contentRangeStart returns the first byte of a Content-Range header of the
+// form 'bytes first-last/length'
+func contentRangeStart(contentRange string) (int64, error) {
+	if !strings.HasPrefix(contentRange, "bytes ") {
+		return 0, errors.New("invalid Content-Range '" + contentRange + "'")
+	}
+	byteRange := strings.TrimPrefix(contentRange, "bytes ")
+	dash := strings.Index(byteRange, "-")
+	if dash < 0 {
+		return 0, errors.New("invalid Content-Range '" + contentRange + "'")
+	}
+	start, err := strconv.ParseInt(byteRange[:dash], 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid Content-Range '" + contentRange + "'")
+	}
+	return start, nil
+}
+
+// request returns a request for the source, signed if it is in S3
+func (d *Downloader) request(source string) (*http.Request, error) {
+	sourceURL, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if sourceURL.Scheme != s3Scheme {
+		return http.NewRequest("GET", source, nil)
+	}
+
+	if d.s3Signer == nil {
+		return nil, errors.New("no credentials to download " + source)
+	}
+	key := strings.TrimPrefix(sourceURL.Path, "/")
+	if sourceURL.Host == "" || key == "" {
+		return nil, errors.New("invalid S3 location " + source + "; expected s3://bucket/key")
+	}
+	req, err := http.NewRequest("GET", "https://"+sourceURL.Host+".s3."+d.region+".amazonaws.com/"+key, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.s3Signer.SignHttpRequest(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// fileDigest returns the hex encoded sha256 digest of the file at path
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// throttledReader reads no faster, on average, than bytesPerSecond
+type throttledReader struct {
+	reader         io.Reader
+	bytesPerSecond int64
+	start          time.Time
+	read           int64
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > r.bytesPerSecond {
+		p = p[:r.bytesPerSecond]
+	}
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	due := time.Duration(float64(r.read) / float64(r.bytesPerSecond) * float64(time.Second))
+	if elapsed := ttime.Since(r.start); elapsed < due {
+		ttime.Sleep(due - elapsed)
+	}
+	return n, err
+}
@@ -0,0 +1,342 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package downloader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/ecs_client/authv4/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+const artifactData = "artifact-data"
+
+func digest(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func setup(t *testing.T) (*Downloader, string) {
+	dir, err := ioutil.TempDir("", "downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(dir, &http.Client{}, "us-west-2", nil, 0), dir
+}
+
+// artifactServer serves artifactData, honoring ranged requests, and records
+// the Range header of each request
+func artifactServer(ranges *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "artifact", time.Time{}, strings.NewReader(artifactData))
+	}))
+}
+
+func TestFetch(t *testing.T) {
+	d, dir := setup(t)
+	defer os.RemoveAll(dir)
+	var ranges []string
+	server := artifactServer(&ranges)
+	defer server.Close()
+
+	artifact := Artifact{Source: server.URL, SHA256: digest(artifactData)}
+	path, err := d.Fetch(artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != d.Path(artifact.SHA256) {
+		t.Error("Expected the artifact to be cached by digest", path)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != artifactData {
+		t.Error("Incorrect data downloaded", string(data))
+	}
+
+	if _, err := d.Fetch(artifact); err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 {
+		t.Error("Expected a cached artifact not to be downloaded again", len(ranges))
+	}
+}
+
+func TestFetchDigestMismatch(t *testing.T) {
+	d, dir := setup(t)
+	defer os.RemoveAll(dir)
+	var ranges []string
+	server := artifactServer(&ranges)
+	defer server.Close()
+
+	_, err := d.Fetch(Artifact{Source: server.URL, SHA256: digest("other-data")})
+	if _, ok := err.(DigestMismatchError); !ok {
+		t.Fatal("Expected a digest mismatch", err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Error("Expected the mismatched artifact to be discarded", files)
+	}
+}
+
+func TestFetchInvalidDigest(t *testing.T) {
+	d, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := d.Fetch(Artifact{Source: "https://example.com/artifact", SHA256: "not a digest"}); err == nil {
+		t.Error("Expected an invalid digest to be rejected")
+	}
+}
+
+func TestFetchResumesPartialDownload(t *testing.T) {
+	d, dir := setup(t)
+	defer os.RemoveAll(dir)
+	var ranges []string
+	server := artifactServer(&ranges)
+	defer server.Close()
+
+	artifact := Artifact{Source: server.URL, SHA256: digest(artifactData)}
+	ioutil.WriteFile(d.Path(artifact.SHA256)+partialSuffix, []byte(artifactData[:5]), 0644)
+
+	path, err := d.Fetch(artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=5-" {
+		t.Error("Expected the download to resume from the partial artifact", ranges)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != artifactData {
+		t.Error("Incorrect data downloaded", string(data))
+	}
+}
+
+func TestFetchRestartsWithoutRangeSupport(t *testing.T) {
+	d, dir := setup(t)
+	defer os.RemoveAll(dir)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(artifactData))
+	}))
+	defer server.Close()
+
+	artifact := Artifact{Source: server.URL, SHA256: digest(artifactData)}
+	ioutil.WriteFile(d.Path(artifact.SHA256)+partialSuffix, []byte("garbage"), 0644)
+
+	path, err := d.Fetch(artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != artifactData {
+		t.Error("Expected the download to restart", string(data))
+	}
+}
+
+func TestFetchKeepsPartialDownloadOnFailure(t *testing.T) {
+	d, dir := setup(t)
+	defer os.RemoveAll(dir)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	artifact := Artifact{Source: server.URL, SHA256: digest(artifactData)}
+	ioutil.WriteFile(d.Path(artifact.SHA256)+partialSuffix, []byte(artifactData[:5]), 0644)
+
+	if _, err := d.Fetch(artifact); err == nil {
+		t.Fatal("Expected an error downloading from an unavailable source")
+	}
+	if data, _ := ioutil.ReadFile(d.Path(artifact.SHA256) + partialSuffix); string(data) != artifactData[:5] {
+		t.Error("Expected the partial artifact to be kept", string(data))
+	}
+}
+
+func TestFetchRejectsOversizedArtifact(t *testing.T) {
+	d, dir := setup(t)
+	defer os.RemoveAll(dir)
+	defer func(size int64) { maxArtifactSize = size }(maxArtifactSize)
+	maxArtifactSize = 5
+	var ranges []string
+	server := artifactServer(&ranges)
+	defer server.Close()
+
+	artifact := Artifact{Source: server.URL, SHA256: digest(artifactData)}
+	if _, err := d.Fetch(artifact); err == nil {
+		t.Fatal("Expected an artifact larger than the maximum to be rejected")
+	}
+	if data, _ := ioutil.ReadFile(d.Path(artifact.SHA256) + partialSuffix); len(data) != 0 {
+		t.Error("Expected nothing to be written", string(data))
+	}
+}
+
+func TestFetchRejectsOversizedArtifactWithoutContentLength(t *testing.T) {
+	d, dir := setup(t)
+	defer os.RemoveAll(dir)
+	defer func(size int64) { maxArtifactSize = size }(maxArtifactSize)
+	maxArtifactSize = 5
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Flushing first sends the body chunked, without a Content-Length
+		w.(http.Flusher).Flush()
+		w.Write([]byte(artifactData))
+	}))
+	defer server.Close()
+
+	artifact := Artifact{Source: server.URL, SHA256: digest(artifactData)}
+	if _, err := d.Fetch(artifact); err == nil {
+		t.Fatal("Expected an artifact larger than the maximum to be rejected")
+	}
+	if data, _ := ioutil.ReadFile(d.Path(artifact.SHA256) + partialSuffix); len(data) != 0 {
+		t.Error("Expected the oversized download to be discarded", string(data))
+	}
+}
+
+func TestFetchRejectsMismatchedContentRange(t *testing.T) {
+	d, dir := setup(t)
+	defer os.RemoveAll(dir)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-12/13")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(artifactData))
+	}))
+	defer server.Close()
+
+	artifact := Artifact{Source: server.URL, SHA256: digest(artifactData)}
+	ioutil.WriteFile(d.Path(artifact.SHA256)+partialSuffix, []byte(artifactData[:5]), 0644)
+
+	if _, err := d.Fetch(artifact); err == nil {
+		t.Fatal("Expected a range not starting at the partial artifact's end to be rejected")
+	}
+	if data, _ := ioutil.ReadFile(d.Path(artifact.SHA256) + partialSuffix); string(data) != artifactData[:5] {
+		t.Error("Expected the partial artifact to be left alone", string(data))
+	}
+}
+
+func TestContentRangeStart(t *testing.T) {
+	if start, err := contentRangeStart("bytes 5-12/13"); err != nil || start != 5 {
+		t.Error("Expected the range to start at 5", start, err)
+	}
+	for _, contentRange := range []string{"", "bytes */13", "items 5-12/13", "bytes x-12/13"} {
+		if _, err := contentRangeStart(contentRange); err == nil {
+			t.Error("Expected an invalid Content-Range to be rejected", contentRange)
+		}
+	}
+}
+
+func TestS3Request(t *testing.T) {
+	d := New("", &http.Client{}, "us-west-2", credentials.NewCredentialProvider("AKID", "SECRET"), 0)
+
+	req, err := d.request("s3://bucket/path/to/artifact.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.String() != "https://bucket.s3.us-west-2.amazonaws.com/path/to/artifact.tar" {
+		t.Error("Wrong url for S3 location", req.URL.String())
+	}
+	if !strings.HasPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		t.Error("Expected the request to be signed", req.Header.Get("Authorization"))
+	}
+
+	if _, err := d.request("s3://bucket"); err == nil {
+		t.Error("Expected an error for an S3 location without a key")
+	}
+}
+
+func TestS3RequestWithoutCredentials(t *testing.T) {
+	d := New("", &http.Client{}, "us-west-2", nil, 0)
+
+	if _, err := d.request("s3://bucket/artifact.tar"); err == nil {
+		t.Error("Expected an error for an S3 location without credentials")
+	}
+}
+
+func TestThrottledReader(t *testing.T) {
+	testTime := ttime.NewTestTime()
+	testTime.LudicrousSpeed(true)
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	start := ttime.Now()
+	reader := &throttledReader{reader: bytes.NewReader(make([]byte, 3000)), bytesPerSecond: 1000, start: start}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil || len(data) != 3000 {
+		t.Fatal("Expected all the data to be read", len(data), err)
+	}
+	if elapsed := ttime.Since(start); elapsed < 3*time.Second {
+		t.Error("Expected reading 3000 bytes at 1000 bytes per second to take 3 seconds", elapsed)
+	}
+}
+
+func TestFetchDifferentDigestsConcurrently(t *testing.T) {
+	d, dir := setup(t)
+	defer os.RemoveAll(dir)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.Write([]byte(strings.TrimPrefix(r.URL.Path, "/")))
+	}))
+	defer server.Close()
+
+	slow := make(chan error, 1)
+	go func() {
+		_, err := d.Fetch(Artifact{Source: server.URL + "/slow", SHA256: digest("slow")})
+		slow <- err
+	}()
+	// The slow download holds only its own digest's lock
+	if _, err := d.Fetch(Artifact{Source: server.URL + "/fast", SHA256: digest("fast")}); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if err := <-slow; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	d, dir := setup(t)
+	defer os.RemoveAll(dir)
+	testTime := ttime.NewTestTime()
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	unused := d.Path(digest("unused"))
+	recent := d.Path(digest("recent"))
+	partial := d.Path(digest("partial")) + partialSuffix
+	other := filepath.Join(dir, "other")
+	for _, path := range []string{unused, recent, partial, other} {
+		ioutil.WriteFile(path, []byte("data"), 0644)
+	}
+	old := testTime.Now().Add(-2 * time.Hour)
+	for _, path := range []string{unused, partial, other} {
+		os.Chtimes(path, old, old)
+	}
+
+	d.Prune(time.Hour)
+	for _, path := range []string{unused, partial} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Error("Expected an unused artifact to be pruned", path, err)
+		}
+	}
+	for _, path := range []string{recent, other} {
+		if _, err := os.Stat(path); err != nil {
+			t.Error("Expected the file to be kept", path, err)
+		}
+	}
+}
@@ -29,6 +29,10 @@ const (
 	INSTANCE_IDENTITY_DOCUMENT_RESOURCE           = "/2014-02-25/dynamic/instance-identity/document"
 	INSTANCE_IDENTITY_DOCUMENT_SIGNATURE_RESOURCE = "/2014-02-25/dynamic/instance-identity/signature"
 	SIGNED_INSTANCE_IDENTITY_DOCUMENT_RESOURCE    = "/2014-02-25/dynamic/instance-identity/pkcs7"
+	INSTANCE_TAGS_RESOURCE                        = "/latest/meta-data/tags/instance"
+	SCHEDULED_EVENTS_RESOURCE                     = "/latest/meta-data/events/maintenance/scheduled"
+	PLACEMENT_GROUP_RESOURCE                      = "/latest/meta-data/placement/group-name"
+	PLACEMENT_PARTITION_RESOURCE                  = "/latest/meta-data/placement/partition-number"
 	EC2_METADATA_REQUEST_TIMEOUT                  = time.Duration(1 * time.Second)
 )
 
@@ -50,6 +54,28 @@ type InstanceIdentityDocument struct {
 	AvailabilityZone string  `json:"availabilityZone"`
 }
 
+// InstancePlacement describes where the instance runs. PlacementGroup is empty
+// unless the instance was launched into a placement group, and
+// PartitionNumber unless that group is a partition placement group.
+type InstancePlacement struct {
+	Region           string
+	AvailabilityZone string
+	PlacementGroup   string
+	PartitionNumber  string
+}
+
+// ScheduledEvent is an event EC2 has scheduled for the instance, such as its
+// retirement or maintenance of its host. Code is e.g. "instance-retirement"
+// or "system-maintenance"; State is "active", "completed" or "canceled".
+type ScheduledEvent struct {
+	Code        string `json:"Code"`
+	Description string `json:"Description"`
+	EventId     string `json:"EventId"`
+	NotBefore   string `json:"NotBefore"`
+	NotAfter    string `json:"NotAfter"`
+	State       string `json:"State"`
+}
+
 type HttpClient interface {
 	Get(string) (*http.Response, error)
 }
@@ -58,6 +84,7 @@ type EC2MetadataClient interface {
 	DefaultCredentials() (*RoleCredentials, error)
 	ReadResource(string) ([]byte, error)
 	InstanceIdentityDocument() (*InstanceIdentityDocument, error)
+	ScheduledEvents() ([]ScheduledEvent, error)
 }
 
 type ec2MetadataClientImpl struct {
@@ -116,6 +143,36 @@ func (c *ec2MetadataClientImpl) InstanceIdentityDocument() (*InstanceIdentityDoc
 	return &iid, nil
 }
 
+func (c *ec2MetadataClientImpl) ScheduledEvents() ([]ScheduledEvent, error) {
+	rawEvents, err := c.ReadResource(SCHEDULED_EVENTS_RESOURCE)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []ScheduledEvent
+	err = json.Unmarshal(rawEvents, &events)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ReadInstancePlacement reads the placement of the instance described by iid.
+// Instances outside of a placement group have no group or partition to read,
+// so failing to read them is not an error.
+func ReadInstancePlacement(client EC2MetadataClient, iid *InstanceIdentityDocument) *InstancePlacement {
+	placement := &InstancePlacement{Region: iid.Region, AvailabilityZone: iid.AvailabilityZone}
+	group, err := client.ReadResource(PLACEMENT_GROUP_RESOURCE)
+	if err != nil {
+		return placement
+	}
+	placement.PlacementGroup = strings.TrimSpace(string(group))
+	if partition, err := client.ReadResource(PLACEMENT_PARTITION_RESOURCE); err == nil {
+		placement.PartitionNumber = strings.TrimSpace(string(partition))
+	}
+	return placement
+}
+
 func (c *ec2MetadataClientImpl) ResourceServiceUrl(path string) string {
 	// TODO, override EC2_METADATA_SERVICE_URL based on the environment
 	return EC2_METADATA_SERVICE_URL + path
@@ -129,6 +186,9 @@ func (c *ec2MetadataClientImpl) ReadResource(path string) ([]byte, error) {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("Unexpected status reading " + path + ": " + resp.Status)
+	}
 
 	return ioutil.ReadAll(resp.Body)
 }
@@ -153,3 +213,13 @@ func GetInstanceIdentityDocument() (*InstanceIdentityDocument, error) {
 func DefaultCredentials() (*RoleCredentials, error) {
 	return DefaultClient.DefaultCredentials()
 }
+
+// GetInstancePlacement returns the instance's placement read using the default
+// client
+func GetInstancePlacement() (*InstancePlacement, error) {
+	iid, err := DefaultClient.InstanceIdentityDocument()
+	if err != nil {
+		return nil, err
+	}
+	return ReadInstancePlacement(DefaultClient, iid), nil
+}
@@ -62,10 +62,22 @@ var testInstanceIdentityDoc = `{
   "architecture" : "x86_64"
 }`
 
+var testScheduledEvents = `[
+  {
+    "NotBefore" : "21 Jan 2019 09:00:43 GMT",
+    "Code" : "instance-retirement",
+    "Description" : "The instance is running on degraded hardware",
+    "EventId" : "instance-event-0d59937288b749b32",
+    "NotAfter" : "21 Jan 2019 09:17:23 GMT",
+    "State" : "active"
+  }
+]`
+
 var test_response = map[string]string{
 	test_client.ResourceServiceUrl(SECURITY_CREDENTIALS_RESOURCE):                  TEST_ROLE_NAME,
 	test_client.ResourceServiceUrl(SECURITY_CREDENTIALS_RESOURCE + TEST_ROLE_NAME): string(ignoreError(json.Marshal(MakeTestRoleCredentials())).([]byte)),
 	test_client.ResourceServiceUrl(INSTANCE_IDENTITY_DOCUMENT_RESOURCE):            testInstanceIdentityDoc,
+	test_client.ResourceServiceUrl(SCHEDULED_EVENTS_RESOURCE):                      testScheduledEvents,
 }
 
 type testHttpClient struct{}
@@ -105,3 +117,55 @@ func TestGetInstanceIdentityDoc(t *testing.T) {
 		t.Error("Wrong region; expected us-east-1 but got " + doc.Region)
 	}
 }
+
+func TestScheduledEvents(t *testing.T) {
+	events, err := test_client.ScheduledEvents()
+	if err != nil {
+		t.Fatal("Expected to be able to get scheduled events", err)
+	}
+	if len(events) != 1 {
+		t.Fatal("Expected 1 scheduled event, got", len(events))
+	}
+	if events[0].Code != "instance-retirement" || events[0].State != "active" || events[0].EventId != "instance-event-0d59937288b749b32" {
+		t.Error("Wrong scheduled event", events[0])
+	}
+}
+
+type notFoundHttpClient struct{}
+
+func (c notFoundHttpClient) Get(url string) (*http.Response, error) {
+	return &http.Response{
+		Status:     "404 Not Found",
+		StatusCode: 404,
+		Proto:      "HTTP/1.0",
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("Not Found"))),
+	}, nil
+}
+
+func TestReadResourceNotFound(t *testing.T) {
+	client := ec2MetadataClientImpl{client: notFoundHttpClient{}}
+	_, err := client.ReadResource(INSTANCE_TAGS_RESOURCE)
+	if err == nil {
+		t.Error("Expected an error for a resource which is not found")
+	}
+}
+
+func TestReadInstancePlacement(t *testing.T) {
+	doc, err := test_client.InstanceIdentityDocument()
+	if err != nil {
+		t.Fatal("Expected to be able to get doc")
+	}
+	placement := ReadInstancePlacement(&test_client, doc)
+	if *placement != (InstancePlacement{Region: "us-east-1", AvailabilityZone: "us-east-1a"}) {
+		t.Error("Expected no placement group outside of one, got", placement)
+	}
+
+	test_response[test_client.ResourceServiceUrl(PLACEMENT_GROUP_RESOURCE)] = "analytics\n"
+	test_response[test_client.ResourceServiceUrl(PLACEMENT_PARTITION_RESOURCE)] = "3"
+	defer delete(test_response, test_client.ResourceServiceUrl(PLACEMENT_GROUP_RESOURCE))
+	defer delete(test_response, test_client.ResourceServiceUrl(PLACEMENT_PARTITION_RESOURCE))
+	placement = ReadInstancePlacement(&test_client, doc)
+	if placement.PlacementGroup != "analytics" || placement.PartitionNumber != "3" {
+		t.Error("Wrong placement group", placement)
+	}
+}
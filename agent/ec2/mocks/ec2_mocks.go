@@ -74,3 +74,14 @@ func (_m *MockEC2MetadataClient) ReadResource(_param0 string) ([]byte, error) {
 func (_mr *_MockEC2MetadataClientRecorder) ReadResource(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ReadResource", arg0)
 }
+
+func (_m *MockEC2MetadataClient) ScheduledEvents() ([]ec2.ScheduledEvent, error) {
+	ret := _m.ctrl.Call(_m, "ScheduledEvents")
+	ret0, _ := ret[0].([]ec2.ScheduledEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockEC2MetadataClientRecorder) ScheduledEvents() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ScheduledEvents")
+}
@@ -819,6 +819,37 @@ func (c *ECS) UpdateContainerAgent(input *UpdateContainerAgentInput) (output *Up
 
 var opUpdateContainerAgent *aws.Operation
 
+// UpdateContainerInstancesStateRequest generates a request for the UpdateContainerInstancesState operation.
+func (c *ECS) UpdateContainerInstancesStateRequest(input *UpdateContainerInstancesStateInput) (req *aws.Request, output *UpdateContainerInstancesStateOutput) {
+	oprw.Lock()
+	defer oprw.Unlock()
+
+	if opUpdateContainerInstancesState == nil {
+		opUpdateContainerInstancesState = &aws.Operation{
+			Name:       "UpdateContainerInstancesState",
+			HTTPMethod: "POST",
+			HTTPPath:   "/",
+		}
+	}
+
+	req = c.newRequest(opUpdateContainerInstancesState, input, output)
+	output = &UpdateContainerInstancesStateOutput{}
+	req.Data = output
+	return
+}
+
+// Modifies the status of container instances. Setting a container instance
+// to DRAINING stops new tasks being placed on it and replaces the tasks of
+// services running on it.
+func (c *ECS) UpdateContainerInstancesState(input *UpdateContainerInstancesStateInput) (output *UpdateContainerInstancesStateOutput, err error) {
+	req, out := c.UpdateContainerInstancesStateRequest(input)
+	output = out
+	err = req.Send()
+	return
+}
+
+var opUpdateContainerInstancesState *aws.Operation
+
 // UpdateServiceRequest generates a request for the UpdateService operation.
 func (c *ECS) UpdateServiceRequest(input *UpdateServiceInput) (req *aws.Request, output *UpdateServiceOutput) {
 	oprw.Lock()
@@ -862,6 +893,23 @@ func (c *ECS) UpdateService(input *UpdateServiceInput) (output *UpdateServiceOut
 
 var opUpdateService *aws.Operation
 
+// An attribute of a container instance, which can be used in task placement
+// constraints.
+type Attribute struct {
+	// The name of the attribute. Up to 128 letters, numbers, hyphens, underscores,
+	// periods, and forward slashes are allowed.
+	Name *string `locationName:"name" type:"string" required:"true"`
+
+	// The value of the attribute.
+	Value *string `locationName:"value" type:"string"`
+
+	metadataAttribute `json:"-", xml:"-"`
+}
+
+type metadataAttribute struct {
+	SDKShapeTraits bool `type:"structure"`
+}
+
 // A regional grouping of one or more container instances on which you can run
 // task requests. Each account receives a default cluster the first time you
 // use the Amazon ECS service, but you may also create other clusters. Clusters
@@ -1906,6 +1954,9 @@ type metadataPortMapping struct {
 }
 
 type RegisterContainerInstanceInput struct {
+	// The container instance attributes that this container instance supports.
+	Attributes []*Attribute `locationName:"attributes" type:"list"`
+
 	// The short name or full Amazon Resource Name (ARN) of the cluster that you
 	// want to register your container instance with. If you do not specify a cluster,
 	// the default cluster is assumed..
@@ -1915,6 +1966,9 @@ type RegisterContainerInstanceInput struct {
 
 	InstanceIdentityDocumentSignature *string `locationName:"instanceIdentityDocumentSignature" type:"string"`
 
+	// The tags to apply to the container instance.
+	Tags []*Tag `locationName:"tags" type:"list"`
+
 	TotalResources []*Resource `locationName:"totalResources" type:"list"`
 
 	VersionInfo *VersionInfo `locationName:"versionInfo" type:"structure"`
@@ -2283,6 +2337,21 @@ type metadataSubmitTaskStateChangeOutput struct {
 	SDKShapeTraits bool `type:"structure"`
 }
 
+// A key and value pair used to label a resource.
+type Tag struct {
+	// The key of the tag.
+	Key *string `locationName:"key" type:"string"`
+
+	// The value of the tag.
+	Value *string `locationName:"value" type:"string"`
+
+	metadataTag `json:"-", xml:"-"`
+}
+
+type metadataTag struct {
+	SDKShapeTraits bool `type:"structure"`
+}
+
 type Task struct {
 	// The Amazon Resource Name (ARN) of the of the cluster that hosts the task.
 	ClusterARN *string `locationName:"clusterArn" type:"string"`
@@ -2397,6 +2466,40 @@ type metadataUpdateContainerAgentOutput struct {
 	SDKShapeTraits bool `type:"structure"`
 }
 
+type UpdateContainerInstancesStateInput struct {
+	// The short name or full Amazon Resource Name (ARN) of the cluster that hosts
+	// the container instances. If you do not specify a cluster, the default cluster
+	// is assumed.
+	Cluster *string `locationName:"cluster" type:"string"`
+
+	// A list of container instance UUIDs or full Amazon Resource Name (ARN) entries.
+	ContainerInstances []*string `locationName:"containerInstances" type:"list" required:"true"`
+
+	// The container instance state to update the container instances with: ACTIVE
+	// or DRAINING.
+	Status *string `locationName:"status" type:"string" required:"true"`
+
+	metadataUpdateContainerInstancesStateInput `json:"-", xml:"-"`
+}
+
+type metadataUpdateContainerInstancesStateInput struct {
+	SDKShapeTraits bool `type:"structure"`
+}
+
+type UpdateContainerInstancesStateOutput struct {
+	// The list of container instances.
+	ContainerInstances []*ContainerInstance `locationName:"containerInstances" type:"list"`
+
+	// Any failures associated with the call.
+	Failures []*Failure `locationName:"failures" type:"list"`
+
+	metadataUpdateContainerInstancesStateOutput `json:"-", xml:"-"`
+}
+
+type metadataUpdateContainerInstancesStateOutput struct {
+	SDKShapeTraits bool `type:"structure"`
+}
+
 type UpdateServiceInput struct {
 	// The short name or full Amazon Resource Name (ARN) of the cluster that your
 	// service is running on. If you do not specify a cluster, the default cluster
@@ -0,0 +1,169 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/downloader"
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
+)
+
+const (
+	// artifactDir is the directory under a task's mount directory holding
+	// its containers' artifacts. Like scratchMountDir, it cannot collide with
+	// an EFS volume's mount point.
+	artifactDir = ".artifacts"
+	// artifactCacheDir is the directory under the data directory in which
+	// downloaded artifacts are cached by digest, so that tasks sharing an
+	// artifact download it once
+	artifactCacheDir = "artifacts"
+	// artifactCacheDuration is how long an artifact no task has used since
+	// is kept in the cache
+	artifactCacheDuration   = 24 * time.Hour
+	artifactDownloadTimeout = 15 * time.Minute
+	defaultArtifactMode     = 0444
+)
+
+// ArtifactError is returned when one of a container's artifacts could not be
+// downloaded into its task
+type ArtifactError struct {
+	msg string
+}
+
+func (err ArtifactError) Error() string     { return err.msg }
+func (err ArtifactError) ErrorName() string { return "ArtifactError" }
+func (err ArtifactError) ErrorCode() string { return api.ErrorCodeResourceInitialization }
+
+// newArtifactDownloader returns a downloader which caches artifacts in the
+// data directory. It has no credentials: S3 sources would have to be fetched
+// with the task's own, and tasks have none in this agent, so they are refused
+// rather than fetched with the instance's.
+func newArtifactDownloader(cfg *config.Config) *downloader.Downloader {
+	return downloader.New(filepath.Join(cfg.DataDir, artifactCacheDir), httpclient.New(artifactDownloadTimeout, false), cfg.AWSRegion, nil, cfg.DownloadBandwidthLimit)
+}
+
+// downloadArtifacts downloads each of the container's artifacts which is not
+// yet downloaded into the task's artifact directory. They are bound into the
+// container by its host config.
+func (engine *DockerTaskEngine) downloadArtifacts(task *api.Task, container *api.Container) api.NamedError {
+	for i := range container.Artifacts {
+		artifact := &container.Artifacts[i]
+		if artifact.HostPath != "" {
+			continue
+		}
+		if !filepath.IsAbs(artifact.ContainerPath) {
+			return ArtifactError{"Invalid artifact path '" + artifact.ContainerPath + "': it must be absolute"}
+		}
+		if strings.HasPrefix(artifact.Source, "s3://") {
+			return ArtifactError{"Unable to download artifact for " + artifact.ContainerPath + ": S3 sources need task credentials, which this agent does not support; use a presigned https url"}
+		}
+		mode, uid, gid, err := parseArtifactPermissions(artifact)
+		if err != nil {
+			return ArtifactError{"Invalid permissions for artifact " + artifact.ContainerPath + ": " + err.Error()}
+		}
+
+		log.Info("Downloading artifact", "task", task.Arn, "container", container.Name, "source", artifact.Source, "path", artifact.ContainerPath)
+		cached, err := engine.artifacts.Fetch(downloader.Artifact{Source: artifact.Source, SHA256: artifact.SHA256})
+		if err != nil {
+			return ArtifactError{"Unable to download artifact for " + artifact.ContainerPath + ": " + err.Error()}
+		}
+		target := filepath.Join(engine.cfg.EFSMountDir, taskIDFromArn(task.Arn), artifactDir, container.Name, strconv.Itoa(i))
+		if err := installArtifact(cached, target, mode, uid, gid); err != nil {
+			return ArtifactError{"Unable to install artifact for " + artifact.ContainerPath + ": " + err.Error()}
+		}
+		artifact.HostPath = target
+	}
+	return nil
+}
+
+// parseArtifactPermissions returns the mode and owner the artifact is
+// installed with. A uid or gid of -1 leaves that of the agent in place.
+func parseArtifactPermissions(artifact *api.ContainerArtifact) (os.FileMode, int, int, error) {
+	mode := os.FileMode(defaultArtifactMode)
+	if artifact.Mode != "" {
+		parsed, err := strconv.ParseUint(artifact.Mode, 8, 32)
+		if err != nil || parsed > 0777 {
+			return 0, 0, 0, errors.New("mode '" + artifact.Mode + "' is not an octal file mode such as 0440")
+		}
+		mode = os.FileMode(parsed)
+	}
+
+	uid, gid := -1, -1
+	if artifact.Owner != "" {
+		parts := strings.SplitN(artifact.Owner, ":", 2)
+		ids := make([]int, len(parts))
+		for i, part := range parts {
+			id, err := strconv.Atoi(part)
+			if err != nil || id < 0 {
+				return 0, 0, 0, errors.New("owner '" + artifact.Owner + "' is not a numeric uid or uid:gid")
+			}
+			ids[i] = id
+		}
+		uid = ids[0]
+		if len(ids) > 1 {
+			gid = ids[1]
+		}
+	}
+	return mode, uid, gid, nil
+}
+
+// installArtifact copies the cached artifact to target, which is given the
+// mode and owner. The task gets its own copy so that its permissions don't
+// affect other tasks sharing the artifact.
+func installArtifact(cached, target string, mode os.FileMode, uid, gid int) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	source, err := os.Open(cached)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, source); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if uid != -1 || gid != -1 {
+		if err := os.Chown(target, uid, gid); err != nil {
+			return err
+		}
+	}
+	return os.Chmod(target, mode)
+}
+
+// removeArtifacts removes the task's downloaded artifacts, and those in the
+// cache no task has used for artifactCacheDuration
+func (engine *DockerTaskEngine) removeArtifacts(task *api.Task) {
+	dir := filepath.Join(engine.cfg.EFSMountDir, taskIDFromArn(task.Arn), artifactDir)
+	if err := os.RemoveAll(dir); err != nil {
+		log.Warn("Unable to remove task artifacts", "task", task.Arn, "dir", dir, "err", err)
+	}
+	engine.artifacts.Prune(artifactCacheDuration)
+}
@@ -0,0 +1,118 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+)
+
+func TestDownloadArtifacts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("model-data"))
+	}))
+	defer server.Close()
+	checksum := sha256.Sum256([]byte("model-data"))
+
+	engine := NewDockerTaskEngine(&config.Config{DataDir: filepath.Join(dir, "data"), EFSMountDir: filepath.Join(dir, "mnt")})
+	container := &api.Container{
+		Name: "web",
+		Artifacts: []api.ContainerArtifact{{
+			Source:        server.URL + "/model.bin",
+			SHA256:        hex.EncodeToString(checksum[:]),
+			ContainerPath: "/opt/model.bin",
+			Mode:          "0440",
+		}},
+	}
+	task := &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc", Containers: []*api.Container{container}}
+
+	if err := engine.downloadArtifacts(task, container); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "mnt", "abc", ".artifacts", "web", "0")
+	if container.Artifacts[0].HostPath != target {
+		t.Error("Expected the artifact to be downloaded into the task", container.Artifacts[0].HostPath)
+	}
+	if data, _ := ioutil.ReadFile(target); string(data) != "model-data" {
+		t.Error("Incorrect artifact data", string(data))
+	}
+	if info, err := os.Stat(target); err != nil || info.Mode().Perm() != 0440 {
+		t.Error("Expected the artifact to have its mode", info, err)
+	}
+
+	hostConfig, hcErr := task.DockerHostConfig(container, nil)
+	if hcErr != nil {
+		t.Fatal(hcErr)
+	}
+	if !reflect.DeepEqual(hostConfig.Binds, []string{target + ":/opt/model.bin:ro"}) {
+		t.Error("Expected the artifact to be bound read only", hostConfig.Binds)
+	}
+
+	engine.removeArtifacts(task)
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Error("Expected the task's artifacts to be removed", err)
+	}
+}
+
+func TestDownloadArtifactsInvalid(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{})
+
+	for _, artifact := range []api.ContainerArtifact{
+		{ContainerPath: "model.bin", SHA256: "abc"},
+		{ContainerPath: "/model.bin", SHA256: "abc", Mode: "rw"},
+		{ContainerPath: "/model.bin", SHA256: "abc", Owner: "nobody"},
+		{ContainerPath: "/model.bin", Source: "https://example.com/model.bin", SHA256: "not a digest"},
+		{ContainerPath: "/model.bin", Source: "s3://bucket/model.bin", SHA256: strings.Repeat("a", 64)},
+	} {
+		container := &api.Container{Name: "web", Artifacts: []api.ContainerArtifact{artifact}}
+		task := &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc", Containers: []*api.Container{container}}
+		err := engine.downloadArtifacts(task, container)
+		if _, ok := err.(ArtifactError); !ok {
+			t.Error("Expected an ArtifactError for", artifact, err)
+		}
+	}
+}
+
+func TestParseArtifactPermissions(t *testing.T) {
+	mode, uid, gid, err := parseArtifactPermissions(&api.ContainerArtifact{})
+	if err != nil || mode != 0444 || uid != -1 || gid != -1 {
+		t.Error("Expected read only and unchanged ownership by default", mode, uid, gid, err)
+	}
+	mode, uid, gid, err = parseArtifactPermissions(&api.ContainerArtifact{Mode: "0600", Owner: "1000:2000"})
+	if err != nil || mode != 0600 || uid != 1000 || gid != 2000 {
+		t.Error("Wrong permissions", mode, uid, gid, err)
+	}
+	if _, uid, gid, _ = parseArtifactPermissions(&api.ContainerArtifact{Owner: "1000"}); uid != 1000 || gid != -1 {
+		t.Error("Expected an owner without a group to leave the group", uid, gid)
+	}
+	if _, _, _, err = parseArtifactPermissions(&api.ContainerArtifact{Mode: "01777"}); err == nil {
+		t.Error("Expected an error for a mode with special bits")
+	}
+}
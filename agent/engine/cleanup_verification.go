@@ -0,0 +1,266 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+const (
+	// cleanupVerificationAttempts is how many times a task's cleanup is
+	// checked, and retried if anything was left behind
+	cleanupVerificationAttempts = 4
+
+	// maxOrphanedResources bounds how many orphaned resources are remembered
+	// for reporting
+	maxOrphanedResources = 100
+
+	orphanedContainer = "container"
+	orphanedCgroup    = "cgroup"
+	orphanedMount     = "mount"
+	orphanedDirectory = "directory"
+)
+
+// OrphanedResource is something on the host which a task's cleanup should
+// have removed, but did not
+type OrphanedResource struct {
+	TaskArn string
+	// Type is "container", "cgroup", "mount" or "directory"
+	Type     string
+	Resource string
+	FoundAt  time.Time
+}
+
+// CleanupMetrics are counters describing task cleanup since the agent started
+type CleanupMetrics struct {
+	TasksVerified uint64
+	// Retries counts the cleanups retried because something was left behind
+	Retries uint64
+	// Orphaned counts the resources still left behind once every attempt
+	// to remove them failed
+	Orphaned uint64
+}
+
+// cleanupReport records the outcome of verifying task cleanups
+type cleanupReport struct {
+	lock    sync.Mutex
+	metrics CleanupMetrics
+	orphans []OrphanedResource
+}
+
+func (report *cleanupReport) record(verified bool, retries int, orphans []OrphanedResource) {
+	report.lock.Lock()
+	defer report.lock.Unlock()
+	if verified {
+		report.metrics.TasksVerified++
+	}
+	report.metrics.Retries += uint64(retries)
+	report.metrics.Orphaned += uint64(len(orphans))
+	report.orphans = append(report.orphans, orphans...)
+	if len(report.orphans) > maxOrphanedResources {
+		report.orphans = report.orphans[len(report.orphans)-maxOrphanedResources:]
+	}
+}
+
+// CleanupMetrics returns counters describing task cleanup
+func (engine *DockerTaskEngine) CleanupMetrics() CleanupMetrics {
+	engine.cleanupReport.lock.Lock()
+	defer engine.cleanupReport.lock.Unlock()
+	return engine.cleanupReport.metrics
+}
+
+// OrphanedResources returns the most recent resources that task cleanups
+// left behind, oldest first
+func (engine *DockerTaskEngine) OrphanedResources() []OrphanedResource {
+	engine.cleanupReport.lock.Lock()
+	defer engine.cleanupReport.lock.Unlock()
+	return append([]OrphanedResource{}, engine.cleanupReport.orphans...)
+}
+
+// verifyCleanup checks, in the background, that the task's cleanup left
+// nothing behind once it was swept. The ids of its containers are taken now,
+// since the task is removed from the engine's state without waiting for the
+// check.
+func (engine *DockerTaskEngine) verifyCleanup(task *api.Task) {
+	var dockerIDs []string
+	containerMap, _ := engine.state.ContainerMapByArn(task.Arn)
+	for _, dockerContainer := range containerMap {
+		if dockerContainer.DockerId != "" {
+			dockerIDs = append(dockerIDs, dockerContainer.DockerId)
+		}
+	}
+	go engine.checkCleanup(task, dockerIDs, cgroupPath)
+}
+
+// checkCleanup checks that the task's containers, their cgroups, its mounts
+// and its directories are gone, removing what is left again with backoff
+// while anything is. What is still left afterwards is logged and reported as
+// orphaned.
+func (engine *DockerTaskEngine) checkCleanup(task *api.Task, dockerIDs []string, cgroupRoot string) {
+	backoff := utils.NewSimpleBackoff(time.Second, 30*time.Second, 0.2, 2)
+	var orphans []OrphanedResource
+	for attempt := 0; attempt < cleanupVerificationAttempts; attempt++ {
+		if attempt > 0 {
+			ttime.Sleep(backoff.Duration())
+			log.Info("Retrying cleanup of resources left behind", "task", task.Arn, "resources", len(orphans))
+			engine.removeOrphans(task, orphans)
+		}
+		orphans = engine.findOrphans(task, dockerIDs, cgroupRoot)
+		if len(orphans) == 0 {
+			engine.cleanupReport.record(true, attempt, nil)
+			return
+		}
+	}
+	for _, orphan := range orphans {
+		log.Warn("Task cleanup left a resource behind", "task", task.Arn, "type", orphan.Type, "resource", orphan.Resource)
+	}
+	engine.cleanupReport.record(false, cleanupVerificationAttempts-1, orphans)
+}
+
+// findOrphans returns what the cleanup of the task, whose containers had
+// dockerIDs, left behind
+func (engine *DockerTaskEngine) findOrphans(task *api.Task, dockerIDs []string, cgroupRoot string) []OrphanedResource {
+	var orphans []OrphanedResource
+	orphan := func(resourceType, resource string) {
+		orphans = append(orphans, OrphanedResource{TaskArn: task.Arn, Type: resourceType, Resource: resource, FoundAt: ttime.Now()})
+	}
+
+	hierarchies := cgroupHierarchies(cgroupRoot)
+	for _, dockerID := range dockerIDs {
+		if _, err := engine.client.InspectContainer(dockerID); err == nil {
+			orphan(orphanedContainer, dockerID)
+		}
+		for _, dir := range containerCgroupDirs(cgroupRoot, hierarchies, dockerID) {
+			if _, err := os.Stat(dir); err == nil {
+				orphan(orphanedCgroup, dir)
+			}
+		}
+	}
+
+	for _, taskVolume := range task.Volumes {
+		if efs, ok := taskVolume.Volume.(*api.EFSVolume); ok && efs.HostPath != "" {
+			orphan(orphanedMount, efs.HostPath)
+		}
+	}
+	for _, container := range task.Containers {
+		if container.LinuxParameters == nil {
+			continue
+		}
+		for _, tmpfs := range container.LinuxParameters.Tmpfs {
+			if tmpfs.HostPath != "" {
+				orphan(orphanedMount, tmpfs.HostPath)
+			}
+		}
+	}
+
+	dirs := []string{filepath.Join(engine.cfg.EFSMountDir, taskIDFromArn(task.Arn))}
+	if engine.cfg.TaskLogDir != "" {
+		if taskLogDir, err := engine.taskLogDir(task); err == nil {
+			dirs = append(dirs, taskLogDir)
+		}
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err == nil {
+			orphan(orphanedDirectory, dir)
+		}
+	}
+	return orphans
+}
+
+// cgroupHierarchies returns the cgroup v1 hierarchies mounted under
+// cgroupRoot, one per controller or set of controllers mounted together, and
+// "" for the unified hierarchy of cgroup v2. Links, such as cpu to
+// cpu,cpuacct, are skipped so that no hierarchy is listed twice.
+func cgroupHierarchies(cgroupRoot string) []string {
+	hierarchies := []string{""}
+	entries, err := ioutil.ReadDir(cgroupRoot)
+	if err != nil {
+		return hierarchies
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			hierarchies = append(hierarchies, entry.Name())
+		}
+	}
+	return hierarchies
+}
+
+// containerCgroupDirs returns where docker may have put the container's
+// cgroup, under either cgroup driver, in each of the hierarchies
+func containerCgroupDirs(cgroupRoot string, hierarchies []string, dockerID string) []string {
+	var dirs []string
+	for _, hierarchy := range hierarchies {
+		dirs = append(dirs,
+			filepath.Join(cgroupRoot, hierarchy, "docker", dockerID),
+			filepath.Join(cgroupRoot, hierarchy, "system.slice", "docker-"+dockerID+".scope"))
+	}
+	return dirs
+}
+
+// removeOrphans tries again to remove what the task's cleanup left behind.
+// The task may already be gone from the engine's state, so containers are
+// removed by their ids.
+func (engine *DockerTaskEngine) removeOrphans(task *api.Task, orphans []OrphanedResource) {
+	for _, orphan := range orphans {
+		if orphan.Type == orphanedContainer {
+			if err := engine.client.RemoveContainer(orphan.Resource); err != nil {
+				log.Debug("Unable to remove old container", "err", err, "task", task.Arn, "id", orphan.Resource)
+			}
+		}
+	}
+	removeCgroupDirs(orphans)
+	engine.unmountEFSVolumes(task)
+	engine.unmountScratchVolumes(task)
+	engine.removeTaskMountDir(task)
+	engine.removeTaskLogDir(task)
+}
+
+// removeCgroupDirs removes the orphaned cgroups. A cgroup with no processes
+// left in it is removed by removing its directory.
+func removeCgroupDirs(orphans []OrphanedResource) {
+	for _, orphan := range orphans {
+		if orphan.Type == orphanedCgroup {
+			os.Remove(orphan.Resource)
+		}
+	}
+}
+
+// removeTaskMountDir removes the directory holding the task's mount points.
+// Only empty directories are removed, so that nothing is deleted from a
+// volume which is still mounted.
+func (engine *DockerTaskEngine) removeTaskMountDir(task *api.Task) {
+	removeEmptyDirs(filepath.Join(engine.cfg.EFSMountDir, taskIDFromArn(task.Arn)))
+}
+
+// removeEmptyDirs removes dir if it holds nothing but empty directories
+func removeEmptyDirs(dir string) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			removeEmptyDirs(filepath.Join(dir, entry.Name()))
+		}
+	}
+	os.Remove(dir)
+}
@@ -0,0 +1,155 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// removedClient reports every container except those in remaining as gone
+type removedClient struct {
+	DockerClient
+	remaining map[string]bool
+}
+
+func (client *removedClient) InspectContainer(id string) (*docker.Container, error) {
+	if client.remaining[id] {
+		return &docker.Container{ID: id}, nil
+	}
+	return nil, errors.New("No such container: " + id)
+}
+
+func (client *removedClient) RemoveContainer(id string) error {
+	return errors.New("device or resource busy")
+}
+
+func cleanupTestEngine(t *testing.T) (*DockerTaskEngine, *api.Task, string) {
+	dir, err := ioutil.TempDir("", "ecs_cleanup_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewDockerTaskEngine(&config.Config{EFSMountDir: filepath.Join(dir, "mounts")})
+	engine.client = &removedClient{remaining: map[string]bool{"stuck": true}}
+
+	task := &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc", Containers: []*api.Container{{Name: "gone"}, {Name: "stuck"}}}
+	engine.state.AddTask(task)
+	engine.state.AddContainer(&api.DockerContainer{DockerId: "gone", DockerName: "gone", Container: task.Containers[0]}, task)
+	engine.state.AddContainer(&api.DockerContainer{DockerId: "stuck", DockerName: "stuck", Container: task.Containers[1]}, task)
+	return engine, task, dir
+}
+
+func TestFindOrphans(t *testing.T) {
+	engine, task, dir := cleanupTestEngine(t)
+	defer os.RemoveAll(dir)
+	cgroupRoot := filepath.Join(dir, "cgroup")
+	os.MkdirAll(filepath.Join(cgroupRoot, "memory", "docker", "gone"), 0755)
+	mountDir := filepath.Join(dir, "mounts", "abc")
+	os.MkdirAll(filepath.Join(mountDir, scratchMountDir, "stuck", "0"), 0755)
+
+	os.MkdirAll(filepath.Join(cgroupRoot, "pids", "system.slice", "docker-gone.scope"), 0755)
+	os.MkdirAll(filepath.Join(cgroupRoot, "cpu,cpuacct"), 0755)
+	os.Symlink(filepath.Join(cgroupRoot, "cpu,cpuacct"), filepath.Join(cgroupRoot, "cpu"))
+	os.MkdirAll(filepath.Join(cgroupRoot, "cpu,cpuacct", "docker", "stuck"), 0755)
+
+	orphans := engine.findOrphans(task, []string{"gone", "stuck"}, cgroupRoot)
+	found := make(map[string]string)
+	for _, orphan := range orphans {
+		found[orphan.Resource] = orphan.Type
+	}
+	if len(found) != 5 || found["stuck"] != orphanedContainer || found[mountDir] != orphanedDirectory ||
+		found[filepath.Join(cgroupRoot, "memory", "docker", "gone")] != orphanedCgroup ||
+		found[filepath.Join(cgroupRoot, "pids", "system.slice", "docker-gone.scope")] != orphanedCgroup ||
+		found[filepath.Join(cgroupRoot, "cpu,cpuacct", "docker", "stuck")] != orphanedCgroup {
+		t.Error("Wrong orphans", orphans)
+	}
+
+	removeCgroupDirs(orphans)
+	engine.removeTaskMountDir(task)
+	engine.client = &removedClient{}
+	if orphans = engine.findOrphans(task, []string{"gone", "stuck"}, cgroupRoot); len(orphans) != 0 {
+		t.Error("Expected nothing left behind, got", orphans)
+	}
+}
+
+func TestRemoveTaskMountDirKeepsFiles(t *testing.T) {
+	engine, task, dir := cleanupTestEngine(t)
+	defer os.RemoveAll(dir)
+	mountDir := filepath.Join(dir, "mounts", "abc")
+	os.MkdirAll(filepath.Join(mountDir, "efs"), 0755)
+	os.MkdirAll(filepath.Join(mountDir, scratchMountDir, "stuck", "0"), 0755)
+	ioutil.WriteFile(filepath.Join(mountDir, "efs", "data"), []byte("keep"), 0644)
+
+	engine.removeTaskMountDir(task)
+	if _, err := os.Stat(filepath.Join(mountDir, "efs", "data")); err != nil {
+		t.Error("Expected files in the mount directory to be kept", err)
+	}
+	if _, err := os.Stat(filepath.Join(mountDir, scratchMountDir)); !os.IsNotExist(err) {
+		t.Error("Expected empty directories to be removed", err)
+	}
+}
+
+func TestVerifyCleanupReportsOrphans(t *testing.T) {
+	engine, task, dir := cleanupTestEngine(t)
+	defer os.RemoveAll(dir)
+	testTime := ttime.NewTestTime()
+	testTime.LudicrousSpeed(true)
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	cgroupRoot := filepath.Join(dir, "cgroup")
+	engine.checkCleanup(task, []string{"gone", "stuck"}, cgroupRoot)
+	metrics := engine.CleanupMetrics()
+	if metrics.TasksVerified != 0 || metrics.Retries != cleanupVerificationAttempts-1 || metrics.Orphaned != 1 {
+		t.Error("Wrong metrics", metrics)
+	}
+	if orphans := engine.OrphanedResources(); len(orphans) != 1 || orphans[0].Resource != "stuck" || orphans[0].TaskArn != task.Arn {
+		t.Error("Wrong orphans", orphans)
+	}
+
+	engine.client = &removedClient{}
+	engine.checkCleanup(task, []string{"gone", "stuck"}, cgroupRoot)
+	if metrics = engine.CleanupMetrics(); metrics.TasksVerified != 1 || metrics.Orphaned != 1 {
+		t.Error("Expected a clean task to be verified", metrics)
+	}
+}
+
+func TestVerifyCleanupAfterTaskRemoved(t *testing.T) {
+	engine, task, dir := cleanupTestEngine(t)
+	defer os.RemoveAll(dir)
+	testTime := ttime.NewTestTime()
+	testTime.LudicrousSpeed(true)
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	// The check runs after the task is removed from the state, and still
+	// finds the container left behind
+	engine.verifyCleanup(task)
+	engine.state.RemoveTask(task)
+	for i := 0; i < 100 && engine.CleanupMetrics().Orphaned == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if orphans := engine.OrphanedResources(); len(orphans) != 1 || orphans[0].Resource != "stuck" {
+		t.Error("Wrong orphans", orphans)
+	}
+}
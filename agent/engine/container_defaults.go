@@ -0,0 +1,56 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// applyContainerDefaults merges the instance's container defaults under what
+// the container's task definition set in its docker config. Labels reserved
+// for the agent are never applied, and DNS settings aren't applied to
+// containers sharing another's network, which docker doesn't allow.
+func applyContainerDefaults(defaults *config.ContainerDefaults, dockerConfig *docker.Config, hostConfig *docker.HostConfig) {
+	if defaults == nil {
+		return
+	}
+	for key, value := range defaults.Labels {
+		if strings.HasPrefix(key, api.AgentLabelPrefix) {
+			continue
+		}
+		if _, ok := dockerConfig.Labels[key]; !ok {
+			dockerConfig.Labels[key] = value
+		}
+	}
+	if hostConfig.LogConfig.Type == "" && defaults.LogDriver != "" {
+		options := make(map[string]string, len(defaults.LogOptions))
+		for key, value := range defaults.LogOptions {
+			options[key] = value
+		}
+		hostConfig.LogConfig = docker.LogConfig{Type: defaults.LogDriver, Config: options}
+	}
+	if hostConfig.NetworkMode == "host" || strings.HasPrefix(hostConfig.NetworkMode, "container:") {
+		return
+	}
+	if len(hostConfig.DNS) == 0 {
+		hostConfig.DNS = defaults.DNSServers
+	}
+	if len(hostConfig.DNSSearch) == 0 {
+		hostConfig.DNSSearch = defaults.DNSSearchDomains
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestApplyContainerDefaults(t *testing.T) {
+	defaults := &config.ContainerDefaults{
+		LogDriver:        "fluentd",
+		LogOptions:       map[string]string{"fluentd-address": "localhost:24224"},
+		Labels:           map[string]string{"team": "platform", "env": "prod", api.AgentLabelPrefix + "task-arn": "spoofed"},
+		DNSServers:       []string{"10.0.0.2"},
+		DNSSearchDomains: []string{"internal.example.com"},
+	}
+	dockerConfig := &docker.Config{Labels: map[string]string{"team": "web", api.AgentLabelPrefix + "task-arn": "task1"}}
+	hostConfig := &docker.HostConfig{}
+
+	applyContainerDefaults(defaults, dockerConfig, hostConfig)
+	expectedLabels := map[string]string{"team": "web", "env": "prod", api.AgentLabelPrefix + "task-arn": "task1"}
+	if !reflect.DeepEqual(dockerConfig.Labels, expectedLabels) {
+		t.Error("Expected default labels merged under the container's", dockerConfig.Labels)
+	}
+	if hostConfig.LogConfig.Type != "fluentd" || hostConfig.LogConfig.Config["fluentd-address"] != "localhost:24224" {
+		t.Error("Expected the default log driver", hostConfig.LogConfig)
+	}
+	if !reflect.DeepEqual(hostConfig.DNS, []string{"10.0.0.2"}) || !reflect.DeepEqual(hostConfig.DNSSearch, []string{"internal.example.com"}) {
+		t.Error("Expected the default DNS settings", hostConfig.DNS, hostConfig.DNSSearch)
+	}
+}
+
+func TestApplyContainerDefaultsKeepsContainerSettings(t *testing.T) {
+	defaults := &config.ContainerDefaults{LogDriver: "fluentd", DNSServers: []string{"10.0.0.2"}}
+	dockerConfig := &docker.Config{Labels: map[string]string{}}
+	hostConfig := &docker.HostConfig{
+		LogConfig: docker.LogConfig{Type: "syslog"},
+		DNS:       []string{"8.8.8.8"},
+	}
+
+	applyContainerDefaults(defaults, dockerConfig, hostConfig)
+	if hostConfig.LogConfig.Type != "syslog" || !reflect.DeepEqual(hostConfig.DNS, []string{"8.8.8.8"}) {
+		t.Error("Expected the container's own settings to be kept", hostConfig.LogConfig, hostConfig.DNS)
+	}
+
+	hostConfig = &docker.HostConfig{NetworkMode: "host"}
+	applyContainerDefaults(defaults, dockerConfig, hostConfig)
+	if len(hostConfig.DNS) != 0 {
+		t.Error("Expected no DNS settings for a container on the host's network", hostConfig.DNS)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
+)
+
+// Environment variables the agent gives every container, unless its task
+// definition sets them itself. The introspection uri isn't named
+// ECS_CONTAINER_METADATA_URI, which SDKs expect to serve the v3 task metadata
+// format.
+const (
+	TaskIntrospectionURIEnvVar = "ECS_TASK_INTROSPECTION_URI"
+	RegionEnvVar               = "AWS_DEFAULT_REGION"
+	AvailabilityZoneEnvVar     = "ECS_AVAILABILITY_ZONE"
+	PlacementGroupEnvVar       = "ECS_PLACEMENT_GROUP"
+	PartitionEnvVar            = "ECS_PLACEMENT_PARTITION"
+	ContainerInstanceArnEnvVar = "ECS_CONTAINER_INSTANCE_ARN"
+)
+
+// envReferencePattern matches a ${NAME} reference in an environment value
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// SetContainerInstance records the container instance the engine runs tasks
+// for and its placement, if known, which are given to containers in their
+// environment
+func (engine *DockerTaskEngine) SetContainerInstance(containerInstanceArn string, placement *ec2.InstancePlacement) {
+	engine.instanceLock.Lock()
+	defer engine.instanceLock.Unlock()
+	engine.containerInstanceArn = containerInstanceArn
+	if placement != nil {
+		engine.placement = *placement
+	}
+}
+
+// agentEnvironment returns the variables the agent gives the task's
+// containers. Those it doesn't know are left out.
+func (engine *DockerTaskEngine) agentEnvironment(task *api.Task) map[string]string {
+	engine.instanceLock.RLock()
+	defer engine.instanceLock.RUnlock()
+
+	env := make(map[string]string)
+	if engine.cfg.ContainerMetadataEndpoint != "" {
+		env[TaskIntrospectionURIEnvVar] = strings.TrimSuffix(engine.cfg.ContainerMetadataEndpoint, "/") + "/v1/tasks?taskarn=" + url.QueryEscape(task.Arn)
+	}
+	if engine.cfg.AWSRegion != "" {
+		env[RegionEnvVar] = engine.cfg.AWSRegion
+	} else if engine.placement.Region != "" {
+		env[RegionEnvVar] = engine.placement.Region
+	}
+	if engine.placement.AvailabilityZone != "" {
+		env[AvailabilityZoneEnvVar] = engine.placement.AvailabilityZone
+	}
+	if engine.placement.PlacementGroup != "" {
+		env[PlacementGroupEnvVar] = engine.placement.PlacementGroup
+	}
+	if engine.placement.PartitionNumber != "" {
+		env[PartitionEnvVar] = engine.placement.PartitionNumber
+	}
+	if engine.containerInstanceArn != "" {
+		env[ContainerInstanceArnEnvVar] = engine.containerInstanceArn
+	}
+	return env
+}
+
+// withAgentEnvironment expands ${NAME} references to the agent's variables in
+// the values of dockerEnv, a list of NAME=value, and adds the variables
+// dockerEnv doesn't set, sorted by name. References to other names are left
+// as they are.
+func withAgentEnvironment(dockerEnv []string, agentEnv map[string]string) []string {
+	if len(agentEnv) == 0 {
+		return dockerEnv
+	}
+	expanded := make([]string, 0, len(dockerEnv)+len(agentEnv))
+	set := make(map[string]bool)
+	for _, variable := range dockerEnv {
+		name := variable
+		if equals := strings.Index(variable, "="); equals != -1 {
+			name = variable[:equals]
+			variable = name + "=" + envReferencePattern.ReplaceAllStringFunc(variable[equals+1:], func(reference string) string {
+				if value, ok := agentEnv[reference[2:len(reference)-1]]; ok {
+					return value
+				}
+				return reference
+			})
+		}
+		set[name] = true
+		expanded = append(expanded, variable)
+	}
+	names := make([]string, 0, len(agentEnv))
+	for name := range agentEnv {
+		if !set[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		expanded = append(expanded, name+"="+agentEnv[name])
+	}
+	return expanded
+}
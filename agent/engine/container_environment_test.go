@@ -0,0 +1,84 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
+)
+
+func TestAgentEnvironment(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{AWSRegion: "us-west-2", ContainerMetadataEndpoint: "http://172.17.42.1:51678/"})
+	task := &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc"}
+
+	env := engine.agentEnvironment(task)
+	if len(env) != 2 || env[RegionEnvVar] != "us-west-2" {
+		t.Error("Expected only the region and metadata uri before the instance is known", env)
+	}
+	if env[TaskIntrospectionURIEnvVar] != "http://172.17.42.1:51678/v1/tasks?taskarn=arn%3Aaws%3Aecs%3Aus-west-2%3A123456789012%3Atask%2Fabc" {
+		t.Error("Wrong metadata uri", env[TaskIntrospectionURIEnvVar])
+	}
+
+	engine.SetContainerInstance("arn:aws:ecs:us-west-2:123456789012:container-instance/ci", &ec2.InstancePlacement{Region: "us-west-2", AvailabilityZone: "us-west-2a"})
+	env = engine.agentEnvironment(task)
+	if env[AvailabilityZoneEnvVar] != "us-west-2a" || env[ContainerInstanceArnEnvVar] != "arn:aws:ecs:us-west-2:123456789012:container-instance/ci" {
+		t.Error("Expected the container instance in the environment", env)
+	}
+	if _, ok := env[PlacementGroupEnvVar]; ok {
+		t.Error("Expected no placement group outside of one", env)
+	}
+
+	engine.SetContainerInstance("arn:aws:ecs:us-west-2:123456789012:container-instance/ci", &ec2.InstancePlacement{AvailabilityZone: "us-west-2a", PlacementGroup: "analytics", PartitionNumber: "3"})
+	env = engine.agentEnvironment(task)
+	if env[PlacementGroupEnvVar] != "analytics" || env[PartitionEnvVar] != "3" {
+		t.Error("Expected the placement group in the environment", env)
+	}
+}
+
+func TestWithAgentEnvironment(t *testing.T) {
+	agentEnv := map[string]string{
+		RegionEnvVar:               "us-west-2",
+		AvailabilityZoneEnvVar:     "us-west-2a",
+		ContainerInstanceArnEnvVar: "arn:aws:ecs:us-west-2:123456789012:container-instance/ci",
+		PlacementGroupEnvVar:       "analytics",
+	}
+	env := withAgentEnvironment([]string{
+		"BUCKET=logs-${AWS_DEFAULT_REGION}",
+		"PLACEMENT=${ECS_AVAILABILITY_ZONE}/${UNKNOWN}",
+		"AWS_DEFAULT_REGION=eu-west-1",
+		"PRICE=$5",
+	}, agentEnv)
+
+	// The task's variables keep their order, and the agent's follow by name
+	expected := []string{
+		"BUCKET=logs-us-west-2",
+		"PLACEMENT=us-west-2a/${UNKNOWN}",
+		"AWS_DEFAULT_REGION=eu-west-1",
+		"PRICE=$5",
+		"ECS_AVAILABILITY_ZONE=us-west-2a",
+		"ECS_CONTAINER_INSTANCE_ARN=arn:aws:ecs:us-west-2:123456789012:container-instance/ci",
+		"ECS_PLACEMENT_GROUP=analytics",
+	}
+	if len(env) != len(expected) {
+		t.Fatal("Unexpected environment", env)
+	}
+	for i := range expected {
+		if env[i] != expected[i] {
+			t.Error("Expected", expected[i], "got", env[i])
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/fsouza/go-dockerclient"
+)
+
+const (
+	// defaultContainerNameTemplate is how the agent has always named
+	// containers
+	defaultContainerNameTemplate = "ecs-{family}-{version}-{name}-{random}"
+
+	// maxNameConflictRetries is how many suffixed names are tried when a
+	// container's name is taken by another container
+	maxNameConflictRetries = 5
+)
+
+var (
+	// containerNamePlaceholder matches a {placeholder} in a container name
+	// template, optionally sliced to its first n characters as {taskid:8}
+	containerNamePlaceholder = regexp.MustCompile(`\{([a-z]+)(?::([0-9]+))?\}`)
+	// invalidContainerNameChars matches what docker does not allow in names
+	invalidContainerNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+)
+
+// containerNameTemplate names containers from a template, such as
+// "{family}-{name}-{taskid:8}". The placeholders are the task definition's
+// {family} and {version}, the container's {name}, the {taskid} and a
+// {random} suffix.
+type containerNameTemplate string
+
+// parseContainerNameTemplate checks the template's placeholders, returning
+// the default template if it is blank. The template must have a {random} or
+// {taskid} placeholder, or every task of a task definition would name its
+// containers the same.
+func parseContainerNameTemplate(template string) (containerNameTemplate, error) {
+	if template == "" {
+		return defaultContainerNameTemplate, nil
+	}
+	unique := false
+	for _, match := range containerNamePlaceholder.FindAllStringSubmatch(template, -1) {
+		switch match[1] {
+		case "random", "taskid":
+			unique = true
+		case "family", "version", "name":
+		default:
+			return "", errors.New("unknown placeholder " + match[0])
+		}
+	}
+	if strings.Count(template, "{") != len(containerNamePlaceholder.FindAllString(template, -1)) {
+		return "", errors.New("malformed placeholder in " + template)
+	}
+	if !unique {
+		return "", errors.New("no {random} or {taskid} placeholder in " + template)
+	}
+	return containerNameTemplate(template), nil
+}
+
+// render returns the name of the task's container
+func (template containerNameTemplate) render(task *api.Task, container *api.Container) string {
+	name := containerNamePlaceholder.ReplaceAllStringFunc(string(template), func(placeholder string) string {
+		match := containerNamePlaceholder.FindStringSubmatch(placeholder)
+		var value string
+		switch match[1] {
+		case "family":
+			value = task.Family
+		case "version":
+			value = task.Version
+		case "name":
+			value = legacyContainerName(container.Name)
+		case "taskid":
+			value = taskIDFromArn(task.Arn)
+		case "random":
+			value = utils.RandHex()
+		}
+		if length, err := strconv.Atoi(match[2]); err == nil && length < len(value) {
+			value = value[:length]
+		}
+		return value
+	})
+	// Docker names must start with a letter or number
+	return strings.TrimLeft(invalidContainerNameChars.ReplaceAllString(name, ""), "_.-")
+}
+
+// legacyContainerName keeps only the letters, numbers and hyphens of a
+// container's name, as the agent always has in the names of its containers
+func legacyContainerName(name string) string {
+	legacy := ""
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !((c <= '9' && c >= '0') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c == '-')) {
+			continue
+		}
+		legacy += string(c)
+	}
+	return legacy
+}
+
+// createNamedContainer creates the container under the name rendered from the
+// engine's template, returning the name it was created with. Names are
+// deterministic unless the template has a {random} placeholder, so a name may
+// already be taken. If it is taken by this very container, created by an
+// earlier attempt that the engine lost track of, that container is adopted
+// rather than created again. If it is taken by any other container, the
+// suffixes -1, -2 and so on are tried in turn, and the name isn't kept in
+// the engine's state.
+func (engine *DockerTaskEngine) createNamedContainer(task *api.Task, container *api.Container, config *docker.Config, hostConfig *docker.HostConfig) (DockerContainerMetadata, string) {
+	baseName := engine.containerNames.render(task, container)
+	name := baseName
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			name = baseName + "-" + strconv.Itoa(attempt)
+		}
+		// Pre-add the container in case we stop before the next, more useful,
+		// AddContainer call. This ensures we have a way to get the container if
+		// we die before 'createContainer' returns because we can inspect by
+		// name
+		dockerContainer := &api.DockerContainer{DockerName: name, Container: container}
+		engine.state.AddContainer(dockerContainer, task)
+
+		metadata := engine.client.CreateContainer(config, hostConfig, name)
+		if _, conflict := metadata.Error.(ContainerNameConflictError); !conflict {
+			return metadata, name
+		}
+		existing, err := engine.client.InspectContainer(name)
+		if err == nil && existing.Config != nil && existing.Config.Labels[api.TaskArnLabel] == task.Arn && existing.Config.Labels[api.ContainerNameLabel] == container.Name {
+			log.Info("Adopting container created by an earlier attempt", "task", task.Arn, "container", container.Name, "name", name)
+			return metadataFromContainer(existing), name
+		}
+		// The name belongs to another container, which must not be mistaken
+		// for this one if the agent restarts before it is created
+		engine.state.RemoveContainer(dockerContainer, task)
+		if attempt == maxNameConflictRetries {
+			return metadata, name
+		}
+		log.Warn("Container name is taken by another container; trying the next", "task", task.Arn, "container", container.Name, "name", name)
+	}
+}
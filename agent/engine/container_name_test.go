@@ -0,0 +1,119 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/fsouza/go-dockerclient"
+)
+
+var nameTestTask = &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/0123456789abcdef", Family: "web", Version: "3"}
+
+func TestParseContainerNameTemplate(t *testing.T) {
+	if template, err := parseContainerNameTemplate(""); err != nil || template != defaultContainerNameTemplate {
+		t.Error("Expected the default template", template, err)
+	}
+	if _, err := parseContainerNameTemplate("{family}-{name}-{taskid:8}"); err != nil {
+		t.Error("Unexpected error", err)
+	}
+	for _, invalid := range []string{"{family}-{cluster}-{random}", "{family}-{name-{random}", "{family}-{name}"} {
+		if _, err := parseContainerNameTemplate(invalid); err == nil {
+			t.Error("Expected an error for", invalid)
+		}
+	}
+}
+
+func TestRenderContainerName(t *testing.T) {
+	container := &api.Container{Name: "app_server"}
+
+	name := containerNameTemplate("{family}-{name}-{taskid:8}").render(nameTestTask, container)
+	if name != "web-appserver-01234567" {
+		t.Error("Wrong name", name)
+	}
+	name = containerNameTemplate("_{family}/{version}").render(nameTestTask, container)
+	if name != "web3" {
+		t.Error("Expected invalid characters to be dropped, got", name)
+	}
+	name = containerNameTemplate(defaultContainerNameTemplate).render(nameTestTask, container)
+	if !regexp.MustCompile(`^ecs-web-3-appserver-[0-9a-f]{20}$`).MatchString(name) {
+		t.Error("Wrong default name", name)
+	}
+}
+
+// namingClient fails to create containers whose names are taken
+type namingClient struct {
+	DockerClient
+	taken   map[string]*docker.Container
+	created []string
+}
+
+func (client *namingClient) CreateContainer(config *docker.Config, hostConfig *docker.HostConfig, name string) DockerContainerMetadata {
+	if _, ok := client.taken[name]; ok {
+		return DockerContainerMetadata{Error: ContainerNameConflictError{name}}
+	}
+	client.created = append(client.created, name)
+	return DockerContainerMetadata{DockerId: "id-" + name}
+}
+
+func (client *namingClient) InspectContainer(name string) (*docker.Container, error) {
+	return client.taken[name], nil
+}
+
+func TestCreateNamedContainerConflicts(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{ContainerNameTemplate: "{family}-{name}-{taskid:4}"})
+	container := &api.Container{Name: "app"}
+	other := &docker.Container{ID: "other", Config: &docker.Config{Labels: map[string]string{api.TaskArnLabel: "arn:another", api.ContainerNameLabel: "app"}}}
+	client := &namingClient{taken: map[string]*docker.Container{"web-app-0123": other, "web-app-0123-1": other}}
+	engine.client = client
+
+	metadata, name := engine.createNamedContainer(nameTestTask, container, &docker.Config{}, &docker.HostConfig{})
+	if metadata.Error != nil || name != "web-app-0123-2" || metadata.DockerId != "id-web-app-0123-2" {
+		t.Error("Expected the first free suffix", name, metadata)
+	}
+
+	ours := &docker.Container{ID: "ours", Config: &docker.Config{Labels: map[string]string{api.TaskArnLabel: nameTestTask.Arn, api.ContainerNameLabel: "app"}}}
+	client.taken["web-app-0123"] = ours
+	metadata, name = engine.createNamedContainer(nameTestTask, container, &docker.Config{}, &docker.HostConfig{})
+	if metadata.Error != nil || name != "web-app-0123" || metadata.DockerId != "ours" {
+		t.Error("Expected the container from the earlier attempt to be adopted", name, metadata)
+	}
+	if len(client.created) != 1 {
+		t.Error("Expected the adopted container not to be created again", client.created)
+	}
+}
+
+func TestCreateNamedContainerForgetsTakenNames(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{ContainerNameTemplate: "{family}-{name}-{taskid:4}"})
+	container := &api.Container{Name: "app"}
+	other := &docker.Container{ID: "other", Config: &docker.Config{Labels: map[string]string{api.TaskArnLabel: "arn:another", api.ContainerNameLabel: "app"}}}
+	client := &namingClient{taken: make(map[string]*docker.Container)}
+	client.taken["web-app-0123"] = other
+	for i := 1; i <= maxNameConflictRetries; i++ {
+		client.taken["web-app-0123-"+strconv.Itoa(i)] = other
+	}
+	engine.client = client
+
+	metadata, _ := engine.createNamedContainer(nameTestTask, container, &docker.Config{}, &docker.HostConfig{})
+	if _, conflict := metadata.Error.(ContainerNameConflictError); !conflict {
+		t.Error("Expected a name conflict once every suffix is taken", metadata.Error)
+	}
+	if containerMap, _ := engine.state.ContainerMapByArn(nameTestTask.Arn); containerMap["app"] != nil {
+		t.Error("Expected no name taken by another container to be kept", containerMap["app"])
+	}
+}
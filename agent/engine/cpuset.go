@@ -0,0 +1,269 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+// CPUSetError is returned when a container cannot be pinned to the cores it
+// asks for
+type CPUSetError struct {
+	msg string
+}
+
+func (err CPUSetError) Error() string     { return err.msg }
+func (err CPUSetError) ErrorName() string { return "CPUSetError" }
+func (err CPUSetError) ErrorCode() string { return api.ErrorCodeResourceInitialization }
+
+// cpuSetAllocator tracks which cores are pinned to which containers, so that
+// no two containers are pinned to the same core and containers which aren't
+// pinned are kept off pinned cores
+type cpuSetAllocator struct {
+	lock      sync.Mutex
+	numCPUs   int
+	allocated map[string][]int
+
+	// nodes are the cores of each NUMA node if NUMA placement is enabled on
+	// a host with more than one. placed records the node each container
+	// which isn't pinned was placed on.
+	nodes  [][]int
+	placed map[string]numaPlacement
+}
+
+// numaPlacement is the NUMA node a container was placed on, with its task
+// and cpu reservation for balancing later placements
+type numaPlacement struct {
+	taskArn string
+	node    int
+	cpu     uint
+}
+
+func newCPUSetAllocator(placeOnNUMANodes bool) *cpuSetAllocator {
+	allocator := &cpuSetAllocator{numCPUs: runtime.NumCPU(), allocated: make(map[string][]int)}
+	if placeOnNUMANodes {
+		nodes, err := numaNodes(numaNodePath)
+		if err != nil {
+			log.Warn("Unable to read NUMA topology; tasks will not be placed on NUMA nodes", "err", err)
+		} else if len(nodes) < 2 {
+			log.Info("The instance has a single NUMA node; tasks will not be placed on NUMA nodes")
+		} else {
+			allocator.nodes = nodes
+			allocator.placed = make(map[string]numaPlacement)
+		}
+	}
+	return allocator
+}
+
+func cpuSetKey(task *api.Task, container *api.Container) string {
+	return task.Arn + "/" + container.Name
+}
+
+func pinnedCPUs(container *api.Container) string {
+	if container.LinuxParameters == nil {
+		return ""
+	}
+	return container.LinuxParameters.CPUSetCPUs
+}
+
+// assign returns the cpuset the container is to be created with. A pinned
+// container is given the cores it asks for if no other container is pinned
+// to them; one which isn't pinned is given every core which isn't pinned, or
+// "" for all cores if none are.
+func (allocator *cpuSetAllocator) assign(task *api.Task, container *api.Container) (string, api.NamedError) {
+	allocator.lock.Lock()
+	defer allocator.lock.Unlock()
+
+	requested := pinnedCPUs(container)
+	if requested == "" {
+		if allocator.nodes != nil {
+			return formatCPUSet(allocator.place(task, container)), nil
+		}
+		if len(allocator.allocated) == 0 {
+			return "", nil
+		}
+		return formatCPUSet(allocator.unpinned(nil)), nil
+	}
+
+	key := cpuSetKey(task, container)
+	cpus, err := parseCPUSet(requested, allocator.numCPUs)
+	if err != nil {
+		return "", CPUSetError{"Invalid cpusetCpus '" + requested + "': " + err.Error()}
+	}
+	for other, otherCPUs := range allocator.allocated {
+		if other != key && overlaps(cpus, otherCPUs) {
+			return "", CPUSetError{"Cannot pin to cores " + formatCPUSet(cpus) + "; " + formatCPUSet(otherCPUs) + " are pinned to " + other}
+		}
+	}
+	if len(allocator.unpinned(map[string][]int{key: cpus})) == 0 {
+		return "", CPUSetError{"Cannot pin to cores " + formatCPUSet(cpus) + "; at least one core must be left for other containers"}
+	}
+	allocator.allocated[key] = cpus
+	return formatCPUSet(cpus), nil
+}
+
+// restore records the cores of a pinned container which was created before
+// the agent restarted
+func (allocator *cpuSetAllocator) restore(task *api.Task, container *api.Container) {
+	cpus, err := parseCPUSet(pinnedCPUs(container), allocator.numCPUs)
+	if err != nil || len(cpus) == 0 {
+		return
+	}
+	allocator.lock.Lock()
+	defer allocator.lock.Unlock()
+	allocator.allocated[cpuSetKey(task, container)] = cpus
+}
+
+// release frees the cores pinned to the container, if any
+func (allocator *cpuSetAllocator) release(task *api.Task, container *api.Container) {
+	allocator.lock.Lock()
+	defer allocator.lock.Unlock()
+	delete(allocator.allocated, cpuSetKey(task, container))
+	delete(allocator.placed, cpuSetKey(task, container))
+}
+
+// place places a container which isn't pinned on a NUMA node, returning the
+// node's cores which aren't pinned. The containers of a task share a node;
+// a task's first container goes on the node with the least cpu reserved by
+// running containers, then the fewest of them. If all of the node's cores
+// are pinned, the container may use any core which isn't. The caller must
+// hold the lock.
+func (allocator *cpuSetAllocator) place(task *api.Task, container *api.Container) []int {
+	node := -1
+	reserved := make([]uint, len(allocator.nodes))
+	containers := make([]int, len(allocator.nodes))
+	for key, placement := range allocator.placed {
+		if placement.taskArn == task.Arn && key != cpuSetKey(task, container) {
+			node = placement.node
+		}
+		reserved[placement.node] += placement.cpu
+		containers[placement.node]++
+	}
+	if node == -1 {
+		node = 0
+		for candidate := range allocator.nodes {
+			if reserved[candidate] < reserved[node] || (reserved[candidate] == reserved[node] && containers[candidate] < containers[node]) {
+				node = candidate
+			}
+		}
+	}
+	allocator.placed[cpuSetKey(task, container)] = numaPlacement{taskArn: task.Arn, node: node, cpu: container.Cpu}
+
+	unpinned := allocator.unpinned(nil)
+	var cpus []int
+	for _, cpu := range allocator.nodes[node] {
+		for _, free := range unpinned {
+			if cpu == free {
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+	if len(cpus) == 0 {
+		return unpinned
+	}
+	return cpus
+}
+
+// unpinned returns the cores pinned neither to an allocated container nor to
+// one in extra. The caller must hold the lock.
+func (allocator *cpuSetAllocator) unpinned(extra map[string][]int) []int {
+	pinned := make(map[int]bool)
+	for _, allocated := range []map[string][]int{allocator.allocated, extra} {
+		for _, cpus := range allocated {
+			for _, cpu := range cpus {
+				pinned[cpu] = true
+			}
+		}
+	}
+	var cpus []int
+	for cpu := 0; cpu < allocator.numCPUs; cpu++ {
+		if !pinned[cpu] {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus
+}
+
+func overlaps(a, b []int) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseCPUSet parses a list of cores and ranges such as "0-1,3" into sorted,
+// distinct cores. Cores must be below numCPUs, which is checked before ranges
+// are expanded so that a huge range can't exhaust memory.
+func parseCPUSet(value string, numCPUs int) ([]int, error) {
+	seen := make(map[int]bool)
+	var cpus []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last := part, part
+		if dash := strings.Index(part, "-"); dash != -1 {
+			first, last = part[:dash], part[dash+1:]
+		}
+		from, err := strconv.Atoi(first)
+		if err != nil || from < 0 {
+			return nil, errors.New("invalid core '" + first + "'")
+		}
+		to, err := strconv.Atoi(last)
+		if err != nil || to < from {
+			return nil, errors.New("invalid range '" + part + "'")
+		}
+		if to >= numCPUs {
+			return nil, errors.New("core " + strconv.Itoa(to) + " is beyond the instance's " + strconv.Itoa(numCPUs) + " cores")
+		}
+		for cpu := from; cpu <= to; cpu++ {
+			if !seen[cpu] {
+				seen[cpu] = true
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// formatCPUSet formats sorted cores as a list of cores and ranges
+func formatCPUSet(cpus []int) string {
+	var parts []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(cpus[i]))
+		} else {
+			parts = append(parts, strconv.Itoa(cpus[i])+"-"+strconv.Itoa(cpus[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
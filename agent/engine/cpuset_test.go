@@ -0,0 +1,167 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/fsouza/go-dockerclient"
+	"golang.org/x/net/context"
+)
+
+func pinnedContainer(name, cpus string) *api.Container {
+	return &api.Container{Name: name, LinuxParameters: &api.LinuxParameters{CPUSetCPUs: cpus}}
+}
+
+func TestParseCPUSet(t *testing.T) {
+	cpus, err := parseCPUSet("3, 0-1,1", 4)
+	if err != nil || !reflect.DeepEqual(cpus, []int{0, 1, 3}) {
+		t.Error("Unexpected cores", cpus, err)
+	}
+	if formatted := formatCPUSet([]int{0, 1, 2, 4, 6, 7}); formatted != "0-2,4,6-7" {
+		t.Error("Unexpected formatting", formatted)
+	}
+	for _, invalid := range []string{"a", "2-1", "-1", "0-x", "4", "0-2000000000"} {
+		if _, err := parseCPUSet(invalid, 4); err == nil {
+			t.Error("Expected an error parsing", invalid)
+		}
+	}
+}
+
+func TestCPUSetAllocator(t *testing.T) {
+	allocator := &cpuSetAllocator{numCPUs: 4, allocated: make(map[string][]int)}
+	task := &api.Task{Arn: "t1"}
+	other := &api.Task{Arn: "t2"}
+	unpinned := &api.Container{Name: "app"}
+
+	if cpus, err := allocator.assign(task, unpinned); err != nil || cpus != "" {
+		t.Error("Expected all cores when nothing is pinned", cpus, err)
+	}
+
+	web := pinnedContainer("web", "1,0")
+	if cpus, err := allocator.assign(task, web); err != nil || cpus != "0-1" {
+		t.Error("Expected web to be pinned", cpus, err)
+	}
+	// Assigning the same container again, as when it's recreated, is fine
+	if _, err := allocator.assign(task, web); err != nil {
+		t.Error("Expected web to keep its cores", err)
+	}
+	if cpus, err := allocator.assign(other, unpinned); err != nil || cpus != "2-3" {
+		t.Error("Expected unpinned containers kept off pinned cores", cpus, err)
+	}
+
+	if _, err := allocator.assign(other, pinnedContainer("db", "1-2")); err == nil {
+		t.Error("Expected overlapping cores to be refused")
+	}
+	if _, err := allocator.assign(other, pinnedContainer("db", "4")); err == nil {
+		t.Error("Expected a core the instance lacks to be refused")
+	}
+	if _, err := allocator.assign(other, pinnedContainer("db", "2-3")); err == nil {
+		t.Error("Expected pinning the last free cores to be refused")
+	}
+	if cpus, err := allocator.assign(other, pinnedContainer("db", "2")); err != nil || cpus != "2" {
+		t.Error("Expected db to be pinned", cpus, err)
+	}
+
+	allocator.release(task, web)
+	if cpus, _ := allocator.assign(task, unpinned); cpus != "0-1,3" {
+		t.Error("Expected web's cores to be released", cpus)
+	}
+}
+
+// failingCreateClient fails to create any container
+type failingCreateClient struct {
+	DockerClient
+}
+
+func (failingCreateClient) CreateContainer(config *docker.Config, hostConfig *docker.HostConfig, name string) DockerContainerMetadata {
+	return DockerContainerMetadata{Error: CannotXContainerError{"Create", "docker is unavailable"}}
+}
+
+func TestCreateContainerFailureReleasesCPUSet(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{})
+	engine.client = failingCreateClient{}
+	engine.cpuSets = &cpuSetAllocator{numCPUs: 4, allocated: make(map[string][]int)}
+	container := pinnedContainer("web", "0-1")
+	task := &api.Task{Arn: "t1", Containers: []*api.Container{container}}
+	engine.state.AddTask(task)
+
+	if metadata := engine.createContainer(context.Background(), task, container, nil); metadata.Error == nil {
+		t.Fatal("Expected the container not to be created")
+	}
+	if len(engine.cpuSets.allocated) != 0 {
+		t.Error("Expected the container's cores to be released", engine.cpuSets.allocated)
+	}
+}
+
+func TestNUMANodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "numa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for node, cpuList := range map[string]string{"node0": "0-1\n", "node1": "2-3\n", "node2": "\n"} {
+		os.MkdirAll(filepath.Join(dir, node), 0755)
+		ioutil.WriteFile(filepath.Join(dir, node, "cpulist"), []byte(cpuList), 0644)
+	}
+	os.MkdirAll(filepath.Join(dir, "power"), 0755)
+
+	nodes, err := numaNodes(dir)
+	if err != nil || !reflect.DeepEqual(nodes, [][]int{{0, 1}, {2, 3}}) {
+		t.Error("Unexpected nodes", nodes, err)
+	}
+}
+
+func TestNUMAPlacement(t *testing.T) {
+	allocator := &cpuSetAllocator{
+		numCPUs:   4,
+		allocated: make(map[string][]int),
+		nodes:     [][]int{{0, 1}, {2, 3}},
+		placed:    make(map[string]numaPlacement),
+	}
+	task := &api.Task{Arn: "t1"}
+	other := &api.Task{Arn: "t2"}
+
+	if cpus, _ := allocator.assign(task, &api.Container{Name: "app", Cpu: 512}); cpus != "0-1" {
+		t.Error("Expected the first task on the first node", cpus)
+	}
+	if cpus, _ := allocator.assign(task, &api.Container{Name: "sidecar"}); cpus != "0-1" {
+		t.Error("Expected the task's containers on the same node", cpus)
+	}
+	if cpus, _ := allocator.assign(other, &api.Container{Name: "app", Cpu: 256}); cpus != "2-3" {
+		t.Error("Expected another task on the less loaded node", cpus)
+	}
+
+	// Pinned containers are left where they are pinned, and others are kept
+	// off their cores
+	if cpus, _ := allocator.assign(other, pinnedContainer("pinned", "2")); cpus != "2" {
+		t.Error("Expected the pinned container on its core", cpus)
+	}
+	third := &api.Task{Arn: "t3"}
+	if cpus, _ := allocator.assign(third, &api.Container{Name: "app"}); cpus != "3" {
+		t.Error("Expected the node's unpinned cores", cpus)
+	}
+
+	allocator.release(task, &api.Container{Name: "app"})
+	allocator.release(task, &api.Container{Name: "sidecar"})
+	if cpus, _ := allocator.assign(&api.Task{Arn: "t4"}, &api.Container{Name: "app"}); cpus != "0-1" {
+		t.Error("Expected released cores to be used again", cpus)
+	}
+}
@@ -169,6 +169,53 @@ func volumeIsResolved(target *api.Container, volume *api.Container) bool {
 	return false
 }
 
+// DependentsAreStopped validates that `target` can be stopped given the
+// current known state of the containers in `by`. A container is stopped only
+// after every stopping container which links to it, takes volumes from it, or
+// must run after it has stopped, so that a container is never stopped from
+// under one still using it (for example, a log router is stopped only after
+// the application writing to it). Containers which are not stopping are not
+// waited for.
+func DependentsAreStopped(target *api.Container, by []*api.Container) bool {
+	return len(RunningDependents(target, by)) == 0
+}
+
+// RunningDependents returns the stopping containers in `by` which `target`
+// waits for before it is stopped
+func RunningDependents(target *api.Container, by []*api.Container) []*api.Container {
+	var dependents []*api.Container
+	for _, dependent := range by {
+		if dependent == target || !dependent.DesiredTerminal() || dependent.GetKnownStatus() != api.ContainerRunning {
+			continue
+		}
+		if dependsOn(dependent, target.Name) {
+			dependents = append(dependents, dependent)
+		}
+	}
+	return dependents
+}
+
+// dependsOn returns whether `dependent` links to, takes volumes from, or must
+// run after the container named `name`
+func dependsOn(dependent *api.Container, name string) bool {
+	for _, link := range linksToContainerNames(dependent.Links) {
+		if link == name {
+			return true
+		}
+	}
+	for _, volume := range dependent.VolumesFrom {
+		if volume.SourceContainer == name {
+			return true
+		}
+	}
+	for _, run := range dependent.RunDependencies {
+		if run == name {
+			return true
+		}
+	}
+	return false
+}
+
 // onRunIsResolved defines a relationship where a target cannot be created until
 // 'run' has reached a running state.
 func onRunIsResolved(target *api.Container, run *api.Container) bool {
@@ -201,3 +201,32 @@ func TestRunningependsOnDependencies(t *testing.T) {
 		t.Error("Dependencies should be resolved")
 	}
 }
+
+func TestDependentsAreStopped(t *testing.T) {
+	envoy := runningContainer("envoy", []string{}, []string{})
+	logs := runningContainer("logs", []string{}, []string{})
+	app := runningContainer("app", []string{"envoy:proxy"}, []string{"logs"})
+	for _, cont := range []*api.Container{envoy, logs, app} {
+		cont.KnownStatus = api.ContainerRunning
+	}
+	containers := []*api.Container{envoy, logs, app}
+
+	if !DependentsAreStopped(envoy, containers) {
+		t.Error("Containers which are not stopping should not be waited for")
+	}
+
+	for _, cont := range containers {
+		cont.DesiredStatus = api.ContainerStopped
+	}
+	if DependentsAreStopped(envoy, containers) || DependentsAreStopped(logs, containers) {
+		t.Error("Sidecars should wait for the app using them to stop")
+	}
+	if !DependentsAreStopped(app, containers) {
+		t.Error("Nothing depends on the app, so it should stop straight away")
+	}
+
+	app.KnownStatus = api.ContainerStopped
+	if !DependentsAreStopped(envoy, containers) || !DependentsAreStopped(logs, containers) {
+		t.Error("Sidecars should stop once the app has")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+// InvalidDeviceError is returned when a container references a host device
+// which does not exist or which is not permitted by the agent's configuration
+type InvalidDeviceError struct {
+	msg string
+}
+
+func (err InvalidDeviceError) Error() string     { return err.msg }
+func (err InvalidDeviceError) ErrorName() string { return "InvalidDeviceError" }
+func (err InvalidDeviceError) ErrorCode() string { return api.ErrorCodeCannotCreateContainer }
+
+// validateDevices ensures every device the container requests exists on the
+// host and lives under one of the allowed path prefixes
+func validateDevices(container *api.Container, allowedPrefixes []string) api.NamedError {
+	if container.LinuxParameters == nil {
+		return nil
+	}
+	for _, device := range container.LinuxParameters.Devices {
+		hostPath := filepath.Clean(device.HostPath)
+		if !devicePathAllowed(hostPath, allowedPrefixes) {
+			return InvalidDeviceError{"Device " + device.HostPath + " for container " + container.Name + " is not in the allowed device path prefixes"}
+		}
+		info, err := os.Stat(hostPath)
+		if err != nil {
+			return InvalidDeviceError{"Device " + device.HostPath + " for container " + container.Name + " is not available: " + err.Error()}
+		}
+		if info.Mode()&os.ModeDevice == 0 {
+			return InvalidDeviceError{"Path " + device.HostPath + " for container " + container.Name + " is not a device"}
+		}
+	}
+	return nil
+}
+
+func devicePathAllowed(hostPath string, allowedPrefixes []string) bool {
+	for _, prefix := range allowedPrefixes {
+		if strings.HasPrefix(hostPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
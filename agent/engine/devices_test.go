@@ -0,0 +1,63 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+func deviceContainer(hostPath string) *api.Container {
+	return &api.Container{
+		Name: "c1",
+		LinuxParameters: &api.LinuxParameters{
+			Devices: []api.Device{api.Device{HostPath: hostPath}},
+		},
+	}
+}
+
+func TestValidateDevicesAllowed(t *testing.T) {
+	if err := validateDevices(deviceContainer("/dev/null"), []string{"/dev/"}); err != nil {
+		t.Error("Expected /dev/null to be allowed", err)
+	}
+}
+
+func TestValidateDevicesNotInAllowlist(t *testing.T) {
+	err := validateDevices(deviceContainer("/dev/null"), []string{"/dev/fuse"})
+	if err == nil {
+		t.Fatal("Expected an error for a device outside the allowlist")
+	}
+	if err.ErrorName() != "InvalidDeviceError" {
+		t.Error("Unexpected error name", err.ErrorName())
+	}
+}
+
+func TestValidateDevicesMissingDevice(t *testing.T) {
+	if err := validateDevices(deviceContainer("/dev/does-not-exist"), []string{"/dev/"}); err == nil {
+		t.Error("Expected an error for a missing device")
+	}
+}
+
+func TestValidateDevicesPathEscape(t *testing.T) {
+	if err := validateDevices(deviceContainer("/dev/../etc/hostname"), []string{"/dev/"}); err == nil {
+		t.Error("Expected an error for a path escaping the allowlist")
+	}
+}
+
+func TestValidateDevicesNotADevice(t *testing.T) {
+	if err := validateDevices(deviceContainer("/dev"), []string{"/"}); err == nil {
+		t.Error("Expected an error for a path which is not a device")
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/health"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+// diskPressureCheckInterval is how often the filesystems holding docker's data
+// and the agent's are checked for pressure
+const diskPressureCheckInterval = time.Minute
+
+var (
+	defaultDiskUsedPercent = health.DiskUsedPercent
+	diskUsedPercent        = defaultDiskUsedPercent
+)
+
+// expeditedCleanup lets stopped tasks be cleaned up before their cleanup
+// wait has passed. Tasks waiting for cleanup wait on the current channel,
+// which is closed and replaced each time cleanup is expedited.
+type expeditedCleanup struct {
+	lock sync.Mutex
+	now  chan struct{}
+}
+
+func newExpeditedCleanup() *expeditedCleanup {
+	return &expeditedCleanup{now: make(chan struct{})}
+}
+
+// wait returns a channel which is closed the next time cleanup is expedited
+func (cleanup *expeditedCleanup) wait() <-chan struct{} {
+	cleanup.lock.Lock()
+	defer cleanup.lock.Unlock()
+	return cleanup.now
+}
+
+// expedite releases every task currently waiting for cleanup
+func (cleanup *expeditedCleanup) expedite() {
+	cleanup.lock.Lock()
+	defer cleanup.lock.Unlock()
+	close(cleanup.now)
+	cleanup.now = make(chan struct{})
+}
+
+// monitorDiskPressure periodically checks whether docker's or the agent's
+// filesystem is under pressure until ctx is cancelled. It returns at once if
+// no threshold is configured.
+func (engine *DockerTaskEngine) monitorDiskPressure(ctx context.Context) {
+	if engine.cfg.DiskPressureThreshold <= 0 {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ttime.After(diskPressureCheckInterval):
+		}
+		engine.checkDiskPressure()
+	}
+}
+
+// checkDiskPressure cleans up every stopped task immediately if docker's or
+// the agent's filesystem is fuller than the configured threshold
+func (engine *DockerTaskEngine) checkDiskPressure() bool {
+	threshold := engine.cfg.DiskPressureThreshold
+	underPressure := false
+	for _, path := range []string{engine.cfg.DockerGraphPath, engine.cfg.DataDir} {
+		if path == "" {
+			continue
+		}
+		used, err := diskUsedPercent(path)
+		if err != nil {
+			log.Debug("Unable to check disk usage", "path", path, "err", err)
+			continue
+		}
+		if used > threshold {
+			log.Warn("Disk under pressure; cleaning up stopped tasks early", "path", path, "usedPercent", used, "threshold", threshold)
+			underPressure = true
+		}
+	}
+	if underPressure {
+		engine.cleanup.expedite()
+	}
+	return underPressure
+}
+
+// removeTaskImages removes the images of a cleaned up task's containers which
+// no remaining task uses. Docker refuses to remove an image that a container
+// still exists for, so images shared with containers the agent doesn't manage
+// are kept, as are pre-pulled images.
+func (engine *DockerTaskEngine) removeTaskImages(task *api.Task) {
+	inUse := make(map[string]bool)
+	for _, other := range engine.state.AllTasks() {
+		if other.Arn == task.Arn {
+			continue
+		}
+		for _, cont := range other.Containers {
+			inUse[cont.Image] = true
+		}
+	}
+	for _, cont := range task.Containers {
+		if cont.Image == "" || inUse[cont.Image] || engine.prePulled.has(cont.Image) {
+			continue
+		}
+		// Don't try the same image twice for a task
+		inUse[cont.Image] = true
+		if err := engine.client.RemoveImage(cont.Image); err != nil {
+			log.Debug("Unable to remove image", "image", cont.Image, "task", task.Arn, "err", err)
+			continue
+		}
+		log.Info("Removed image to free disk space", "image", cont.Image, "task", task.Arn)
+	}
+}
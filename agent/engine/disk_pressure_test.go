@@ -0,0 +1,107 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+)
+
+// imageRemovingClient records the images it is asked to remove
+type imageRemovingClient struct {
+	DockerClient
+	removed []string
+}
+
+func (client *imageRemovingClient) RemoveImage(image string) error {
+	client.removed = append(client.removed, image)
+	if image == "in-use" {
+		return errors.New("conflict")
+	}
+	return nil
+}
+
+func TestCheckDiskPressure(t *testing.T) {
+	defer func() { diskUsedPercent = defaultDiskUsedPercent }()
+	usage := map[string]int{"/var/lib/docker": 50, "/data": 50}
+	diskUsedPercent = func(path string) (int, error) {
+		used, ok := usage[path]
+		if !ok {
+			return 0, errors.New("no such path")
+		}
+		return used, nil
+	}
+
+	engine := NewDockerTaskEngine(&config.Config{DockerGraphPath: "/var/lib/docker", DataDir: "/data", DiskPressureThreshold: 80})
+	waiting := engine.cleanup.wait()
+
+	if engine.checkDiskPressure() {
+		t.Error("Expected no pressure below the threshold")
+	}
+	select {
+	case <-waiting:
+		t.Fatal("Cleanup should not be expedited without pressure")
+	default:
+	}
+
+	usage["/var/lib/docker"] = 81
+	if !engine.checkDiskPressure() {
+		t.Error("Expected pressure above the threshold")
+	}
+	select {
+	case <-waiting:
+	default:
+		t.Fatal("Cleanup should be expedited under pressure")
+	}
+
+	// Tasks which start waiting afterwards wait for the next expedite
+	select {
+	case <-engine.cleanup.wait():
+		t.Error("A new wait should not already be released")
+	default:
+	}
+}
+
+func TestRemoveTaskImages(t *testing.T) {
+	client := &imageRemovingClient{}
+	engine := NewDockerTaskEngine(&config.Config{})
+	engine.client = client
+
+	running := &api.Task{Arn: "running", Containers: []*api.Container{{Name: "c", Image: "shared"}}}
+	engine.state.AddTask(running)
+	stopped := &api.Task{Arn: "stopped", Containers: []*api.Container{
+		{Name: "c1", Image: "shared"},
+		{Name: "c2", Image: "unused"},
+		{Name: "c3", Image: "unused"},
+		{Name: "c4", Image: "in-use"},
+	}}
+	engine.state.AddTask(stopped)
+
+	engine.removeTaskImages(stopped)
+	if expected := []string{"unused", "in-use"}; !reflect.DeepEqual(client.removed, expected) {
+		t.Errorf("Expected %v to be removed, got %v", expected, client.removed)
+	}
+}
+
+func TestMonitorDiskPressureDisabledWithoutThreshold(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{})
+	// Returns at once rather than checking until the context is cancelled
+	engine.monitorDiskPressure(context.Background())
+}
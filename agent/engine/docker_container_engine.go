@@ -16,8 +16,11 @@ package engine
 import (
 	"archive/tar"
 	"bufio"
+	"errors"
 	"io"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -28,6 +31,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerauth"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerclient"
 	"github.com/aws/amazon-ecs-agent/agent/engine/emptyvolume"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
 	"github.com/docker/docker/pkg/parsers"
@@ -36,7 +40,10 @@ import (
 )
 
 const (
-	dockerStopTimeoutSeconds = 30
+	// defaultDockerStopTimeout is how long a container is given to exit
+	// after being asked to stop if neither its task definition nor the
+	// agent's configuration say otherwise
+	defaultDockerStopTimeout = 30 * time.Second
 	dockerDefaultTag         = "latest"
 )
 
@@ -47,6 +54,8 @@ const (
 	startContainerTimeout   = 1 * time.Minute
 	stopContainerTimeout    = 1 * time.Minute
 	removeContainerTimeout  = 5 * time.Minute
+	removeImageTimeout      = 3 * time.Minute
+	tagImageTimeout         = 1 * time.Minute
 	inspectContainerTimeout = 10 * time.Second
 	listContainersTimeout   = 10 * time.Minute
 
@@ -56,35 +65,66 @@ const (
 	dockerPullBeginTimeout = 5 * time.Minute
 )
 
+// errPullAbandoned fails the writes of an abandoned pull's progress, so that
+// the docker client stops reading it and closes the request
+var errPullAbandoned = errors.New("pull abandoned")
+
 // Interface to make testing it easier
 type DockerClient interface {
 	ContainerEvents(ctx context.Context) (<-chan DockerContainerChangeEvent, error)
 
-	PullImage(image string) DockerContainerMetadata
+	// PullImage pulls an image, abandoning the pull if ctx is cancelled
+	PullImage(ctx context.Context, image string) DockerContainerMetadata
 	CreateContainer(*docker.Config, *docker.HostConfig, string) DockerContainerMetadata
 	StartContainer(string) DockerContainerMetadata
-	StopContainer(string) DockerContainerMetadata
+	// StopContainer asks a container to stop, killing it if it has not
+	// exited after the given timeout
+	StopContainer(string, time.Duration) DockerContainerMetadata
+	// StopContainerWithSignal is StopContainer for containers which are
+	// asked to stop with a signal other than SIGTERM
+	StopContainerWithSignal(string, docker.Signal, time.Duration) DockerContainerMetadata
 	DescribeContainer(string) (api.ContainerStatus, DockerContainerMetadata)
 
 	RemoveContainer(string) error
+	RemoveImage(string) error
+	// TagImage names a pulled image, such as one pulled by digest
+	TagImage(image, name string) error
 
 	GetContainerName(string) (string, error)
 	InspectContainer(string) (*docker.Container, error)
+	ImageRepoDigests(string) ([]string, error)
+	// ContainerLogs writes the last tail lines of a container's stdout and
+	// stderr to output and, if follow is set, keeps writing new lines until
+	// the container stops or writing to output fails
+	ContainerLogs(dockerID string, tail int, follow bool, output io.Writer) error
 
 	ListContainers(bool) ListContainersResponse
 
+	Info() (*docker.Env, error)
 	Version() (string, error)
+
+	// EventQueueDepth returns how many docker events are waiting to be
+	// processed
+	EventQueueDepth() EventQueueDepth
 }
 
 // Implements DockerClient
 type DockerGoClient struct {
 	dockerClient dockerclient.Client
+
+	// eventQueue holds the docker events waiting to be processed
+	eventQueueLock sync.RWMutex
+	eventQueue     *dockerEventQueue
 }
 
 func (dg *DockerGoClient) SetGoDockerClient(to dockerclient.Client) {
 	dg.dockerClient = to
 }
 
+// dockerLog is the logger for the docker client, whose level may be set
+// apart from the rest of the engine's
+var dockerLog = logger.ForModule("dockerclient")
+
 // pullLock is a temporary workaround for a devicemapper issue. See: https://github.com/docker/docker/issues/9718
 var pullLock sync.Mutex
 
@@ -100,7 +140,7 @@ func NewDockerGoClient() (*DockerGoClient, error) {
 
 	client, err := docker.NewVersionedClient(endpoint, "1.17")
 	if err != nil {
-		log.Error("Unable to connect to docker daemon . Ensure docker is running", "endpoint", endpoint, "err", err)
+		dockerLog.Error("Unable to connect to docker daemon . Ensure docker is running", "endpoint", endpoint, "err", err)
 		return nil, err
 	}
 
@@ -108,7 +148,7 @@ func NewDockerGoClient() (*DockerGoClient, error) {
 	// to ensure it's up.
 	err = client.Ping()
 	if err != nil {
-		log.Error("Unable to ping docker daemon. Ensure docker is running", "endpoint", endpoint, "err", err)
+		dockerLog.Error("Unable to ping docker daemon. Ensure docker is running", "endpoint", endpoint, "err", err)
 		return nil, err
 	}
 
@@ -117,11 +157,15 @@ func NewDockerGoClient() (*DockerGoClient, error) {
 	}, nil
 }
 
-func (dg *DockerGoClient) PullImage(image string) DockerContainerMetadata {
+func (dg *DockerGoClient) PullImage(ctx context.Context, image string) DockerContainerMetadata {
 	timeout := ttime.After(pullImageTimeout)
+	ctx, cancel := context.WithCancel(ctx)
+	// Cancelling the pull once it timed out stops reading its progress, which
+	// ends the request to docker and releases the pull lock
+	defer cancel()
 
 	response := make(chan DockerContainerMetadata, 1)
-	go func() { response <- dg.pullImage(image) }()
+	go func() { response <- dg.pullImage(ctx, image) }()
 	select {
 	case resp := <-response:
 		return resp
@@ -130,8 +174,8 @@ func (dg *DockerGoClient) PullImage(image string) DockerContainerMetadata {
 	}
 }
 
-func (dg *DockerGoClient) pullImage(image string) DockerContainerMetadata {
-	log.Debug("Pulling image", "image", image)
+func (dg *DockerGoClient) pullImage(ctx context.Context, image string) DockerContainerMetadata {
+	dockerLog.Debug("Pulling image", "image", image)
 	client := dg.dockerClient
 
 	// Special case; this image is not one that should be pulled, but rather
@@ -178,20 +222,20 @@ func (dg *DockerGoClient) pullImage(image string) DockerContainerMetadata {
 			pullBeganOnce.Do(func() {
 				pullBegan <- true
 			})
-			log.Debug("Pulling image", "image", image, "status", line)
+			dockerLog.Debug("Pulling image", "image", image, "status", line)
 			if strings.Contains(line, "already being pulled by another client. Waiting.") {
 				// This can mean the deamon is 'hung' in pulling status for this image, but we can't be sure.
-				log.Error("Image 'pull' status marked as already being pulled", "image", image, "status", line)
+				dockerLog.Error("Image 'pull' status marked as already being pulled", "image", image, "status", line)
 			}
 		}
-		if err != nil && err != io.EOF {
-			log.Warn("Error reading pull image status", "image", image, "err", err)
+		if err != nil && err != io.EOF && err != io.ErrClosedPipe {
+			dockerLog.Warn("Error reading pull image status", "image", image, "err", err)
 		}
 	}()
 	pullFinished := make(chan error, 1)
 	go func() {
 		pullFinished <- client.PullImage(opts, authConfig)
-		log.Debug("Pulling image complete", "image", image)
+		dockerLog.Debug("Pulling image complete", "image", image)
 	}()
 
 	select {
@@ -203,16 +247,25 @@ func (dg *DockerGoClient) pullImage(image string) DockerContainerMetadata {
 		}
 		return DockerContainerMetadata{}
 	case <-timeout:
+		pullDebugOut.CloseWithError(errPullAbandoned)
 		return DockerContainerMetadata{Error: &DockerTimeoutError{dockerPullBeginTimeout, "pullBegin"}}
+	case <-ctx.Done():
+		pullDebugOut.CloseWithError(errPullAbandoned)
+		return DockerContainerMetadata{Error: TransitionCancelledError{"pulled"}}
 	}
-	log.Debug("Pull began for image", "image", image)
-	defer log.Debug("Pull completed for image", "image", image)
+	dockerLog.Debug("Pull began for image", "image", image)
+	defer dockerLog.Debug("Pull completed for image", "image", image)
 
-	err := <-pullFinished
-	if err != nil {
-		return DockerContainerMetadata{Error: CannotXContainerError{"Pull", err.Error()}}
+	select {
+	case err := <-pullFinished:
+		if err != nil {
+			return DockerContainerMetadata{Error: CannotXContainerError{"Pull", err.Error()}}
+		}
+		return DockerContainerMetadata{}
+	case <-ctx.Done():
+		pullDebugOut.CloseWithError(errPullAbandoned)
+		return DockerContainerMetadata{Error: TransitionCancelledError{"pulled"}}
 	}
-	return DockerContainerMetadata{}
 }
 
 func (dg *DockerGoClient) createScratchImageIfNotExists() error {
@@ -273,6 +326,9 @@ func (dg *DockerGoClient) createContainer(ctx context.Context, config *docker.Co
 		return DockerContainerMetadata{}
 	default:
 	}
+	if dockerErr, ok := err.(*docker.Error); ok && dockerErr.Status == http.StatusConflict {
+		return DockerContainerMetadata{Error: ContainerNameConflictError{name}}
+	}
 	if err != nil {
 		return DockerContainerMetadata{Error: CannotXContainerError{"Create", err.Error()}}
 	}
@@ -353,26 +409,88 @@ func (dg *DockerGoClient) inspectContainer(dockerId string) (*docker.Container,
 	return dg.dockerClient.InspectContainer(dockerId)
 }
 
-func (dg *DockerGoClient) StopContainer(dockerId string) DockerContainerMetadata {
-	timeout := ttime.After(stopContainerTimeout)
+// ImageRepoDigests returns the repository digests, such as
+// "repository@sha256:...", of a pulled image
+func (dg *DockerGoClient) ImageRepoDigests(image string) ([]string, error) {
+	timeout := ttime.After(inspectContainerTimeout)
+
+	type digestsResponse struct {
+		digests []string
+		err     error
+	}
+	response := make(chan digestsResponse, 1)
+	go func() {
+		digests, err := dg.imageRepoDigests(image)
+		response <- digestsResponse{digests, err}
+	}()
+	select {
+	case resp := <-response:
+		return resp.digests, resp.err
+	case <-timeout:
+		return nil, &DockerTimeoutError{inspectContainerTimeout, "inspecting"}
+	}
+}
+
+// imageRepoDigests finds the image's digests in the image list, as inspecting
+// an image does not return them
+func (dg *DockerGoClient) imageRepoDigests(image string) ([]string, error) {
+	inspected, err := dg.dockerClient.InspectImage(image)
+	if err != nil {
+		return nil, err
+	}
+	images, err := dg.dockerClient.ListImages(docker.ListImagesOptions{Digests: true})
+	if err != nil {
+		return nil, err
+	}
+	for _, listed := range images {
+		if listed.ID == inspected.ID {
+			return listed.RepoDigests, nil
+		}
+	}
+	return nil, nil
+}
+
+func (dg *DockerGoClient) ContainerLogs(dockerID string, tail int, follow bool, output io.Writer) error {
+	// Containers with a tty log a raw stream rather than docker's
+	// multiplexed stdout and stderr
+	container, err := dg.InspectContainer(dockerID)
+	if err != nil {
+		return err
+	}
+	return dg.dockerClient.Logs(docker.LogsOptions{
+		Container:    dockerID,
+		OutputStream: output,
+		ErrorStream:  output,
+		Follow:       follow,
+		Stdout:       true,
+		Stderr:       true,
+		Tail:         strconv.Itoa(tail),
+		RawTerminal:  container.Config != nil && container.Config.Tty,
+	})
+}
+
+func (dg *DockerGoClient) StopContainer(dockerId string, stopTimeout time.Duration) DockerContainerMetadata {
+	// Docker waits out the container's stop timeout before killing it, so
+	// allow for that on top of the time the stop itself may take
+	timeout := ttime.After(stopContainerTimeout + stopTimeout)
 
 	ctx, cancelFunc := context.WithCancel(context.TODO()) // Could pass one through from engine
 	// Buffered channel so in the case of timeout it takes one write, never gets
 	// read, and can still be GC'd
 	response := make(chan DockerContainerMetadata, 1)
-	go func() { response <- dg.stopContainer(ctx, dockerId) }()
+	go func() { response <- dg.stopContainer(ctx, dockerId, stopTimeout) }()
 	select {
 	case resp := <-response:
 		return resp
 	case <-timeout:
 		cancelFunc()
-		return DockerContainerMetadata{Error: &DockerTimeoutError{stopContainerTimeout, "stopped"}}
+		return DockerContainerMetadata{Error: &DockerTimeoutError{stopContainerTimeout + stopTimeout, "stopped"}}
 	}
 }
 
-func (dg *DockerGoClient) stopContainer(ctx context.Context, dockerId string) DockerContainerMetadata {
+func (dg *DockerGoClient) stopContainer(ctx context.Context, dockerId string, stopTimeout time.Duration) DockerContainerMetadata {
 	client := dg.dockerClient
-	err := client.StopContainer(dockerId, dockerStopTimeoutSeconds)
+	err := client.StopContainer(dockerId, uint(stopTimeout.Seconds()))
 	select {
 	case <-ctx.Done():
 		// parent function has already timed out and returned; we're writing to a
@@ -382,7 +500,63 @@ func (dg *DockerGoClient) stopContainer(ctx context.Context, dockerId string) Do
 	}
 	metadata := dg.containerMetadata(dockerId)
 	if err != nil {
-		log.Debug("Error stopping container", "err", err, "id", dockerId)
+		dockerLog.Debug("Error stopping container", "err", err, "id", dockerId)
+		if metadata.Error == nil {
+			metadata.Error = CannotXContainerError{"Stop", err.Error()}
+		}
+	}
+	return metadata
+}
+
+func (dg *DockerGoClient) StopContainerWithSignal(dockerId string, signal docker.Signal, stopTimeout time.Duration) DockerContainerMetadata {
+	timeout := ttime.After(stopContainerTimeout + stopTimeout)
+
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	response := make(chan DockerContainerMetadata, 1)
+	go func() { response <- dg.stopContainerWithSignal(ctx, dockerId, signal, stopTimeout) }()
+	select {
+	case resp := <-response:
+		return resp
+	case <-timeout:
+		cancelFunc()
+		return DockerContainerMetadata{Error: &DockerTimeoutError{stopContainerTimeout + stopTimeout, "stopped"}}
+	}
+}
+
+// stopContainerWithSignal does what 'docker stop' does for a container
+// created with a stop signal, which this version of the docker api can't
+// create containers with: it sends the signal, then kills the container if
+// it has not exited within the stop timeout
+func (dg *DockerGoClient) stopContainerWithSignal(ctx context.Context, dockerId string, signal docker.Signal, stopTimeout time.Duration) DockerContainerMetadata {
+	client := dg.dockerClient
+	err := client.KillContainer(docker.KillContainerOptions{ID: dockerId, Signal: signal})
+	if err == nil {
+		exited := make(chan struct{})
+		go func() {
+			client.WaitContainer(dockerId)
+			close(exited)
+		}()
+		select {
+		case <-exited:
+		case <-ttime.After(stopTimeout):
+			err = client.KillContainer(docker.KillContainerOptions{ID: dockerId, Signal: docker.SIGKILL})
+			// The wait returns once the killed container exits. Waiting
+			// for it keeps its goroutine from outliving the stop, unless
+			// docker hangs and the stop is abandoned.
+			select {
+			case <-exited:
+			case <-ctx.Done():
+			}
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return DockerContainerMetadata{}
+	default:
+	}
+	metadata := dg.containerMetadata(dockerId)
+	if err != nil {
+		dockerLog.Debug("Error stopping container", "err", err, "id", dockerId, "signal", signal)
 		if metadata.Error == nil {
 			metadata.Error = CannotXContainerError{"Stop", err.Error()}
 		}
@@ -407,6 +581,38 @@ func (dg *DockerGoClient) removeContainer(dockerId string) error {
 	return dg.dockerClient.RemoveContainer(docker.RemoveContainerOptions{ID: dockerId, RemoveVolumes: true, Force: false})
 }
 
+func (dg *DockerGoClient) RemoveImage(image string) error {
+	timeout := ttime.After(removeImageTimeout)
+
+	response := make(chan error, 1)
+	go func() { response <- dg.dockerClient.RemoveImage(image) }()
+	select {
+	case resp := <-response:
+		return resp
+	case <-timeout:
+		return &DockerTimeoutError{removeImageTimeout, "removing image"}
+	}
+}
+
+func (dg *DockerGoClient) TagImage(image, name string) error {
+	timeout := ttime.After(tagImageTimeout)
+
+	repository, tag := parsers.ParseRepositoryTag(name)
+	opts := docker.TagImageOptions{
+		Repo:  repository,
+		Tag:   utils.DefaultIfBlank(tag, dockerDefaultTag),
+		Force: true,
+	}
+	response := make(chan error, 1)
+	go func() { response <- dg.dockerClient.TagImage(image, opts) }()
+	select {
+	case resp := <-response:
+		return resp
+	case <-timeout:
+		return &DockerTimeoutError{tagImageTimeout, "tagging image"}
+	}
+}
+
 func (dg *DockerGoClient) GetContainerName(id string) (string, error) {
 	container, err := dg.InspectContainer(id)
 	if err != nil {
@@ -430,7 +636,7 @@ func metadataFromContainer(dockerContainer *docker.Container) DockerContainerMet
 		// Convert port bindings into the format our container expects
 		bindings, err = api.PortBindingFromDockerPortBinding(dockerContainer.NetworkSettings.Ports)
 		if err != nil {
-			log.Crit("Docker had network bindings we couldn't understand", "err", err)
+			dockerLog.Crit("Docker had network bindings we couldn't understand", "err", err)
 			return DockerContainerMetadata{Error: api.NamedError(err)}
 		}
 	}
@@ -441,6 +647,7 @@ func metadataFromContainer(dockerContainer *docker.Container) DockerContainerMet
 	}
 	if dockerContainer.State.Running == false {
 		metadata.ExitCode = &dockerContainer.State.ExitCode
+		metadata.FinishedAt = dockerContainer.State.FinishedAt
 	}
 	if dockerContainer.State.Error != "" {
 		metadata.Error = NewDockerStateError(dockerContainer.State.Error)
@@ -459,7 +666,7 @@ func (dg *DockerGoClient) ContainerEvents(ctx context.Context) (<-chan DockerCon
 
 	err := client.AddEventListener(events)
 	if err != nil {
-		log.Error("Unable to add a docker event listener", "err", err)
+		dockerLog.Error("Unable to add a docker event listener", "err", err)
 		return nil, err
 	}
 	go func() {
@@ -467,15 +674,29 @@ func (dg *DockerGoClient) ContainerEvents(ctx context.Context) (<-chan DockerCon
 		client.RemoveEventListener(events)
 	}()
 
-	changedContainers := make(chan DockerContainerChangeEvent)
-
+	queue := newDockerEventQueue()
+	dg.eventQueueLock.Lock()
+	dg.eventQueue = queue
+	dg.eventQueueLock.Unlock()
 	go func() {
 		for event := range events {
-			containerId := event.ID
-			if containerId == "" {
+			if event.ID == "" {
 				continue
 			}
-			log.Debug("Got event from docker daemon", "event", event)
+			dockerLog.Debug("Got event from docker daemon", "event", event)
+			queue.push(event)
+		}
+	}()
+
+	changedContainers := make(chan DockerContainerChangeEvent)
+
+	go func() {
+		for {
+			event, ok := queue.pop(ctx)
+			if !ok {
+				return
+			}
+			containerId := event.ID
 
 			var status api.ContainerStatus
 			switch event.Status {
@@ -508,10 +729,18 @@ func (dg *DockerGoClient) ContainerEvents(ctx context.Context) (<-chan DockerCon
 				// No interest in image events
 				continue
 			default:
-				log.Info("Unknown status event! Maybe docker updated? ", "status", event.Status)
+				dockerLog.Info("Unknown status event! Maybe docker updated? ", "status", event.Status)
 			}
 
 			metadata := dg.containerMetadata(containerId)
+			if status == api.ContainerStopped && metadata.Error == nil && metadata.ExitCode == nil {
+				// 'oom' and 'kill' can arrive while the container is still
+				// running. Wait for its 'die' event, which carries the exit
+				// code and OOMKilled state, rather than reporting it stopped
+				// without them.
+				dockerLog.Debug("Container still running after stop event; waiting for it to die", "event", event)
+				continue
+			}
 
 			changedContainers <- DockerContainerChangeEvent{
 				Status:                  status,
@@ -523,6 +752,16 @@ func (dg *DockerGoClient) ContainerEvents(ctx context.Context) (<-chan DockerCon
 	return changedContainers, nil
 }
 
+// EventQueueDepth returns how many docker events are waiting to be processed
+func (dg *DockerGoClient) EventQueueDepth() EventQueueDepth {
+	dg.eventQueueLock.RLock()
+	defer dg.eventQueueLock.RUnlock()
+	if dg.eventQueue == nil {
+		return EventQueueDepth{}
+	}
+	return dg.eventQueue.currentDepth()
+}
+
 // ListContainers returns a slice of container IDs.
 func (dg *DockerGoClient) ListContainers(all bool) ListContainersResponse {
 	timeout := ttime.After(listContainersTimeout)
@@ -555,6 +794,11 @@ func (dg *DockerGoClient) listContainers(all bool) ListContainersResponse {
 	return ListContainersResponse{DockerIds: containerIDs, Error: nil}
 }
 
+// Info returns docker's system-wide information
+func (dg *DockerGoClient) Info() (*docker.Env, error) {
+	return dg.dockerClient.Info()
+}
+
 func (dg *DockerGoClient) Version() (string, error) {
 	client := dg.dockerClient
 	info, err := client.Version()
@@ -14,6 +14,7 @@
 package engine
 
 import (
+	"bytes"
 	"errors"
 	"reflect"
 	"strconv"
@@ -66,7 +67,7 @@ func TestPullImageTimeout(t *testing.T) {
 		// Don't return, verify timeout happens
 	})
 
-	metadata := client.PullImage("image")
+	metadata := client.PullImage(context.Background(), "image")
 	if metadata.Error == nil {
 		t.Error("Expected error for pull timeout")
 	}
@@ -77,13 +78,43 @@ func TestPullImageTimeout(t *testing.T) {
 	wait.Done()
 }
 
+func TestPullImageCancelled(t *testing.T) {
+	mockDocker, client, _, done := dockerclientSetup(t)
+	defer done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	gomock.InOrder(
+		mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{"image:latest"}, gomock.Any()).Do(func(x, y interface{}) {
+			// Like the docker client, keep copying progress until it can't
+			out := x.(docker.PullImageOptions).OutputStream
+			for {
+				if _, err := out.Write([]byte("Downloading\n")); err != nil {
+					return
+				}
+				cancel()
+			}
+		}).Return(errors.New("write failed")),
+		mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{"other:latest"}, gomock.Any()).Return(nil),
+	)
+
+	metadata := client.PullImage(ctx, "image")
+	if _, ok := metadata.Error.(TransitionCancelledError); !ok {
+		t.Fatal("Expected the pull to be cancelled", metadata.Error)
+	}
+	// The abandoned pull must not keep holding the pull lock
+	metadata = client.PullImage(context.Background(), "other")
+	if metadata.Error != nil {
+		t.Error("Expected the next pull to succeed", metadata.Error)
+	}
+}
+
 func TestPullImage(t *testing.T) {
 	mockDocker, client, _, done := dockerclientSetup(t)
 	defer done()
 
 	mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{"image:latest"}, gomock.Any()).Return(nil)
 
-	metadata := client.PullImage("image")
+	metadata := client.PullImage(context.Background(), "image")
 	if metadata.Error != nil {
 		t.Error("Expected pull to succeed")
 	}
@@ -108,7 +139,7 @@ func TestPullEmptyvolumeImage(t *testing.T) {
 		}),
 	)
 
-	metadata := client.PullImage(emptyvolume.Image + ":" + emptyvolume.Tag)
+	metadata := client.PullImage(context.Background(), emptyvolume.Image+":"+emptyvolume.Tag)
 	if metadata.Error != nil {
 		t.Error(metadata.Error)
 	}
@@ -123,7 +154,7 @@ func TestPullExistingEmptyvolumeImage(t *testing.T) {
 		mockDocker.EXPECT().InspectImage(emptyvolume.Image+":"+emptyvolume.Tag).Return(&docker.Image{}, nil),
 	)
 
-	metadata := client.PullImage(emptyvolume.Image + ":" + emptyvolume.Tag)
+	metadata := client.PullImage(context.Background(), emptyvolume.Image+":"+emptyvolume.Tag)
 	if metadata.Error != nil {
 		t.Error(metadata.Error)
 	}
@@ -213,12 +244,12 @@ func TestStopContainerTimeout(t *testing.T) {
 
 	wait := &sync.WaitGroup{}
 	wait.Add(1)
-	mockDocker.EXPECT().StopContainer("id", uint(dockerStopTimeoutSeconds)).Do(func(x, y interface{}) {
-		testTime.Warp(stopContainerTimeout)
+	mockDocker.EXPECT().StopContainer("id", uint(30)).Do(func(x, y interface{}) {
+		testTime.Warp(stopContainerTimeout + 30*time.Second)
 		wait.Wait()
 		// Don't return, verify timeout happens
 	})
-	metadata := client.StopContainer("id")
+	metadata := client.StopContainer("id", 30*time.Second)
 	if metadata.Error == nil {
 		t.Error("Expected error for pull timeout")
 	}
@@ -233,10 +264,10 @@ func TestStopContainer(t *testing.T) {
 	defer done()
 
 	gomock.InOrder(
-		mockDocker.EXPECT().StopContainer("id", uint(dockerStopTimeoutSeconds)).Return(nil),
+		mockDocker.EXPECT().StopContainer("id", uint(90)).Return(nil),
 		mockDocker.EXPECT().InspectContainer("id").Return(&docker.Container{ID: "id", State: docker.State{ExitCode: 10}}, nil),
 	)
-	metadata := client.StopContainer("id")
+	metadata := client.StopContainer("id", 90*time.Second)
 	if metadata.Error != nil {
 		t.Error("Did not expect error")
 	}
@@ -245,6 +276,52 @@ func TestStopContainer(t *testing.T) {
 	}
 }
 
+func TestStopContainerWithSignal(t *testing.T) {
+	mockDocker, client, _, done := dockerclientSetup(t)
+	defer done()
+
+	gomock.InOrder(
+		mockDocker.EXPECT().KillContainer(docker.KillContainerOptions{ID: "id", Signal: docker.SIGQUIT}).Return(nil),
+		mockDocker.EXPECT().WaitContainer("id").Return(0, nil),
+		mockDocker.EXPECT().InspectContainer("id").Return(&docker.Container{ID: "id", State: docker.State{ExitCode: 0}}, nil),
+	)
+	metadata := client.StopContainerWithSignal("id", docker.SIGQUIT, 90*time.Second)
+	if metadata.Error != nil {
+		t.Error("Did not expect error", metadata.Error)
+	}
+	if metadata.DockerId != "id" {
+		t.Error("Wrong id")
+	}
+}
+
+func TestStopContainerWithSignalKillsAfterTimeout(t *testing.T) {
+	mockDocker, client, _, done := dockerclientSetup(t)
+	defer done()
+
+	killed := make(chan struct{})
+	waited := make(chan struct{})
+	mockDocker.EXPECT().KillContainer(docker.KillContainerOptions{ID: "id", Signal: docker.SIGUSR2}).Return(nil)
+	mockDocker.EXPECT().WaitContainer("id").Do(func(id string) {
+		// The container only exits once it is killed
+		<-killed
+		close(waited)
+	}).Return(137, nil)
+	mockDocker.EXPECT().KillContainer(docker.KillContainerOptions{ID: "id", Signal: docker.SIGKILL}).Do(func(opts docker.KillContainerOptions) {
+		close(killed)
+	}).Return(nil)
+	mockDocker.EXPECT().InspectContainer("id").Return(&docker.Container{ID: "id", State: docker.State{ExitCode: 137}}, nil)
+
+	metadata := client.StopContainerWithSignal("id", docker.SIGUSR2, 10*time.Millisecond)
+	if metadata.Error != nil {
+		t.Error("Did not expect error", metadata.Error)
+	}
+	select {
+	case <-waited:
+	default:
+		t.Error("Expected the wait for the container to exit to be over")
+	}
+}
+
 func TestInspectContainerTimeout(t *testing.T) {
 	mockDocker, client, testTime, done := dockerclientSetup(t)
 	defer done()
@@ -283,6 +360,48 @@ func TestInspectContainer(t *testing.T) {
 	}
 }
 
+func TestImageRepoDigests(t *testing.T) {
+	mockDocker, client, _, done := dockerclientSetup(t)
+	defer done()
+
+	gomock.InOrder(
+		mockDocker.EXPECT().InspectImage("app:v1").Return(&docker.Image{ID: "sha256:2"}, nil),
+		mockDocker.EXPECT().ListImages(docker.ListImagesOptions{Digests: true}).Return([]docker.APIImages{
+			{ID: "sha256:1", RepoDigests: []string{"other@sha256:a"}},
+			{ID: "sha256:2", RepoDigests: []string{"app@sha256:b"}},
+		}, nil),
+	)
+	digests, err := client.ImageRepoDigests("app:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(digests, []string{"app@sha256:b"}) {
+		t.Error("Unexpected digests", digests)
+	}
+}
+
+func TestContainerLogs(t *testing.T) {
+	mockDocker, client, _, done := dockerclientSetup(t)
+	defer done()
+
+	var output bytes.Buffer
+	gomock.InOrder(
+		mockDocker.EXPECT().InspectContainer("id").Return(&docker.Container{Config: &docker.Config{Tty: true}}, nil),
+		mockDocker.EXPECT().Logs(gomock.Any()).Do(func(opts docker.LogsOptions) {
+			if opts.Container != "id" || opts.Tail != "10" || !opts.Follow || !opts.RawTerminal || !opts.Stdout || !opts.Stderr {
+				t.Error("Unexpected logs options", opts)
+			}
+			opts.OutputStream.Write([]byte("line\n"))
+		}).Return(nil),
+	)
+	if err := client.ContainerLogs("id", 10, true, &output); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "line\n" {
+		t.Error("Unexpected logs", output.String())
+	}
+}
+
 func TestContainerEvents(t *testing.T) {
 	mockDocker, client, _, done := dockerclientSetup(t)
 	defer done()
@@ -365,6 +484,26 @@ func TestContainerEvents(t *testing.T) {
 		}
 	}
 
+	// An oom event while the container is still running is held back until
+	// the container dies, at which point the OOM is reported
+	runningContainer := &docker.Container{ID: "cid4", State: docker.State{Running: true}}
+	oomContainer := &docker.Container{ID: "cid4", State: docker.State{ExitCode: 137, OOMKilled: true}}
+	gomock.InOrder(
+		mockDocker.EXPECT().InspectContainer("cid4").Return(runningContainer, nil),
+		mockDocker.EXPECT().InspectContainer("cid4").Return(oomContainer, nil),
+	)
+	go func() {
+		events <- &docker.APIEvents{ID: "cid4", Status: "oom"}
+		events <- &docker.APIEvents{ID: "cid4", Status: "die"}
+	}()
+	anEvent := <-dockerEvents
+	if anEvent.Status != api.ContainerStopped || anEvent.ExitCode == nil || *anEvent.ExitCode != 137 {
+		t.Error("Expected the die event with its exit code", anEvent)
+	}
+	if _, ok := anEvent.Error.(OutOfMemoryError); !ok {
+		t.Error("Expected an OutOfMemoryError", anEvent.Error)
+	}
+
 	// Verify the following events do not translate into our event stream
 	for _, eventStatus := range []string{"pause", "export", "pull", "untag", "delete"} {
 		events <- &docker.APIEvents{ID: "123", Status: eventStatus}
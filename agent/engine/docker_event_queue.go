@@ -0,0 +1,169 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sync"
+
+	"github.com/fsouza/go-dockerclient"
+	"golang.org/x/net/context"
+)
+
+// Docker events are processed in order of priority, so that containers which
+// stop are handled promptly even while the daemon sends events faster than
+// they can be inspected, such as when many containers exit at once
+const (
+	dockerEventPriorityStop = iota
+	dockerEventPriorityOther
+	dockerEventPriorities
+)
+
+// eventQueueWarningDepth is how many docker events may be waiting before a
+// warning is logged
+const eventQueueWarningDepth = 100
+
+// EventQueueDepth is how many docker events are waiting to be processed
+type EventQueueDepth struct {
+	// Stop counts stop, die, oom and kill events, which are processed first
+	Stop int
+	// Other counts all other events
+	Other int
+	// MaxStop and MaxOther are the most of each that have been waiting at once
+	MaxStop  int
+	MaxOther int
+	// Superseded counts events dropped because a later stop event for the
+	// same container was queued; its inspection describes the container
+	// more recently than theirs would have
+	Superseded int
+}
+
+// dockerEventPriority returns the priority of a docker event status
+func dockerEventPriority(status string) int {
+	switch status {
+	case "stop", "die", "oom", "kill":
+		return dockerEventPriorityStop
+	}
+	return dockerEventPriorityOther
+}
+
+// dockerEventQueue holds docker events until they are processed, highest
+// priority first. It is not bounded, so that the daemon's event stream is
+// never blocked by slow processing.
+type dockerEventQueue struct {
+	lock   sync.Mutex
+	queues [dockerEventPriorities][]*docker.APIEvents
+	depth  EventQueueDepth
+	warned bool
+	// ready is signalled when an event is pushed
+	ready chan struct{}
+}
+
+func newDockerEventQueue() *dockerEventQueue {
+	return &dockerEventQueue{ready: make(chan struct{}, 1)}
+}
+
+// push queues an event. A stop event supersedes the other events queued for
+// the same container.
+func (queue *dockerEventQueue) push(event *docker.APIEvents) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	priority := dockerEventPriority(event.Status)
+	if priority == dockerEventPriorityStop {
+		other := queue.queues[dockerEventPriorityOther][:0]
+		for _, queued := range queue.queues[dockerEventPriorityOther] {
+			if queued.ID == event.ID {
+				queue.depth.Superseded++
+				continue
+			}
+			other = append(other, queued)
+		}
+		queue.queues[dockerEventPriorityOther] = other
+	}
+	queue.queues[priority] = append(queue.queues[priority], event)
+	queue.updateDepth()
+
+	select {
+	case queue.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pop returns the next event to process, waiting for one to be pushed, or
+// false once ctx is done
+func (queue *dockerEventQueue) pop(ctx context.Context) (*docker.APIEvents, bool) {
+	for {
+		if event := queue.next(); event != nil {
+			return event, true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-queue.ready:
+		}
+	}
+}
+
+// next removes and returns the highest priority event, or nil if none are
+// queued
+func (queue *dockerEventQueue) next() *docker.APIEvents {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	for priority := range queue.queues {
+		if len(queue.queues[priority]) == 0 {
+			continue
+		}
+		event := queue.queues[priority][0]
+		queue.queues[priority][0] = nil
+		queue.queues[priority] = queue.queues[priority][1:]
+		queue.updateDepth()
+		return event
+	}
+	return nil
+}
+
+func (queue *dockerEventQueue) updateDepth() {
+	queue.depth.Stop = len(queue.queues[dockerEventPriorityStop])
+	queue.depth.Other = len(queue.queues[dockerEventPriorityOther])
+	if queue.depth.Stop > queue.depth.MaxStop {
+		queue.depth.MaxStop = queue.depth.Stop
+	}
+	if queue.depth.Other > queue.depth.MaxOther {
+		queue.depth.MaxOther = queue.depth.Other
+	}
+
+	waiting := queue.depth.Stop + queue.depth.Other
+	if waiting >= eventQueueWarningDepth && !queue.warned {
+		dockerLog.Warn("Docker events are arriving faster than they are processed", "stop", queue.depth.Stop, "other", queue.depth.Other)
+		queue.warned = true
+	} else if waiting == 0 && queue.warned {
+		dockerLog.Info("Caught up processing docker events", "maxStop", queue.depth.MaxStop, "maxOther", queue.depth.MaxOther)
+		queue.warned = false
+	}
+}
+
+// currentDepth returns how many events are waiting to be processed
+func (queue *dockerEventQueue) currentDepth() EventQueueDepth {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	return queue.depth
+}
+
+// EventQueueDepth returns how many docker events are waiting to be processed
+func (engine *DockerTaskEngine) EventQueueDepth() EventQueueDepth {
+	if engine.client == nil {
+		return EventQueueDepth{}
+	}
+	return engine.client.EventQueueDepth()
+}
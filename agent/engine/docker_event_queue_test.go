@@ -0,0 +1,67 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"golang.org/x/net/context"
+)
+
+func TestDockerEventQueuePrioritizesStops(t *testing.T) {
+	queue := newDockerEventQueue()
+	queue.push(&docker.APIEvents{ID: "a", Status: "create"})
+	queue.push(&docker.APIEvents{ID: "b", Status: "start"})
+	queue.push(&docker.APIEvents{ID: "c", Status: "exec_start"})
+	queue.push(&docker.APIEvents{ID: "b", Status: "die"})
+	queue.push(&docker.APIEvents{ID: "d", Status: "oom"})
+
+	depth := queue.currentDepth()
+	if depth.Stop != 2 || depth.Other != 2 || depth.Superseded != 1 {
+		t.Error("Wrong depth", depth)
+	}
+
+	expected := []string{"b/die", "d/oom", "a/create", "c/exec_start"}
+	for _, want := range expected {
+		event, ok := queue.pop(context.Background())
+		if !ok || event.ID+"/"+event.Status != want {
+			t.Fatal("Expected", want, "got", event)
+		}
+	}
+	depth = queue.currentDepth()
+	if depth.Stop != 0 || depth.Other != 0 || depth.MaxStop != 2 || depth.MaxOther != 3 {
+		t.Error("Wrong depth after processing", depth)
+	}
+}
+
+func TestDockerEventQueuePopWaits(t *testing.T) {
+	queue := newDockerEventQueue()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	popped := make(chan *docker.APIEvents)
+	go func() {
+		event, _ := queue.pop(ctx)
+		popped <- event
+	}()
+	queue.push(&docker.APIEvents{ID: "a", Status: "kill"})
+	if event := <-popped; event == nil || event.ID != "a" {
+		t.Error("Expected the pushed event, got", event)
+	}
+
+	cancel()
+	if _, ok := queue.pop(ctx); ok {
+		t.Error("Expected no event once cancelled")
+	}
+}
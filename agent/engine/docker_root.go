@@ -0,0 +1,35 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import "github.com/aws/amazon-ecs-agent/agent/config"
+
+// DetectDockerGraphPath returns the configured docker graph path, or asks the
+// docker daemon for its root directory if none is configured. Daemons which
+// can't be reached or are too old to report it are assumed to use the
+// default.
+func DetectDockerGraphPath(client DockerClient, configured string) string {
+	if configured != "" {
+		return configured
+	}
+	info, err := client.Info()
+	if err != nil {
+		log.Warn("Could not get the root directory from docker; assuming the default", "default", config.DefaultDockerGraphPath, "err", err)
+		return config.DefaultDockerGraphPath
+	}
+	if root := info.Get("DockerRootDir"); root != "" {
+		return root
+	}
+	return config.DefaultDockerGraphPath
+}
@@ -0,0 +1,48 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// infoClient answers Info with a fixed environment
+type infoClient struct {
+	DockerClient
+	info *docker.Env
+	err  error
+}
+
+func (client *infoClient) Info() (*docker.Env, error) {
+	return client.info, client.err
+}
+
+func TestDetectDockerGraphPath(t *testing.T) {
+	if path := DetectDockerGraphPath(&infoClient{}, "/data/docker"); path != "/data/docker" {
+		t.Error("Expected the configured path, got", path)
+	}
+	if path := DetectDockerGraphPath(&infoClient{info: &docker.Env{"DockerRootDir=/mnt/docker"}}, ""); path != "/mnt/docker" {
+		t.Error("Expected the daemon's root, got", path)
+	}
+	if path := DetectDockerGraphPath(&infoClient{info: &docker.Env{}}, ""); path != config.DefaultDockerGraphPath {
+		t.Error("Expected the default from an older daemon, got", path)
+	}
+	if path := DetectDockerGraphPath(&infoClient{err: errors.New("unreachable")}, ""); path != config.DefaultDockerGraphPath {
+		t.Error("Expected the default when docker is unreachable, got", path)
+	}
+}
@@ -17,6 +17,7 @@ package engine
 
 import (
 	"errors"
+	"io"
 	"sync"
 	"time"
 
@@ -24,16 +25,29 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/downloader"
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerauth"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	utilsync "github.com/aws/amazon-ecs-agent/agent/utils/sync"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	docker "github.com/fsouza/go-dockerclient"
 )
 
 const (
 	DOCKER_ENDPOINT_ENV_VARIABLE = "DOCKER_HOST"
 	DOCKER_DEFAULT_ENDPOINT      = "unix:///var/run/docker.sock"
+
+	// defaultImagePullConcurrency is used when the configuration does not
+	// specify how many images may be pulled at once
+	defaultImagePullConcurrency = 4
+
+	// defaultTaskReconciliationInterval is used when the configuration does
+	// not specify how often to reconcile tasks with docker
+	defaultTaskReconciliationInterval = 10 * time.Minute
 )
 
 // The DockerTaskEngine interacts with docker to implement a task
@@ -41,6 +55,8 @@ const (
 type DockerTaskEngine struct {
 	// implements TaskEngine
 
+	cfg *config.Config
+
 	// state stores all tasks this task engine is aware of, including their
 	// current state and mappings to/from dockerId and name.
 	// This is used to checkpoint state to disk so tasks may survive agent
@@ -55,10 +71,75 @@ type DockerTaskEngine struct {
 	taskEvents      chan api.TaskStateChange
 	saver           statemanager.Saver
 
+	// livenessProbes are taken by the loop handling docker events, to show
+	// that it is still advancing
+	livenessProbes chan struct{}
+
 	client DockerClient
 
+	// mounter mounts task volumes, such as EFS and tmpfs scratch volumes, on
+	// the host. mountLocks serializes mounting and unmounting each mount
+	// point.
+	mounter    volumeMounter
+	mountLocks *utilsync.KeyedMutex
+
+	// netns runs network diagnostics in task network namespaces
+	netns netnsRunner
+
+	// preStartHooks are asked whether each container may start
+	preStartHooks []PreStartHook
+
+	// taskHooks are run before each task's first container is created and
+	// after it stops
+	taskHooks []TaskHook
+
+	// imageVerifier, if set, verifies the signature of each image before a
+	// container is created from it. Results are cached by digest.
+	imageVerifier      imageVerifier
+	imageVerifications *imageVerificationCache
+
+	// manifests, if set, picks the manifest of each image for the platform
+	// its task runs on before it is pulled
+	manifests manifestResolver
+
+	// artifacts downloads the files declared on containers before they are
+	// created
+	artifacts *downloader.Downloader
+
+	// containerDefaults are merged under the settings of each container the
+	// engine creates
+	containerDefaults *config.ContainerDefaults
+
+	// pullSemaphore bounds the number of concurrent image pulls
+	pullSemaphore utils.Semaphore
+	// prePulled are the images pulled ahead of the tasks using them, which
+	// are kept when tasks are cleaned up
+	prePulled *prePulledImages
+
 	stopEngine context.CancelFunc
 
+	// cleanup releases stopped tasks for cleanup early when the disk is under
+	// pressure
+	cleanup *expeditedCleanup
+
+	// tracer traces task launches; it is nil when tracing is disabled
+	tracer *tracing.Tracer
+
+	// containerInstanceArn and placement describe where containers run, for
+	// their environment
+	instanceLock         sync.RWMutex
+	containerInstanceArn string
+	placement            ec2.InstancePlacement
+
+	// cpuSets tracks the cores pinned to containers
+	cpuSets *cpuSetAllocator
+
+	// containerNames names the containers the engine creates
+	containerNames containerNameTemplate
+
+	// cleanupReport records what task cleanups left behind
+	cleanupReport cleanupReport
+
 	// processTasks is a mutex that the task engine must aquire before changing
 	// any task's state which it manages. Since this is a lock that encompasses
 	// all tasks, it must not aquire it for any significant duration
@@ -72,18 +153,51 @@ type DockerTaskEngine struct {
 // is also initialized.
 func NewDockerTaskEngine(cfg *config.Config) *DockerTaskEngine {
 	dockerTaskEngine := &DockerTaskEngine{
-		client: nil,
-		saver:  statemanager.NewNoopStateManager(),
+		cfg:           cfg,
+		client:        nil,
+		mounter:       execVolumeMounter{},
+		mountLocks:    utilsync.NewKeyedMutex(),
+		netns:         nsenterRunner{},
+		preStartHooks: newPreStartHooks(cfg),
+		taskHooks:     newTaskHooks(cfg),
+		saver:         statemanager.NewNoopStateManager(),
+
+		imageVerifier:      newImageVerifier(cfg),
+		imageVerifications: newImageVerificationCache(),
+		manifests:          newManifestResolver(cfg),
+		artifacts:          newArtifactDownloader(cfg),
+		containerDefaults:  cfg.ContainerDefaults,
 
 		state:         dockerstate.NewDockerTaskEngineState(),
 		managedTasks:  make(map[string]*managedTask),
 		taskStopGroup: utilsync.NewSequentialWaitGroup(),
+		cleanup:       newExpeditedCleanup(),
+		cpuSets:       newCPUSetAllocator(cfg.NUMAPlacement),
+		prePulled:     newPrePulledImages(),
 
+		livenessProbes:  make(chan struct{}),
 		containerEvents: make(chan api.ContainerStateChange),
 		taskEvents:      make(chan api.TaskStateChange),
 	}
 	dockerauth.SetConfig(cfg)
 
+	pullConcurrency := cfg.ImagePullConcurrency
+	if pullConcurrency <= 0 {
+		pullConcurrency = defaultImagePullConcurrency
+	}
+	dockerTaskEngine.pullSemaphore = utils.NewSemaphore(pullConcurrency)
+
+	containerNames, err := parseContainerNameTemplate(cfg.ContainerNameTemplate)
+	if err != nil {
+		log.Warn("Invalid container name template; using the default", "template", cfg.ContainerNameTemplate, "err", err)
+		containerNames = defaultContainerNameTemplate
+	}
+	dockerTaskEngine.containerNames = containerNames
+
+	if cfg.TaskTracing && cfg.OTLPEndpoint != "" {
+		dockerTaskEngine.tracer = tracing.New(cfg.OTLPEndpoint, map[string]string{"aws.ecs.cluster.arn": cfg.Cluster})
+	}
+
 	return dockerTaskEngine
 }
 
@@ -119,6 +233,11 @@ func (engine *DockerTaskEngine) Init() error {
 	engine.synchronizeState()
 	// Now catch up and start processing new events per normal
 	go engine.handleDockerEvents(ctx)
+	go engine.reconcileTasksPeriodically(ctx)
+	go engine.monitorDiskPressure(ctx)
+	if err := engine.PrePullImages(engine.cfg.PrePullImages); err != nil {
+		log.Warn("Unable to pre-pull all images", "err", err)
+	}
 
 	return nil
 }
@@ -130,6 +249,8 @@ func (engine *DockerTaskEngine) initDockerClient() error {
 			return err
 		}
 		engine.client = client
+		engine.cfg.DockerGraphPath = DetectDockerGraphPath(client, engine.cfg.DockerGraphPath)
+		log.Info("Using docker graph path", "path", engine.cfg.DockerGraphPath)
 	}
 	return nil
 }
@@ -210,10 +331,13 @@ func (engine *DockerTaskEngine) synchronizeState() {
 						log.Warn("Could not describe previously known container; assuming dead", "err", metadata.Error, "id", cont.DockerId, "name", cont.DockerName)
 					}
 				}
-				if currentState > cont.Container.KnownStatus {
-					cont.Container.KnownStatus = currentState
+				if currentState > cont.Container.GetKnownStatus() {
+					cont.Container.SetKnownStatus(currentState)
 				}
 			}
+			if !cont.Container.KnownTerminal() {
+				engine.cpuSets.restore(task, cont.Container)
+			}
 		}
 		engine.startTask(task)
 	}
@@ -234,10 +358,10 @@ func (engine *DockerTaskEngine) CheckTaskState(task *api.Task) {
 			continue
 		}
 		status, metadata := engine.client.DescribeContainer(dockerContainer.DockerId)
+		// Hold the lock while sending so that the task's cleanup cannot close
+		// the channel in between
 		engine.processTasks.RLock()
 		managedTask, ok := engine.managedTasks[task.Arn]
-		engine.processTasks.RUnlock()
-
 		if ok {
 			managedTask.dockerMessages <- dockerContainerChange{
 				container: container,
@@ -247,6 +371,45 @@ func (engine *DockerTaskEngine) CheckTaskState(task *api.Task) {
 				},
 			}
 		}
+		engine.processTasks.RUnlock()
+	}
+}
+
+// reconcileTasksPeriodically is a slow sweep which inspects every active task's
+// containers. The engine is otherwise driven entirely by docker events; this
+// only catches changes whose events were missed.
+func (engine *DockerTaskEngine) reconcileTasksPeriodically(ctx context.Context) {
+	interval := engine.cfg.TaskReconciliationInterval
+	if interval <= 0 {
+		interval = defaultTaskReconciliationInterval
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ttime.After(interval):
+		}
+		engine.reconcileTasks()
+	}
+}
+
+// reconcileTasks checks the state of every task which is not yet stopped. Tasks
+// are checked one at a time to avoid a burst of docker calls on hosts with
+// many containers.
+func (engine *DockerTaskEngine) reconcileTasks() {
+	engine.processTasks.RLock()
+	tasks := make([]*api.Task, 0, len(engine.managedTasks))
+	for _, task := range engine.managedTasks {
+		// Each task's own goroutine may be changing its status
+		if knownStatus := task.GetKnownStatus(); !knownStatus.Terminal() {
+			tasks = append(tasks, task.Task)
+		}
+	}
+	engine.processTasks.RUnlock()
+
+	log.Debug("Reconciling tasks with docker", "count", len(tasks))
+	for _, task := range tasks {
+		engine.CheckTaskState(task)
 	}
 }
 
@@ -258,21 +421,39 @@ func (engine *DockerTaskEngine) sweepTask(task *api.Task) {
 			log.Debug("Unable to remove old container", "err", err, "task", task, "cont", cont)
 		}
 	}
+	engine.unmountEFSVolumes(task)
+	engine.unmountScratchVolumes(task)
+	engine.removeArtifacts(task)
+	engine.removeTaskMountDir(task)
+	engine.removeTaskLogDir(task)
+	for _, cont := range task.Containers {
+		engine.cpuSets.release(task, cont)
+	}
 }
 
 func (engine *DockerTaskEngine) emitTaskEvent(task *api.Task, reason string) {
-	if !task.KnownStatus.BackendRecognized() {
+	knownStatus := task.GetKnownStatus()
+	if !knownStatus.BackendRecognized() {
 		return
 	}
-	if task.SentStatus >= task.KnownStatus {
-		log.Debug("Already sent task event; no need to re-send", "task", task.Arn, "event", task.KnownStatus.String())
+	if task.GetSentStatus() >= knownStatus {
+		log.Debug("Already sent task event; no need to re-send", "task", task.Arn, "event", knownStatus.String())
 		return
 	}
+	if reason == "" && knownStatus == api.TaskStopped {
+		// Explain a stopped task by the first container error, if any
+		for _, cont := range task.Containers {
+			if cont.ApplyingError != nil {
+				reason = cont.ApplyingError.Reason()
+				break
+			}
+		}
+	}
 	event := api.TaskStateChange{
-		TaskArn:    task.Arn,
-		Status:     task.KnownStatus,
-		Reason:     reason,
-		SentStatus: &task.SentStatus,
+		TaskArn: task.Arn,
+		Status:  knownStatus,
+		Reason:  reason,
+		Task:    task,
 	}
 	log.Info("Task change event", "event", event)
 	engine.taskEvents <- event
@@ -287,6 +468,11 @@ func (engine *DockerTaskEngine) startTask(task *api.Task) {
 	// what tasks need to be waited for for this one to start, and then spin off
 	// a goroutine to oversee this task
 
+	// A second overseer for the same task would create its containers again
+	if _, ok := engine.managedTasks[task.Arn]; ok {
+		log.Warn("Task is already being managed; not starting it again", "task", task.Arn)
+		return
+	}
 	thisTask := engine.newManagedTask(task)
 
 	go thisTask.overseeTask()
@@ -295,28 +481,29 @@ func (engine *DockerTaskEngine) startTask(task *api.Task) {
 // emitContainerEvent passes a given event up through the containerEvents channel if necessary.
 // It will omit events the backend would not process and will perform best-effort deduplication of events.
 func (engine *DockerTaskEngine) emitContainerEvent(task *api.Task, cont *api.Container, reason string) {
-	if !cont.KnownStatus.BackendRecognized() {
+	knownStatus := cont.GetKnownStatus()
+	if !knownStatus.BackendRecognized() {
 		return
 	}
 	if cont.IsInternal {
 		return
 	}
-	if cont.SentStatus >= cont.KnownStatus {
-		log.Debug("Already sent container event; no need to re-send", "task", task.Arn, "container", cont.Name, "event", cont.KnownStatus.String())
+	if cont.GetSentStatus() >= knownStatus {
+		log.Debug("Already sent container event; no need to re-send", "task", task.Arn, "container", cont.Name, "event", knownStatus.String())
 		return
 	}
 
 	if reason == "" && cont.ApplyingError != nil {
-		reason = cont.ApplyingError.Error()
+		reason = cont.ApplyingError.Reason()
 	}
 	event := api.ContainerStateChange{
 		TaskArn:       task.Arn,
 		ContainerName: cont.Name,
-		Status:        cont.KnownStatus,
+		Status:        knownStatus,
 		ExitCode:      cont.KnownExitCode,
 		PortBindings:  cont.KnownPortBindings,
 		Reason:        reason,
-		SentStatus:    &cont.SentStatus,
+		Container:     cont,
 	}
 	log.Debug("Container change event", "event", event)
 	engine.containerEvents <- event
@@ -340,6 +527,7 @@ func (engine *DockerTaskEngine) handleDockerEvents(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case <-engine.livenessProbes:
 		case event := <-engine.events:
 			log.Debug("Handling a docker event", "event", event)
 
@@ -362,6 +550,18 @@ func (engine *DockerTaskEngine) handleDockerEvents(ctx context.Context) {
 	}
 }
 
+// Alive returns whether the engine's event loop takes a liveness probe within
+// timeout. A loop which is stuck, such as on a task which no longer reads its
+// events, doesn't; one which is merely idle does.
+func (engine *DockerTaskEngine) Alive(timeout time.Duration) bool {
+	select {
+	case engine.livenessProbes <- struct{}{}:
+		return true
+	case <-ttime.After(timeout):
+		return false
+	}
+}
+
 // TaskEvents returns channels to read task and container state changes. These
 // changes should be read as soon as possible as them not being read will block
 // processing the task referenced by the event.
@@ -386,24 +586,88 @@ func (engine *DockerTaskEngine) AddTask(task *api.Task) error {
 	return nil
 }
 
-type transitionApplyFunc (func(*api.Task, *api.Container) DockerContainerMetadata)
+// transitionApplyFunc applies a transition to a container. Calls it makes to
+// docker are traced as children of span. Transitions towards running are
+// abandoned once ctx is cancelled, as it is when the task starts stopping.
+type transitionApplyFunc (func(context.Context, *api.Task, *api.Container, *tracing.Span) DockerContainerMetadata)
 
-func tryApplyTransition(task *api.Task, container *api.Container, to api.ContainerStatus, f transitionApplyFunc) DockerContainerMetadata {
-	return f(task, container)
+func tryApplyTransition(ctx context.Context, task *api.Task, container *api.Container, to api.ContainerStatus, f transitionApplyFunc, span *tracing.Span) DockerContainerMetadata {
+	return f(ctx, task, container, span)
 }
 
 func (engine *DockerTaskEngine) ListTasks() ([]*api.Task, error) {
 	return engine.state.AllTasks(), nil
 }
 
-func (engine *DockerTaskEngine) pullContainer(task *api.Task, container *api.Container) DockerContainerMetadata {
+func (engine *DockerTaskEngine) pullContainer(ctx context.Context, task *api.Task, container *api.Container, span *tracing.Span) DockerContainerMetadata {
+	phases := make(map[string]time.Duration)
+	waitStart := ttime.Now()
+	waitSpan := span.StartChild("wait for pull slot")
+	if !engine.pullSemaphore.WaitUntil(ctx.Done()) {
+		err := TransitionCancelledError{"pulled"}
+		waitSpan.End(err)
+		return DockerContainerMetadata{Error: err}
+	}
+	waitSpan.End(nil)
+	phases[api.LaunchPhasePullWait] = ttime.Since(waitStart)
+
+	// Reading the image's manifest is part of pulling it, so it takes a slot
+	image, resolveErr := engine.resolveImagePlatform(ctx, task, container, span)
+	if resolveErr == nil && ctx.Err() != nil {
+		resolveErr = TransitionCancelledError{"pulled"}
+	}
+	if resolveErr != nil {
+		engine.pullSemaphore.Post()
+		return DockerContainerMetadata{Error: resolveErr, LaunchPhases: phases}
+	}
+
 	log.Info("Pulling container", "task", task, "container", container)
+	pullStart := ttime.Now()
+	dockerSpan := span.StartChild("docker pull")
+	dockerSpan.SetAttribute("container.image.name", container.Image)
+	// If the task stops first, the pull is cancelled and its slot released
+	// without waiting for docker to give up on it
+	var releaseOnce sync.Once
+	release := func() { releaseOnce.Do(engine.pullSemaphore.Post) }
+	response := make(chan DockerContainerMetadata, 1)
+	go func() {
+		defer release()
+		response <- engine.pullImage(ctx, image, container.Image)
+	}()
+	var metadata DockerContainerMetadata
+	select {
+	case metadata = <-response:
+	case <-ctx.Done():
+		log.Info("Abandoning pull; the task is stopping", "task", task, "container", container)
+		release()
+		metadata = DockerContainerMetadata{Error: TransitionCancelledError{"pulled"}}
+	}
+	dockerSpan.End(metadata.Error)
+	phases[api.LaunchPhasePull] = ttime.Since(pullStart)
+
+	metadata.LaunchPhases = phases
+	return metadata
+}
 
-	return engine.client.PullImage(container.Image)
+// pullImage pulls image, which is the image name or, if it was resolved to a
+// digest, the digest to pull instead, until ctx is cancelled
+func (engine *DockerTaskEngine) pullImage(ctx context.Context, image, name string) DockerContainerMetadata {
+	metadata := engine.client.PullImage(ctx, image)
+	if metadata.Error == nil && image != name {
+		// The image was pulled by digest; containers are created from it by
+		// the name they ask for
+		if err := engine.client.TagImage(image, name); err != nil {
+			metadata.Error = CannotXContainerError{"Pull", "Unable to tag " + image + " as " + name + ": " + err.Error()}
+		}
+	}
+	return metadata
 }
 
-func (engine *DockerTaskEngine) createContainer(task *api.Task, container *api.Container) DockerContainerMetadata {
+func (engine *DockerTaskEngine) createContainer(ctx context.Context, task *api.Task, container *api.Container, span *tracing.Span) DockerContainerMetadata {
 	log.Info("Creating container", "task", task, "container", container)
+	if ctx.Err() != nil {
+		return DockerContainerMetadata{Error: TransitionCancelledError{"created"}}
+	}
 
 	// Resolve HostConfig
 	// we have to do this in create, not start, because docker no longer handles
@@ -414,6 +678,45 @@ func (engine *DockerTaskEngine) createContainer(task *api.Task, container *api.C
 		containerMap = make(map[string]*api.DockerContainer)
 	}
 
+	if err := validateDevices(container, engine.cfg.AllowedDevicePathPrefixes); err != nil {
+		return DockerContainerMetadata{Error: err}
+	}
+	if err := validatePidsLimit(container, cgroupPath); err != nil {
+		return DockerContainerMetadata{Error: err}
+	}
+	if err := validateStopSignal(container); err != nil {
+		return DockerContainerMetadata{Error: err}
+	}
+	if len(containerMap) == 0 {
+		// None of the task's containers have been created yet, so it's starting
+		if err := validateHugePages(task, hugePagesPath, cgroupPath); err != nil {
+			return DockerContainerMetadata{Error: err}
+		}
+	}
+	verifiedImage, verifyErr := engine.verifyImage(task, container, span)
+	if verifyErr != nil {
+		return DockerContainerMetadata{Error: verifyErr}
+	}
+	if err := engine.runPreStartTaskHooks(task, span); err != nil {
+		return DockerContainerMetadata{Error: err}
+	}
+	phases := make(map[string]time.Duration)
+	volumesStart := ttime.Now()
+	mountErr := engine.mountEFSVolumes(task)
+	if mountErr == nil {
+		mountErr = engine.mountScratchVolumes(task, container)
+	}
+	if mountErr == nil {
+		mountErr = engine.downloadArtifacts(task, container)
+	}
+	phases[api.LaunchPhaseVolumes] = ttime.Since(volumesStart)
+	if mountErr != nil {
+		return DockerContainerMetadata{Error: mountErr, LaunchPhases: phases}
+	}
+	if ctx.Err() != nil {
+		return DockerContainerMetadata{Error: TransitionCancelledError{"created"}, LaunchPhases: phases}
+	}
+
 	hostConfig, hcerr := task.DockerHostConfig(container, containerMap)
 	hostConfig.Privileged = true
 	if hcerr != nil {
@@ -424,27 +727,32 @@ func (engine *DockerTaskEngine) createContainer(task *api.Task, container *api.C
 	if err != nil {
 		return DockerContainerMetadata{Error: api.NamedError(err)}
 	}
-
-	name := ""
-	for i := 0; i < len(container.Name); i++ {
-		c := container.Name[i]
-		if !((c <= '9' && c >= '0') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c == '-')) {
-			continue
-		}
-		name += string(c)
+	// The task doesn't know which cluster it belongs to, so the engine adds
+	// that label itself
+	config.Labels[api.ClusterLabel] = engine.cfg.Cluster
+	if verifiedImage != "" {
+		config.Image = verifiedImage
 	}
-
-	containerName := "ecs-" + task.Family + "-" + task.Version + "-" + name + "-" + utils.RandHex()
-
-	// Pre-add the container in case we stop before the next, more useful,
-	// AddContainer call. This ensures we have a way to get the container if
-	// we die before 'createContainer' returns because we can inspect by
-	// name
-	engine.state.AddContainer(&api.DockerContainer{DockerName: containerName, Container: container}, task)
+	config.Env = withAgentEnvironment(config.Env, engine.agentEnvironment(task))
+	applyContainerDefaults(engine.containerDefaults, config, hostConfig)
+	if err := validateEnvironmentSize(container, config); err != nil {
+		return DockerContainerMetadata{Error: err}
+	}
+	cpuSet, cpuSetErr := engine.cpuSets.assign(task, container)
+	if cpuSetErr != nil {
+		return DockerContainerMetadata{Error: cpuSetErr}
+	}
+	config.CPUSet = cpuSet
 
 	hostConfig.Privileged = true
-	metadata := engine.client.CreateContainer(config, hostConfig, containerName)
+	createStart := ttime.Now()
+	dockerSpan := span.StartChild("docker create")
+	metadata, containerName := engine.createNamedContainer(task, container, config, hostConfig)
+	dockerSpan.End(metadata.Error)
+	phases[api.LaunchPhaseCreate] = ttime.Since(createStart)
+	metadata.LaunchPhases = phases
 	if metadata.Error != nil {
+		engine.cpuSets.release(task, container)
 		return metadata
 	}
 	engine.state.AddContainer(&api.DockerContainer{DockerId: metadata.DockerId, DockerName: containerName, Container: container}, task)
@@ -452,7 +760,7 @@ func (engine *DockerTaskEngine) createContainer(task *api.Task, container *api.C
 	return metadata
 }
 
-func (engine *DockerTaskEngine) startContainer(task *api.Task, container *api.Container) DockerContainerMetadata {
+func (engine *DockerTaskEngine) startContainer(ctx context.Context, task *api.Task, container *api.Container, span *tracing.Span) DockerContainerMetadata {
 	log.Info("Starting container", "task", task, "container", container)
 	containerMap, ok := engine.state.ContainerMapByArn(task.Arn)
 	if !ok {
@@ -463,10 +771,44 @@ func (engine *DockerTaskEngine) startContainer(task *api.Task, container *api.Co
 	if !ok {
 		return DockerContainerMetadata{Error: CannotXContainerError{"Start", "Container not recorded as created"}}
 	}
-	return engine.client.StartContainer(dockerContainer.DockerId)
+	if err := engine.checkPreStartHooks(task, container, dockerContainer.DockerId, span); err != nil {
+		return DockerContainerMetadata{Error: err}
+	}
+	if ctx.Err() != nil {
+		return DockerContainerMetadata{Error: TransitionCancelledError{"started"}}
+	}
+
+	begin := ttime.Now()
+	dockerSpan := span.StartChild("docker start")
+	metadata := engine.client.StartContainer(dockerContainer.DockerId)
+	dockerSpan.End(metadata.Error)
+	metadata.LaunchPhases = map[string]time.Duration{api.LaunchPhaseStart: ttime.Since(begin)}
+	if metadata.Error == nil {
+		if err := engine.limitHugePages(task, container, dockerContainer.DockerId); err != nil {
+			engine.stopUnlimitedContainer(task, container, dockerContainer.DockerId)
+			metadata.Error = err
+			return metadata
+		}
+		if err := engine.limitPids(task, container, dockerContainer.DockerId); err != nil {
+			engine.stopUnlimitedContainer(task, container, dockerContainer.DockerId)
+			metadata.Error = err
+			return metadata
+		}
+		engine.linkContainerLog(task, container, dockerContainer.DockerId)
+	}
+	return metadata
+}
+
+// stopUnlimitedContainer kills a container which was started but whose
+// limits could not be applied, so that it doesn't keep running without them
+func (engine *DockerTaskEngine) stopUnlimitedContainer(task *api.Task, container *api.Container, dockerID string) {
+	log.Warn("Stopping container whose limits could not be applied", "task", task.Arn, "container", container.Name)
+	if metadata := engine.client.StopContainer(dockerID, 0); metadata.Error != nil {
+		log.Warn("Unable to stop container whose limits could not be applied", "task", task.Arn, "container", container.Name, "err", metadata.Error)
+	}
 }
 
-func (engine *DockerTaskEngine) stopContainer(task *api.Task, container *api.Container) DockerContainerMetadata {
+func (engine *DockerTaskEngine) stopContainer(ctx context.Context, task *api.Task, container *api.Container, span *tracing.Span) DockerContainerMetadata {
 	log.Info("Stopping container", "task", task, "container", container)
 	containerMap, ok := engine.state.ContainerMapByArn(task.Arn)
 	if !ok {
@@ -478,7 +820,28 @@ func (engine *DockerTaskEngine) stopContainer(task *api.Task, container *api.Con
 		return DockerContainerMetadata{Error: CannotXContainerError{"Stop", "Container not recorded as created"}}
 	}
 
-	return engine.client.StopContainer(dockerContainer.DockerId)
+	dockerSpan := span.StartChild("docker stop")
+	var metadata DockerContainerMetadata
+	if signal, ok := stopSignal(container); ok {
+		metadata = engine.client.StopContainerWithSignal(dockerContainer.DockerId, signal, engine.stopTimeout(container))
+	} else {
+		metadata = engine.client.StopContainer(dockerContainer.DockerId, engine.stopTimeout(container))
+	}
+	dockerSpan.End(metadata.Error)
+	return metadata
+}
+
+// stopTimeout returns how long the container is given to exit when stopped:
+// its own stop timeout if its task definition sets one, otherwise the
+// configured default
+func (engine *DockerTaskEngine) stopTimeout(container *api.Container) time.Duration {
+	if container.StopTimeout > 0 {
+		return time.Duration(container.StopTimeout) * time.Second
+	}
+	if engine.cfg.DockerStopTimeout > 0 {
+		return engine.cfg.DockerStopTimeout
+	}
+	return defaultDockerStopTimeout
 }
 
 func (engine *DockerTaskEngine) removeContainer(task *api.Task, container *api.Container) error {
@@ -504,10 +867,12 @@ func (engine *DockerTaskEngine) updateTask(task *api.Task, update *api.Task) {
 	managedTask, ok := engine.managedTasks[task.Arn]
 	if !ok {
 		log.Crit("ACS message for a task we thought we managed, but don't!", "arn", task.Arn)
-		// Is this the right thing to do?
-		// Calling startTask should overwrite our bad 'state' data with the new
-		// task which we do manage.. but this is still scary and shouldn't have happened
-		engine.startTask(update)
+		// Resume managing the task we know, rather than the update, as only
+		// the known task records which containers were already created.
+		// Managing the update would create them all again.
+		managedTask = engine.newManagedTask(task)
+		managedTask.handleDesiredStatusChange(update.DesiredStatus, update.StopSequenceNumber)
+		go managedTask.overseeTask()
 		return
 	}
 	// Keep the lock because sequence numbers cannot be correct unless they are
@@ -531,7 +896,7 @@ func (engine *DockerTaskEngine) transitionFunctionMap() map[api.ContainerStatus]
 }
 
 // applyContainerState moves the container to the given state
-func (engine *DockerTaskEngine) applyContainerState(task *api.Task, container *api.Container, nextState api.ContainerStatus) DockerContainerMetadata {
+func (engine *DockerTaskEngine) applyContainerState(ctx context.Context, task *api.Task, container *api.Container, nextState api.ContainerStatus, span *tracing.Span) DockerContainerMetadata {
 	clog := log.New("task", task, "container", container)
 	transitionFunction, ok := engine.transitionFunctionMap()[nextState]
 	if !ok {
@@ -539,7 +904,7 @@ func (engine *DockerTaskEngine) applyContainerState(task *api.Task, container *a
 		return DockerContainerMetadata{Error: &impossibleTransitionError{nextState}}
 	}
 
-	metadata := tryApplyTransition(task, container, nextState, transitionFunction)
+	metadata := tryApplyTransition(ctx, task, container, nextState, transitionFunction, span)
 	if metadata.Error != nil {
 		clog.Info("Error transitioning container", "state", nextState.String())
 	} else {
@@ -549,10 +914,17 @@ func (engine *DockerTaskEngine) applyContainerState(task *api.Task, container *a
 	return metadata
 }
 
-func (engine *DockerTaskEngine) transitionContainer(task *api.Task, container *api.Container, to api.ContainerStatus) {
+// transitionContainer applies a transition to the container and reports the
+// result to the task's manager. The transition is traced as a child of
+// parent, if any, and abandoned if ctx is cancelled first.
+func (engine *DockerTaskEngine) transitionContainer(ctx context.Context, task *api.Task, container *api.Container, to api.ContainerStatus, parent *tracing.Span) {
+	span := parent.StartChild("container " + to.String())
+	span.SetAttribute("container.name", container.Name)
+
 	// Let docker events operate async so that we can continue to handle ACS / other requests
 	// This is safe because 'applyContainerState' will not mutate the task
-	metadata := engine.applyContainerState(task, container, to)
+	metadata := engine.applyContainerState(ctx, task, container, to, span)
+	span.End(metadata.Error)
 
 	engine.processTasks.RLock()
 	managedTask, ok := engine.managedTasks[task.Arn]
@@ -575,6 +947,23 @@ func (engine *DockerTaskEngine) State() *dockerstate.DockerTaskEngineState {
 	return engine.state
 }
 
+// DockerInfo returns docker's system-wide information, for debugging
+func (engine *DockerTaskEngine) DockerInfo() (*docker.Env, error) {
+	return engine.client.Info()
+}
+
+// InspectContainer returns docker's description of a container, for
+// debugging
+func (engine *DockerTaskEngine) InspectContainer(dockerID string) (*docker.Container, error) {
+	return engine.client.InspectContainer(dockerID)
+}
+
+// ContainerLogs writes the last tail lines of a container's logs to output,
+// following them if follow is set, for debugging
+func (engine *DockerTaskEngine) ContainerLogs(dockerID string, tail int, follow bool, output io.Writer) error {
+	return engine.client.ContainerLogs(dockerID, tail, follow, output)
+}
+
 // Version returns the underlying docker version.
 func (engine *DockerTaskEngine) Version() (string, error) {
 	// Must be able to be called before Init()
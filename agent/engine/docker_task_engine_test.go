@@ -25,6 +25,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine/testdata"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
 	"github.com/golang/mock/gomock"
+	"golang.org/x/net/context"
 )
 
 var test_time = ttime.NewTestTime()
@@ -38,7 +39,7 @@ func mocks(t *testing.T, cfg *config.Config) (*gomock.Controller, *mock_engine.M
 }
 
 func TestBatchContainerHappyPath(t *testing.T) {
-	ctrl, client, taskEngine := mocks(t, &config.Config{})
+	ctrl, client, taskEngine := mocks(t, &config.Config{Cluster: "mycluster"})
 	defer ctrl.Finish()
 	ttime.SetTime(test_time)
 
@@ -56,12 +57,13 @@ func TestBatchContainerHappyPath(t *testing.T) {
 	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
 	for _, container := range sleepTask.Containers {
 
-		client.EXPECT().PullImage(container.Image).Return(engine.DockerContainerMetadata{})
+		client.EXPECT().PullImage(gomock.Any(), container.Image).Return(engine.DockerContainerMetadata{})
 
 		dockerConfig, err := sleepTask.DockerConfig(container)
 		if err != nil {
 			t.Fatal(err)
 		}
+		dockerConfig.Labels[api.ClusterLabel] = "mycluster"
 		client.EXPECT().CreateContainer(dockerConfig, gomock.Any(), gomock.Any()).Do(func(x, y, z interface{}) {
 			go func() { eventStream <- dockerEvent(api.ContainerCreated) }()
 		}).Return(engine.DockerContainerMetadata{DockerId: "containerId"})
@@ -121,6 +123,8 @@ func TestBatchContainerHappyPath(t *testing.T) {
 	// Expect a bunch of steady state 'poll' describes when we warp 4 hours
 	client.EXPECT().DescribeContainer(gomock.Any()).AnyTimes()
 	client.EXPECT().RemoveContainer("containerId").Return(nil)
+	// Cleanup is verified by inspecting the removed container
+	client.EXPECT().InspectContainer("containerId").Return(nil, errors.New("No such container")).AnyTimes()
 
 	test_time.Warp(4 * time.Hour)
 	go func() { eventStream <- dockerEvent(api.ContainerStopped) }()
@@ -153,12 +157,13 @@ func TestStartTimeoutThenStart(t *testing.T) {
 	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
 	for _, container := range sleepTask.Containers {
 
-		client.EXPECT().PullImage(container.Image).Return(engine.DockerContainerMetadata{})
+		client.EXPECT().PullImage(gomock.Any(), container.Image).Return(engine.DockerContainerMetadata{})
 
 		dockerConfig, err := sleepTask.DockerConfig(container)
 		if err != nil {
 			t.Fatal(err)
 		}
+		dockerConfig.Labels[api.ClusterLabel] = ""
 		client.EXPECT().CreateContainer(dockerConfig, gomock.Any(), gomock.Any()).Do(func(x, y, z interface{}) {
 			go func() { eventStream <- dockerEvent(api.ContainerCreated) }()
 		}).Return(engine.DockerContainerMetadata{DockerId: "containerId"})
@@ -168,7 +173,7 @@ func TestStartTimeoutThenStart(t *testing.T) {
 		// Expect it to try to stop the container before going on;
 		// in the future the agent might optimize to not stop unless the known
 		// status is running, at which poitn this can be safeuly removed
-		client.EXPECT().StopContainer("containerId").Return(engine.DockerContainerMetadata{Error: errors.New("Cannot start")})
+		client.EXPECT().StopContainer("containerId", gomock.Any()).Return(engine.DockerContainerMetadata{Error: errors.New("Cannot start")})
 	}
 
 	err := taskEngine.Init()
@@ -184,13 +189,13 @@ func TestStartTimeoutThenStart(t *testing.T) {
 	if contEvent.Status != api.ContainerStopped {
 		t.Fatal("Expected container to timeout on start and stop")
 	}
-	*contEvent.SentStatus = api.ContainerStopped
+	contEvent.Container.SetSentStatus(api.ContainerStopped)
 
 	taskEvent := <-taskEvents
 	if taskEvent.Status != api.TaskStopped {
 		t.Fatal("And then task")
 	}
-	*taskEvent.SentStatus = api.TaskStopped
+	taskEvent.Task.SetSentStatus(api.TaskStopped)
 	select {
 	case <-taskEvents:
 		t.Fatal("Should be out of events")
@@ -200,7 +205,7 @@ func TestStartTimeoutThenStart(t *testing.T) {
 	}
 
 	// Expect it to try to stop it once now
-	client.EXPECT().StopContainer("containerId").Return(engine.DockerContainerMetadata{Error: errors.New("Cannot start")})
+	client.EXPECT().StopContainer("containerId", gomock.Any()).Return(engine.DockerContainerMetadata{Error: errors.New("Cannot start")})
 	// Now surprise surprise, it actually did start!
 	eventStream <- dockerEvent(api.ContainerRunning)
 
@@ -236,12 +241,13 @@ func TestSteadyStatePoll(t *testing.T) {
 	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
 	for _, container := range sleepTask.Containers {
 
-		client.EXPECT().PullImage(container.Image).Return(engine.DockerContainerMetadata{})
+		client.EXPECT().PullImage(gomock.Any(), container.Image).Return(engine.DockerContainerMetadata{})
 
 		dockerConfig, err := sleepTask.DockerConfig(container)
 		if err != nil {
 			t.Fatal(err)
 		}
+		dockerConfig.Labels[api.ClusterLabel] = ""
 		client.EXPECT().CreateContainer(dockerConfig, gomock.Any(), gomock.Any()).Do(func(x, y, z interface{}) {
 			go func() { eventStream <- dockerEvent(api.ContainerCreated) }()
 		}).Return(engine.DockerContainerMetadata{DockerId: "containerId"})
@@ -276,14 +282,13 @@ func TestSteadyStatePoll(t *testing.T) {
 	// Two steady state oks, one stop
 	gomock.InOrder(
 		client.EXPECT().DescribeContainer("containerId").Return(api.ContainerRunning, engine.DockerContainerMetadata{DockerId: "containerId"}).Times(2),
-		client.EXPECT().DescribeContainer("containerId").Return(api.ContainerStopped, engine.DockerContainerMetadata{DockerId: "containerId"}),
+		// A sweep may start before the stop has been processed
+		client.EXPECT().DescribeContainer("containerId").Return(api.ContainerStopped, engine.DockerContainerMetadata{DockerId: "containerId"}).AnyTimes(),
 	)
 	// Due to how the mock time works, we actually have to warp 10 minutes per
-	// steady-state event. That's 10 per timeout + 10 per describe * 1 container.
-	// The reason for this is the '.After' call happens regardless of whether the
-	// value gets read, and the test sleep will add that time to elapsed, even
-	// though in real-time-units that much time would not have elapsed.
-	test_time.Warp(60 * time.Minute)
+	// reconciliation sweep. The reason for this is the '.After' call in the
+	// sweep loop adds its duration to elapsed time once it returns.
+	test_time.Warp(30 * time.Minute)
 
 	contEvent := <-contEvents
 	if contEvent.Status != api.ContainerStopped {
@@ -331,10 +336,12 @@ func TestStopWithPendingStops(t *testing.T) {
 		}
 	}()
 
+	// The stop may arrive before the pull begins, in which case the pull is
+	// cancelled without calling docker
 	pulling := make(chan bool)
-	client.EXPECT().PullImage(gomock.Any()).Do(func(x interface{}) {
+	client.EXPECT().PullImage(gomock.Any(), gomock.Any()).Do(func(ctx, image interface{}) {
 		<-pulling
-	})
+	}).AnyTimes()
 	taskEngine.AddTask(sleepTask2)
 	stopSleep2 := *sleepTask2
 	stopSleep2.DesiredStatus = api.TaskStopped
@@ -346,6 +353,185 @@ func TestStopWithPendingStops(t *testing.T) {
 	stopSleep1.DesiredStatus = api.TaskStopped
 	stopSleep1.StopSequenceNumber = 5
 	taskEngine.AddTask(&stopSleep1)
-	pulling <- true
+	close(pulling)
 	// If we get here without deadlocking, we passed the test
 }
+
+func TestStopDuringPullDoesNotWaitForPull(t *testing.T) {
+	ctrl, client, taskEngine := mocks(t, &config.Config{})
+	defer ctrl.Finish()
+	ttime.SetTime(test_time)
+
+	sleepTask := testdata.LoadTask("sleep5")
+
+	eventStream := make(chan engine.DockerContainerChangeEvent)
+	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
+
+	pullStarted := make(chan bool)
+	pulling := make(chan bool)
+	defer close(pulling)
+	client.EXPECT().PullImage(gomock.Any(), gomock.Any()).Do(func(ctx, image interface{}) {
+		pullStarted <- true
+		<-pulling
+	})
+
+	err := taskEngine.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskEvents, contEvents := taskEngine.TaskEvents()
+	go func() {
+		for {
+			<-contEvents
+		}
+	}()
+
+	taskEngine.AddTask(sleepTask)
+	<-pullStarted
+	stopTask := testdata.LoadTask("sleep5")
+	stopTask.DesiredStatus = api.TaskStopped
+	taskEngine.AddTask(stopTask)
+
+	for {
+		select {
+		case event := <-taskEvents:
+			if event.Status == api.TaskStopped {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Task did not stop while its image was still being pulled")
+		}
+	}
+}
+
+func TestStopDuringPullReleasesPullSlot(t *testing.T) {
+	ctrl, client, taskEngine := mocks(t, &config.Config{ImagePullConcurrency: 1})
+	defer ctrl.Finish()
+	ttime.SetTime(test_time)
+
+	eventStream := make(chan engine.DockerContainerChangeEvent)
+	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
+
+	pullStarted := make(chan bool)
+	pulling := make(chan bool)
+	defer close(pulling)
+	// The first pull doesn't give up when its task stops
+	client.EXPECT().PullImage(gomock.Any(), gomock.Any()).Do(func(ctx, image interface{}) {
+		pullStarted <- true
+		<-pulling
+	})
+	client.EXPECT().PullImage(gomock.Any(), gomock.Any()).Do(func(ctx, image interface{}) {
+		pullStarted <- true
+		<-ctx.(context.Context).Done()
+	})
+
+	err := taskEngine.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, contEvents := taskEngine.TaskEvents()
+	go func() {
+		for {
+			<-contEvents
+		}
+	}()
+
+	sleepTask := testdata.LoadTask("sleep5")
+	taskEngine.AddTask(sleepTask)
+	<-pullStarted
+	stopTask := testdata.LoadTask("sleep5")
+	stopTask.DesiredStatus = api.TaskStopped
+	taskEngine.AddTask(stopTask)
+
+	otherTask := testdata.LoadTask("sleep5")
+	otherTask.Arn = "otherArn"
+	taskEngine.AddTask(otherTask)
+	select {
+	case <-pullStarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The stopped task's pull kept its slot")
+	}
+}
+
+func TestRedeliveredTaskStartsOnce(t *testing.T) {
+	ctrl, client, taskEngine := mocks(t, &config.Config{})
+	defer ctrl.Finish()
+	ttime.SetTime(test_time)
+
+	sleepTask := testdata.LoadTask("sleep5")
+
+	eventStream := make(chan engine.DockerContainerChangeEvent)
+
+	dockerEvent := func(status api.ContainerStatus) engine.DockerContainerChangeEvent {
+		meta := engine.DockerContainerMetadata{
+			DockerId: "containerId",
+		}
+		return engine.DockerContainerChangeEvent{status, meta}
+	}
+
+	// Each container is pulled, created and started exactly once, however
+	// many times the task is delivered
+	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
+	for _, container := range sleepTask.Containers {
+		client.EXPECT().PullImage(gomock.Any(), container.Image).Return(engine.DockerContainerMetadata{})
+
+		client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(x, y, z interface{}) {
+			go func() { eventStream <- dockerEvent(api.ContainerCreated) }()
+		}).Return(engine.DockerContainerMetadata{DockerId: "containerId"})
+
+		client.EXPECT().StartContainer("containerId").Do(func(id string) {
+			go func() { eventStream <- dockerEvent(api.ContainerRunning) }()
+		}).Return(engine.DockerContainerMetadata{DockerId: "containerId"})
+	}
+
+	err := taskEngine.Init()
+	taskEvents, contEvents := taskEngine.TaskEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taskEngine.AddTask(sleepTask)
+	// ACS may deliver the same task again while it is still launching
+	taskEngine.AddTask(testdata.LoadTask("sleep5"))
+	taskEngine.AddTask(testdata.LoadTask("sleep5"))
+
+	if (<-contEvents).Status != api.ContainerRunning {
+		t.Fatal("Expected container to run first")
+	}
+	if (<-taskEvents).Status != api.TaskRunning {
+		t.Fatal("And then task")
+	}
+
+	taskEngine.AddTask(testdata.LoadTask("sleep5"))
+	select {
+	case <-taskEvents:
+		t.Fatal("Should be out of events")
+	case <-contEvents:
+		t.Fatal("Should be out of events")
+	default:
+	}
+	tasks, _ := taskEngine.ListTasks()
+	if len(tasks) != 1 {
+		t.Error("Expected a single task, got", len(tasks))
+	}
+}
+
+func TestEngineAlive(t *testing.T) {
+	ctrl, client, taskEngine := mocks(t, &config.Config{})
+	defer ctrl.Finish()
+	ttime.SetTime(&ttime.DefaultTime{})
+	dockerTaskEngine := taskEngine.(*engine.DockerTaskEngine)
+
+	if dockerTaskEngine.Alive(10 * time.Millisecond) {
+		t.Error("Expected an engine whose event loop isn't running not to be alive")
+	}
+
+	client.EXPECT().ContainerEvents(gomock.Any()).Return(make(chan engine.DockerContainerChangeEvent), nil)
+	if err := taskEngine.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer dockerTaskEngine.Shutdown()
+	if !dockerTaskEngine.Alive(time.Second) {
+		t.Error("Expected an idle engine to be alive")
+	}
+}
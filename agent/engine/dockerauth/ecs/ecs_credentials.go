@@ -17,6 +17,7 @@
 package ecs_credentials
 
 import (
+	"encoding/base64"
 	"encoding/json"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/credentialprovider"
@@ -56,6 +57,10 @@ func (creds *ecsCredentials) Provide() credentialprovider.DockerConfig {
 		if err != nil {
 			log.Error("Unable to decode provided docker credentials", "type", creds.EngineAuthType)
 		}
+		for _, entry := range providedCreds {
+			logger.RegisterSecret(entry.Password)
+			logger.RegisterSecret(base64.StdEncoding.EncodeToString([]byte(entry.Username + ":" + entry.Password)))
+		}
 	case "":
 	default:
 		log.Error("Unrecognized AuthType type")
@@ -22,13 +22,20 @@ type Client interface {
 	AddEventListener(listener chan<- *docker.APIEvents) error
 	CreateContainer(opts docker.CreateContainerOptions) (*docker.Container, error)
 	ImportImage(opts docker.ImportImageOptions) error
+	Info() (*docker.Env, error)
 	InspectContainer(id string) (*docker.Container, error)
 	InspectImage(name string) (*docker.Image, error)
+	KillContainer(opts docker.KillContainerOptions) error
 	ListContainers(opts docker.ListContainersOptions) ([]docker.APIContainers, error)
+	ListImages(opts docker.ListImagesOptions) ([]docker.APIImages, error)
+	Logs(opts docker.LogsOptions) error
 	PullImage(opts docker.PullImageOptions, auth docker.AuthConfiguration) error
 	RemoveContainer(opts docker.RemoveContainerOptions) error
 	RemoveEventListener(listener chan *docker.APIEvents) error
+	RemoveImage(name string) error
 	StartContainer(id string, hostConfig *docker.HostConfig) error
 	StopContainer(id string, timeout uint) error
+	TagImage(name string, opts docker.TagImageOptions) error
 	Version() (*docker.Env, error)
+	WaitContainer(id string) (int, error)
 }
@@ -73,6 +73,17 @@ func (_mr *_MockClientRecorder) ImportImage(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ImportImage", arg0)
 }
 
+func (_m *MockClient) Info() (*go_dockerclient.Env, error) {
+	ret := _m.ctrl.Call(_m, "Info")
+	ret0, _ := ret[0].(*go_dockerclient.Env)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockClientRecorder) Info() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Info")
+}
+
 func (_m *MockClient) InspectContainer(_param0 string) (*go_dockerclient.Container, error) {
 	ret := _m.ctrl.Call(_m, "InspectContainer", _param0)
 	ret0, _ := ret[0].(*go_dockerclient.Container)
@@ -95,6 +106,16 @@ func (_mr *_MockClientRecorder) InspectImage(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "InspectImage", arg0)
 }
 
+func (_m *MockClient) KillContainer(_param0 go_dockerclient.KillContainerOptions) error {
+	ret := _m.ctrl.Call(_m, "KillContainer", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClientRecorder) KillContainer(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "KillContainer", arg0)
+}
+
 func (_m *MockClient) ListContainers(_param0 go_dockerclient.ListContainersOptions) ([]go_dockerclient.APIContainers, error) {
 	ret := _m.ctrl.Call(_m, "ListContainers", _param0)
 	ret0, _ := ret[0].([]go_dockerclient.APIContainers)
@@ -106,6 +127,27 @@ func (_mr *_MockClientRecorder) ListContainers(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ListContainers", arg0)
 }
 
+func (_m *MockClient) ListImages(_param0 go_dockerclient.ListImagesOptions) ([]go_dockerclient.APIImages, error) {
+	ret := _m.ctrl.Call(_m, "ListImages", _param0)
+	ret0, _ := ret[0].([]go_dockerclient.APIImages)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockClientRecorder) ListImages(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ListImages", arg0)
+}
+
+func (_m *MockClient) Logs(_param0 go_dockerclient.LogsOptions) error {
+	ret := _m.ctrl.Call(_m, "Logs", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClientRecorder) Logs(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Logs", arg0)
+}
+
 func (_m *MockClient) PullImage(_param0 go_dockerclient.PullImageOptions, _param1 go_dockerclient.AuthConfiguration) error {
 	ret := _m.ctrl.Call(_m, "PullImage", _param0, _param1)
 	ret0, _ := ret[0].(error)
@@ -136,6 +178,16 @@ func (_mr *_MockClientRecorder) RemoveEventListener(arg0 interface{}) *gomock.Ca
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "RemoveEventListener", arg0)
 }
 
+func (_m *MockClient) RemoveImage(_param0 string) error {
+	ret := _m.ctrl.Call(_m, "RemoveImage", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClientRecorder) RemoveImage(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RemoveImage", arg0)
+}
+
 func (_m *MockClient) StartContainer(_param0 string, _param1 *go_dockerclient.HostConfig) error {
 	ret := _m.ctrl.Call(_m, "StartContainer", _param0, _param1)
 	ret0, _ := ret[0].(error)
@@ -156,6 +208,16 @@ func (_mr *_MockClientRecorder) StopContainer(arg0, arg1 interface{}) *gomock.Ca
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "StopContainer", arg0, arg1)
 }
 
+func (_m *MockClient) TagImage(_param0 string, _param1 go_dockerclient.TagImageOptions) error {
+	ret := _m.ctrl.Call(_m, "TagImage", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClientRecorder) TagImage(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "TagImage", arg0, arg1)
+}
+
 func (_m *MockClient) Version() (*go_dockerclient.Env, error) {
 	ret := _m.ctrl.Call(_m, "Version")
 	ret0, _ := ret[0].(*go_dockerclient.Env)
@@ -166,3 +228,14 @@ func (_m *MockClient) Version() (*go_dockerclient.Env, error) {
 func (_mr *_MockClientRecorder) Version() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Version")
 }
+
+func (_m *MockClient) WaitContainer(_param0 string) (int, error) {
+	ret := _m.ctrl.Call(_m, "WaitContainer", _param0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockClientRecorder) WaitContainer(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "WaitContainer", arg0)
+}
@@ -146,6 +146,23 @@ func (state *DockerTaskEngineState) AddContainer(container *api.DockerContainer,
 	}
 }
 
+// RemoveContainer removes a container added to the state, if it is still the
+// one recorded for its task's container. It does aquire the write lock.
+func (state *DockerTaskEngineState) RemoveContainer(container *api.DockerContainer, task *api.Task) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	containerMap, ok := state.taskToId[task.Arn]
+	if !ok || containerMap[container.Container.Name] != container {
+		return
+	}
+	delete(containerMap, container.Container.Name)
+	if container.DockerId != "" {
+		delete(state.idToTask, container.DockerId)
+		delete(state.idToContainer, container.DockerId)
+	}
+}
+
 func (state *DockerTaskEngineState) TaskByArn(arn string) (*api.Task, bool) {
 	state.lock.RLock()
 	defer state.lock.RUnlock()
@@ -151,3 +151,26 @@ func TestRemoveTask(t *testing.T) {
 		t.Error("Expected task to be removed")
 	}
 }
+
+func TestRemoveContainer(t *testing.T) {
+	state := NewDockerTaskEngineState()
+	testContainer := &api.Container{Name: "c1"}
+	testTask := &api.Task{Arn: "t1", Containers: []*api.Container{testContainer}}
+	named := &api.DockerContainer{DockerName: "taken", Container: testContainer}
+	state.AddContainer(named, testTask)
+
+	created := &api.DockerContainer{DockerId: "did", DockerName: "free", Container: testContainer}
+	state.AddContainer(created, testTask)
+	state.RemoveContainer(named, testTask)
+	if containerMap, _ := state.ContainerMapByArn("t1"); containerMap["c1"] != created {
+		t.Error("Expected a container which was replaced not to be removed")
+	}
+
+	state.RemoveContainer(created, testTask)
+	if containerMap, _ := state.ContainerMapByArn("t1"); len(containerMap) != 0 {
+		t.Error("Expected the container to be removed", containerMap)
+	}
+	if _, ok := state.ContainerById("did"); ok {
+		t.Error("Expected the container's id to be forgotten")
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+)
+
+const (
+	efsFilesystemType  = "efs"
+	efsUnmountAttempts = 5
+	efsMaxPort         = 65535
+)
+
+// The ids are checked before being passed to the mount helper, whose options
+// are separated by commas, so that a task cannot add options of its own
+var (
+	efsFileSystemIDPattern  = regexp.MustCompile(`^fs-[0-9a-f]+$`)
+	efsAccessPointIDPattern = regexp.MustCompile(`^fsap-[0-9a-f]+$`)
+)
+
+// EFSMountError is returned when an EFS volume for a task could not be
+// mounted on the host
+type EFSMountError struct {
+	msg string
+}
+
+func (err EFSMountError) Error() string     { return err.msg }
+func (err EFSMountError) ErrorName() string { return "EFSMountError" }
+func (err EFSMountError) ErrorCode() string { return api.ErrorCodeResourceInitialization }
+
+// volumeMounter mounts and unmounts filesystems on the host
+type volumeMounter interface {
+	Mount(source, target, fsType string, options []string) error
+	Unmount(target string) error
+}
+
+// execVolumeMounter shells out to mount(8) and umount(8). Mounting EFS
+// requires the amazon-efs-utils mount helper to be installed.
+type execVolumeMounter struct{}
+
+func (execVolumeMounter) Mount(source, target, fsType string, options []string) error {
+	args := []string{"-t", fsType}
+	if len(options) > 0 {
+		args = append(args, "-o", strings.Join(options, ","))
+	}
+	args = append(args, source, target)
+	out, err := exec.Command("mount", args...).CombinedOutput()
+	if err != nil {
+		return errors.New(err.Error() + ": " + strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (execVolumeMounter) Unmount(target string) error {
+	out, err := exec.Command("umount", target).CombinedOutput()
+	if err != nil {
+		return errors.New(err.Error() + ": " + strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// mountEFSVolumes mounts any of the task's EFS volumes which are not yet
+// mounted. It is safe to call from multiple container transitions at once;
+// mounts of different volumes don't wait for each other.
+func (engine *DockerTaskEngine) mountEFSVolumes(task *api.Task) api.NamedError {
+	for _, taskVolume := range task.Volumes {
+		efs, ok := taskVolume.Volume.(*api.EFSVolume)
+		if !ok {
+			continue
+		}
+		if err := engine.mountEFSVolume(task, taskVolume.Name, efs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (engine *DockerTaskEngine) mountEFSVolume(task *api.Task, name string, efs *api.EFSVolume) api.NamedError {
+	target := engine.efsMountPoint(task, name)
+	engine.mountLocks.Lock(target)
+	defer engine.mountLocks.Unlock(target)
+	if efs.HostPath != "" {
+		return nil
+	}
+
+	source, err := efsMountSource(efs)
+	if err != nil {
+		return EFSMountError{"Invalid EFS volume " + name + ": " + err.Error()}
+	}
+	options, err := efsMountOptions(efs)
+	if err != nil {
+		return EFSMountError{"Invalid EFS volume " + name + ": " + err.Error()}
+	}
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return EFSMountError{"Unable to create mount point for EFS volume " + name + ": " + err.Error()}
+	}
+	log.Info("Mounting EFS volume", "task", task.Arn, "volume", name, "target", target)
+	if err := engine.mounter.Mount(source, target, efsFilesystemType, options); err != nil {
+		return EFSMountError{"Unable to mount EFS volume " + name + ": " + err.Error()}
+	}
+	efs.HostPath = target
+	return nil
+}
+
+// unmountEFSVolumes unmounts all of the task's mounted EFS volumes, retrying
+// as a filesystem may remain busy briefly after its containers are removed
+func (engine *DockerTaskEngine) unmountEFSVolumes(task *api.Task) {
+	for _, taskVolume := range task.Volumes {
+		efs, ok := taskVolume.Volume.(*api.EFSVolume)
+		if !ok {
+			continue
+		}
+		engine.unmountEFSVolume(task, taskVolume.Name, efs)
+	}
+}
+
+func (engine *DockerTaskEngine) unmountEFSVolume(task *api.Task, name string, efs *api.EFSVolume) {
+	target := engine.efsMountPoint(task, name)
+	engine.mountLocks.Lock(target)
+	defer engine.mountLocks.Unlock(target)
+	if efs.HostPath == "" {
+		return
+	}
+
+	backoff := utils.NewSimpleBackoff(time.Second, 30*time.Second, 0.2, 2)
+	err := utils.RetryNWithBackoff(backoff, efsUnmountAttempts, func() error {
+		return engine.mounter.Unmount(efs.HostPath)
+	})
+	if err != nil {
+		log.Warn("Unable to unmount EFS volume", "task", task.Arn, "volume", name, "err", err)
+		return
+	}
+	os.Remove(efs.HostPath)
+	efs.HostPath = ""
+}
+
+// efsMountPoint is where one of the task's EFS volumes is mounted on the host
+func (engine *DockerTaskEngine) efsMountPoint(task *api.Task, name string) string {
+	return filepath.Join(engine.cfg.EFSMountDir, taskIDFromArn(task.Arn), name)
+}
+
+// efsMountSource returns the filesystem and directory in it to mount
+func efsMountSource(efs *api.EFSVolume) (string, error) {
+	if !efsFileSystemIDPattern.MatchString(efs.FileSystemID) {
+		return "", errors.New("invalid file system id " + strconv.Quote(efs.FileSystemID))
+	}
+	rootDirectory := efs.RootDirectory
+	if rootDirectory == "" {
+		rootDirectory = "/"
+	}
+	if strings.Contains(rootDirectory, ",") {
+		return "", errors.New("invalid root directory " + strconv.Quote(rootDirectory) + "; it may not contain a comma")
+	}
+	return efs.FileSystemID + ":" + rootDirectory, nil
+}
+
+// efsMountOptions returns the options for the efs mount helper. IAM
+// authorization is refused: the mount helper would use the credentials
+// available to the agent, which are the instance's, as tasks are not given
+// credentials of their own, and so grant tasks access meant for the instance.
+func efsMountOptions(efs *api.EFSVolume) ([]string, error) {
+	if efs.IAMEnabled() {
+		return nil, errors.New("IAM authorization is not supported, as tasks have no credentials of their own to authorize with")
+	}
+	accessPointID := efs.AuthorizationConfig.AccessPointID
+	if accessPointID != "" && !efsAccessPointIDPattern.MatchString(accessPointID) {
+		return nil, errors.New("invalid access point id " + strconv.Quote(accessPointID))
+	}
+	if efs.TransitEncryptionPort < 0 || efs.TransitEncryptionPort > efsMaxPort {
+		return nil, errors.New("invalid transit encryption port " + strconv.FormatInt(efs.TransitEncryptionPort, 10))
+	}
+	options := []string{}
+	if efs.TransitEncryptionEnabled() {
+		options = append(options, "tls")
+		if efs.TransitEncryptionPort != 0 {
+			options = append(options, "tlsport="+strconv.FormatInt(efs.TransitEncryptionPort, 10))
+		}
+	} else if accessPointID != "" {
+		return nil, errors.New("transit encryption is required for access points")
+	}
+	if accessPointID != "" {
+		options = append(options, "accesspoint="+accessPointID)
+	}
+	return options, nil
+}
+
+// taskIDFromArn returns the final component of a task arn
+func taskIDFromArn(taskArn string) string {
+	return taskArn[strings.LastIndex(taskArn, "/")+1:]
+}
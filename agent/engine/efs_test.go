@@ -0,0 +1,153 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+)
+
+type fakeMounter struct {
+	mounts        map[string][]string
+	unmountErrors int
+	unmounts      int
+}
+
+func (m *fakeMounter) Mount(source, target, fsType string, options []string) error {
+	m.mounts[target] = append([]string{fsType, source}, options...)
+	return nil
+}
+
+func (m *fakeMounter) Unmount(target string) error {
+	m.unmounts++
+	if m.unmountErrors > 0 {
+		m.unmountErrors--
+		return errors.New("device busy")
+	}
+	delete(m.mounts, target)
+	return nil
+}
+
+func efsTask() *api.Task {
+	return &api.Task{
+		Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc",
+		Volumes: []api.TaskVolume{
+			api.TaskVolume{Name: "efs", Volume: &api.EFSVolume{
+				FileSystemID:          "fs-1234",
+				TransitEncryption:     "ENABLED",
+				TransitEncryptionPort: 20049,
+				AuthorizationConfig:   api.EFSAuthorizationConfig{AccessPointID: "fsap-1234"},
+			}},
+			api.TaskVolume{Name: "host", Volume: &api.FSHostVolume{FSSourcePath: "/tmp"}},
+		},
+	}
+}
+
+func TestMountUnmountEFSVolumes(t *testing.T) {
+	mountDir, err := ioutil.TempDir("", "efs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mountDir)
+
+	engine := NewDockerTaskEngine(&config.Config{EFSMountDir: mountDir})
+	mounter := &fakeMounter{mounts: make(map[string][]string), unmountErrors: 1}
+	engine.mounter = mounter
+
+	task := efsTask()
+	if err := engine.mountEFSVolumes(task); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(mountDir, "abc", "efs")
+	expected := []string{"efs", "fs-1234:/", "tls", "tlsport=20049", "accesspoint=fsap-1234"}
+	if !reflect.DeepEqual(mounter.mounts[target], expected) {
+		t.Error("Unexpected mount", mounter.mounts)
+	}
+	if task.Volumes[0].Volume.SourcePath() != target {
+		t.Error("Expected volume source path to be the mount target")
+	}
+
+	// Mounting again is a no-op
+	if err := engine.mountEFSVolumes(task); err != nil || len(mounter.mounts) != 1 {
+		t.Error("Expected a single mount", err, mounter.mounts)
+	}
+
+	engine.unmountEFSVolumes(task)
+	if mounter.unmounts != 2 || len(mounter.mounts) != 0 {
+		t.Error("Expected unmount to be retried until it succeeded", mounter.unmounts)
+	}
+	if task.Volumes[0].Volume.SourcePath() != "" {
+		t.Error("Expected volume to no longer be mounted")
+	}
+}
+
+func TestEFSMountOptionsRequireTLS(t *testing.T) {
+	_, err := efsMountOptions(&api.EFSVolume{
+		FileSystemID:        "fs-1234",
+		AuthorizationConfig: api.EFSAuthorizationConfig{AccessPointID: "fsap-1234"},
+	})
+	if err == nil {
+		t.Error("Expected an error using an access point without transit encryption")
+	}
+}
+
+func TestEFSMountOptionsRefuseIAM(t *testing.T) {
+	_, err := efsMountOptions(&api.EFSVolume{
+		FileSystemID:        "fs-1234",
+		TransitEncryption:   "ENABLED",
+		AuthorizationConfig: api.EFSAuthorizationConfig{IAM: "ENABLED"},
+	})
+	if err == nil {
+		t.Error("Expected IAM authorization to be refused rather than use the instance's credentials")
+	}
+}
+
+func TestEFSMountSourceRejectsInvalidValues(t *testing.T) {
+	for _, efs := range []*api.EFSVolume{
+		&api.EFSVolume{FileSystemID: ""},
+		&api.EFSVolume{FileSystemID: "fs-1234,tls"},
+		&api.EFSVolume{FileSystemID: "fs-XYZ"},
+		&api.EFSVolume{FileSystemID: "fs-1234:/other"},
+		&api.EFSVolume{FileSystemID: "fs-1234", RootDirectory: "/data,iam"},
+	} {
+		if _, err := efsMountSource(efs); err == nil {
+			t.Error("Expected an error for", efs.FileSystemID, efs.RootDirectory)
+		}
+	}
+
+	source, err := efsMountSource(&api.EFSVolume{FileSystemID: "fs-1234", RootDirectory: "/data"})
+	if err != nil || source != "fs-1234:/data" {
+		t.Error("Unexpected mount source", source, err)
+	}
+}
+
+func TestEFSMountOptionsRejectInvalidValues(t *testing.T) {
+	for _, efs := range []*api.EFSVolume{
+		&api.EFSVolume{TransitEncryption: "ENABLED", AuthorizationConfig: api.EFSAuthorizationConfig{AccessPointID: "fsap-1234,iam"}},
+		&api.EFSVolume{TransitEncryption: "ENABLED", AuthorizationConfig: api.EFSAuthorizationConfig{AccessPointID: "fs-1234"}},
+		&api.EFSVolume{TransitEncryption: "ENABLED", TransitEncryptionPort: -1},
+		&api.EFSVolume{TransitEncryption: "ENABLED", TransitEncryptionPort: 65536},
+	} {
+		if options, err := efsMountOptions(efs); err == nil {
+			t.Error("Expected an error for", efs.AuthorizationConfig.AccessPointID, efs.TransitEncryptionPort, options)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const (
+	// maxEnvVariableSize is the longest a single NAME=value may be, with its
+	// terminating byte: the kernel's MAX_ARG_STRLEN
+	maxEnvVariableSize = 128 * 1024
+	// maxExecArgsSize is how much a process's environment and arguments may
+	// take together: the kernel's limit under the default 8 MiB stack. The
+	// image's own environment is added on top, so this is a lower bound on
+	// what docker will fail to start.
+	maxExecArgsSize = 2 * 1024 * 1024
+	// execArgPointerSize is what each variable and argument costs on top of
+	// its bytes
+	execArgPointerSize = 8
+)
+
+// EnvironmentSizeError is returned when a container's environment is too
+// large for its process to be started
+type EnvironmentSizeError struct {
+	msg string
+}
+
+func (err EnvironmentSizeError) Error() string     { return err.msg }
+func (err EnvironmentSizeError) ErrorName() string { return "EnvironmentSizeError" }
+func (err EnvironmentSizeError) ErrorCode() string { return api.ErrorCodeCannotCreateContainer }
+
+// validateEnvironmentSize checks that the container's environment, together
+// with its command, fits within what a process can be started with. Docker
+// creates such containers but fails to start them with an opaque error.
+func validateEnvironmentSize(container *api.Container, config *docker.Config) api.NamedError {
+	total := 0
+	for _, variable := range config.Env {
+		size := len(variable) + 1
+		if size > maxEnvVariableSize {
+			name := variable
+			if equals := strings.Index(variable, "="); equals != -1 {
+				name = variable[:equals]
+			}
+			return EnvironmentSizeError{"Environment variable " + name + " of container " + container.Name + " is " + strconv.Itoa(size) + " bytes, more than the limit of " + strconv.Itoa(maxEnvVariableSize) + " bytes for one variable; pass large values in a file instead"}
+		}
+		total += size + execArgPointerSize
+	}
+	for _, args := range [][]string{config.Entrypoint, config.Cmd} {
+		for _, arg := range args {
+			total += len(arg) + 1 + execArgPointerSize
+		}
+	}
+	if total > maxExecArgsSize {
+		return EnvironmentSizeError{"Environment of container " + container.Name + " is too large: its " + strconv.Itoa(len(config.Env)) + " variables and command take " + strconv.Itoa(total) + " bytes, more than the limit of " + strconv.Itoa(maxExecArgsSize) + " bytes; remove variables or pass large values in a file instead"}
+	}
+	return nil
+}
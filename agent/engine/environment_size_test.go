@@ -0,0 +1,67 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestValidateEnvironmentSize(t *testing.T) {
+	container := &api.Container{Name: "web"}
+
+	config := &docker.Config{Env: []string{"A=1", "B=2"}, Cmd: []string{"nginx", "-g", "daemon off;"}}
+	if err := validateEnvironmentSize(container, config); err != nil {
+		t.Error("Expected a small environment to be valid", err)
+	}
+
+	config = &docker.Config{Env: []string{"BIG=" + strings.Repeat("x", maxEnvVariableSize)}}
+	err := validateEnvironmentSize(container, config)
+	if _, ok := err.(EnvironmentSizeError); !ok {
+		t.Fatal("Expected an oversized variable to be refused", err)
+	}
+	if !strings.Contains(err.Error(), "BIG") || !strings.Contains(err.Error(), "web") {
+		t.Error("Expected the error to name the variable and container", err)
+	}
+
+	config = &docker.Config{}
+	for i := 0; i < 30; i++ {
+		config.Env = append(config.Env, "VAR"+strconv.Itoa(i)+"="+strings.Repeat("x", 100*1024))
+	}
+	err = validateEnvironmentSize(container, config)
+	if _, ok := err.(EnvironmentSizeError); !ok {
+		t.Fatal("Expected an oversized environment to be refused", err)
+	}
+	if !strings.Contains(err.Error(), "30 variables") {
+		t.Error("Expected the error to count the variables", err)
+	}
+	if code := api.ErrorCode(err); code != api.ErrorCodeCannotCreateContainer {
+		t.Error("Unexpected error code", code)
+	}
+}
+
+func TestValidateEnvironmentSizeCountsCommand(t *testing.T) {
+	container := &api.Container{Name: "web"}
+	config := &docker.Config{Env: []string{"A=" + strings.Repeat("x", 100*1024)}}
+	for i := 0; i < 20; i++ {
+		config.Cmd = append(config.Cmd, strings.Repeat("y", 100*1024))
+	}
+	if err := validateEnvironmentSize(container, config); err == nil {
+		t.Error("Expected the command to count towards the limit")
+	}
+}
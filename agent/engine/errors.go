@@ -29,6 +29,19 @@ func (err *impossibleTransitionError) Error() string {
 	return "Cannot transition to " + err.state.String()
 }
 func (err *impossibleTransitionError) ErrorName() string { return "ImpossibleStateTransitionError" }
+func (err *impossibleTransitionError) ErrorCode() string { return api.ErrorCodeInternal }
+
+// TransitionCancelledError is returned by a container transition which was
+// abandoned because the container's task is stopping
+type TransitionCancelledError struct {
+	transition string
+}
+
+func (err TransitionCancelledError) Error() string {
+	return "Transition to " + err.transition + " cancelled; the task is stopping"
+}
+func (err TransitionCancelledError) ErrorName() string { return "TransitionCancelledError" }
+func (err TransitionCancelledError) ErrorCode() string { return api.ErrorCodeInternal }
 
 type DockerTimeoutError struct {
 	duration   time.Duration
@@ -39,11 +52,27 @@ func (err *DockerTimeoutError) Error() string {
 	return "Could not transition to " + err.transition + "; timed out after waiting " + err.duration.String()
 }
 func (err *DockerTimeoutError) ErrorName() string { return "DockerTimeoutError" }
+func (err *DockerTimeoutError) ErrorCode() string {
+	switch err.transition {
+	case "pulled", "pullBegin":
+		return api.ErrorCodeCannotPullContainer
+	case "created":
+		return api.ErrorCodeCannotCreateContainer
+	case "started":
+		return api.ErrorCodeCannotStartContainer
+	case "stopped":
+		return api.ErrorCodeCannotStopContainer
+	case "inspecting":
+		return api.ErrorCodeCannotInspectContainer
+	}
+	return api.ErrorCodeInternal
+}
 
 type ContainerVanishedError struct{}
 
 func (err ContainerVanishedError) Error() string     { return "No container matching saved ID found" }
 func (err ContainerVanishedError) ErrorName() string { return "ContainerVanishedError" }
+func (err ContainerVanishedError) ErrorCode() string { return api.ErrorCodeCannotInspectContainer }
 
 type CannotXContainerError struct {
 	transition string
@@ -54,11 +83,39 @@ func (err CannotXContainerError) Error() string { return err.msg }
 func (err CannotXContainerError) ErrorName() string {
 	return "Cannot" + err.transition + "ContainerError"
 }
+func (err CannotXContainerError) ErrorCode() string {
+	switch err.transition {
+	case "Pull":
+		return api.ErrorCodeCannotPullContainer
+	case "Create":
+		return api.ErrorCodeCannotCreateContainer
+	case "Start":
+		return api.ErrorCodeCannotStartContainer
+	case "Stop":
+		return api.ErrorCodeCannotStopContainer
+	case "Inspect", "Describe":
+		return api.ErrorCodeCannotInspectContainer
+	}
+	return api.ErrorCodeInternal
+}
+
+// ContainerNameConflictError is returned when a container could not be created
+// because its name is already in use
+type ContainerNameConflictError struct {
+	name string
+}
+
+func (err ContainerNameConflictError) Error() string {
+	return "Container name " + err.name + " is already in use"
+}
+func (err ContainerNameConflictError) ErrorName() string { return "ContainerNameConflictError" }
+func (err ContainerNameConflictError) ErrorCode() string { return api.ErrorCodeCannotCreateContainer }
 
 type OutOfMemoryError struct{}
 
 func (err OutOfMemoryError) Error() string     { return "Container killed due to memory usage" }
 func (err OutOfMemoryError) ErrorName() string { return "OutOfMemoryError" }
+func (err OutOfMemoryError) ErrorCode() string { return api.ErrorCodeOutOfMemory }
 
 // DockerStateError is a wrapper around the error docker puts in the '.State.Error' field of its inspect output.
 type DockerStateError struct {
@@ -80,3 +137,9 @@ func (err DockerStateError) Error() string {
 func (err DockerStateError) ErrorName() string {
 	return err.name
 }
+
+// ErrorCode implements api.CodedError. Docker only records an error in the
+// container's state when it could not run it.
+func (err DockerStateError) ErrorCode() string {
+	return api.ErrorCodeCannotStartContainer
+}
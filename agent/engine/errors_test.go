@@ -0,0 +1,48 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+func TestErrorCodes(t *testing.T) {
+	testCases := []struct {
+		err  error
+		code string
+	}{
+		{CannotXContainerError{"Pull", "msg"}, api.ErrorCodeCannotPullContainer},
+		{CannotXContainerError{"Create", "msg"}, api.ErrorCodeCannotCreateContainer},
+		{CannotXContainerError{"Start", "msg"}, api.ErrorCodeCannotStartContainer},
+		{CannotXContainerError{"Stop", "msg"}, api.ErrorCodeCannotStopContainer},
+		{CannotXContainerError{"Describe", "msg"}, api.ErrorCodeCannotInspectContainer},
+		{&DockerTimeoutError{time.Second, "pullBegin"}, api.ErrorCodeCannotPullContainer},
+		{&DockerTimeoutError{time.Second, "created"}, api.ErrorCodeCannotCreateContainer},
+		{&DockerTimeoutError{time.Second, "listing"}, api.ErrorCodeInternal},
+		{ContainerVanishedError{}, api.ErrorCodeCannotInspectContainer},
+		{OutOfMemoryError{}, api.ErrorCodeOutOfMemory},
+		{NewDockerStateError("msg"), api.ErrorCodeCannotStartContainer},
+		{EFSMountError{"msg"}, api.ErrorCodeResourceInitialization},
+		{ImageVerificationError{"msg"}, api.ErrorCodeCannotVerifyImage},
+		{InvalidDeviceError{"msg"}, api.ErrorCodeCannotCreateContainer},
+	}
+	for _, tc := range testCases {
+		if code := api.ErrorCode(tc.err); code != tc.code {
+			t.Errorf("Expected %v for %v, got %v", tc.code, tc.err, code)
+		}
+	}
+}
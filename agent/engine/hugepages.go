@@ -0,0 +1,203 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+const (
+	// hugePagesPath is where the kernel describes the host's hugepages
+	hugePagesPath = "/sys/kernel/mm/hugepages"
+	procPath      = "/proc"
+	cgroupPath    = "/sys/fs/cgroup"
+)
+
+// hugePageSizes are the page sizes a container's hugepages may be limited
+// for, in kB
+var hugePageSizes = map[string]int64{
+	"2MB": 2048,
+	"1GB": 1024 * 1024,
+}
+
+// HugePagesError is returned when a task's hugepage limits are invalid or
+// can't be met by the host, or a container's limits could not be applied
+type HugePagesError struct {
+	msg string
+}
+
+func (err HugePagesError) Error() string     { return err.msg }
+func (err HugePagesError) ErrorName() string { return "HugePagesError" }
+func (err HugePagesError) ErrorCode() string { return api.ErrorCodeResourceInitialization }
+
+// validateHugePages checks that each of the task's hugepage limits is for a
+// supported page size and a whole number of pages, that the host, as
+// described under path, has enough free pages of each size for all of them,
+// and that the agent can see the hugetlb cgroups, under cgroupRoot, to apply
+// them
+func validateHugePages(task *api.Task, path, cgroupRoot string) api.NamedError {
+	required := make(map[string]int64)
+	for _, container := range task.Containers {
+		if container.LinuxParameters == nil {
+			continue
+		}
+		for _, limit := range container.LinuxParameters.HugePages {
+			sizeKB, ok := hugePageSizes[limit.PageSize]
+			if !ok {
+				return HugePagesError{"Invalid hugepage size '" + limit.PageSize + "' for container " + container.Name + ": it must be 2MB or 1GB"}
+			}
+			if limit.Limit <= 0 || limit.Limit*1024%sizeKB != 0 {
+				return HugePagesError{"Invalid " + limit.PageSize + " hugepage limit for container " + container.Name + ": it must be a whole number of pages"}
+			}
+			required[limit.PageSize] += limit.Limit * 1024 / sizeKB
+		}
+	}
+	if len(required) == 0 {
+		return nil
+	}
+	if err := checkCgroupController("hugetlb", cgroupRoot); err != nil {
+		return HugePagesError{"Unable to limit hugepages: " + err.Error()}
+	}
+
+	for size, pages := range required {
+		freeFile := filepath.Join(path, "hugepages-"+strconv.FormatInt(hugePageSizes[size], 10)+"kB", "free_hugepages")
+		contents, err := ioutil.ReadFile(freeFile)
+		if os.IsNotExist(err) {
+			return HugePagesError{"The instance does not support " + size + " hugepages"}
+		}
+		if err != nil {
+			return HugePagesError{"Unable to read the instance's free " + size + " hugepages: " + err.Error()}
+		}
+		free, err := strconv.ParseInt(strings.TrimSpace(string(contents)), 10, 64)
+		if err != nil {
+			return HugePagesError{"Unable to read the instance's free " + size + " hugepages: " + err.Error()}
+		}
+		if free < pages {
+			return HugePagesError{"The task requires " + strconv.FormatInt(pages, 10) + " " + size + " hugepages but the instance has " + strconv.FormatInt(free, 10) + " free"}
+		}
+	}
+	return nil
+}
+
+// limitHugePages applies the container's hugepage limits to its cgroup. The
+// docker API version the agent uses can't set them when the container is
+// created, so they are written once the container has started and its cgroup
+// exists; until then, the container runs without them. The container must be
+// stopped if they can't be written.
+func (engine *DockerTaskEngine) limitHugePages(task *api.Task, container *api.Container, dockerID string) api.NamedError {
+	if container.LinuxParameters == nil || len(container.LinuxParameters.HugePages) == 0 {
+		return nil
+	}
+	dockerContainer, err := engine.client.InspectContainer(dockerID)
+	if err != nil {
+		return HugePagesError{"Unable to find the cgroup of container " + container.Name + " for its hugepage limits: " + err.Error()}
+	}
+	log.Info("Limiting hugepages", "task", task.Arn, "container", container.Name)
+	err = writeHugePageLimits(container.LinuxParameters.HugePages, dockerContainer.State.Pid, procPath, cgroupPath)
+	if err != nil {
+		return HugePagesError{"Unable to limit hugepages of container " + container.Name + ": " + err.Error()}
+	}
+	return nil
+}
+
+// writeHugePageLimits writes limits to the hugetlb cgroup of the process pid,
+// under cgroup v1 or v2, given where proc and the cgroup hierarchies are
+// mounted
+func writeHugePageLimits(limits []api.HugePageLimit, pid int, proc, cgroupRoot string) error {
+	dir, err := processCgroupDir(pid, "hugetlb", proc, cgroupRoot)
+	if err != nil {
+		return err
+	}
+	for _, limit := range limits {
+		name := "hugetlb." + limit.PageSize + ".limit_in_bytes"
+		if _, err := os.Stat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			name = "hugetlb." + limit.PageSize + ".max"
+		}
+		bytes := strconv.FormatInt(limit.Limit*1024*1024, 10)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(bytes), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processCgroupDir returns the directory of the cgroup of the process pid
+// for controller: its directory in the controller's hierarchy under cgroup
+// v1, or its only directory under v2
+func processCgroupDir(pid int, controller, proc, cgroupRoot string) (string, error) {
+	if pid == 0 {
+		return "", errors.New("the container is not running")
+	}
+	file, err := os.Open(filepath.Join(proc, strconv.Itoa(pid), "cgroup"))
+	if os.IsNotExist(err) {
+		return "", errors.New("the agent can't see the container's processes under " + proc + "; it must share the host's pid namespace")
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// Each line is "hierarchy-id:controllers:path". Under cgroup v2 there is
+	// a single line with id 0 and no controllers.
+	dir := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		for _, enabled := range strings.Split(fields[1], ",") {
+			if enabled == controller {
+				dir = filepath.Join(cgroupRoot, controller, fields[2])
+			}
+		}
+		if fields[0] == "0" && fields[1] == "" && dir == "" {
+			dir = filepath.Join(cgroupRoot, fields[2])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if dir == "" {
+		return "", errors.New("the " + controller + " cgroup controller is not enabled")
+	}
+	return dir, nil
+}
+
+// checkCgroupController checks that the agent can see the host's cgroups for
+// controller, under cgroup v1 or v2, given where they are mounted. A
+// containerized agent needs the host's cgroup filesystem mounted there to
+// apply the limits docker can't.
+func checkCgroupController(controller, cgroupRoot string) error {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, controller)); err == nil {
+		return nil
+	}
+	controllers, err := ioutil.ReadFile(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	if err == nil {
+		for _, enabled := range strings.Fields(string(controllers)) {
+			if enabled == controller {
+				return nil
+			}
+		}
+	}
+	return errors.New("the agent can't see the host's " + controller + " cgroups under " + cgroupRoot)
+}
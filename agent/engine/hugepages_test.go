@@ -0,0 +1,105 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+func hugePagesTask(limits ...api.HugePageLimit) *api.Task {
+	return &api.Task{
+		Arn: "t1",
+		Containers: []*api.Container{
+			&api.Container{Name: "app", LinuxParameters: &api.LinuxParameters{HugePages: limits}},
+			&api.Container{Name: "sidecar"},
+		},
+	}
+}
+
+func TestValidateHugePages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hugepages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "hugepages-2048kB"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "hugepages-2048kB", "free_hugepages"), []byte("4\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "cgroup", "hugetlb"), 0755)
+	cgroupRoot := filepath.Join(dir, "cgroup")
+
+	if err := validateHugePages(hugePagesTask(api.HugePageLimit{PageSize: "2MB", Limit: 8}), dir, cgroupRoot); err != nil {
+		t.Error("Expected the free pages to be enough", err)
+	}
+	if err := validateHugePages(hugePagesTask(), dir, filepath.Join(dir, "missing")); err != nil {
+		t.Error("Expected a task without limits not to need the hugetlb cgroups", err)
+	}
+	if err := validateHugePages(hugePagesTask(api.HugePageLimit{PageSize: "2MB", Limit: 8}), dir, filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error when the hugetlb cgroups can't be seen")
+	}
+	for _, limit := range []api.HugePageLimit{
+		{PageSize: "2MB", Limit: 10},
+		{PageSize: "2MB", Limit: 3},
+		{PageSize: "4MB", Limit: 4},
+		{PageSize: "1GB", Limit: 1024},
+	} {
+		if err := validateHugePages(hugePagesTask(limit), dir, cgroupRoot); err == nil {
+			t.Error("Expected an error for", limit)
+		} else if err.ErrorName() != "HugePagesError" {
+			t.Error("Unexpected error", err)
+		}
+	}
+}
+
+func TestWriteHugePageLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hugepages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	limits := []api.HugePageLimit{{PageSize: "2MB", Limit: 8}}
+
+	// cgroup v1
+	v1 := filepath.Join(dir, "cgroup", "hugetlb", "docker", "abc")
+	os.MkdirAll(v1, 0755)
+	ioutil.WriteFile(filepath.Join(v1, "hugetlb.2MB.limit_in_bytes"), []byte("0"), 0644)
+	os.MkdirAll(filepath.Join(dir, "proc", "10"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "proc", "10", "cgroup"), []byte("4:memory:/docker/abc\n3:hugetlb:/docker/abc\n"), 0644)
+	if err := writeHugePageLimits(limits, 10, filepath.Join(dir, "proc"), filepath.Join(dir, "cgroup")); err != nil {
+		t.Fatal(err)
+	}
+	if limit, _ := ioutil.ReadFile(filepath.Join(v1, "hugetlb.2MB.limit_in_bytes")); string(limit) != "8388608" {
+		t.Error("Unexpected v1 limit", string(limit))
+	}
+
+	// cgroup v2
+	v2 := filepath.Join(dir, "cgroup", "system.slice", "docker-abc.scope")
+	os.MkdirAll(v2, 0755)
+	os.MkdirAll(filepath.Join(dir, "proc", "11"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "proc", "11", "cgroup"), []byte("0::/system.slice/docker-abc.scope\n"), 0644)
+	if err := writeHugePageLimits(limits, 11, filepath.Join(dir, "proc"), filepath.Join(dir, "cgroup")); err != nil {
+		t.Fatal(err)
+	}
+	if limit, _ := ioutil.ReadFile(filepath.Join(v2, "hugetlb.2MB.max")); string(limit) != "8388608" {
+		t.Error("Unexpected v2 limit", string(limit))
+	}
+
+	if err := writeHugePageLimits(limits, 0, filepath.Join(dir, "proc"), filepath.Join(dir, "cgroup")); err == nil {
+		t.Error("Expected an error for a container which isn't running")
+	}
+}
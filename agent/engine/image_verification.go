@@ -0,0 +1,202 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+const (
+	imageVerifierCosign   = "cosign"
+	imageVerifierNotation = "notation"
+
+	// verificationCacheDuration and failedVerificationCacheDuration are how
+	// long a verification of a digest is remembered before it is verified
+	// again, so that revoked keys and signatures are noticed
+	verificationCacheDuration       = time.Hour
+	failedVerificationCacheDuration = 5 * time.Minute
+
+	// imageVerificationTimeout bounds each run of the verifier, which may
+	// fetch signatures and transparency logs over the network
+	imageVerificationTimeout = 2 * time.Minute
+)
+
+// ImageVerificationError is returned when the signature of a container's
+// image could not be verified
+type ImageVerificationError struct {
+	msg string
+}
+
+func (err ImageVerificationError) Error() string     { return err.msg }
+func (err ImageVerificationError) ErrorName() string { return "ImageVerificationError" }
+func (err ImageVerificationError) ErrorCode() string { return api.ErrorCodeCannotVerifyImage }
+
+// imageVerifier verifies the signature of an image, given by digest as
+// repository@sha256:...
+type imageVerifier interface {
+	Verify(imageDigest string) error
+}
+
+// execImageVerifier shells out to cosign or notation. Neither is in the
+// agent's image, so the agent must be run where one is installed.
+type execImageVerifier struct {
+	tool string
+	key  string
+}
+
+func (verifier execImageVerifier) Verify(imageDigest string) error {
+	var args []string
+	switch verifier.tool {
+	case imageVerifierCosign:
+		if verifier.key == "" {
+			return errors.New("cosign verification requires a key")
+		}
+		args = []string{"verify", "--key", verifier.key, imageDigest}
+	case imageVerifierNotation:
+		args = []string{"verify", imageDigest}
+	default:
+		return errors.New("unsupported image verifier " + verifier.tool)
+	}
+	if _, err := exec.LookPath(verifier.tool); err != nil {
+		return errors.New(verifier.tool + " is not installed where the agent runs")
+	}
+	_, err := runCommand(verifier.tool, args, nil, imageVerificationTimeout)
+	return err
+}
+
+// verificationResult is a cached verification of a digest
+type verificationResult struct {
+	err     error
+	expires time.Time
+}
+
+// imageVerificationCache remembers verification results by digest for a
+// while, so that each image is not verified for every container
+type imageVerificationCache struct {
+	lock    sync.Mutex
+	results map[string]verificationResult
+}
+
+func newImageVerificationCache() *imageVerificationCache {
+	return &imageVerificationCache{results: make(map[string]verificationResult)}
+}
+
+// get returns the cached result for digest, if there is one
+func (cache *imageVerificationCache) get(digest string) (error, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	result, ok := cache.results[digest]
+	if !ok || ttime.Now().After(result.expires) {
+		return nil, false
+	}
+	return result.err, true
+}
+
+// put remembers the result for digest, forgetting those which have expired
+func (cache *imageVerificationCache) put(digest string, err error) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	now := ttime.Now()
+	for cached, result := range cache.results {
+		if now.After(result.expires) {
+			delete(cache.results, cached)
+		}
+	}
+	duration := verificationCacheDuration
+	if err != nil {
+		duration = failedVerificationCacheDuration
+	}
+	cache.results[digest] = verificationResult{err: err, expires: now.Add(duration)}
+}
+
+// newImageVerifier returns the verifier configured in cfg, or nil if images
+// are not verified
+func newImageVerifier(cfg *config.Config) imageVerifier {
+	if cfg.ImageVerifier == "" {
+		return nil
+	}
+	if _, err := exec.LookPath(cfg.ImageVerifier); err != nil {
+		log.Warn("The image verifier is not installed where the agent runs; containers will fail to be created", "verifier", cfg.ImageVerifier)
+	}
+	return execImageVerifier{tool: cfg.ImageVerifier, key: cfg.ImageVerificationKey}
+}
+
+// verifyImage verifies the signature of the container's pulled image, if
+// image verification is enabled, and returns the digest it verified. The
+// container must be created from that digest rather than from its image's
+// tag, which may since have been pulled again for another image.
+func (engine *DockerTaskEngine) verifyImage(task *api.Task, container *api.Container, span *tracing.Span) (string, api.NamedError) {
+	if engine.imageVerifier == nil {
+		return "", nil
+	}
+	verifySpan := span.StartChild("verify image signature")
+	digest, err := engine.verifyImageDigest(task, container)
+	if err != nil {
+		verifySpan.End(err)
+		return "", err
+	}
+	verifySpan.End(nil)
+	return digest, nil
+}
+
+func (engine *DockerTaskEngine) verifyImageDigest(task *api.Task, container *api.Container) (string, api.NamedError) {
+	repoDigests, err := engine.client.ImageRepoDigests(container.Image)
+	if err != nil {
+		return "", ImageVerificationError{"Unable to inspect image " + container.Image + ": " + err.Error()}
+	}
+	digest := repoDigest(container.Image, repoDigests)
+	if digest == "" {
+		return "", ImageVerificationError{"Unable to verify image " + container.Image + ": it has no repository digest"}
+	}
+
+	err, cached := engine.imageVerifications.get(digest)
+	if !cached {
+		log.Info("Verifying image signature", "task", task.Arn, "container", container.Name, "digest", digest)
+		err = engine.imageVerifier.Verify(digest)
+		engine.imageVerifications.put(digest, err)
+	}
+	if err != nil {
+		return "", ImageVerificationError{"Unable to verify the signature of image " + digest + ": " + err.Error()}
+	}
+	return digest, nil
+}
+
+// repoDigest returns the digest of image among an image's repository
+// digests, preferring the one from the same repository
+func repoDigest(image string, repoDigests []string) string {
+	repository := image
+	if at := strings.Index(repository, "@"); at != -1 {
+		repository = repository[:at]
+	} else if colon := strings.LastIndex(repository, ":"); colon > strings.LastIndex(repository, "/") {
+		repository = repository[:colon]
+	}
+	for _, digest := range repoDigests {
+		if strings.HasPrefix(digest, repository+"@") {
+			return digest
+		}
+	}
+	if len(repoDigests) == 1 {
+		return repoDigests[0]
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+// imageInspectingClient answers ImageRepoDigests with fixed digests
+type imageInspectingClient struct {
+	DockerClient
+	repoDigests []string
+}
+
+func (client *imageInspectingClient) ImageRepoDigests(image string) ([]string, error) {
+	return client.repoDigests, nil
+}
+
+// fakeImageVerifier records the digests it is asked to verify
+type fakeImageVerifier struct {
+	err      error
+	verified []string
+}
+
+func (verifier *fakeImageVerifier) Verify(imageDigest string) error {
+	verifier.verified = append(verifier.verified, imageDigest)
+	return verifier.err
+}
+
+func TestVerifyImage(t *testing.T) {
+	testTime := ttime.NewTestTime()
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+	engine := NewDockerTaskEngine(&config.Config{})
+	engine.client = &imageInspectingClient{repoDigests: []string{"example.com/other@sha256:aaaa", "example.com/app@sha256:bbbb"}}
+	verifier := &fakeImageVerifier{}
+	engine.imageVerifier = verifier
+	task := &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc"}
+	container := &api.Container{Name: "app", Image: "example.com/app:v1"}
+
+	for i := 0; i < 2; i++ {
+		digest, err := engine.verifyImage(task, container, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if digest != "example.com/app@sha256:bbbb" {
+			t.Error("Expected the verified digest", digest)
+		}
+	}
+	if len(verifier.verified) != 1 || verifier.verified[0] != "example.com/app@sha256:bbbb" {
+		t.Error("Expected the image's digest to be verified once", verifier.verified)
+	}
+
+	// Verifications expire, so that revoked signatures are noticed
+	testTime.Warp(verificationCacheDuration + time.Minute)
+	if _, err := engine.verifyImage(task, container, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(verifier.verified) != 2 {
+		t.Error("Expected the image's digest to be verified again", verifier.verified)
+	}
+}
+
+func TestVerifyImageFailure(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{})
+	engine.client = &imageInspectingClient{repoDigests: []string{"app@sha256:bbbb"}}
+	verifier := &fakeImageVerifier{err: errors.New("no matching signatures")}
+	engine.imageVerifier = verifier
+	task := &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc"}
+	container := &api.Container{Name: "app", Image: "app"}
+
+	_, err := engine.verifyImage(task, container, nil)
+	if api.ErrorCode(err) != api.ErrorCodeCannotVerifyImage {
+		t.Error("Expected an image verification error, got", err)
+	}
+	engine.verifyImage(task, container, nil)
+	if len(verifier.verified) != 1 {
+		t.Error("Expected the failure to be cached", verifier.verified)
+	}
+
+	engine.client = &imageInspectingClient{}
+	if _, err := engine.verifyImage(task, container, nil); err == nil {
+		t.Error("Expected an image without a digest to fail verification")
+	}
+}
+
+func TestRepoDigest(t *testing.T) {
+	testCases := []struct {
+		image    string
+		digests  []string
+		expected string
+	}{
+		{"app", []string{"app@sha256:1"}, "app@sha256:1"},
+		{"registry:5000/app:v1", []string{"other@sha256:1", "registry:5000/app@sha256:2"}, "registry:5000/app@sha256:2"},
+		{"app@sha256:2", []string{"other@sha256:1", "app@sha256:2"}, "app@sha256:2"},
+		{"library/app", []string{"app@sha256:1"}, "app@sha256:1"},
+		{"app", []string{"a@sha256:1", "b@sha256:2"}, ""},
+		{"app", nil, ""},
+	}
+	for _, tc := range testCases {
+		if digest := repoDigest(tc.image, tc.digests); digest != tc.expected {
+			t.Errorf("Expected %q for %s in %v, got %q", tc.expected, tc.image, tc.digests, digest)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+// RemoveManagedContainers stops and removes every container the agent created
+// for a task, found by the task arn label. It is used to start over when the
+// agent's record of its tasks is lost, so that they are not left running
+// unmanaged. Containers which cannot be removed are skipped; the last error is
+// returned.
+func RemoveManagedContainers(client DockerClient, stopTimeout time.Duration) error {
+	listed := client.ListContainers(true)
+	if listed.Error != nil {
+		return listed.Error
+	}
+	var lastErr error
+	for _, id := range listed.DockerIds {
+		container, err := client.InspectContainer(id)
+		if err != nil {
+			log.Warn("Unable to inspect container", "id", id, "err", err)
+			lastErr = err
+			continue
+		}
+		if container.Config == nil || container.Config.Labels[api.TaskArnLabel] == "" {
+			continue
+		}
+		log.Info("Removing container of lost task", "id", id, "task", container.Config.Labels[api.TaskArnLabel])
+		if container.State.Running {
+			if metadata := client.StopContainer(id, stopTimeout); metadata.Error != nil {
+				log.Warn("Unable to stop container", "id", id, "err", metadata.Error)
+				lastErr = metadata.Error
+				continue
+			}
+		}
+		if err := client.RemoveContainer(id); err != nil {
+			log.Warn("Unable to remove container", "id", id, "err", err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
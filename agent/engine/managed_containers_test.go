@@ -0,0 +1,82 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// containersClient lists and inspects a fixed set of containers, recording
+// which are stopped and removed
+type containersClient struct {
+	DockerClient
+	containers map[string]*docker.Container
+	stopped    []string
+	removed    []string
+}
+
+func (client *containersClient) ListContainers(all bool) ListContainersResponse {
+	ids := []string{}
+	for id := range client.containers {
+		ids = append(ids, id)
+	}
+	return ListContainersResponse{DockerIds: ids}
+}
+
+func (client *containersClient) InspectContainer(id string) (*docker.Container, error) {
+	return client.containers[id], nil
+}
+
+func (client *containersClient) StopContainer(id string, timeout time.Duration) DockerContainerMetadata {
+	client.stopped = append(client.stopped, id)
+	return DockerContainerMetadata{}
+}
+
+func (client *containersClient) RemoveContainer(id string) error {
+	if id == "stuck" {
+		return errors.New("device busy")
+	}
+	client.removed = append(client.removed, id)
+	return nil
+}
+
+func TestRemoveManagedContainers(t *testing.T) {
+	taskLabels := map[string]string{api.TaskArnLabel: "arn:task"}
+	client := &containersClient{containers: map[string]*docker.Container{
+		"running": {Config: &docker.Config{Labels: taskLabels}, State: docker.State{Running: true}},
+		"exited":  {Config: &docker.Config{Labels: taskLabels}},
+		"agent":   {Config: &docker.Config{}, State: docker.State{Running: true}},
+	}}
+
+	if err := RemoveManagedContainers(client, time.Second); err != nil {
+		t.Error("Unexpected error", err)
+	}
+	if !reflect.DeepEqual(client.stopped, []string{"running"}) {
+		t.Error("Expected only the running task container to be stopped, got", client.stopped)
+	}
+	if len(client.removed) != 2 || client.containers[client.removed[0]].Config.Labels == nil || client.containers[client.removed[1]].Config.Labels == nil {
+		t.Error("Expected both task containers to be removed, got", client.removed)
+	}
+
+	client.containers = map[string]*docker.Container{"stuck": {Config: &docker.Config{Labels: taskLabels}}}
+	if err := RemoveManagedContainers(client, time.Second); err == nil {
+		t.Error("Expected the removal error")
+	}
+}
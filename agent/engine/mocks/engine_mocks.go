@@ -23,6 +23,8 @@ import (
 	go_dockerclient "github.com/fsouza/go-dockerclient"
 	gomock "github.com/golang/mock/gomock"
 	context "golang.org/x/net/context"
+	io "io"
+	time "time"
 )
 
 // Mock of TaskEngine interface
@@ -165,6 +167,16 @@ func (_m *MockDockerClient) EXPECT() *_MockDockerClientRecorder {
 	return _m.recorder
 }
 
+func (_m *MockDockerClient) ContainerLogs(_param0 string, _param1 int, _param2 bool, _param3 io.Writer) error {
+	ret := _m.ctrl.Call(_m, "ContainerLogs", _param0, _param1, _param2, _param3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDockerClientRecorder) ContainerLogs(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ContainerLogs", arg0, arg1, arg2, arg3)
+}
+
 func (_m *MockDockerClient) ContainerEvents(_param0 context.Context) (<-chan engine.DockerContainerChangeEvent, error) {
 	ret := _m.ctrl.Call(_m, "ContainerEvents", _param0)
 	ret0, _ := ret[0].(<-chan engine.DockerContainerChangeEvent)
@@ -197,6 +209,16 @@ func (_mr *_MockDockerClientRecorder) DescribeContainer(arg0 interface{}) *gomoc
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "DescribeContainer", arg0)
 }
 
+func (_m *MockDockerClient) EventQueueDepth() engine.EventQueueDepth {
+	ret := _m.ctrl.Call(_m, "EventQueueDepth")
+	ret0, _ := ret[0].(engine.EventQueueDepth)
+	return ret0
+}
+
+func (_mr *_MockDockerClientRecorder) EventQueueDepth() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "EventQueueDepth")
+}
+
 func (_m *MockDockerClient) GetContainerName(_param0 string) (string, error) {
 	ret := _m.ctrl.Call(_m, "GetContainerName", _param0)
 	ret0, _ := ret[0].(string)
@@ -208,6 +230,28 @@ func (_mr *_MockDockerClientRecorder) GetContainerName(arg0 interface{}) *gomock
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "GetContainerName", arg0)
 }
 
+func (_m *MockDockerClient) ImageRepoDigests(_param0 string) ([]string, error) {
+	ret := _m.ctrl.Call(_m, "ImageRepoDigests", _param0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDockerClientRecorder) ImageRepoDigests(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ImageRepoDigests", arg0)
+}
+
+func (_m *MockDockerClient) Info() (*go_dockerclient.Env, error) {
+	ret := _m.ctrl.Call(_m, "Info")
+	ret0, _ := ret[0].(*go_dockerclient.Env)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDockerClientRecorder) Info() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Info")
+}
+
 func (_m *MockDockerClient) InspectContainer(_param0 string) (*go_dockerclient.Container, error) {
 	ret := _m.ctrl.Call(_m, "InspectContainer", _param0)
 	ret0, _ := ret[0].(*go_dockerclient.Container)
@@ -229,14 +273,14 @@ func (_mr *_MockDockerClientRecorder) ListContainers(arg0 interface{}) *gomock.C
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ListContainers", arg0)
 }
 
-func (_m *MockDockerClient) PullImage(_param0 string) engine.DockerContainerMetadata {
-	ret := _m.ctrl.Call(_m, "PullImage", _param0)
+func (_m *MockDockerClient) PullImage(_param0 context.Context, _param1 string) engine.DockerContainerMetadata {
+	ret := _m.ctrl.Call(_m, "PullImage", _param0, _param1)
 	ret0, _ := ret[0].(engine.DockerContainerMetadata)
 	return ret0
 }
 
-func (_mr *_MockDockerClientRecorder) PullImage(arg0 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "PullImage", arg0)
+func (_mr *_MockDockerClientRecorder) PullImage(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "PullImage", arg0, arg1)
 }
 
 func (_m *MockDockerClient) RemoveContainer(_param0 string) error {
@@ -249,6 +293,16 @@ func (_mr *_MockDockerClientRecorder) RemoveContainer(arg0 interface{}) *gomock.
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "RemoveContainer", arg0)
 }
 
+func (_m *MockDockerClient) RemoveImage(_param0 string) error {
+	ret := _m.ctrl.Call(_m, "RemoveImage", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDockerClientRecorder) RemoveImage(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RemoveImage", arg0)
+}
+
 func (_m *MockDockerClient) StartContainer(_param0 string) engine.DockerContainerMetadata {
 	ret := _m.ctrl.Call(_m, "StartContainer", _param0)
 	ret0, _ := ret[0].(engine.DockerContainerMetadata)
@@ -259,14 +313,34 @@ func (_mr *_MockDockerClientRecorder) StartContainer(arg0 interface{}) *gomock.C
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "StartContainer", arg0)
 }
 
-func (_m *MockDockerClient) StopContainer(_param0 string) engine.DockerContainerMetadata {
-	ret := _m.ctrl.Call(_m, "StopContainer", _param0)
+func (_m *MockDockerClient) StopContainer(_param0 string, _param1 time.Duration) engine.DockerContainerMetadata {
+	ret := _m.ctrl.Call(_m, "StopContainer", _param0, _param1)
+	ret0, _ := ret[0].(engine.DockerContainerMetadata)
+	return ret0
+}
+
+func (_mr *_MockDockerClientRecorder) StopContainer(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "StopContainer", arg0, arg1)
+}
+
+func (_m *MockDockerClient) StopContainerWithSignal(_param0 string, _param1 go_dockerclient.Signal, _param2 time.Duration) engine.DockerContainerMetadata {
+	ret := _m.ctrl.Call(_m, "StopContainerWithSignal", _param0, _param1, _param2)
 	ret0, _ := ret[0].(engine.DockerContainerMetadata)
 	return ret0
 }
 
-func (_mr *_MockDockerClientRecorder) StopContainer(arg0 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "StopContainer", arg0)
+func (_mr *_MockDockerClientRecorder) StopContainerWithSignal(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "StopContainerWithSignal", arg0, arg1, arg2)
+}
+
+func (_m *MockDockerClient) TagImage(_param0 string, _param1 string) error {
+	ret := _m.ctrl.Call(_m, "TagImage", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDockerClientRecorder) TagImage(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "TagImage", arg0, arg1)
 }
 
 func (_m *MockDockerClient) Version() (string, error) {
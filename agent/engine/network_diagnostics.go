@@ -0,0 +1,198 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+// NetworkDiagnosticTimeout is how long a network diagnostic command may run
+// before it is killed. Commands which run until stopped are cut off here and
+// return what they wrote so far.
+const NetworkDiagnosticTimeout = 30 * time.Second
+
+// networkDiagnostic is a diagnostic command with a fixed form; only the host
+// and port it checks are chosen by the caller
+type networkDiagnostic struct {
+	command string
+	// target is whether the diagnostic checks a host, and port whether it
+	// checks a port on it
+	target, port bool
+	args         func(host, port string) []string
+}
+
+// networkDiagnostics are the diagnostics which may be run in a task's network
+// namespace. They only inspect the network, and their arguments are fixed, so
+// a diagnostic can neither reconfigure a task's networking nor touch files.
+var networkDiagnostics = map[string]networkDiagnostic{
+	"ping": {command: "ping", target: true, args: func(host, _ string) []string {
+		return []string{"-c", "4", "-W", "2", host}
+	}},
+	"dns": {command: "dig", target: true, args: func(host, _ string) []string {
+		return []string{"+time=2", "+tries=2", host}
+	}},
+	"traceroute": {command: "traceroute", target: true, args: func(host, _ string) []string {
+		return []string{"-w", "2", "-m", "20", host}
+	}},
+	"tcp": {command: "nc", target: true, port: true, args: func(host, port string) []string {
+		return []string{"-z", "-v", "-w", "5", host, port}
+	}},
+	"sockets": {command: "ss", args: func(_, _ string) []string {
+		return []string{"-tuan"}
+	}},
+}
+
+// hostnamePattern matches DNS names, which cannot begin with '-' and so
+// cannot be mistaken for an option
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_.-]*[A-Za-z0-9])?$`)
+
+// NetworkDiagnosticResult is the output of a diagnostic command run in a
+// task's network namespace
+type NetworkDiagnosticResult struct {
+	Output string
+	// Error is why the command failed, such as a non-zero exit or timing out
+	Error string `json:",omitempty"`
+}
+
+// netnsRunner runs commands in the network namespace of a process
+type netnsRunner interface {
+	Run(pid int, command string, args []string, timeout time.Duration) ([]byte, error)
+}
+
+// nsenterRunner shells out to nsenter(1). It and the commands it runs must be
+// installed where the agent runs, which they are not in the agent's image.
+// The agent must also share the host's pid namespace for the process's
+// namespace to be found under /proc.
+type nsenterRunner struct{}
+
+func (nsenterRunner) Run(pid int, command string, args []string, timeout time.Duration) ([]byte, error) {
+	for _, required := range []string{"nsenter", command} {
+		if _, err := exec.LookPath(required); err != nil {
+			return nil, errors.New(required + " is not installed where the agent runs")
+		}
+	}
+	netns := filepath.Join("/proc", strconv.Itoa(pid), "ns", "net")
+	var output bytes.Buffer
+	cmd := exec.Command("nsenter", append([]string{"--net=" + netns, "--", command}, args...)...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return output.Bytes(), err
+	case <-ttime.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return output.Bytes(), errors.New("timed out after " + timeout.String())
+	}
+}
+
+// RunNetworkDiagnostic runs one of the network diagnostics, such as ping or
+// dns, against host and port from the network namespace of a task's
+// container, for debugging its connectivity. If containerName is empty, the
+// task's first running container is used. An error means the diagnostic
+// could not be run at all; its failures are reported in the result.
+func (engine *DockerTaskEngine) RunNetworkDiagnostic(taskArn, containerName, diagnosticName, host, port string) (*NetworkDiagnosticResult, error) {
+	diagnostic, ok := networkDiagnostics[diagnosticName]
+	if !ok {
+		return nil, errors.New("unsupported network diagnostic " + diagnosticName)
+	}
+	if err := validateNetworkDiagnosticTarget(diagnostic, host, port); err != nil {
+		return nil, err
+	}
+	dockerID, err := engine.networkDiagnosticContainer(taskArn, containerName)
+	if err != nil {
+		return nil, err
+	}
+	dockerContainer, err := engine.client.InspectContainer(dockerID)
+	if err != nil {
+		return nil, err
+	}
+	if dockerContainer.State.Pid == 0 {
+		return nil, errors.New("container " + dockerID + " is not running")
+	}
+
+	args := diagnostic.args(host, port)
+	log.Info("Running network diagnostic", "task", taskArn, "dockerId", dockerID, "command", diagnostic.command, "args", args)
+	output, err := engine.netns.Run(dockerContainer.State.Pid, diagnostic.command, args, NetworkDiagnosticTimeout)
+	result := &NetworkDiagnosticResult{Output: string(output)}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+// validateNetworkDiagnosticTarget checks that the diagnostic is given exactly
+// the host and port it needs, and that they are an address or name and a
+// port number rather than anything the command could read as an option
+func validateNetworkDiagnosticTarget(diagnostic networkDiagnostic, host, port string) error {
+	if !diagnostic.target {
+		if host != "" || port != "" {
+			return errors.New(diagnostic.command + " takes no host or port")
+		}
+		return nil
+	}
+	if net.ParseIP(host) == nil && (len(host) > 253 || !hostnamePattern.MatchString(host)) {
+		return errors.New("invalid host " + strconv.Quote(host))
+	}
+	if !diagnostic.port {
+		if port != "" {
+			return errors.New(diagnostic.command + " takes no port")
+		}
+		return nil
+	}
+	if portNumber, err := strconv.Atoi(port); err != nil || portNumber < 1 || portNumber > 65535 {
+		return errors.New("invalid port " + strconv.Quote(port))
+	}
+	return nil
+}
+
+// networkDiagnosticContainer returns the docker id of the task's container
+// to run a diagnostic beside
+func (engine *DockerTaskEngine) networkDiagnosticContainer(taskArn, containerName string) (string, error) {
+	task, ok := engine.state.TaskByArn(taskArn)
+	if !ok {
+		return "", errors.New("unknown task " + taskArn)
+	}
+	containers, _ := engine.state.ContainerMapByArn(taskArn)
+	if containerName != "" {
+		container, ok := containers[containerName]
+		if !ok || container.DockerId == "" {
+			return "", errors.New("unknown container " + containerName + " in task " + taskArn)
+		}
+		return container.DockerId, nil
+	}
+	for _, container := range task.Containers {
+		dockerContainer, ok := containers[container.Name]
+		if ok && dockerContainer.DockerId != "" && container.KnownStatus == api.ContainerRunning {
+			return dockerContainer.DockerId, nil
+		}
+	}
+	return "", errors.New("task " + taskArn + " has no running containers")
+}
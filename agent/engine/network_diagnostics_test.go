@@ -0,0 +1,138 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// pidClient answers InspectContainer with a pid for each docker id
+type pidClient struct {
+	DockerClient
+	pids map[string]int
+}
+
+func (client *pidClient) InspectContainer(id string) (*docker.Container, error) {
+	return &docker.Container{ID: id, State: docker.State{Pid: client.pids[id]}}, nil
+}
+
+// fakeNetnsRunner records the commands it is asked to run
+type fakeNetnsRunner struct {
+	pid     int
+	command []string
+	err     error
+}
+
+func (runner *fakeNetnsRunner) Run(pid int, command string, args []string, timeout time.Duration) ([]byte, error) {
+	runner.pid = pid
+	runner.command = append([]string{command}, args...)
+	return []byte("output"), runner.err
+}
+
+func networkDiagnosticEngine() (*DockerTaskEngine, *fakeNetnsRunner) {
+	engine := NewDockerTaskEngine(&config.Config{})
+	engine.client = &pidClient{pids: map[string]int{"dockerid1": 100, "dockerid2": 200}}
+	runner := &fakeNetnsRunner{}
+	engine.netns = runner
+
+	pending := &api.Container{Name: "init"}
+	running := &api.Container{Name: "web", KnownStatus: api.ContainerRunning}
+	stopped := &api.Container{Name: "sidecar", KnownStatus: api.ContainerStopped}
+	task := &api.Task{Arn: "task1", Containers: []*api.Container{pending, running, stopped}}
+	engine.state.AddTask(task)
+	engine.state.AddContainer(&api.DockerContainer{DockerId: "dockerid1", DockerName: "web", Container: running}, task)
+	engine.state.AddContainer(&api.DockerContainer{DockerId: "dockerid2", DockerName: "sidecar", Container: stopped}, task)
+	return engine, runner
+}
+
+func TestRunNetworkDiagnostic(t *testing.T) {
+	engine, runner := networkDiagnosticEngine()
+
+	result, err := engine.RunNetworkDiagnostic("task1", "", "ping", "10.0.0.1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Output != "output" || result.Error != "" {
+		t.Error("Unexpected result", result)
+	}
+	if runner.pid != 100 || !reflect.DeepEqual(runner.command, []string{"ping", "-c", "4", "-W", "2", "10.0.0.1"}) {
+		t.Error("Expected the diagnostic to run beside the running container", runner.pid, runner.command)
+	}
+
+	if _, err := engine.RunNetworkDiagnostic("task1", "sidecar", "sockets", "", ""); err != nil || runner.pid != 200 {
+		t.Error("Expected the diagnostic to run beside the named container", runner.pid, err)
+	}
+
+	if _, err := engine.RunNetworkDiagnostic("task1", "", "tcp", "db.internal.example.com", "5432"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(runner.command, []string{"nc", "-z", "-v", "-w", "5", "db.internal.example.com", "5432"}) {
+		t.Error("Unexpected command", runner.command)
+	}
+
+	runner.err = errors.New("exit status 1")
+	result, err = engine.RunNetworkDiagnostic("task1", "", "dns", "example.com", "")
+	if err != nil || result.Error != "exit status 1" || result.Output != "output" {
+		t.Error("Expected the command's failure in the result", result, err)
+	}
+}
+
+func TestRunNetworkDiagnosticInvalid(t *testing.T) {
+	engine, runner := networkDiagnosticEngine()
+
+	for _, diagnostic := range [][3]string{
+		{"ip", "", ""},
+		{"ping", "", ""},
+		{"ping", "-f", ""},
+		{"ping", "10.0.0.1 -f", ""},
+		{"dns", "@attacker", ""},
+		{"dns", "example.com", "53"},
+		{"tcp", "example.com", ""},
+		{"tcp", "example.com", "-e"},
+		{"tcp", "example.com", "70000"},
+		{"sockets", "10.0.0.1", ""},
+	} {
+		if _, err := engine.RunNetworkDiagnostic("task1", "", diagnostic[0], diagnostic[1], diagnostic[2]); err == nil {
+			t.Error("Expected the diagnostic to be refused", diagnostic)
+		}
+	}
+	if runner.command != nil {
+		t.Error("Expected no refused diagnostic to be run", runner.command)
+	}
+
+	if _, err := engine.RunNetworkDiagnostic("task2", "", "sockets", "", ""); err == nil {
+		t.Error("Expected an unknown task to be refused")
+	}
+	if _, err := engine.RunNetworkDiagnostic("task1", "init", "sockets", "", ""); err == nil {
+		t.Error("Expected a container without a docker container to be refused")
+	}
+	engine.client = &pidClient{}
+	if _, err := engine.RunNetworkDiagnostic("task1", "", "sockets", "", ""); err == nil {
+		t.Error("Expected a container without a process to be refused")
+	}
+}
+
+func TestNsenterRunnerMissingCommand(t *testing.T) {
+	_, err := nsenterRunner{}.Run(1, "no-such-diagnostic-command", nil, time.Second)
+	if err == nil {
+		t.Error("Expected a missing command to be reported")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numaNodePath is where the kernel describes the host's NUMA nodes
+const numaNodePath = "/sys/devices/system/node"
+
+// maxCPUs is the most cores the kernel supports, bounding the cores a NUMA
+// node's cpulist may name
+const maxCPUs = 8192
+
+// numaNodes returns the cores of each of the host's NUMA nodes, in order of
+// node, as described under path
+func numaNodes(path string) ([][]int, error) {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "node") {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "node"))
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var nodes [][]int
+	for _, id := range ids {
+		cpuList, err := ioutil.ReadFile(filepath.Join(path, "node"+strconv.Itoa(id), "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+		cpus, err := parseCPUSet(strings.TrimSpace(string(cpuList)), maxCPUs)
+		if err != nil {
+			return nil, err
+		}
+		// Nodes with memory but no cores can't run containers
+		if len(cpus) > 0 {
+			nodes = append(nodes, cpus)
+		}
+	}
+	return nodes, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+// PidsLimitError is returned when a container's pids limit is invalid or
+// could not be applied
+type PidsLimitError struct {
+	msg string
+}
+
+func (err PidsLimitError) Error() string     { return err.msg }
+func (err PidsLimitError) ErrorName() string { return "PidsLimitError" }
+func (err PidsLimitError) ErrorCode() string { return api.ErrorCodeResourceInitialization }
+
+// pidsLimit returns the container's pids limit, or 0 if it has none
+func pidsLimit(container *api.Container) int64 {
+	if container.LinuxParameters == nil || container.LinuxParameters.PidsLimit == nil {
+		return 0
+	}
+	return *container.LinuxParameters.PidsLimit
+}
+
+// validatePidsLimit checks that the container's pids limit, if it has one,
+// is positive, and that the agent can see the pids cgroups, under
+// cgroupRoot, to apply it
+func validatePidsLimit(container *api.Container, cgroupRoot string) api.NamedError {
+	if container.LinuxParameters == nil || container.LinuxParameters.PidsLimit == nil {
+		return nil
+	}
+	if *container.LinuxParameters.PidsLimit <= 0 {
+		return PidsLimitError{"Invalid pids limit for container " + container.Name + ": it must be positive"}
+	}
+	if err := checkCgroupController("pids", cgroupRoot); err != nil {
+		return PidsLimitError{"Unable to limit pids of container " + container.Name + ": " + err.Error()}
+	}
+	return nil
+}
+
+// limitPids applies the container's pids limit to its cgroup. The docker API
+// version the agent uses can't set it when the container is created, so it is
+// written once the container has started and its cgroup exists; until then,
+// the container runs without it. The container must be stopped if it can't be
+// written.
+func (engine *DockerTaskEngine) limitPids(task *api.Task, container *api.Container, dockerID string) api.NamedError {
+	limit := pidsLimit(container)
+	if limit == 0 {
+		return nil
+	}
+	dockerContainer, err := engine.client.InspectContainer(dockerID)
+	if err != nil {
+		return PidsLimitError{"Unable to find the cgroup of container " + container.Name + " for its pids limit: " + err.Error()}
+	}
+	log.Info("Limiting pids", "task", task.Arn, "container", container.Name, "limit", limit)
+	if err := writePidsLimit(limit, dockerContainer.State.Pid, procPath, cgroupPath); err != nil {
+		return PidsLimitError{"Unable to limit pids of container " + container.Name + ": " + err.Error()}
+	}
+	return nil
+}
+
+// writePidsLimit writes limit to the pids cgroup of the process pid
+func writePidsLimit(limit int64, pid int, proc, cgroupRoot string) error {
+	dir, err := processCgroupDir(pid, "pids", proc, cgroupRoot)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "pids.max"), []byte(strconv.FormatInt(limit, 10)), 0644)
+}
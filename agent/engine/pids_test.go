@@ -0,0 +1,98 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+func TestValidatePidsLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pids")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "pids"), 0755)
+
+	limit := int64(100)
+	if err := validatePidsLimit(&api.Container{LinuxParameters: &api.LinuxParameters{PidsLimit: &limit}}, dir); err != nil {
+		t.Error("Expected a positive limit to be valid", err)
+	}
+	if err := validatePidsLimit(&api.Container{}, filepath.Join(dir, "missing")); err != nil {
+		t.Error("Expected no limit to be valid", err)
+	}
+	if err := validatePidsLimit(&api.Container{LinuxParameters: &api.LinuxParameters{PidsLimit: &limit}}, filepath.Join(dir, "missing")); err == nil || err.ErrorName() != "PidsLimitError" {
+		t.Error("Expected a limit to be rejected when the pids cgroups can't be seen", err)
+	}
+	limit = 0
+	if err := validatePidsLimit(&api.Container{LinuxParameters: &api.LinuxParameters{PidsLimit: &limit}}, dir); err == nil || err.ErrorName() != "PidsLimitError" {
+		t.Error("Expected a zero limit to be rejected", err)
+	}
+}
+
+func TestCheckCgroupController(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := checkCgroupController("pids", dir); err == nil {
+		t.Error("Expected an error when the hierarchy can't be seen")
+	}
+	// Under cgroup v2 the controller is listed instead
+	ioutil.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte("cpu io memory pids\n"), 0644)
+	if err := checkCgroupController("pids", dir); err != nil {
+		t.Error("Expected a listed controller to be found", err)
+	}
+	if err := checkCgroupController("hugetlb", dir); err == nil {
+		t.Error("Expected an error for a controller which isn't listed")
+	}
+}
+
+func TestProcessCgroupDirHiddenProcess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	_, err = processCgroupDir(10, "pids", dir, "/sys/fs/cgroup")
+	if err == nil || !strings.Contains(err.Error(), "pid namespace") {
+		t.Error("Expected an error naming the pid namespace", err)
+	}
+}
+
+func TestWritePidsLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pids")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cgroup := filepath.Join(dir, "cgroup", "pids", "docker", "abc")
+	os.MkdirAll(cgroup, 0755)
+	os.MkdirAll(filepath.Join(dir, "proc", "10"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "proc", "10", "cgroup"), []byte("5:pids:/docker/abc\n4:memory:/docker/abc\n"), 0644)
+
+	if err := writePidsLimit(100, 10, filepath.Join(dir, "proc"), filepath.Join(dir, "cgroup")); err != nil {
+		t.Fatal(err)
+	}
+	if limit, _ := ioutil.ReadFile(filepath.Join(cgroup, "pids.max")); string(limit) != "100" {
+		t.Error("Unexpected limit", string(limit))
+	}
+}
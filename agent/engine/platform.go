@@ -0,0 +1,137 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"runtime"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerauth"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
+	"github.com/fsouza/go-dockerclient"
+	"golang.org/x/net/context"
+)
+
+// ImagePlatformError is returned when a container's image has no manifest for
+// the platform it is to run on, or the platform is invalid
+type ImagePlatformError struct {
+	msg string
+}
+
+func (err ImagePlatformError) Error() string     { return err.msg }
+func (err ImagePlatformError) ErrorName() string { return "ImagePlatformError" }
+func (err ImagePlatformError) ErrorCode() string { return api.ErrorCodeCannotPullContainer }
+
+// imagePlatform is the os, architecture, and optionally the variant, such as
+// "v8" of "linux/arm64/v8", an image is built for. It is read from manifest
+// lists and image configs.
+type imagePlatform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant"`
+}
+
+// hostPlatform is the platform of the host the agent runs on
+func hostPlatform() imagePlatform {
+	return imagePlatform{OS: runtime.GOOS, Architecture: runtime.GOARCH}
+}
+
+// parseImagePlatform parses a platform written as os/architecture[/variant]
+func parseImagePlatform(platform string) (imagePlatform, api.NamedError) {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return imagePlatform{}, ImagePlatformError{"Invalid platform '" + platform + "': it must be os/architecture[/variant]"}
+	}
+	parsed := imagePlatform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		parsed.Variant = parts[2]
+	}
+	return parsed, nil
+}
+
+func (platform imagePlatform) String() string {
+	if platform.Variant == "" {
+		return platform.OS + "/" + platform.Architecture
+	}
+	return platform.OS + "/" + platform.Architecture + "/" + platform.Variant
+}
+
+// matches returns whether an image built for other runs on platform. Any
+// variant matches if platform doesn't name one, and an image which doesn't
+// name its os or architecture is taken to run on any.
+func (platform imagePlatform) matches(other imagePlatform) bool {
+	return (other.OS == "" || platform.OS == other.OS) &&
+		(other.Architecture == "" || platform.Architecture == other.Architecture) &&
+		(platform.Variant == "" || platform.Variant == other.Variant)
+}
+
+// manifestResolver finds the manifest in an image's manifest list for a
+// platform
+type manifestResolver interface {
+	ResolvePlatform(ctx context.Context, image string, platform imagePlatform, auth docker.AuthConfiguration) (string, error)
+}
+
+// newManifestResolver returns a resolver reading manifests from registries,
+// or nil if platform selection isn't enabled
+func newManifestResolver(cfg *config.Config) manifestResolver {
+	if !cfg.ImagePlatformSelectionEnabled {
+		return nil
+	}
+	return newRegistryClient()
+}
+
+// resolveImagePlatform returns the image to pull for the container: the
+// manifest in its image's manifest list for the task's platform, or the host's
+// if the task doesn't name one, so that the daemon doesn't pick by its own
+// defaults. Images which aren't manifest lists are pulled as they are, as are
+// images the registry can't be asked about. Images named by digest are only
+// checked, since a container created from one must use the same digest. If
+// platform selection isn't enabled, the daemon picks, even for tasks which
+// name a platform.
+func (engine *DockerTaskEngine) resolveImagePlatform(ctx context.Context, task *api.Task, container *api.Container, span *tracing.Span) (string, api.NamedError) {
+	if engine.manifests == nil {
+		if task.Platform != "" {
+			log.Warn("Image platform selection is not enabled; pulling the daemon's default platform", "task", task.Arn, "container", container.Name, "platform", task.Platform)
+		}
+		return container.Image, nil
+	}
+	platform := hostPlatform()
+	if task.Platform != "" {
+		var err api.NamedError
+		if platform, err = parseImagePlatform(task.Platform); err != nil {
+			return "", err
+		}
+	}
+
+	resolveSpan := span.StartChild("resolve image platform")
+	digest, err := engine.manifests.ResolvePlatform(ctx, container.Image, platform, dockerauth.GetAuthconfig(container.Image))
+	if platformErr, ok := err.(ImagePlatformError); ok {
+		resolveSpan.End(platformErr)
+		return "", platformErr
+	}
+	resolveSpan.End(nil)
+	if err != nil {
+		log.Warn("Unable to read the image's manifest; pulling the daemon's default platform", "task", task.Arn, "container", container.Name, "image", container.Image, "err", err)
+		return container.Image, nil
+	}
+
+	ref := parseImageReference(container.Image)
+	if digest == "" || ref.byDigest {
+		return container.Image, nil
+	}
+	log.Debug("Pulling image for platform", "task", task.Arn, "container", container.Name, "platform", platform.String(), "digest", digest)
+	return ref.name + "@" + digest, nil
+}
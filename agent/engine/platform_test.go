@@ -0,0 +1,91 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/fsouza/go-dockerclient"
+	"golang.org/x/net/context"
+)
+
+// fakeManifestResolver answers with a fixed digest or error, recording the
+// platform it was asked for
+type fakeManifestResolver struct {
+	digest   string
+	err      error
+	platform imagePlatform
+}
+
+func (resolver *fakeManifestResolver) ResolvePlatform(ctx context.Context, image string, platform imagePlatform, auth docker.AuthConfiguration) (string, error) {
+	resolver.platform = platform
+	return resolver.digest, resolver.err
+}
+
+func TestParseImagePlatform(t *testing.T) {
+	if platform, err := parseImagePlatform("linux/arm/v7"); err != nil || platform != (imagePlatform{OS: "linux", Architecture: "arm", Variant: "v7"}) {
+		t.Error("Unexpected platform", platform, err)
+	}
+	if _, err := parseImagePlatform("arm64"); err == nil {
+		t.Error("Expected a platform without an os to be invalid")
+	}
+	if !(imagePlatform{OS: "linux", Architecture: "arm"}).matches(imagePlatform{OS: "linux", Architecture: "arm", Variant: "v6"}) {
+		t.Error("Expected any variant to match a platform without one")
+	}
+	if !(imagePlatform{OS: "linux", Architecture: "arm64"}).matches(imagePlatform{}) {
+		t.Error("Expected an image without an os or architecture to match any platform")
+	}
+	if (imagePlatform{OS: "linux", Architecture: "arm64"}).matches(imagePlatform{Architecture: "amd64"}) {
+		t.Error("Expected an image for another architecture not to match")
+	}
+}
+
+func TestResolveImagePlatform(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{ImagePlatformSelectionEnabled: true})
+	resolver := &fakeManifestResolver{digest: "sha256:abcd"}
+	engine.manifests = resolver
+	task := &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc", Platform: "linux/arm64"}
+
+	image, err := engine.resolveImagePlatform(context.Background(), task, &api.Container{Name: "app", Image: "example.com/app:v1"}, nil)
+	if err != nil || image != "example.com/app@sha256:abcd" {
+		t.Error("Expected the platform's manifest to be pulled", image, err)
+	}
+	if resolver.platform != (imagePlatform{OS: "linux", Architecture: "arm64"}) {
+		t.Error("Expected the task's platform to be resolved", resolver.platform)
+	}
+
+	image, err = engine.resolveImagePlatform(context.Background(), task, &api.Container{Name: "app", Image: "example.com/app@sha256:list"}, nil)
+	if err != nil || image != "example.com/app@sha256:list" {
+		t.Error("Expected an image named by digest to be pulled as it is", image, err)
+	}
+
+	resolver.err = errors.New("unreachable")
+	image, err = engine.resolveImagePlatform(context.Background(), task, &api.Container{Name: "app", Image: "example.com/app:v1"}, nil)
+	if err != nil || image != "example.com/app:v1" {
+		t.Error("Expected the daemon to pick when the registry can't be read", image, err)
+	}
+
+	resolver.err = ImagePlatformError{"no manifest"}
+	if _, err = engine.resolveImagePlatform(context.Background(), task, &api.Container{Name: "app", Image: "example.com/app:v1"}, nil); err == nil || err.ErrorName() != "ImagePlatformError" {
+		t.Error("Expected a missing platform to fail the pull", err)
+	}
+
+	task.Platform = "arm64"
+	if _, err = engine.resolveImagePlatform(context.Background(), task, &api.Container{Name: "app", Image: "example.com/app:v1"}, nil); err == nil {
+		t.Error("Expected an invalid platform to fail the pull")
+	}
+}
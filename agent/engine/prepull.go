@@ -0,0 +1,179 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"golang.org/x/net/context"
+)
+
+// The statuses of a pre-pulled image
+const (
+	PrePullPulling = "PULLING"
+	PrePullPulled  = "PULLED"
+	PrePullFailed  = "FAILED"
+)
+
+// MaxPrePulledImages bounds how many images may be kept pre-pulled. As they
+// aren't removed when tasks are cleaned up, or when the disk fills, many of
+// them could otherwise fill the disk.
+const MaxPrePulledImages = 20
+
+// ErrTooManyPrePulledImages is returned when pre-pulling an image would keep
+// more than MaxPrePulledImages
+var ErrTooManyPrePulledImages = errors.New("Too many images are pre-pulled; release some before pre-pulling more")
+
+// PrePulledImage describes an image pulled ahead of the tasks which use it
+type PrePulledImage struct {
+	Image    string
+	Status   string
+	Error    string `json:",omitempty"`
+	PulledAt time.Time
+}
+
+// prePulledImages tracks the images pulled ahead of the tasks using them, so
+// that they aren't removed when other tasks using them are cleaned up
+type prePulledImages struct {
+	lock   sync.RWMutex
+	images map[string]*PrePulledImage
+}
+
+func newPrePulledImages() *prePulledImages {
+	return &prePulledImages{images: make(map[string]*PrePulledImage)}
+}
+
+// start records that image is being pulled. It returns false if it is
+// already pulling or pulled, and an error if MaxPrePulledImages are.
+func (prePulled *prePulledImages) start(image string) (bool, error) {
+	prePulled.lock.Lock()
+	defer prePulled.lock.Unlock()
+	existing, ok := prePulled.images[image]
+	if ok && existing.Status != PrePullFailed {
+		return false, nil
+	}
+	if !ok && len(prePulled.images) >= MaxPrePulledImages {
+		return false, ErrTooManyPrePulledImages
+	}
+	prePulled.images[image] = &PrePulledImage{Image: image, Status: PrePullPulling}
+	return true, nil
+}
+
+// finish records the result of pulling image, unless it was released while
+// it was being pulled
+func (prePulled *prePulledImages) finish(image string, err error) {
+	prePulled.lock.Lock()
+	defer prePulled.lock.Unlock()
+	existing, ok := prePulled.images[image]
+	if !ok {
+		return
+	}
+	if err != nil {
+		existing.Status, existing.Error = PrePullFailed, err.Error()
+		return
+	}
+	existing.Status, existing.PulledAt = PrePullPulled, ttime.Now()
+}
+
+func (prePulled *prePulledImages) has(image string) bool {
+	prePulled.lock.RLock()
+	defer prePulled.lock.RUnlock()
+	_, ok := prePulled.images[image]
+	return ok
+}
+
+func (prePulled *prePulledImages) release(image string) bool {
+	prePulled.lock.Lock()
+	defer prePulled.lock.Unlock()
+	_, ok := prePulled.images[image]
+	delete(prePulled.images, image)
+	return ok
+}
+
+func (prePulled *prePulledImages) list() []PrePulledImage {
+	prePulled.lock.RLock()
+	defer prePulled.lock.RUnlock()
+	images := make([]PrePulledImage, 0, len(prePulled.images))
+	for _, image := range prePulled.images {
+		images = append(images, *image)
+	}
+	sort.Sort(prePulledImagesByName(images))
+	return images
+}
+
+type prePulledImagesByName []PrePulledImage
+
+func (images prePulledImagesByName) Len() int           { return len(images) }
+func (images prePulledImagesByName) Less(i, j int) bool { return images[i].Image < images[j].Image }
+func (images prePulledImagesByName) Swap(i, j int)      { images[i], images[j] = images[j], images[i] }
+
+// PrePullImages pulls each of images in the background, such as when an
+// instance scales out, so that tasks using them start without waiting. They
+// share the pull slots of tasks' pulls. Images which are already pulled or
+// being pulled are skipped, and failed pulls are retried. Once
+// MaxPrePulledImages are kept, the rest are not pulled and
+// ErrTooManyPrePulledImages is returned.
+func (engine *DockerTaskEngine) PrePullImages(images []string) error {
+	for _, image := range images {
+		if image == "" {
+			continue
+		}
+		started, err := engine.prePulled.start(image)
+		if err != nil {
+			return err
+		}
+		if started {
+			go engine.prePullImage(image)
+		}
+	}
+	return nil
+}
+
+func (engine *DockerTaskEngine) prePullImage(image string) {
+	container := &api.Container{Name: "prepull", Image: image}
+	engine.pullSemaphore.Wait()
+	pullImage, err := engine.resolveImagePlatform(context.Background(), &api.Task{}, container, nil)
+	if err != nil {
+		engine.pullSemaphore.Post()
+		log.Warn("Unable to pre-pull image", "image", image, "err", err)
+		engine.prePulled.finish(image, err)
+		return
+	}
+	log.Info("Pre-pulling image", "image", image)
+	metadata := engine.pullImage(context.Background(), pullImage, image)
+	engine.pullSemaphore.Post()
+	if metadata.Error != nil {
+		log.Warn("Unable to pre-pull image", "image", image, "err", metadata.Error)
+		engine.prePulled.finish(image, metadata.Error)
+		return
+	}
+	engine.prePulled.finish(image, nil)
+}
+
+// PrePulledImages lists the images pulled ahead of tasks, by name
+func (engine *DockerTaskEngine) PrePulledImages() []PrePulledImage {
+	return engine.prePulled.list()
+}
+
+// ReleasePrePulledImage stops keeping a pre-pulled image, so that it may be
+// removed when the last task using it is cleaned up. It returns false if the
+// image wasn't pre-pulled.
+func (engine *DockerTaskEngine) ReleasePrePulledImage(image string) bool {
+	return engine.prePulled.release(image)
+}
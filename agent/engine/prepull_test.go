@@ -0,0 +1,122 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"golang.org/x/net/context"
+)
+
+// imagePullingClient pulls images instantly, failing to pull "missing"
+type imagePullingClient struct {
+	DockerClient
+	lock    sync.Mutex
+	pulled  []string
+	removed []string
+}
+
+func (client *imagePullingClient) PullImage(ctx context.Context, image string) DockerContainerMetadata {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	client.pulled = append(client.pulled, image)
+	if image == "missing" {
+		return DockerContainerMetadata{Error: CannotXContainerError{"Pull", "not found"}}
+	}
+	return DockerContainerMetadata{}
+}
+
+func (client *imagePullingClient) RemoveImage(image string) error {
+	client.removed = append(client.removed, image)
+	return nil
+}
+
+// waitForPrePulls waits until none of the engine's pre-pulled images are
+// still pulling
+func waitForPrePulls(t *testing.T, engine *DockerTaskEngine) []PrePulledImage {
+	for i := 0; i < 100; i++ {
+		images := engine.PrePulledImages()
+		pulling := false
+		for _, image := range images {
+			pulling = pulling || image.Status == PrePullPulling
+		}
+		if !pulling {
+			return images
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for pre-pulls")
+	return nil
+}
+
+func TestPrePullImages(t *testing.T) {
+	client := &imagePullingClient{}
+	engine := NewDockerTaskEngine(&config.Config{})
+	engine.client = client
+
+	engine.PrePullImages([]string{"busybox", "missing"})
+	images := waitForPrePulls(t, engine)
+	if len(images) != 2 || images[0].Image != "busybox" || images[0].Status != PrePullPulled || images[1].Status != PrePullFailed || images[1].Error == "" {
+		t.Error("Unexpected pre-pulled images", images)
+	}
+
+	// Pulled images are skipped, and failed ones retried
+	engine.PrePullImages([]string{"busybox", "missing"})
+	waitForPrePulls(t, engine)
+	if len(client.pulled) != 3 || client.pulled[2] != "missing" {
+		t.Error("Expected only the failed image to be pulled again", client.pulled)
+	}
+
+	// Pre-pulled images are kept when tasks using them are cleaned up
+	task := &api.Task{Arn: "stopped", Containers: []*api.Container{{Name: "c1", Image: "busybox"}, {Name: "c2", Image: "unused"}}}
+	engine.state.AddTask(task)
+	engine.removeTaskImages(task)
+	if expected := []string{"unused"}; !reflect.DeepEqual(client.removed, expected) {
+		t.Errorf("Expected %v to be removed, got %v", expected, client.removed)
+	}
+
+	if !engine.ReleasePrePulledImage("busybox") || engine.ReleasePrePulledImage("busybox") {
+		t.Error("Expected the image to be released once")
+	}
+	engine.removeTaskImages(task)
+	if expected := []string{"unused", "busybox", "unused"}; !reflect.DeepEqual(client.removed, expected) {
+		t.Errorf("Expected %v to be removed, got %v", expected, client.removed)
+	}
+}
+
+func TestPrePullImagesBounded(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{})
+	engine.client = &imagePullingClient{}
+
+	images := make([]string, MaxPrePulledImages+1)
+	for i := range images {
+		images[i] = "image" + strconv.Itoa(i)
+	}
+	if err := engine.PrePullImages(images); err != ErrTooManyPrePulledImages {
+		t.Error("Expected too many images to be refused", err)
+	}
+	if pulled := waitForPrePulls(t, engine); len(pulled) != MaxPrePulledImages {
+		t.Error("Expected only the first images to be pre-pulled", len(pulled))
+	}
+	// Images which are already pre-pulled are still fine
+	if err := engine.PrePullImages(images[:1]); err != nil {
+		t.Error("Expected a pre-pulled image to be skipped", err)
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+// PreStartHook decides whether a container may be started, such as by
+// scanning its image. Check returns whether the container is allowed and, if
+// not, why. An error means the hook could not decide.
+type PreStartHook interface {
+	Name() string
+	Check(request *PreStartRequest, timeout time.Duration) (bool, string, error)
+}
+
+// PreStartRequest describes the container about to be started. It is sent to
+// pre-start hooks as json.
+type PreStartRequest struct {
+	Cluster       string `json:"cluster"`
+	TaskArn       string `json:"taskArn"`
+	Family        string `json:"family"`
+	Version       string `json:"version"`
+	ContainerName string `json:"containerName"`
+	Image         string `json:"image"`
+	// ImageID is the digest of the pulled image the container was created
+	// from, such as "sha256:..."
+	ImageID string `json:"imageId"`
+}
+
+// preStartResponse is the json a pre-start hook answers with
+type preStartResponse struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason"`
+}
+
+// ImagePolicyError is returned when a pre-start hook denies a container, or
+// fails to answer and the agent is not configured to fail open
+type ImagePolicyError struct {
+	msg string
+}
+
+func (err ImagePolicyError) Error() string     { return err.msg }
+func (err ImagePolicyError) ErrorName() string { return "ImagePolicyError" }
+func (err ImagePolicyError) ErrorCode() string { return api.ErrorCodeCannotStartContainer }
+
+// newPreStartHooks returns the hooks configured in cfg
+func newPreStartHooks(cfg *config.Config) []PreStartHook {
+	hooks := []PreStartHook{}
+	if cfg.PreStartHookCommand != "" {
+		hooks = append(hooks, &execPreStartHook{path: cfg.PreStartHookCommand})
+	}
+	if cfg.PreStartHookURL != "" {
+		hooks = append(hooks, &webhookPreStartHook{url: cfg.PreStartHookURL})
+	}
+	return hooks
+}
+
+// checkPreStartHooks asks each pre-start hook whether the container may start
+func (engine *DockerTaskEngine) checkPreStartHooks(task *api.Task, container *api.Container, dockerID string, span *tracing.Span) api.NamedError {
+	if len(engine.preStartHooks) == 0 {
+		return nil
+	}
+	hookSpan := span.StartChild("pre-start hooks")
+	err := engine.runPreStartHooks(task, container, dockerID)
+	if err != nil {
+		hookSpan.End(err)
+		return err
+	}
+	hookSpan.End(nil)
+	return nil
+}
+
+func (engine *DockerTaskEngine) runPreStartHooks(task *api.Task, container *api.Container, dockerID string) api.NamedError {
+	request := &PreStartRequest{
+		Cluster:       engine.cfg.Cluster,
+		TaskArn:       task.Arn,
+		Family:        task.Family,
+		Version:       task.Version,
+		ContainerName: container.Name,
+		Image:         container.Image,
+	}
+	dockerContainer, err := engine.client.InspectContainer(dockerID)
+	if err != nil {
+		if !engine.cfg.PreStartHookFailOpen {
+			return ImagePolicyError{"Unable to find the image of the container for pre-start hooks: " + err.Error()}
+		}
+		log.Warn("Unable to find the image of the container for pre-start hooks", "task", task.Arn, "container", container.Name, "err", err)
+	} else {
+		request.ImageID = dockerContainer.Image
+	}
+
+	for _, hook := range engine.preStartHooks {
+		allow, reason, err := hook.Check(request, engine.cfg.PreStartHookTimeout)
+		if err != nil {
+			if !engine.cfg.PreStartHookFailOpen {
+				return ImagePolicyError{"Pre-start hook " + hook.Name() + " failed: " + err.Error()}
+			}
+			log.Warn("Pre-start hook failed; starting container anyway", "hook", hook.Name(), "task", task.Arn, "container", container.Name, "err", err)
+			continue
+		}
+		if !allow {
+			log.Info("Pre-start hook denied container", "hook", hook.Name(), "task", task.Arn, "container", container.Name, "reason", reason)
+			return ImagePolicyError{"Pre-start hook " + hook.Name() + " denied container: " + reason}
+		}
+	}
+	return nil
+}
+
+// execPreStartHook runs an executable on the host with the request on stdin.
+// It must exit 0 and write its response to stdout.
+type execPreStartHook struct {
+	path string
+}
+
+func (hook *execPreStartHook) Name() string { return hook.path }
+
+func (hook *execPreStartHook) Check(request *PreStartRequest, timeout time.Duration) (bool, string, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return false, "", err
+	}
+	out, err := runCommand(hook.path, nil, body, timeout)
+	if err != nil {
+		return false, "", err
+	}
+	return decodePreStartResponse(out)
+}
+
+// runCommand runs the executable at path with args and body on stdin,
+// killing it and any processes it started if it takes longer than timeout,
+// and returns what it wrote to stdout. It must exit 0.
+func runCommand(path string, args []string, body []byte, timeout time.Duration) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Its own process group lets the processes it started be killed with it;
+	// they would otherwise keep its output open, and Wait with it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ttime.After(timeout):
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return nil, errors.New("timed out after " + timeout.String())
+	}
+	if err != nil {
+		return nil, errors.New(err.Error() + ": " + strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// webhookPreStartHook posts the request to a url, such as a scanning service
+// or a Lambda function url, which must answer 200 with its response
+type webhookPreStartHook struct {
+	url string
+}
+
+func (hook *webhookPreStartHook) Name() string { return hook.url }
+
+func (hook *webhookPreStartHook) Check(request *PreStartRequest, timeout time.Duration) (bool, string, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return false, "", err
+	}
+	out, err := postHook(hook.url, body, timeout)
+	if err != nil {
+		return false, "", err
+	}
+	return decodePreStartResponse(out)
+}
+
+// hookTransport is shared by the webhook hooks, so that their connections
+// are reused
+var hookTransport = &http.Transport{Proxy: http.ProxyFromEnvironment}
+
+var hookClient = &http.Client{Transport: hookTransport}
+
+// postHook posts body as json to url, which must answer 200 within timeout,
+// and returns the response body
+func postHook(url string, body []byte, timeout time.Duration) ([]byte, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// The request, including reading its response, is cancelled at timeout
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ttime.After(timeout):
+			hookTransport.CancelRequest(req)
+		case <-done:
+		}
+	}()
+
+	resp, err := hookClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status " + strconv.Itoa(resp.StatusCode))
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodePreStartResponse(data []byte) (bool, string, error) {
+	var response preStartResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return false, "", errors.New("invalid response: " + err.Error())
+	}
+	return response.Allow, response.Reason, nil
+}
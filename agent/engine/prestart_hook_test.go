@@ -0,0 +1,165 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// inspectingClient answers InspectContainer with a fixed image
+type inspectingClient struct {
+	DockerClient
+	err error
+}
+
+func (client *inspectingClient) InspectContainer(id string) (*docker.Container, error) {
+	if client.err != nil {
+		return nil, client.err
+	}
+	return &docker.Container{ID: id, Image: "sha256:1234"}, nil
+}
+
+// fakePreStartHook records the requests it is asked about
+type fakePreStartHook struct {
+	allow    bool
+	err      error
+	requests []*PreStartRequest
+}
+
+func (hook *fakePreStartHook) Name() string { return "fake" }
+
+func (hook *fakePreStartHook) Check(request *PreStartRequest, timeout time.Duration) (bool, string, error) {
+	hook.requests = append(hook.requests, request)
+	return hook.allow, "policy", hook.err
+}
+
+func preStartHookTask() (*api.Task, *api.Container) {
+	container := &api.Container{Name: "web", Image: "nginx:latest"}
+	return &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc", Family: "web", Version: "3", Containers: []*api.Container{container}}, container
+}
+
+func TestPreStartHooks(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{Cluster: "default"})
+	engine.client = &inspectingClient{}
+	hook := &fakePreStartHook{allow: true}
+	engine.preStartHooks = []PreStartHook{hook}
+	task, container := preStartHookTask()
+
+	if err := engine.checkPreStartHooks(task, container, "dockerid", nil); err != nil {
+		t.Fatal("Expected container to be allowed", err)
+	}
+	expected := PreStartRequest{Cluster: "default", TaskArn: task.Arn, Family: "web", Version: "3", ContainerName: "web", Image: "nginx:latest", ImageID: "sha256:1234"}
+	if len(hook.requests) != 1 || *hook.requests[0] != expected {
+		t.Error("Unexpected pre-start request", hook.requests)
+	}
+
+	hook.allow = false
+	if _, ok := engine.checkPreStartHooks(task, container, "dockerid", nil).(ImagePolicyError); !ok {
+		t.Error("Expected a denied container to be an ImagePolicyError")
+	}
+}
+
+func TestPreStartHookFailurePolicy(t *testing.T) {
+	cfg := &config.Config{}
+	engine := NewDockerTaskEngine(cfg)
+	engine.client = &inspectingClient{err: errors.New("no such container")}
+	engine.preStartHooks = []PreStartHook{&fakePreStartHook{err: errors.New("scanner down")}}
+	task, container := preStartHookTask()
+
+	if err := engine.checkPreStartHooks(task, container, "dockerid", nil); err == nil {
+		t.Error("Expected a failing hook to deny the container by default")
+	}
+
+	cfg.PreStartHookFailOpen = true
+	if err := engine.checkPreStartHooks(task, container, "dockerid", nil); err != nil {
+		t.Error("Expected a failing hook to allow the container when failing open", err)
+	}
+}
+
+func TestWebhookPreStartHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request PreStartRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		json.NewEncoder(w).Encode(preStartResponse{Allow: request.Image != "bad", Reason: "vulnerable"})
+	}))
+	defer server.Close()
+	hook := &webhookPreStartHook{url: server.URL}
+
+	if allow, _, err := hook.Check(&PreStartRequest{Image: "good"}, time.Second); !allow || err != nil {
+		t.Error("Expected image to be allowed", err)
+	}
+	if allow, reason, err := hook.Check(&PreStartRequest{Image: "bad"}, time.Second); allow || reason != "vulnerable" || err != nil {
+		t.Error("Expected image to be denied", reason, err)
+	}
+}
+
+func TestWebhookPreStartHookTimeout(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+	hook := &webhookPreStartHook{url: server.URL}
+
+	start := time.Now()
+	if _, _, err := hook.Check(&PreStartRequest{}, 100*time.Millisecond); err == nil {
+		t.Error("Expected a hook which doesn't answer to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Error("Expected the request to be cancelled at its timeout, took", elapsed)
+	}
+}
+
+func TestExecPreStartHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prestart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	script := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	allow := &execPreStartHook{path: script("allow", `grep -q '"imageId":"sha256:1234"' && echo '{"allow":true}'`)}
+	if ok, _, err := allow.Check(&PreStartRequest{ImageID: "sha256:1234"}, 5*time.Second); !ok || err != nil {
+		t.Error("Expected image to be allowed", err)
+	}
+
+	fail := &execPreStartHook{path: script("fail", "echo broken >&2; exit 1")}
+	if _, _, err := fail.Check(&PreStartRequest{}, 5*time.Second); err == nil {
+		t.Error("Expected a failing hook to return an error")
+	}
+
+	slow := &execPreStartHook{path: script("slow", "exec sleep 10")}
+	if _, _, err := slow.Check(&PreStartRequest{}, 100*time.Millisecond); err == nil {
+		t.Error("Expected a slow hook to time out")
+	}
+}
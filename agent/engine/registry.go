@@ -0,0 +1,317 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
+	"github.com/fsouza/go-dockerclient"
+	"golang.org/x/net/context"
+)
+
+const (
+	// dockerHubRegistry is where images without a registry are pulled from
+	dockerHubRegistry = "registry-1.docker.io"
+
+	registryTimeout = 30 * time.Second
+	// maxManifestSize bounds how much of a manifest or image config is read
+	maxManifestSize = 4 * 1024 * 1024
+)
+
+// The media types of manifests a registry may answer with
+const (
+	mediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeImageIndex   = "application/vnd.oci.image.index.v1+json"
+	mediaTypeManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeOCIManifest  = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeManifestV1   = "application/vnd.docker.distribution.manifest.v1+prettyjws"
+)
+
+var manifestMediaTypes = []string{mediaTypeManifestList, mediaTypeImageIndex, mediaTypeManifest, mediaTypeOCIManifest, mediaTypeManifestV1}
+
+// imageReference is an image split into the registry it is pulled from, its
+// repository there, and the tag or digest pulled. name is the image as
+// written, without its tag or digest.
+type imageReference struct {
+	name       string
+	registry   string
+	repository string
+	reference  string
+	byDigest   bool
+}
+
+func parseImageReference(image string) imageReference {
+	ref := imageReference{name: image, reference: dockerDefaultTag}
+	if at := strings.Index(image, "@"); at != -1 {
+		ref.name, ref.reference, ref.byDigest = image[:at], image[at+1:], true
+	} else if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		ref.name, ref.reference = image[:colon], image[colon+1:]
+	}
+
+	ref.registry, ref.repository = dockerHubRegistry, ref.name
+	if slash := strings.Index(ref.name, "/"); slash != -1 {
+		if host := ref.name[:slash]; strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.registry, ref.repository = host, ref.name[slash+1:]
+		}
+	}
+	if ref.registry == "docker.io" || ref.registry == "index.docker.io" {
+		ref.registry = dockerHubRegistry
+	}
+	if ref.registry == dockerHubRegistry && !strings.Contains(ref.repository, "/") {
+		ref.repository = "library/" + ref.repository
+	}
+	return ref
+}
+
+// manifest holds the fields of each kind of manifest needed to find which
+// platforms an image is for: a list's manifests, a v2 manifest's config, or
+// a v1 manifest's architecture
+type manifest struct {
+	Manifests []struct {
+		Digest   string        `json:"digest"`
+		Platform imagePlatform `json:"platform"`
+	} `json:"manifests"`
+	Config struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Architecture string `json:"architecture"`
+}
+
+// registryClient reads manifests from docker registries, authenticating with
+// the credentials the image would be pulled with
+type registryClient struct {
+	client *http.Client
+	scheme string
+}
+
+func newRegistryClient() *registryClient {
+	return &registryClient{client: httpclient.New(registryTimeout, false), scheme: "https"}
+}
+
+// ResolvePlatform returns the digest of the manifest for platform in the
+// image's manifest list, or "" if the image isn't a manifest list but is for
+// platform. It returns an ImagePlatformError if the image has no manifest for
+// platform. Its requests are cancelled if ctx is.
+func (registry *registryClient) ResolvePlatform(ctx context.Context, image string, platform imagePlatform, auth docker.AuthConfiguration) (string, error) {
+	ref := parseImageReference(image)
+	body, err := registry.get(ctx, ref, "manifests/"+ref.reference, manifestMediaTypes, auth)
+	if err != nil {
+		return "", err
+	}
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return "", errors.New("invalid manifest: " + err.Error())
+	}
+
+	switch {
+	case len(m.Manifests) > 0:
+		available := make([]string, 0, len(m.Manifests))
+		for _, listed := range m.Manifests {
+			if platform.matches(listed.Platform) {
+				return listed.Digest, nil
+			}
+			available = append(available, listed.Platform.String())
+		}
+		return "", ImagePlatformError{"Image " + image + " has no manifest for platform " + platform.String() + "; it is available for " + strings.Join(available, ", ")}
+	case m.Config.Digest != "":
+		body, err := registry.get(ctx, ref, "blobs/"+m.Config.Digest, nil, auth)
+		if err != nil {
+			return "", err
+		}
+		var config imagePlatform
+		if err := json.Unmarshal(body, &config); err != nil {
+			return "", errors.New("invalid image config: " + err.Error())
+		}
+		if !platform.matches(config) {
+			return "", ImagePlatformError{"Image " + image + " is for platform " + config.String() + ", not " + platform.String()}
+		}
+	case m.Architecture != "":
+		if v1 := (imagePlatform{OS: "linux", Architecture: m.Architecture}); !platform.matches(v1) {
+			return "", ImagePlatformError{"Image " + image + " is for platform " + v1.String() + ", not " + platform.String()}
+		}
+	}
+	return "", nil
+}
+
+// get reads path under the image's repository in its registry. If the
+// registry asks for credentials, the request is authorized and retried.
+func (registry *registryClient) get(ctx context.Context, ref imageReference, path string, accept []string, auth docker.AuthConfiguration) ([]byte, error) {
+	target := registry.scheme + "://" + ref.registry + "/v2/" + ref.repository + "/" + path
+	resp, err := registry.do(ctx, target, accept, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		authorization, err := registry.authorize(ctx, challenge, auth)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = registry.do(ctx, target, accept, authorization); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("unexpected status " + strconv.Itoa(resp.StatusCode) + " from " + target)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+}
+
+func (registry *registryClient) do(ctx context.Context, target string, accept []string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, mediaType := range accept {
+		req.Header.Add("Accept", mediaType)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return registry.send(ctx, req)
+}
+
+// requestCanceler is implemented by transports which can cancel a request
+// in flight
+type requestCanceler interface {
+	CancelRequest(*http.Request)
+}
+
+// send sends req, cancelling it if ctx is cancelled before its response
+// arrives
+func (registry *registryClient) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	transport := registry.client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if canceler, ok := transport.(requestCanceler); ok {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				canceler.CancelRequest(req)
+			case <-done:
+			}
+		}()
+	}
+	resp, err := registry.client.Do(req)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return resp, err
+}
+
+// authorize answers a registry's authentication challenge, either with the
+// credentials themselves or with a token requested using them
+func (registry *registryClient) authorize(ctx context.Context, challenge string, auth docker.AuthConfiguration) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if auth.Username == "" {
+			return "", errors.New("registry requires credentials")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password)), nil
+	case "bearer":
+		realm, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return "", errors.New("invalid token realm '" + params["realm"] + "'")
+		}
+		query := realm.Query()
+		for _, key := range []string{"service", "scope"} {
+			if params[key] != "" {
+				query.Set(key, params[key])
+			}
+		}
+		realm.RawQuery = query.Encode()
+		req, err := http.NewRequest("GET", realm.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		if auth.Username != "" {
+			req.SetBasicAuth(auth.Username, auth.Password)
+		}
+		resp, err := registry.send(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", errors.New("unexpected status " + strconv.Itoa(resp.StatusCode) + " requesting a registry token")
+		}
+		var token struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return "", errors.New("invalid registry token: " + err.Error())
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+	}
+	return "", errors.New("unsupported registry authentication '" + scheme + "'")
+}
+
+// parseChallenge splits a WWW-Authenticate header, such as
+//
+//	Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/busybox:pull"
+//
+// into its scheme and parameters. Quoted values may contain commas.
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+	challenge = strings.TrimSpace(challenge)
+	space := strings.Index(challenge, " ")
+	if space == -1 {
+		return challenge, params
+	}
+	scheme, rest := challenge[:space], challenge[space+1:]
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		equals := strings.Index(rest, "=")
+		if equals == -1 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:equals]))
+		rest = rest[equals+1:]
+		var value string
+		if strings.HasPrefix(rest, "\"") {
+			end := strings.Index(rest[1:], "\"")
+			if end == -1 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma != -1 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+	}
+	return scheme, params
+}
@@ -0,0 +1,133 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"golang.org/x/net/context"
+)
+
+func TestParseImageReference(t *testing.T) {
+	for image, expected := range map[string]imageReference{
+		"busybox":                          {name: "busybox", registry: dockerHubRegistry, repository: "library/busybox", reference: "latest"},
+		"amazon/amazon-ecs-agent:v1":       {name: "amazon/amazon-ecs-agent", registry: dockerHubRegistry, repository: "amazon/amazon-ecs-agent", reference: "v1"},
+		"localhost:5000/app":               {name: "localhost:5000/app", registry: "localhost:5000", repository: "app", reference: "latest"},
+		"example.com/team/app@sha256:abcd": {name: "example.com/team/app", registry: "example.com", repository: "team/app", reference: "sha256:abcd", byDigest: true},
+		"docker.io/library/busybox:musl":   {name: "docker.io/library/busybox", registry: dockerHubRegistry, repository: "library/busybox", reference: "musl"},
+	} {
+		if ref := parseImageReference(image); ref != expected {
+			t.Error("Unexpected reference for", image, ref)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/busybox:pull,push"`)
+	if scheme != "Bearer" || params["realm"] != "https://auth.docker.io/token" || params["service"] != "registry.docker.io" || params["scope"] != "repository:library/busybox:pull,push" {
+		t.Error("Unexpected challenge", scheme, params)
+	}
+	if scheme, _ := parseChallenge(`Basic realm="registry"`); scheme != "Basic" {
+		t.Error("Unexpected scheme", scheme)
+	}
+}
+
+// fakeRegistry serves manifests and blobs from a map of paths, requiring a
+// token from its own token endpoint
+func fakeRegistry(t *testing.T, contents map[string]string) (*httptest.Server, *registryClient) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token":"abc"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="fake",scope="repository:app:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := contents[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	return server, &registryClient{client: http.DefaultClient, scheme: "http"}
+}
+
+func TestResolvePlatform(t *testing.T) {
+	server, registry := fakeRegistry(t, map[string]string{
+		"/v2/app/manifests/multi": `{"manifests":[
+			{"digest":"sha256:amd64","platform":{"os":"linux","architecture":"amd64"}},
+			{"digest":"sha256:armv7","platform":{"os":"linux","architecture":"arm","variant":"v7"}}]}`,
+		"/v2/app/manifests/single":    `{"config":{"digest":"sha256:config"}}`,
+		"/v2/app/blobs/sha256:config": `{"os":"linux","architecture":"arm64"}`,
+	})
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	auth := docker.AuthConfiguration{Username: "user", Password: "pass"}
+
+	digest, err := registry.ResolvePlatform(context.Background(), host+"/app:multi", imagePlatform{OS: "linux", Architecture: "arm", Variant: "v7"}, auth)
+	if err != nil || digest != "sha256:armv7" {
+		t.Error("Expected the arm manifest", digest, err)
+	}
+	_, err = registry.ResolvePlatform(context.Background(), host+"/app:multi", imagePlatform{OS: "linux", Architecture: "arm64"}, auth)
+	if _, ok := err.(ImagePlatformError); !ok {
+		t.Error("Expected no manifest for arm64", err)
+	}
+
+	digest, err = registry.ResolvePlatform(context.Background(), host+"/app:single", imagePlatform{OS: "linux", Architecture: "arm64"}, auth)
+	if err != nil || digest != "" {
+		t.Error("Expected a single manifest for the platform to be pulled as it is", digest, err)
+	}
+	_, err = registry.ResolvePlatform(context.Background(), host+"/app:single", imagePlatform{OS: "linux", Architecture: "amd64"}, auth)
+	if _, ok := err.(ImagePlatformError); !ok {
+		t.Error("Expected a single manifest for another platform to be rejected", err)
+	}
+
+	_, err = registry.ResolvePlatform(context.Background(), host+"/app:multi", imagePlatform{OS: "linux", Architecture: "amd64"}, docker.AuthConfiguration{})
+	if _, ok := err.(ImagePlatformError); err == nil || ok {
+		t.Error("Expected an error authenticating without credentials", err)
+	}
+}
+
+func TestResolvePlatformCancelled(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+	registry := &registryClient{client: http.DefaultClient, scheme: "http"}
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := registry.ResolvePlatform(ctx, host+"/app:v1", hostPlatform(), docker.AuthConfiguration{}); err != context.Canceled {
+		t.Error("Expected the request to be cancelled", err)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+)
+
+const (
+	tmpfsFilesystemType = "tmpfs"
+	// scratchMountDir is the directory under a task's mount directory holding
+	// its scratch mounts. Volume names may not contain '.', so it cannot
+	// collide with an EFS volume's mount point.
+	scratchMountDir = ".scratch"
+)
+
+// ScratchMountError is returned when a container's tmpfs scratch mount could
+// not be mounted on the host
+type ScratchMountError struct {
+	msg string
+}
+
+func (err ScratchMountError) Error() string     { return err.msg }
+func (err ScratchMountError) ErrorName() string { return "ScratchMountError" }
+func (err ScratchMountError) ErrorCode() string { return api.ErrorCodeResourceInitialization }
+
+// mountScratchVolumes mounts a tmpfs on the host for each of the container's
+// scratch mounts which is not yet mounted. They are bound into the container
+// by its host config.
+func (engine *DockerTaskEngine) mountScratchVolumes(task *api.Task, container *api.Container) api.NamedError {
+	if container.LinuxParameters == nil {
+		return nil
+	}
+	for i := range container.LinuxParameters.Tmpfs {
+		tmpfs := &container.LinuxParameters.Tmpfs[i]
+		if !filepath.IsAbs(tmpfs.ContainerPath) || tmpfs.Size <= 0 {
+			return ScratchMountError{"Invalid scratch mount at '" + tmpfs.ContainerPath + "': it must have an absolute path and a positive size"}
+		}
+		if err := engine.mountScratchVolume(task, container, i, tmpfs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (engine *DockerTaskEngine) mountScratchVolume(task *api.Task, container *api.Container, i int, tmpfs *api.Tmpfs) api.NamedError {
+	target := engine.scratchMountPoint(task, container, i)
+	engine.mountLocks.Lock(target)
+	defer engine.mountLocks.Unlock(target)
+	if tmpfs.HostPath != "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return ScratchMountError{"Unable to create scratch mount point for " + tmpfs.ContainerPath + ": " + err.Error()}
+	}
+	options := []string{"size=" + strconv.FormatInt(tmpfs.Size, 10) + "m", "nosuid", "nodev"}
+	log.Info("Mounting scratch volume", "task", task.Arn, "container", container.Name, "path", tmpfs.ContainerPath, "target", target)
+	if err := engine.mounter.Mount(tmpfsFilesystemType, target, tmpfsFilesystemType, options); err != nil {
+		return ScratchMountError{"Unable to mount scratch volume for " + tmpfs.ContainerPath + ": " + err.Error()}
+	}
+	tmpfs.HostPath = target
+	return nil
+}
+
+// unmountScratchVolumes unmounts all of the task's mounted scratch volumes
+func (engine *DockerTaskEngine) unmountScratchVolumes(task *api.Task) {
+	for _, container := range task.Containers {
+		if container.LinuxParameters == nil {
+			continue
+		}
+		for i := range container.LinuxParameters.Tmpfs {
+			engine.unmountScratchVolume(task, container, i, &container.LinuxParameters.Tmpfs[i])
+		}
+	}
+}
+
+func (engine *DockerTaskEngine) unmountScratchVolume(task *api.Task, container *api.Container, i int, tmpfs *api.Tmpfs) {
+	target := engine.scratchMountPoint(task, container, i)
+	engine.mountLocks.Lock(target)
+	defer engine.mountLocks.Unlock(target)
+	if tmpfs.HostPath == "" {
+		return
+	}
+
+	backoff := utils.NewSimpleBackoff(time.Second, 30*time.Second, 0.2, 2)
+	err := utils.RetryNWithBackoff(backoff, efsUnmountAttempts, func() error {
+		return engine.mounter.Unmount(tmpfs.HostPath)
+	})
+	if err != nil {
+		log.Warn("Unable to unmount scratch volume", "task", task.Arn, "container", container.Name, "target", tmpfs.HostPath, "err", err)
+		return
+	}
+	os.Remove(tmpfs.HostPath)
+	tmpfs.HostPath = ""
+}
+
+// scratchMountPoint is where the container's i'th scratch volume is mounted
+// on the host
+func (engine *DockerTaskEngine) scratchMountPoint(task *api.Task, container *api.Container, i int) string {
+	return filepath.Join(engine.cfg.EFSMountDir, taskIDFromArn(task.Arn), scratchMountDir, container.Name, strconv.Itoa(i))
+}
@@ -0,0 +1,88 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+)
+
+func TestMountUnmountScratchVolumes(t *testing.T) {
+	mountDir, err := ioutil.TempDir("", "scratch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mountDir)
+
+	engine := NewDockerTaskEngine(&config.Config{EFSMountDir: mountDir})
+	mounter := &fakeMounter{mounts: make(map[string][]string)}
+	engine.mounter = mounter
+
+	container := &api.Container{
+		Name:                   "web",
+		ReadonlyRootFilesystem: true,
+		LinuxParameters:        &api.LinuxParameters{Tmpfs: []api.Tmpfs{{ContainerPath: "/tmp", Size: 64}}},
+	}
+	task := &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc", Containers: []*api.Container{container}}
+
+	if err := engine.mountScratchVolumes(task, container); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(mountDir, "abc", ".scratch", "web", "0")
+	expected := []string{"tmpfs", "tmpfs", "size=64m", "nosuid", "nodev"}
+	if !reflect.DeepEqual(mounter.mounts[target], expected) {
+		t.Error("Unexpected mount", mounter.mounts)
+	}
+	if container.LinuxParameters.Tmpfs[0].HostPath != target {
+		t.Error("Expected the host path to be the mount target")
+	}
+
+	hostConfig, hcErr := task.DockerHostConfig(container, nil)
+	if hcErr != nil {
+		t.Fatal(hcErr)
+	}
+	if !hostConfig.ReadonlyRootfs || !reflect.DeepEqual(hostConfig.Binds, []string{target + ":/tmp"}) {
+		t.Error("Expected a read only root with the scratch volume bound", hostConfig.ReadonlyRootfs, hostConfig.Binds)
+	}
+
+	// Mounting again is a no-op
+	if err := engine.mountScratchVolumes(task, container); err != nil || len(mounter.mounts) != 1 {
+		t.Error("Expected a single mount", err, mounter.mounts)
+	}
+
+	engine.unmountScratchVolumes(task)
+	if len(mounter.mounts) != 0 || container.LinuxParameters.Tmpfs[0].HostPath != "" {
+		t.Error("Expected the scratch volume to be unmounted", mounter.mounts)
+	}
+}
+
+func TestMountScratchVolumesInvalid(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{})
+	engine.mounter = &fakeMounter{mounts: make(map[string][]string)}
+
+	for _, tmpfs := range []api.Tmpfs{{ContainerPath: "tmp", Size: 64}, {ContainerPath: "/tmp"}} {
+		container := &api.Container{Name: "web", LinuxParameters: &api.LinuxParameters{Tmpfs: []api.Tmpfs{tmpfs}}}
+		task := &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc", Containers: []*api.Container{container}}
+		err := engine.mountScratchVolumes(task, container)
+		if _, ok := err.(ScratchMountError); !ok {
+			t.Error("Expected a ScratchMountError for", tmpfs, err)
+		}
+	}
+}
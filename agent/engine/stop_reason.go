@@ -0,0 +1,59 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"strconv"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+// signalExitCodeBase is added to the number of the signal that killed a
+// process to form its exit code
+const signalExitCodeBase = 128
+
+var signalNames = map[int]string{
+	1:  "SIGHUP",
+	2:  "SIGINT",
+	3:  "SIGQUIT",
+	4:  "SIGILL",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	9:  "SIGKILL",
+	11: "SIGSEGV",
+	13: "SIGPIPE",
+	15: "SIGTERM",
+}
+
+// containerStopReason explains why a container stopped, for inclusion in its
+// state change. Containers killed for exceeding their memory limit are always
+// explained, and logged so that the kill is visible on the instance as well.
+// Containers killed by a signal are explained only if the agent did not stop
+// them. Otherwise the reason is empty.
+func containerStopReason(task *api.Task, container *api.Container, metadata DockerContainerMetadata, unexpected bool) string {
+	if _, ok := metadata.Error.(OutOfMemoryError); ok {
+		log.Warn("Container killed due to memory usage", "task", task.Arn, "container", container.Name, "memory", container.Memory)
+		return api.NewNamedError(metadata.Error).Reason()
+	}
+	if !unexpected || metadata.Error != nil || metadata.ExitCode == nil || *metadata.ExitCode <= signalExitCodeBase {
+		return ""
+	}
+	signal := *metadata.ExitCode - signalExitCodeBase
+	name, ok := signalNames[signal]
+	if !ok {
+		name = "signal " + strconv.Itoa(signal)
+	}
+	return "Container exited due to " + name + " (exit code " + strconv.Itoa(*metadata.ExitCode) + ")"
+}
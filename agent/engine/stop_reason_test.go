@@ -0,0 +1,47 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+func TestContainerStopReason(t *testing.T) {
+	task := &api.Task{Arn: "arn"}
+	container := &api.Container{Name: "c"}
+	exitCode := func(code int) *int { return &code }
+
+	testCases := []struct {
+		metadata   DockerContainerMetadata
+		unexpected bool
+		reason     string
+	}{
+		{DockerContainerMetadata{ExitCode: exitCode(137), Error: OutOfMemoryError{}}, false, "OutOfMemoryError: Container killed due to memory usage"},
+		{DockerContainerMetadata{ExitCode: exitCode(137), Error: OutOfMemoryError{}}, true, "OutOfMemoryError: Container killed due to memory usage"},
+		{DockerContainerMetadata{ExitCode: exitCode(137)}, true, "Container exited due to SIGKILL (exit code 137)"},
+		{DockerContainerMetadata{ExitCode: exitCode(139)}, true, "Container exited due to SIGSEGV (exit code 139)"},
+		{DockerContainerMetadata{ExitCode: exitCode(140)}, true, "Container exited due to signal 12 (exit code 140)"},
+		{DockerContainerMetadata{ExitCode: exitCode(137)}, false, ""},
+		{DockerContainerMetadata{ExitCode: exitCode(1)}, true, ""},
+		{DockerContainerMetadata{}, true, ""},
+	}
+	for i, tc := range testCases {
+		reason := containerStopReason(task, container, tc.metadata, tc.unexpected)
+		if reason != tc.reason {
+			t.Errorf("#%v: expected reason %q, got %q", i, tc.reason, reason)
+		}
+	}
+}
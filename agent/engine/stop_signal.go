@@ -0,0 +1,87 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// stopSignals are the signals a container may be asked to stop with. Each
+// is followed by SIGKILL if the container has not exited within its stop
+// timeout, so only signals a process can handle are allowed, along with
+// SIGKILL itself for containers which need no grace period.
+var stopSignals = map[string]docker.Signal{
+	"SIGHUP":   docker.SIGHUP,
+	"SIGINT":   docker.SIGINT,
+	"SIGKILL":  docker.SIGKILL,
+	"SIGQUIT":  docker.SIGQUIT,
+	"SIGTERM":  docker.SIGTERM,
+	"SIGUSR1":  docker.SIGUSR1,
+	"SIGUSR2":  docker.SIGUSR2,
+	"SIGWINCH": docker.SIGWINCH,
+}
+
+// StopSignalError is returned when a container's stop signal is not one the
+// agent can stop it with
+type StopSignalError struct {
+	msg string
+}
+
+func (err StopSignalError) Error() string     { return err.msg }
+func (err StopSignalError) ErrorName() string { return "StopSignalError" }
+func (err StopSignalError) ErrorCode() string { return api.ErrorCodeCannotCreateContainer }
+
+// parseStopSignal returns the signal named, such as "SIGQUIT" or "quit"
+func parseStopSignal(name string) (docker.Signal, bool) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	signal, ok := stopSignals[name]
+	return signal, ok
+}
+
+// stopSignal returns the signal the container is stopped with, if it has
+// one other than SIGTERM
+func stopSignal(container *api.Container) (docker.Signal, bool) {
+	if container.StopSignal == "" {
+		return 0, false
+	}
+	signal, ok := parseStopSignal(container.StopSignal)
+	if !ok || signal == docker.SIGTERM {
+		return 0, false
+	}
+	return signal, true
+}
+
+// validateStopSignal checks that the container's stop signal, if it has one,
+// is one it can be stopped with
+func validateStopSignal(container *api.Container) api.NamedError {
+	if container.StopSignal == "" {
+		return nil
+	}
+	if _, ok := parseStopSignal(container.StopSignal); !ok {
+		names := make([]string, 0, len(stopSignals))
+		for name := range stopSignals {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return StopSignalError{"Invalid stop signal " + container.StopSignal + " for container " + container.Name + ": it must be one of " + strings.Join(names, ", ")}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// stoppingClient records how containers are stopped
+type stoppingClient struct {
+	DockerClient
+	signal  docker.Signal
+	timeout time.Duration
+}
+
+func (client *stoppingClient) StopContainer(id string, timeout time.Duration) DockerContainerMetadata {
+	client.signal = docker.SIGTERM
+	client.timeout = timeout
+	return DockerContainerMetadata{DockerId: id}
+}
+
+func (client *stoppingClient) StopContainerWithSignal(id string, signal docker.Signal, timeout time.Duration) DockerContainerMetadata {
+	client.signal = signal
+	client.timeout = timeout
+	return DockerContainerMetadata{DockerId: id}
+}
+
+func TestParseStopSignal(t *testing.T) {
+	for name, expected := range map[string]docker.Signal{
+		"SIGQUIT":  docker.SIGQUIT,
+		"quit":     docker.SIGQUIT,
+		"SIGUSR2":  docker.SIGUSR2,
+		"sigwinch": docker.SIGWINCH,
+	} {
+		if signal, ok := parseStopSignal(name); !ok || signal != expected {
+			t.Error("Unexpected signal for", name, signal, ok)
+		}
+	}
+	for _, name := range []string{"SIGSTOP", "SIGSEGV", "15", "NOPE"} {
+		if _, ok := parseStopSignal(name); ok {
+			t.Error("Expected signal to be refused", name)
+		}
+	}
+}
+
+func TestStopSignal(t *testing.T) {
+	if _, ok := stopSignal(&api.Container{}); ok {
+		t.Error("Expected no stop signal by default")
+	}
+	if _, ok := stopSignal(&api.Container{StopSignal: "SIGTERM"}); ok {
+		t.Error("Expected SIGTERM to stop the container as usual")
+	}
+	if signal, ok := stopSignal(&api.Container{StopSignal: "SIGQUIT"}); !ok || signal != docker.SIGQUIT {
+		t.Error("Unexpected stop signal", signal, ok)
+	}
+}
+
+func TestValidateStopSignal(t *testing.T) {
+	if err := validateStopSignal(&api.Container{Name: "web", StopSignal: "SIGQUIT"}); err != nil {
+		t.Error("Expected SIGQUIT to be valid", err)
+	}
+	err := validateStopSignal(&api.Container{Name: "web", StopSignal: "SIGSTOP"})
+	if _, ok := err.(StopSignalError); !ok {
+		t.Error("Expected SIGSTOP to be refused", err)
+	}
+}
+
+func TestStopContainerUsesStopSignal(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{})
+	client := &stoppingClient{}
+	engine.client = client
+	container := &api.Container{Name: "web", StopSignal: "SIGQUIT", StopTimeout: 10}
+	task := &api.Task{Arn: "task1", Containers: []*api.Container{container}}
+	engine.state.AddTask(task)
+	engine.state.AddContainer(&api.DockerContainer{DockerId: "dockerid", DockerName: "web", Container: container}, task)
+
+	engine.stopContainer(nil, task, container, nil)
+	if client.signal != docker.SIGQUIT || client.timeout != 10*time.Second {
+		t.Error("Expected the container to be stopped with its signal and timeout", client.signal, client.timeout)
+	}
+
+	container.StopSignal = ""
+	engine.stopContainer(nil, task, container, nil)
+	if client.signal != docker.SIGTERM {
+		t.Error("Expected the container to be stopped as usual", client.signal)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
+)
+
+// The task lifecycle events hooks are run for
+const (
+	TaskHookPreStart = "preStart"
+	TaskHookPostStop = "postStop"
+)
+
+// TaskHook is run on the host before a task's first container is created and
+// after the task stops, such as to create and remove a user or firewall rules
+// for the task. Run returns an error if the hook failed.
+type TaskHook interface {
+	Name() string
+	Run(request *TaskHookRequest, timeout time.Duration) error
+}
+
+// TaskHookRequest describes the task a hook is run for. It is sent to task
+// hooks as json.
+type TaskHookRequest struct {
+	// Event is TaskHookPreStart or TaskHookPostStop
+	Event                string              `json:"event"`
+	Cluster              string              `json:"cluster"`
+	ContainerInstanceArn string              `json:"containerInstanceArn"`
+	TaskArn              string              `json:"taskArn"`
+	Family               string              `json:"family"`
+	Version              string              `json:"version"`
+	Containers           []TaskHookContainer `json:"containers"`
+}
+
+// TaskHookContainer describes one of the task's containers. Its docker id is
+// known once it has been created, and its exit code once it has stopped.
+type TaskHookContainer struct {
+	Name     string `json:"name"`
+	Image    string `json:"image"`
+	DockerID string `json:"dockerId,omitempty"`
+	ExitCode *int   `json:"exitCode,omitempty"`
+}
+
+// TaskHookError is returned when a pre-start task hook fails and the agent is
+// not configured to fail open
+type TaskHookError struct {
+	msg string
+}
+
+func (err TaskHookError) Error() string     { return err.msg }
+func (err TaskHookError) ErrorName() string { return "TaskHookError" }
+func (err TaskHookError) ErrorCode() string { return api.ErrorCodeResourceInitialization }
+
+// newTaskHooks returns the task hooks configured in cfg
+func newTaskHooks(cfg *config.Config) []TaskHook {
+	hooks := []TaskHook{}
+	if cfg.TaskHookCommand != "" {
+		hooks = append(hooks, &execTaskHook{path: cfg.TaskHookCommand})
+	}
+	if cfg.TaskHookURL != "" {
+		hooks = append(hooks, &webhookTaskHook{url: cfg.TaskHookURL})
+	}
+	return hooks
+}
+
+// runPreStartTaskHooks runs the task hooks before the task's first container
+// is created. The task's containers created concurrently wait for them, and
+// they are not run again once they have succeeded; other tasks' hooks run
+// meanwhile.
+func (engine *DockerTaskEngine) runPreStartTaskHooks(task *api.Task, span *tracing.Span) api.NamedError {
+	if len(engine.taskHooks) == 0 {
+		return nil
+	}
+	task.HooksLock.Lock()
+	defer task.HooksLock.Unlock()
+	if task.PreStartHooksRun {
+		return nil
+	}
+
+	hookSpan := span.StartChild("pre-start task hooks")
+	request := engine.taskHookRequest(TaskHookPreStart, task)
+	for _, hook := range engine.taskHooks {
+		err := hook.Run(request, engine.cfg.TaskHookTimeout)
+		if err == nil {
+			continue
+		}
+		if !engine.cfg.TaskHookFailOpen {
+			hookErr := TaskHookError{"Pre-start task hook " + hook.Name() + " failed: " + err.Error()}
+			hookSpan.End(hookErr)
+			return hookErr
+		}
+		log.Warn("Pre-start task hook failed; starting task anyway", "hook", hook.Name(), "task", task.Arn, "err", err)
+	}
+	hookSpan.End(nil)
+	task.PreStartHooksRun = true
+	return nil
+}
+
+// runPostStopTaskHooks runs the task hooks once the task has stopped, if
+// they were run before it started. Failures are only logged, since the task
+// has already stopped.
+func (engine *DockerTaskEngine) runPostStopTaskHooks(task *api.Task) {
+	task.HooksLock.Lock()
+	defer task.HooksLock.Unlock()
+	if !task.PreStartHooksRun || task.PostStopHooksRun {
+		return
+	}
+
+	request := engine.taskHookRequest(TaskHookPostStop, task)
+	for _, hook := range engine.taskHooks {
+		if err := hook.Run(request, engine.cfg.TaskHookTimeout); err != nil {
+			log.Warn("Post-stop task hook failed", "hook", hook.Name(), "task", task.Arn, "err", err)
+		}
+	}
+	task.PostStopHooksRun = true
+}
+
+func (engine *DockerTaskEngine) taskHookRequest(event string, task *api.Task) *TaskHookRequest {
+	engine.instanceLock.RLock()
+	containerInstanceArn := engine.containerInstanceArn
+	engine.instanceLock.RUnlock()
+
+	request := &TaskHookRequest{
+		Event:                event,
+		Cluster:              engine.cfg.Cluster,
+		ContainerInstanceArn: containerInstanceArn,
+		TaskArn:              task.Arn,
+		Family:               task.Family,
+		Version:              task.Version,
+		Containers:           make([]TaskHookContainer, 0, len(task.Containers)),
+	}
+	containerMap, _ := engine.state.ContainerMapByArn(task.Arn)
+	for _, container := range task.Containers {
+		hookContainer := TaskHookContainer{Name: container.Name, Image: container.Image, ExitCode: container.KnownExitCode}
+		if dockerContainer, ok := containerMap[container.Name]; ok {
+			hookContainer.DockerID = dockerContainer.DockerId
+		}
+		request.Containers = append(request.Containers, hookContainer)
+	}
+	return request
+}
+
+// execTaskHook runs an executable on the host with the request on stdin. It
+// must exit 0.
+type execTaskHook struct {
+	path string
+}
+
+func (hook *execTaskHook) Name() string { return hook.path }
+
+func (hook *execTaskHook) Run(request *TaskHookRequest, timeout time.Duration) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	_, err = runCommand(hook.path, nil, body, timeout)
+	return err
+}
+
+// webhookTaskHook posts the request to a url, which must answer 200
+type webhookTaskHook struct {
+	url string
+}
+
+func (hook *webhookTaskHook) Name() string { return hook.url }
+
+func (hook *webhookTaskHook) Run(request *TaskHookRequest, timeout time.Duration) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	_, err = postHook(hook.url, body, timeout)
+	return err
+}
@@ -0,0 +1,185 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+)
+
+// fakeTaskHook records the events it is run for
+type fakeTaskHook struct {
+	err    error
+	events []string
+}
+
+func (hook *fakeTaskHook) Name() string { return "fake" }
+
+func (hook *fakeTaskHook) Run(request *TaskHookRequest, timeout time.Duration) error {
+	hook.events = append(hook.events, request.Event)
+	return hook.err
+}
+
+func TestTaskHooks(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{Cluster: "default", TaskHookTimeout: time.Second})
+	hook := &fakeTaskHook{}
+	engine.taskHooks = []TaskHook{hook}
+	task, _ := preStartHookTask()
+
+	engine.runPostStopTaskHooks(task)
+	if len(hook.events) != 0 {
+		t.Error("Expected no post-stop hooks for a task which never started", hook.events)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := engine.runPreStartTaskHooks(task, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	engine.runPostStopTaskHooks(task)
+	engine.runPostStopTaskHooks(task)
+	if len(hook.events) != 2 || hook.events[0] != TaskHookPreStart || hook.events[1] != TaskHookPostStop {
+		t.Error("Expected each hook to run once", hook.events)
+	}
+}
+
+// blockingTaskHook blocks running for the task named blocked until unblocked
+// is closed
+type blockingTaskHook struct {
+	blocked   string
+	unblocked chan struct{}
+}
+
+func (hook *blockingTaskHook) Name() string { return "blocking" }
+
+func (hook *blockingTaskHook) Run(request *TaskHookRequest, timeout time.Duration) error {
+	if request.TaskArn == hook.blocked {
+		<-hook.unblocked
+	}
+	return nil
+}
+
+func TestTaskHooksRunConcurrentlyForTasks(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{TaskHookTimeout: time.Second})
+	hook := &blockingTaskHook{blocked: "slow", unblocked: make(chan struct{})}
+	engine.taskHooks = []TaskHook{hook}
+	slow := &api.Task{Arn: "slow"}
+	fast := &api.Task{Arn: "fast"}
+
+	slowDone := make(chan api.NamedError, 1)
+	go func() { slowDone <- engine.runPreStartTaskHooks(slow, nil) }()
+	fastDone := make(chan api.NamedError, 1)
+	go func() { fastDone <- engine.runPreStartTaskHooks(fast, nil) }()
+
+	select {
+	case err := <-fastDone:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected one task's hooks not to wait for another's")
+	}
+	close(hook.unblocked)
+	if err := <-slowDone; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTaskHookFailurePolicy(t *testing.T) {
+	cfg := &config.Config{TaskHookTimeout: time.Second}
+	engine := NewDockerTaskEngine(cfg)
+	engine.taskHooks = []TaskHook{&fakeTaskHook{err: errors.New("unreachable")}}
+
+	task, _ := preStartHookTask()
+	if _, ok := engine.runPreStartTaskHooks(task, nil).(TaskHookError); !ok {
+		t.Error("Expected the task to fail when its hook fails")
+	}
+	if task.PreStartHooksRun {
+		t.Error("Expected the hook to be retried")
+	}
+
+	cfg.TaskHookFailOpen = true
+	if err := engine.runPreStartTaskHooks(task, nil); err != nil {
+		t.Error("Expected the task to start when failing open", err)
+	}
+}
+
+func TestExecTaskHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "taskhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hook")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\ngrep -q '\"event\":\"postStop\"'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	hook := &execTaskHook{path: path}
+
+	exitCode := 137
+	request := &TaskHookRequest{Event: TaskHookPostStop, TaskArn: "arn", Containers: []TaskHookContainer{{Name: "web", ExitCode: &exitCode}}}
+	if err := hook.Run(request, 5*time.Second); err != nil {
+		t.Error("Expected the hook to be given the event", err)
+	}
+	request.Event = TaskHookPreStart
+	if err := hook.Run(request, 5*time.Second); err == nil {
+		t.Error("Expected a failing hook to return an error")
+	}
+}
+
+func TestExecTaskHookTimeoutKillsChildren(t *testing.T) {
+	dir, err := ioutil.TempDir("", "taskhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hook")
+	// The background sleep keeps the hook's output open unless it is killed
+	// too
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\nsleep 10 &\nsleep 10\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	hook := &execTaskHook{path: path}
+
+	start := time.Now()
+	if err := hook.Run(&TaskHookRequest{Event: TaskHookPreStart}, 100*time.Millisecond); err == nil {
+		t.Error("Expected the hook to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Error("Expected the hook's children to be killed with it, took", elapsed)
+	}
+}
+
+func TestTaskHookRequest(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{Cluster: "default"})
+	engine.SetContainerInstance("arn:instance", nil)
+	task, container := preStartHookTask()
+	engine.state.AddTask(task)
+	engine.state.AddContainer(&api.DockerContainer{DockerId: "dockerid", DockerName: "name", Container: container}, task)
+
+	request := engine.taskHookRequest(TaskHookPreStart, task)
+	if request.Cluster != "default" || request.ContainerInstanceArn != "arn:instance" || request.Family != "web" {
+		t.Error("Unexpected request", request)
+	}
+	if len(request.Containers) != 1 || request.Containers[0].DockerID != "dockerid" || request.Containers[0].Image != "nginx:latest" {
+		t.Error("Unexpected containers", request.Containers)
+	}
+}
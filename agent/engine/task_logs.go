@@ -0,0 +1,106 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+const (
+	// jsonFileLogDriver is docker's default logging driver, which writes a
+	// container's output to a file in docker's graph directory
+	jsonFileLogDriver = "json-file"
+	// taskLogArnFile is the file in a task's log directory holding its arn
+	taskLogArnFile = "task-arn"
+)
+
+// taskLogDir returns the directory the task's container logs are linked in.
+// As it is removed along with the task, it must be a directory of its own
+// directly under TaskLogDir, which a malformed task id could escape.
+func (engine *DockerTaskEngine) taskLogDir(task *api.Task) (string, error) {
+	id := taskIDFromArn(task.Arn)
+	if id == "" || id == "." || id == ".." || strings.ContainsRune(id, filepath.Separator) {
+		return "", errors.New("invalid task id " + strconv.Quote(id))
+	}
+	dir := filepath.Join(engine.cfg.TaskLogDir, id)
+	if filepath.Dir(dir) != filepath.Clean(engine.cfg.TaskLogDir) {
+		return "", errors.New("task log directory " + dir + " is not in " + engine.cfg.TaskLogDir)
+	}
+	return dir, nil
+}
+
+// linkContainerLog links the log file docker writes for the container into
+// its task's log directory as <container name>.log, so that log shippers on
+// the host can find a task's logs. Containers whose logs docker does not
+// write to a file are skipped.
+func (engine *DockerTaskEngine) linkContainerLog(task *api.Task, container *api.Container, dockerID string) {
+	if engine.cfg.TaskLogDir == "" {
+		return
+	}
+	dockerContainer, err := engine.client.InspectContainer(dockerID)
+	if err != nil {
+		log.Warn("Unable to find the log driver of container; not linking its log", "task", task.Arn, "container", container.Name, "err", err)
+		return
+	}
+	if dockerContainer.HostConfig != nil {
+		driver := dockerContainer.HostConfig.LogConfig.Type
+		if driver != "" && driver != jsonFileLogDriver {
+			log.Debug("Container does not log to a file; not linking its log", "task", task.Arn, "container", container.Name, "driver", driver)
+			return
+		}
+	}
+
+	taskDir, err := engine.taskLogDir(task)
+	if err != nil {
+		log.Warn("Not linking container log", "task", task.Arn, "container", container.Name, "err", err)
+		return
+	}
+	if err := os.MkdirAll(taskDir, 0755); err != nil {
+		log.Warn("Unable to create task log directory", "task", task.Arn, "dir", taskDir, "err", err)
+		return
+	}
+	if err := ioutil.WriteFile(filepath.Join(taskDir, taskLogArnFile), []byte(task.Arn+"\n"), 0644); err != nil {
+		log.Warn("Unable to record arn in task log directory", "task", task.Arn, "dir", taskDir, "err", err)
+	}
+	target := filepath.Join(engine.cfg.DockerGraphPath, "containers", dockerID, dockerID+"-json.log")
+	link := filepath.Join(taskDir, container.Name+".log")
+	// A container which is recreated replaces the link to its old log
+	os.Remove(link)
+	if err := os.Symlink(target, link); err != nil {
+		log.Warn("Unable to link container log", "task", task.Arn, "container", container.Name, "err", err)
+	}
+}
+
+// removeTaskLogDir removes the task's log directory. The logs themselves are
+// removed by docker along with their containers.
+func (engine *DockerTaskEngine) removeTaskLogDir(task *api.Task) {
+	if engine.cfg.TaskLogDir == "" {
+		return
+	}
+	taskDir, err := engine.taskLogDir(task)
+	if err != nil {
+		log.Warn("Not removing task log directory", "task", task.Arn, "err", err)
+		return
+	}
+	if err := os.RemoveAll(taskDir); err != nil {
+		log.Warn("Unable to remove task log directory", "task", task.Arn, "err", err)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// logDriverClient answers InspectContainer with a fixed log driver
+type logDriverClient struct {
+	DockerClient
+	driver string
+}
+
+func (client *logDriverClient) InspectContainer(id string) (*docker.Container, error) {
+	return &docker.Container{ID: id, HostConfig: &docker.HostConfig{LogConfig: docker.LogConfig{Type: client.driver}}}, nil
+}
+
+func TestLinkContainerLog(t *testing.T) {
+	logDir, err := ioutil.TempDir("", "tasklogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(logDir)
+
+	engine := NewDockerTaskEngine(&config.Config{TaskLogDir: logDir, DockerGraphPath: "/var/lib/docker"})
+	engine.client = &logDriverClient{driver: jsonFileLogDriver}
+	web := &api.Container{Name: "web"}
+	sidecar := &api.Container{Name: "sidecar"}
+	task := &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc", Containers: []*api.Container{web, sidecar}}
+
+	engine.linkContainerLog(task, web, "dockerid")
+	target, err := os.Readlink(filepath.Join(logDir, "abc", "web.log"))
+	if err != nil || target != "/var/lib/docker/containers/dockerid/dockerid-json.log" {
+		t.Error("Expected a link to the container's json log", target, err)
+	}
+	arn, err := ioutil.ReadFile(filepath.Join(logDir, "abc", taskLogArnFile))
+	if err != nil || string(arn) != task.Arn+"\n" {
+		t.Error("Expected the task's arn in its log directory", string(arn), err)
+	}
+
+	// A recreated container's link is replaced
+	engine.linkContainerLog(task, web, "newdockerid")
+	if target, _ := os.Readlink(filepath.Join(logDir, "abc", "web.log")); target != "/var/lib/docker/containers/newdockerid/newdockerid-json.log" {
+		t.Error("Expected the link to be replaced", target)
+	}
+
+	engine.client = &logDriverClient{driver: "syslog"}
+	engine.linkContainerLog(task, sidecar, "sidecarid")
+	if _, err := os.Lstat(filepath.Join(logDir, "abc", "sidecar.log")); !os.IsNotExist(err) {
+		t.Error("Expected no link for a container logging to syslog", err)
+	}
+
+	engine.removeTaskLogDir(task)
+	if _, err := os.Stat(filepath.Join(logDir, "abc")); !os.IsNotExist(err) {
+		t.Error("Expected the task log directory to be removed", err)
+	}
+}
+
+func TestLinkContainerLogDisabled(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{})
+	// The client isn't consulted when task log directories are disabled
+	engine.client = nil
+	task := &api.Task{Arn: "arn:aws:ecs:us-west-2:123456789012:task/abc"}
+	engine.linkContainerLog(task, &api.Container{Name: "web"}, "dockerid")
+	engine.removeTaskLogDir(task)
+}
+
+func TestRemoveTaskLogDirRefusesInvalidTaskIDs(t *testing.T) {
+	logDir, err := ioutil.TempDir("", "tasklogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(logDir)
+	if err := os.Mkdir(filepath.Join(logDir, "other"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewDockerTaskEngine(&config.Config{TaskLogDir: logDir})
+	for _, arn := range []string{"arn:aws:ecs:us-west-2:123456789012:task/", "arn:aws:ecs:us-west-2:123456789012:task/.", "arn:aws:ecs:us-west-2:123456789012:task/..", ""} {
+		engine.removeTaskLogDir(&api.Task{Arn: arn})
+		if _, err := os.Stat(filepath.Join(logDir, "other")); err != nil {
+			t.Fatal("Expected other tasks' log directories to be kept after removing", arn, err)
+		}
+	}
+}
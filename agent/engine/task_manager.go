@@ -14,17 +14,22 @@
 package engine
 
 import (
+	"errors"
 	"sync"
 	"time"
 
+	"golang.org/x/net/context"
+
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dependencygraph"
+	"github.com/aws/amazon-ecs-agent/agent/tracing"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
 )
 
 const (
-	taskStoppedDuration           = 3 * time.Hour
-	steadyStateTaskVerifyInterval = 10 * time.Minute
+	// taskStoppedDuration is used when the configuration does not specify
+	// how long to keep stopped tasks before cleaning them up
+	taskStoppedDuration = 3 * time.Hour
 )
 
 type acsTaskUpdate struct {
@@ -69,6 +74,9 @@ type managedTask struct {
 	acsMessages    chan acsTransition
 	dockerMessages chan dockerContainerChange
 
+	// routines tracks the goroutines started on behalf of this task
+	routines *taskRoutines
+
 	// unexpectedStart is a once that controls stopping a container that
 	// unexpectedly started one time.
 	// This exists because a 'start' after a container is meant to be stopped is
@@ -77,6 +85,21 @@ type managedTask struct {
 	// case it's a user trying to debug it or in case we're fighting with another
 	// thing managing the container.
 	unexpectedStart sync.Once
+
+	// launchStart is when the task was received, if it has not yet first
+	// reached steady state or stopped. launchSpan traces the launch; it is nil
+	// if tracing is disabled.
+	launchStart time.Time
+	launchSpan  *tracing.Span
+
+	// launchCtx is cancelled once the task should stop, abandoning any
+	// transitions still moving its containers towards running
+	launchCtx    context.Context
+	cancelLaunch context.CancelFunc
+
+	// dependentsWaitStart is when each container began waiting for the
+	// containers depending on it to stop
+	dependentsWaitStart map[string]time.Time
 }
 
 func (engine *DockerTaskEngine) newManagedTask(task *api.Task) *managedTask {
@@ -85,6 +108,19 @@ func (engine *DockerTaskEngine) newManagedTask(task *api.Task) *managedTask {
 		acsMessages:    make(chan acsTransition),
 		dockerMessages: make(chan dockerContainerChange),
 		engine:         engine,
+		routines:       newTaskRoutines(task.Arn, len(task.Containers)),
+	}
+	t.launchCtx, t.cancelLaunch = context.WithCancel(context.Background())
+	desiredStatus := task.GetDesiredStatus()
+	if desiredStatus.Terminal() {
+		t.cancelLaunch()
+	}
+	if task.GetKnownStatus() < api.TaskRunning && !desiredStatus.Terminal() {
+		t.launchStart = ttime.Now()
+		t.launchSpan = engine.tracer.StartSpan("task launch")
+		t.launchSpan.SetAttribute("aws.ecs.task.arn", task.Arn)
+		t.launchSpan.SetAttribute("aws.ecs.task.family", task.Family)
+		t.launchSpan.SetAttribute("aws.ecs.task.revision", task.Version)
 	}
 	engine.managedTasks[task.Arn] = t
 	return t
@@ -100,42 +136,38 @@ func (task *managedTask) overseeTask() {
 	// If this was a 'state restore', send all unsent statuses
 	task.emitCurrentStatus()
 
-	if task.StartSequenceNumber != 0 && !task.DesiredStatus.Terminal() {
+	if desiredStatus := task.GetDesiredStatus(); task.StartSequenceNumber != 0 && !desiredStatus.Terminal() {
 		llog.Debug("Waiting for any previous stops to complete", "seqnum", task.StartSequenceNumber)
 		othersStopped := make(chan bool, 1)
-		go func() {
+		task.routines.Go("stop-group-wait", func(ctx context.Context) {
 			task.engine.taskStopGroup.Wait(task.StartSequenceNumber)
 			othersStopped <- true
-		}()
+		})
 		for !task.waitEvent(othersStopped) {
-			if task.DesiredStatus.Terminal() {
+			if desiredStatus := task.GetDesiredStatus(); desiredStatus.Terminal() {
 				// If we end up here, that means we recieved a start then stop for this
 				// task before a task that was expected to stop before it could
 				// actually stop
 				break
 			}
 		}
-		llog.Debug("Wait over; ready to move towards status: " + task.DesiredStatus.String())
+		desiredStatus := task.GetDesiredStatus()
+		llog.Debug("Wait over; ready to move towards status: " + desiredStatus.String())
 	}
 	for {
-		// If it's steadyState, just spin until we need to do work
+		// If it's steadyState, just spin until we need to do work. Changes
+		// arrive as docker events, or from the engine's periodic
+		// reconciliation if an event was missed.
+		if task.steadyState() {
+			task.endLaunch(nil)
+		}
 		for task.steadyState() {
-			llog.Debug("Task at steady state", "state", task.KnownStatus.String())
-			maxWait := make(chan bool, 1)
-			timer := ttime.After(steadyStateTaskVerifyInterval)
-			go func() {
-				<-timer
-				maxWait <- true
-			}()
-			timedOut := task.waitEvent(maxWait)
-
-			if timedOut {
-				llog.Debug("Checking task to make sure it's still at steadystate")
-				go task.engine.CheckTaskState(task.Task)
-			}
+			knownStatus := task.GetKnownStatus()
+			llog.Debug("Task at steady state", "state", knownStatus.String())
+			task.waitEvent(nil)
 		}
 
-		if !task.KnownStatus.Terminal() {
+		if knownStatus := task.GetKnownStatus(); !knownStatus.Terminal() {
 			// If we aren't terminal and we aren't steady state, we should be able to move some containers along
 			llog.Debug("Task not steady state or terminal; progressing it")
 			task.progressContainers()
@@ -149,13 +181,25 @@ func (task *managedTask) overseeTask() {
 		if err != nil {
 			llog.Warn("Error checkpointing task's states to disk", "err", err)
 		}
-		if task.KnownStatus.Terminal() {
+		if knownStatus := task.GetKnownStatus(); knownStatus.Terminal() {
 			break
 		}
 	}
 	// We only break out of the above if this task is known to be stopped. Do
 	// onetime cleanup here, including removing the task after a timeout
 	llog.Debug("Task has reached stopped. We're just waiting and removing containers now")
+	task.endLaunch(errors.New("task stopped before reaching steady state"))
+	task.cancelLaunch()
+	if len(task.engine.taskHooks) > 0 {
+		// Hooks may take a while, and the task must keep draining its
+		// messages in the meantime
+		task.routines.Go("post-stop-hooks", func(ctx context.Context) {
+			task.engine.runPostStopTaskHooks(task.Task)
+			if err := task.engine.saver.Save(); err != nil {
+				llog.Warn("Error checkpointing task's states to disk", "err", err)
+			}
+		})
+	}
 	if task.StopSequenceNumber != 0 {
 		llog.Debug("Marking done for this sequence", "seqnum", task.StopSequenceNumber)
 		task.engine.taskStopGroup.Done(task.StopSequenceNumber)
@@ -163,6 +207,28 @@ func (task *managedTask) overseeTask() {
 	task.cleanupTask()
 }
 
+// endLaunch logs how long launching the task and each phase of launching its
+// containers took, if the task was launching. err is nil if the launch
+// succeeded.
+func (mtask *managedTask) endLaunch(err error) {
+	if mtask.launchStart.IsZero() {
+		return
+	}
+	ctx := []interface{}{"task", mtask.Task, "duration", ttime.Since(mtask.launchStart).String()}
+	for _, container := range mtask.Containers {
+		for phase, duration := range container.GetLaunchPhases() {
+			ctx = append(ctx, container.Name+"."+phase, duration.String())
+		}
+	}
+	if err != nil {
+		ctx = append(ctx, "err", err)
+	}
+	log.Info("Task launch finished", ctx...)
+
+	mtask.launchSpan.End(err)
+	mtask.launchStart = time.Time{}
+}
+
 func (mtask *managedTask) emitCurrentStatus() {
 	for _, container := range mtask.Containers {
 		mtask.engine.emitContainerEvent(mtask.Task, container, "")
@@ -175,8 +241,8 @@ func (mtask *managedTask) handleDesiredStatusChange(desiredStatus api.TaskStatus
 	// Handle acs message changes this task's desired status to whatever
 	// acs says it should be if it is compatible
 	llog.Debug("New acs transition", "status", desiredStatus.String(), "seqnum", seqnum, "taskSeqnum", mtask.StopSequenceNumber)
-	if desiredStatus <= mtask.DesiredStatus {
-		llog.Debug("Redundant transition; ignoring", "old", mtask.DesiredStatus.String(), "new", desiredStatus.String())
+	if oldStatus := mtask.GetDesiredStatus(); desiredStatus <= oldStatus {
+		llog.Debug("Redundant transition; ignoring", "old", oldStatus.String(), "new", desiredStatus.String())
 		return
 	}
 	if desiredStatus == api.TaskStopped && seqnum != 0 && mtask.StopSequenceNumber == 0 {
@@ -184,8 +250,11 @@ func (mtask *managedTask) handleDesiredStatusChange(desiredStatus api.TaskStatus
 		mtask.StopSequenceNumber = seqnum
 		mtask.engine.taskStopGroup.Add(seqnum, 1)
 	}
-	mtask.DesiredStatus = desiredStatus
+	mtask.SetDesiredStatus(desiredStatus)
 	mtask.UpdateDesiredStatus()
+	if desiredStatus.Terminal() {
+		mtask.cancelLaunch()
+	}
 }
 
 func (mtask *managedTask) handleContainerChange(containerChange dockerContainerChange) {
@@ -206,30 +275,54 @@ func (mtask *managedTask) handleContainerChange(containerChange dockerContainerC
 	}
 	event := containerChange.event
 	llog.Debug("Handling container change", "change", containerChange)
+	container.RecordLaunchPhases(event.LaunchPhases)
+	// Whether the container stopped without us asking it to
+	unexpectedStop := container.GetDesiredStatus() < api.ContainerStopped
 
 	// Cases: If this is a forward transition (else) update the container to be known to be at that status.
 	// If this is a backwards transition stopped->running, the first time set it
 	// to be known running so it will be stopped. Subsequently ignore these backward transitions
-	if event.Status <= container.KnownStatus && container.KnownStatus == api.ContainerStopped {
+	if knownStatus := container.GetKnownStatus(); event.Status <= knownStatus && knownStatus == api.ContainerStopped {
 		if event.Status == api.ContainerRunning {
 			// If the container becomes running after we've stopped it (possibly
 			// because we got an error running it and it ran anyways), the first time
 			// update it to 'known running' so that it will be driven back to stopped
 			mtask.unexpectedStart.Do(func() {
 				llog.Warn("Container that we thought was stopped came back; re-stopping it once")
-				go mtask.engine.transitionContainer(mtask.Task, container, api.ContainerStopped)
+				mtask.routines.Go("transition-"+container.Name, func(ctx context.Context) {
+					mtask.engine.transitionContainer(context.Background(), mtask.Task, container, api.ContainerStopped, nil)
+				})
 				// This will not proceed afterwards because status <= knownstatus below
 			})
 		}
 	}
-	if event.Status <= container.KnownStatus {
-		llog.Info("Redundant status change; ignoring", "current", container.KnownStatus.String(), "change", event.Status.String())
+	if knownStatus := container.GetKnownStatus(); event.Status <= knownStatus {
+		llog.Info("Redundant status change; ignoring", "current", knownStatus.String(), "change", event.Status.String())
 		return
 	}
-	container.KnownStatus = event.Status
+	if _, ok := event.Error.(TransitionCancelledError); ok {
+		// Nothing happened, so the container's known status is unchanged and
+		// there is no error to report; it simply moves on to stopped
+		llog.Info("Container transition cancelled; the task is stopping", "container", container, "transition", event.Status.String())
+		container.SetDesiredStatus(api.ContainerStopped)
+		return
+	}
+	container.SetKnownStatus(event.Status)
+	if event.Status == api.ContainerStopped {
+		mtask.engine.cpuSets.release(mtask.Task, container)
+		container.KnownFinishedAt = event.FinishedAt
+		if container.KnownFinishedAt.IsZero() {
+			container.KnownFinishedAt = ttime.Now()
+		}
+	}
 
 	if event.Error != nil {
-		if container.ApplyingError == nil {
+		// Only an error which stopped the container is recorded, as it is
+		// also what explains the container and its task stopping. Errors
+		// stopping a container which was already being stopped, and pull
+		// errors which may not be fatal, are only logged.
+		fatal := event.Status != api.ContainerPulled && (event.Status != api.ContainerStopped || unexpectedStop)
+		if fatal && container.ApplyingError == nil {
 			container.ApplyingError = api.NewNamedError(event.Error)
 		}
 		if event.Status == api.ContainerStopped {
@@ -238,15 +331,15 @@ func (mtask *managedTask) handleContainerChange(containerChange dockerContainerC
 			// again and again... In this case, assume it's stopped (or close
 			// enough) and get on with it
 			// This actually happens a lot for the case of stopping something that was not running.
-			llog.Info("Error for 'docker stop' of container; assuming it's stopped anyways")
-			container.KnownStatus = api.ContainerStopped
-			container.DesiredStatus = api.ContainerStopped
+			llog.Info("Error for 'docker stop' of container; assuming it's stopped anyways", "err", event.Error)
+			container.SetKnownStatus(api.ContainerStopped)
+			container.SetDesiredStatus(api.ContainerStopped)
 		} else if event.Status == api.ContainerPulled {
 			// Another special case; a failure to pull might not be fatal if e.g. the image already exists.
 			llog.Info("Error while pulling container; will try to run anyways", "err", event.Error)
 		} else {
 			llog.Warn("Error with docker; stopping container", "container", container, "err", event.Error)
-			container.DesiredStatus = api.ContainerStopped
+			container.SetDesiredStatus(api.ContainerStopped)
 			// the above 'knownstatus' is not truthful because of the error
 			// No point in emitting it, just continue on to stopped
 			return
@@ -263,7 +356,15 @@ func (mtask *managedTask) handleContainerChange(containerChange dockerContainerC
 		mtask.UpdateMountPoints(container, event.Volumes)
 	}
 
-	mtask.engine.emitContainerEvent(mtask.Task, container, "")
+	reason := ""
+	if event.Status == api.ContainerStopped {
+		reason = containerStopReason(mtask.Task, container, event.DockerContainerMetadata, unexpectedStop)
+		if reason == "" && container.ApplyingError != nil {
+			reason = container.ApplyingError.Reason()
+		}
+		container.KnownReason = reason
+	}
+	mtask.engine.emitContainerEvent(mtask.Task, container, reason)
 	if mtask.UpdateStatus() {
 		llog.Debug("Container change also resulted in task change")
 		// If knownStatus changed, let it be known
@@ -272,7 +373,8 @@ func (mtask *managedTask) handleContainerChange(containerChange dockerContainerC
 }
 
 func (mtask *managedTask) steadyState() bool {
-	return mtask.KnownStatus == api.TaskRunning && mtask.KnownStatus >= mtask.DesiredStatus
+	knownStatus := mtask.GetKnownStatus()
+	return knownStatus == api.TaskRunning && knownStatus >= mtask.GetDesiredStatus()
 }
 
 // waitEvent waits for any event to occur. If the event is the passed in
@@ -305,28 +407,41 @@ func (mtask *managedTask) waitEvent(stopWaiting <-chan bool) bool {
 func (mtask *managedTask) containerNextState(container *api.Container) (api.ContainerStatus, bool, bool) {
 	clog := log.New("task", mtask.Task, "container", container)
 
-	if container.KnownStatus == container.DesiredStatus {
-		clog.Debug("Container at desired status", "desired", container.DesiredStatus)
+	knownStatus := container.GetKnownStatus()
+	desiredStatus := container.GetDesiredStatus()
+	if knownStatus == desiredStatus {
+		clog.Debug("Container at desired status", "desired", desiredStatus)
 		return api.ContainerStatusNone, false, false
 	}
-	if container.KnownStatus > container.DesiredStatus {
+	if knownStatus > desiredStatus {
 		clog.Debug("Container past desired status")
 		return api.ContainerStatusNone, false, false
 	}
-	if !dependencygraph.DependenciesAreResolved(container, mtask.Containers) {
-		clog.Debug("Can't apply state to container yet; dependencies unresolved", "state", container.DesiredStatus)
+	// Pulling an image does not depend on any other container, so only
+	// later transitions need to wait for dependencies
+	if knownStatus >= api.ContainerPulled && !dependencygraph.DependenciesAreResolved(container, mtask.Containers) {
+		clog.Debug("Can't apply state to container yet; dependencies unresolved", "state", desiredStatus)
 		return api.ContainerStatusNone, false, false
 	}
 
 	var nextState api.ContainerStatus
 	if container.DesiredTerminal() {
 		nextState = api.ContainerStopped
-		if container.KnownStatus != api.ContainerRunning {
+		if knownStatus != api.ContainerRunning {
 			// If it's not currently running we do not need to do anything to make it become stopped.
 			return nextState, false, true
 		}
+		// Stop in reverse dependency order so that sidecars outlive the
+		// containers using them
+		if dependents := dependencygraph.RunningDependents(container, mtask.Containers); len(dependents) > 0 {
+			if !mtask.dependentsWaitExpired(container, dependents) {
+				clog.Debug("Can't stop container yet; containers depending on it are still running")
+				return api.ContainerStatusNone, false, false
+			}
+			clog.Warn("Containers depending on container did not stop in time; stopping it anyway")
+		}
 	} else {
-		nextState = container.KnownStatus + 1
+		nextState = knownStatus + 1
 	}
 	return nextState, true, true
 }
@@ -347,30 +462,10 @@ func (task *managedTask) progressContainers() {
 	// Map of containerName -> applyingTransition
 	transitionsMap := make(map[string]api.ContainerStatus)
 
-	anyCanTransition := false
-	for _, cont := range task.Containers {
-		nextState, shouldCallTransitionFunc, canTransition := task.containerNextState(cont)
-		if !canTransition {
-			continue
-		}
-		// At least one container is able to be moved forwards, so we're not deadlocked
-		anyCanTransition = true
-
-		if !shouldCallTransitionFunc {
-			task.handleContainerChange(dockerContainerChange{cont, DockerContainerChangeEvent{Status: nextState}})
-			continue
-		}
-		transitionsMap[cont.Name] = nextState
-		go func(container *api.Container, nextStatus api.ContainerStatus) {
-			task.engine.transitionContainer(task.Task, container, nextStatus)
-			transitionChange <- true
-			transitionChangeContainer <- container.Name
-		}(cont, nextState)
-	}
-
+	anyCanTransition := task.startContainerTransitions(transitionsMap, transitionChange, transitionChangeContainer)
 	if !anyCanTransition {
 		log.Crit("Task in a bad state; it's not steadystate but no containers want to transition", "task", task.Task)
-		if task.DesiredStatus.Terminal() {
+		if desiredStatus := task.GetDesiredStatus(); desiredStatus.Terminal() {
 			// Ack, really bad. We want it to stop but the containers don't think
 			// that's possible... let's just break out and hope for the best!
 			log.Crit("The state is so bad that we're just giving up on it")
@@ -391,9 +486,15 @@ func (task *managedTask) progressContainers() {
 			changedContainer := <-transitionChangeContainer
 			log.Debug("Transition for container finished", "task", task.Task, "container", changedContainer)
 			delete(transitionsMap, changedContainer)
+			// Rather than waiting for every transition in this batch, start
+			// anything the finished transition unblocked (e.g. a create
+			// following its pull, or a container waiting on a link)
+			task.startContainerTransitions(transitionsMap, transitionChange, transitionChangeContainer)
 			log.Debug("Still waiting for", "map", transitionsMap)
 		}
-		if task.DesiredStatus.Terminal() || task.KnownStatus.Terminal() {
+		desiredStatus := task.GetDesiredStatus()
+		knownStatus := task.GetKnownStatus()
+		if desiredStatus.Terminal() || knownStatus.Terminal() {
 			allWaitingOnPulled := true
 			for _, desired := range transitionsMap {
 				if desired != api.ContainerPulled {
@@ -414,23 +515,93 @@ func (task *managedTask) progressContainers() {
 	task.UpdateStatus()
 }
 
+// dependentsWaitExpired returns whether the container has waited for its
+// dependents to stop for as long as stopping them can take. After that a
+// dependent whose stop failed, and which is still running, is no longer
+// waited for.
+func (mtask *managedTask) dependentsWaitExpired(container *api.Container, dependents []*api.Container) bool {
+	if mtask.dependentsWaitStart == nil {
+		mtask.dependentsWaitStart = make(map[string]time.Time)
+	}
+	start, ok := mtask.dependentsWaitStart[container.Name]
+	if !ok {
+		start = ttime.Now()
+		mtask.dependentsWaitStart[container.Name] = start
+	}
+	var longest time.Duration
+	for _, dependent := range dependents {
+		if timeout := mtask.engine.stopTimeout(dependent); timeout > longest {
+			longest = timeout
+		}
+	}
+	return ttime.Since(start) > longest+stopContainerTimeout
+}
+
+// startContainerTransitions kicks off a transition for every container that
+// can move towards its desired status and is not already transitioning.
+// Transitions are recorded in transitionsMap and report completion on the
+// given channels. It returns true if any container was able to move.
+func (task *managedTask) startContainerTransitions(transitionsMap map[string]api.ContainerStatus, transitionChange chan<- bool, transitionChangeContainer chan<- string) bool {
+	anyCanTransition := false
+	for _, cont := range task.Containers {
+		if _, ok := transitionsMap[cont.Name]; ok {
+			continue
+		}
+		nextState, shouldCallTransitionFunc, canTransition := task.containerNextState(cont)
+		if !canTransition {
+			continue
+		}
+		// At least one container is able to be moved forwards, so we're not deadlocked
+		anyCanTransition = true
+
+		if !shouldCallTransitionFunc {
+			task.handleContainerChange(dockerContainerChange{cont, DockerContainerChangeEvent{Status: nextState}})
+			continue
+		}
+		transitionsMap[cont.Name] = nextState
+		container, nextStatus := cont, nextState
+		task.routines.Go("transition-"+container.Name, func(ctx context.Context) {
+			task.engine.transitionContainer(task.launchCtx, task.Task, container, nextStatus, task.launchSpan)
+			transitionChange <- true
+			transitionChangeContainer <- container.Name
+		})
+	}
+	return anyCanTransition
+}
+
 func (task *managedTask) cleanupTask() {
-	cleanupTime := ttime.After(task.KnownStatusTime.Add(taskStoppedDuration).Sub(ttime.Now()))
+	cleanupWait := task.engine.cfg.TaskCleanupWaitDuration
+	if cleanupWait <= 0 {
+		cleanupWait = taskStoppedDuration
+	}
+	cleanupTime := ttime.After(task.KnownStatusTime.Add(cleanupWait).Sub(ttime.Now()))
+	expedited := task.engine.cleanup.wait()
 	cleanupTimeBool := make(chan bool)
-	go func() {
-		<-cleanupTime
+	underPressure := false
+	task.routines.Go("cleanup-timer", func(ctx context.Context) {
+		select {
+		case <-cleanupTime:
+		case <-expedited:
+			underPressure = true
+		}
 		cleanupTimeBool <- true
 		close(cleanupTimeBool)
-	}()
+	})
 	for !task.waitEvent(cleanupTimeBool) {
 	}
-	log.Debug("Cleaning up task's containers and data", "task", task.Task)
+	log.Debug("Cleaning up task's containers and data", "task", task.Task, "diskPressure", underPressure)
 
 	// First make an attempt to cleanup resources
 	task.engine.sweepTask(task.Task)
-	task.engine.state.RemoveTask(task.Task)
-	// Now remove ourselves from the global state and cleanup channels
+	task.engine.verifyCleanup(task.Task)
+	if underPressure {
+		task.engine.removeTaskImages(task.Task)
+	}
+	// Now remove ourselves from the global state and cleanup channels. Both
+	// are removed under the lock so that a redelivery of the task sees it
+	// either wholly managed or wholly forgotten.
 	task.engine.processTasks.Lock()
+	task.engine.state.RemoveTask(task.Task)
 	delete(task.engine.managedTasks, task.Arn)
 	task.engine.processTasks.Unlock()
 	task.engine.saver.Save()
@@ -442,6 +613,15 @@ func (task *managedTask) cleanupTask() {
 
 	close(task.dockerMessages)
 	close(task.acsMessages)
+
+	// The task is gone whether or not its goroutines have exited, so they
+	// are only waited for in the background to report any which leaked
+	leaked := task.routines.Stop(taskRoutinesStopTimeout)
+	go func() {
+		if running := <-leaked; len(running) > 0 {
+			log.Warn("Task goroutines still running after cleanup", "task", task.Arn, "goroutines", running)
+		}
+	}()
 }
 
 func (task *managedTask) discardPendingMessages() {
@@ -0,0 +1,163 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+func TestContainerNextStatePullIgnoresDependencies(t *testing.T) {
+	linked := &api.Container{Name: "linked", DesiredStatus: api.ContainerRunning}
+	dependent := &api.Container{Name: "dependent", Links: []string{"linked:alias"}, DesiredStatus: api.ContainerRunning}
+	task := &managedTask{Task: &api.Task{Containers: []*api.Container{linked, dependent}}}
+
+	nextState, shouldCall, canTransition := task.containerNextState(dependent)
+	if nextState != api.ContainerPulled || !shouldCall || !canTransition {
+		t.Error("Expected a container to be pullable before its links are running", nextState)
+	}
+
+	dependent.KnownStatus = api.ContainerPulled
+	_, _, canTransition = task.containerNextState(dependent)
+	if canTransition {
+		t.Error("Expected create to wait for the linked container")
+	}
+
+	linked.KnownStatus = api.ContainerRunning
+	nextState, _, canTransition = task.containerNextState(dependent)
+	if nextState != api.ContainerCreated || !canTransition {
+		t.Error("Expected create once the linked container is running", nextState)
+	}
+}
+
+func TestContainerNextStateStopsDependentsFirst(t *testing.T) {
+	router := &api.Container{Name: "router", KnownStatus: api.ContainerRunning, DesiredStatus: api.ContainerStopped}
+	app := &api.Container{Name: "app", Links: []string{"router:logs"}, KnownStatus: api.ContainerRunning, DesiredStatus: api.ContainerStopped}
+	task := &managedTask{Task: &api.Task{Containers: []*api.Container{router, app}}, engine: NewDockerTaskEngine(&config.Config{})}
+
+	_, _, canTransition := task.containerNextState(router)
+	if canTransition {
+		t.Error("Expected the log router to wait for the app to stop")
+	}
+	nextState, shouldCall, canTransition := task.containerNextState(app)
+	if nextState != api.ContainerStopped || !shouldCall || !canTransition {
+		t.Error("Expected the app to stop first", nextState)
+	}
+
+	app.KnownStatus = api.ContainerStopped
+	nextState, shouldCall, canTransition = task.containerNextState(router)
+	if nextState != api.ContainerStopped || !shouldCall || !canTransition {
+		t.Error("Expected the log router to stop once the app has", nextState)
+	}
+}
+
+func TestContainerNextStateBoundsWaitForDependents(t *testing.T) {
+	testTime := ttime.NewTestTime()
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	router := &api.Container{Name: "router", KnownStatus: api.ContainerRunning, DesiredStatus: api.ContainerStopped}
+	// The app's stop failed, so it is still running
+	app := &api.Container{Name: "app", Links: []string{"router:logs"}, KnownStatus: api.ContainerRunning, DesiredStatus: api.ContainerStopped, StopTimeout: 10}
+	task := &managedTask{Task: &api.Task{Containers: []*api.Container{router, app}}, engine: NewDockerTaskEngine(&config.Config{})}
+
+	if _, _, canTransition := task.containerNextState(router); canTransition {
+		t.Error("Expected the log router to wait for the app to stop")
+	}
+	testTime.Warp(stopContainerTimeout)
+	if _, _, canTransition := task.containerNextState(router); canTransition {
+		t.Error("Expected the log router to wait out the app's stop timeout")
+	}
+	testTime.Warp(11 * time.Second)
+	nextState, shouldCall, canTransition := task.containerNextState(router)
+	if nextState != api.ContainerStopped || !shouldCall || !canTransition {
+		t.Error("Expected the log router to stop once the app took too long", nextState)
+	}
+}
+
+func TestUpdateUnmanagedTaskResumesKnownTask(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{})
+	// The task is known and stopped, but no longer managed
+	container := &api.Container{Name: "c1", KnownStatus: api.ContainerStopped, DesiredStatus: api.ContainerStopped}
+	task := &api.Task{
+		Arn:           "arn:aws:ecs:us-west-2:123456789012:task/abc",
+		KnownStatus:   api.TaskStopped,
+		DesiredStatus: api.TaskRunning,
+		Containers:    []*api.Container{container},
+	}
+	engine.state.AddTask(task)
+
+	update := &api.Task{Arn: task.Arn, DesiredStatus: api.TaskStopped, Containers: []*api.Container{&api.Container{Name: "c1"}}}
+	engine.processTasks.Lock()
+	engine.updateTask(task, update)
+	managed, ok := engine.managedTasks[task.Arn]
+	engine.processTasks.Unlock()
+
+	if !ok || managed.Task != task {
+		t.Fatal("Expected the known task to be managed again, not the update")
+	}
+	if task.DesiredStatus != api.TaskStopped {
+		t.Error("Expected the update's desired status to be applied, got", task.DesiredStatus)
+	}
+}
+
+func TestHandleContainerChangeRecordsOnlyFatalErrors(t *testing.T) {
+	engine := NewDockerTaskEngine(&config.Config{})
+	taskEvents, containerEvents := engine.TaskEvents()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-taskEvents:
+			case <-containerEvents:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	testCases := []struct {
+		name          string
+		desiredStatus api.ContainerStatus
+		event         DockerContainerChangeEvent
+		recorded      bool
+	}{
+		{"pull error", api.ContainerRunning, DockerContainerChangeEvent{Status: api.ContainerPulled, DockerContainerMetadata: DockerContainerMetadata{Error: CannotXContainerError{"pull", "not found"}}}, false},
+		{"stop error", api.ContainerStopped, DockerContainerChangeEvent{Status: api.ContainerStopped, DockerContainerMetadata: DockerContainerMetadata{Error: CannotXContainerError{"stop", "not running"}}}, false},
+		{"unexpected stop", api.ContainerRunning, DockerContainerChangeEvent{Status: api.ContainerStopped, DockerContainerMetadata: DockerContainerMetadata{Error: OutOfMemoryError{}}}, true},
+		{"start error", api.ContainerRunning, DockerContainerChangeEvent{Status: api.ContainerRunning, DockerContainerMetadata: DockerContainerMetadata{Error: CannotXContainerError{"start", "no such file"}}}, true},
+	}
+	for _, tc := range testCases {
+		container := &api.Container{Name: "c1", KnownStatus: api.ContainerCreated, DesiredStatus: tc.desiredStatus}
+		if tc.event.Status == api.ContainerPulled {
+			container.KnownStatus = api.ContainerStatusNone
+		}
+		task := &api.Task{
+			Arn:           "arn:aws:ecs:us-west-2:123456789012:task/" + tc.name,
+			KnownStatus:   api.TaskCreated,
+			DesiredStatus: api.TaskRunning,
+			Containers:    []*api.Container{container},
+		}
+		mtask := engine.newManagedTask(task)
+		mtask.handleContainerChange(dockerContainerChange{container: container, event: tc.event})
+		if recorded := container.ApplyingError != nil; recorded != tc.recorded {
+			t.Errorf("%s: expected the error to be recorded to be %v, got %v", tc.name, tc.recorded, container.ApplyingError)
+		}
+	}
+}
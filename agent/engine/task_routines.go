@@ -0,0 +1,127 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+const (
+	// taskRoutinesPerContainer and taskRoutinesBase make up the number of
+	// goroutines a task may have running at once before a warning is logged
+	taskRoutinesPerContainer = 4
+	taskRoutinesBase         = 4
+
+	// taskRoutinesStopTimeout is how long cleanup waits for a task's
+	// goroutines to exit before reporting them as leaked
+	taskRoutinesStopTimeout = 30 * time.Second
+)
+
+// taskRoutines tracks the goroutines spawned on behalf of a single task so
+// that task cleanup can cancel them and verify none of them outlive it.
+type taskRoutines struct {
+	taskArn string
+	budget  int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	lock          sync.Mutex
+	running       map[string]int
+	total         int
+	budgetWarning sync.Once
+}
+
+func newTaskRoutines(taskArn string, numContainers int) *taskRoutines {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &taskRoutines{
+		taskArn: taskArn,
+		budget:  taskRoutinesBase + taskRoutinesPerContainer*numContainers,
+		ctx:     ctx,
+		cancel:  cancel,
+		running: make(map[string]int),
+	}
+}
+
+// Go runs fn in a new, tracked goroutine. The context passed to fn is
+// cancelled when the task is cleaned up and fn should return promptly when
+// that happens.
+func (r *taskRoutines) Go(name string, fn func(ctx context.Context)) {
+	r.lock.Lock()
+	r.running[name]++
+	r.total++
+	overBudget := r.total > r.budget
+	r.lock.Unlock()
+	if overBudget {
+		r.budgetWarning.Do(func() {
+			log.Warn("Task exceeded its goroutine budget", "task", r.taskArn, "budget", r.budget, "running", r.Running())
+		})
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.done(name)
+		fn(r.ctx)
+	}()
+}
+
+func (r *taskRoutines) done(name string) {
+	r.lock.Lock()
+	r.running[name]--
+	if r.running[name] == 0 {
+		delete(r.running, name)
+	}
+	r.total--
+	r.lock.Unlock()
+	r.wg.Done()
+}
+
+// Running returns the number of running goroutines by name
+func (r *taskRoutines) Running() map[string]int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	running := make(map[string]int, len(r.running))
+	for name, count := range r.running {
+		running[name] = count
+	}
+	return running
+}
+
+// Stop cancels all of the task's goroutines and returns without waiting for
+// them to exit. The returned channel receives the goroutines which are still
+// running after timeout, or nil once they have all exited, whichever is first.
+func (r *taskRoutines) Stop(timeout time.Duration) <-chan map[string]int {
+	r.cancel()
+	leaked := make(chan map[string]int, 1)
+	stopped := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(stopped)
+	}()
+	go func() {
+		select {
+		case <-stopped:
+			leaked <- nil
+		case <-ttime.After(timeout):
+			leaked <- r.Running()
+		}
+	}()
+	return leaked
+}
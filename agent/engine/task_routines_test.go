@@ -0,0 +1,71 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestTaskRoutinesStopCancels(t *testing.T) {
+	routines := newTaskRoutines("arn", 1)
+	started := make(chan struct{})
+	routines.Go("waiter", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	})
+	<-started
+	if routines.Running()["waiter"] != 1 {
+		t.Error("Expected the waiter to be tracked", routines.Running())
+	}
+	if leaked := <-routines.Stop(time.Second); len(leaked) != 0 {
+		t.Error("Expected no leaked goroutines", leaked)
+	}
+	if len(routines.Running()) != 0 {
+		t.Error("Expected no running goroutines", routines.Running())
+	}
+}
+
+func TestTaskRoutinesStopReportsLeaks(t *testing.T) {
+	routines := newTaskRoutines("arn", 1)
+	block := make(chan struct{})
+	defer close(block)
+	routines.Go("stuck", func(ctx context.Context) {
+		<-block
+	})
+	leaked := <-routines.Stop(10 * time.Millisecond)
+	if leaked["stuck"] != 1 {
+		t.Error("Expected the stuck goroutine to be reported", leaked)
+	}
+}
+
+func TestTaskRoutinesStopDoesNotWait(t *testing.T) {
+	routines := newTaskRoutines("arn", 1)
+	block := make(chan struct{})
+	routines.Go("stuck", func(ctx context.Context) {
+		<-block
+	})
+	leaked := routines.Stop(time.Hour)
+	select {
+	case running := <-leaked:
+		t.Error("Expected the stuck goroutine to still be waited for", running)
+	default:
+	}
+	close(block)
+	if running := <-leaked; len(running) != 0 {
+		t.Error("Expected no leaked goroutines once the stuck one exited", running)
+	}
+}
@@ -14,6 +14,7 @@
 package engine
 
 import "fmt"
+import "time"
 import "github.com/aws/amazon-ecs-agent/agent/api"
 
 type ContainerNotFound struct {
@@ -37,6 +38,10 @@ type DockerContainerMetadata struct {
 	PortBindings []api.PortBinding
 	Error        error
 	Volumes      map[string]string
+	FinishedAt   time.Time
+
+	// LaunchPhases times the launch phases performed by a transition
+	LaunchPhases map[string]time.Duration
 }
 
 // ListContainersResponse encapsulates the response from the docker client for the
@@ -16,6 +16,7 @@ package eventhandler
 import (
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/eventstream"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 )
@@ -40,6 +41,7 @@ func HandleEngineEvents(taskEngine engine.TaskEngine, client api.ECSClient, save
 					break
 				}
 
+				eventstream.Default.ContainerStateChanged(event)
 				AddContainerEvent(event, client)
 			case event, open := <-taskEvents:
 				if !open {
@@ -48,6 +50,7 @@ func HandleEngineEvents(taskEngine engine.TaskEngine, client api.ECSClient, save
 					break
 				}
 
+				eventstream.Default.TaskStateChanged(event)
 				AddTaskEvent(event, client)
 			}
 		}
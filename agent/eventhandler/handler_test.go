@@ -42,6 +42,15 @@ func (m *MockECSClient) CredentialProvider() credentials.AWSCredentialProvider {
 func (m *MockECSClient) RegisterContainerInstance() (string, error) {
 	return "", nil
 }
+func (m *MockECSClient) DeregisterContainerInstance(string, string) error {
+	return nil
+}
+func (m *MockECSClient) DrainContainerInstance(string) error {
+	return nil
+}
+func (m *MockECSClient) ActivateContainerInstance(string) error {
+	return nil
+}
 func (m *MockECSClient) DiscoverPollEndpoint(string) (string, error) {
 	return "", nil
 }
@@ -54,6 +63,8 @@ func (m *MockECSClient) SubmitContainerStateChange(change api.ContainerStateChan
 func (m *MockECSClient) DiscoverTelemetryEndpoint(string) (string, error) {
 	return "", nil
 }
+func (m *MockECSClient) InvalidatePollEndpoints(string) {
+}
 
 func mockClient(task taskChangeFn, cont containerChangeFn) api.ECSClient {
 	return &MockECSClient{
@@ -313,11 +324,9 @@ func TestSendsEvents(t *testing.T) {
 
 	// Verify that a task doesn't get sent if we already have 'sent' it
 	task := taskEvent("alreadySent")
-	taskRunning := api.TaskRunning
-	task.SentStatus = &taskRunning
+	task.Task = &api.Task{SentStatus: api.TaskRunning}
 	cont := contEvent("alreadySent")
-	containerRunning := api.ContainerRunning
-	cont.SentStatus = &containerRunning
+	cont.Container = &api.Container{SentStatus: api.ContainerRunning}
 	AddContainerEvent(cont, client)
 	AddTaskEvent(task, client)
 	time.Sleep(5 * time.Millisecond)
@@ -334,10 +343,9 @@ func TestSendsEvents(t *testing.T) {
 	}
 
 	task = taskEvent("containerSent")
-	taskNone := api.TaskStatusNone
-	task.SentStatus = &taskNone
+	task.Task = &api.Task{}
 	cont = contEvent("containerSent")
-	cont.SentStatus = &containerRunning
+	cont.Container = &api.Container{SentStatus: api.ContainerRunning}
 	AddContainerEvent(cont, client)
 	AddTaskEvent(task, client)
 	// Expect to send a task status but not a container status
@@ -352,7 +360,7 @@ func TestSendsEvents(t *testing.T) {
 	if tsent.Status != api.TaskRunning {
 		t.Error("Wrong status")
 	}
-	if *task.SentStatus != api.TaskRunning {
+	if task.Task.GetSentStatus() != api.TaskRunning {
 		t.Error("Status not updated: ")
 	}
 
@@ -382,3 +390,26 @@ func TestShouldBeSent(t *testing.T) {
 		t.Error("Container should be sent if it's the first try")
 	}
 }
+
+func TestLocalTaskEventsNotSent(t *testing.T) {
+	client := mockClient(func(change api.TaskStateChange) utils.RetriableError {
+		t.Error("Local task change should not be sent", change)
+		return nil
+	}, func(change api.ContainerStateChange) utils.RetriableError {
+		t.Error("Local container change should not be sent", change)
+		return nil
+	})
+
+	arn := api.LocalTaskArnPrefix + "local"
+	change := contEvent(arn)
+	change.Container = &api.Container{}
+	AddContainerEvent(change, client)
+	taskChange := taskEvent(arn)
+	taskChange.Task = &api.Task{}
+	AddTaskEvent(taskChange, client)
+
+	contSent, taskSent := change.Container.GetSentStatus(), taskChange.Task.GetSentStatus()
+	if contSent != api.ContainerRunning || taskSent != api.TaskRunning {
+		t.Error("Local task changes should be marked sent", contSent, taskSent)
+	}
+}
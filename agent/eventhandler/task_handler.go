@@ -15,10 +15,10 @@ package eventhandler
 
 import (
 	"container/list"
-	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 )
 
 var handler *taskHandler
@@ -44,6 +44,17 @@ func addEvent(change *sendableEvent, client api.ECSClient) {
 	var preexisting bool
 	log.Info("Adding event", "change", change)
 
+	if api.IsLocalTaskArn(change.taskArn()) {
+		// The backend knows nothing of local tasks; consider the change sent
+		log.Debug("Not sending event for local task", "change", change)
+		if change.isContainerEvent && change.containerChange.Container != nil {
+			change.containerChange.Container.SetSentStatus(change.containerChange.Status)
+		} else if !change.isContainerEvent && change.taskChange.Task != nil {
+			change.taskChange.Task.SetSentStatus(change.taskChange.Status)
+		}
+		return
+	}
+
 	// TaskEvents lock scope
 	func() {
 		handler.Lock()
@@ -72,7 +83,7 @@ func addEvent(change *sendableEvent, client api.ECSClient) {
 // Continuously retries sending an event until it succeeds, sleeping between each
 // attempt
 func SubmitTaskEvents(events *eventList, client api.ECSClient) {
-	backoff := utils.NewSimpleBackoff(1*time.Second, 30*time.Second, 0.20, 1.3)
+	backoff := retry.ECS.NewBackoff()
 
 	// Mirror events.sending, but without the need to lock since this is local
 	// to our goroutine
@@ -82,7 +93,7 @@ func SubmitTaskEvents(events *eventList, client api.ECSClient) {
 		// If we looped back up here, we successfully submitted an event, but
 		// we haven't emptied the list so we should keep submitting
 		backoff.Reset()
-		utils.RetryWithBackoff(backoff, func() error {
+		backoff.Retry(func() error {
 			// Lock and unlock within this function, allowing the list to be added
 			// to while we're not actively sending an event
 			log.Debug("Waiting on semaphore to send...")
@@ -114,8 +125,8 @@ func SubmitTaskEvents(events *eventList, client api.ECSClient) {
 				if err == nil || !err.Retry() {
 					// submitted or can't be retried; ensure we don't retry it
 					event.containerSent = true
-					if event.containerChange.SentStatus != nil {
-						*event.containerChange.SentStatus = event.containerChange.Status
+					if event.containerChange.Container != nil {
+						event.containerChange.Container.SetSentStatus(event.containerChange.Status)
 					}
 					statesaver.Save()
 					if err != nil {
@@ -131,8 +142,8 @@ func SubmitTaskEvents(events *eventList, client api.ECSClient) {
 				if err == nil || !err.Retry() {
 					// submitted or can't be retried; ensure we don't retry it
 					event.taskSent = true
-					if event.taskChange.SentStatus != nil {
-						*event.taskChange.SentStatus = event.taskChange.Status
+					if event.taskChange.Task != nil {
+						event.taskChange.Task.SetSentStatus(event.taskChange.Status)
 					}
 					statesaver.Save()
 					if err != nil {
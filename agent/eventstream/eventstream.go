@@ -0,0 +1,261 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package eventstream streams task, container and health events as json over
+// a unix socket, so that daemons on the host can react to them without
+// polling the introspection api.
+//
+// Each connection is first sent the most recent events, then every event as
+// it happens, one json object per line. Events are numbered in order so that
+// a client which reconnects can skip those it has already seen.
+package eventstream
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/health"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+var log = logger.ForModule("eventstream")
+
+const (
+	// replaySize is how many of the most recent events a new connection is
+	// sent
+	replaySize = 1000
+	// clientBufferSize is how many events may be waiting to be written to a
+	// connection before it is considered too slow and closed
+	clientBufferSize = 256
+	// socketMode lets the agent's group read the stream
+	socketMode = 0660
+)
+
+// Types of events
+const (
+	EventTypeTask      = "task"
+	EventTypeContainer = "container"
+	EventTypeHealth    = "health"
+	EventTypeOOMKill   = "oomkill"
+	EventTypePids      = "pids"
+)
+
+// Event is a change in a task, container or component of the agent
+type Event struct {
+	Sequence uint64    `json:"sequence"`
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+
+	TaskArn       string `json:"taskArn,omitempty"`
+	ContainerName string `json:"containerName,omitempty"`
+	Component     string `json:"component,omitempty"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason,omitempty"`
+	ExitCode      *int   `json:"exitCode,omitempty"`
+	// OOMKills is how many processes were killed for running out of memory
+	OOMKills uint64 `json:"oomKills,omitempty"`
+	// Pids, PidsLimit and Zombies are how many processes and threads are in
+	// a container, its limit, and how many of its processes are zombies
+	Pids      uint64 `json:"pids,omitempty"`
+	PidsLimit uint64 `json:"pidsLimit,omitempty"`
+	Zombies   int    `json:"zombies,omitempty"`
+}
+
+// Stream broadcasts events to connected clients
+type Stream struct {
+	lock     sync.Mutex
+	sequence uint64
+	replay   []Event
+	clients  map[chan Event]bool
+}
+
+// Default is the stream the agent publishes its events to
+var Default = New()
+
+// New returns a Stream with no events and no clients
+func New() *Stream {
+	return &Stream{clients: make(map[chan Event]bool)}
+}
+
+// TaskStateChanged publishes a change in a task's status
+func (stream *Stream) TaskStateChanged(change api.TaskStateChange) {
+	stream.publish(Event{
+		Type:    EventTypeTask,
+		TaskArn: change.TaskArn,
+		Status:  change.Status.BackendStatus(),
+		Reason:  change.Reason,
+	})
+}
+
+// ContainerStateChanged publishes a change in a container's status
+func (stream *Stream) ContainerStateChanged(change api.ContainerStateChange) {
+	stream.publish(Event{
+		Type:          EventTypeContainer,
+		TaskArn:       change.TaskArn,
+		ContainerName: change.ContainerName,
+		Status:        change.Status.String(),
+		Reason:        change.Reason,
+		ExitCode:      change.ExitCode,
+	})
+}
+
+// ContainerOOMKilled publishes that processes in a container were killed for
+// running out of memory. The container keeps running unless its main process
+// was killed.
+func (stream *Stream) ContainerOOMKilled(taskArn, containerName string, kills uint64) {
+	stream.publish(Event{
+		Type:          EventTypeOOMKill,
+		TaskArn:       taskArn,
+		ContainerName: containerName,
+		Status:        "OOM_KILLED",
+		OOMKills:      kills,
+	})
+}
+
+// ContainerPidsPressure publishes that a container is approaching its pids
+// limit, after which it can't start new processes or threads
+func (stream *Stream) ContainerPidsPressure(taskArn, containerName string, pids, limit uint64, zombies int) {
+	stream.publish(Event{
+		Type:          EventTypePids,
+		TaskArn:       taskArn,
+		ContainerName: containerName,
+		Status:        "PIDS_PRESSURE",
+		Pids:          pids,
+		PidsLimit:     limit,
+		Zombies:       zombies,
+	})
+}
+
+// HealthChanged publishes a change in the health of a component of the
+// agent. It is a health.Listener.
+func (stream *Stream) HealthChanged(component string, status health.ComponentStatus) {
+	stream.publish(Event{
+		Type:      EventTypeHealth,
+		Component: component,
+		Status:    status.Status,
+		Reason:    status.Error,
+	})
+}
+
+func (stream *Stream) publish(event Event) {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+
+	stream.sequence++
+	event.Sequence = stream.sequence
+	event.Time = ttime.Now()
+	if len(stream.replay) == replaySize {
+		copy(stream.replay, stream.replay[1:])
+		stream.replay = stream.replay[:replaySize-1]
+	}
+	stream.replay = append(stream.replay, event)
+
+	for client := range stream.clients {
+		select {
+		case client <- event:
+		default:
+			// The client is too slow; closing it lets it reconnect and
+			// catch up from the replayed events
+			log.Warn("Event stream client is not keeping up; disconnecting it")
+			delete(stream.clients, client)
+			close(client)
+		}
+	}
+}
+
+// subscribe returns the events to replay and a channel of every later event
+func (stream *Stream) subscribe() ([]Event, chan Event) {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	replay := make([]Event, len(stream.replay))
+	copy(replay, stream.replay)
+	client := make(chan Event, clientBufferSize)
+	stream.clients[client] = true
+	return replay, client
+}
+
+func (stream *Stream) unsubscribe(client chan Event) {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	if stream.clients[client] {
+		delete(stream.clients, client)
+		close(client)
+	}
+}
+
+// Serve listens on a unix socket at path and streams events to each
+// connection until it closes. Any stale socket at path is removed first.
+func (stream *Stream) Serve(path string) error {
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	if err := os.Chmod(path, socketMode); err != nil {
+		return err
+	}
+	log.Info("Streaming events", "socket", path)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go stream.serveConn(conn)
+	}
+}
+
+func (stream *Stream) serveConn(conn net.Conn) {
+	defer conn.Close()
+	replay, client := stream.subscribe()
+	defer stream.unsubscribe(client)
+
+	// Notice the client closing the connection even while there are no
+	// events to write
+	closed := make(chan struct{})
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := conn.Read(buf); err != nil {
+				close(closed)
+				return
+			}
+		}
+	}()
+
+	encoder := json.NewEncoder(conn)
+	for _, event := range replay {
+		if err := encoder.Encode(&event); err != nil {
+			return
+		}
+	}
+	for {
+		select {
+		case event, ok := <-client:
+			if !ok {
+				return
+			}
+			if err := encoder.Encode(&event); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
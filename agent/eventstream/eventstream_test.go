@@ -0,0 +1,128 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package eventstream
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/health"
+)
+
+func readEvent(t *testing.T, reader *bufio.Reader) Event {
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		t.Fatal("Error reading event:", err)
+	}
+	var event Event
+	if err := json.Unmarshal(line, &event); err != nil {
+		t.Fatal("Error decoding event:", err)
+	}
+	return event
+}
+
+func TestStream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventstream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "events.sock")
+
+	stream := New()
+	exitCode := 1
+	stream.ContainerStateChanged(api.ContainerStateChange{TaskArn: "t1", ContainerName: "web", Status: api.ContainerStopped, ExitCode: &exitCode})
+	go stream.Serve(socket)
+
+	var conn net.Conn
+	for i := 0; i < 100; i++ {
+		if conn, err = net.Dial("unix", socket); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal("Unable to connect to event stream:", err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	// Events from before the connection are replayed
+	event := readEvent(t, reader)
+	if event.Sequence != 1 || event.Type != EventTypeContainer || event.TaskArn != "t1" || event.ContainerName != "web" || event.Status != "STOPPED" || event.ExitCode == nil || *event.ExitCode != 1 {
+		t.Error("Unexpected replayed event", event)
+	}
+
+	stream.TaskStateChanged(api.TaskStateChange{TaskArn: "t1", Status: api.TaskStopped, Reason: "Essential container exited"})
+	event = readEvent(t, reader)
+	if event.Sequence != 2 || event.Type != EventTypeTask || event.Status != "STOPPED" || event.Reason != "Essential container exited" {
+		t.Error("Unexpected task event", event)
+	}
+
+	registry := health.NewRegistry()
+	registry.AddListener(stream.HealthChanged)
+	registry.Report(health.ComponentDocker, errors.New("unreachable"))
+	event = readEvent(t, reader)
+	if event.Sequence != 3 || event.Type != EventTypeHealth || event.Component != health.ComponentDocker || event.Status != health.StatusUnhealthy || event.Reason != "unreachable" {
+		t.Error("Unexpected health event", event)
+	}
+
+	stream.ContainerOOMKilled("t1", "web", 2)
+	event = readEvent(t, reader)
+	if event.Sequence != 4 || event.Type != EventTypeOOMKill || event.ContainerName != "web" || event.OOMKills != 2 {
+		t.Error("Unexpected oom kill event", event)
+	}
+
+	stream.ContainerPidsPressure("t1", "web", 95, 100, 3)
+	event = readEvent(t, reader)
+	if event.Sequence != 5 || event.Type != EventTypePids || event.Pids != 95 || event.PidsLimit != 100 || event.Zombies != 3 {
+		t.Error("Unexpected pids event", event)
+	}
+}
+
+func TestStreamReplayIsBounded(t *testing.T) {
+	stream := New()
+	for i := 0; i < replaySize+10; i++ {
+		stream.TaskStateChanged(api.TaskStateChange{TaskArn: "t1", Status: api.TaskRunning})
+	}
+	replay, client := stream.subscribe()
+	defer stream.unsubscribe(client)
+	if len(replay) != replaySize || replay[0].Sequence != 11 || replay[replaySize-1].Sequence != replaySize+10 {
+		t.Error("Expected only the most recent events to be replayed", len(replay), replay[0].Sequence)
+	}
+}
+
+func TestStreamDisconnectsSlowClients(t *testing.T) {
+	stream := New()
+	_, client := stream.subscribe()
+	for i := 0; i < clientBufferSize+1; i++ {
+		stream.TaskStateChanged(api.TaskStateChange{TaskArn: "t1", Status: api.TaskRunning})
+	}
+	for i := 0; i < clientBufferSize; i++ {
+		<-client
+	}
+	if _, ok := <-client; ok {
+		t.Error("Expected a client which fell behind to be disconnected")
+	}
+	// Unsubscribing a disconnected client is harmless
+	stream.unsubscribe(client)
+}
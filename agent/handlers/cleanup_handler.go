@@ -0,0 +1,46 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+)
+
+// CleanupReporter reports what task cleanups left behind
+type CleanupReporter interface {
+	CleanupMetrics() engine.CleanupMetrics
+	OrphanedResources() []engine.OrphanedResource
+}
+
+type CleanupResponse struct {
+	Metrics engine.CleanupMetrics
+	Orphans []engine.OrphanedResource
+}
+
+// CleanupV1RequestHandlerMaker returns a handler for the 'v1/cleanup' API. It
+// reports how many task cleanups were verified and retried, and the most
+// recent containers, cgroups, mounts and directories they left behind.
+func CleanupV1RequestHandlerMaker(reporter CleanupReporter) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := CleanupResponse{
+			Metrics: reporter.CleanupMetrics(),
+			Orphans: reporter.OrphanedResources(),
+		}
+		responseJSON, _ := json.Marshal(&response)
+		w.Write(responseJSON)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+)
+
+type fixedCleanupReport CleanupResponse
+
+func (report fixedCleanupReport) CleanupMetrics() engine.CleanupMetrics {
+	return report.Metrics
+}
+
+func (report fixedCleanupReport) OrphanedResources() []engine.OrphanedResource {
+	return report.Orphans
+}
+
+func TestCleanupHandler(t *testing.T) {
+	request, err := http.NewRequest("GET", "http://localhost/v1/cleanup", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	CleanupV1RequestHandlerMaker(fixedCleanupReport{
+		Metrics: engine.CleanupMetrics{TasksVerified: 10, Retries: 3, Orphaned: 1},
+		Orphans: []engine.OrphanedResource{{TaskArn: "arn", Type: "container", Resource: "abc"}},
+	})(recorder, request)
+
+	var response CleanupResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Metrics.TasksVerified != 10 || response.Metrics.Orphaned != 1 || len(response.Orphans) != 1 || response.Orphans[0].Resource != "abc" {
+		t.Error("Wrong cleanup report", response)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+const (
+	containerNameQueryField = "container"
+	tailQueryField          = "tail"
+	followQueryField        = "follow"
+
+	defaultLogTail = 100
+	maxLogTail     = 10000
+
+	// logTailDuration and maxLogFollowDuration are how long logs are written,
+	// without and with following them, before the response is ended. The
+	// local api has no write timeout, so these are its only deadlines.
+	logTailDuration      = 5 * time.Second
+	maxLogFollowDuration = 5 * time.Minute
+)
+
+var errLogStreamClosed = errors.New("log stream closed")
+
+// ContainerLogsV1RequestHandlerMaker returns a handler for the
+// 'v1/containerlogs' API. It responds with the last lines of a container's
+// stdout and stderr, as plain text. The container is named by the 'dockerid'
+// query field, or by the 'taskarn' and 'container' fields, and must be one of
+// the agent's. The 'tail' field sets how many lines, 100 by default, and
+// 'follow=true' keeps writing new lines for up to maxLogFollowDuration. As
+// logs often hold what containers were given, it is only served on the local
+// api.
+func ContainerLogsV1RequestHandlerMaker(taskEngine engine.TaskEngine) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dockerTaskEngine, ok := taskEngine.(*engine.DockerTaskEngine)
+		if !ok {
+			w.WriteHeader(statusInternalServerError)
+			return
+		}
+		dockerID, ok := containerLogsDockerID(dockerTaskEngine, r)
+		if !ok {
+			w.WriteHeader(statusBadRequest)
+			return
+		}
+		tail := defaultLogTail
+		if tailValue, exists := valueFromRequest(r, tailQueryField); exists {
+			parsed, err := strconv.Atoi(tailValue)
+			if err != nil || parsed < 0 {
+				log.Warn("Invalid tail for container logs", "tail", tailValue)
+				w.WriteHeader(statusBadRequest)
+				return
+			}
+			tail = parsed
+		}
+		if tail > maxLogTail {
+			tail = maxLogTail
+		}
+		followValue, _ := valueFromRequest(r, followQueryField)
+		follow := followValue == "true"
+		deadline := logTailDuration
+		if follow {
+			deadline = maxLogFollowDuration
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		stream := &logStreamWriter{w: w}
+		done := make(chan error, 1)
+		go func() {
+			done <- dockerTaskEngine.ContainerLogs(dockerID, tail, follow, stream)
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				log.Warn("Error reading container logs", "dockerId", dockerID, "err", err)
+			}
+			stream.close(err)
+		case <-ttime.After(deadline):
+			// Docker stops streaming once its next write fails
+			stream.close(nil)
+		}
+	}
+}
+
+// containerLogsDockerID returns the docker id of the container the request
+// names, if it is one of the agent's
+func containerLogsDockerID(taskEngine *engine.DockerTaskEngine, r *http.Request) (string, bool) {
+	state := taskEngine.State()
+	dockerID, dockerIDExists := valueFromRequest(r, dockerIdQueryField)
+	taskArn, taskArnExists := valueFromRequest(r, taskArnQueryField)
+	if dockerIDExists == taskArnExists {
+		log.Info("Container logs request must contain exactly one of " + dockerIdQueryField + " and " + taskArnQueryField)
+		return "", false
+	}
+	if dockerIDExists {
+		if _, found := state.ContainerById(dockerID); !found {
+			log.Warn("Could not find requested container for logs", "dockerId", dockerID)
+			return "", false
+		}
+		return dockerID, true
+	}
+	containerName, _ := valueFromRequest(r, containerNameQueryField)
+	containers, _ := state.ContainerMapByArn(taskArn)
+	container, found := containers[containerName]
+	if !found || container.DockerId == "" {
+		log.Warn("Could not find requested container for logs", "task", taskArn, "container", containerName)
+		return "", false
+	}
+	return container.DockerId, true
+}
+
+// logStreamWriter writes logs to the response as they arrive. Once closed it
+// refuses further writes, as docker may still be streaming after the handler
+// has returned.
+type logStreamWriter struct {
+	lock    sync.Mutex
+	w       http.ResponseWriter
+	written bool
+	closed  bool
+}
+
+func (stream *logStreamWriter) Write(data []byte) (int, error) {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	if stream.closed {
+		return 0, errLogStreamClosed
+	}
+	stream.written = true
+	n, err := stream.w.Write(data)
+	if flusher, ok := stream.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
+
+// close ends the stream, reporting err as the response's status if nothing
+// has been written yet
+func (stream *logStreamWriter) close(err error) {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+	if err != nil && !stream.written {
+		stream.w.WriteHeader(statusInternalServerError)
+	}
+	stream.closed = true
+}
@@ -0,0 +1,169 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/golang/mock/gomock"
+)
+
+func containerLogsTestEngine(t *testing.T) (*mock_engine.MockDockerClient, engine.TaskEngine, *gomock.Controller) {
+	ctrl := gomock.NewController(t)
+	client := mock_engine.NewMockDockerClient(ctrl)
+	taskEngine := engine.NewTaskEngine(&config.Config{})
+	dockerTaskEngine := taskEngine.(*engine.DockerTaskEngine)
+	dockerTaskEngine.SetDockerClient(client)
+	container := &api.Container{Name: "c1"}
+	task := &api.Task{Arn: "task1", Containers: []*api.Container{container}}
+	dockerTaskEngine.State().AddTask(task)
+	dockerTaskEngine.State().AddContainer(&api.DockerContainer{DockerId: "docker1", DockerName: "someName", Container: container}, task)
+	return client, taskEngine, ctrl
+}
+
+func TestContainerLogs(t *testing.T) {
+	client, taskEngine, ctrl := containerLogsTestEngine(t)
+	defer ctrl.Finish()
+
+	client.EXPECT().ContainerLogs("docker1", 20, true, gomock.Any()).Do(func(dockerID string, tail int, follow bool, output io.Writer) {
+		output.Write([]byte("hello\n"))
+	}).Return(nil)
+
+	handler := ContainerLogsV1RequestHandlerMaker(taskEngine)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://localhost/v1/containerlogs?taskarn=task1&container=c1&tail=20&follow=true", nil)
+	handler(w, req)
+
+	if w.Code != statusOK {
+		t.Error("Unexpected status", w.Code)
+	}
+	if w.Body.String() != "hello\n" {
+		t.Error("Unexpected logs", w.Body.String())
+	}
+}
+
+func TestContainerLogsDefaultTail(t *testing.T) {
+	client, taskEngine, ctrl := containerLogsTestEngine(t)
+	defer ctrl.Finish()
+
+	client.EXPECT().ContainerLogs("docker1", defaultLogTail, false, gomock.Any()).Return(nil)
+
+	handler := ContainerLogsV1RequestHandlerMaker(taskEngine)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://localhost/v1/containerlogs?dockerid=docker1", nil)
+	handler(w, req)
+
+	if w.Code != statusOK {
+		t.Error("Unexpected status", w.Code)
+	}
+}
+
+func TestContainerLogsDeadline(t *testing.T) {
+	client, taskEngine, ctrl := containerLogsTestEngine(t)
+	defer ctrl.Finish()
+	testTime := ttime.NewTestTime()
+	testTime.LudicrousSpeed(true)
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	// Docker keeps following until a write fails
+	closed := make(chan error, 1)
+	client.EXPECT().ContainerLogs("docker1", defaultLogTail, true, gomock.Any()).Do(func(dockerID string, tail int, follow bool, output io.Writer) {
+		for {
+			if _, err := output.Write([]byte("line\n")); err != nil {
+				closed <- err
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}).Return(nil)
+
+	handler := ContainerLogsV1RequestHandlerMaker(taskEngine)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://localhost/v1/containerlogs?dockerid=docker1&follow=true", nil)
+	handler(w, req)
+
+	if w.Code != statusOK {
+		t.Error("Unexpected status", w.Code)
+	}
+	select {
+	case err := <-closed:
+		if err != errLogStreamClosed {
+			t.Error("Expected the stream to be closed", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected following to stop once the deadline passed")
+	}
+}
+
+func TestContainerLogsError(t *testing.T) {
+	client, taskEngine, ctrl := containerLogsTestEngine(t)
+	defer ctrl.Finish()
+
+	client.EXPECT().ContainerLogs("docker1", defaultLogTail, false, gomock.Any()).Return(errors.New("no such container"))
+
+	handler := ContainerLogsV1RequestHandlerMaker(taskEngine)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://localhost/v1/containerlogs?dockerid=docker1", nil)
+	handler(w, req)
+
+	if w.Code != statusInternalServerError {
+		t.Error("Unexpected status", w.Code)
+	}
+}
+
+func TestContainerLogsBadRequests(t *testing.T) {
+	_, taskEngine, ctrl := containerLogsTestEngine(t)
+	defer ctrl.Finish()
+
+	handler := ContainerLogsV1RequestHandlerMaker(taskEngine)
+	for _, query := range []string{
+		"",
+		"dockerid=docker1&taskarn=task1",
+		"dockerid=unmanaged",
+		"taskarn=task1&container=missing",
+		"dockerid=docker1&tail=-1",
+		"dockerid=docker1&tail=all",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "http://localhost/v1/containerlogs?"+query, nil)
+		handler(w, req)
+		if w.Code != statusBadRequest {
+			t.Error("Unexpected status for", query, w.Code)
+		}
+	}
+}
+
+func TestLogStreamWriterClosed(t *testing.T) {
+	w := httptest.NewRecorder()
+	stream := &logStreamWriter{w: w}
+	stream.Write([]byte("a"))
+	stream.close(nil)
+	if _, err := stream.Write([]byte("b")); err != errLogStreamClosed {
+		t.Error("Expected writes to fail once closed", err)
+	}
+	if w.Body.String() != "a" || !w.Flushed {
+		t.Error("Unexpected response", w.Body.String(), w.Flushed)
+	}
+}
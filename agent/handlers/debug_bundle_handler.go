@@ -0,0 +1,262 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/aws/amazon-ecs-agent/agent/version"
+)
+
+// maxBundledLogBytes bounds how much of each log file is bundled; the end
+// of larger files is kept
+const maxBundledLogBytes = 20 * 1024 * 1024
+
+// bundleRedactedFields are the fields whose values are removed from the
+// bundle, as secrets are often passed in them: the environments, commands
+// and overrides of tasks in the state file, and those of docker's containers
+var bundleRedactedFields = map[string]bool{
+	"environment": true,
+	"Environment": true,
+	"Env":         true,
+	"Command":     true,
+	"EntryPoint":  true,
+	"overrides":   true,
+	"Cmd":         true,
+	"Entrypoint":  true,
+	"Path":        true,
+	"Args":        true,
+}
+
+// DebugBundleV1RequestHandlerMaker returns a handler for the 'v1/debugbundle'
+// API. It responds with a gzipped tarball, for support cases, of:
+//
+//	logs/                the agent's log files
+//	state.json           the agent's saved state
+//	introspection/       the metadata and tasks introspection responses
+//	docker/info.json     docker's system-wide information
+//	docker/<name>.json   docker's inspect output for the task's containers,
+//	                     if the 'taskarn' query field names a task
+//
+// Environment variables and commands are redacted throughout. As the bundle
+// still shows what the agent runs, it is only served on the local api.
+func DebugBundleV1RequestHandlerMaker(containerInstanceArn *string, taskEngine engine.TaskEngine, cfg *config.Config) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dockerTaskEngine, ok := taskEngine.(*engine.DockerTaskEngine)
+		if !ok {
+			w.WriteHeader(statusInternalServerError)
+			return
+		}
+		taskArn, _ := valueFromRequest(r, taskArnQueryField)
+		if taskArn != "" {
+			if _, ok := dockerTaskEngine.State().TaskByArn(taskArn); !ok {
+				log.Warn("Could not find requested task for debug bundle", "task", taskArn)
+				w.WriteHeader(statusBadRequest)
+				return
+			}
+		}
+
+		name := "ecs-agent-debug-" + time.Now().UTC().Format("20060102T150405Z")
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.tar.gz"`)
+		err := writeDebugBundle(w, name, containerInstanceArn, dockerTaskEngine, cfg, taskArn)
+		if err != nil {
+			log.Warn("Error writing debug bundle", "err", err)
+		}
+	}
+}
+
+// debugBundle adds files under a directory of a tarball
+type debugBundle struct {
+	dir    string
+	writer *tar.Writer
+}
+
+// add adds a file to the bundle with secrets removed
+func (bundle *debugBundle) add(name string, data []byte) error {
+	data = []byte(logger.Sanitize(string(data)))
+	err := bundle.writer.WriteHeader(&tar.Header{
+		Name:    bundle.dir + "/" + name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = bundle.writer.Write(data)
+	return err
+}
+
+// addJSON adds value as indented json with the bundle's redacted fields
+// removed
+func (bundle *debugBundle) addJSON(name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return bundle.addRedactedJSON(name, data)
+}
+
+func (bundle *debugBundle) addRedactedJSON(name string, data []byte) error {
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	redacted, err := json.MarshalIndent(utils.Redact(decoded, bundleRedactedFields), "", "  ")
+	if err != nil {
+		return err
+	}
+	return bundle.add(name, redacted)
+}
+
+// addError records why part of the bundle is missing, so a failure to
+// collect one thing does not lose the rest
+func (bundle *debugBundle) addError(name string, err error) error {
+	return bundle.add(name+".error", []byte(err.Error()+"\n"))
+}
+
+func writeDebugBundle(w io.Writer, name string, containerInstanceArn *string, taskEngine *engine.DockerTaskEngine, cfg *config.Config, taskArn string) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	bundle := &debugBundle{dir: name, writer: tarWriter}
+
+	if err := addDebugBundleContents(bundle, containerInstanceArn, taskEngine, cfg, taskArn); err != nil {
+		return err
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+func addDebugBundleContents(bundle *debugBundle, containerInstanceArn *string, taskEngine *engine.DockerTaskEngine, cfg *config.Config, taskArn string) error {
+	state := taskEngine.State()
+	metadata := &MetadataResponse{
+		Cluster:              cfg.Cluster,
+		ContainerInstanceArn: containerInstanceArn,
+		Version:              version.String(),
+	}
+	if err := bundle.addJSON("introspection/metadata.json", metadata); err != nil {
+		return err
+	}
+	if err := bundle.addJSON("introspection/tasks.json", NewTasksResponse(state)); err != nil {
+		return err
+	}
+
+	if err := addStateFile(bundle, cfg); err != nil {
+		return err
+	}
+	if err := addLogFiles(bundle); err != nil {
+		return err
+	}
+
+	info, err := taskEngine.DockerInfo()
+	if err != nil {
+		err = bundle.addError("docker/info.json", err)
+	} else {
+		err = bundle.addJSON("docker/info.json", info.Map())
+	}
+	if err != nil {
+		return err
+	}
+
+	if taskArn == "" {
+		return nil
+	}
+	containers, _ := state.ContainerMapByArn(taskArn)
+	for containerName, container := range containers {
+		fileName := "docker/" + filepath.Base(containerName) + ".json"
+		inspected, err := taskEngine.InspectContainer(container.DockerId)
+		if err != nil {
+			err = bundle.addError(fileName, err)
+		} else {
+			err = bundle.addJSON(fileName, inspected)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addStateFile(bundle *debugBundle, cfg *config.Config) error {
+	if !cfg.Checkpoint {
+		return nil
+	}
+	data, err := ioutil.ReadFile(statemanager.StateFile(cfg.DataDir))
+	if err == nil {
+		err = bundle.addRedactedJSON("state.json", data)
+	}
+	if err != nil {
+		return bundle.addError("state.json", err)
+	}
+	return nil
+}
+
+// addLogFiles adds the agent's log file and its rotated logs
+func addLogFiles(bundle *debugBundle) error {
+	logFile := logger.LogFile()
+	if logFile == "" {
+		return nil
+	}
+	logFiles, err := filepath.Glob(logFile + "*")
+	if err != nil {
+		return err
+	}
+	for _, path := range logFiles {
+		data, err := readLogTail(path)
+		if err != nil {
+			err = bundle.addError("logs/"+filepath.Base(path), err)
+		} else {
+			err = bundle.add("logs/"+filepath.Base(path), data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readLogTail reads at most the last maxBundledLogBytes of a file
+func readLogTail(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxBundledLogBytes {
+		if _, err := file.Seek(-maxBundledLogBytes, os.SEEK_END); err != nil {
+			return nil, err
+		}
+	}
+	return ioutil.ReadAll(file)
+}
@@ -0,0 +1,122 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+)
+
+func readBundle(t *testing.T, body []byte) map[string]string {
+	gzipReader, err := gzip.NewReader(strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			break
+		}
+		data, _ := ioutil.ReadAll(tarReader)
+		// Strip the bundle's top level directory
+		files[strings.SplitN(header.Name, "/", 2)[1]] = string(data)
+	}
+	return files
+}
+
+func TestDebugBundle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_engine.NewMockDockerClient(ctrl)
+
+	dataDir, err := ioutil.TempDir("", "debugbundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	stateJSON := `{"Data":{"TaskEngine":{"Tasks":[{"Arn":"task1","Containers":[{"Name":"c1","environment":{"PASSWORD":"hunter2"},"Command":["--password","hunter2"],"overrides":{"command":["--password=hunter2"]}}]}]}}}`
+	ioutil.WriteFile(statemanager.StateFile(dataDir), []byte(stateJSON), 0600)
+
+	cfg := &config.Config{Cluster: TestClusterArn, Checkpoint: true, DataDir: dataDir}
+	taskEngine := engine.NewTaskEngine(cfg)
+	dockerTaskEngine := taskEngine.(*engine.DockerTaskEngine)
+	dockerTaskEngine.SetDockerClient(client)
+	container := &api.Container{Name: "c1"}
+	task := &api.Task{Arn: "task1", Containers: []*api.Container{container}}
+	dockerTaskEngine.State().AddTask(task)
+	dockerTaskEngine.State().AddContainer(&api.DockerContainer{DockerId: "docker1", DockerName: "someName", Container: container}, task)
+
+	client.EXPECT().Info().Return(nil, errors.New("docker unavailable"))
+	client.EXPECT().InspectContainer("docker1").Return(&docker.Container{
+		ID:     "docker1",
+		Path:   "/bin/app",
+		Args:   []string{"--password", "hunter2"},
+		Config: &docker.Config{Env: []string{"PASSWORD=hunter2"}, Cmd: []string{"--password", "hunter2"}},
+	}, nil)
+
+	handler := DebugBundleV1RequestHandlerMaker(utils.Strptr(TestContainerInstanceArn), taskEngine, cfg)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://localhost/v1/debugbundle?taskarn=task1", nil)
+	handler(w, req)
+
+	if w.Code != statusOK {
+		t.Fatal("Unexpected status", w.Code)
+	}
+	files := readBundle(t, w.Body.Bytes())
+	for _, name := range []string{"introspection/metadata.json", "introspection/tasks.json", "state.json", "docker/info.json.error", "docker/c1.json"} {
+		if _, ok := files[name]; !ok {
+			t.Error("Expected bundle to contain", name)
+		}
+	}
+	for name, contents := range files {
+		if strings.Contains(contents, "hunter2") {
+			t.Error("Expected environment and commands to be redacted from", name)
+		}
+	}
+	if !strings.Contains(files["state.json"], `"PASSWORD": "[REDACTED]"`) {
+		t.Error("Expected environment names to be kept", files["state.json"])
+	}
+	if !strings.Contains(files["introspection/tasks.json"], `"task1"`) {
+		t.Error("Expected the tasks response", files["introspection/tasks.json"])
+	}
+}
+
+func TestDebugBundleUnknownTask(t *testing.T) {
+	cfg := &config.Config{}
+	handler := DebugBundleV1RequestHandlerMaker(utils.Strptr(TestContainerInstanceArn), engine.NewTaskEngine(cfg), cfg)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://localhost/v1/debugbundle?taskarn=unknown", nil)
+	handler(w, req)
+	if w.Code != statusBadRequest {
+		t.Error("Expected a bad request for an unknown task", w.Code)
+	}
+}
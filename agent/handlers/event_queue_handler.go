@@ -0,0 +1,39 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+)
+
+// EventQueueReporter reports how many docker events are waiting to be
+// processed
+type EventQueueReporter interface {
+	EventQueueDepth() engine.EventQueueDepth
+}
+
+// EventQueueV1RequestHandlerMaker returns a handler for the 'v1/eventqueue'
+// API. It reports how many docker events are waiting to be processed, by
+// priority, and the most that have waited at once, so that an agent slow to
+// notice stopped containers can be diagnosed.
+func EventQueueV1RequestHandlerMaker(reporter EventQueueReporter) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		depth := reporter.EventQueueDepth()
+		responseJSON, _ := json.Marshal(&depth)
+		w.Write(responseJSON)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+)
+
+type fixedEventQueue engine.EventQueueDepth
+
+func (queue fixedEventQueue) EventQueueDepth() engine.EventQueueDepth {
+	return engine.EventQueueDepth(queue)
+}
+
+func TestEventQueueHandler(t *testing.T) {
+	request, err := http.NewRequest("GET", "http://localhost/v1/eventqueue", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	EventQueueV1RequestHandlerMaker(fixedEventQueue{Stop: 2, Other: 40, MaxOther: 120})(recorder, request)
+
+	var response engine.EventQueueDepth
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Stop != 2 || response.Other != 40 || response.MaxOther != 120 {
+		t.Error("Wrong event queue depth", response)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/health"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const statusServiceUnavailable = 503
+
+// defaultInstanceHealthChecks are the checks run if the config names none
+var defaultInstanceHealthChecks = []string{"docker", "disk"}
+
+// instanceHealthChecks makes each of the checks which may be configured, by
+// name, returning the component it reports on and the check
+var instanceHealthChecks = map[string]func(engine.TaskEngine, *config.Config) (string, func() error){
+	"docker": func(taskEngine engine.TaskEngine, cfg *config.Config) (string, func() error) {
+		return health.ComponentDocker, func() error {
+			_, err := taskEngine.Version()
+			return err
+		}
+	},
+	"disk": func(taskEngine engine.TaskEngine, cfg *config.Config) (string, func() error) {
+		return health.ComponentDisk, func() error {
+			if err := health.CheckDiskSpace(cfg.DataDir, health.MinFreeDiskBytes); err != nil {
+				return err
+			}
+			if cfg.DiskUnhealthyThreshold <= 0 {
+				return nil
+			}
+			for _, path := range []string{cfg.DataDir, cfg.DockerGraphPath} {
+				if err := health.CheckDiskUsage(path, cfg.DiskUnhealthyThreshold); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	},
+	"selinux": func(taskEngine engine.TaskEngine, cfg *config.Config) (string, func() error) {
+		return health.ComponentSELinux, func() error {
+			return health.CheckSELinux(func() (string, error) {
+				dockerInfo, ok := taskEngine.(interface {
+					DockerInfo() (*docker.Env, error)
+				})
+				if !ok {
+					return "", errors.New("Docker's security options are unavailable")
+				}
+				info, err := dockerInfo.DockerInfo()
+				if err != nil {
+					return "", err
+				}
+				return info.Get("SecurityOptions"), nil
+			})
+		}
+	},
+	"cni": func(taskEngine engine.TaskEngine, cfg *config.Config) (string, func() error) {
+		return health.ComponentCNI, func() error {
+			return health.CheckCNIPlugins(cfg.CNIPluginsPath)
+		}
+	},
+}
+
+type HealthcheckResponse struct {
+	Healthy    bool
+	Components map[string]health.ComponentStatus
+}
+
+// AddHealthChecks registers the instance health checks named in cfg, by
+// default that docker is reachable and that the data directory has space. If
+// cfg sets DiskUnhealthyThreshold, the disk is also unhealthy when the data
+// directory's or docker's filesystem is fuller than it. The selinux check
+// fails if SELinux is enforcing but docker does not support it, and the cni
+// check if there are no CNI plugins in cfg's CNIPluginsPath.
+func AddHealthChecks(registry *health.Registry, taskEngine engine.TaskEngine, cfg *config.Config) {
+	checks := cfg.InstanceHealthChecks
+	if checks == nil {
+		checks = defaultInstanceHealthChecks
+	}
+	for _, name := range checks {
+		makeCheck, ok := instanceHealthChecks[name]
+		if !ok {
+			log.Warn("Ignoring unknown instance health check", "check", name)
+			continue
+		}
+		registry.AddCheck(makeCheck(taskEngine, cfg))
+	}
+}
+
+// HealthcheckRequestHandlerMaker returns a handler which runs the registry's
+// checks and then reports the health of each of the agent's components. It
+// responds with 503 if any component is unhealthy.
+func HealthcheckRequestHandlerMaker(registry *health.Registry) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		registry.RunChecks()
+		components, healthy := registry.Status()
+		responseJSON, _ := json.Marshal(&HealthcheckResponse{Healthy: healthy, Components: components})
+		if !healthy {
+			w.WriteHeader(statusServiceUnavailable)
+		}
+		w.Write(responseJSON)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/health"
+	"github.com/golang/mock/gomock"
+)
+
+func TestHealthcheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+
+	dataDir, err := ioutil.TempDir("", "healthcheck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	registry := health.NewRegistry()
+	registry.Report(health.ComponentACS, nil)
+	AddHealthChecks(registry, taskEngine, &config.Config{DataDir: dataDir})
+	handler := HealthcheckRequestHandlerMaker(registry)
+
+	taskEngine.EXPECT().Version().Return("DockerVersion: 1.7.1", nil)
+	recorder := httptest.NewRecorder()
+	handler(recorder, nil)
+	if recorder.Code != statusOK {
+		t.Error("Expected healthy response, got", recorder.Code)
+	}
+	var response HealthcheckResponse
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	if !response.Healthy || len(response.Components) != 3 {
+		t.Error("Unexpected healthcheck response", recorder.Body.String())
+	}
+	if response.Components[health.ComponentDocker].Status != health.StatusHealthy {
+		t.Error("Expected docker to be healthy", response.Components[health.ComponentDocker])
+	}
+
+	taskEngine.EXPECT().Version().Return("", errors.New("unreachable"))
+	recorder = httptest.NewRecorder()
+	handler(recorder, nil)
+	if recorder.Code != statusServiceUnavailable {
+		t.Error("Expected unhealthy response, got", recorder.Code)
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	if response.Healthy || response.Components[health.ComponentDocker].Error != "unreachable" {
+		t.Error("Unexpected healthcheck response", recorder.Body.String())
+	}
+}
+
+func TestConfiguredHealthChecks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+
+	cniDir, err := ioutil.TempDir("", "cni")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cniDir)
+
+	registry := health.NewRegistry()
+	AddHealthChecks(registry, taskEngine, &config.Config{InstanceHealthChecks: []string{"cni", "unknown"}, CNIPluginsPath: cniDir})
+	registry.RunChecks()
+	components, healthy := registry.Status()
+	if healthy || len(components) != 1 {
+		t.Error("Expected only an unhealthy CNI check", components)
+	}
+	if components[health.ComponentCNI].Status != health.StatusUnhealthy {
+		t.Error("Expected CNI to be unhealthy without plugins", components[health.ComponentCNI])
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+)
+
+const (
+	imageQueryField = "image"
+	statusNotFound  = 404
+
+	// maxPrePullRequestSize bounds the size of a posted list of images
+	maxPrePullRequestSize = 64 * 1024
+)
+
+// ImagePrePuller pulls images ahead of the tasks which use them
+type ImagePrePuller interface {
+	PrePullImages(images []string) error
+	PrePulledImages() []engine.PrePulledImage
+	ReleasePrePulledImage(image string) bool
+}
+
+// PrePullRequest is the body of a request to pre-pull images
+type PrePullRequest struct {
+	Images []string
+}
+
+type PrePulledImagesResponse struct {
+	Images []engine.PrePulledImage
+	Error  string `json:",omitempty"`
+}
+
+// ImagesV1RequestHandlerMaker returns a handler for the 'v1/images' API,
+// which warms the instance's image cache. It lists the pre-pulled images and
+// whether they have been pulled. If changes are allowed, as they are only on
+// the local api socket, a POST of {"Images": [...]} pulls each image in the
+// background, up to engine.MaxPrePulledImages; pre-pulled images are not
+// removed when tasks are cleaned up until a DELETE with ?image= releases them.
+// Images pre-pulled this way are forgotten when the agent restarts;
+// ECS_PREPULL_IMAGES lists images pulled every time it starts.
+func ImagesV1RequestHandlerMaker(prePuller ImagePrePuller, allowChanges bool) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := statusOK
+		var response PrePulledImagesResponse
+		switch r.Method {
+		case "POST", "PUT", "DELETE":
+			if !allowChanges {
+				status = statusMethodNotAllowed
+				response.Error = "Images can only be pre-pulled or released through the local api"
+				break
+			}
+			if r.Method == "DELETE" {
+				status, response.Error = releasePrePulledImage(prePuller, r)
+				break
+			}
+			var request PrePullRequest
+			if err := json.NewDecoder(io.LimitReader(r.Body, maxPrePullRequestSize)).Decode(&request); err != nil {
+				status = statusBadRequest
+				response.Error = "Invalid request: " + err.Error()
+				break
+			}
+			log.Info("Pre-pulling images", "images", request.Images)
+			if err := prePuller.PrePullImages(request.Images); err != nil {
+				status = statusBadRequest
+				response.Error = err.Error()
+			}
+		}
+		response.Images = prePuller.PrePulledImages()
+		responseJSON, _ := json.Marshal(&response)
+		w.WriteHeader(status)
+		w.Write(responseJSON)
+	}
+}
+
+func releasePrePulledImage(prePuller ImagePrePuller, r *http.Request) (int, string) {
+	image, _ := valueFromRequest(r, imageQueryField)
+	if !prePuller.ReleasePrePulledImage(image) {
+		return statusNotFound, "Image " + image + " was not pre-pulled"
+	}
+	log.Info("Released pre-pulled image", "image", image)
+	return statusOK, ""
+}
@@ -0,0 +1,133 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+)
+
+// fakePrePuller records the images it is asked to pull as pulled
+type fakePrePuller struct {
+	images []engine.PrePulledImage
+}
+
+func (prePuller *fakePrePuller) PrePullImages(images []string) error {
+	for _, image := range images {
+		if len(prePuller.images) >= engine.MaxPrePulledImages {
+			return engine.ErrTooManyPrePulledImages
+		}
+		prePuller.images = append(prePuller.images, engine.PrePulledImage{Image: image, Status: engine.PrePullPulled})
+	}
+	return nil
+}
+
+func (prePuller *fakePrePuller) PrePulledImages() []engine.PrePulledImage {
+	return prePuller.images
+}
+
+func (prePuller *fakePrePuller) ReleasePrePulledImage(image string) bool {
+	for i, prePulled := range prePuller.images {
+		if prePulled.Image == image {
+			prePuller.images = append(prePuller.images[:i], prePuller.images[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func imagesRequest(t *testing.T, prePuller ImagePrePuller, method, query, body string) (int, PrePulledImagesResponse) {
+	return imagesRequestTo(t, ImagesV1RequestHandlerMaker(prePuller, true), method, query, body)
+}
+
+func imagesRequestTo(t *testing.T, handler func(http.ResponseWriter, *http.Request), method, query, body string) (int, PrePulledImagesResponse) {
+	request, err := http.NewRequest(method, "http://localhost/v1/images?"+query, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+	var response PrePulledImagesResponse
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	return recorder.Code, response
+}
+
+func TestImagesHandler(t *testing.T) {
+	prePuller := &fakePrePuller{}
+
+	code, response := imagesRequest(t, prePuller, "POST", "", `{"Images":["busybox","nginx"]}`)
+	if code != statusOK || len(response.Images) != 2 || response.Images[1].Image != "nginx" {
+		t.Error("Expected images to be pre-pulled", code, response)
+	}
+
+	code, response = imagesRequest(t, prePuller, "POST", "", `busybox`)
+	if code != statusBadRequest || response.Error == "" {
+		t.Error("Expected an invalid request to be rejected", code, response)
+	}
+
+	code, response = imagesRequest(t, prePuller, "DELETE", "image=busybox", "")
+	if code != statusOK || len(response.Images) != 1 {
+		t.Error("Expected the image to be released", code, response)
+	}
+
+	code, response = imagesRequest(t, prePuller, "DELETE", "image=busybox", "")
+	if code != statusNotFound || response.Error == "" {
+		t.Error("Expected releasing an image which isn't pre-pulled to fail", code, response)
+	}
+
+	code, response = imagesRequest(t, prePuller, "GET", "", "")
+	if code != statusOK || len(response.Images) != 1 || response.Images[0].Image != "nginx" {
+		t.Error("Unexpected images", code, response)
+	}
+}
+
+func TestImagesHandlerChangesOnlyAllowedLocally(t *testing.T) {
+	prePuller := &fakePrePuller{}
+	handler := ImagesV1RequestHandlerMaker(prePuller, false)
+
+	code, response := imagesRequestTo(t, handler, "POST", "", `{"Images":["busybox"]}`)
+	if code != statusMethodNotAllowed || response.Error == "" || len(prePuller.images) != 0 {
+		t.Error("Expected pre-pulling to be refused", code, response)
+	}
+
+	prePuller.PrePullImages([]string{"busybox"})
+	code, response = imagesRequestTo(t, handler, "DELETE", "image=busybox", "")
+	if code != statusMethodNotAllowed || response.Error == "" || len(prePuller.images) != 1 {
+		t.Error("Expected releasing to be refused", code, response)
+	}
+
+	code, response = imagesRequestTo(t, handler, "GET", "", "")
+	if code != statusOK || len(response.Images) != 1 {
+		t.Error("Expected images to be listed", code, response)
+	}
+}
+
+func TestImagesHandlerBounded(t *testing.T) {
+	prePuller := &fakePrePuller{}
+	images := make([]string, engine.MaxPrePulledImages+1)
+	for i := range images {
+		images[i] = "\"image" + strconv.Itoa(i) + "\""
+	}
+
+	code, response := imagesRequest(t, prePuller, "POST", "", `{"Images":[`+strings.Join(images, ",")+`]}`)
+	if code != statusBadRequest || response.Error == "" || len(response.Images) != engine.MaxPrePulledImages {
+		t.Error("Expected too many images to be refused", code, response.Error, len(response.Images))
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+)
+
+const statusMethodNotAllowed = 405
+
+// maxLocalTaskSize bounds the size of a posted task
+const maxLocalTaskSize = 1024 * 1024
+
+// localTaskIDPattern matches the ids newLocalTaskArn generates. The id names
+// the task's directories on the host, so no other id is accepted.
+var localTaskIDPattern = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// LocalTaskResponse is the response to posting a local task
+type LocalTaskResponse struct {
+	Arn   string `json:",omitempty"`
+	Error string `json:",omitempty"`
+}
+
+// LocalTasksV1RequestHandlerMaker returns a handler for the 'v1/localtasks'
+// API of the local api socket, which runs a task without the backend so that the agent's whole launch
+// pipeline can be exercised on a single host. A task is posted as json in the
+// form ACS sends it, with a family, version and containers. Its arn and
+// desiredStatus may be omitted; an arn is generated and the task is started.
+// Posting the task again with its arn and a desiredStatus of STOPPED stops
+// it. Local tasks are listed by the 'v1/tasks' API like any other.
+func LocalTasksV1RequestHandlerMaker(taskEngine engine.TaskEngine) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			writeLocalTaskResponse(w, statusMethodNotAllowed, &LocalTaskResponse{Error: "Local tasks must be posted"})
+			return
+		}
+		var acsTask ecsacs.Task
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxLocalTaskSize)).Decode(&acsTask); err != nil {
+			writeLocalTaskResponse(w, statusBadRequest, &LocalTaskResponse{Error: "Invalid task: " + err.Error()})
+			return
+		}
+		if acsTask.Arn == nil || *acsTask.Arn == "" {
+			arn, err := newLocalTaskArn()
+			if err != nil {
+				writeLocalTaskResponse(w, statusInternalServerError, &LocalTaskResponse{Error: err.Error()})
+				return
+			}
+			acsTask.Arn = &arn
+		} else if !api.IsLocalTaskArn(*acsTask.Arn) || !localTaskIDPattern.MatchString(strings.TrimPrefix(*acsTask.Arn, api.LocalTaskArnPrefix)) {
+			writeLocalTaskResponse(w, statusBadRequest, &LocalTaskResponse{Error: "Local task arns must be " + api.LocalTaskArnPrefix + " followed by a hex id"})
+			return
+		}
+		if acsTask.DesiredStatus == nil {
+			running := "RUNNING"
+			acsTask.DesiredStatus = &running
+		}
+
+		task, err := api.TaskFromACS(&acsTask, &ecsacs.PayloadMessage{})
+		if err != nil {
+			writeLocalTaskResponse(w, statusBadRequest, &LocalTaskResponse{Arn: *acsTask.Arn, Error: err.Error()})
+			return
+		}
+		log.Info("Adding local task", "task", task.Arn, "desiredStatus", task.DesiredStatus.String())
+		if err := taskEngine.AddTask(task); err != nil {
+			writeLocalTaskResponse(w, statusInternalServerError, &LocalTaskResponse{Arn: task.Arn, Error: err.Error()})
+			return
+		}
+		writeLocalTaskResponse(w, statusOK, &LocalTaskResponse{Arn: task.Arn})
+	}
+}
+
+func writeLocalTaskResponse(w http.ResponseWriter, status int, response *LocalTaskResponse) {
+	responseJSON, _ := json.Marshal(response)
+	w.WriteHeader(status)
+	w.Write(responseJSON)
+}
+
+// newLocalTaskArn returns a random arn for a local task
+func newLocalTaskArn() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return api.LocalTaskArnPrefix + hex.EncodeToString(id), nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/golang/mock/gomock"
+)
+
+func postLocalTask(t *testing.T, handler func(http.ResponseWriter, *http.Request), body string) (*httptest.ResponseRecorder, LocalTaskResponse) {
+	request, err := http.NewRequest("POST", "/v1/localtasks", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+	var response LocalTaskResponse
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	return recorder, response
+}
+
+func TestLocalTasksHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	handler := LocalTasksV1RequestHandlerMaker(taskEngine)
+
+	var added *api.Task
+	taskEngine.EXPECT().AddTask(gomock.Any()).Do(func(task *api.Task) { added = task }).Return(nil)
+	recorder, response := postLocalTask(t, handler, `{"family":"test","version":"1","containers":[{"name":"web","image":"nginx","essential":true}]}`)
+	if recorder.Code != statusOK {
+		t.Fatal("Expected task to be added, got", recorder.Code, recorder.Body.String())
+	}
+	if !api.IsLocalTaskArn(response.Arn) || added.Arn != response.Arn {
+		t.Error("Expected a local task arn", response.Arn)
+	}
+	if added.DesiredStatus != api.TaskRunning || added.Family != "test" || len(added.Containers) != 1 || added.Containers[0].Image != "nginx" {
+		t.Error("Unexpected task added", added)
+	}
+
+	taskEngine.EXPECT().AddTask(gomock.Any()).Do(func(task *api.Task) { added = task }).Return(nil)
+	recorder, _ = postLocalTask(t, handler, `{"arn":"`+response.Arn+`","family":"test","version":"1","desiredStatus":"STOPPED"}`)
+	if recorder.Code != statusOK || added.Arn != response.Arn || added.DesiredStatus != api.TaskStopped {
+		t.Error("Expected task to be stopped", recorder.Code, added)
+	}
+}
+
+func TestLocalTasksHandlerInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	handler := LocalTasksV1RequestHandlerMaker(taskEngine)
+
+	for _, body := range []string{
+		`not json`,
+		`{"version":"1"}`,
+		`{"arn":"arn:aws:ecs:us-west-2:123456789012:task/real","family":"test","version":"1"}`,
+		`{"arn":"` + api.LocalTaskArnPrefix + `","family":"test","version":"1"}`,
+		`{"arn":"` + api.LocalTaskArnPrefix + `..","family":"test","version":"1"}`,
+		`{"arn":"` + api.LocalTaskArnPrefix + `a/../../x","family":"test","version":"1"}`,
+		`{"arn":"` + api.LocalTaskArnPrefix + `ABC","family":"test","version":"1"}`,
+	} {
+		recorder, response := postLocalTask(t, handler, body)
+		if recorder.Code != statusBadRequest || response.Error == "" {
+			t.Error("Expected bad request for", body, recorder.Code, response)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, &http.Request{Method: "GET"})
+	if recorder.Code != statusMethodNotAllowed {
+		t.Error("Expected only posts to be allowed, got", recorder.Code)
+	}
+}
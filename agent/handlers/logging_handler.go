@@ -13,7 +13,11 @@
 
 package handlers
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+)
 
 type LoggingHandler struct{ h http.Handler }
 
@@ -21,3 +25,35 @@ func (lh LoggingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	log.Info("Handling http request", "method", r.Method, "from", r.RemoteAddr, "uri", r.RequestURI)
 	lh.h.ServeHTTP(w, r)
 }
+
+// SanitizingHandler removes secrets from the responses of the handler it
+// wraps. Gzipped responses, such as debug bundles, are sanitized as they're
+// built instead.
+type SanitizingHandler struct{ h http.Handler }
+
+func (sh SanitizingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sh.h.ServeHTTP(sanitizingResponseWriter{w}, r)
+}
+
+type sanitizingResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w sanitizingResponseWriter) Write(data []byte) (int, error) {
+	if w.Header().Get("Content-Type") == "application/gzip" {
+		return w.ResponseWriter.Write(data)
+	}
+	if _, err := w.ResponseWriter.Write([]byte(logger.Sanitize(string(data)))); err != nil {
+		return 0, err
+	}
+	// Callers check the length of what they asked to write
+	return len(data), nil
+}
+
+// Flush sends buffered data to the client, so that streamed responses such as
+// followed container logs arrive as they're written
+func (w sanitizingResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
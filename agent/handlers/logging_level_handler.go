@@ -0,0 +1,98 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+)
+
+const (
+	moduleQueryField    = "module"
+	levelQueryField     = "level"
+	debugDumpQueryField = "debugdump"
+
+	// defaultLevelValue resets a module to the agent's log level
+	defaultLevelValue = "default"
+
+	// maxDebugDumpDuration bounds how long everything may be logged at debug,
+	// so that a forgotten dump doesn't fill the disk
+	maxDebugDumpDuration = time.Hour
+)
+
+type LoggingResponse struct {
+	Level   string
+	Modules map[string]string
+	Error   string `json:",omitempty"`
+}
+
+// LoggingV1RequestHandlerMaker returns a handler for the 'v1/logging' API.
+// It reports the agent's log level and any per-module levels. If changes are
+// allowed, as they are only on the local api socket, a POST or PUT changes
+// them:
+//
+//	?level=debug                 sets the agent's log level
+//	?module=engine&level=debug   sets the level of modules matching 'engine'
+//	?module=engine&level=default returns those modules to the agent's level
+//	?debugdump=10m               logs everything at debug for ten minutes, up
+//	                             to an hour
+func LoggingV1RequestHandlerMaker(allowChanges bool) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := statusOK
+		var response LoggingResponse
+		if (r.Method == "POST" || r.Method == "PUT") && !allowChanges {
+			status = statusMethodNotAllowed
+			response.Error = "Log levels can only be changed through the local api"
+		} else if r.Method == "POST" || r.Method == "PUT" {
+			if err := updateLogLevels(r); err != nil {
+				status = statusBadRequest
+				response.Error = err.Error()
+			}
+		}
+		response.Level, response.Modules = logger.Levels()
+		responseJSON, _ := json.Marshal(&response)
+		w.WriteHeader(status)
+		w.Write(responseJSON)
+	}
+}
+
+func updateLogLevels(r *http.Request) error {
+	if debugDump, ok := valueFromRequest(r, debugDumpQueryField); ok {
+		duration, err := time.ParseDuration(debugDump)
+		if err != nil {
+			return err
+		}
+		if duration <= 0 || duration > maxDebugDumpDuration {
+			return errors.New("debug dumps must last between 0 and " + maxDebugDumpDuration.String())
+		}
+		logger.StartDebugDump(duration)
+	}
+	level, levelExists := valueFromRequest(r, levelQueryField)
+	if !levelExists {
+		return nil
+	}
+	module, moduleExists := valueFromRequest(r, moduleQueryField)
+	if !moduleExists {
+		return logger.SetLevel(level)
+	}
+	if level == defaultLevelValue {
+		logger.ResetModuleLevel(module)
+		return nil
+	}
+	return logger.SetModuleLevel(module, level)
+}
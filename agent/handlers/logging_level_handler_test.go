@@ -0,0 +1,88 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+)
+
+func loggingRequest(t *testing.T, method, query string) (int, LoggingResponse) {
+	return loggingRequestTo(t, LoggingV1RequestHandlerMaker(true), method, query)
+}
+
+func loggingRequestTo(t *testing.T, handler func(http.ResponseWriter, *http.Request), method, query string) (int, LoggingResponse) {
+	request, err := http.NewRequest(method, "http://localhost/v1/logging?"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+	var response LoggingResponse
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	return recorder.Code, response
+}
+
+func TestLoggingHandler(t *testing.T) {
+	defer logger.SetLevel("info")
+	defer logger.ResetModuleLevel("engine")
+
+	// GETs don't change anything
+	code, response := loggingRequest(t, "GET", "module=engine&level=debug")
+	if code != statusOK || response.Modules["engine"] != "" {
+		t.Error("Expected GET to only report levels", code, response)
+	}
+
+	code, response = loggingRequest(t, "POST", "module=engine&level=debug")
+	if code != statusOK || response.Modules["engine"] != "debug" {
+		t.Error("Expected engine to be set to debug", code, response)
+	}
+
+	code, response = loggingRequest(t, "PUT", "level=warn")
+	if code != statusOK || response.Level != "warn" {
+		t.Error("Expected agent level to be warn", code, response)
+	}
+
+	code, response = loggingRequest(t, "POST", "module=engine&level=loud")
+	if code != statusBadRequest || response.Error == "" || response.Modules["engine"] != "debug" {
+		t.Error("Expected an unknown level to be rejected", code, response)
+	}
+
+	code, response = loggingRequest(t, "POST", "module=engine&level=default")
+	if code != statusOK || len(response.Modules) != 0 {
+		t.Error("Expected engine to be reset", code, response)
+	}
+}
+
+func TestLoggingHandlerChangesOnlyAllowedLocally(t *testing.T) {
+	defer logger.ResetModuleLevel("engine")
+
+	code, response := loggingRequestTo(t, LoggingV1RequestHandlerMaker(false), "POST", "module=engine&level=debug")
+	if code != statusMethodNotAllowed || response.Error == "" || response.Modules["engine"] != "" {
+		t.Error("Expected changes to be refused", code, response)
+	}
+}
+
+func TestLoggingHandlerBoundsDebugDump(t *testing.T) {
+	for _, duration := range []string{"2h", "-1m", "0s"} {
+		code, response := loggingRequest(t, "POST", "debugdump="+duration)
+		if code != statusBadRequest || response.Error == "" {
+			t.Error("Expected debug dump to be refused", duration, code, response)
+		}
+	}
+}
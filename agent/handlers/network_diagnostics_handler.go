@@ -0,0 +1,60 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+)
+
+const (
+	diagnosticQueryField = "diagnostic"
+	hostQueryField       = "host"
+	portQueryField       = "port"
+)
+
+// NetworkDiagnosticsV1RequestHandlerMaker returns a handler for the
+// 'v1/networkdiagnostics' API of the local api socket. It runs the
+// 'diagnostic' query field in the network namespace of the task named by
+// 'taskarn', and responds with its output: 'ping', 'dns' or 'traceroute' of
+// the 'host' field, 'tcp' to check a connection to its 'port', or 'sockets'
+// to list the namespace's sockets. The 'container' field picks which of the
+// task's containers' namespace is used.
+func NetworkDiagnosticsV1RequestHandlerMaker(taskEngine engine.TaskEngine) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dockerTaskEngine, ok := taskEngine.(*engine.DockerTaskEngine)
+		if !ok {
+			w.WriteHeader(statusInternalServerError)
+			return
+		}
+		taskArn, _ := valueFromRequest(r, taskArnQueryField)
+		containerName, _ := valueFromRequest(r, containerNameQueryField)
+		diagnostic, _ := valueFromRequest(r, diagnosticQueryField)
+		host, _ := valueFromRequest(r, hostQueryField)
+		port, _ := valueFromRequest(r, portQueryField)
+
+		result, err := dockerTaskEngine.RunNetworkDiagnostic(taskArn, containerName, diagnostic, host, port)
+		if err != nil {
+			log.Warn("Unable to run network diagnostic", "task", taskArn, "diagnostic", diagnostic, "err", err)
+			w.WriteHeader(statusBadRequest)
+			responseJSON, _ := json.Marshal(&engine.NetworkDiagnosticResult{Error: err.Error()})
+			w.Write(responseJSON)
+			return
+		}
+		responseJSON, _ := json.Marshal(result)
+		w.Write(responseJSON)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+)
+
+func TestNetworkDiagnosticsRefused(t *testing.T) {
+	taskEngine := engine.NewTaskEngine(&config.Config{})
+	handler := NetworkDiagnosticsV1RequestHandlerMaker(taskEngine)
+
+	for _, query := range []string{
+		"taskarn=task1&diagnostic=sockets",
+		"taskarn=task1&diagnostic=rm&host=-rf",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "http://localhost/v1/networkdiagnostics?"+query, nil)
+		handler(w, req)
+		if w.Code != statusBadRequest {
+			t.Error("Unexpected status for", query, w.Code)
+		}
+		var result engine.NetworkDiagnosticResult
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || result.Error == "" {
+			t.Error("Expected an error in the response", w.Body.String())
+		}
+	}
+}
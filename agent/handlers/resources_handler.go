@@ -0,0 +1,119 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+)
+
+// ResourceAmounts are amounts of cpu, in cpu units of which there are 1024
+// per core, and of memory in MiB
+type ResourceAmounts struct {
+	CPU    int64
+	Memory int64
+}
+
+// TaskResources are the resources a task holds on the instance
+type TaskResources struct {
+	Arn         string
+	KnownStatus string
+	CPU         int64
+	Memory      int64
+	Ports       []uint16 `json:",omitempty"`
+	PortsUDP    []uint16 `json:",omitempty"`
+}
+
+// ResourcesResponse is the agent's view of the instance's resources
+type ResourcesResponse struct {
+	Registered        ResourceAmounts
+	Allocated         ResourceAmounts
+	Available         ResourceAmounts
+	ReservedPorts     []uint16
+	ReservedPortsUDP  []uint16
+	AllocatedPorts    []uint16
+	AllocatedPortsUDP []uint16
+	Tasks             []TaskResources
+}
+
+// ResourcesV1RequestHandlerMaker returns a handler for the 'v1/resources'
+// API, which shows how the agent accounts for the instance's resources, to
+// debug why the scheduler believes an instance is full. Registered is what
+// the instance registered with. A task holds its containers' cpu, memory and
+// host ports from when it is received until its stop has been sent to the
+// backend, which is when the scheduler releases them.
+func ResourcesV1RequestHandlerMaker(taskEngine engine.TaskEngine, cfg *config.Config) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tasks, err := taskEngine.ListTasks()
+		if err != nil {
+			w.WriteHeader(statusInternalServerError)
+			return
+		}
+		cpu, memory := api.RegisteredCPUAndMemory(cfg)
+		responseJSON, _ := json.Marshal(newResourcesResponse(tasks, cfg, ResourceAmounts{CPU: cpu, Memory: memory}))
+		w.Write(responseJSON)
+	}
+}
+
+func newResourcesResponse(tasks []*api.Task, cfg *config.Config, registered ResourceAmounts) *ResourcesResponse {
+	response := &ResourcesResponse{
+		Registered:       registered,
+		ReservedPorts:    cfg.ReservedPorts,
+		ReservedPortsUDP: cfg.ReservedPortsUDP,
+		Tasks:            []TaskResources{},
+	}
+	for _, task := range tasks {
+		if task.GetSentStatus() == api.TaskStopped {
+			continue
+		}
+		resources := TaskResources{Arn: task.Arn, KnownStatus: task.KnownStatus.String()}
+		for _, container := range task.Containers {
+			resources.CPU += int64(container.Cpu)
+			resources.Memory += int64(container.Memory)
+			for _, port := range container.Ports {
+				if port.HostPort == 0 {
+					continue
+				}
+				if port.Protocol == api.TransportProtocolUDP {
+					resources.PortsUDP = append(resources.PortsUDP, port.HostPort)
+				} else {
+					resources.Ports = append(resources.Ports, port.HostPort)
+				}
+			}
+		}
+		response.Allocated.CPU += resources.CPU
+		response.Allocated.Memory += resources.Memory
+		response.AllocatedPorts = append(response.AllocatedPorts, resources.Ports...)
+		response.AllocatedPortsUDP = append(response.AllocatedPortsUDP, resources.PortsUDP...)
+		response.Tasks = append(response.Tasks, resources)
+	}
+	sort.Sort(uint16s(response.AllocatedPorts))
+	sort.Sort(uint16s(response.AllocatedPortsUDP))
+	response.Available = ResourceAmounts{
+		CPU:    registered.CPU - response.Allocated.CPU,
+		Memory: registered.Memory - response.Allocated.Memory,
+	}
+	return response
+}
+
+type uint16s []uint16
+
+func (s uint16s) Len() int           { return len(s) }
+func (s uint16s) Less(i, j int) bool { return s[i] < s[j] }
+func (s uint16s) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,52 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+)
+
+func TestResourcesResponse(t *testing.T) {
+	cfg := &config.Config{ReservedPorts: []uint16{22, 2375}, ReservedPortsUDP: []uint16{}}
+	tasks := []*api.Task{
+		{Arn: "running", KnownStatus: api.TaskRunning, Containers: []*api.Container{
+			{Cpu: 512, Memory: 256, Ports: []api.PortBinding{{ContainerPort: 80, HostPort: 8080}, {ContainerPort: 53, HostPort: 53, Protocol: api.TransportProtocolUDP}}},
+			{Cpu: 256, Memory: 128, Ports: []api.PortBinding{{ContainerPort: 443}}},
+		}},
+		{Arn: "stopping", KnownStatus: api.TaskStopped, Containers: []*api.Container{
+			{Cpu: 128, Memory: 64, Ports: []api.PortBinding{{ContainerPort: 80, HostPort: 80}}},
+		}},
+		{Arn: "stopped", KnownStatus: api.TaskStopped, SentStatus: api.TaskStopped, Containers: []*api.Container{
+			{Cpu: 1024, Memory: 1024},
+		}},
+	}
+
+	response := newResourcesResponse(tasks, cfg, ResourceAmounts{CPU: 2048, Memory: 3768})
+	if response.Allocated != (ResourceAmounts{CPU: 896, Memory: 448}) {
+		t.Error("Expected tasks whose stop hasn't been sent to be allocated", response.Allocated)
+	}
+	if response.Available != (ResourceAmounts{CPU: 1152, Memory: 3320}) {
+		t.Error("Unexpected available resources", response.Available)
+	}
+	if !reflect.DeepEqual(response.AllocatedPorts, []uint16{80, 8080}) || !reflect.DeepEqual(response.AllocatedPortsUDP, []uint16{53}) {
+		t.Error("Unexpected allocated ports", response.AllocatedPorts, response.AllocatedPortsUDP)
+	}
+	if len(response.Tasks) != 2 || response.Tasks[0].Arn != "running" || response.Tasks[0].CPU != 768 {
+		t.Error("Unexpected task resources", response.Tasks)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	tcshandler "github.com/aws/amazon-ecs-agent/agent/tcs/handler"
+)
+
+const enabledQueryField = "enabled"
+
+type TelemetryResponse struct {
+	Enabled bool
+	Error   string `json:",omitempty"`
+}
+
+// TelemetryV1RequestHandlerMaker returns a handler for the 'v1/telemetry'
+// API. It reports whether metrics are published to the telemetry service. If
+// changes are allowed, as they are only on the local api socket, a POST or PUT
+// with ?enabled=false stops publishing them, and closes the session with the
+// telemetry service, until one with ?enabled=true; this lasts until the agent
+// restarts.
+func TelemetryV1RequestHandlerMaker(telemetry *tcshandler.TelemetrySwitch, allowChanges bool) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := statusOK
+		var response TelemetryResponse
+		if r.Method == "POST" || r.Method == "PUT" {
+			if !allowChanges {
+				status = statusMethodNotAllowed
+				response.Error = "Telemetry can only be changed through the local api"
+			} else if value, ok := valueFromRequest(r, enabledQueryField); ok {
+				enabled, err := strconv.ParseBool(value)
+				if err != nil {
+					status = statusBadRequest
+					response.Error = "Invalid value for " + enabledQueryField + ": " + value
+				} else {
+					log.Info("Changing telemetry", "enabled", enabled)
+					telemetry.SetEnabled(enabled)
+				}
+			}
+		}
+		response.Enabled = telemetry.Enabled()
+		responseJSON, _ := json.Marshal(&response)
+		w.WriteHeader(status)
+		w.Write(responseJSON)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tcshandler "github.com/aws/amazon-ecs-agent/agent/tcs/handler"
+)
+
+func telemetryRequest(t *testing.T, telemetry *tcshandler.TelemetrySwitch, allowChanges bool, method, query string) (int, TelemetryResponse) {
+	request, err := http.NewRequest(method, "http://localhost/v1/telemetry?"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	TelemetryV1RequestHandlerMaker(telemetry, allowChanges)(recorder, request)
+	var response TelemetryResponse
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	return recorder.Code, response
+}
+
+func TestTelemetryHandler(t *testing.T) {
+	telemetry := tcshandler.NewTelemetrySwitch(true)
+
+	code, response := telemetryRequest(t, telemetry, true, "GET", "enabled=false")
+	if code != statusOK || !response.Enabled {
+		t.Error("Expected GET to only report telemetry", code, response)
+	}
+
+	code, response = telemetryRequest(t, telemetry, true, "POST", "enabled=false")
+	if code != statusOK || response.Enabled || telemetry.Enabled() {
+		t.Error("Expected telemetry to be disabled", code, response)
+	}
+
+	code, response = telemetryRequest(t, telemetry, true, "PUT", "enabled=maybe")
+	if code != statusBadRequest || response.Error == "" || response.Enabled {
+		t.Error("Expected an invalid value to be rejected", code, response)
+	}
+
+	code, response = telemetryRequest(t, telemetry, true, "PUT", "enabled=true")
+	if code != statusOK || !response.Enabled {
+		t.Error("Expected telemetry to be enabled", code, response)
+	}
+}
+
+func TestTelemetryHandlerReadOnly(t *testing.T) {
+	telemetry := tcshandler.NewTelemetrySwitch(true)
+
+	code, response := telemetryRequest(t, telemetry, false, "POST", "enabled=false")
+	if code != statusMethodNotAllowed || response.Error == "" || !response.Enabled || !telemetry.Enabled() {
+		t.Error("Expected telemetry not to be changed", code, response)
+	}
+	code, response = telemetryRequest(t, telemetry, false, "GET", "")
+	if code != statusOK || !response.Enabled {
+		t.Error("Expected telemetry to be reported", code, response)
+	}
+}
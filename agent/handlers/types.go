@@ -13,10 +13,26 @@
 
 package handlers
 
+import (
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/dnscache"
+)
+
 type MetadataResponse struct {
 	Cluster              string
 	ContainerInstanceArn *string
 	Version              string
+
+	// ACSConnected is whether the agent has had a recent heartbeat from ACS,
+	// and ACSLastHeartbeat and ACSLastPayload when it last heard from it
+	ACSConnected     bool
+	ACSLastHeartbeat *time.Time `json:",omitempty"`
+	ACSLastPayload   *time.Time `json:",omitempty"`
+
+	// DNSCache counts the lookups of AWS endpoints the agent has made, how
+	// many were answered from its cache and how many failed
+	DNSCache dnscache.Metrics
 }
 
 type TaskResponse struct {
@@ -36,4 +52,16 @@ type ContainerResponse struct {
 	DockerId   string
 	DockerName string
 	Name       string
+	ErrorCode  string     `json:",omitempty"`
+	Error      string     `json:",omitempty"`
+	ExitCode   *int       `json:",omitempty"`
+	Reason     string     `json:",omitempty"`
+	FinishedAt *time.Time `json:",omitempty"`
+
+	// CPUSet is the cores the container is pinned to, if any
+	CPUSet string `json:",omitempty"`
+
+	// LaunchPhases is how many milliseconds each phase of launching the
+	// container took
+	LaunchPhases map[string]int64 `json:",omitempty"`
 }
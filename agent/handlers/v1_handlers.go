@@ -16,16 +16,22 @@ package handlers
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
+	"os"
 	"strconv"
 	"sync"
 	"time"
 
+	acshandler "github.com/aws/amazon-ecs-agent/agent/acs/handler"
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dnscache"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/health"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
+	tcshandler "github.com/aws/amazon-ecs-agent/agent/tcs/handler"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/amazon-ecs-agent/agent/version"
 )
@@ -37,6 +43,10 @@ const statusNotImplemented = 501
 const statusOK = 200
 const statusInternalServerError = 500
 
+// localAPISocketMode lets only the agent's user use the local api, as it can
+// run tasks and read what they run
+const localAPISocketMode = 0600
+
 const dockerIdQueryField = "dockerid"
 const taskArnQueryField = "taskarn"
 
@@ -45,14 +55,22 @@ type RootResponse struct {
 }
 
 func MetadataV1RequestHandlerMaker(containerInstanceArn *string, cfg *config.Config) func(http.ResponseWriter, *http.Request) {
-	resp := &MetadataResponse{
-		Cluster:              cfg.Cluster,
-		ContainerInstanceArn: containerInstanceArn,
-		Version:              version.String(),
-	}
-	responseJSON, _ := json.Marshal(resp)
-
 	return func(w http.ResponseWriter, r *http.Request) {
+		acsStatus := acshandler.Liveness.Status()
+		resp := &MetadataResponse{
+			Cluster:              cfg.Cluster,
+			ContainerInstanceArn: containerInstanceArn,
+			Version:              version.String(),
+			ACSConnected:         acsStatus.Connected,
+			DNSCache:             dnscache.Default.Metrics(),
+		}
+		if !acsStatus.LastHeartbeat.IsZero() {
+			resp.ACSLastHeartbeat = &acsStatus.LastHeartbeat
+		}
+		if !acsStatus.LastPayload.IsZero() {
+			resp.ACSLastPayload = &acsStatus.LastPayload
+		}
+		responseJSON, _ := json.Marshal(resp)
 		w.Write(responseJSON)
 	}
 }
@@ -63,7 +81,31 @@ func NewTaskResponse(task *api.Task, containerMap map[string]*api.DockerContaine
 		if container.Container.IsInternal {
 			continue
 		}
-		containers = append(containers, ContainerResponse{container.DockerId, container.DockerName, containerName})
+		containerResponse := ContainerResponse{
+			DockerId:   container.DockerId,
+			DockerName: container.DockerName,
+			Name:       containerName,
+			ExitCode:   container.Container.KnownExitCode,
+			Reason:     container.Container.KnownReason,
+		}
+		if !container.Container.KnownFinishedAt.IsZero() {
+			finishedAt := container.Container.KnownFinishedAt
+			containerResponse.FinishedAt = &finishedAt
+		}
+		if container.Container.LinuxParameters != nil {
+			containerResponse.CPUSet = container.Container.LinuxParameters.CPUSetCPUs
+		}
+		if launchPhases := container.Container.GetLaunchPhases(); len(launchPhases) > 0 {
+			containerResponse.LaunchPhases = make(map[string]int64)
+			for phase, duration := range launchPhases {
+				containerResponse.LaunchPhases[phase] = int64(duration / time.Millisecond)
+			}
+		}
+		if container.Container.ApplyingError != nil {
+			containerResponse.ErrorCode = api.ErrorCode(container.Container.ApplyingError)
+			containerResponse.Error = container.Container.ApplyingError.Error()
+		}
+		containers = append(containers, containerResponse)
 	}
 
 	knownStatus := task.KnownStatus.BackendStatus()
@@ -158,17 +200,101 @@ func TasksV1RequestHandlerMaker(taskEngine engine.TaskEngine) func(http.Response
 	}
 }
 
+// ServeHttp serves the introspection api on the introspection port, and the
+// parts of it that change the agent or expose what its tasks run on the local
+// api socket, if one is configured. The local api is only reachable by those
+// who can reach the socket, so it is the only place these are served.
 func ServeHttp(containerInstanceArn *string, taskEngine engine.TaskEngine, cfg *config.Config) {
 	serverFunctions := map[string]func(w http.ResponseWriter, r *http.Request){
-		"/v1/metadata": MetadataV1RequestHandlerMaker(containerInstanceArn, cfg),
-		"/v1/tasks":    TasksV1RequestHandlerMaker(taskEngine),
+		"/v1/metadata":  MetadataV1RequestHandlerMaker(containerInstanceArn, cfg),
+		"/v1/tasks":     TasksV1RequestHandlerMaker(taskEngine),
+		"/healthcheck":  HealthcheckRequestHandlerMaker(health.Default),
+		"/v1/logging":   LoggingV1RequestHandlerMaker(false),
+		"/v1/telemetry": TelemetryV1RequestHandlerMaker(tcshandler.Telemetry, false),
+		"/v1/resources": ResourcesV1RequestHandlerMaker(taskEngine, cfg),
+	}
+	if prePuller, ok := taskEngine.(ImagePrePuller); ok {
+		serverFunctions["/v1/images"] = ImagesV1RequestHandlerMaker(prePuller, false)
+	}
+	if reporter, ok := taskEngine.(EventQueueReporter); ok {
+		serverFunctions["/v1/eventqueue"] = EventQueueV1RequestHandlerMaker(reporter)
+	}
+	if reporter, ok := taskEngine.(CleanupReporter); ok {
+		serverFunctions["/v1/cleanup"] = CleanupV1RequestHandlerMaker(reporter)
 	}
 
-	paths := make([]string, 0, len(serverFunctions))
-	for path := range serverFunctions {
-		paths = append(paths, path)
+	// The local api has no write timeout, so that each of its handlers can
+	// take as long as it needs; those which answer at once are bounded here
+	localFunctions := map[string]func(w http.ResponseWriter, r *http.Request){
+		"/v1/logging": withTimeout(LoggingV1RequestHandlerMaker(true), 5*time.Second),
+		// Bundles are streamed as they are collected, so aren't bounded
+		"/v1/debugbundle": DebugBundleV1RequestHandlerMaker(containerInstanceArn, taskEngine, cfg),
+	}
+	if prePuller, ok := taskEngine.(ImagePrePuller); ok {
+		localFunctions["/v1/images"] = withTimeout(ImagesV1RequestHandlerMaker(prePuller, true), 5*time.Second)
+	}
+	if cfg.LocalTaskAPIEnabled {
+		localFunctions["/v1/localtasks"] = withTimeout(LocalTasksV1RequestHandlerMaker(taskEngine), 5*time.Second)
+	}
+	if cfg.TelemetryEnabled {
+		localFunctions["/v1/telemetry"] = withTimeout(TelemetryV1RequestHandlerMaker(tcshandler.Telemetry, true), 5*time.Second)
 	}
-	availableCommands := &RootResponse{paths}
+	if cfg.ContainerLogsAPIEnabled {
+		// Logs are written as they arrive, until the handler's own deadline
+		localFunctions["/v1/containerlogs"] = ContainerLogsV1RequestHandlerMaker(taskEngine)
+	}
+	if cfg.NetworkDiagnosticsAPIEnabled {
+		localFunctions["/v1/networkdiagnostics"] = withTimeout(NetworkDiagnosticsV1RequestHandlerMaker(taskEngine), 5*time.Second+engine.NetworkDiagnosticTimeout)
+	}
+	if cfg.LocalAPISocket != "" {
+		if cfg.LocalTaskAPIEnabled {
+			log.Warn("The local task api is enabled; it is meant only for development")
+		}
+		go serveLocalAPI(cfg.LocalAPISocket, localFunctions)
+	} else if cfg.LocalTaskAPIEnabled || cfg.ContainerLogsAPIEnabled || cfg.NetworkDiagnosticsAPIEnabled {
+		log.Warn("The local api is not served without a socket for it", "endpoints", commands(localFunctions))
+	}
+
+	server := http.Server{
+		Addr:         ":" + strconv.Itoa(config.AGENT_INTROSPECTION_PORT),
+		Handler:      newServeMux(serverFunctions),
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+	serveWithRetry("http api", server.ListenAndServe)
+}
+
+// serveLocalAPI serves the given api on a unix socket at path that only the
+// agent's user can use
+func serveLocalAPI(path string, serverFunctions map[string]func(w http.ResponseWriter, r *http.Request)) {
+	server := http.Server{
+		Handler:     newServeMux(serverFunctions),
+		ReadTimeout: 5 * time.Second,
+	}
+	serveWithRetry("local api", func() error {
+		os.Remove(path)
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+		if err := os.Chmod(path, localAPISocketMode); err != nil {
+			return err
+		}
+		log.Info("Serving the local api", "socket", path, "endpoints", commands(serverFunctions))
+		return server.Serve(listener)
+	})
+}
+
+// withTimeout answers with a 503 if fn has not answered within timeout
+func withTimeout(fn func(w http.ResponseWriter, r *http.Request), timeout time.Duration) func(w http.ResponseWriter, r *http.Request) {
+	return http.TimeoutHandler(http.HandlerFunc(fn), timeout, "").ServeHTTP
+}
+
+// newServeMux returns a handler that serves the given functions, and lists
+// their paths at the root
+func newServeMux(serverFunctions map[string]func(w http.ResponseWriter, r *http.Request)) http.Handler {
+	availableCommands := &RootResponse{commands(serverFunctions)}
 	// Autogenerated list of the above serverFunctions paths
 	availableCommandResponse, _ := json.Marshal(&availableCommands)
 
@@ -182,25 +308,32 @@ func ServeHttp(containerInstanceArn *string, taskEngine engine.TaskEngine, cfg *
 		serverMux.HandleFunc(key, fn)
 	}
 
-	// Log all requests and then pass through to serverMux
+	// Log all requests and then pass through to serverMux, sanitizing its
+	// responses
 	loggingServeMux := http.NewServeMux()
-	loggingServeMux.Handle("/", LoggingHandler{serverMux})
+	loggingServeMux.Handle("/", LoggingHandler{SanitizingHandler{serverMux}})
+	return loggingServeMux
+}
 
-	server := http.Server{
-		Addr:         ":" + strconv.Itoa(config.AGENT_INTROSPECTION_PORT),
-		Handler:      loggingServeMux,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
+func commands(serverFunctions map[string]func(w http.ResponseWriter, r *http.Request)) []string {
+	paths := make([]string, 0, len(serverFunctions))
+	for path := range serverFunctions {
+		paths = append(paths, path)
 	}
+	return paths
+}
 
+// serveWithRetry calls serve again whenever it returns, backing off between
+// calls
+func serveWithRetry(name string, serve func() error) {
 	for {
 		once := sync.Once{}
 		utils.RetryWithBackoff(utils.NewSimpleBackoff(time.Second, time.Minute, 0.2, 2), func() error {
 			// TODO, make this cancellable and use the passed in context; for
 			// now, not critical if this gets interrupted
-			err := server.ListenAndServe()
+			err := serve()
 			once.Do(func() {
-				log.Error("Error running http api", "err", err)
+				log.Error("Error running "+name, "err", err)
 			})
 			return err
 		})
@@ -16,10 +16,16 @@ package handlers
 import (
 	"encoding/json"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	"github.com/aws/amazon-ecs-agent/agent/config"
@@ -46,6 +52,12 @@ func TestMetadataHandler(t *testing.T) {
 	if *resp.ContainerInstanceArn != TestContainerInstanceArn {
 		t.Error("Metadata returned the wrong cluster arn")
 	}
+	if resp.ACSConnected || resp.ACSLastHeartbeat != nil {
+		t.Error("Metadata reported an ACS heartbeat which was never received")
+	}
+	if !strings.Contains(w.Body.String(), `"DNSCache":{"Lookups":`) {
+		t.Error("Metadata did not report the DNS cache's counts", w.Body.String())
+	}
 }
 
 func getResponseBodyFromLocalHost(url string, t *testing.T) []byte {
@@ -152,6 +164,44 @@ func TestServeHttp(t *testing.T) {
 	}
 }
 
+func TestServeLocalAPI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "api.sock")
+	go serveLocalAPI(path, map[string]func(w http.ResponseWriter, r *http.Request){
+		"/v1/local": func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("local")) },
+	})
+
+	client := http.Client{Transport: &http.Transport{Dial: func(string, string) (net.Conn, error) {
+		return net.Dial("unix", path)
+	}}}
+	var resp *http.Response
+	for i := 0; i < 100; i++ {
+		if resp, err = client.Get("http://localhost/v1/local"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "local" {
+		t.Error("Unexpected response from the local api", string(body))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != localAPISocketMode {
+		t.Error("Expected only the agent's user to be able to use the socket", info.Mode())
+	}
+}
+
 func backendMappingTestHelper(containers []*api.Container, testTask *api.Task, desiredStatus string, knownStatus string, t *testing.T) {
 	taskEngine := engine.NewTaskEngine(&config.Config{})
 	// Populate Tasks and Container map in the engine.
@@ -207,3 +257,78 @@ func TestBackendMapping(t *testing.T) {
 	// Since the KnownStatus (STOPPED) > DesiredStatus (RUNNING), DesiredStatus should be empty
 	backendMappingTestHelper(containers, &testTask, "", "STOPPED", t)
 }
+
+func TestStoppedContainerResponse(t *testing.T) {
+	exitCode := 137
+	finishedAt := time.Date(2015, 8, 1, 12, 0, 0, 0, time.UTC)
+	container := &api.Container{
+		Name:            "c1",
+		KnownStatus:     api.ContainerStopped,
+		KnownExitCode:   &exitCode,
+		KnownReason:     "OutOfMemoryError: Container killed due to memory usage",
+		KnownFinishedAt: finishedAt,
+		ApplyingError:   api.NewNamedError(&api.DefaultNamedError{Err: "Container killed due to memory usage", Name: "OutOfMemoryError", Code: api.ErrorCodeOutOfMemory}),
+	}
+	task := &api.Task{
+		Arn:           "task1",
+		DesiredStatus: api.TaskStopped,
+		KnownStatus:   api.TaskStopped,
+		Containers:    []*api.Container{container},
+	}
+	containerMap := map[string]*api.DockerContainer{
+		"c1": &api.DockerContainer{DockerId: "cid", DockerName: "name", Container: container},
+	}
+
+	response := NewTaskResponse(task, containerMap)
+	if len(response.Containers) != 1 {
+		t.Fatal("Expected one container", response.Containers)
+	}
+	containerResponse := response.Containers[0]
+	if containerResponse.ExitCode == nil || *containerResponse.ExitCode != 137 {
+		t.Error("Expected exit code 137", containerResponse.ExitCode)
+	}
+	if containerResponse.Reason != container.KnownReason {
+		t.Error("Unexpected reason", containerResponse.Reason)
+	}
+	if containerResponse.FinishedAt == nil || !containerResponse.FinishedAt.Equal(finishedAt) {
+		t.Error("Unexpected finishedAt", containerResponse.FinishedAt)
+	}
+	if containerResponse.ErrorCode != api.ErrorCodeOutOfMemory {
+		t.Error("Unexpected error code", containerResponse.ErrorCode)
+	}
+
+	// A running container reports none of these
+	runningContainer := &api.Container{Name: "c2"}
+	response = NewTaskResponse(task, map[string]*api.DockerContainer{"c2": &api.DockerContainer{Container: runningContainer}})
+	responseJSON, _ := json.Marshal(response.Containers[0])
+	if string(responseJSON) != `{"DockerId":"","DockerName":"","Name":"c2"}` {
+		t.Error("Unexpected running container response", string(responseJSON))
+	}
+}
+
+func TestLaunchPhasesResponse(t *testing.T) {
+	container := &api.Container{Name: "c1"}
+	container.RecordLaunchPhases(map[string]time.Duration{api.LaunchPhasePull: 3 * time.Second})
+	container.RecordLaunchPhases(map[string]time.Duration{
+		api.LaunchPhaseCreate: 250 * time.Millisecond,
+		api.LaunchPhaseStart:  1500 * time.Microsecond,
+	})
+	task := &api.Task{Arn: "task1", Containers: []*api.Container{container}}
+
+	response := NewTaskResponse(task, map[string]*api.DockerContainer{"c1": &api.DockerContainer{Container: container}})
+	phases := response.Containers[0].LaunchPhases
+	expected := map[string]int64{api.LaunchPhasePull: 3000, api.LaunchPhaseCreate: 250, api.LaunchPhaseStart: 1}
+	if !reflect.DeepEqual(phases, expected) {
+		t.Error("Expected launch phases in milliseconds", phases)
+	}
+}
+
+func TestPinnedContainerResponse(t *testing.T) {
+	container := &api.Container{Name: "c1", LinuxParameters: &api.LinuxParameters{CPUSetCPUs: "0-1"}}
+	task := &api.Task{Arn: "task1", Containers: []*api.Container{container}}
+
+	response := NewTaskResponse(task, map[string]*api.DockerContainer{"c1": &api.DockerContainer{Container: container}})
+	if response.Containers[0].CPUSet != "0-1" {
+		t.Error("Expected the container's pinned cores", response.Containers[0].CPUSet)
+	}
+}
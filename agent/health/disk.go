@@ -0,0 +1,69 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package health
+
+import (
+	"errors"
+	"strconv"
+	"syscall"
+)
+
+// MinFreeDiskBytes is the least free space the agent's data directory needs
+// to keep saving state
+const MinFreeDiskBytes = 100 * 1024 * 1024
+
+var statfs = syscall.Statfs
+
+// CheckDiskSpace returns an error if the filesystem holding path has less
+// than minFree bytes available
+func CheckDiskSpace(path string, minFree uint64) error {
+	var stat syscall.Statfs_t
+	if err := statfs(path, &stat); err != nil {
+		return err
+	}
+	free := stat.Bavail * uint64(stat.Bsize)
+	if free < minFree {
+		return errors.New("Only " + strconv.FormatUint(free, 10) + " bytes free on the filesystem of " + path)
+	}
+	return nil
+}
+
+// DiskUsedPercent returns the percentage of the filesystem holding path which
+// is in use, counting space reserved for root as unavailable
+func DiskUsedPercent(path string) (int, error) {
+	var stat syscall.Statfs_t
+	if err := statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	used := stat.Blocks - stat.Bfree
+	total := used + stat.Bavail
+	if total == 0 {
+		return 0, nil
+	}
+	// Round up, as df does
+	return int((used*100 + total - 1) / total), nil
+}
+
+// CheckDiskUsage returns an error if more than maxUsedPercent of the
+// filesystem holding path is in use
+func CheckDiskUsage(path string, maxUsedPercent int) error {
+	used, err := DiskUsedPercent(path)
+	if err != nil {
+		return err
+	}
+	if used > maxUsedPercent {
+		return errors.New(strconv.Itoa(used) + "% of the filesystem of " + path + " is in use")
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package health tracks the health of the agent's subsystems for the
+// introspection api's healthcheck.
+package health
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+// Components of the agent whose health is tracked
+const (
+	ComponentACS       = "ACS"
+	ComponentTCS       = "TCS"
+	ComponentDocker    = "Docker"
+	ComponentStateSave = "StateSave"
+	ComponentDisk      = "Disk"
+)
+
+// Statuses a component may have. A component is unknown until its health has
+// first been reported.
+const (
+	StatusUnknown   = "UNKNOWN"
+	StatusHealthy   = "HEALTHY"
+	StatusUnhealthy = "UNHEALTHY"
+)
+
+// checkTimeout bounds how long a single check may take
+const checkTimeout = 5 * time.Second
+
+// Default is the registry the agent's subsystems report to
+var Default = NewRegistry()
+
+// ComponentStatus is the last reported health of a component
+type ComponentStatus struct {
+	Status      string
+	Error       string     `json:",omitempty"`
+	LastUpdated time.Time  `json:",omitempty"`
+	LastHealthy *time.Time `json:",omitempty"`
+}
+
+// Listener is told when a component's status changes, such as from healthy
+// to unhealthy
+type Listener func(component string, status ComponentStatus)
+
+// Registry records the health of components as it is reported
+type Registry struct {
+	lock       sync.RWMutex
+	components map[string]*ComponentStatus
+	checks     map[string]func() error
+	listeners  []Listener
+}
+
+// NewRegistry returns a Registry with no components
+func NewRegistry() *Registry {
+	return &Registry{
+		components: make(map[string]*ComponentStatus),
+		checks:     make(map[string]func() error),
+	}
+}
+
+// AddCheck registers a check whose result is reported as the health of the
+// component each time RunChecks is called
+func (r *Registry) AddCheck(component string, check func() error) {
+	r.lock.Lock()
+	r.checks[component] = check
+	r.lock.Unlock()
+	r.Register(component)
+}
+
+// RunChecks runs every registered check concurrently and reports its result.
+// A check which does not finish within checkTimeout is reported unhealthy.
+func (r *Registry) RunChecks() {
+	r.lock.RLock()
+	checks := make(map[string]func() error, len(r.checks))
+	for component, check := range r.checks {
+		checks[component] = check
+	}
+	r.lock.RUnlock()
+
+	var wg sync.WaitGroup
+	for component, check := range checks {
+		wg.Add(1)
+		go func(component string, check func() error) {
+			defer wg.Done()
+			r.Report(component, runCheck(check))
+		}(component, check)
+	}
+	wg.Wait()
+}
+
+func runCheck(check func() error) error {
+	result := make(chan error, 1)
+	go func() { result <- check() }()
+	select {
+	case err := <-result:
+		return err
+	case <-ttime.After(checkTimeout):
+		return errors.New("Check timed out after " + checkTimeout.String())
+	}
+}
+
+// Register adds a component whose health is not yet known, so that it is
+// reported as unknown rather than omitted. Registering a component which
+// already has a status has no effect.
+func (r *Registry) Register(component string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.components[component]; !ok {
+		r.components[component] = &ComponentStatus{Status: StatusUnknown}
+	}
+}
+
+// Report records the health of a component; it is healthy if err is nil
+func (r *Registry) Report(component string, err error) {
+	now := ttime.Now()
+	r.lock.Lock()
+	status, ok := r.components[component]
+	if !ok {
+		status = &ComponentStatus{}
+		r.components[component] = status
+	}
+	previous := status.Status
+	status.LastUpdated = now
+	if err != nil {
+		status.Status = StatusUnhealthy
+		status.Error = err.Error()
+	} else {
+		status.Status = StatusHealthy
+		status.Error = ""
+		status.LastHealthy = &now
+	}
+	changed := *status
+	listeners := r.listeners
+	r.lock.Unlock()
+
+	if changed.Status != previous {
+		for _, listener := range listeners {
+			listener(component, changed)
+		}
+	}
+}
+
+// AddListener registers a listener to be told of every later change in a
+// component's status
+func (r *Registry) AddListener(listener Listener) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.listeners = append(r.listeners, listener)
+}
+
+// Status returns a snapshot of every component's health, and whether all of
+// them are healthy. Components whose health is unknown do not count against
+// the agent's health.
+func (r *Registry) Status() (map[string]ComponentStatus, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	healthy := true
+	components := make(map[string]ComponentStatus, len(r.components))
+	for name, status := range r.components {
+		components[name] = *status
+		if status.Status == StatusUnhealthy {
+			healthy = false
+		}
+	}
+	return components, healthy
+}
@@ -0,0 +1,128 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package health
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(ComponentACS)
+
+	components, healthy := registry.Status()
+	if !healthy {
+		t.Error("Unknown components should not make the agent unhealthy")
+	}
+	if components[ComponentACS].Status != StatusUnknown {
+		t.Error("Expected ACS to be unknown", components[ComponentACS])
+	}
+
+	registry.Report(ComponentACS, nil)
+	registry.Report(ComponentDocker, errors.New("unreachable"))
+	components, healthy = registry.Status()
+	if healthy {
+		t.Error("Expected an unhealthy component to make the agent unhealthy")
+	}
+	if components[ComponentACS].Status != StatusHealthy || components[ComponentACS].LastHealthy == nil {
+		t.Error("Expected ACS to be healthy", components[ComponentACS])
+	}
+	docker := components[ComponentDocker]
+	if docker.Status != StatusUnhealthy || docker.Error != "unreachable" || docker.LastUpdated.IsZero() || docker.LastHealthy != nil {
+		t.Error("Expected docker to be unhealthy", docker)
+	}
+
+	// Registering a reported component doesn't reset it
+	registry.Register(ComponentDocker)
+	registry.Report(ComponentACS, errors.New("disconnected"))
+	components, _ = registry.Status()
+	if components[ComponentDocker].Status != StatusUnhealthy {
+		t.Error("Expected docker to still be unhealthy", components[ComponentDocker])
+	}
+	if components[ComponentACS].LastHealthy == nil {
+		t.Error("Expected ACS to remember when it was last healthy")
+	}
+}
+
+func TestRegistryListener(t *testing.T) {
+	registry := NewRegistry()
+	var changes []string
+	registry.AddListener(func(component string, status ComponentStatus) {
+		changes = append(changes, component+"="+status.Status)
+	})
+
+	registry.Report(ComponentACS, nil)
+	registry.Report(ComponentACS, nil)
+	registry.Report(ComponentACS, errors.New("disconnected"))
+	registry.Report(ComponentACS, errors.New("still disconnected"))
+	registry.Report(ComponentACS, nil)
+
+	expected := []string{"ACS=HEALTHY", "ACS=UNHEALTHY", "ACS=HEALTHY"}
+	if len(changes) != len(expected) {
+		t.Fatal("Expected only changes in status to be reported, got", changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Error("Expected", expected[i], "got", changes[i])
+		}
+	}
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := CheckDiskSpace(dir, 0); err != nil {
+		t.Error("Expected any filesystem to have 0 bytes free", err)
+	}
+	if err := CheckDiskSpace(dir, math.MaxUint64); err == nil {
+		t.Error("Expected no filesystem to have MaxUint64 bytes free")
+	}
+	if err := CheckDiskSpace(dir+"/missing", 0); err == nil {
+		t.Error("Expected an error for a missing path")
+	}
+}
+
+func TestCheckDiskUsage(t *testing.T) {
+	defer func() { statfs = syscall.Statfs }()
+	statfs = func(path string, stat *syscall.Statfs_t) error {
+		stat.Blocks = 100
+		stat.Bfree = 10
+		stat.Bavail = 5
+		return nil
+	}
+
+	used, err := DiskUsedPercent("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 90 blocks used of the 95 usable
+	if used != 95 {
+		t.Error("Expected 95% used, got", used)
+	}
+	if err := CheckDiskUsage("/", 95); err != nil {
+		t.Error("Expected usage at the threshold to be healthy", err)
+	}
+	if err := CheckDiskUsage("/", 90); err == nil {
+		t.Error("Expected usage over the threshold to be unhealthy")
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package health
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	ComponentSELinux = "SELinux"
+	ComponentCNI     = "CNI"
+)
+
+var selinuxEnforcePath = "/sys/fs/selinux/enforce"
+
+// CheckSELinux fails if SELinux is enforcing but docker was not started with
+// SELinux support, in which case containers may be denied their volumes.
+// securityOptions returns docker's security options, such as
+// ["name=seccomp","name=selinux"].
+func CheckSELinux(securityOptions func() (string, error)) error {
+	enforce, err := ioutil.ReadFile(selinuxEnforcePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(enforce)) != "1" {
+		return nil
+	}
+	options, err := securityOptions()
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(options, "selinux") {
+		return errors.New("SELinux is enforcing but docker does not have SELinux support enabled")
+	}
+	return nil
+}
+
+func CheckCNIPlugins(path string) error {
+	plugins, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, plugin := range plugins {
+		if plugin.Mode().IsRegular() && plugin.Mode()&0111 != 0 {
+			return nil
+		}
+	}
+	return errors.New("No CNI plugins in " + path)
+}
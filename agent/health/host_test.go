@@ -0,0 +1,73 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package health
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckSELinux(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selinux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { selinuxEnforcePath = path }(selinuxEnforcePath)
+	selinuxEnforcePath = filepath.Join(dir, "enforce")
+
+	withoutSELinux := func() (string, error) { return `["name=seccomp"]`, nil }
+	withSELinux := func() (string, error) { return `["name=seccomp","name=selinux"]`, nil }
+
+	if err := CheckSELinux(withoutSELinux); err != nil {
+		t.Error("Expected no error without selinuxfs", err)
+	}
+	ioutil.WriteFile(selinuxEnforcePath, []byte("0\n"), 0644)
+	if err := CheckSELinux(withoutSELinux); err != nil {
+		t.Error("Expected no error when SELinux is permissive", err)
+	}
+	ioutil.WriteFile(selinuxEnforcePath, []byte("1\n"), 0644)
+	if err := CheckSELinux(withoutSELinux); err == nil {
+		t.Error("Expected an error when SELinux is enforcing without docker support")
+	}
+	if err := CheckSELinux(withSELinux); err != nil {
+		t.Error("Expected no error when docker supports SELinux", err)
+	}
+	if err := CheckSELinux(func() (string, error) { return "", errors.New("unreachable") }); err == nil {
+		t.Error("Expected an error when docker's options are unknown")
+	}
+}
+
+func TestCheckCNIPlugins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cni")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := CheckCNIPlugins(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for a missing plugins directory")
+	}
+	ioutil.WriteFile(filepath.Join(dir, "README"), []byte("docs"), 0644)
+	if err := CheckCNIPlugins(dir); err == nil {
+		t.Error("Expected an error without executable plugins")
+	}
+	ioutil.WriteFile(filepath.Join(dir, "bridge"), []byte("#!/bin/sh"), 0755)
+	if err := CheckCNIPlugins(dir); err != nil {
+		t.Error("Expected no error with a plugin", err)
+	}
+}
@@ -16,11 +16,12 @@
 package httpclient
 
 import (
-	"crypto/tls"
 	"net"
 	"net/http"
 	"time"
 
+	"github.com/aws/amazon-ecs-agent/agent/dnscache"
+	"github.com/aws/amazon-ecs-agent/agent/tlsconfig"
 	"github.com/aws/amazon-ecs-agent/agent/version"
 )
 
@@ -59,14 +60,12 @@ func New(timeout time.Duration, insecureSkipVerify bool) *http.Client {
 	// explicitly do not use theirs to avoid changing their behavior.
 	transport := &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
-		Dial: (&net.Dialer{
+		Dial: dnscache.Default.Dial(&net.Dialer{
 			Timeout:   defaultDialTimeout,
 			KeepAlive: defaultDialKeepalive,
-		}).Dial,
+		}),
 		TLSHandshakeTimeout: 10 * time.Second,
-	}
-	if insecureSkipVerify {
-		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		TLSClientConfig:     tlsconfig.New("", insecureSkipVerify),
 	}
 	client := &http.Client{
 		Transport: &ecsRoundTripper{insecureSkipVerify, transport},
@@ -0,0 +1,146 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logger
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+// Module log levels override the agent's log level for the modules they
+// match. A module level applies to every module whose name, lowercased and
+// without spaces, contains it; "acs" matches both "acs client" and
+// "acs handler", and "engine" matches "TaskEngine". Where several match, the
+// longest wins.
+var (
+	levelLock    sync.RWMutex
+	moduleLevels = make(map[string]string)
+
+	// debugDumpGeneration identifies the latest debug dump so that an
+	// earlier dump's expiry does not end a later one
+	debugDumpGeneration int
+	debugDumpActive     bool
+)
+
+// ErrUnknownLevel is returned when setting a log level which doesn't exist
+var ErrUnknownLevel = errors.New("Unknown log level; expected one of debug, info, warn, error, crit, none")
+
+// SetModuleLevel sets the log level for modules matching module
+func SetModuleLevel(module, logLevel string) error {
+	parsedLevel, ok := levels[strings.ToLower(logLevel)]
+	if !ok {
+		return ErrUnknownLevel
+	}
+	levelLock.Lock()
+	moduleLevels[moduleKey(module)] = parsedLevel
+	levelLock.Unlock()
+	reloadConfig()
+	return nil
+}
+
+// ResetModuleLevel removes the log level set for module, returning the
+// modules it matched to the agent's log level
+func ResetModuleLevel(module string) {
+	levelLock.Lock()
+	delete(moduleLevels, moduleKey(module))
+	levelLock.Unlock()
+	reloadConfig()
+}
+
+// Levels returns the agent's log level and the level set for each module
+func Levels() (string, map[string]string) {
+	levelLock.RLock()
+	defer levelLock.RUnlock()
+	modules := make(map[string]string, len(moduleLevels))
+	for module, moduleLevel := range moduleLevels {
+		modules[module] = moduleLevel
+	}
+	if debugDumpActive {
+		return "debug", modules
+	}
+	return level, modules
+}
+
+// StartDebugDump logs every module at debug level for duration, after which
+// the configured levels are restored. Starting a dump while one is running
+// extends it.
+func StartDebugDump(duration time.Duration) {
+	levelLock.Lock()
+	debugDumpGeneration++
+	generation := debugDumpGeneration
+	debugDumpActive = true
+	levelLock.Unlock()
+	reloadConfig()
+	log.Infof("Debug logging enabled for %v", duration)
+
+	go func() {
+		<-ttime.After(duration)
+		levelLock.Lock()
+		if generation != debugDumpGeneration {
+			levelLock.Unlock()
+			return
+		}
+		debugDumpActive = false
+		levelLock.Unlock()
+		reloadConfig()
+		log.Info("Debug logging expired; restored configured log levels")
+	}()
+}
+
+func moduleKey(module string) string {
+	return strings.Replace(strings.ToLower(module), " ", "", -1)
+}
+
+// moduleLevel returns the level a module logs at
+func moduleLevel(module string) log.LogLevel {
+	levelLock.RLock()
+	defer levelLock.RUnlock()
+	if debugDumpActive {
+		return log.DebugLvl
+	}
+	effective := level
+	key := moduleKey(module)
+	matched := ""
+	for match, matchLevel := range moduleLevels {
+		if strings.Contains(key, match) && len(match) > len(matched) {
+			matched = match
+			effective = matchLevel
+		}
+	}
+	parsed, _ := log.LogLevelFromString(effective)
+	return parsed
+}
+
+// minLevel returns the lowest level any module logs at, which seelog must
+// allow through for the modules' own levels to take effect
+func minLevel() string {
+	levelLock.RLock()
+	defer levelLock.RUnlock()
+	if debugDumpActive {
+		return "debug"
+	}
+	min, _ := log.LogLevelFromString(level)
+	for _, moduleLevel := range moduleLevels {
+		if parsed, _ := log.LogLevelFromString(moduleLevel); parsed < min {
+			min = parsed
+		}
+	}
+	return min.String()
+}
@@ -0,0 +1,90 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logger
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/cihub/seelog"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+func TestModuleLevels(t *testing.T) {
+	SetLevel("info")
+	defer SetLevel("info")
+	defer ResetModuleLevel("acs")
+	defer ResetModuleLevel("acs handler")
+
+	if moduleLevel("acs client") != log.InfoLvl {
+		t.Error("Expected modules to default to the agent's level")
+	}
+	if err := SetModuleLevel("acs", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetModuleLevel("ACS Handler", "crit"); err != nil {
+		t.Fatal(err)
+	}
+	if SetModuleLevel("acs", "loud") != ErrUnknownLevel {
+		t.Error("Expected an error for an unknown level")
+	}
+
+	if moduleLevel("acs client") != log.DebugLvl {
+		t.Error("Expected acs client to match the acs level")
+	}
+	if moduleLevel("acs handler") != log.CriticalLvl {
+		t.Error("Expected the longest match to win")
+	}
+	if moduleLevel("tcs client") != log.InfoLvl {
+		t.Error("Expected tcs client to be unaffected")
+	}
+	if minLevel() != "debug" {
+		t.Error("Expected seelog to allow the lowest module level through, got " + minLevel())
+	}
+
+	agentLevel, modules := Levels()
+	if agentLevel != "info" || modules["acs"] != "debug" || modules["acshandler"] != "critical" {
+		t.Error("Unexpected levels", agentLevel, modules)
+	}
+
+	ResetModuleLevel("acs")
+	if moduleLevel("acs client") != log.InfoLvl || minLevel() != "info" {
+		t.Error("Expected reset module to return to the agent's level")
+	}
+}
+
+func TestDebugDump(t *testing.T) {
+	testTime := ttime.NewTestTime()
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+	SetLevel("warn")
+	defer SetLevel("info")
+
+	StartDebugDump(10 * time.Minute)
+	if moduleLevel("stats") != log.DebugLvl {
+		t.Error("Expected debug dump to log every module at debug")
+	}
+	if agentLevel, _ := Levels(); agentLevel != "debug" {
+		t.Error("Expected debug dump to be reported, got " + agentLevel)
+	}
+
+	testTime.Warp(11 * time.Minute)
+	for i := 0; i < 100 && moduleLevel("stats") == log.DebugLvl; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if moduleLevel("stats") != log.WarnLvl {
+		t.Error("Expected debug dump to expire")
+	}
+}
@@ -31,7 +31,6 @@ const (
 var logfile string
 var level string
 var levels map[string]string
-var logger OldLogger
 
 // Initialize this logger once
 var once sync.Once
@@ -51,11 +50,10 @@ func initLogger() {
 	}
 	level = DEFAULT_LOGLEVEL
 
-	logger = &Shim{}
-
 	envLevel := os.Getenv(LOGLEVEL_ENV_VAR)
 
 	logfile = os.Getenv(LOGFILE_ENV_VAR)
+	registerSanitizer()
 	SetLevel(envLevel)
 	reloadConfig()
 }
@@ -69,18 +67,28 @@ func reloadConfig() {
 	}
 }
 
+// LogFile returns the file the agent logs to, or "" if it only logs to the
+// console. Rotated logs are kept alongside it with the same prefix.
+func LogFile() string {
+	return logfile
+}
+
 // SetLevel sets the log level for logging
-func SetLevel(logLevel string) {
+func SetLevel(logLevel string) error {
 	parsedLevel, ok := levels[strings.ToLower(logLevel)]
-	if ok {
-		level = parsedLevel
-		reloadConfig()
+	if !ok {
+		return ErrUnknownLevel
 	}
+	levelLock.Lock()
+	level = parsedLevel
+	levelLock.Unlock()
+	reloadConfig()
+	return nil
 }
 
 // ForModule returns an OldLogger instance.  OldLogger is deprecated and kept
 // for compatibility reasons.  Prefer using Seelog directly.
 func ForModule(module string) OldLogger {
 	once.Do(initLogger)
-	return logger.New("module", module)
+	return &Shim{module: module, ctx: []interface{}{"module", module}}
 }
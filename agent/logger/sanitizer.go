@@ -0,0 +1,103 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package logger
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/cihub/seelog"
+)
+
+const (
+	// sanitizedValue replaces sensitive values in log messages
+	sanitizedValue = "[REDACTED]"
+
+	// minSecretLength is the shortest secret which is registered; redacting
+	// shorter values would mangle unrelated text
+	minSecretLength = 4
+
+	// sanitizedMsgFormatter is the seelog format verb for a sanitized message
+	sanitizedMsgFormatter = "CustomSanitizedMsg"
+)
+
+// sanitizerPattern replaces sensitive values recognised by their form
+type sanitizerPattern struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// sanitizerPatterns match the signatures and credentials of pre-signed urls
+// and signed requests, authorization tokens, AWS access key ids, and the
+// values of fields named like passwords, secrets and tokens
+var sanitizerPatterns = []sanitizerPattern{
+	{regexp.MustCompile(`(?i)(X-Amz-(?:Signature|Credential|Security-Token)=)[^&\s"']+`), "${1}" + sanitizedValue},
+	{regexp.MustCompile(`(?i)(Signature=)[0-9a-f]{64}`), "${1}" + sanitizedValue},
+	{regexp.MustCompile(`(?i)\b(Bearer|Basic)\s+[A-Za-z0-9+/=._~-]+`), "${1} " + sanitizedValue},
+	{regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), sanitizedValue},
+	{regexp.MustCompile(`(?i)((?:password|passwd|secret\w*|\w*token|auth)"?\s*[:=]\s*"?)[^"\s,&}]+`), "${1}" + sanitizedValue},
+}
+
+// secrets are the registered sensitive values
+var secrets = struct {
+	sync.RWMutex
+	values map[string]bool
+}{values: make(map[string]bool)}
+
+// RegisterSecret registers a sensitive value, such as a registry password, to
+// be removed from all log messages and introspection output until it is
+// unregistered
+func RegisterSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	secrets.Lock()
+	defer secrets.Unlock()
+	secrets.values[value] = true
+}
+
+// UnregisterSecret stops removing value from log messages
+func UnregisterSecret(value string) {
+	secrets.Lock()
+	defer secrets.Unlock()
+	delete(secrets.values, value)
+}
+
+// Sanitize removes registered secrets and values which look sensitive from
+// message
+func Sanitize(message string) string {
+	secrets.RLock()
+	for value := range secrets.values {
+		message = strings.Replace(message, value, sanitizedValue, -1)
+	}
+	secrets.RUnlock()
+	for _, sanitizer := range sanitizerPatterns {
+		message = sanitizer.pattern.ReplaceAllString(message, sanitizer.replacement)
+	}
+	return message
+}
+
+// registerSanitizer lets the seelog format sanitize every message, including
+// those logged through seelog directly
+func registerSanitizer() {
+	err := log.RegisterCustomFormatter(sanitizedMsgFormatter, func(string) log.FormatterFunc {
+		return func(message string, level log.LogLevel, context log.LogContextInterface) interface{} {
+			return Sanitize(message)
+		}
+	})
+	if err != nil {
+		log.Error(err)
+	}
+}
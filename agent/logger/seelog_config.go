@@ -15,7 +15,7 @@ package logger
 
 func loggerConfig() string {
 	config := `
-	<seelog type="asyncloop" minlevel="` + level + `">
+	<seelog type="asyncloop" minlevel="` + minLevel() + `">
 		<outputs formatid="main">
 			<console />`
 	if logfile != "" {
@@ -25,7 +25,7 @@ func loggerConfig() string {
 	config += `
 		</outputs>
 		<formats>
-			<format id="main" format="%UTCDate(2006-01-02T15:04:05Z07:00) [%LEVEL] %Msg%n" />
+			<format id="main" format="%UTCDate(2006-01-02T15:04:05Z07:00) [%LEVEL] %CustomSanitizedMsg%n" />
 		</formats>
 	</seelog>
 `
@@ -31,7 +31,8 @@ type OldLogger interface {
 }
 
 type Shim struct {
-	ctx []interface{}
+	module string
+	ctx    []interface{}
 }
 
 func (s *Shim) New(ctx ...interface{}) OldLogger {
@@ -40,27 +41,48 @@ func (s *Shim) New(ctx ...interface{}) OldLogger {
 		ctx = nil
 	}
 	return &Shim{
-		ctx: append(s.ctx, ctx...),
+		module: s.module,
+		ctx:    append(s.ctx, ctx...),
 	}
 }
 
+// enabled returns whether messages at lvl are logged for this module
+func (s *Shim) enabled(lvl log.LogLevel) bool {
+	return lvl >= moduleLevel(s.module)
+}
+
 func (s *Shim) Debug(msg string, ctx ...interface{}) {
+	if !s.enabled(log.DebugLvl) {
+		return
+	}
 	log.Debug(s.formatMessage(msg, ctx...))
 }
 
 func (s *Shim) Info(msg string, ctx ...interface{}) {
+	if !s.enabled(log.InfoLvl) {
+		return
+	}
 	log.Info(s.formatMessage(msg, ctx...))
 }
 
 func (s *Shim) Warn(msg string, ctx ...interface{}) {
+	if !s.enabled(log.WarnLvl) {
+		return
+	}
 	log.Warn(s.formatMessage(msg, ctx...))
 }
 
 func (s *Shim) Error(msg string, ctx ...interface{}) {
+	if !s.enabled(log.ErrorLvl) {
+		return
+	}
 	log.Error(s.formatMessage(msg, ctx...))
 }
 
 func (s *Shim) Crit(msg string, ctx ...interface{}) {
+	if !s.enabled(log.CriticalLvl) {
+		return
+	}
 	log.Critical(s.formatMessage(msg, ctx...))
 }
 
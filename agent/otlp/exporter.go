@@ -0,0 +1,86 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package otlp exports metrics and spans to an OpenTelemetry collector on the
+// host using OTLP over HTTP, with requests encoded as protobuf.
+package otlp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
+)
+
+const (
+	exportTimeout = 10 * time.Second
+
+	metricsExportPath = "/v1/metrics"
+	traceExportPath   = "/v1/traces"
+
+	protobufContentType = "application/x-protobuf"
+)
+
+// Exporter exports to one collector
+type Exporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewExporter returns an exporter to the collector listening for OTLP/HTTP at
+// endpoint, such as "http://127.0.0.1:4318". The scheme may be left out.
+func NewExporter(endpoint string) *Exporter {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	return &Exporter{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   httpclient.New(exportTimeout, false),
+	}
+}
+
+// ExportGauges exports gauges of the resource described by resource
+func (exporter *Exporter) ExportGauges(resource []Attribute, gauges []Gauge) error {
+	return exporter.export(metricsExportPath, marshalGauges(resource, gauges))
+}
+
+// ExportSpans exports spans of the resource described by resource
+func (exporter *Exporter) ExportSpans(resource []Attribute, spans []Span) error {
+	request, err := marshalSpans(resource, spans)
+	if err != nil {
+		return err
+	}
+	return exporter.export(traceExportPath, request)
+}
+
+// export posts an encoded request to the collector. The response message is
+// ignored.
+func (exporter *Exporter) export(path string, request []byte) error {
+	resp, err := exporter.client.Post(exporter.endpoint+path, protobufContentType, bytes.NewReader(request))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// The body is an encoded status, whose message is still readable
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("OTLP collector responded with status %d: %q", resp.StatusCode, body)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
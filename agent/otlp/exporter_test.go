@@ -0,0 +1,89 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package otlp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCollector records the request it receives and responds with status
+func testCollector(t *testing.T, status int, path *string, body *[]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Content-Type") != protobufContentType {
+			t.Error("Wrong request", r.Method, r.Header)
+		}
+		*path = r.URL.Path
+		*body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+}
+
+func TestExportGauges(t *testing.T) {
+	var path string
+	var body []byte
+	collector := testCollector(t, http.StatusOK, &path, &body)
+	defer collector.Close()
+
+	gauges := []Gauge{{Name: "cpu", Unit: "%", DataPoints: []DataPoint{{Time: time.Unix(1, 0), Value: 12.5}}}}
+	if err := NewExporter(collector.URL+"/").ExportGauges(nil, gauges); err != nil {
+		t.Fatal(err)
+	}
+	if path != metricsExportPath || !bytes.Equal(body, marshalGauges(nil, gauges)) {
+		t.Error("Wrong request", path)
+	}
+}
+
+func TestExportSpans(t *testing.T) {
+	var path string
+	var body []byte
+	collector := testCollector(t, http.StatusOK, &path, &body)
+	defer collector.Close()
+
+	spans := []Span{{TraceID: "0102", SpanID: "03", Name: "span", Kind: SpanKindInternal}}
+	expected, err := marshalSpans(nil, spans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without a scheme, http is assumed
+	if err := NewExporter(strings.TrimPrefix(collector.URL, "http://")).ExportSpans(nil, spans); err != nil {
+		t.Fatal(err)
+	}
+	if path != traceExportPath || !bytes.Equal(body, expected) {
+		t.Error("Wrong request", path)
+	}
+}
+
+func TestExportErrorStatus(t *testing.T) {
+	var path string
+	var body []byte
+	collector := testCollector(t, http.StatusServiceUnavailable, &path, &body)
+	defer collector.Close()
+
+	err := NewExporter(collector.URL).ExportGauges(nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "status 503") {
+		t.Error("Expected the status to be returned", err)
+	}
+}
+
+func TestExportInvalidSpanID(t *testing.T) {
+	if err := NewExporter("127.0.0.1:0").ExportSpans(nil, []Span{{TraceID: "not hex"}}); err == nil {
+		t.Error("Expected an invalid trace id to fail the export")
+	}
+}
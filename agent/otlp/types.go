@@ -0,0 +1,250 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"sort"
+	"time"
+)
+
+// scopeName is the instrumentation scope of everything the agent exports
+const scopeName = "amazon-ecs-agent"
+
+// Span kind and status codes
+const (
+	SpanKindInternal = 1
+	StatusCodeOk     = 1
+	StatusCodeError  = 2
+)
+
+// Attribute is a string valued attribute of a resource, data point or span
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Attributes returns attributes as a list sorted by key
+func Attributes(attributes map[string]string) []Attribute {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]Attribute, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, Attribute{Key: key, Value: attributes[key]})
+	}
+	return result
+}
+
+// Gauge is a metric whose data points are each its value at a time
+type Gauge struct {
+	Name       string
+	Unit       string
+	DataPoints []DataPoint
+}
+
+type DataPoint struct {
+	Attributes []Attribute
+	Time       time.Time
+	Value      float64
+}
+
+// Span is a completed operation of a trace. Ids are hex encoded, as in the
+// W3C trace context.
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	Kind         int
+	Start        time.Time
+	End          time.Time
+	Attributes   []Attribute
+	Status       Status
+}
+
+type Status struct {
+	Code    int
+	Message string
+}
+
+// protoBuffer encodes a protobuf message. Only the wire types OTLP needs are
+// supported, and fields with zero values are left out except where noted.
+type protoBuffer []byte
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func (buf *protoBuffer) varint(v uint64) {
+	for v >= 0x80 {
+		*buf = append(*buf, byte(v)|0x80)
+		v >>= 7
+	}
+	*buf = append(*buf, byte(v))
+}
+
+func (buf *protoBuffer) tag(field, wireType int) {
+	buf.varint(uint64(field<<3 | wireType))
+}
+
+func (buf *protoBuffer) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	buf.tag(field, wireVarint)
+	buf.varint(v)
+}
+
+func (buf *protoBuffer) bytes(field int, v []byte) {
+	if len(v) == 0 {
+		return
+	}
+	buf.tag(field, wireBytes)
+	buf.varint(uint64(len(v)))
+	*buf = append(*buf, v...)
+}
+
+func (buf *protoBuffer) string(field int, v string) {
+	buf.bytes(field, []byte(v))
+}
+
+func (buf *protoBuffer) fixed64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	buf.tag(field, wireFixed64)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	*buf = append(*buf, b[:]...)
+}
+
+// double is always encoded, as the data point values it is used for are
+// members of a oneof, whose presence matters even when zero
+func (buf *protoBuffer) double(field int, v float64) {
+	buf.tag(field, wireFixed64)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	*buf = append(*buf, b[:]...)
+}
+
+// message encodes the message written by encode as a field, even if it is
+// empty
+func (buf *protoBuffer) message(field int, encode func(*protoBuffer)) {
+	var nested protoBuffer
+	encode(&nested)
+	buf.tag(field, wireBytes)
+	buf.varint(uint64(len(nested)))
+	*buf = append(*buf, nested...)
+}
+
+func (buf *protoBuffer) attributes(field int, attributes []Attribute) {
+	for _, attribute := range attributes {
+		attribute := attribute
+		// KeyValue{key, value: AnyValue{string_value}}
+		buf.message(field, func(keyValue *protoBuffer) {
+			keyValue.string(1, attribute.Key)
+			keyValue.message(2, func(value *protoBuffer) {
+				value.string(1, attribute.Value)
+			})
+		})
+	}
+}
+
+func unixNano(t time.Time) uint64 {
+	return uint64(t.UnixNano())
+}
+
+// marshalRequest encodes an export request for one resource whose items, the
+// metrics or spans written by encodeItems, are all from the agent's scope.
+// Export requests for metrics and traces use the same field numbers for this
+// envelope.
+func marshalRequest(resource []Attribute, encodeItems func(*protoBuffer)) []byte {
+	var request protoBuffer
+	request.message(1, func(resourceItems *protoBuffer) {
+		resourceItems.message(1, func(r *protoBuffer) {
+			r.attributes(1, resource)
+		})
+		resourceItems.message(2, func(scopeItems *protoBuffer) {
+			scopeItems.message(1, func(scope *protoBuffer) {
+				scope.string(1, scopeName)
+			})
+			encodeItems(scopeItems)
+		})
+	})
+	return request
+}
+
+func marshalGauges(resource []Attribute, gauges []Gauge) []byte {
+	return marshalRequest(resource, func(scopeMetrics *protoBuffer) {
+		for _, gauge := range gauges {
+			gauge := gauge
+			scopeMetrics.message(2, func(metric *protoBuffer) {
+				metric.string(1, gauge.Name)
+				metric.string(3, gauge.Unit)
+				metric.message(5, func(g *protoBuffer) {
+					for _, point := range gauge.DataPoints {
+						point := point
+						g.message(1, func(p *protoBuffer) {
+							p.fixed64(3, unixNano(point.Time))
+							p.double(4, point.Value)
+							p.attributes(7, point.Attributes)
+						})
+					}
+				})
+			})
+		}
+	})
+}
+
+func marshalSpans(resource []Attribute, spans []Span) ([]byte, error) {
+	encoded := make([]protoBuffer, 0, len(spans))
+	for _, span := range spans {
+		var s protoBuffer
+		for _, id := range []struct {
+			field int
+			hex   string
+		}{{1, span.TraceID}, {2, span.SpanID}, {4, span.ParentSpanID}} {
+			b, err := hex.DecodeString(id.hex)
+			if err != nil {
+				return nil, err
+			}
+			s.bytes(id.field, b)
+		}
+		s.string(5, span.Name)
+		s.uint(6, uint64(span.Kind))
+		s.fixed64(7, unixNano(span.Start))
+		s.fixed64(8, unixNano(span.End))
+		s.attributes(9, span.Attributes)
+		s.message(15, func(status *protoBuffer) {
+			status.string(2, span.Status.Message)
+			status.uint(3, uint64(span.Status.Code))
+		})
+		encoded = append(encoded, s)
+	}
+	return marshalRequest(resource, func(scopeSpans *protoBuffer) {
+		for _, s := range encoded {
+			s := s
+			scopeSpans.message(2, func(span *protoBuffer) {
+				*span = append(*span, s...)
+			})
+		}
+	}), nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package otlp
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testFields decodes a protobuf message into the values of its fields, by
+// number. Varints are returned as uint64 and fixed64s as [8]byte.
+func testFields(t *testing.T, message []byte) map[int][]interface{} {
+	fields := make(map[int][]interface{})
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			t.Fatal("Invalid field key")
+		}
+		message = message[n:]
+		var value interface{}
+		switch key & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(message)
+			if n <= 0 {
+				t.Fatal("Invalid varint")
+			}
+			value, message = v, message[n:]
+		case wireFixed64:
+			var v [8]byte
+			copy(v[:], message)
+			value, message = v, message[8:]
+		case wireBytes:
+			length, n := binary.Uvarint(message)
+			if n <= 0 || int(length) > len(message)-n {
+				t.Fatal("Invalid length")
+			}
+			value, message = message[n:n+int(length)], message[n+int(length):]
+		default:
+			t.Fatal("Unexpected wire type", key&7)
+		}
+		fields[int(key>>3)] = append(fields[int(key>>3)], value)
+	}
+	return fields
+}
+
+// testField returns the only value of a field
+func testField(t *testing.T, message []byte, path ...int) interface{} {
+	var value interface{} = message
+	for _, field := range path {
+		values := testFields(t, value.([]byte))[field]
+		if len(values) != 1 {
+			t.Fatalf("Expected one value of field %d, got %d", field, len(values))
+		}
+		value = values[0]
+	}
+	return value
+}
+
+func TestMarshalGauges(t *testing.T) {
+	resource := Attributes(map[string]string{"b": "2", "a": "1"})
+	request := marshalGauges(resource, []Gauge{{Name: "cpu", Unit: "%", DataPoints: []DataPoint{
+		{Attributes: []Attribute{{"task", "arn"}}, Time: time.Unix(0, 42), Value: 0},
+	}}})
+
+	// ExportMetricsServiceRequest.resource_metrics.resource.attributes
+	attributes := testFields(t, testField(t, request, 1, 1).([]byte))[1]
+	if len(attributes) != 2 || string(testField(t, attributes[0].([]byte), 1).([]byte)) != "a" || string(testField(t, attributes[1].([]byte), 2, 1).([]byte)) != "2" {
+		t.Error("Expected the resource attributes in order")
+	}
+	// .scope_metrics
+	scopeMetrics := testField(t, request, 1, 2).([]byte)
+	if string(testField(t, scopeMetrics, 1, 1).([]byte)) != scopeName {
+		t.Error("Wrong scope")
+	}
+	metric := testField(t, scopeMetrics, 2).([]byte)
+	if string(testField(t, metric, 1).([]byte)) != "cpu" || string(testField(t, metric, 3).([]byte)) != "%" {
+		t.Error("Wrong metric")
+	}
+	point := testField(t, metric, 5, 1).([]byte)
+	timestamp, value := testField(t, point, 3).([8]byte), testField(t, point, 4).([8]byte)
+	if binary.LittleEndian.Uint64(timestamp[:]) != 42 || math.Float64frombits(binary.LittleEndian.Uint64(value[:])) != 0 {
+		t.Error("Wrong data point; a zero value must still be set")
+	}
+	if string(testField(t, point, 7, 2, 1).([]byte)) != "arn" {
+		t.Error("Wrong data point attributes")
+	}
+}
+
+func TestMarshalSpans(t *testing.T) {
+	request, err := marshalSpans(nil, []Span{{
+		TraceID: "0102",
+		SpanID:  "03",
+		Name:    "pull",
+		Kind:    SpanKindInternal,
+		Status:  Status{Code: StatusCodeError, Message: "failed"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := testField(t, request, 1, 2, 2).([]byte)
+	fields := testFields(t, span)
+	if !reflect.DeepEqual(fields[1], []interface{}{[]byte{1, 2}}) || !reflect.DeepEqual(fields[2], []interface{}{[]byte{3}}) || len(fields[4]) != 0 {
+		t.Error("Wrong span ids", fields)
+	}
+	if string(testField(t, span, 5).([]byte)) != "pull" || testField(t, span, 6).(uint64) != SpanKindInternal {
+		t.Error("Wrong span", fields)
+	}
+	if testField(t, span, 15, 3).(uint64) != StatusCodeError || string(testField(t, span, 15, 2).([]byte)) != "failed" {
+		t.Error("Wrong span status")
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package replay
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+var errNoSuchContainer = errors.New("No such container")
+
+// fakeDockerClient is an in-memory docker which completes every call after a
+// fixed latency. Transitions are reported by the calls' return values, so it
+// never emits events.
+type fakeDockerClient struct {
+	latency time.Duration
+
+	lock       sync.Mutex
+	nextID     int
+	containers map[string]api.ContainerStatus
+	names      map[string]string
+}
+
+func newFakeDockerClient(latency time.Duration) *fakeDockerClient {
+	return &fakeDockerClient{
+		latency:    latency,
+		containers: make(map[string]api.ContainerStatus),
+		names:      make(map[string]string),
+	}
+}
+
+func (client *fakeDockerClient) wait() {
+	if client.latency > 0 {
+		time.Sleep(client.latency)
+	}
+}
+
+// transition moves a container to status if it exists
+func (client *fakeDockerClient) transition(id string, status api.ContainerStatus) engine.DockerContainerMetadata {
+	client.wait()
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	if _, ok := client.containers[id]; !ok {
+		return engine.DockerContainerMetadata{Error: errNoSuchContainer}
+	}
+	client.containers[id] = status
+	metadata := engine.DockerContainerMetadata{DockerId: id}
+	if status == api.ContainerStopped {
+		exitCode := 0
+		metadata.ExitCode = &exitCode
+	}
+	return metadata
+}
+
+func (client *fakeDockerClient) ContainerEvents(ctx context.Context) (<-chan engine.DockerContainerChangeEvent, error) {
+	return make(chan engine.DockerContainerChangeEvent), nil
+}
+
+func (client *fakeDockerClient) PullImage(ctx context.Context, image string) engine.DockerContainerMetadata {
+	client.wait()
+	return engine.DockerContainerMetadata{}
+}
+
+func (client *fakeDockerClient) CreateContainer(config *docker.Config, hostConfig *docker.HostConfig, name string) engine.DockerContainerMetadata {
+	client.wait()
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	client.nextID++
+	id := strconv.Itoa(client.nextID)
+	client.containers[id] = api.ContainerCreated
+	client.names[id] = name
+	return engine.DockerContainerMetadata{DockerId: id}
+}
+
+func (client *fakeDockerClient) StartContainer(id string) engine.DockerContainerMetadata {
+	return client.transition(id, api.ContainerRunning)
+}
+
+func (client *fakeDockerClient) StopContainer(id string, timeout time.Duration) engine.DockerContainerMetadata {
+	return client.transition(id, api.ContainerStopped)
+}
+
+func (client *fakeDockerClient) StopContainerWithSignal(id string, signal docker.Signal, timeout time.Duration) engine.DockerContainerMetadata {
+	return client.transition(id, api.ContainerStopped)
+}
+
+func (client *fakeDockerClient) DescribeContainer(id string) (api.ContainerStatus, engine.DockerContainerMetadata) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	status, ok := client.containers[id]
+	if !ok {
+		return api.ContainerStatusNone, engine.DockerContainerMetadata{Error: errNoSuchContainer}
+	}
+	return status, engine.DockerContainerMetadata{DockerId: id}
+}
+
+func (client *fakeDockerClient) RemoveContainer(id string) error {
+	client.wait()
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	if _, ok := client.containers[id]; !ok {
+		return errNoSuchContainer
+	}
+	delete(client.containers, id)
+	delete(client.names, id)
+	return nil
+}
+
+func (client *fakeDockerClient) RemoveImage(image string) error {
+	client.wait()
+	return nil
+}
+
+func (client *fakeDockerClient) TagImage(image, name string) error {
+	client.wait()
+	return nil
+}
+
+func (client *fakeDockerClient) EventQueueDepth() engine.EventQueueDepth {
+	return engine.EventQueueDepth{}
+}
+
+func (client *fakeDockerClient) GetContainerName(id string) (string, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	name, ok := client.names[id]
+	if !ok {
+		return "", errNoSuchContainer
+	}
+	return name, nil
+}
+
+func (client *fakeDockerClient) InspectContainer(name string) (*docker.Container, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	for id, containerName := range client.names {
+		if containerName == name || id == name {
+			return &docker.Container{ID: id, Name: containerName}, nil
+		}
+	}
+	return nil, errNoSuchContainer
+}
+
+func (client *fakeDockerClient) ImageRepoDigests(image string) ([]string, error) {
+	return nil, nil
+}
+
+func (client *fakeDockerClient) ContainerLogs(dockerID string, tail int, follow bool, output io.Writer) error {
+	return nil
+}
+
+func (client *fakeDockerClient) ListContainers(all bool) engine.ListContainersResponse {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	ids := make([]string, 0, len(client.containers))
+	for id, status := range client.containers {
+		if all || status == api.ContainerRunning {
+			ids = append(ids, id)
+		}
+	}
+	return engine.ListContainersResponse{DockerIds: ids}
+}
+
+func (client *fakeDockerClient) Info() (*docker.Env, error) {
+	return &docker.Env{"Driver=replay"}, nil
+}
+
+func (client *fakeDockerClient) Version() (string, error) {
+	return "replay", nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package replay measures the task engine's throughput and memory use by
+// replaying recorded ACS payloads against an in-memory docker.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/awslabs/aws-sdk-go/internal/protocol/json/jsonutil"
+)
+
+var log = logger.ForModule("replay")
+
+// defaultTimeout is used when the options do not specify a timeout
+const defaultTimeout = 10 * time.Minute
+
+// Options control a replay
+type Options struct {
+	// Iterations is how many times the payloads are replayed. Each iteration
+	// uses distinct task arns.
+	Iterations int
+
+	// DockerLatency is how long each call to the fake docker takes
+	DockerLatency time.Duration
+
+	// Timeout bounds how long the replay waits for tasks to run and stop
+	Timeout time.Duration
+}
+
+// Result describes the engine's performance during a replay
+type Result struct {
+	Tasks int
+
+	// TimeToRunning is how long it took from adding the first task until
+	// every task was running; TimeToStopped is how long it then took to stop
+	// them all
+	TimeToRunning  time.Duration
+	TimeToStopped  time.Duration
+	TasksPerSecond float64
+
+	// Memory statistics, in bytes, sampled once every task has stopped
+	HeapAlloc  uint64
+	TotalAlloc uint64
+	NumGC      uint32
+	Goroutines int
+}
+
+// ReadPayloads reads newline delimited payload messages, in the json form
+// logged by ECS_DUMP_ACS_PAYLOADS. Blank lines are ignored.
+func ReadPayloads(reader io.Reader) ([]*ecsacs.PayloadMessage, error) {
+	var payloads []*ecsacs.PayloadMessage
+	// Payloads can be larger than a bufio.Scanner's line limit
+	buffered := bufio.NewReader(reader)
+	for {
+		line, err := buffered.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			payload := &ecsacs.PayloadMessage{}
+			if err := jsonutil.UnmarshalJSON(payload, bytes.NewReader(line)); err != nil {
+				return nil, err
+			}
+			payloads = append(payloads, payload)
+		}
+		if err == io.EOF {
+			return payloads, nil
+		}
+	}
+}
+
+// Run replays the tasks to be run in payloads against a new task engine,
+// waits for all of them to run, stops them, and reports how the engine
+// performed
+func Run(cfg *config.Config, payloads []*ecsacs.PayloadMessage, options Options) (*Result, error) {
+	iterations := options.Iterations
+	if iterations <= 0 {
+		iterations = 1
+	}
+	var tasks []*api.Task
+	for iteration := 0; iteration < iterations; iteration++ {
+		for _, payload := range payloads {
+			for _, acsTask := range payload.Tasks {
+				task, err := api.TaskFromACS(acsTask, payload)
+				if err != nil {
+					return nil, err
+				}
+				if task.DesiredStatus != api.TaskRunning {
+					continue
+				}
+				task.Arn += "-" + strconv.Itoa(iteration)
+				tasks = append(tasks, task)
+			}
+		}
+	}
+	if len(tasks) == 0 {
+		return nil, errors.New("No tasks to run in the replayed payloads")
+	}
+
+	// Images aren't really pulled, so there are no manifests to read
+	replayCfg := *cfg
+	replayCfg.ImagePlatformSelectionEnabled = false
+	taskEngine := engine.NewDockerTaskEngine(&replayCfg)
+	taskEngine.SetDockerClient(newFakeDockerClient(options.DockerLatency))
+	if err := taskEngine.Init(); err != nil {
+		return nil, err
+	}
+	defer taskEngine.Shutdown()
+
+	taskEvents, containerEvents := taskEngine.TaskEvents()
+	go func() {
+		for range containerEvents {
+		}
+	}()
+	timeoutDuration := options.Timeout
+	if timeoutDuration <= 0 {
+		timeoutDuration = defaultTimeout
+	}
+	timeout := time.After(timeoutDuration)
+	waitFor := func(status api.TaskStatus) error {
+		remaining := make(map[string]bool)
+		for _, task := range tasks {
+			remaining[task.Arn] = true
+		}
+		for len(remaining) > 0 {
+			select {
+			case event := <-taskEvents:
+				if event.Status >= status {
+					delete(remaining, event.TaskArn)
+				}
+			case <-timeout:
+				return errors.New("Timed out with " + strconv.Itoa(len(remaining)) + " tasks not " + status.String())
+			}
+		}
+		return nil
+	}
+
+	result := &Result{Tasks: len(tasks)}
+	start := time.Now()
+	for _, task := range tasks {
+		taskEngine.AddTask(task)
+	}
+	if err := waitFor(api.TaskRunning); err != nil {
+		return nil, err
+	}
+	result.TimeToRunning = time.Since(start)
+	log.Info("All replayed tasks running", "tasks", len(tasks), "duration", result.TimeToRunning.String())
+
+	stopStart := time.Now()
+	for _, task := range tasks {
+		taskEngine.AddTask(&api.Task{Arn: task.Arn, DesiredStatus: api.TaskStopped})
+	}
+	if err := waitFor(api.TaskStopped); err != nil {
+		return nil, err
+	}
+	result.TimeToStopped = time.Since(stopStart)
+	result.TasksPerSecond = float64(len(tasks)) / (result.TimeToRunning + result.TimeToStopped).Seconds()
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	result.HeapAlloc = memStats.HeapAlloc
+	result.TotalAlloc = memStats.TotalAlloc
+	result.NumGC = memStats.NumGC
+	result.Goroutines = runtime.NumGoroutine()
+	return result, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package replay
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+)
+
+const testPayloads = `
+{"tasks":[{"arn":"arn1","desiredStatus":"RUNNING","overrides":"{}","family":"test","version":"1","containers":[{"name":"c1","image":"redis","cpu":10,"memory":20,"essential":true,"overrides":"{}","desiredStatus":"RUNNING"},{"name":"c2","image":"busybox","cpu":10,"memory":20,"essential":true,"overrides":"{}","desiredStatus":"RUNNING"}]}],"messageId":"messageId1"}
+
+{"tasks":[{"arn":"arn2","desiredStatus":"RUNNING","overrides":"{}","family":"test","version":"1","containers":[{"name":"c1","image":"redis","cpu":10,"memory":20,"essential":true,"overrides":"{}","desiredStatus":"RUNNING"}]},{"arn":"arn3","desiredStatus":"STOPPED","overrides":"{}","family":"test","version":"1","containers":[]}],"messageId":"messageId2"}
+`
+
+func TestReadPayloads(t *testing.T) {
+	payloads, err := ReadPayloads(strings.NewReader(testPayloads))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 2 || len(payloads[1].Tasks) != 2 || *payloads[0].MessageId != "messageId1" {
+		t.Error("Unexpected payloads", payloads)
+	}
+
+	// A payload larger than a bufio.Scanner's line, without a newline
+	messageID := strings.Repeat("m", 128*1024)
+	payloads, err = ReadPayloads(strings.NewReader(`{"tasks":[],"messageId":"` + messageID + `"}`))
+	if err != nil || len(payloads) != 1 || *payloads[0].MessageId != messageID {
+		t.Error("Expected a large payload to be read", err)
+	}
+
+	if _, err := ReadPayloads(strings.NewReader("{not json")); err == nil {
+		t.Error("Expected an error for malformed payloads")
+	}
+}
+
+func TestRun(t *testing.T) {
+	payloads, err := ReadPayloads(strings.NewReader(testPayloads))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	result, err := Run(&cfg, payloads, Options{Iterations: 3, DockerLatency: time.Millisecond, Timeout: 30 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only tasks which should be running are replayed
+	if result.Tasks != 6 {
+		t.Error("Expected 6 tasks, got", result.Tasks)
+	}
+	if result.TimeToRunning <= 0 || result.TimeToStopped <= 0 || result.TasksPerSecond <= 0 {
+		t.Error("Expected timings", result)
+	}
+	if result.HeapAlloc == 0 || result.Goroutines == 0 {
+		t.Error("Expected memory statistics", result)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build benchmark
+// +build benchmark
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/replay"
+	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
+	log "github.com/cihub/seelog"
+)
+
+// The replay flags only exist in agents built with the benchmark tag
+var (
+	replayFile       = flag.String("replay", "", "Replay the ACS payloads in this file against a fake docker, print the engine's performance, and exit")
+	replayIterations = flag.Int("replay-iterations", 1, "Number of times to replay the payloads")
+	replayLatency    = flag.Duration("replay-docker-latency", 0, "Duration of each fake docker call")
+	replayTimeout    = flag.Duration("replay-timeout", 10*time.Minute, "Maximum duration of the replay")
+)
+
+func init() {
+	replayMain = runReplay
+}
+
+// runReplay replays payloads if the replay flag was given. It returns the
+// exit code and true if it did.
+func runReplay() (int, bool) {
+	if *replayFile == "" {
+		return 0, false
+	}
+	file, err := os.Open(*replayFile)
+	if err != nil {
+		log.Criticalf("Error opening payloads to replay: %v", err)
+		return exitcodes.ExitError, true
+	}
+	defer file.Close()
+	payloads, err := replay.ReadPayloads(file)
+	if err != nil {
+		log.Criticalf("Error reading payloads to replay: %v", err)
+		return exitcodes.ExitError, true
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Merge(config.EnvironmentConfig())
+	result, err := replay.Run(&cfg, payloads, replay.Options{
+		Iterations:    *replayIterations,
+		DockerLatency: *replayLatency,
+		Timeout:       *replayTimeout,
+	})
+	if err != nil {
+		log.Criticalf("Error replaying payloads: %v", err)
+		return exitcodes.ExitError, true
+	}
+	json.NewEncoder(os.Stdout).Encode(result)
+	return exitcodes.ExitSuccess, true
+}
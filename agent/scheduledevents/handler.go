@@ -0,0 +1,128 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package scheduledevents watches for events EC2 schedules for the instance,
+// such as its retirement, and optionally drains the container instance ahead
+// of them.
+package scheduledevents
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+var log = logger.ForModule("scheduled events")
+
+// pollInterval is how often the instance metadata service is checked for
+// scheduled events. EC2 schedules retirement and maintenance days in
+// advance, so this need not be frequent.
+const pollInterval = 5 * time.Minute
+
+// eventActive is the state of a scheduled event which has not yet completed
+// or been canceled
+const eventActive = "active"
+
+// drainEventCodes are the codes of the scheduled events which take the
+// instance, or its host, away for long enough that services should be
+// replaced elsewhere. Reboots are only logged.
+var drainEventCodes = map[string]bool{
+	"instance-retirement": true,
+	"instance-stop":       true,
+	"system-maintenance":  true,
+}
+
+// StartHandler watches for events EC2 schedules for the instance, such as its
+// retirement or maintenance of its host, and logs each one. If drain is set,
+// the container instance is also drained while a retirement, stop or
+// maintenance event is scheduled, so that services are replaced elsewhere
+// before the instance goes away, and set back to active once no such event
+// is.
+func StartHandler(metadata ec2.EC2MetadataClient, client api.ECSClient, containerInstanceArn string, drain bool) {
+	handler := newHandler(metadata, client, containerInstanceArn, drain)
+	for {
+		handler.checkScheduledEvents()
+		ttime.Sleep(pollInterval)
+	}
+}
+
+type handler struct {
+	metadata             ec2.EC2MetadataClient
+	client               api.ECSClient
+	containerInstanceArn string
+	drain                bool
+
+	// logged is the ids of the events already logged
+	logged map[string]bool
+	// drainedFor is the id of the event the container instance was drained
+	// for, if it was
+	drainedFor string
+}
+
+func newHandler(metadata ec2.EC2MetadataClient, client api.ECSClient, containerInstanceArn string, drain bool) *handler {
+	return &handler{
+		metadata:             metadata,
+		client:               client,
+		containerInstanceArn: containerInstanceArn,
+		drain:                drain,
+		logged:               make(map[string]bool),
+	}
+}
+
+// checkScheduledEvents logs each active event not yet logged, then drains the
+// container instance if an event it should be drained for is active, or
+// reactivates it if it was drained and none is any longer. A failed drain or
+// reactivation is retried at the next check.
+func (handler *handler) checkScheduledEvents() {
+	events, err := handler.metadata.ScheduledEvents()
+	if err != nil {
+		log.Debug("Unable to read scheduled events", "err", err)
+		return
+	}
+	drainFor := ""
+	for _, event := range events {
+		if event.State != eventActive {
+			continue
+		}
+		if !handler.logged[event.EventId] {
+			log.Warn("EC2 has scheduled an event for this instance", "code", event.Code, "id", event.EventId, "notBefore", event.NotBefore, "notAfter", event.NotAfter, "description", event.Description)
+			handler.logged[event.EventId] = true
+		}
+		// Keep to the event already drained for while it is active
+		if drainEventCodes[event.Code] && (drainFor == "" || event.EventId == handler.drainedFor) {
+			drainFor = event.EventId
+		}
+	}
+	if !handler.drain {
+		return
+	}
+
+	switch {
+	case drainFor != "" && handler.drainedFor == "":
+		if err := handler.client.DrainContainerInstance(handler.containerInstanceArn); err != nil {
+			log.Error("Unable to drain before the scheduled event; retrying", "id", drainFor, "err", err)
+			return
+		}
+		log.Info("Drained the container instance for the scheduled event", "id", drainFor)
+	case drainFor == "" && handler.drainedFor != "":
+		if err := handler.client.ActivateContainerInstance(handler.containerInstanceArn); err != nil {
+			log.Error("Unable to reactivate after the scheduled event; retrying", "id", handler.drainedFor, "err", err)
+			return
+		}
+		log.Info("Reactivated the container instance after the scheduled event", "id", handler.drainedFor)
+	}
+	handler.drainedFor = drainFor
+}
@@ -0,0 +1,134 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scheduledevents
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
+	"github.com/aws/amazon-ecs-agent/agent/ec2/mocks"
+	"github.com/golang/mock/gomock"
+)
+
+var (
+	retirementEvent  = ec2.ScheduledEvent{Code: "instance-retirement", EventId: "instance-event-1", State: "active"}
+	maintenanceEvent = ec2.ScheduledEvent{Code: "system-maintenance", EventId: "instance-event-2", State: "active"}
+	rebootEvent      = ec2.ScheduledEvent{Code: "system-reboot", EventId: "instance-event-3", State: "active"}
+)
+
+func TestHandlerDrainsOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	metadata := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	client := mock_api.NewMockECSClient(ctrl)
+
+	metadata.EXPECT().ScheduledEvents().Return([]ec2.ScheduledEvent{retirementEvent, maintenanceEvent, rebootEvent}, nil).Times(2)
+	client.EXPECT().DrainContainerInstance("arn").Return(nil)
+
+	handler := newHandler(metadata, client, "arn", true)
+	handler.checkScheduledEvents()
+	handler.checkScheduledEvents()
+	if len(handler.logged) != 3 {
+		t.Error("Expected the three active events to be logged, got", handler.logged)
+	}
+	if handler.drainedFor != retirementEvent.EventId {
+		t.Error("Expected to be drained for the retirement, got", handler.drainedFor)
+	}
+}
+
+func TestHandlerDoesNotDrainForReboots(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	metadata := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	client := mock_api.NewMockECSClient(ctrl)
+
+	metadata.EXPECT().ScheduledEvents().Return([]ec2.ScheduledEvent{rebootEvent}, nil)
+
+	handler := newHandler(metadata, client, "arn", true)
+	handler.checkScheduledEvents()
+	if handler.drainedFor != "" {
+		t.Error("Expected not to drain for a reboot")
+	}
+}
+
+func TestHandlerRetriesDrain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	metadata := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	client := mock_api.NewMockECSClient(ctrl)
+
+	metadata.EXPECT().ScheduledEvents().Return([]ec2.ScheduledEvent{retirementEvent}, nil).Times(2)
+	gomock.InOrder(
+		client.EXPECT().DrainContainerInstance("arn").Return(errors.New("throttled")),
+		client.EXPECT().DrainContainerInstance("arn").Return(nil),
+	)
+
+	handler := newHandler(metadata, client, "arn", true)
+	handler.checkScheduledEvents()
+	if handler.drainedFor != "" {
+		t.Error("Expected not to be drained after the drain failed")
+	}
+	handler.checkScheduledEvents()
+	if handler.drainedFor != retirementEvent.EventId {
+		t.Error("Expected to be drained after retrying")
+	}
+}
+
+func TestHandlerReactivatesAfterEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	metadata := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	client := mock_api.NewMockECSClient(ctrl)
+
+	completed := maintenanceEvent
+	completed.State = "completed"
+	gomock.InOrder(
+		metadata.EXPECT().ScheduledEvents().Return([]ec2.ScheduledEvent{maintenanceEvent}, nil),
+		client.EXPECT().DrainContainerInstance("arn").Return(nil),
+		metadata.EXPECT().ScheduledEvents().Return([]ec2.ScheduledEvent{completed}, nil),
+		client.EXPECT().ActivateContainerInstance("arn").Return(errors.New("throttled")),
+		metadata.EXPECT().ScheduledEvents().Return([]ec2.ScheduledEvent{completed}, nil),
+		client.EXPECT().ActivateContainerInstance("arn").Return(nil),
+		metadata.EXPECT().ScheduledEvents().Return([]ec2.ScheduledEvent{completed}, nil),
+	)
+
+	handler := newHandler(metadata, client, "arn", true)
+	handler.checkScheduledEvents()
+	handler.checkScheduledEvents()
+	if handler.drainedFor == "" {
+		t.Error("Expected to stay drained until reactivating succeeds")
+	}
+	handler.checkScheduledEvents()
+	handler.checkScheduledEvents()
+	if handler.drainedFor != "" {
+		t.Error("Expected to be reactivated once the event completed")
+	}
+}
+
+func TestHandlerWithoutDrain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	metadata := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	client := mock_api.NewMockECSClient(ctrl)
+
+	metadata.EXPECT().ScheduledEvents().Return([]ec2.ScheduledEvent{retirementEvent}, nil)
+
+	handler := newHandler(metadata, client, "arn", false)
+	handler.checkScheduledEvents()
+	if handler.drainedFor != "" {
+		t.Error("Expected not to drain when draining is disabled")
+	}
+}
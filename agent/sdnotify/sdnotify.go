@@ -0,0 +1,95 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sdnotify implements the systemd notification protocol so that the
+// agent may run as a Type=notify service with a watchdog.
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"golang.org/x/net/context"
+)
+
+var log = logger.ForModule("sdnotify")
+
+// Notification states understood by systemd
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Notify sends state to systemd. It returns false, without error, if the
+// agent was not started by systemd with a notification socket.
+func Notify(state string) (bool, error) {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return false, nil
+	}
+	if socketPath[0] == '@' {
+		// An abstract socket
+		socketPath = "\x00" + socketPath[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the interval systemd expects watchdog
+// notifications within, or 0 if the watchdog is not enabled for the agent
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		// The watchdog is meant for another process
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// RunWatchdog notifies systemd at half of interval for as long as healthy
+// returns true, until ctx is cancelled. If the agent becomes unhealthy the
+// notifications stop and systemd restarts it once interval has passed.
+func RunWatchdog(ctx context.Context, interval time.Duration, healthy func() bool) {
+	if interval <= 0 {
+		return
+	}
+	for {
+		if healthy() {
+			if _, err := Notify(Watchdog); err != nil {
+				log.Warn("Unable to notify systemd watchdog", "err", err)
+			}
+		} else {
+			log.Warn("Agent unhealthy; withholding systemd watchdog notification")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ttime.After(interval / 2):
+		}
+	}
+}
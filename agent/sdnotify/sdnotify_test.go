@@ -0,0 +1,126 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sdnotify
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"golang.org/x/net/context"
+)
+
+func listen(t *testing.T) (*net.UnixConn, func()) {
+	dir, err := ioutil.TempDir("", "sdnotify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socketPath := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("NOTIFY_SOCKET", socketPath)
+	return conn, func() {
+		os.Unsetenv("NOTIFY_SOCKET")
+		conn.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func read(t *testing.T, conn *net.UnixConn) string {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestNotify(t *testing.T) {
+	os.Unsetenv("NOTIFY_SOCKET")
+	if sent, err := Notify(Ready); sent || err != nil {
+		t.Error("Expected no notification without a socket", sent, err)
+	}
+
+	conn, done := listen(t)
+	defer done()
+	if sent, err := Notify(Ready); !sent || err != nil {
+		t.Fatal("Expected notification to be sent", sent, err)
+	}
+	if state := read(t, conn); state != Ready {
+		t.Error("Unexpected state " + state)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	defer os.Unsetenv("WATCHDOG_USEC")
+	defer os.Unsetenv("WATCHDOG_PID")
+
+	os.Unsetenv("WATCHDOG_USEC")
+	if WatchdogInterval() != 0 {
+		t.Error("Expected no watchdog without WATCHDOG_USEC")
+	}
+	os.Setenv("WATCHDOG_USEC", "30000000")
+	if WatchdogInterval() != 30*time.Second {
+		t.Error("Expected a 30 second watchdog", WatchdogInterval())
+	}
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+	if WatchdogInterval() != 0 {
+		t.Error("Expected no watchdog for another process")
+	}
+}
+
+func TestRunWatchdogWithholdsWhenUnhealthy(t *testing.T) {
+	testTime := ttime.NewTestTime()
+	testTime.LudicrousSpeed(true)
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	conn, done := listen(t)
+	defer done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	checks := 0
+	finished := make(chan struct{})
+	go func() {
+		RunWatchdog(ctx, time.Minute, func() bool {
+			checks++
+			if checks == 3 {
+				cancel()
+			}
+			return checks != 2
+		})
+		close(finished)
+	}()
+	<-finished
+
+	// Two of the three checks were healthy
+	if state := read(t, conn); state != Watchdog {
+		t.Error("Unexpected state " + state)
+	}
+	if state := read(t, conn); state != Watchdog {
+		t.Error("Unexpected state " + state)
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := conn.Read(make([]byte, 64)); err == nil {
+		t.Error("Expected no notification for the unhealthy check")
+	}
+}
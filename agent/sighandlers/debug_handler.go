@@ -0,0 +1,135 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sighandlers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+)
+
+// debugDumpDuration is how long debug logging stays enabled after SIGUSR1
+const debugDumpDuration = 10 * time.Minute
+
+// maxStackDumpSize caps the size of the goroutine dump
+const maxStackDumpSize = 8 * 1024 * 1024
+
+// debugDumpChunkSize is the most of the dump logged in one message, so that
+// each log line stays within what log files and collectors handle well
+const debugDumpChunkSize = 64 * 1024
+
+// StatsQueueSizer reports how many stats are queued for each container
+type StatsQueueSizer interface {
+	QueueSizes() map[string]int
+}
+
+// StartDebugHandler handles SIGUSR1 by logging the stacks of all goroutines,
+// the state of each task and container the engine knows about, and the
+// number of stats queued for each container, so that a hung agent can be
+// diagnosed without killing it. It also enables debug logging for
+// debugDumpDuration.
+func StartDebugHandler(taskEngine engine.TaskEngine, statsEngine StatsQueueSizer) {
+	signalChannel := make(chan os.Signal, 1)
+	signal.Notify(signalChannel, syscall.SIGUSR1)
+
+	for sig := range signalChannel {
+		log.Info("Received debug signal", "signal", sig.String())
+		logger.StartDebugDump(debugDumpDuration)
+		chunks := splitDump(debugDump(taskEngine, statsEngine), debugDumpChunkSize)
+		for i, chunk := range chunks {
+			log.Info(fmt.Sprintf("Debug dump (%d/%d)\n", i+1, len(chunks)) + chunk)
+		}
+	}
+}
+
+// debugDump describes the agent's goroutines, tasks, and stats queues
+func debugDump(taskEngine engine.TaskEngine, statsEngine StatsQueueSizer) string {
+	var dump bytes.Buffer
+
+	dump.WriteString("=== Tasks\n")
+	tasks, err := taskEngine.ListTasks()
+	if err != nil {
+		fmt.Fprintf(&dump, "Unable to list tasks: %v\n", err)
+	}
+	for _, task := range tasks {
+		// The engine updates the statuses while they are read, so each is
+		// read under its task's or container's lock
+		known, desired, sent := task.GetKnownStatus(), task.GetDesiredStatus(), task.GetSentStatus()
+		fmt.Fprintf(&dump, "%s: known=%s desired=%s sent=%s since=%s\n", task.Arn, known.String(), desired.String(), sent.String(), task.GetKnownStatusTime().Format(time.RFC3339))
+		for _, container := range task.Containers {
+			known, desired, applied, sent := container.GetKnownStatus(), container.GetDesiredStatus(), container.GetAppliedStatus(), container.GetSentStatus()
+			fmt.Fprintf(&dump, "  %s: known=%s desired=%s applied=%s sent=%s\n", container.Name, known.String(), desired.String(), applied.String(), sent.String())
+		}
+	}
+
+	dump.WriteString("=== Stats queues\n")
+	if statsEngine != nil {
+		sizes := statsEngine.QueueSizes()
+		ids := make([]string, 0, len(sizes))
+		for id := range sizes {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			fmt.Fprintf(&dump, "%s: %d\n", id, sizes[id])
+		}
+	}
+
+	dump.WriteString("=== Goroutines\n")
+	dump.Write(goroutineStacks())
+	return dump.String()
+}
+
+// goroutineStacks returns the stacks of all goroutines, growing the buffer
+// until they fit or it reaches maxStackDumpSize
+func goroutineStacks() []byte {
+	buf := make([]byte, 1024*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		if len(buf) >= maxStackDumpSize {
+			return append(buf[:n], "\n... truncated\n"...)
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// splitDump splits dump into chunks of at most size bytes, breaking after a
+// newline where there is one
+func splitDump(dump string, size int) []string {
+	var chunks []string
+	for len(dump) > size {
+		end := strings.LastIndex(dump[:size], "\n") + 1
+		if end == 0 {
+			end = size
+		}
+		chunks = append(chunks, dump[:end])
+		dump = dump[end:]
+	}
+	if len(dump) > 0 {
+		chunks = append(chunks, dump)
+	}
+	return chunks
+}
@@ -0,0 +1,67 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sighandlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/golang/mock/gomock"
+)
+
+type fakeStatsEngine map[string]int
+
+func (sizes fakeStatsEngine) QueueSizes() map[string]int { return sizes }
+
+func TestDebugDump(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+
+	task := &api.Task{
+		Arn:           "arn",
+		KnownStatus:   api.TaskCreated,
+		DesiredStatus: api.TaskRunning,
+		Containers: []*api.Container{
+			{Name: "app", KnownStatus: api.ContainerPulled, DesiredStatus: api.ContainerRunning},
+		},
+	}
+	taskEngine.EXPECT().ListTasks().Return([]*api.Task{task}, nil)
+
+	dump := debugDump(taskEngine, fakeStatsEngine{"dockerid": 3})
+	for _, expected := range []string{
+		"arn: known=CREATED desired=RUNNING",
+		"  app: known=PULLED desired=RUNNING",
+		"dockerid: 3",
+		"TestDebugDump",
+	} {
+		if !strings.Contains(dump, expected) {
+			t.Errorf("Expected dump to contain %q:\n%s", expected, dump)
+		}
+	}
+}
+
+func TestSplitDump(t *testing.T) {
+	chunks := splitDump("aaa\nbb\ncccccc\n", 5)
+	expected := []string{"aaa\n", "bb\n", "ccccc", "c\n"}
+	if !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("Expected chunks %q, got %q", expected, chunks)
+	}
+	if chunks := splitDump("", 5); len(chunks) != 0 {
+		t.Error("Expected no chunks of an empty dump", chunks)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sighandlers
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/sdnotify"
+	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
+	"github.com/aws/amazon-ecs-agent/agent/statemanager"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+const (
+	// deregistrationStopTimeout is how long tasks are given to stop, and
+	// their stops to be sent, before the instance is deregistered anyway
+	deregistrationStopTimeout = 5 * time.Minute
+	// deregistrationPollInterval is how often tasks are checked while waiting
+	// for them to stop
+	deregistrationPollInterval = time.Second
+)
+
+// StartDeregistrationHandler handles SIGUSR2 by taking the container instance
+// out of its cluster cleanly and exiting, for tearing down immutable
+// infrastructure. The instance is drained, its tasks are stopped and their
+// stops sent to the backend, it is deregistered, and the agent saves its
+// state and exits without asking to be restarted.
+func StartDeregistrationHandler(client api.ECSClient, cluster, containerInstanceArn string, saver statemanager.Saver, taskEngine engine.TaskEngine) {
+	signalChannel := make(chan os.Signal, 1)
+	signal.Notify(signalChannel, syscall.SIGUSR2)
+
+	sig := <-signalChannel
+	log.Info("Received deregistration signal", "signal", sig.String())
+	sdnotify.Notify(sdnotify.Stopping)
+
+	if err := deregister(client, cluster, containerInstanceArn, taskEngine, deregistrationStopTimeout); err != nil {
+		log.Crit("Unable to deregister the container instance", "err", err)
+		os.Exit(exitcodes.ExitError)
+	}
+	if err := FinalSave(saver, taskEngine); err != nil {
+		log.Crit("Error saving state before final shutdown", "err", err)
+	}
+	os.Exit(exitcodes.ExitSuccess)
+}
+
+// deregister drains the container instance, stops its tasks, waits up to
+// timeout for their stops to be sent, and deregisters it. Only failing to
+// deregister is an error; the rest is best effort, as deregistering stops any
+// remaining tasks in the backend anyway.
+func deregister(client api.ECSClient, cluster, containerInstanceArn string, taskEngine engine.TaskEngine, timeout time.Duration) error {
+	log.Info("Draining the container instance", "containerInstance", containerInstanceArn)
+	if err := client.DrainContainerInstance(containerInstanceArn); err != nil {
+		log.Warn("Unable to drain the container instance; stopping its tasks anyway", "err", err)
+	}
+
+	tasks, err := taskEngine.ListTasks()
+	if err != nil {
+		log.Warn("Unable to list tasks to stop", "err", err)
+	}
+	for _, task := range tasks {
+		if task.GetDesiredStatus() == api.TaskStopped {
+			continue
+		}
+		log.Info("Stopping task before deregistering", "task", task.Arn)
+		if err := taskEngine.AddTask(&api.Task{Arn: task.Arn, DesiredStatus: api.TaskStopped}); err != nil {
+			log.Warn("Unable to stop task", "task", task.Arn, "err", err)
+		}
+	}
+
+	deadline := ttime.Now().Add(timeout)
+	for !tasksStopped(taskEngine) {
+		if ttime.Now().After(deadline) {
+			log.Warn("Timed out waiting for tasks to stop; deregistering anyway", "timeout", timeout.String())
+			break
+		}
+		ttime.Sleep(deregistrationPollInterval)
+	}
+
+	log.Info("Deregistering the container instance", "cluster", cluster, "containerInstance", containerInstanceArn)
+	return client.DeregisterContainerInstance(cluster, containerInstanceArn)
+}
+
+// tasksStopped returns whether the stop of every task has been sent to the
+// backend
+func tasksStopped(taskEngine engine.TaskEngine) bool {
+	tasks, err := taskEngine.ListTasks()
+	if err != nil {
+		return false
+	}
+	for _, task := range tasks {
+		if task.GetSentStatus() != api.TaskStopped {
+			return false
+		}
+	}
+	return true
+}
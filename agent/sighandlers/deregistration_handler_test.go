@@ -0,0 +1,70 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sighandlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/api/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/golang/mock/gomock"
+)
+
+func TestDeregisterStopsTasksFirst(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+
+	running := &api.Task{Arn: "running", DesiredStatus: api.TaskRunning, SentStatus: api.TaskRunning}
+	stopped := &api.Task{Arn: "stopped", DesiredStatus: api.TaskStopped, SentStatus: api.TaskStopped}
+	gomock.InOrder(
+		client.EXPECT().DrainContainerInstance("arn").Return(errors.New("throttled")),
+		taskEngine.EXPECT().ListTasks().Return([]*api.Task{running, stopped}, nil),
+		taskEngine.EXPECT().AddTask(&api.Task{Arn: "running", DesiredStatus: api.TaskStopped}).Do(func(*api.Task) {
+			running.DesiredStatus = api.TaskStopped
+			running.SentStatus = api.TaskStopped
+		}),
+		taskEngine.EXPECT().ListTasks().Return([]*api.Task{running, stopped}, nil),
+		client.EXPECT().DeregisterContainerInstance("cluster", "arn").Return(nil),
+	)
+
+	if err := deregister(client, "cluster", "arn", taskEngine, time.Minute); err != nil {
+		t.Error("Expected to deregister, got", err)
+	}
+}
+
+func TestDeregisterTimesOutWaitingForTasks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+	taskEngine := mock_engine.NewMockTaskEngine(ctrl)
+	testTime := ttime.NewTestTime()
+	testTime.LudicrousSpeed(true)
+	ttime.SetTime(testTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	stuck := []*api.Task{{Arn: "stuck", DesiredStatus: api.TaskStopped, SentStatus: api.TaskRunning}}
+	client.EXPECT().DrainContainerInstance("arn").Return(nil)
+	taskEngine.EXPECT().ListTasks().Return(stuck, nil).AnyTimes()
+	client.EXPECT().DeregisterContainerInstance("cluster", "arn").Return(errors.New("not found"))
+
+	if err := deregister(client, "cluster", "arn", taskEngine, time.Minute); err == nil {
+		t.Error("Expected the deregistration error")
+	}
+}
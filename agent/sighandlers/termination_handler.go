@@ -11,9 +11,10 @@
 // express or implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
-// sighandlers handle signals and behave appropriately. Currently, the only
-// supported signal is SIGTERM which causes state to be flushed to disk before
-// exiting.
+// sighandlers handle signals and behave appropriately. SIGTERM causes state
+// to be flushed to disk before exiting, SIGUSR1 dumps the agent's state to
+// its log for debugging, and SIGUSR2 deregisters the container instance
+// before exiting.
 package sighandlers
 
 import (
@@ -25,6 +26,7 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/sdnotify"
 	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
@@ -38,6 +40,7 @@ func StartTerminationHandler(saver statemanager.Saver, taskEngine engine.TaskEng
 
 	sig := <-signalChannel
 	log.Debug("Received termination signal", "signal", sig.String())
+	sdnotify.Notify(sdnotify.Stopping)
 
 	err := FinalSave(saver, taskEngine)
 	if err != nil {
@@ -0,0 +1,72 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package statemanager
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// stateKeySize is the size in bytes of the AES-256 state encryption key
+const stateKeySize = 32
+
+// loadStateKey reads the base64 encoded state encryption key from keyFile
+func loadStateKey(keyFile string) (cipher.AEAD, error) {
+	encoded, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, errors.New("State encryption key is not valid base64: " + err.Error())
+	}
+	if len(key) != stateKeySize {
+		return nil, errors.New("State encryption key must be 256 bits")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptState seals the marshalled state, returning the marshalled
+// encryptedState to save in its place. The nonce is prepended to the
+// ciphertext.
+func encryptState(aead cipher.AEAD, data []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return json.Marshal(encryptedState{
+		Version:   EncryptedEcsDataVersion,
+		Encrypted: aead.Seal(nonce, nonce, data, nil),
+	})
+}
+
+// decryptState opens state sealed by encryptState
+func decryptState(aead cipher.AEAD, encrypted []byte) ([]byte, error) {
+	if len(encrypted) < aead.NonceSize() {
+		return nil, errors.New("Encrypted state is truncated")
+	}
+	nonce := encrypted[:aead.NonceSize()]
+	return aead.Open(nil, nonce, encrypted[aead.NonceSize():], nil)
+}
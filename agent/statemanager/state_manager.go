@@ -18,6 +18,7 @@
 package statemanager
 
 import (
+	"crypto/cipher"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
@@ -28,6 +29,7 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/health"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
 )
 
@@ -42,8 +44,26 @@ import (
 //   b) remove 'DEAD', 'UNKNOWN' state from ever being marshalled (backward and
 //      forward compatible)
 // 3) Add 'Protocol' field to 'portMappings' and 'KnownPortBindings'
+//    a) Add 'ACSHandledMessages' top level field (backwards and forwards
+//       compatible, so the version isn't changed)
+// 4) Add 'Encrypted' top level field holding the encrypted state in place of
+//    'Data' if a state encryption key is configured (backwards compatible).
+//    Only encrypted state is saved as this version, so that agents which
+//    can't decrypt it refuse it, while those which can read plaintext state
+//    still can.
 const EcsDataVersion = 3
 
+// EncryptedEcsDataVersion is the version of saved data which is encrypted
+const EncryptedEcsDataVersion = 4
+
+// SavedDataVersion returns the version of the data the agent saves with cfg
+func SavedDataVersion(cfg *config.Config) int {
+	if cfg.StateEncryptionKeyFile != "" {
+		return EncryptedEcsDataVersion
+	}
+	return EcsDataVersion
+}
+
 // Filename in the ECS_DATADIR
 const ecsDataFile = "ecs_agent_data.json"
 
@@ -52,6 +72,30 @@ const minSaveInterval = 10 * time.Second
 
 var log = logger.ForModule("statemanager")
 
+// StateFile returns the path of the state file saved in dataDir
+func StateFile(dataDir string) string {
+	return filepath.Join(dataDir, ecsDataFile)
+}
+
+// CorruptStateError is returned by Load when the saved state cannot be parsed
+type CorruptStateError struct {
+	err error
+}
+
+func (err CorruptStateError) Error() string {
+	return "Corrupt state file: " + err.err.Error()
+}
+
+// BackupStateFile moves the state file saved in dataDir aside, such as after
+// it was found to be corrupt, and returns where it was moved to
+func BackupStateFile(dataDir string) (string, error) {
+	backup := StateFile(dataDir) + ".corrupt." + strconv.FormatInt(time.Now().Unix(), 10)
+	if err := os.Rename(StateFile(dataDir), backup); err != nil {
+		return "", err
+	}
+	return backup, nil
+}
+
 // Saveable types should be able to be json serializable and deserializable
 // Properly, this should have json.Marshaler/json.Unmarshaler here, but string
 // and so on can be marshaled/unmarshaled sanely but don't fit those interfaces.
@@ -87,8 +131,11 @@ type intermediateState struct {
 	Data intermediateSaveableState
 }
 
-type versionOnlyState struct {
-	Version int
+// encryptedState is saved in place of state if it is encrypted. Its Version is
+// also enough to check whether either can be loaded.
+type encryptedState struct {
+	Version   int
+	Encrypted []byte
 }
 
 // A StateManager can load and save state from disk.
@@ -103,6 +150,8 @@ type basicStateManager struct {
 
 	state *state // pointers to the data we should save / load into
 
+	cipher cipher.AEAD // encrypts saved state, if configured
+
 	sync.Mutex                // guards save times
 	lastSave        time.Time //the last time a save completed
 	nextPlannedSave time.Time //the next time a save is planned
@@ -129,10 +178,17 @@ func NewStateManager(cfg *config.Config, options ...Option) (StateManager, error
 		statePath: cfg.DataDir,
 		state:     state,
 	}
+	if cfg.StateEncryptionKeyFile != "" {
+		manager.cipher, err = loadStateKey(cfg.StateEncryptionKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	for _, option := range options {
 		option(manager)
 	}
+	health.Default.Register(health.ComponentStateSave)
 
 	return manager, nil
 }
@@ -185,6 +241,12 @@ func (manager *basicStateManager) Save() error {
 // In addition, the StateManager internally buffers save requests in order to
 // only save at most every STATE_SAVE_INTERVAL.
 func (manager *basicStateManager) ForceSave() error {
+	err := manager.forceSave()
+	health.Default.Report(health.ComponentStateSave, err)
+	return err
+}
+
+func (manager *basicStateManager) forceSave() error {
 	log.Info("Saving state!")
 	s := manager.state
 	s.Version = EcsDataVersion
@@ -194,6 +256,13 @@ func (manager *basicStateManager) ForceSave() error {
 		log.Error("Error saving state; could not marshal data; this is odd", "err", err)
 		return err
 	}
+	if manager.cipher != nil {
+		data, err = encryptState(manager.cipher, data)
+		if err != nil {
+			log.Error("Error saving state; could not encrypt data", "err", err)
+			return err
+		}
+	}
 	// Make our temp-file on the same volume as our data-file to ensure we can
 	// actually move it atomically; cross-device renaming will error out.
 	tmpfile, err := ioutil.TempFile(manager.statePath, "tmp_ecs_agent_data")
@@ -235,15 +304,30 @@ func (manager *basicStateManager) Load() error {
 		return err
 	}
 	// Dry-run to make sure this is a version we can understand
-	tmps := versionOnlyState{}
+	tmps := encryptedState{}
 	err = json.Unmarshal(data, &tmps)
 	if err != nil {
 		log.Crit("Could not unmarshal existing state; corrupted data?", "err", err, "data", data)
-		return err
+		return CorruptStateError{err}
 	}
-	if tmps.Version > EcsDataVersion {
+	if tmps.Version > EncryptedEcsDataVersion {
 		strversion := strconv.Itoa(tmps.Version)
-		return errors.New("Unsupported data format: Version " + strversion + " not " + strconv.Itoa(EcsDataVersion))
+		return errors.New("Unsupported data format: Version " + strversion + " not " + strconv.Itoa(EncryptedEcsDataVersion))
+	}
+	migrate := false
+	if tmps.Encrypted != nil {
+		if manager.cipher == nil {
+			return errors.New("State is encrypted but no state encryption key is configured")
+		}
+		data, err = decryptState(manager.cipher, tmps.Encrypted)
+		if err != nil {
+			log.Crit("Could not decrypt existing state; wrong key?", "err", err)
+			return err
+		}
+	} else if manager.cipher != nil {
+		// Plaintext state from before encryption was configured; it is
+		// encrypted by saving it again once it's loaded
+		migrate = true
 	}
 	// Now load it into the actual state. The reason we do this with the
 	// intermediate state is that we *must* unmarshal directly into the
@@ -256,7 +340,7 @@ func (manager *basicStateManager) Load() error {
 	err = json.Unmarshal(data, &intermediate)
 	if err != nil {
 		log.Debug("Could not unmarshal into intermediate")
-		return err
+		return CorruptStateError{err}
 	}
 
 	for key, rawJSON := range intermediate.Data {
@@ -268,10 +352,14 @@ func (manager *basicStateManager) Load() error {
 		err = json.Unmarshal(rawJSON, actualPointer)
 		if err != nil {
 			log.Debug("Could not unmarshal into actual")
-			return err
+			return CorruptStateError{err}
 		}
 	}
 
 	log.Debug("Loaded state!", "state", s)
+	if migrate {
+		log.Info("Encrypting existing plaintext state")
+		return manager.ForceSave()
+	}
 	return nil
 }
@@ -14,6 +14,8 @@
 package statemanager_test
 
 import (
+	"bytes"
+	"encoding/base64"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -170,3 +172,92 @@ func TestLoadsV1DataCorrectly(t *testing.T) {
 		t.Fatal("Time was not correct")
 	}
 }
+
+func TestEncryptedStateManager(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("/tmp", "ecs_statemanager_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	keyFile := filepath.Join(tmpDir, "state.key")
+	ioutil.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))+"\n"), 0600)
+
+	// Save plaintext state, as from before encryption was configured
+	cluster := "plaintext-cluster"
+	manager, err := statemanager.NewStateManager(&config.Config{DataDir: tmpDir}, statemanager.AddSaveable("Cluster", &cluster))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = manager.ForceSave(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Loading it with a key migrates it
+	encryptedCfg := &config.Config{DataDir: tmpDir, StateEncryptionKeyFile: keyFile}
+	var loadedCluster string
+	manager, err = statemanager.NewStateManager(encryptedCfg, statemanager.AddSaveable("Cluster", &loadedCluster))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = manager.Load(); err != nil {
+		t.Fatal("Error loading plaintext state", err)
+	}
+	if loadedCluster != cluster {
+		t.Error("Wrong cluster", loadedCluster)
+	}
+	data, _ := ioutil.ReadFile(statemanager.StateFile(tmpDir))
+	if bytes.Contains(data, []byte(cluster)) {
+		t.Error("Expected state to be encrypted after loading plaintext", string(data))
+	}
+
+	loadedCluster = ""
+	manager, err = statemanager.NewStateManager(encryptedCfg, statemanager.AddSaveable("Cluster", &loadedCluster))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = manager.Load(); err != nil || loadedCluster != cluster {
+		t.Error("Error loading encrypted state", loadedCluster, err)
+	}
+
+	manager, err = statemanager.NewStateManager(&config.Config{DataDir: tmpDir}, statemanager.AddSaveable("Cluster", &loadedCluster))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = manager.Load(); err == nil {
+		t.Error("Expected an error loading encrypted state without a key")
+	}
+
+	ioutil.WriteFile(keyFile, []byte("c2hvcnQ="), 0600)
+	if _, err = statemanager.NewStateManager(encryptedCfg); err == nil {
+		t.Error("Expected an error for a short key")
+	}
+}
+
+func TestCorruptState(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("/tmp", "ecs_statemanager_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	ioutil.WriteFile(statemanager.StateFile(tmpDir), []byte(`{"Data":{"Cluster":`), 0600)
+
+	var cluster string
+	manager, err := statemanager.NewStateManager(&config.Config{DataDir: tmpDir}, statemanager.AddSaveable("Cluster", &cluster))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := manager.Load().(statemanager.CorruptStateError); !ok {
+		t.Error("Expected a corrupt state error")
+	}
+
+	backup, err := statemanager.BackupStateFile(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(backup); err != nil {
+		t.Error("Expected the state to be backed up", err)
+	}
+	if err = manager.Load(); err != nil {
+		t.Error("Expected to start over without the state file", err)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"path/filepath"
+
+	ecsengine "github.com/aws/amazon-ecs-agent/agent/engine"
+)
+
+const (
+	// CgroupDriverCgroupfs places each container in a cgroup directory named
+	// docker/<id>
+	CgroupDriverCgroupfs = "cgroupfs"
+	// CgroupDriverSystemd places each container in a systemd scope named
+	// system.slice/docker-<id>.scope
+	CgroupDriverSystemd = "systemd"
+
+	cgroupRoot = "/sys/fs/cgroup"
+)
+
+// cgroupSubsystems are the cgroup hierarchies stats are read from
+var cgroupSubsystems = []string{"cpu", "cpuacct", "memory", "blkio"}
+
+// containerCgroupPaths returns where the container's cgroup lives in each
+// hierarchy under the given cgroup driver. It is used when docker's state for
+// the container does not record the paths itself.
+func containerCgroupPaths(driver, dockerID string) map[string]string {
+	relative := containerCgroupRelativePath(driver, dockerID)
+	paths := make(map[string]string)
+	for _, subsystem := range cgroupSubsystems {
+		paths[subsystem] = filepath.Join(cgroupRoot, subsystem, relative)
+	}
+	return paths
+}
+
+// containerCgroupRelativePath returns where the container's cgroup lives
+// within a hierarchy under the given cgroup driver
+func containerCgroupRelativePath(driver, dockerID string) string {
+	if driver == CgroupDriverSystemd {
+		return filepath.Join("system.slice", "docker-"+dockerID+".scope")
+	}
+	return filepath.Join("docker", dockerID)
+}
+
+// detectCgroupDriver returns the configured cgroup driver, or asks the docker
+// daemon which it uses if none is configured. Daemons too old to report one
+// only support cgroupfs.
+func detectCgroupDriver(client ecsengine.DockerClient, configured string) string {
+	if configured != "" {
+		return configured
+	}
+	info, err := client.Info()
+	if err != nil {
+		log.Warn("Could not get the cgroup driver from docker; assuming cgroupfs", "err", err)
+		return CgroupDriverCgroupfs
+	}
+	if driver := info.Get("CgroupDriver"); driver != "" {
+		return driver
+	}
+	return CgroupDriverCgroupfs
+}
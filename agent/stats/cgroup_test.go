@@ -0,0 +1,63 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+)
+
+func TestContainerCgroupPaths(t *testing.T) {
+	paths := containerCgroupPaths(CgroupDriverCgroupfs, "abc")
+	if paths["memory"] != "/sys/fs/cgroup/memory/docker/abc" {
+		t.Error("Wrong cgroupfs memory path", paths["memory"])
+	}
+
+	paths = containerCgroupPaths(CgroupDriverSystemd, "abc")
+	if paths["cpuacct"] != "/sys/fs/cgroup/cpuacct/system.slice/docker-abc.scope" {
+		t.Error("Wrong systemd cpuacct path", paths["cpuacct"])
+	}
+	if len(paths) != len(cgroupSubsystems) {
+		t.Error("Expected a path for each subsystem, got", paths)
+	}
+}
+
+func TestDetectCgroupDriver(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := mock_engine.NewMockDockerClient(mockCtrl)
+
+	if driver := detectCgroupDriver(client, CgroupDriverSystemd); driver != CgroupDriverSystemd {
+		t.Error("Expected the configured driver, got", driver)
+	}
+
+	client.EXPECT().Info().Return(&docker.Env{"CgroupDriver=systemd"}, nil)
+	if driver := detectCgroupDriver(client, ""); driver != CgroupDriverSystemd {
+		t.Error("Expected the daemon's driver, got", driver)
+	}
+
+	client.EXPECT().Info().Return(&docker.Env{}, nil)
+	if driver := detectCgroupDriver(client, ""); driver != CgroupDriverCgroupfs {
+		t.Error("Expected cgroupfs from an older daemon, got", driver)
+	}
+
+	client.EXPECT().Info().Return(nil, errors.New("unreachable"))
+	if driver := detectCgroupDriver(client, ""); driver != CgroupDriverCgroupfs {
+		t.Error("Expected cgroupfs when docker is unreachable, got", driver)
+	}
+}
@@ -14,7 +14,7 @@
 package stats
 
 import (
-	"path/filepath"
+	"fmt"
 	"time"
 
 	"github.com/docker/libcontainer"
@@ -22,9 +22,6 @@ import (
 )
 
 const (
-	// DockerExecDriverPath points to the docker exec driver path.
-	DockerExecDriverPath = "execdriver/native"
-
 	// SleepBetweenUsageDataCollection is the sleep duration between collecting usage data for a container.
 	SleepBetweenUsageDataCollection = 500 * time.Millisecond
 
@@ -39,8 +36,12 @@ type ContainerStatsCollector interface {
 	getContainerStats(container *CronContainer) (*ContainerStats, error)
 }
 
-// LibcontainerStatsCollector implements ContainerStatsCollector.
-type LibcontainerStatsCollector struct{}
+// LibcontainerStatsCollector implements ContainerStatsCollector. Each
+// container has its own collector, whose stats are overwritten by every
+// collection; the queue copies them when they are added.
+type LibcontainerStatsCollector struct {
+	stats ContainerStats
+}
 
 // StartStatsCron starts a go routine to periodically pull usage data for the container.
 func (container *CronContainer) StartStatsCron() {
@@ -58,15 +59,15 @@ func (container *CronContainer) StopStatsCron() {
 	container.cancel()
 }
 
-// newCronContainer creates a CronContainer object.
-func newCronContainer(dockerID *string, dockerGraphPath string) *CronContainer {
-	statePath := filepath.Join(dockerGraphPath, DockerExecDriverPath, *dockerID)
-
+// newCronContainer creates a CronContainer object. Its state is looked for in
+// stateDirs.
+func newCronContainer(dockerID *string, stateDirs []string, cgroupDriver string) *CronContainer {
 	container := &CronContainer{
 		containerMetadata: &ContainerMetadata{
 			DockerID: dockerID,
 		},
-		statePath: statePath,
+		stateDirs:    stateDirs,
+		cgroupDriver: cgroupDriver,
 	}
 
 	container.statsCollector = &LibcontainerStatsCollector{}
@@ -80,25 +81,46 @@ func (container *CronContainer) cronStats() {
 		case <-container.ctx.Done():
 			return
 		default:
+			start := time.Now()
 			stats, err := container.statsCollector.getContainerStats(container)
+			container.sampler.recordLatency(time.Since(start))
 			if err != nil {
 				log.Debug("Error getting stats", "error", err, "contianer", container)
 			} else {
 				container.statsQueue.Add(stats)
+				if stats.oomKillsKnown {
+					container.checkOOMKills(stats.oomKills)
+				}
+				if stats.pidsKnown {
+					container.checkPids(stats.pids, stats.pidsLimit, stats.pidsDir)
+				}
 			}
-			time.Sleep(SleepBetweenUsageDataCollection)
+			time.Sleep(container.sampler.interval())
 		}
 	}
 }
 
 // getContainerStats reads usage data of a container from the cgroup fs.
 func (collector *LibcontainerStatsCollector) getContainerStats(container *CronContainer) (*ContainerStats, error) {
+	if container.statePath == "" {
+		container.statePath = findStatePath(container.stateDirs, *container.containerMetadata.DockerID)
+		if container.statePath == "" {
+			// The state file is not created immediately when a container
+			// starts. Bubble up the error.
+			return nil, fmt.Errorf("No state for container in %v", container.stateDirs)
+		}
+	}
 	state, err := libcontainer.GetState(container.statePath)
 	if err != nil {
 		// The state file is not created immediately when a container starts.
 		// Bubble up the error.
 		return nil, err
 	}
+	if len(state.CgroupPaths) == 0 {
+		// Not every exec driver records where it put the container's cgroups;
+		// fall back to where the daemon's cgroup driver puts them
+		state.CgroupPaths = containerCgroupPaths(container.cgroupDriver, *container.containerMetadata.DockerID)
+	}
 	// libcontainer.GetStats ignores the config argument. So, don't bother providing one.
 	containerStats, err := libcontainer.GetStats(nil, state)
 	if err != nil && !isNetworkStatsError(err) {
@@ -106,6 +128,15 @@ func (collector *LibcontainerStatsCollector) getContainerStats(container *CronCo
 		return nil, err
 	}
 
-	cs := toContainerStats(*containerStats)
-	return cs, nil
+	toContainerStats(containerStats, &collector.stats)
+	if !container.cgroupV2Checked {
+		container.cgroupV2Dir = containerCgroupV2Dir(container.cgroupDriver, *container.containerMetadata.DockerID)
+		container.cgroupV2Checked = true
+	}
+	readPressure(container.cgroupV2Dir, &collector.stats.pressure)
+	collector.stats.oomKills, collector.stats.oomKillsKnown = readOOMKills(container.cgroupV2Dir, state.CgroupPaths["memory"])
+	collector.stats.pidsDir = containerPidsCgroupDir(container, state.CgroupPaths)
+	collector.stats.pids, collector.stats.pidsLimit, collector.stats.pidsKnown = readPids(collector.stats.pidsDir)
+	collector.stats.extra = convertExtraStats(container.converters, containerStats, state.CgroupPaths, container.cgroupV2Dir, collector.stats.extra)
+	return &collector.stats, nil
 }
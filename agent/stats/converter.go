@@ -0,0 +1,59 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"github.com/docker/libcontainer"
+)
+
+// StatsConverter maps a container's cgroup stats into an extra metric, such
+// as one from a controller the collector doesn't read itself. Its samples are
+// queued with the container's other stats and published as a local metric.
+type StatsConverter interface {
+	// Name names the metric
+	Name() string
+	// Convert returns the metric's value from one collection of the
+	// container's stats, and whether it has one. cgroupPaths are the
+	// container's directories in each cgroup v1 hierarchy, by subsystem, and
+	// cgroupV2Dir its cgroup v2 directory, or "" if it has none.
+	Convert(stats *libcontainer.ContainerStats, cgroupPaths map[string]string, cgroupV2Dir string) (float64, bool)
+}
+
+// RegisterStatsConverter adds a converter whose metric is collected for
+// every container the engine starts watching afterwards. Containers already
+// being watched keep the converters they started with.
+func (engine *DockerStatsEngine) RegisterStatsConverter(converter StatsConverter) {
+	engine.containersLock.Lock()
+	defer engine.containersLock.Unlock()
+	// Containers share the converters slice, so it is copied rather than
+	// appended to in place
+	converters := make([]StatsConverter, len(engine.converters), len(engine.converters)+1)
+	copy(converters, engine.converters)
+	engine.converters = append(converters, converter)
+}
+
+// convertExtraStats returns the value of each converter, or NaN where it has
+// none, reusing extra
+func convertExtraStats(converters []StatsConverter, containerStats *libcontainer.ContainerStats, cgroupPaths map[string]string, cgroupV2Dir string, extra []float32) []float32 {
+	extra = extra[:0]
+	for _, converter := range converters {
+		value, ok := converter.Convert(containerStats, cgroupPaths, cgroupV2Dir)
+		if !ok {
+			extra = append(extra, nan32())
+			continue
+		}
+		extra = append(extra, float32(value))
+	}
+	return extra
+}
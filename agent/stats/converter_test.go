@@ -0,0 +1,97 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/libcontainer"
+	"github.com/docker/libcontainer/cgroups"
+)
+
+// throttledConverter reports how often the container was throttled by its
+// cfs quota, if it was ever throttled
+type throttledConverter struct{}
+
+func (throttledConverter) Name() string { return "ThrottledPeriods" }
+
+func (throttledConverter) Convert(stats *libcontainer.ContainerStats, cgroupPaths map[string]string, cgroupV2Dir string) (float64, bool) {
+	periods := stats.CgroupStats.CpuStats.ThrottlingData.ThrottledPeriods
+	return float64(periods), periods > 0
+}
+
+func TestStatsConverters(t *testing.T) {
+	converters := []StatsConverter{throttledConverter{}}
+
+	containerStats := &libcontainer.ContainerStats{CgroupStats: &cgroups.Stats{}}
+	queue := NewQueue(2)
+	start := time.Now()
+	var extra []float32
+	for i, throttled := range []uint64{0, 5, 7} {
+		containerStats.CgroupStats.CpuStats.ThrottlingData.ThrottledPeriods = throttled
+		extra = convertExtraStats(converters, containerStats, nil, "", extra)
+		stats := createContainerStats(uint64(i), 1024*1024, start.Add(time.Duration(i)*time.Second))
+		stats.extra = extra
+		queue.Add(stats)
+	}
+
+	statsSet, err := queue.GetExtraStatsSet(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *statsSet.SampleCount != 2 || *statsSet.Min != 5 || *statsSet.Max != 7 {
+		t.Error("Unexpected stats set", *statsSet.SampleCount, *statsSet.Min, *statsSet.Max)
+	}
+	raw, err := queue.GetRawUsageStats(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw[0].Extra) != 1 || raw[0].Extra[0] != 7 {
+		t.Error("Expected raw stats to include the converted value", raw[0].Extra)
+	}
+	if _, err := queue.GetExtraStatsSet(1); err == nil {
+		t.Error("Expected an error for a converter which isn't registered")
+	}
+}
+
+func TestQueueExtraNotReused(t *testing.T) {
+	queue := NewQueue(1)
+	start := time.Now()
+	stats := createContainerStats(1, 1024*1024, start)
+	stats.extra = []float32{5}
+	queue.Add(stats)
+	raw, err := queue.GetRawUsageStats(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := queue.at(0).Extra
+
+	stats = createContainerStats(2, 1024*1024, start.Add(time.Second))
+	stats.extra = []float32{7}
+	queue.Add(stats)
+	if held[0] != 5 || raw[0].Extra[0] != 5 {
+		t.Error("Expected a replaced stat's values to be left alone", held, raw[0].Extra)
+	}
+}
+
+func TestRegisterStatsConverter(t *testing.T) {
+	engine := &DockerStatsEngine{}
+	engine.RegisterStatsConverter(throttledConverter{})
+	watched := engine.converters
+	engine.RegisterStatsConverter(throttledConverter{})
+	if len(watched) != 1 || len(engine.converters) != 2 {
+		t.Error("Expected registering a converter to leave watched containers' converters alone", len(watched), len(engine.converters))
+	}
+}
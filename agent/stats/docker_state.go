@@ -0,0 +1,73 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	ecsengine "github.com/aws/amazon-ecs-agent/agent/engine"
+)
+
+const (
+	// dockerRunDir is where docker 1.6 and later keep the exec driver's state
+	dockerRunDir = "/var/run/docker"
+	// runcRunDir is where runc keeps the state of the containers docker 1.11
+	// and later hand to it
+	runcRunDir = "/run/runc"
+	// defaultExecDriver is the exec driver of daemons too old to report one
+	defaultExecDriver = "native"
+)
+
+// detectExecDriver asks the docker daemon which exec driver it uses, such as
+// "native" from "native-0.2". Only the native driver and runc record the
+// container state stats are read from.
+func detectExecDriver(client ecsengine.DockerClient) string {
+	info, err := client.Info()
+	if err != nil {
+		log.Warn("Could not get the exec driver from docker; assuming native", "err", err)
+		return defaultExecDriver
+	}
+	driver := info.Get("ExecutionDriver")
+	if driver == "" {
+		return defaultExecDriver
+	}
+	return strings.SplitN(driver, "-", 2)[0]
+}
+
+// containerStateDirs returns the directories the state of a container may be
+// kept in, given the daemon's graph path and exec driver. It has moved from
+// the graph path to /var/run/docker, and then to runc's own directory once
+// docker stopped running containers itself.
+func containerStateDirs(graphPath, execDriver string) []string {
+	return []string{
+		filepath.Join(graphPath, "execdriver", execDriver),
+		filepath.Join(dockerRunDir, "execdriver", execDriver),
+		runcRunDir,
+		filepath.Join(dockerRunDir, "runtime-runc", "moby"),
+	}
+}
+
+// findStatePath returns the directory holding the state of the container
+// among dirs, or "" if it hasn't been written yet
+func findStatePath(dirs []string, dockerID string) string {
+	for _, dir := range dirs {
+		path := filepath.Join(dir, dockerID)
+		if _, err := os.Stat(filepath.Join(path, "state.json")); err == nil {
+			return path
+		}
+	}
+	return ""
+}
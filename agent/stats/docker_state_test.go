@@ -0,0 +1,69 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine/mocks"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+)
+
+func TestDetectExecDriver(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	client := mock_engine.NewMockDockerClient(mockCtrl)
+
+	client.EXPECT().Info().Return(&docker.Env{"ExecutionDriver=native-0.2"}, nil)
+	if driver := detectExecDriver(client); driver != "native" {
+		t.Error("Expected the daemon's driver, got", driver)
+	}
+
+	client.EXPECT().Info().Return(&docker.Env{}, nil)
+	if driver := detectExecDriver(client); driver != defaultExecDriver {
+		t.Error("Expected native from a daemon which doesn't report it, got", driver)
+	}
+
+	client.EXPECT().Info().Return(nil, errors.New("unreachable"))
+	if driver := detectExecDriver(client); driver != defaultExecDriver {
+		t.Error("Expected native when docker is unreachable, got", driver)
+	}
+}
+
+func TestFindStatePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dirs := containerStateDirs(filepath.Join(dir, "root"), "native")
+	if dirs[0] != filepath.Join(dir, "root", "execdriver", "native") {
+		t.Error("Expected the graph path to be searched first, got", dirs)
+	}
+
+	if path := findStatePath(dirs, "abc"); path != "" {
+		t.Error("Expected no state before it is written, got", path)
+	}
+	statePath := filepath.Join(dirs[0], "abc")
+	os.MkdirAll(statePath, 0755)
+	ioutil.WriteFile(filepath.Join(statePath, "state.json"), []byte("{}"), 0644)
+	if path := findStatePath(dirs, "abc"); path != statePath {
+		t.Error("Unexpected state path", path)
+	}
+}
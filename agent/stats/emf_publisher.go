@@ -0,0 +1,184 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+const (
+	// defaultEMFAddress is where the CloudWatch agent listens for embedded
+	// metric format records by default
+	defaultEMFAddress = "127.0.0.1:25888"
+
+	// emfNamespace is the CloudWatch namespace of the published metrics
+	emfNamespace = "ECS/Tasks"
+
+	emfDialTimeout = 5 * time.Second
+)
+
+// EMFPublisher sends each task's usage as a CloudWatch embedded metric
+// format record to the CloudWatch agent, which writes it to a log group and
+// extracts the metrics from it.
+type EMFPublisher struct {
+	address  string
+	logGroup string
+}
+
+// NewEMFPublisher returns a publisher which sends records for logGroup to the
+// CloudWatch agent listening for tcp at address, or at its default address if
+// address is empty
+func NewEMFPublisher(address, logGroup string) *EMFPublisher {
+	if address == "" {
+		address = defaultEMFAddress
+	}
+	return &EMFPublisher{address: address, logGroup: logGroup}
+}
+
+func (publisher *EMFPublisher) Name() string { return "emf" }
+
+type emfMetric struct {
+	Name string
+	Unit string
+}
+
+type emfMetricDirective struct {
+	Namespace  string
+	Dimensions [][]string
+	Metrics    []emfMetric
+}
+
+type emfMetadata struct {
+	Timestamp         int64
+	LogGroupName      string
+	CloudWatchMetrics []emfMetricDirective
+}
+
+type emfRecord struct {
+	AWS                   emfMetadata `json:"_aws"`
+	ClusterName           string
+	ContainerInstance     string
+	TaskArn               string
+	TaskDefinitionFamily  string
+	TaskDefinitionVersion string
+	CpuUtilized           float64
+	MemoryUtilized        float64
+}
+
+// Publish sends one newline delimited record per task
+func (publisher *EMFPublisher) Publish(metadata *ecstcs.MetricsMetadata, taskMetrics []*ecstcs.TaskMetric) error {
+	conn, err := net.DialTimeout("tcp", publisher.address, emfDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	timestamp := ttime.Now().UnixNano() / int64(time.Millisecond)
+	encoder := json.NewEncoder(conn)
+	for _, usage := range taskUsages(taskMetrics) {
+		record := emfRecord{
+			AWS: emfMetadata{
+				Timestamp:    timestamp,
+				LogGroupName: publisher.logGroup,
+				CloudWatchMetrics: []emfMetricDirective{{
+					Namespace:  emfNamespace,
+					Dimensions: [][]string{{"ClusterName", "TaskDefinitionFamily"}},
+					Metrics: []emfMetric{
+						{Name: "CpuUtilized", Unit: "Percent"},
+						{Name: "MemoryUtilized", Unit: "Megabytes"},
+					},
+				}},
+			},
+			ClusterName:           stringValue(metadata.Cluster),
+			ContainerInstance:     stringValue(metadata.ContainerInstance),
+			TaskArn:               usage.taskArn,
+			TaskDefinitionFamily:  usage.family,
+			TaskDefinitionVersion: usage.version,
+			CpuUtilized:           usage.cpuPercent,
+			MemoryUtilized:        usage.memoryMiB,
+		}
+		if err := encoder.Encode(&record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PublishLocal sends one newline delimited record for the instance's
+// utilization, if it was sampled, and one per container with local metrics
+func (publisher *EMFPublisher) PublishLocal(metadata *ecstcs.MetricsMetadata, local *LocalMetrics) error {
+	if local.Instance == nil && len(local.Containers) == 0 {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", publisher.address, emfDialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	timestamp := ttime.Now().UnixNano() / int64(time.Millisecond)
+	encoder := json.NewEncoder(conn)
+	if local.Instance != nil {
+		record := map[string]interface{}{
+			"ClusterName":       stringValue(metadata.Cluster),
+			"ContainerInstance": stringValue(metadata.ContainerInstance),
+		}
+		var metrics []emfMetric
+		for _, value := range local.Instance.values() {
+			record[value.name] = value.value
+			metrics = append(metrics, emfMetric{Name: value.name, Unit: value.unit})
+		}
+		record["_aws"] = publisher.metadata(timestamp, [][]string{{"ClusterName", "ContainerInstance"}}, metrics)
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	for _, container := range local.Containers {
+		// The metrics a container has vary, so its record is built as a map
+		record := map[string]interface{}{
+			"ClusterName":       stringValue(metadata.Cluster),
+			"ContainerInstance": stringValue(metadata.ContainerInstance),
+			"TaskArn":           container.TaskArn,
+			"ContainerName":     container.ContainerName,
+		}
+		var metrics []emfMetric
+		for _, value := range container.values() {
+			record[value.name] = value.value
+			metrics = append(metrics, emfMetric{Name: value.name, Unit: value.unit})
+		}
+		record["_aws"] = publisher.metadata(timestamp, [][]string{{"ClusterName", "ContainerName"}}, metrics)
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metadata returns the metadata of a record of metrics with dimensions
+func (publisher *EMFPublisher) metadata(timestamp int64, dimensions [][]string, metrics []emfMetric) emfMetadata {
+	return emfMetadata{
+		Timestamp:    timestamp,
+		LogGroupName: publisher.logGroup,
+		CloudWatchMetrics: []emfMetricDirective{{
+			Namespace:  emfNamespace,
+			Dimensions: dimensions,
+			Metrics:    metrics,
+		}},
+	}
+}
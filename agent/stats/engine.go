@@ -45,13 +45,24 @@ type Engine interface {
 // DockerStatsEngine is used to monitor docker container events and to report
 // utlization metrics of the same.
 type DockerStatsEngine struct {
-	client          ecsengine.DockerClient
-	containersLock  sync.RWMutex
+	client         ecsengine.DockerClient
+	cgroupDriver   string
+	containersLock sync.RWMutex
+	// converters are the registered stats converters. The slice is replaced
+	// rather than modified, since containers share it.
+	converters      []StatsConverter
 	ctx             context.Context
 	dockerGraphPath string
+	execDriver      string
 	events          <-chan ecsengine.DockerContainerChangeEvent
-	metricsMetadata *ecstcs.MetricsMetadata
-	resolver        resolver.ContainerMetadataResolver
+	exclusions      *statsExclusions
+	instance        *instanceStats
+	// localMetrics are those read by the last GetInstanceMetrics
+	localMetricsLock sync.Mutex
+	localMetrics     *LocalMetrics
+	metricsMetadata  *ecstcs.MetricsMetadata
+	resolver         resolver.ContainerMetadataResolver
+	sampler          *statsSampler
 	// tasksToContainers maps task arns to a map of container ids to CronContainer objects.
 	tasksToContainers map[string]map[string]*CronContainer
 	// tasksToDefinitions maps task arns to task definiton name and family metadata objects.
@@ -75,6 +86,19 @@ func (resolver *DockerContainerMetadataResolver) ResolveTask(dockerID string) (*
 	return task, nil
 }
 
+// ResolveContainer resolves the api container object, given container id.
+func (resolver *DockerContainerMetadataResolver) ResolveContainer(dockerID string) (*api.DockerContainer, error) {
+	if resolver.dockerTaskEngine == nil {
+		return nil, fmt.Errorf("Docker task engine uninitialized")
+	}
+	container, found := resolver.dockerTaskEngine.State().ContainerById(dockerID)
+	if !found {
+		return nil, fmt.Errorf("Could not map docker id to container")
+	}
+
+	return container, nil
+}
+
 // NewDockerStatsEngine creates a new instance of the DockerStatsEngine object.
 // MustInit() must be called to initialize the fields of the new event listener.
 func NewDockerStatsEngine(cfg *config.Config) *DockerStatsEngine {
@@ -82,7 +106,12 @@ func NewDockerStatsEngine(cfg *config.Config) *DockerStatsEngine {
 		dockerStatsEngine = &DockerStatsEngine{
 			client:             nil,
 			dockerGraphPath:    cfg.DockerGraphPath,
+			execDriver:         defaultExecDriver,
+			cgroupDriver:       cfg.CgroupDriver,
 			resolver:           nil,
+			sampler:            newStatsSampler(cfg),
+			exclusions:         newStatsExclusions(cfg),
+			instance:           newInstanceStats(procRoot, cfg.DockerGraphPath),
 			tasksToContainers:  make(map[string]map[string]*CronContainer),
 			tasksToDefinitions: make(map[string]*taskDefinition),
 		}
@@ -98,6 +127,12 @@ func (engine *DockerStatsEngine) MustInit(taskEngine ecsengine.TaskEngine, md *e
 	if err != nil {
 		return err
 	}
+	engine.cgroupDriver = detectCgroupDriver(engine.client, engine.cgroupDriver)
+	log.Info("Reading container stats from cgroups", "driver", engine.cgroupDriver)
+	engine.dockerGraphPath = ecsengine.DetectDockerGraphPath(engine.client, engine.dockerGraphPath)
+	engine.instance.setDiskPath(engine.dockerGraphPath)
+	engine.execDriver = detectExecDriver(engine.client)
+	log.Info("Reading container state", "dirs", containerStateDirs(engine.dockerGraphPath, engine.execDriver))
 
 	engine.metricsMetadata = md
 
@@ -119,9 +154,29 @@ func (engine *DockerStatsEngine) Init() error {
 	}
 
 	go engine.listContainersAndStartEventHandler()
+	go engine.instance.sampleEvery(engine.ctx, instanceSamplingInterval)
 	return nil
 }
 
+// Stop stops collecting stats and forgets the containers being watched. The
+// engine collects stats again once it is initialized again.
+func (engine *DockerStatsEngine) Stop() {
+	log.Info("Stopping stats engine")
+	if engine.unsubscribeContainerEvents != nil {
+		engine.unsubscribeContainerEvents()
+	}
+	engine.containersLock.Lock()
+	defer engine.containersLock.Unlock()
+	for _, containers := range engine.tasksToContainers {
+		for _, container := range containers {
+			container.StopStatsCron()
+		}
+	}
+	engine.tasksToContainers = make(map[string]map[string]*CronContainer)
+	engine.tasksToDefinitions = make(map[string]*taskDefinition)
+	engine.sampler.setContainers(0)
+}
+
 // listContainersAndStartEventHandler adds existing containers to the watch-list
 // and starts the docker event handler.
 func (engine *DockerStatsEngine) listContainersAndStartEventHandler() {
@@ -151,12 +206,16 @@ func (engine *DockerStatsEngine) addExistingContainers() error {
 	return nil
 }
 
-// GetInstanceMetrics gets all task metrics and instance metadata from stats engine.
+// GetInstanceMetrics gets all task metrics and instance metadata from stats
+// engine. The metadata includes the utilization of the whole instance.
 func (engine *DockerStatsEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata, []*ecstcs.TaskMetric, error) {
 	var taskMetrics []*ecstcs.TaskMetric
+	local := &LocalMetrics{}
 	idle := engine.isIdle()
 	engine.metricsMetadata.Idle = &idle
 	if idle {
+		local.Instance = engine.instance.metric()
+		engine.setLocalMetrics(local)
 		log.Debug("Instance is idle. No task metrics to report")
 		return engine.metricsMetadata, taskMetrics, nil
 	}
@@ -187,6 +246,7 @@ func (engine *DockerStatsEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata,
 			ContainerMetrics:      containerMetrics,
 		}
 		taskMetrics = append(taskMetrics, taskMetric)
+		local.Containers = append(local.Containers, engine.getLocalMetricsForTask(taskArn)...)
 	}
 
 	if len(taskMetrics) == 0 {
@@ -196,10 +256,37 @@ func (engine *DockerStatsEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata,
 
 	// Reset current stats. Retaining older stats results in incorrect utilization stats
 	// until they are removed from the queue.
+	local.Instance = engine.instance.metric()
+	engine.setLocalMetrics(local)
 	engine.resetStats()
 	return engine.metricsMetadata, taskMetrics, nil
 }
 
+// QueueSizes returns the number of stats queued for each container being
+// watched, by docker id.
+func (engine *DockerStatsEngine) QueueSizes() map[string]int {
+	engine.containersLock.RLock()
+	defer engine.containersLock.RUnlock()
+
+	sizes := make(map[string]int)
+	for _, containers := range engine.tasksToContainers {
+		for dockerID, container := range containers {
+			sizes[dockerID] = container.statsQueue.Len()
+		}
+	}
+	return sizes
+}
+
+// numContainers returns the number of containers being watched. The caller
+// must hold the containers lock.
+func (engine *DockerStatsEngine) numContainers() int {
+	containers := 0
+	for _, containerMap := range engine.tasksToContainers {
+		containers += len(containerMap)
+	}
+	return containers
+}
+
 func (engine *DockerStatsEngine) isIdle() bool {
 	return len(engine.tasksToContainers) == 0
 }
@@ -279,6 +366,18 @@ func (engine *DockerStatsEngine) addContainer(dockerID string) {
 		return
 	}
 
+	var apiContainer *api.Container
+	dockerContainer, err := engine.resolver.ResolveContainer(dockerID)
+	if err != nil {
+		log.Debug("Could not map container to its definition; not reporting cpu relative to reservation", "err", err, "id", dockerID)
+	} else {
+		apiContainer = dockerContainer.Container
+	}
+	if engine.exclusions.excludes(task, apiContainer) {
+		log.Debug("Container excluded from stats, ignoring", "id", dockerID, "task", task.Arn)
+		return
+	}
+
 	// Check if this container is already being watched.
 	_, taskExists := engine.tasksToContainers[task.Arn]
 	if taskExists {
@@ -295,9 +394,20 @@ func (engine *DockerStatsEngine) addContainer(dockerID string) {
 	}
 
 	log.Debug("Adding container to stats watch list", "id", dockerID, "task", task.Arn)
-	container := newCronContainer(&dockerID, engine.dockerGraphPath)
+	container := newCronContainer(&dockerID, containerStateDirs(engine.dockerGraphPath, engine.execDriver), engine.cgroupDriver)
+	if apiContainer != nil {
+		container.cpuShares = apiContainer.Cpu
+	}
+	container.sampler = engine.sampler
+	container.converters = engine.converters
+	container.taskArn = task.Arn
+	container.containerName = dockerID
+	if apiContainer != nil {
+		container.containerName = apiContainer.Name
+	}
 	engine.tasksToContainers[task.Arn][dockerID] = container
 	engine.tasksToDefinitions[task.Arn] = &taskDefinition{family: task.Family, version: task.Version}
+	engine.sampler.setContainers(engine.numContainers())
 	container.StartStatsCron()
 }
 
@@ -330,6 +440,7 @@ func (engine *DockerStatsEngine) removeContainer(dockerID string) {
 
 	container.StopStatsCron()
 	delete(engine.tasksToContainers[task.Arn], dockerID)
+	engine.sampler.setContainers(engine.numContainers())
 	log.Debug("Deleted container from tasks", "id", dockerID)
 
 	if len(engine.tasksToContainers[task.Arn]) == 0 {
@@ -48,11 +48,6 @@ var client, _ = docker.NewClient(endpoint)
 
 var cfg = config.DefaultConfig()
 
-func init() {
-	// Set DockerGraphPath as per changes in 1.6
-	cfg.DockerGraphPath = "/var/run/docker"
-}
-
 // createGremlin creates the gremlin container using the docker client.
 // It is used only in the test code.
 func createGremlin(client *docker.Client) (*docker.Container, error) {
@@ -88,6 +83,15 @@ func (resolver *IntegContainerMetadataResolver) ResolveTask(containerID string)
 	return task, nil
 }
 
+func (resolver *IntegContainerMetadataResolver) ResolveContainer(containerID string) (*api.DockerContainer, error) {
+	name, exists := resolver.containerIDToName[containerID]
+	if !exists {
+		return nil, fmt.Errorf("unmapped container")
+	}
+
+	return &api.DockerContainer{DockerId: containerID, Container: &api.Container{Name: name}}, nil
+}
+
 func (resolver *IntegContainerMetadataResolver) ResolveName(dockerID string) (string, error) {
 	name, exists := resolver.containerIDToName[dockerID]
 	if !exists {
@@ -129,6 +129,7 @@ func TestStatsEngineAddRemoveContainers(t *testing.T) {
 	resolver.EXPECT().ResolveTask("c4").AnyTimes().Return(nil, fmt.Errorf("unmapped container"))
 	resolver.EXPECT().ResolveTask("c5").AnyTimes().Return(t2, nil)
 	resolver.EXPECT().ResolveTask("c6").AnyTimes().Return(t3, nil)
+	resolver.EXPECT().ResolveContainer(gomock.Any()).AnyTimes().Return(nil, fmt.Errorf("unmapped container"))
 
 	engine := NewDockerStatsEngine(&cfg)
 	engine.resolver = resolver
@@ -253,6 +254,10 @@ func TestStatsEngineMetadataInStatsSets(t *testing.T) {
 	resolver := mock_resolver.NewMockContainerMetadataResolver(mockCtrl)
 	t1 := &api.Task{Arn: "t1", Family: "f1"}
 	resolver.EXPECT().ResolveTask("c1").AnyTimes().Return(t1, nil)
+	resolver.EXPECT().ResolveContainer("c1").AnyTimes().Return(&api.DockerContainer{
+		DockerId:  "c1",
+		Container: &api.Container{Name: "c1", Cpu: 512},
+	}, nil)
 
 	engine := NewDockerStatsEngine(&cfg)
 	engine.resolver = resolver
@@ -282,6 +287,16 @@ func TestStatsEngineMetadataInStatsSets(t *testing.T) {
 	if *taskMetrics[0].TaskArn != "t1" {
 		t.Error("Incorrect task arn. Expected: t1, got: ", *taskMetrics[0].TaskArn)
 	}
+	cpuStatsSet := taskMetrics[0].ContainerMetrics[0].CpuStatsSet
+	local := engine.LocalMetrics()
+	if local == nil || len(local.Containers) != 1 || local.Containers[0].CPUReservationStatsSet == nil {
+		t.Fatal("Expected cpu reservation stats for a container with cpu shares")
+	}
+	cpuReservationStatsSet := local.Containers[0].CPUReservationStatsSet
+	// Half a core is reserved, so usage relative to it is double
+	if *cpuReservationStatsSet.Max != 2**cpuStatsSet.Max {
+		t.Errorf("Expected cpu reservation max of %f, got %f", 2**cpuStatsSet.Max, *cpuReservationStatsSet.Max)
+	}
 	if *metadata.Cluster != defaultCluster {
 		t.Errorf("Cluster Arn not set in metadata. Expected: %s, got: %s", defaultCluster, *metadata.Cluster)
 	}
@@ -332,6 +347,28 @@ func TestStatsEngineTerminalTask(t *testing.T) {
 	}
 }
 
+func TestStatsEngineExcludedContainer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	resolver := mock_resolver.NewMockContainerMetadataResolver(mockCtrl)
+	resolver.EXPECT().ResolveTask("c1").Return(&api.Task{Arn: "t1", Family: "f1"}, nil)
+	resolver.EXPECT().ResolveContainer("c1").Return(&api.DockerContainer{
+		DockerId:  "c1",
+		Container: &api.Container{Name: "log-router"},
+	}, nil)
+	engine := NewDockerStatsEngine(&cfg)
+	engine.resolver = resolver
+	engine.metricsMetadata = newMetricsMetadata(&defaultCluster, &defaultContainerInstance)
+	engine.exclusions = &statsExclusions{containerNames: []string{"log-*"}}
+	defer func() { engine.exclusions = newStatsExclusions(&cfg) }()
+
+	engine.addContainer("c1")
+	err := validateIdleContainerMetrics(engine)
+	if err != nil {
+		t.Fatal("Error validating metadata: ", err)
+	}
+}
+
 func TestStatsEngineClientErrorListingContainers(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -0,0 +1,73 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"path"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+)
+
+// statsExclusions decides which containers stats are not collected for
+type statsExclusions struct {
+	labels         []string
+	containerNames []string
+	taskFamilies   []string
+}
+
+func newStatsExclusions(cfg *config.Config) *statsExclusions {
+	for _, pattern := range cfg.StatsExcludeContainerNames {
+		if _, err := path.Match(pattern, ""); err != nil {
+			log.Warn("Invalid container name pattern excluded from stats; it will match nothing", "pattern", pattern, "err", err)
+		}
+	}
+	return &statsExclusions{
+		labels:         cfg.StatsExcludeLabels,
+		containerNames: cfg.StatsExcludeContainerNames,
+		taskFamilies:   cfg.StatsExcludeTaskFamilies,
+	}
+}
+
+// excludes returns whether the container's stats should not be collected.
+// The container may be nil if it could not be resolved, in which case only
+// its task is considered.
+func (exclusions *statsExclusions) excludes(task *api.Task, container *api.Container) bool {
+	for _, family := range exclusions.taskFamilies {
+		if task.Family == family {
+			return true
+		}
+	}
+	if container == nil {
+		return false
+	}
+	for _, pattern := range exclusions.containerNames {
+		if matched, _ := path.Match(pattern, container.Name); matched {
+			return true
+		}
+	}
+	for _, label := range exclusions.labels {
+		key, value := label, ""
+		hasValue := false
+		if eq := strings.Index(label, "="); eq != -1 {
+			key, value, hasValue = label[:eq], label[eq+1:], true
+		}
+		actual, ok := container.DockerLabels[key]
+		if ok && (!hasValue || actual == value) {
+			return true
+		}
+	}
+	return false
+}
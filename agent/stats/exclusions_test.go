@@ -0,0 +1,50 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+)
+
+func TestStatsExclusions(t *testing.T) {
+	exclusions := newStatsExclusions(&config.Config{
+		StatsExcludeLabels:         []string{"ecs.sidecar", "team=infra"},
+		StatsExcludeContainerNames: []string{"log-*", "["},
+		StatsExcludeTaskFamilies:   []string{"daemon"},
+	})
+	task := &api.Task{Family: "web"}
+
+	testCases := []struct {
+		name      string
+		task      *api.Task
+		container *api.Container
+		excluded  bool
+	}{
+		{"no match", task, &api.Container{Name: "app", DockerLabels: map[string]string{"team": "web"}}, false},
+		{"family", &api.Task{Family: "daemon"}, &api.Container{Name: "app"}, true},
+		{"family without container", &api.Task{Family: "daemon"}, nil, true},
+		{"unresolved container", task, nil, false},
+		{"name pattern", task, &api.Container{Name: "log-router"}, true},
+		{"label key", task, &api.Container{Name: "app", DockerLabels: map[string]string{"ecs.sidecar": ""}}, true},
+		{"label value", task, &api.Container{Name: "app", DockerLabels: map[string]string{"team": "infra"}}, true},
+	}
+	for _, testCase := range testCases {
+		if excluded := exclusions.excludes(testCase.task, testCase.container); excluded != testCase.excluded {
+			t.Errorf("%s: expected excluded %v, got %v", testCase.name, testCase.excluded, excluded)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"bufio"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"golang.org/x/net/context"
+)
+
+// instanceSamplingInterval is how often the instance's utilization is sampled
+const instanceSamplingInterval = 5 * time.Second
+
+// instanceStats samples the utilization of the whole instance: the
+// percentage of its cpu capacity in use, the MiB of memory in use, and the
+// percentage of the docker data directory's filesystem in use. A nil
+// instanceStats samples nothing.
+type instanceStats struct {
+	lock     sync.Mutex
+	procDir  string
+	diskPath string
+	// lastCPUIdle and lastCPUTotal are the cumulative cpu times of the
+	// previous sample, in clock ticks. Utilization is calculated between
+	// consecutive samples.
+	lastCPUIdle  uint64
+	lastCPUTotal uint64
+	cpu          statsAccumulator
+	memory       statsAccumulator
+	disk         statsAccumulator
+}
+
+func newInstanceStats(procDir, diskPath string) *instanceStats {
+	return &instanceStats{procDir: procDir, diskPath: diskPath}
+}
+
+// setDiskPath sets the path whose filesystem's usage is sampled
+func (stats *instanceStats) setDiskPath(diskPath string) {
+	if stats == nil {
+		return
+	}
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+	stats.diskPath = diskPath
+}
+
+// sampleEvery samples the instance's utilization every interval until ctx
+// is cancelled
+func (stats *instanceStats) sampleEvery(ctx context.Context, interval time.Duration) {
+	if stats == nil {
+		return
+	}
+	for {
+		stats.sample()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// sample reads the instance's current utilization. Whatever can't be read
+// is left out of the sample.
+func (stats *instanceStats) sample() {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	idle, total, err := readCPUTimes(filepath.Join(stats.procDir, "stat"))
+	if err != nil {
+		log.Debug("Unable to read instance cpu usage", "err", err)
+	} else {
+		if stats.lastCPUTotal != 0 && total > stats.lastCPUTotal {
+			busy := float64(total-stats.lastCPUTotal) - float64(idle-stats.lastCPUIdle)
+			stats.cpu.add(100 * busy / float64(total-stats.lastCPUTotal))
+		}
+		stats.lastCPUIdle, stats.lastCPUTotal = idle, total
+	}
+
+	if usedMiB, err := readMemoryUsedMiB(filepath.Join(stats.procDir, "meminfo")); err != nil {
+		log.Debug("Unable to read instance memory usage", "err", err)
+	} else {
+		stats.memory.add(usedMiB)
+	}
+
+	if stats.diskPath != "" {
+		if usedPercent, err := diskUsedPercent(stats.diskPath); err != nil {
+			log.Debug("Unable to read instance disk usage", "path", stats.diskPath, "err", err)
+		} else {
+			stats.disk.add(usedPercent)
+		}
+	}
+}
+
+// InstanceMetric holds the stats sets of the instance's utilization: the
+// percentage of its cpu capacity in use, the MiB of memory in use, and the
+// percentage of the docker data directory's filesystem in use. Those without
+// samples are nil.
+type InstanceMetric struct {
+	CPUStatsSet    *ecstcs.CWStatsSet
+	MemoryStatsSet *ecstcs.CWStatsSet
+	DiskStatsSet   *ecstcs.CWStatsSet
+}
+
+// metric returns the stats sets of the samples taken since it was last
+// called, or nil if there are none
+func (stats *instanceStats) metric() *InstanceMetric {
+	if stats == nil {
+		return nil
+	}
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	metric := &InstanceMetric{
+		CPUStatsSet:    stats.cpu.statsSet(),
+		MemoryStatsSet: stats.memory.statsSet(),
+		DiskStatsSet:   stats.disk.statsSet(),
+	}
+	stats.cpu, stats.memory, stats.disk = statsAccumulator{}, statsAccumulator{}, statsAccumulator{}
+	if metric.CPUStatsSet == nil && metric.MemoryStatsSet == nil && metric.DiskStatsSet == nil {
+		return nil
+	}
+	return metric
+}
+
+// statsAccumulator accumulates samples into a stats set
+type statsAccumulator struct {
+	min   float64
+	max   float64
+	sum   float64
+	count int64
+}
+
+func (acc *statsAccumulator) add(value float64) {
+	if acc.count == 0 {
+		acc.min, acc.max = value, value
+	}
+	acc.min = math.Min(acc.min, value)
+	acc.max = math.Max(acc.max, value)
+	acc.sum += value
+	acc.count++
+}
+
+// statsSet returns the accumulated stats set, or nil if there are no samples
+func (acc statsAccumulator) statsSet() *ecstcs.CWStatsSet {
+	if acc.count == 0 {
+		return nil
+	}
+	return &ecstcs.CWStatsSet{Min: &acc.min, Max: &acc.max, Sum: &acc.sum, SampleCount: &acc.count}
+}
+
+// readCPUTimes returns the idle and total cpu time of all cpus from the
+// first line of /proc/stat, such as
+//
+//	cpu  4705 356 584 3699176 23060 0 277 0 0 0
+//
+// in which the fields are user, nice, system, idle, iowait, irq, softirq,
+// steal, guest and guest_nice time. Time waiting for io counts as idle, and
+// guest time is already counted in user time.
+func readCPUTimes(file string) (uint64, uint64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return 0, 0, errors.New("empty " + file)
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, errors.New("unexpected first line of " + file)
+	}
+	fields = fields[1:]
+	if len(fields) > 8 {
+		fields = fields[:8]
+	}
+	var idle, total uint64
+	for i, field := range fields {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		if i == 3 || i == 4 {
+			idle += value
+		}
+		total += value
+	}
+	return idle, total, nil
+}
+
+// readMemoryUsedMiB returns the MiB of memory in use from /proc/meminfo: all
+// of it except what is available to start new applications without
+// swapping. Kernels older than 3.14 don't report what is available, so free
+// memory and the page cache are used instead.
+func readMemoryUsedMiB(file string) (float64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	kB := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		kB[strings.TrimSuffix(fields[0], ":")] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	memTotal, ok := kB["MemTotal"]
+	if !ok {
+		return 0, errors.New("no MemTotal in " + file)
+	}
+	available, ok := kB["MemAvailable"]
+	if !ok {
+		available = kB["MemFree"] + kB["Buffers"] + kB["Cached"]
+	}
+	if available > memTotal {
+		available = memTotal
+	}
+	return float64(memTotal-available) / 1024, nil
+}
+
+// diskUsedPercent returns the percentage of the space on path's filesystem
+// which is in use, of the space available to unprivileged users, as df does
+func diskUsedPercent(path string) (float64, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return 0, err
+	}
+	used := fs.Blocks - fs.Bfree
+	if used+fs.Bavail == 0 {
+		return 0, errors.New("no space on " + path)
+	}
+	return 100 * float64(used) / float64(used+fs.Bavail), nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeProcFile(t *testing.T, dir, name, contents string) {
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstanceStats(t *testing.T) {
+	procDir, err := ioutil.TempDir("", "instance-stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(procDir)
+	writeProcFile(t, procDir, "meminfo", "MemTotal:        4096000 kB\nMemFree:          512000 kB\nMemAvailable:    1024000 kB\n")
+
+	stats := newInstanceStats(procDir, procDir)
+	writeProcFile(t, procDir, "stat", "cpu  100 0 100 700 100 0 0 0 50 0\ncpu0 100 0 100 700 100 0 0 0 50 0\n")
+	stats.sample()
+	// 300 of the next 1000 ticks are busy
+	writeProcFile(t, procDir, "stat", "cpu  300 0 200 1300 200 0 0 0 50 0\ncpu0 300 0 200 1300 200 0 0 0 50 0\n")
+	stats.sample()
+
+	metric := stats.metric()
+	if metric == nil {
+		t.Fatal("Expected an instance metric")
+	}
+	if metric.CPUStatsSet == nil || *metric.CPUStatsSet.SampleCount != 1 || *metric.CPUStatsSet.Sum != 30 {
+		t.Error("Unexpected cpu stats", metric.CPUStatsSet)
+	}
+	if metric.MemoryStatsSet == nil || *metric.MemoryStatsSet.SampleCount != 2 || *metric.MemoryStatsSet.Max != 3000 {
+		t.Error("Unexpected memory stats", metric.MemoryStatsSet)
+	}
+	if metric.DiskStatsSet == nil || *metric.DiskStatsSet.SampleCount != 2 || *metric.DiskStatsSet.Max > 100 {
+		t.Error("Unexpected disk stats", metric.DiskStatsSet)
+	}
+	if stats.metric() != nil {
+		t.Error("Expected samples to be reset once read")
+	}
+}
+
+func TestReadMemoryUsedWithoutMemAvailable(t *testing.T) {
+	procDir, err := ioutil.TempDir("", "instance-stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(procDir)
+	writeProcFile(t, procDir, "meminfo", "MemTotal:        2048 kB\nMemFree:          512 kB\nBuffers:          256 kB\nCached:           256 kB\n")
+
+	usedMiB, err := readMemoryUsedMiB(filepath.Join(procDir, "meminfo"))
+	if err != nil || usedMiB != 1 {
+		t.Error("Expected 1 MiB in use", usedMiB, err)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+)
+
+// LocalMetrics holds the metrics read along with those sent to the telemetry
+// service which its model has no fields for. They are only published by
+// the publishers which implement LocalPublisher.
+type LocalMetrics struct {
+	// Instance is the instance's utilization, if it was sampled
+	Instance   *InstanceMetric
+	Containers []*LocalContainerMetric
+}
+
+// LocalContainerMetric holds a container's local metrics over a publishing
+// interval. Those the container has none of are nil.
+type LocalContainerMetric struct {
+	TaskArn       string
+	ContainerName string
+	// CPUReservationStatsSet is of the container's cpu utilization relative
+	// to its reservation. Containers are given cpu shares rather than a cfs
+	// quota, so they may burst past their reservation while the instance has
+	// cpu to spare. It is nil for containers without a reservation.
+	CPUReservationStatsSet *ecstcs.CWStatsSet
+	// PidsStatsSet is of the percentage of its pids limit the container uses
+	PidsStatsSet *ecstcs.CWStatsSet
+	// The pressure stats sets are of the percentage of time some of the
+	// container's tasks stalled waiting for memory, cpu and io. Only kernels
+	// with cgroup v2 and pressure stall information report them.
+	MemoryPressureStatsSet *ecstcs.CWStatsSet
+	CPUPressureStatsSet    *ecstcs.CWStatsSet
+	IOPressureStatsSet     *ecstcs.CWStatsSet
+	// CPUBurstMax is the container's highest cpu utilization in any second.
+	// Bursts shorter than the publishing interval are averaged away in the
+	// cpu stats set sent to the telemetry service.
+	CPUBurstMax *float64
+	// Extra are the metrics of the container's stats converters which had
+	// values for it
+	Extra []*LocalExtraMetric
+	// OOMKills is how many of the container's processes have been killed for
+	// running out of memory since it started, if the kernel counts them
+	OOMKills *uint64
+}
+
+// LocalExtraMetric is the stats set of a stats converter's metric
+type LocalExtraMetric struct {
+	Name     string
+	StatsSet *ecstcs.CWStatsSet
+}
+
+// localMetricValue is the average of one of a container's local metrics
+type localMetricValue struct {
+	name  string
+	unit  string
+	value float64
+}
+
+// values returns the average of each of the container's local metrics, or
+// the latest value of those which are counts or maximums
+func (metric *LocalContainerMetric) values() []localMetricValue {
+	var values []localMetricValue
+	if metric.CPUReservationStatsSet != nil {
+		values = append(values, localMetricValue{"CpuReservationUtilized", "Percent", average(metric.CPUReservationStatsSet)})
+	}
+	if metric.PidsStatsSet != nil {
+		values = append(values, localMetricValue{"PidsUtilized", "Percent", average(metric.PidsStatsSet)})
+	}
+	if metric.MemoryPressureStatsSet != nil {
+		values = append(values, localMetricValue{"MemoryPressure", "Percent", average(metric.MemoryPressureStatsSet)})
+	}
+	if metric.CPUPressureStatsSet != nil {
+		values = append(values, localMetricValue{"CpuPressure", "Percent", average(metric.CPUPressureStatsSet)})
+	}
+	if metric.IOPressureStatsSet != nil {
+		values = append(values, localMetricValue{"IoPressure", "Percent", average(metric.IOPressureStatsSet)})
+	}
+	if metric.CPUBurstMax != nil {
+		values = append(values, localMetricValue{"CpuBurstMax", "Percent", *metric.CPUBurstMax})
+	}
+	if metric.OOMKills != nil {
+		values = append(values, localMetricValue{"OomKills", "Count", float64(*metric.OOMKills)})
+	}
+	for _, extra := range metric.Extra {
+		values = append(values, localMetricValue{extra.Name, "None", average(extra.StatsSet)})
+	}
+	return values
+}
+
+// values returns the average of each of the instance's metrics
+func (metric *InstanceMetric) values() []localMetricValue {
+	var values []localMetricValue
+	if metric.CPUStatsSet != nil {
+		values = append(values, localMetricValue{"InstanceCpuUtilized", "Percent", average(metric.CPUStatsSet)})
+	}
+	if metric.MemoryStatsSet != nil {
+		values = append(values, localMetricValue{"InstanceMemoryUtilized", "Megabytes", average(metric.MemoryStatsSet)})
+	}
+	if metric.DiskStatsSet != nil {
+		values = append(values, localMetricValue{"InstanceDiskUtilized", "Percent", average(metric.DiskStatsSet)})
+	}
+	return values
+}
+
+// LocalPublisher is a Publisher which also publishes local metrics
+type LocalPublisher interface {
+	PublishLocal(metadata *ecstcs.MetricsMetadata, local *LocalMetrics) error
+}
+
+// localMetricsReader is an Engine which keeps the local metrics read by its
+// last GetInstanceMetrics
+type localMetricsReader interface {
+	LocalMetrics() *LocalMetrics
+}
+
+// LocalMetrics returns the local metrics read by the last GetInstanceMetrics,
+// or nil if it hasn't returned any
+func (engine *DockerStatsEngine) LocalMetrics() *LocalMetrics {
+	engine.localMetricsLock.Lock()
+	defer engine.localMetricsLock.Unlock()
+	return engine.localMetrics
+}
+
+func (engine *DockerStatsEngine) setLocalMetrics(local *LocalMetrics) {
+	engine.localMetricsLock.Lock()
+	defer engine.localMetricsLock.Unlock()
+	engine.localMetrics = local
+}
+
+// getLocalMetricsForTask gets the local metrics of each of a task's containers
+func (engine *DockerStatsEngine) getLocalMetricsForTask(taskArn string) []*LocalContainerMetric {
+	engine.containersLock.Lock()
+	defer engine.containersLock.Unlock()
+
+	var metrics []*LocalContainerMetric
+	for _, container := range engine.tasksToContainers[taskArn] {
+		metric := &LocalContainerMetric{TaskArn: taskArn, ContainerName: container.containerName}
+		if container.cpuShares > 0 {
+			cpuReservationStatsSet, err := container.statsQueue.GetCPUReservationStatsSet(container.cpuShares)
+			if err != nil {
+				log.Warn("Error getting cpu reservation stats", "err", err, "container", container.containerMetadata)
+			} else {
+				metric.CPUReservationStatsSet = cpuReservationStatsSet
+			}
+		}
+		if pidsStatsSet, err := container.statsQueue.GetPidsStatsSet(); err == nil {
+			metric.PidsStatsSet = pidsStatsSet
+		}
+		if memoryPressureStatsSet, err := container.statsQueue.GetMemoryPressureStatsSet(); err == nil {
+			metric.MemoryPressureStatsSet = memoryPressureStatsSet
+		}
+		if cpuPressureStatsSet, err := container.statsQueue.GetCPUPressureStatsSet(); err == nil {
+			metric.CPUPressureStatsSet = cpuPressureStatsSet
+		}
+		if ioPressureStatsSet, err := container.statsQueue.GetIOPressureStatsSet(); err == nil {
+			metric.IOPressureStatsSet = ioPressureStatsSet
+		}
+		if cpuBurst, err := container.statsQueue.GetCPUBurst(); err == nil {
+			metric.CPUBurstMax = &cpuBurst
+		}
+		if oomKills, ok := container.OOMKills(); ok {
+			metric.OOMKills = &oomKills
+		}
+		for i, converter := range container.converters {
+			if extraStatsSet, err := container.statsQueue.GetExtraStatsSet(i); err == nil {
+				metric.Extra = append(metric.Extra, &LocalExtraMetric{Name: converter.Name(), StatsSet: extraStatsSet})
+			}
+		}
+		if len(metric.values()) > 0 {
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
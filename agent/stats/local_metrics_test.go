@@ -0,0 +1,47 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLocalContainerMetricValues(t *testing.T) {
+	cpuBurst, oomKills := 150.0, uint64(3)
+	metric := &LocalContainerMetric{
+		CPUReservationStatsSet: testStatsSet(100, 4),
+		PidsStatsSet:           testStatsSet(90, 2),
+		MemoryPressureStatsSet: testStatsSet(30, 3),
+		IOPressureStatsSet:     testStatsSet(4, 2),
+		CPUBurstMax:            &cpuBurst,
+		OOMKills:               &oomKills,
+		Extra:                  []*LocalExtraMetric{{Name: "ThrottledPeriods", StatsSet: testStatsSet(12, 2)}},
+	}
+	expected := []localMetricValue{
+		{"CpuReservationUtilized", "Percent", 25},
+		{"PidsUtilized", "Percent", 45},
+		{"MemoryPressure", "Percent", 10},
+		{"IoPressure", "Percent", 2},
+		{"CpuBurstMax", "Percent", 150},
+		{"OomKills", "Count", 3},
+		{"ThrottledPeriods", "None", 6},
+	}
+	if values := metric.values(); !reflect.DeepEqual(values, expected) {
+		t.Error("Wrong values", values)
+	}
+	if values := (&LocalContainerMetric{}).values(); len(values) != 0 {
+		t.Error("Expected no values for a container without local metrics", values)
+	}
+}
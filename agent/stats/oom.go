@@ -0,0 +1,80 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aws/amazon-ecs-agent/agent/eventstream"
+)
+
+// readOOMKills returns how many processes in the container the kernel has
+// killed for running out of memory. It is read from memory.events under
+// cgroup v2, or memory.oom_control in the memory cgroup under v1. Kernels
+// before 4.13 don't count kills under v1.
+func readOOMKills(cgroupV2Dir, memoryCgroupDir string) (uint64, bool) {
+	if cgroupV2Dir != "" {
+		return readOOMKillCount(filepath.Join(cgroupV2Dir, "memory.events"))
+	}
+	if memoryCgroupDir != "" {
+		return readOOMKillCount(filepath.Join(memoryCgroupDir, "memory.oom_control"))
+	}
+	return 0, false
+}
+
+// readOOMKillCount reads the "oom_kill" line of file
+func readOOMKillCount(file string) (uint64, bool) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != "oom_kill" {
+			continue
+		}
+		kills, err := strconv.ParseUint(fields[1], 10, 64)
+		return kills, err == nil
+	}
+	return 0, false
+}
+
+// checkOOMKills records how many processes in the container have been killed
+// for running out of memory. Kills after the first check are logged and
+// published to the event stream, since the container itself keeps running if
+// the process killed wasn't its main process.
+func (container *CronContainer) checkOOMKills(kills uint64) {
+	previous := atomic.SwapUint64(&container.oomKills, kills)
+	if atomic.SwapUint32(&container.oomKillsChecked, 1) == 0 {
+		return
+	}
+	if kills <= previous {
+		return
+	}
+	log.Warn("Processes in container were killed for running out of memory", "task", container.taskArn, "container", container.containerName, "kills", kills-previous)
+	eventstream.Default.ContainerOOMKilled(container.taskArn, container.containerName, kills-previous)
+}
+
+// OOMKills returns how many processes in the container have been killed for
+// running out of memory, and whether the kernel counts them
+func (container *CronContainer) OOMKills() (uint64, bool) {
+	return atomic.LoadUint64(&container.oomKills), atomic.LoadUint32(&container.oomKillsChecked) == 1
+}
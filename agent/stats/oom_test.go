@@ -0,0 +1,47 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReadOOMKills(t *testing.T) {
+	v1 := filepath.Join("testdata", "oom", "v1")
+	v2 := filepath.Join("testdata", "oom", "v2")
+	if kills, ok := readOOMKills("", v1); !ok || kills != 3 {
+		t.Error("Unexpected cgroup v1 kills", kills, ok)
+	}
+	if kills, ok := readOOMKills(v2, v1); !ok || kills != 2 {
+		t.Error("Expected cgroup v2 kills to be preferred", kills, ok)
+	}
+	if _, ok := readOOMKills("", filepath.Join("testdata", "oom")); ok {
+		t.Error("Expected no kills without a count")
+	}
+}
+
+func TestCheckOOMKills(t *testing.T) {
+	container := &CronContainer{taskArn: "t1", containerName: "web"}
+	if _, ok := container.OOMKills(); ok {
+		t.Error("Expected kills to be unknown before they're checked")
+	}
+
+	// Kills before the container was watched are counted but not reported
+	container.checkOOMKills(1)
+	container.checkOOMKills(3)
+	if kills, ok := container.OOMKills(); !ok || kills != 3 {
+		t.Error("Unexpected kills", kills, ok)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"github.com/aws/amazon-ecs-agent/agent/otlp"
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+// gaugeExporter exports gauges to an OpenTelemetry collector, as
+// otlp.Exporter does
+type gaugeExporter interface {
+	ExportGauges(resource []otlp.Attribute, gauges []otlp.Gauge) error
+}
+
+// OTLPPublisher exports each task's usage as OpenTelemetry gauges to a
+// collector using OTLP over HTTP.
+type OTLPPublisher struct {
+	exporter gaugeExporter
+}
+
+// NewOTLPPublisher returns a publisher which exports to the collector at
+// endpoint, such as http://127.0.0.1:4318
+func NewOTLPPublisher(endpoint string) *OTLPPublisher {
+	return &OTLPPublisher{exporter: otlp.NewExporter(endpoint)}
+}
+
+func (publisher *OTLPPublisher) Name() string { return "otlp" }
+
+// Publish exports the usage of every task in one request
+func (publisher *OTLPPublisher) Publish(metadata *ecstcs.MetricsMetadata, taskMetrics []*ecstcs.TaskMetric) error {
+	resource, gauges := otlpGauges(metadata, taskMetrics)
+	return publisher.exporter.ExportGauges(resource, gauges)
+}
+
+func otlpGauges(metadata *ecstcs.MetricsMetadata, taskMetrics []*ecstcs.TaskMetric) ([]otlp.Attribute, []otlp.Gauge) {
+	now := ttime.Now()
+	cpu := otlp.Gauge{Name: "ecs.task.cpu.utilization", Unit: "%"}
+	memory := otlp.Gauge{Name: "ecs.task.memory.usage", Unit: "MiBy"}
+	for _, usage := range taskUsages(taskMetrics) {
+		attributes := []otlp.Attribute{
+			{Key: "aws.ecs.task.arn", Value: usage.taskArn},
+			{Key: "aws.ecs.task.family", Value: usage.family},
+			{Key: "aws.ecs.task.revision", Value: usage.version},
+		}
+		cpu.DataPoints = append(cpu.DataPoints, otlp.DataPoint{Attributes: attributes, Time: now, Value: usage.cpuPercent})
+		memory.DataPoints = append(memory.DataPoints, otlp.DataPoint{Attributes: attributes, Time: now, Value: usage.memoryMiB})
+	}
+	resource := []otlp.Attribute{
+		{Key: "aws.ecs.cluster.arn", Value: stringValue(metadata.Cluster)},
+		{Key: "aws.ecs.container.instance.arn", Value: stringValue(metadata.ContainerInstance)},
+	}
+	return resource, []otlp.Gauge{cpu, memory}
+}
@@ -0,0 +1,134 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/eventstream"
+)
+
+const (
+	// pidsWarningThreshold is the share of its pids limit a container may use
+	// before a warning is logged and published
+	pidsWarningThreshold = 0.9
+
+	procRoot = "/proc"
+)
+
+// containerPidsCgroupDir returns the container's pids cgroup directory: its
+// cgroup v2 directory if it has one, or else its directory in the v1 pids
+// hierarchy
+func containerPidsCgroupDir(container *CronContainer, cgroupPaths map[string]string) string {
+	if container.cgroupV2Dir != "" {
+		return container.cgroupV2Dir
+	}
+	if dir, ok := cgroupPaths["pids"]; ok {
+		return dir
+	}
+	return filepath.Join(cgroupRoot, "pids", containerCgroupRelativePath(container.cgroupDriver, *container.containerMetadata.DockerID))
+}
+
+// readPids returns how many processes and threads are in the pids cgroup dir
+// and its limit, which is 0 if there is none. It returns false if the kernel
+// has no pids controller.
+func readPids(dir string) (uint64, uint64, bool) {
+	current, err := readCgroupUint(filepath.Join(dir, "pids.current"))
+	if err != nil {
+		return 0, 0, false
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "pids.max"))
+	if err != nil {
+		return 0, 0, false
+	}
+	max := strings.TrimSpace(string(contents))
+	if max == "max" {
+		return current, 0, true
+	}
+	limit, err := strconv.ParseUint(max, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return current, limit, true
+}
+
+func readCgroupUint(file string) (uint64, error) {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(contents)), 10, 64)
+}
+
+// pidsPercent returns how much of its pids limit the container uses, or NaN
+// if it has no limit
+func pidsPercent(current, limit uint64) float32 {
+	if limit == 0 {
+		return nan32()
+	}
+	return 100 * float32(current) / float32(limit)
+}
+
+// countZombies returns how many of the processes in the cgroup dir have exited
+// but not been reaped by their parent, as seen in proc. Processes which can't
+// be seen, such as when the agent doesn't share the host's pid namespace, are
+// not counted.
+func countZombies(dir, proc string) int {
+	f, err := os.Open(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	zombies := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		stat, err := ioutil.ReadFile(filepath.Join(proc, strings.TrimSpace(scanner.Text()), "stat"))
+		if err != nil {
+			continue
+		}
+		// The state follows the command, which is in parentheses and may
+		// itself contain spaces or parentheses
+		end := strings.LastIndex(string(stat), ")")
+		if end == -1 {
+			continue
+		}
+		if fields := strings.Fields(string(stat[end+1:])); len(fields) > 0 && fields[0] == "Z" {
+			zombies++
+		}
+	}
+	return zombies
+}
+
+// checkPids warns when the container approaches its pids limit, once each
+// time it crosses pidsWarningThreshold, since it will be unable to start new
+// processes or threads when it reaches the limit. Zombies are counted as
+// they often explain a leak.
+func (container *CronContainer) checkPids(current, limit uint64, dir string) {
+	if limit == 0 || float64(current) < pidsWarningThreshold*float64(limit) {
+		container.pidsWarned = false
+		return
+	}
+	if container.pidsWarned {
+		return
+	}
+	container.pidsWarned = true
+	zombies := countZombies(dir, procRoot)
+	log.Warn("Container is approaching its pids limit", "task", container.taskArn, "container", container.containerName, "pids", current, "limit", limit, "zombies", zombies)
+	eventstream.Default.ContainerPidsPressure(container.taskArn, container.containerName, current, limit, zombies)
+}
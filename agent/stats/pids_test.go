@@ -0,0 +1,60 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPids(t *testing.T) {
+	if pids, limit, ok := readPids(filepath.Join("testdata", "pids", "limited")); !ok || pids != 95 || limit != 100 {
+		t.Error("Unexpected limited pids", pids, limit, ok)
+	}
+	if pids, limit, ok := readPids(filepath.Join("testdata", "pids", "unlimited")); !ok || pids != 4 || limit != 0 {
+		t.Error("Unexpected unlimited pids", pids, limit, ok)
+	}
+	if _, _, ok := readPids(filepath.Join("testdata", "pids")); ok {
+		t.Error("Expected no pids without a pids controller")
+	}
+	if perc := pidsPercent(95, 100); perc != 95 {
+		t.Error("Unexpected pids percent", perc)
+	}
+	if perc := pidsPercent(4, 0); !math.IsNaN(float64(perc)) {
+		t.Error("Expected no pids percent without a limit", perc)
+	}
+}
+
+func TestCountZombies(t *testing.T) {
+	if zombies := countZombies(filepath.Join("testdata", "pids", "limited"), filepath.Join("testdata", "pids", "proc")); zombies != 1 {
+		t.Error("Unexpected zombies", zombies)
+	}
+}
+
+func TestCheckPids(t *testing.T) {
+	container := &CronContainer{taskArn: "t1", containerName: "web"}
+	container.checkPids(50, 100, "")
+	if container.pidsWarned {
+		t.Error("Expected no warning below the threshold")
+	}
+	container.checkPids(95, 100, "")
+	if !container.pidsWarned {
+		t.Error("Expected a warning above the threshold")
+	}
+	container.checkPids(50, 0, "")
+	if container.pidsWarned {
+		t.Error("Expected the warning to reset without a limit")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The files in a cgroup v2 directory holding its pressure stall information
+const (
+	memoryPressureFile = "memory.pressure"
+	cpuPressureFile    = "cpu.pressure"
+	ioPressureFile     = "io.pressure"
+)
+
+// pressureStats is the percentage of the last ten seconds in which some of a
+// container's tasks stalled waiting for memory, cpu, or io. Each is NaN if the
+// kernel doesn't report it.
+type pressureStats struct {
+	memory float32
+	cpu    float32
+	io     float32
+}
+
+// containerCgroupV2Dir returns the container's cgroup v2 directory, where
+// pressure stall information and memory events are reported, or "" if it has
+// none. Hosts using both cgroup versions mount v2 at "unified".
+func containerCgroupV2Dir(driver, dockerID string) string {
+	relative := containerCgroupRelativePath(driver, dockerID)
+	for _, dir := range []string{filepath.Join(cgroupRoot, relative), filepath.Join(cgroupRoot, "unified", relative)} {
+		if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+// readPressure reads the pressure stall information in dir into stats
+func readPressure(dir string, stats *pressureStats) {
+	if dir == "" {
+		stats.memory, stats.cpu, stats.io = nan32(), nan32(), nan32()
+		return
+	}
+	stats.memory = readPressureFile(filepath.Join(dir, memoryPressureFile))
+	stats.cpu = readPressureFile(filepath.Join(dir, cpuPressureFile))
+	stats.io = readPressureFile(filepath.Join(dir, ioPressureFile))
+}
+
+// readPressureFile returns the ten second average of the "some" line of a
+// pressure file, such as
+//
+//	some avg10=1.52 avg60=0.87 avg300=0.20 total=123456
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//
+// or NaN if it can't be read
+func readPressureFile(file string) float32 {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nan32()
+	}
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "some" {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "avg10=") {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimPrefix(field, "avg10="), 32)
+			if err != nil {
+				return nan32()
+			}
+			return float32(value)
+		}
+	}
+	return nan32()
+}
@@ -0,0 +1,62 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadPressure(t *testing.T) {
+	var pressure pressureStats
+	readPressure(filepath.Join("testdata", "pressure"), &pressure)
+	if pressure.memory != 12.5 || pressure.cpu != 3.75 || pressure.io != 0 {
+		t.Error("Unexpected pressure", pressure)
+	}
+
+	readPressure("", &pressure)
+	if !math.IsNaN(float64(pressure.memory)) || !math.IsNaN(float64(pressure.cpu)) || !math.IsNaN(float64(pressure.io)) {
+		t.Error("Expected no pressure without pressure stall information", pressure)
+	}
+	if !math.IsNaN(float64(readPressureFile(filepath.Join("testdata", "pressure", "missing.pressure")))) {
+		t.Error("Expected no pressure from a missing file")
+	}
+}
+
+func TestPressureStatsSet(t *testing.T) {
+	queue := NewQueue(3)
+	start := time.Now()
+	for i, memory := range []float32{10, 20, 30} {
+		stats := createContainerStats(uint64(i), 1024*1024, start.Add(time.Duration(i)*time.Second))
+		stats.pressure = pressureStats{memory: memory, cpu: 1, io: 0}
+		queue.Add(stats)
+	}
+
+	memoryStatsSet, err := queue.GetMemoryPressureStatsSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *memoryStatsSet.Min != 10 || *memoryStatsSet.Max != 30 || *memoryStatsSet.SampleCount != 3 {
+		t.Error("Unexpected memory pressure", *memoryStatsSet.Min, *memoryStatsSet.Max, *memoryStatsSet.SampleCount)
+	}
+
+	queue = NewQueue(3)
+	queue.Add(createContainerStats(1, 1024*1024, start))
+	queue.Add(createContainerStats(2, 1024*1024, start.Add(time.Second)))
+	if _, err := queue.GetIOPressureStatsSet(); err == nil {
+		t.Error("Expected an error without pressure stall information")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+// Publisher publishes the metrics collected by the stats engine somewhere
+// other than the ECS telemetry service.
+type Publisher interface {
+	// Name identifies the publisher in logs
+	Name() string
+	Publish(metadata *ecstcs.MetricsMetadata, taskMetrics []*ecstcs.TaskMetric) error
+}
+
+// NewPublishers returns the publishers enabled in cfg
+func NewPublishers(cfg *config.Config) []Publisher {
+	var publishers []Publisher
+	if cfg.StatsDAddress != "" {
+		publishers = append(publishers, NewStatsDPublisher(cfg.StatsDAddress))
+	}
+	if cfg.EMFLogGroup != "" {
+		publishers = append(publishers, NewEMFPublisher(cfg.EMFAddress, cfg.EMFLogGroup))
+	}
+	if cfg.OTLPEndpoint != "" {
+		publishers = append(publishers, NewOTLPPublisher(cfg.OTLPEndpoint))
+	}
+	return publishers
+}
+
+// PublishingEngine is an Engine which also passes the metrics it returns to
+// its publishers. Metrics are reset each time they are read, so every
+// consumer must share one reader.
+type PublishingEngine struct {
+	engine     Engine
+	publishers []Publisher
+}
+
+// NewPublishingEngine returns an Engine which reads metrics from engine and
+// publishes them with each of publishers
+func NewPublishingEngine(engine Engine, publishers ...Publisher) *PublishingEngine {
+	return &PublishingEngine{engine: engine, publishers: publishers}
+}
+
+// GetInstanceMetrics gets metrics from the underlying engine and publishes
+// them in the background.
+func (engine *PublishingEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata, []*ecstcs.TaskMetric, error) {
+	metadata, taskMetrics, err := engine.engine.GetInstanceMetrics()
+	// An idle instance has no task metrics, but may have local ones
+	if err == nil && len(engine.publishers) > 0 {
+		var local *LocalMetrics
+		if reader, ok := engine.engine.(localMetricsReader); ok {
+			local = reader.LocalMetrics()
+		}
+		go engine.publish(metadata, taskMetrics, local)
+	}
+	return metadata, taskMetrics, err
+}
+
+func (engine *PublishingEngine) publish(metadata *ecstcs.MetricsMetadata, taskMetrics []*ecstcs.TaskMetric, local *LocalMetrics) {
+	for _, publisher := range engine.publishers {
+		if len(taskMetrics) > 0 {
+			if err := publisher.Publish(metadata, taskMetrics); err != nil {
+				log.Warn("Error publishing metrics", "publisher", publisher.Name(), "err", err)
+			}
+		}
+		localPublisher, ok := publisher.(LocalPublisher)
+		if !ok || local == nil {
+			continue
+		}
+		if err := localPublisher.PublishLocal(metadata, local); err != nil {
+			log.Warn("Error publishing local metrics", "publisher", publisher.Name(), "err", err)
+		}
+	}
+}
+
+// PublishPeriodically reads metrics from engine every interval, for when they
+// are only published locally and not to the telemetry service, until stop is
+// closed
+func PublishPeriodically(engine Engine, interval time.Duration, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ttime.After(interval):
+		}
+		if _, _, err := engine.GetInstanceMetrics(); err != nil {
+			log.Debug("Error getting instance metrics", "err", err)
+		}
+	}
+}
+
+// taskUsage is a task's average resource usage over a publishing interval
+type taskUsage struct {
+	taskArn    string
+	family     string
+	version    string
+	cpuPercent float64
+	memoryMiB  float64
+}
+
+// taskUsages averages the samples of each task's containers and totals them
+// for the task
+func taskUsages(taskMetrics []*ecstcs.TaskMetric) []taskUsage {
+	usages := make([]taskUsage, 0, len(taskMetrics))
+	for _, taskMetric := range taskMetrics {
+		usage := taskUsage{
+			taskArn: stringValue(taskMetric.TaskArn),
+			family:  stringValue(taskMetric.TaskDefinitionFamily),
+			version: stringValue(taskMetric.TaskDefinitionVersion),
+		}
+		for _, containerMetric := range taskMetric.ContainerMetrics {
+			usage.cpuPercent += average(containerMetric.CpuStatsSet)
+			usage.memoryMiB += average(containerMetric.MemoryStatsSet)
+		}
+		usages = append(usages, usage)
+	}
+	return usages
+}
+
+func average(statsSet *ecstcs.CWStatsSet) float64 {
+	if statsSet == nil || statsSet.Sum == nil || statsSet.SampleCount == nil || *statsSet.SampleCount == 0 {
+		return 0
+	}
+	return *statsSet.Sum / float64(*statsSet.SampleCount)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// taskID returns the id at the end of a task arn
+func taskID(taskArn string) string {
+	return taskArn[strings.LastIndex(taskArn, "/")+1:]
+}
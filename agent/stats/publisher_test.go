@@ -0,0 +1,286 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/otlp"
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+)
+
+func testStatsSet(sum float64, sampleCount int64) *ecstcs.CWStatsSet {
+	return &ecstcs.CWStatsSet{Sum: &sum, SampleCount: &sampleCount}
+}
+
+func testMetrics() (*ecstcs.MetricsMetadata, []*ecstcs.TaskMetric) {
+	cluster, instance := "default", "ci"
+	arn, family, version := "arn:aws:ecs:us-east-1:123:task/abc-123", "web.app", "2"
+	metadata := &ecstcs.MetricsMetadata{Cluster: &cluster, ContainerInstance: &instance}
+	taskMetrics := []*ecstcs.TaskMetric{{
+		TaskArn:               &arn,
+		TaskDefinitionFamily:  &family,
+		TaskDefinitionVersion: &version,
+		ContainerMetrics: []*ecstcs.ContainerMetric{
+			{CpuStatsSet: testStatsSet(30, 3), MemoryStatsSet: testStatsSet(200, 2)},
+			{CpuStatsSet: testStatsSet(5, 1), MemoryStatsSet: testStatsSet(50, 1)},
+		},
+	}}
+	return metadata, taskMetrics
+}
+
+func TestTaskUsages(t *testing.T) {
+	_, taskMetrics := testMetrics()
+	usages := taskUsages(taskMetrics)
+	if len(usages) != 1 {
+		t.Fatal("Expected one usage, got", len(usages))
+	}
+	if usages[0].cpuPercent != 15 || usages[0].memoryMiB != 150 {
+		t.Error("Wrong usage", usages[0])
+	}
+	if taskID(usages[0].taskArn) != "abc-123" {
+		t.Error("Wrong task id", taskID(usages[0].taskArn))
+	}
+}
+
+func TestStatsDPublisher(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	err = NewStatsDPublisher(conn.LocalAddr().String()).Publish(testMetrics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "ecs.web_app.abc-123.cpu_utilization:15|g\necs.web_app.abc-123.memory_mib:150|g\n"
+	if string(buf[:n]) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(buf[:n]))
+	}
+}
+
+func TestEMFPublisher(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	records := make(chan map[string]interface{})
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var record map[string]interface{}
+		json.NewDecoder(bufio.NewReader(conn)).Decode(&record)
+		records <- record
+	}()
+
+	err = NewEMFPublisher(listener.Addr().String(), "ecs-metrics").Publish(testMetrics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := <-records
+	if record["ClusterName"] != "default" || record["CpuUtilized"] != 15.0 || record["MemoryUtilized"] != 150.0 {
+		t.Error("Wrong record", record)
+	}
+	aws, ok := record["_aws"].(map[string]interface{})
+	if !ok || aws["LogGroupName"] != "ecs-metrics" {
+		t.Error("Wrong metadata", record["_aws"])
+	}
+}
+
+type fakeGaugeExporter struct {
+	resource []otlp.Attribute
+	gauges   []otlp.Gauge
+}
+
+func (exporter *fakeGaugeExporter) ExportGauges(resource []otlp.Attribute, gauges []otlp.Gauge) error {
+	exporter.resource, exporter.gauges = resource, gauges
+	return nil
+}
+
+func TestOTLPPublisher(t *testing.T) {
+	exporter := &fakeGaugeExporter{}
+	if err := (&OTLPPublisher{exporter: exporter}).Publish(testMetrics()); err != nil {
+		t.Fatal(err)
+	}
+	if len(exporter.resource) != 2 || exporter.resource[0].Value != "default" {
+		t.Error("Wrong resource", exporter.resource)
+	}
+	if len(exporter.gauges) != 2 || exporter.gauges[0].Name != "ecs.task.cpu.utilization" || exporter.gauges[1].Name != "ecs.task.memory.usage" {
+		t.Fatal("Wrong gauges", exporter.gauges)
+	}
+	for i, expected := range []float64{15, 150} {
+		points := exporter.gauges[i].DataPoints
+		if len(points) != 1 || points[0].Value != expected || points[0].Attributes[1].Value != "web.app" {
+			t.Errorf("Expected a data point of %v for the task, got %v", expected, points)
+		}
+	}
+}
+
+type fakeEngine struct {
+	metadata    *ecstcs.MetricsMetadata
+	taskMetrics []*ecstcs.TaskMetric
+}
+
+func (engine *fakeEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata, []*ecstcs.TaskMetric, error) {
+	return engine.metadata, engine.taskMetrics, nil
+}
+
+type fakePublisher struct {
+	published chan []*ecstcs.TaskMetric
+}
+
+func (publisher *fakePublisher) Name() string { return "fake" }
+
+func (publisher *fakePublisher) Publish(metadata *ecstcs.MetricsMetadata, taskMetrics []*ecstcs.TaskMetric) error {
+	publisher.published <- taskMetrics
+	return nil
+}
+
+func TestPublishingEngine(t *testing.T) {
+	metadata, taskMetrics := testMetrics()
+	first := &fakePublisher{make(chan []*ecstcs.TaskMetric, 1)}
+	second := &fakePublisher{make(chan []*ecstcs.TaskMetric, 1)}
+	engine := NewPublishingEngine(&fakeEngine{metadata, taskMetrics}, first, second)
+
+	_, returned, err := engine.GetInstanceMetrics()
+	if err != nil || len(returned) != 1 {
+		t.Fatal("Expected the underlying engine's metrics", returned, err)
+	}
+	for _, publisher := range []*fakePublisher{first, second} {
+		if published := <-publisher.published; len(published) != 1 {
+			t.Error("Expected the metrics to be published", published)
+		}
+	}
+}
+
+func TestEMFPublisherLocal(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	records := make(chan map[string]interface{}, 2)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		decoder := json.NewDecoder(bufio.NewReader(conn))
+		for i := 0; i < 2; i++ {
+			var record map[string]interface{}
+			decoder.Decode(&record)
+			records <- record
+		}
+	}()
+
+	metadata, _ := testMetrics()
+	local := &LocalMetrics{
+		Instance: &InstanceMetric{CPUStatsSet: testStatsSet(50, 2)},
+		Containers: []*LocalContainerMetric{{
+			TaskArn:       "arn:aws:ecs:us-east-1:123:task/abc-123",
+			ContainerName: "web",
+			PidsStatsSet:  testStatsSet(90, 2),
+		}},
+	}
+	if err := NewEMFPublisher(listener.Addr().String(), "ecs-metrics").PublishLocal(metadata, local); err != nil {
+		t.Fatal(err)
+	}
+	record := <-records
+	if record["ContainerInstance"] != stringValue(metadata.ContainerInstance) || record["InstanceCpuUtilized"] != 25.0 {
+		t.Error("Wrong instance record", record)
+	}
+	if _, ok := record["InstanceMemoryUtilized"]; ok {
+		t.Error("Expected no memory utilization without samples", record)
+	}
+	record = <-records
+	if record["ContainerName"] != "web" || record["PidsUtilized"] != 45.0 {
+		t.Error("Wrong record", record)
+	}
+	aws, _ := record["_aws"].(map[string]interface{})
+	directives, _ := aws["CloudWatchMetrics"].([]interface{})
+	if len(directives) != 1 || !strings.Contains(fmt.Sprint(directives[0]), "PidsUtilized") {
+		t.Error("Wrong metadata", record["_aws"])
+	}
+}
+
+type fakeLocalEngine struct {
+	fakeEngine
+	local *LocalMetrics
+}
+
+func (engine *fakeLocalEngine) LocalMetrics() *LocalMetrics { return engine.local }
+
+type fakeLocalPublisher struct {
+	fakePublisher
+	publishedLocal chan *LocalMetrics
+}
+
+func (publisher *fakeLocalPublisher) PublishLocal(metadata *ecstcs.MetricsMetadata, local *LocalMetrics) error {
+	publisher.publishedLocal <- local
+	return nil
+}
+
+func TestPublishingEngineLocal(t *testing.T) {
+	metadata, taskMetrics := testMetrics()
+	local := &LocalMetrics{Containers: []*LocalContainerMetric{{ContainerName: "web", PidsStatsSet: testStatsSet(90, 2)}}}
+	publisher := &fakeLocalPublisher{fakePublisher{make(chan []*ecstcs.TaskMetric, 1)}, make(chan *LocalMetrics, 1)}
+	engine := NewPublishingEngine(&fakeLocalEngine{fakeEngine{metadata, taskMetrics}, local}, publisher)
+
+	if _, _, err := engine.GetInstanceMetrics(); err != nil {
+		t.Fatal(err)
+	}
+	<-publisher.published
+	if published := <-publisher.publishedLocal; published != local {
+		t.Error("Expected the local metrics to be published", published)
+	}
+}
+
+func TestPublishingEngineLocalIdle(t *testing.T) {
+	metadata, _ := testMetrics()
+	local := &LocalMetrics{Instance: &InstanceMetric{CPUStatsSet: testStatsSet(50, 2)}}
+	publisher := &fakeLocalPublisher{fakePublisher{make(chan []*ecstcs.TaskMetric, 1)}, make(chan *LocalMetrics, 1)}
+	engine := NewPublishingEngine(&fakeLocalEngine{fakeEngine{metadata, nil}, local}, publisher)
+
+	if _, _, err := engine.GetInstanceMetrics(); err != nil {
+		t.Fatal(err)
+	}
+	if published := <-publisher.publishedLocal; published != local {
+		t.Error("Expected the local metrics of an idle instance to be published", published)
+	}
+	select {
+	case published := <-publisher.published:
+		t.Error("Expected no task metrics to be published", published)
+	default:
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"math"
 	"sync"
+	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
 )
@@ -24,26 +25,55 @@ import (
 const (
 	// BytesInMiB is the number of bytes in a MebiByte.
 	BytesInMiB = 1024 * 1024
+
+	// CPUSharesPerCore is the number of cpu units that reserve one core.
+	CPUSharesPerCore = 1024
+
+	// CPUBurstWindow is the length of the windows in which cpu bursts are
+	// measured.
+	CPUBurstWindow = time.Second
 )
 
-// Queue abstracts a queue using UsageStats slice.
+// Queue abstracts a queue of UsageStats. It is a ring buffer allocated once
+// at its maximum size, so that adding stats does not allocate; once full, each
+// new stat overwrites the oldest.
 type Queue struct {
-	buffer     []UsageStats
-	maxSize    int
+	buffer []UsageStats
+	// head is the index in buffer of the oldest stat, and length the number
+	// of stats queued
+	head       int
+	length     int
 	bufferLock sync.RWMutex
 }
 
 // NewQueue creates a queue.
 func NewQueue(maxSize int) *Queue {
 	return &Queue{
-		buffer:  make([]UsageStats, 0, maxSize),
-		maxSize: maxSize,
+		buffer: make([]UsageStats, maxSize),
 	}
 }
 
 // Reset resets the stats queue.
 func (queue *Queue) Reset() {
-	queue.buffer = queue.buffer[:0]
+	queue.bufferLock.Lock()
+	defer queue.bufferLock.Unlock()
+
+	queue.head = 0
+	queue.length = 0
+}
+
+// Len returns the number of stats in the queue.
+func (queue *Queue) Len() int {
+	queue.bufferLock.RLock()
+	defer queue.bufferLock.RUnlock()
+
+	return queue.length
+}
+
+// at returns the i'th oldest stat in the queue. The caller must hold the
+// buffer lock.
+func (queue *Queue) at(i int) *UsageStats {
+	return &queue.buffer[(queue.head+i)%len(queue.buffer)]
 }
 
 // Add adds a new set of container stats to the queue.
@@ -51,24 +81,43 @@ func (queue *Queue) Add(rawStat *ContainerStats) {
 	queue.bufferLock.Lock()
 	defer queue.bufferLock.Unlock()
 
-	queueLength := len(queue.buffer)
 	stat := UsageStats{
 		CPUUsagePerc:      (float32)(nan32()),
 		MemoryUsageInMegs: (uint32)(rawStat.memoryUsage) / BytesInMiB,
+		MemoryPressure:    rawStat.pressure.memory,
+		CPUPressure:       rawStat.pressure.cpu,
+		IOPressure:        rawStat.pressure.io,
+		PidsPerc:          nan32(),
 		Timestamp:         rawStat.timestamp,
 		cpuUsage:          rawStat.cpuUsage,
 	}
-	if queueLength != 0 {
+	if rawStat.pidsKnown {
+		stat.PidsPerc = pidsPercent(rawStat.pids, rawStat.pidsLimit)
+	}
+	if queue.length != 0 {
 		// % utilization can be calculated only when queue is non-empty.
-		lastStat := queue.buffer[queueLength-1]
+		lastStat := queue.at(queue.length - 1)
 		stat.CPUUsagePerc = 100 * (float32)(rawStat.cpuUsage-lastStat.cpuUsage) / (float32)(rawStat.timestamp.Sub(lastStat.Timestamp).Nanoseconds())
-		if queue.maxSize == queueLength {
-			// Remove first element if queue is full.
-			queue.buffer = queue.buffer[1:queueLength]
-		}
 	}
 
-	queue.buffer = append(queue.buffer, stat)
+	if queue.length == len(queue.buffer) {
+		// Overwrite the oldest element if queue is full.
+		queue.set(0, stat, rawStat.extra)
+		queue.head = (queue.head + 1) % len(queue.buffer)
+		return
+	}
+	queue.set(queue.length, stat, rawStat.extra)
+	queue.length++
+}
+
+// set stores stat as the i'th oldest stat in the queue with a copy of extra.
+// The slot's previous slice isn't reused, since readers may still hold it.
+// The caller must hold the buffer lock.
+func (queue *Queue) set(i int, stat UsageStats, extra []float32) {
+	if len(extra) > 0 {
+		stat.Extra = append([]float32(nil), extra...)
+	}
+	*queue.at(i) = stat
 }
 
 // GetCPUStatsSet gets the stats set for CPU utilization.
@@ -81,13 +130,134 @@ func (queue *Queue) GetMemoryStatsSet() (*ecstcs.CWStatsSet, error) {
 	return queue.getCWStatsSet(getMemoryUsagePerc)
 }
 
+// GetCPUReservationStatsSet gets the stats set for CPU utilization relative to
+// a reservation of cpuShares. 100 means the container used exactly the cpu it
+// reserved; more means it used spare cpu beyond its reservation.
+func (queue *Queue) GetCPUReservationStatsSet(cpuShares uint) (*ecstcs.CWStatsSet, error) {
+	if cpuShares == 0 {
+		return nil, fmt.Errorf("No cpu reservation")
+	}
+	reservedCores := float64(cpuShares) / CPUSharesPerCore
+	return queue.getCWStatsSet(func(s *UsageStats) float64 {
+		return getCPUUsagePerc(s) / reservedCores
+	})
+}
+
+// GetMemoryPressureStatsSet gets the stats set for the share of time some of
+// the container's tasks stalled waiting for memory.
+func (queue *Queue) GetMemoryPressureStatsSet() (*ecstcs.CWStatsSet, error) {
+	return queue.getPressureStatsSet(func(s *UsageStats) float64 {
+		return float64(s.MemoryPressure)
+	})
+}
+
+// GetCPUPressureStatsSet gets the stats set for the share of time some of the
+// container's tasks stalled waiting for cpu.
+func (queue *Queue) GetCPUPressureStatsSet() (*ecstcs.CWStatsSet, error) {
+	return queue.getPressureStatsSet(func(s *UsageStats) float64 {
+		return float64(s.CPUPressure)
+	})
+}
+
+// GetIOPressureStatsSet gets the stats set for the share of time some of the
+// container's tasks stalled waiting for io.
+func (queue *Queue) GetIOPressureStatsSet() (*ecstcs.CWStatsSet, error) {
+	return queue.getPressureStatsSet(func(s *UsageStats) float64 {
+		return float64(s.IOPressure)
+	})
+}
+
+// getPressureStatsSet gets a stats set of pressure stall information, which
+// is missing if the kernel doesn't report it
+func (queue *Queue) getPressureStatsSet(f getUsageFunc) (*ecstcs.CWStatsSet, error) {
+	statsSet, err := queue.getCWStatsSet(f)
+	if err != nil {
+		return nil, err
+	}
+	if *statsSet.SampleCount == 0 {
+		return nil, fmt.Errorf("No pressure stall information")
+	}
+	return statsSet, nil
+}
+
+// GetPidsStatsSet gets the stats set for the share of its pids limit the
+// container uses. It is missing if the container has no limit.
+func (queue *Queue) GetPidsStatsSet() (*ecstcs.CWStatsSet, error) {
+	statsSet, err := queue.getCWStatsSet(func(s *UsageStats) float64 {
+		return float64(s.PidsPerc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if *statsSet.SampleCount == 0 {
+		return nil, fmt.Errorf("No pids limit")
+	}
+	return statsSet, nil
+}
+
+// GetCPUBurst gets the highest cpu utilization in any window of at least
+// CPUBurstWindow between queued stats. Short bursts are averaged away in the
+// cpu stats set, whose samples are as frequent as the collection interval.
+// When stats are collected less often than CPUBurstWindow, the windows are
+// the intervals between consecutive stats.
+func (queue *Queue) GetCPUBurst() (float64, error) {
+	queue.bufferLock.Lock()
+	defer queue.bufferLock.Unlock()
+
+	burst := -1.0
+	end := 0
+	for start := 0; start < queue.length; start++ {
+		first := queue.at(start)
+		// The shortest window from first is to the first stat at least
+		// CPUBurstWindow later. Windows starting later end no earlier.
+		if end <= start {
+			end = start + 1
+		}
+		for end < queue.length && queue.at(end).Timestamp.Sub(first.Timestamp) < CPUBurstWindow {
+			end++
+		}
+		if end == queue.length {
+			break
+		}
+		last := queue.at(end)
+		if last.cpuUsage < first.cpuUsage {
+			continue
+		}
+		elapsed := last.Timestamp.Sub(first.Timestamp).Nanoseconds()
+		burst = math.Max(burst, 100*float64(last.cpuUsage-first.cpuUsage)/float64(elapsed))
+	}
+	if burst < 0 {
+		return 0, fmt.Errorf("Not enough data in the queue")
+	}
+	return burst, nil
+}
+
+// GetExtraStatsSet gets the stats set for the metric of the container's i'th
+// stats converter. It is missing if the converter had no value for the
+// container.
+func (queue *Queue) GetExtraStatsSet(i int) (*ecstcs.CWStatsSet, error) {
+	statsSet, err := queue.getCWStatsSet(func(s *UsageStats) float64 {
+		if i >= len(s.Extra) {
+			return math.NaN()
+		}
+		return float64(s.Extra[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+	if *statsSet.SampleCount == 0 {
+		return nil, fmt.Errorf("No values from stats converter")
+	}
+	return statsSet, nil
+}
+
 // GetRawUsageStats gets the array of most recent raw UsageStats, in descending
 // order of timestamps.
 func (queue *Queue) GetRawUsageStats(numStats int) ([]UsageStats, error) {
 	queue.bufferLock.Lock()
 	defer queue.bufferLock.Unlock()
 
-	queueLength := len(queue.buffer)
+	queueLength := queue.length
 	if queueLength == 0 {
 		return nil, fmt.Errorf("No data in the queue")
 	}
@@ -99,12 +269,19 @@ func (queue *Queue) GetRawUsageStats(numStats int) ([]UsageStats, error) {
 	usageStats := make([]UsageStats, numStats)
 	for i := 0; i < numStats; i++ {
 		// Order such that usageStats[i].timestamp > usageStats[i+1].timestamp
-		rawUsageStat := queue.buffer[queueLength-i-1]
+		rawUsageStat := queue.at(queueLength - i - 1)
 		usageStats[i] = UsageStats{
 			CPUUsagePerc:      rawUsageStat.CPUUsagePerc,
 			MemoryUsageInMegs: rawUsageStat.MemoryUsageInMegs,
+			MemoryPressure:    rawUsageStat.MemoryPressure,
+			CPUPressure:       rawUsageStat.CPUPressure,
+			IOPressure:        rawUsageStat.IOPressure,
+			PidsPerc:          rawUsageStat.PidsPerc,
 			Timestamp:         rawUsageStat.Timestamp,
 		}
+		if len(rawUsageStat.Extra) > 0 {
+			usageStats[i].Extra = append([]float32(nil), rawUsageStat.Extra...)
+		}
 	}
 
 	return usageStats, nil
@@ -126,7 +303,7 @@ func (queue *Queue) getCWStatsSet(f getUsageFunc) (*ecstcs.CWStatsSet, error) {
 	queue.bufferLock.Lock()
 	defer queue.bufferLock.Unlock()
 
-	queueLength := len(queue.buffer)
+	queueLength := queue.length
 	if queueLength < 2 {
 		// Need at least 2 data points to calculate this.
 		return nil, fmt.Errorf("No data in the queue")
@@ -139,8 +316,8 @@ func (queue *Queue) getCWStatsSet(f getUsageFunc) (*ecstcs.CWStatsSet, error) {
 	sum = 0
 	sampleCount = 0
 
-	for _, stat := range queue.buffer {
-		perc := f(&stat)
+	for i := 0; i < queueLength; i++ {
+		perc := f(queue.at(i))
 		if math.IsNaN(perc) {
 			continue
 		}
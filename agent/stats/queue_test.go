@@ -116,14 +116,13 @@ func TestQueueAddRemove(t *testing.T) {
 	timestamps := getTimestamps()
 	queueLength := 5
 	queue := createQueue(queueLength)
-	buf := queue.buffer
-	if len(buf) != queueLength {
-		t.Error("Buffer size is incorrect. Expected: 4, Got: ", len(buf))
+	if queue.Len() != queueLength {
+		t.Error("Buffer size is incorrect. Expected: 4, Got: ", queue.Len())
 	}
 
-	timestampsIndex := len(timestamps) - len(buf)
-	for i, stat := range buf {
-		if stat.Timestamp != timestamps[timestampsIndex+i] {
+	timestampsIndex := len(timestamps) - queue.Len()
+	for i := 0; i < queue.Len(); i++ {
+		if queue.at(i).Timestamp != timestamps[timestampsIndex+i] {
 			t.Error("Unexpected value for Stats element in buffer")
 		}
 	}
@@ -187,3 +186,88 @@ func TestQueueAddRemove(t *testing.T) {
 	}
 
 }
+
+func TestQueueOverwritesOldestWhenFull(t *testing.T) {
+	queue := NewQueue(3)
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		queue.Add(&ContainerStats{cpuUsage: uint64(i) * 1000, timestamp: start.Add(time.Duration(i) * time.Microsecond)})
+	}
+	if queue.Len() != 3 {
+		t.Fatal("Expected 3 stats in the queue, got ", queue.Len())
+	}
+	for i := 0; i < 3; i++ {
+		expected := start.Add(time.Duration(i+4) * time.Microsecond)
+		if !queue.at(i).Timestamp.Equal(expected) {
+			t.Error("Unexpected stat at ", i, ": ", queue.at(i).Timestamp, " expected: ", expected)
+		}
+		if queue.at(i).CPUUsagePerc != 100 {
+			t.Error("Expected cpu usage of 100% at ", i, ", got: ", queue.at(i).CPUUsagePerc)
+		}
+	}
+
+	queue.Reset()
+	if queue.Len() != 0 {
+		t.Error("Expected an empty queue after reset, got ", queue.Len())
+	}
+	if _, err := queue.GetRawUsageStats(1); err == nil {
+		t.Error("Expected an error getting stats from a reset queue")
+	}
+}
+
+func TestQueueCPUReservationStatsSet(t *testing.T) {
+	queue := createQueue(5)
+	cpuStatsSet, err := queue.GetCPUStatsSet()
+	if err != nil {
+		t.Fatal("Error gettting cpu stats set:", err)
+	}
+
+	// A quarter of a core is reserved, so usage relative to it is 4 times
+	// usage of a core
+	reservationStatsSet, err := queue.GetCPUReservationStatsSet(256)
+	if err != nil {
+		t.Fatal("Error gettting cpu reservation stats set:", err)
+	}
+	if *reservationStatsSet.Sum != 4**cpuStatsSet.Sum {
+		t.Error("Expected sum: ", 4**cpuStatsSet.Sum, " got: ", *reservationStatsSet.Sum)
+	}
+	if *reservationStatsSet.SampleCount != *cpuStatsSet.SampleCount {
+		t.Error("Expected samplecount: ", *cpuStatsSet.SampleCount, " got: ", *reservationStatsSet.SampleCount)
+	}
+
+	if _, err := queue.GetCPUReservationStatsSet(0); err == nil {
+		t.Error("Expected an error without a cpu reservation")
+	}
+}
+
+func TestQueueCPUBurst(t *testing.T) {
+	queue := NewQueue(10)
+	start := time.Now()
+	// Half a core for 2 seconds, with a second of two full cores in between
+	cpuTimes := []uint64{0, 250, 500, 1500, 2500, 2750, 3000}
+	for i, cpuTime := range cpuTimes {
+		queue.Add(createContainerStats(cpuTime*uint64(time.Millisecond), 1024*1024, start.Add(time.Duration(i)*500*time.Millisecond)))
+	}
+
+	burst, err := queue.GetCPUBurst()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if burst != 200 {
+		t.Error("Expected the busiest second to use two cores, got", burst)
+	}
+	cpuStatsSet, err := queue.GetCPUStatsSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cpuStatsSet.Sum/float64(*cpuStatsSet.SampleCount) >= burst {
+		t.Error("Expected the burst to exceed the average utilization")
+	}
+
+	queue = NewQueue(10)
+	queue.Add(createContainerStats(0, 1024*1024, start))
+	queue.Add(createContainerStats(1, 1024*1024, start.Add(500*time.Millisecond)))
+	if _, err := queue.GetCPUBurst(); err == nil {
+		t.Error("Expected an error when the queue spans less than a burst window")
+	}
+}
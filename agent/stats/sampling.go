@@ -0,0 +1,111 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+)
+
+// maxAdaptiveSamplingInterval bounds the sampling interval so that each 20
+// second telemetry publish still has at least three samples per container;
+// cpu utilization is calculated between consecutive samples, and the queues
+// are reset after every publish.
+const maxAdaptiveSamplingInterval = 5 * time.Second
+
+// statsSampler decides how long containers sleep between collections of
+// their stats. Unless adaptive sampling is enabled it is always
+// SleepBetweenUsageDataCollection. A nil sampler is not adaptive.
+type statsSampler struct {
+	enabled            bool
+	containerThreshold int
+	// cpuBudget is the percentage of a core that collecting the stats of all
+	// containers may take
+	cpuBudget int
+
+	lock       sync.Mutex
+	containers int
+	// latency is a moving average of how long one collection takes
+	latency time.Duration
+	current time.Duration
+}
+
+func newStatsSampler(cfg *config.Config) *statsSampler {
+	return &statsSampler{
+		enabled:            cfg.StatsAdaptiveSampling,
+		containerThreshold: cfg.StatsSamplingContainerThreshold,
+		cpuBudget:          cfg.StatsCPUBudget,
+		current:            SleepBetweenUsageDataCollection,
+	}
+}
+
+// setContainers records the number of containers whose stats are collected
+func (sampler *statsSampler) setContainers(containers int) {
+	if sampler == nil {
+		return
+	}
+	sampler.lock.Lock()
+	defer sampler.lock.Unlock()
+	sampler.containers = containers
+}
+
+// recordLatency records how long a collection took
+func (sampler *statsSampler) recordLatency(latency time.Duration) {
+	if sampler == nil || !sampler.enabled {
+		return
+	}
+	sampler.lock.Lock()
+	defer sampler.lock.Unlock()
+	if sampler.latency == 0 {
+		sampler.latency = latency
+		return
+	}
+	sampler.latency += (latency - sampler.latency) / 5
+}
+
+// interval returns how long to sleep before the next collection
+func (sampler *statsSampler) interval() time.Duration {
+	if sampler == nil || !sampler.enabled {
+		return SleepBetweenUsageDataCollection
+	}
+	sampler.lock.Lock()
+	defer sampler.lock.Unlock()
+
+	interval := SleepBetweenUsageDataCollection
+	if sampler.containerThreshold > 0 && sampler.containers > sampler.containerThreshold {
+		interval = interval * time.Duration(sampler.containers) / time.Duration(sampler.containerThreshold)
+	}
+	if sampler.cpuBudget > 0 {
+		// Every container is collected once per interval, so collecting takes
+		// containers*latency of every interval
+		budgeted := time.Duration(float64(sampler.latency) * float64(sampler.containers) * 100 / float64(sampler.cpuBudget))
+		if budgeted > interval {
+			interval = budgeted
+		}
+	}
+	// Round up to a multiple of the default interval, so that the interval
+	// does not change with every small change in latency
+	interval = (interval + SleepBetweenUsageDataCollection - 1) / SleepBetweenUsageDataCollection * SleepBetweenUsageDataCollection
+	if interval > maxAdaptiveSamplingInterval {
+		interval = maxAdaptiveSamplingInterval
+	}
+
+	if interval != sampler.current {
+		log.Info("Changing stats sampling interval", "interval", interval.String(), "containers", sampler.containers, "latency", sampler.latency.String())
+		sampler.current = interval
+	}
+	return interval
+}
@@ -0,0 +1,70 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+)
+
+func TestStatsSamplerDisabled(t *testing.T) {
+	sampler := newStatsSampler(&config.Config{StatsSamplingContainerThreshold: 10, StatsCPUBudget: 5})
+	sampler.setContainers(100)
+	sampler.recordLatency(time.Second)
+	if sampler.interval() != SleepBetweenUsageDataCollection {
+		t.Error("Expected the default interval when adaptive sampling is disabled, got ", sampler.interval())
+	}
+
+	var nilSampler *statsSampler
+	nilSampler.setContainers(100)
+	nilSampler.recordLatency(time.Second)
+	if nilSampler.interval() != SleepBetweenUsageDataCollection {
+		t.Error("Expected the default interval from a nil sampler, got ", nilSampler.interval())
+	}
+}
+
+func TestStatsSamplerContainerThreshold(t *testing.T) {
+	sampler := newStatsSampler(&config.Config{StatsAdaptiveSampling: true, StatsSamplingContainerThreshold: 10})
+	sampler.setContainers(10)
+	if sampler.interval() != SleepBetweenUsageDataCollection {
+		t.Error("Expected the default interval at the threshold, got ", sampler.interval())
+	}
+	sampler.setContainers(30)
+	if sampler.interval() != 3*SleepBetweenUsageDataCollection {
+		t.Error("Expected the interval to grow with the number of containers, got ", sampler.interval())
+	}
+	sampler.setContainers(1000)
+	if sampler.interval() != maxAdaptiveSamplingInterval {
+		t.Error("Expected the interval to be capped, got ", sampler.interval())
+	}
+}
+
+func TestStatsSamplerCPUBudget(t *testing.T) {
+	sampler := newStatsSampler(&config.Config{StatsAdaptiveSampling: true, StatsCPUBudget: 5})
+	sampler.setContainers(4)
+	sampler.recordLatency(time.Millisecond)
+	if sampler.interval() != SleepBetweenUsageDataCollection {
+		t.Error("Expected the default interval within budget, got ", sampler.interval())
+	}
+
+	// 4 containers taking 10ms each need at least 800ms to stay within 5%
+	for i := 0; i < 50; i++ {
+		sampler.recordLatency(10 * time.Millisecond)
+	}
+	if sampler.interval() != 2*SleepBetweenUsageDataCollection {
+		t.Error("Expected the interval to grow with collection latency, got ", sampler.interval())
+	}
+}
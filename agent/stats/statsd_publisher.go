@@ -0,0 +1,61 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+)
+
+// statsDNameReplacer removes the characters statsd uses as separators from
+// metric name segments
+var statsDNameReplacer = strings.NewReplacer(".", "_", ":", "_", "|", "_", "@", "_", " ", "_")
+
+// StatsDPublisher sends each task's usage as gauges to a statsd daemon, named
+// ecs.<family>.<task id>.cpu_utilization and ecs.<family>.<task id>.memory_mib
+type StatsDPublisher struct {
+	address string
+}
+
+// NewStatsDPublisher returns a publisher which sends to the statsd daemon
+// listening for udp at address
+func NewStatsDPublisher(address string) *StatsDPublisher {
+	return &StatsDPublisher{address: address}
+}
+
+func (publisher *StatsDPublisher) Name() string { return "statsd" }
+
+// Publish sends one packet of gauges per task
+func (publisher *StatsDPublisher) Publish(metadata *ecstcs.MetricsMetadata, taskMetrics []*ecstcs.TaskMetric) error {
+	conn, err := net.Dial("udp", publisher.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, usage := range taskUsages(taskMetrics) {
+		prefix := "ecs." + statsDNameReplacer.Replace(usage.family) + "." + statsDNameReplacer.Replace(taskID(usage.taskArn)) + "."
+		var packet bytes.Buffer
+		packet.WriteString(prefix + "cpu_utilization:" + strconv.FormatFloat(usage.cpuPercent, 'f', -1, 64) + "|g\n")
+		packet.WriteString(prefix + "memory_mib:" + strconv.FormatFloat(usage.memoryMiB, 'f', -1, 64) + "|g\n")
+		if _, err := conn.Write(packet.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/docker/libcontainer"
+)
+
+// cgroupFixtures holds golden cgroup fs contents for a container, one
+// directory per subsystem. The container has used 2s of cpu on each of 4
+// cores and 4MiB of memory.
+const cgroupFixtures = "testdata/cgroup"
+
+// syntheticContainer is a container whose cgroups are a copy of the
+// fixtures, so that the stats pipeline can be driven without docker. Tests
+// change its usage to simulate load.
+type syntheticContainer struct {
+	t          *testing.T
+	cgroupPath map[string]string
+	cron       *CronContainer
+}
+
+// harnessClock is a ttime.Time which stands still until it is advanced, so
+// that utilization computed from synthetic stats is exact
+type harnessClock struct {
+	now time.Time
+}
+
+func (clock *harnessClock) Now() time.Time        { return clock.now }
+func (clock *harnessClock) Sleep(d time.Duration) { clock.now = clock.now.Add(d) }
+func (clock *harnessClock) After(d time.Duration) <-chan time.Time {
+	clock.Sleep(d)
+	after := make(chan time.Time, 1)
+	after <- clock.now
+	return after
+}
+
+// statsHarness drives a stats engine watching synthetic containers. Rather
+// than collecting on a cron, stats are collected when the test calls
+// collect, and time only passes when the test calls advance.
+type statsHarness struct {
+	t          *testing.T
+	dir        string
+	clock      *harnessClock
+	engine     *DockerStatsEngine
+	containers []*syntheticContainer
+}
+
+func newStatsHarness(t *testing.T) *statsHarness {
+	dir, err := ioutil.TempDir("", "stats-harness")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{}
+	engine := &DockerStatsEngine{
+		dockerGraphPath:    filepath.Join(dir, "graph"),
+		cgroupDriver:       CgroupDriverCgroupfs,
+		sampler:            newStatsSampler(cfg),
+		exclusions:         newStatsExclusions(cfg),
+		tasksToContainers:  make(map[string]map[string]*CronContainer),
+		tasksToDefinitions: make(map[string]*taskDefinition),
+		metricsMetadata:    newMetricsMetadata(&defaultCluster, &defaultContainerInstance),
+	}
+	clock := &harnessClock{now: time.Date(2015, 2, 12, 21, 22, 5, 0, time.UTC)}
+	ttime.SetTime(clock)
+	return &statsHarness{t: t, dir: dir, clock: clock, engine: engine}
+}
+
+func (harness *statsHarness) close() {
+	ttime.SetTime(&ttime.DefaultTime{})
+	os.RemoveAll(harness.dir)
+}
+
+func (harness *statsHarness) advance(d time.Duration) {
+	harness.clock.Sleep(d)
+}
+
+// addContainer watches a new synthetic container in the given task
+func (harness *statsHarness) addContainer(taskArn, family, dockerID string, cpuShares uint) *syntheticContainer {
+	container := &syntheticContainer{t: harness.t, cgroupPath: make(map[string]string)}
+	subsystems, err := ioutil.ReadDir(cgroupFixtures)
+	if err != nil {
+		harness.t.Fatal(err)
+	}
+	for _, subsystem := range subsystems {
+		path := filepath.Join(harness.dir, "cgroup", subsystem.Name(), dockerID)
+		copyDir(harness.t, filepath.Join(cgroupFixtures, subsystem.Name()), path)
+		container.cgroupPath[subsystem.Name()] = path
+	}
+
+	container.cron = newCronContainer(&dockerID, containerStateDirs(harness.engine.dockerGraphPath, defaultExecDriver), harness.engine.cgroupDriver)
+	statePath := filepath.Join(harness.engine.dockerGraphPath, "execdriver", defaultExecDriver, dockerID)
+	if err := os.MkdirAll(statePath, 0755); err != nil {
+		harness.t.Fatal(err)
+	}
+	if err := libcontainer.SaveState(statePath, &libcontainer.State{CgroupPaths: container.cgroupPath}); err != nil {
+		harness.t.Fatal(err)
+	}
+	container.cron.cpuShares = cpuShares
+	container.cron.statsQueue = NewQueue(ContainerStatsBufferLength)
+
+	if harness.engine.tasksToContainers[taskArn] == nil {
+		harness.engine.tasksToContainers[taskArn] = make(map[string]*CronContainer)
+	}
+	harness.engine.tasksToContainers[taskArn][dockerID] = container.cron
+	harness.engine.tasksToDefinitions[taskArn] = &taskDefinition{family: family, version: "1"}
+	harness.containers = append(harness.containers, container)
+	return container
+}
+
+// collect collects stats from each container's cgroups, as its cron would
+func (harness *statsHarness) collect() {
+	for _, container := range harness.containers {
+		stats, err := container.cron.statsCollector.getContainerStats(container.cron)
+		if err != nil {
+			harness.t.Fatal("Error collecting synthetic stats:", err)
+		}
+		container.cron.statsQueue.Add(stats)
+	}
+}
+
+// setCPUUsage sets the container's total cpu time used on each core
+func (container *syntheticContainer) setCPUUsage(perCPU ...uint64) {
+	var total uint64
+	values := make([]string, len(perCPU))
+	for i, usage := range perCPU {
+		total += usage
+		values[i] = strconv.FormatUint(usage, 10)
+	}
+	container.write("cpuacct", "cpuacct.usage_percpu", strings.Join(values, " "))
+	container.write("cpuacct", "cpuacct.usage", strconv.FormatUint(total, 10))
+}
+
+// setMemoryUsage sets the container's memory usage in bytes
+func (container *syntheticContainer) setMemoryUsage(usage uint64) {
+	container.write("memory", "memory.usage_in_bytes", strconv.FormatUint(usage, 10))
+}
+
+func (container *syntheticContainer) write(subsystem, file, value string) {
+	err := ioutil.WriteFile(filepath.Join(container.cgroupPath[subsystem], file), []byte(value+"\n"), 0644)
+	if err != nil {
+		container.t.Fatal(err)
+	}
+}
+
+func copyDir(t *testing.T, src, dst string) {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(filepath.Join(src, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dst, file.Name()), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSyntheticContainerStats(t *testing.T) {
+	harness := newStatsHarness(t)
+	defer harness.close()
+	container := harness.addContainer("t1", "f1", "c1", 0)
+
+	stats, err := container.cron.statsCollector.getContainerStats(container.cron)
+	if err != nil {
+		t.Fatal("Error collecting synthetic stats:", err)
+	}
+	// 8s of cpu over 4 cores
+	if stats.cpuUsage != 2000000000 {
+		t.Error("Wrong cpu usage", stats.cpuUsage)
+	}
+	if stats.memoryUsage != 4194304 {
+		t.Error("Wrong memory usage", stats.memoryUsage)
+	}
+	if !stats.timestamp.Equal(harness.clock.now) {
+		t.Error("Wrong timestamp", stats.timestamp)
+	}
+}
+
+func TestSyntheticStatsPipeline(t *testing.T) {
+	harness := newStatsHarness(t)
+	defer harness.close()
+	reserved := harness.addContainer("t1", "f1", "reserved", 512)
+	unreserved := harness.addContainer("t2", "f2", "unreserved", 0)
+
+	harness.collect()
+	harness.advance(time.Second)
+	// Half of the instance's 4 cores for the second
+	reserved.setCPUUsage(2500000000, 2500000000, 2500000000, 2500000000)
+	reserved.setMemoryUsage(8 * 1024 * 1024)
+	// A quarter of one core for the second
+	unreserved.setCPUUsage(2250000000, 2000000000, 2000000000, 2000000000)
+	harness.collect()
+
+	_, taskMetrics, err := harness.engine.GetInstanceMetrics()
+	if err != nil {
+		t.Fatal("Error getting instance metrics:", err)
+	}
+	if len(taskMetrics) != 2 {
+		t.Fatal("Expected metrics for 2 tasks, got", len(taskMetrics))
+	}
+	for _, taskMetric := range taskMetrics {
+		if len(taskMetric.ContainerMetrics) != 1 {
+			t.Fatal("Expected metrics for 1 container, got", len(taskMetric.ContainerMetrics))
+		}
+		metric := taskMetric.ContainerMetrics[0]
+		switch *taskMetric.TaskArn {
+		case "t1":
+			expectStatsSet(t, "cpu", metric.CpuStatsSet.Max, 50)
+			expectStatsSet(t, "memory max", metric.MemoryStatsSet.Max, 8)
+			expectStatsSet(t, "memory min", metric.MemoryStatsSet.Min, 4)
+		case "t2":
+			expectStatsSet(t, "cpu", metric.CpuStatsSet.Max, 6.25)
+			expectStatsSet(t, "memory max", metric.MemoryStatsSet.Max, 4)
+		default:
+			t.Error("Unexpected task", *taskMetric.TaskArn)
+		}
+	}
+	local := harness.engine.LocalMetrics()
+	if local == nil || len(local.Containers) != 2 {
+		t.Fatal("Expected local metrics for 2 containers", local)
+	}
+	for _, metric := range local.Containers {
+		switch metric.TaskArn {
+		case "t1":
+			expectStatsSet(t, "cpu reservation", metric.CPUReservationStatsSet.Max, 100)
+		case "t2":
+			if metric.CPUReservationStatsSet != nil {
+				t.Error("Expected no cpu reservation stats without a reservation")
+			}
+		}
+	}
+}
+
+func expectStatsSet(t *testing.T, name string, actual *float64, expected float64) {
+	if actual == nil {
+		t.Errorf("Wrong %s: expected %f, got nothing", name, expected)
+	} else if math.Abs(*actual-expected) > 0.01 {
+		t.Errorf("Wrong %s: expected %f, got %f", name, expected, *actual)
+	}
+}
@@ -23,13 +23,34 @@ import (
 type ContainerStats struct {
 	cpuUsage    uint64
 	memoryUsage uint64
-	timestamp   time.Time
+	pressure    pressureStats
+	// oomKills is the number of processes killed for running out of memory,
+	// if the kernel counts them
+	oomKills      uint64
+	oomKillsKnown bool
+	// pids is the number of processes and threads in the container and
+	// pidsLimit its limit, or 0 if it has none
+	pids      uint64
+	pidsLimit uint64
+	pidsKnown bool
+	pidsDir   string
+	// extra are the values of the container's stats converters
+	extra     []float32
+	timestamp time.Time
 }
 
-// UsageStats abstracts the format in which the queue stores data.
+// UsageStats abstracts the format in which the queue stores data. The
+// pressures are NaN if the kernel doesn't report pressure stall information,
+// and PidsPerc is NaN if the container has no pids limit. Extra holds the
+// value of each of the container's stats converters, or NaN where it had none.
 type UsageStats struct {
 	CPUUsagePerc      float32   `json:"cpuUsagePerc"`
 	MemoryUsageInMegs uint32    `json:"memoryUsageInMegs"`
+	MemoryPressure    float32   `json:"memoryPressure"`
+	CPUPressure       float32   `json:"cpuPressure"`
+	IOPressure        float32   `json:"ioPressure"`
+	PidsPerc          float32   `json:"pidsPerc"`
+	Extra             []float32 `json:"extra,omitempty"`
 	Timestamp         time.Time `json:"timestamp"`
 	cpuUsage          uint64    `json:"-"`
 }
@@ -45,8 +66,36 @@ type CronContainer struct {
 	ctx               context.Context
 	cancel            context.CancelFunc
 	statePath         string
+	cgroupDriver      string
 	statsQueue        *Queue
 	statsCollector    ContainerStatsCollector
+	// cpuShares is the container's cpu reservation, in cpu units of which
+	// there are 1024 per core. It is 0 if the container has no reservation.
+	cpuShares uint
+	// sampler decides how long to sleep between collections
+	sampler *statsSampler
+	// cgroupV2Dir is the container's cgroup v2 directory, or "" if it has
+	// none. It is looked for once the container's cgroups are first read.
+	cgroupV2Dir     string
+	cgroupV2Checked bool
+	// taskArn and containerName identify the container in events
+	taskArn       string
+	containerName string
+	// oomKills is how many of the container's processes have been killed for
+	// running out of memory, once oomKillsChecked is 1. Both are accessed
+	// atomically.
+	oomKills        uint64
+	oomKillsChecked uint32
+	// converters are the stats converters registered with the engine when
+	// the container started being watched, in the order their values are
+	// kept in ContainerStats and UsageStats
+	converters []StatsConverter
+	// pidsWarned is whether the container has been warned about approaching
+	// its pids limit since it last dropped below the threshold
+	pidsWarned bool
+	// stateDirs are where the container's state may be kept. statePath is
+	// set once it is found in one of them.
+	stateDirs []string
 }
 
 // taskDefinition encapsulates family and version strings for a task definition
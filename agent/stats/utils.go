@@ -18,6 +18,7 @@ import (
 	"regexp"
 	"time"
 
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
 	"github.com/docker/libcontainer"
 )
 
@@ -30,15 +31,14 @@ func nan32() float32 {
 	return (float32)(math.NaN())
 }
 
-// toContainerStats returns a new object of the ContainerStats object from libcontainer stats.
-func toContainerStats(containerStats libcontainer.ContainerStats) *ContainerStats {
+// toContainerStats converts libcontainer stats into stats, which callers
+// collecting repeatedly reuse rather than allocating each time.
+func toContainerStats(containerStats *libcontainer.ContainerStats, stats *ContainerStats) {
 	// The length of PercpuUsage represents the number of cores in an instance.
 	numCores := uint64(len(containerStats.CgroupStats.CpuStats.CpuUsage.PercpuUsage))
-	return &ContainerStats{
-		cpuUsage:    containerStats.CgroupStats.CpuStats.CpuUsage.TotalUsage / numCores,
-		memoryUsage: containerStats.CgroupStats.MemoryStats.Usage,
-		timestamp:   time.Now(),
-	}
+	stats.cpuUsage = containerStats.CgroupStats.CpuStats.CpuUsage.TotalUsage / numCores
+	stats.memoryUsage = containerStats.CgroupStats.MemoryStats.Usage
+	stats.timestamp = ttime.Now()
 }
 
 // createContainerStats returns a new object of the ContainerStats object.
@@ -46,6 +46,7 @@ func createContainerStats(cpuTime uint64, memBytes uint64, ts time.Time) *Contai
 	return &ContainerStats{
 		cpuUsage:    cpuTime,
 		memoryUsage: memBytes,
+		pressure:    pressureStats{memory: nan32(), cpu: nan32(), io: nan32()},
 		timestamp:   ts,
 	}
 }
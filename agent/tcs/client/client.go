@@ -47,7 +47,7 @@ type clientServer struct {
 // New returns a client/server to bidirectionally communicate with the backend.
 // The returned struct should have both 'Connect' and 'Serve' called upon it
 // before being used.
-func New(url string, region string, credentialProvider credentials.AWSCredentialProvider, acceptInvalidCert bool, statsEngine stats.Engine, publishMetricsInterval time.Duration) wsclient.ClientServer {
+func New(url string, region string, credentialProvider credentials.AWSCredentialProvider, acceptInvalidCert bool, connConfig wsclient.ConnectionConfig, statsEngine stats.Engine, publishMetricsInterval time.Duration) wsclient.ClientServer {
 	cs := &clientServer{
 		statsEngine:            statsEngine,
 		publishTicker:          nil,
@@ -58,6 +58,7 @@ func New(url string, region string, credentialProvider credentials.AWSCredential
 	cs.Region = region
 	cs.CredentialProvider = credentialProvider
 	cs.AcceptInvalidCert = acceptInvalidCert
+	cs.ConnectionConfig = connConfig
 	cs.ServiceError = &tcsError{}
 	cs.RequestHandlers = make(map[string]wsclient.RequestHandler)
 	cs.TypeDecoder = &TcsDecoder{}
@@ -112,7 +112,7 @@ func TestPublishMetricsRequest(t *testing.T) {
 
 func testCS() (wsclient.ClientServer, *messageLogger) {
 	testCreds := auth.TestCredentialProvider{}
-	cs := New("localhost:443", "us-east-1", testCreds, true, &mockStatsEngine{}, testPublishMetricsInterval).(*clientServer)
+	cs := New("localhost:443", "us-east-1", testCreds, true, wsclient.ConnectionConfig{}, &mockStatsEngine{}, testPublishMetricsInterval).(*clientServer)
 	ml := &messageLogger{make([][]byte, 0), make([][]byte, 0), false}
 	cs.Conn = ml
 	return cs, ml
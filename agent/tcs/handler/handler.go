@@ -20,12 +20,15 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/authv4/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/health"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
 	"github.com/aws/amazon-ecs-agent/agent/stats"
 	"github.com/aws/amazon-ecs-agent/agent/tcs/client"
 	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/aws/amazon-ecs-agent/agent/utils/retry"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/aws/amazon-ecs-agent/agent/wsclient"
 )
 
 const (
@@ -41,28 +44,58 @@ const (
 var log = logger.ForModule("tcs handler")
 
 // StartMetricsSession starts a metric session. It initializes the stats engine
-// and invokes StartSession.
+// and publishes metrics to the telemetry service while Telemetry is enabled,
+// which it starts out as only if ECS_ENABLE_TELEMETRY is set and
+// ECS_DISABLE_METRICS is not. Metrics are collected while they are published
+// to the telemetry service or any local publishers.
 func StartMetricsSession(params TelemetrySessionParams) {
 	disabled, err := params.isTelemetryDisabled()
 	if err != nil {
 		log.Warn("Error getting telemetry config", "err", err)
 		return
 	}
+	Telemetry.SetEnabled(params.Cfg.TelemetryEnabled && !disabled)
 
-	if !disabled {
-		statsEngine := stats.NewDockerStatsEngine(params.Cfg)
-		err := statsEngine.MustInit(params.TaskEngine, ecstcs.NewMetricsMetadata(params.Cfg.Cluster, params.ContainerInstanceArn))
-		if err != nil {
-			log.Warn("Error initializing metrics engine", "err", err)
-			return
+	publishers := stats.NewPublishers(params.Cfg)
+	statsEngine := stats.NewDockerStatsEngine(params.Cfg)
+	publishingEngine := stats.NewPublishingEngine(statsEngine, publishers...)
+	initialized := false
+	collecting := false
+	for {
+		enabled, changed := Telemetry.state()
+		collect := enabled || len(publishers) > 0
+		if collect && !collecting {
+			if initialized {
+				err = statsEngine.Init()
+			} else {
+				err = statsEngine.MustInit(params.TaskEngine, ecstcs.NewMetricsMetadata(params.Cfg.Cluster, params.ContainerInstanceArn))
+				initialized = err == nil
+			}
+			if err != nil {
+				log.Warn("Error initializing metrics engine", "err", err)
+				<-changed
+				continue
+			}
+			collecting = true
+		} else if !collect && collecting {
+			statsEngine.Stop()
+			collecting = false
 		}
-		err = StartSession(params, statsEngine)
-		if err != nil {
-			log.Warn("Error starting metrics session with backend", "err", err)
-			return
+
+		switch {
+		case enabled:
+			log.Info("Publishing metrics to the telemetry service")
+			if err := runSession(params, publishingEngine, changed); err != nil {
+				log.Warn("Error starting metrics session with backend", "err", err)
+				<-changed
+			}
+		case collecting:
+			log.Info("Metrics disabled for the telemetry service; publishing to local publishers only")
+			stats.PublishPeriodically(publishingEngine, defaultPublishMetricsInterval, changed)
+		default:
+			log.Info("Metric collection disabled")
+			<-changed
 		}
-	} else {
-		log.Info("Metric collection disabled")
 	}
 }
 
@@ -71,27 +104,49 @@ func StartMetricsSession(params TelemetrySessionParams) {
 // The engine is expected to initialized and gathering container metrics by
 // the time the websocket client starts using it.
 func StartSession(params TelemetrySessionParams, statsEngine stats.Engine) error {
-	backoff := utils.NewSimpleBackoff(time.Second, 1*time.Minute, 0.2, 2)
+	return runSession(params, statsEngine, nil)
+}
+
+// runSession keeps a session with the backend until stop is closed
+func runSession(params TelemetrySessionParams, statsEngine stats.Engine, stop <-chan struct{}) error {
+	backoff := retry.TCSSession.NewBackoff()
+	health.Default.Register(health.ComponentTCS)
 	for {
-		tcsEndpoint, err := params.EcsClient.DiscoverTelemetryEndpoint(params.ContainerInstanceArn)
+		select {
+		case <-stop:
+			// A session which was turned off is not unhealthy
+			health.Default.Report(health.ComponentTCS, nil)
+			return nil
+		default:
+		}
+		tcsEndpoint, err := params.telemetryEndpoint()
 		if err != nil {
 			log.Error("Unable to discover poll endpoint", "err", err)
+			health.Default.Report(health.ComponentTCS, err)
 			return err
 		}
 		log.Debug("Connecting to TCS endpoint " + tcsEndpoint)
 		url := formatURL(tcsEndpoint, params.Cfg.Cluster, params.ContainerInstanceArn)
-		tcsError := startSession(url, params.Cfg.AWSRegion, params.CredentialProvider, params.AcceptInvalidCert, statsEngine, defaultPublishMetricsInterval)
+		tcsError := startSession(url, params.Cfg.AWSRegion, params.CredentialProvider, params.AcceptInvalidCert, wsclient.NewConnectionConfig(params.Cfg), statsEngine, defaultPublishMetricsInterval, stop)
 		if tcsError == nil || tcsError == io.EOF {
 			backoff.Reset()
+			backoff.Succeeded()
 		} else {
 			log.Info("Error from tcs; backing off", "err", tcsError)
-			ttime.Sleep(backoff.Duration())
+			health.Default.Report(health.ComponentTCS, tcsError)
+			params.invalidateTelemetryEndpoint()
+			select {
+			case <-stop:
+			case <-ttime.After(backoff.DurationFor(tcsError)):
+			}
 		}
 	}
 }
 
-func startSession(url string, region string, credentialProvider credentials.AWSCredentialProvider, acceptInvalidCert bool, statsEngine stats.Engine, publishMetricsInterval time.Duration) error {
-	client := tcsclient.New(url, region, credentialProvider, acceptInvalidCert, statsEngine, publishMetricsInterval)
+// startSession connects to the backend and publishes metrics until the
+// connection closes or stop is closed.
+func startSession(url string, region string, credentialProvider credentials.AWSCredentialProvider, acceptInvalidCert bool, connConfig wsclient.ConnectionConfig, statsEngine stats.Engine, publishMetricsInterval time.Duration, stop <-chan struct{}) error {
+	client := tcsclient.New(url, region, credentialProvider, acceptInvalidCert, connConfig, statsEngine, publishMetricsInterval)
 
 	defer client.Close()
 
@@ -112,6 +167,17 @@ func startSession(url string, region string, credentialProvider credentials.AWSC
 		log.Error("Error connecting to TCS: " + err.Error())
 		return err
 	}
+	health.Default.Report(health.ComponentTCS, nil)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			log.Info("Closing TCS session; telemetry was disabled")
+			client.Close()
+		case <-done:
+		}
+	}()
 	return client.Serve()
 }
 
@@ -22,11 +22,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/amazon-ecs-agent/agent/api/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/auth"
+	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/tcs/client"
 	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
 	"github.com/aws/amazon-ecs-agent/agent/wsclient"
 	"github.com/aws/amazon-ecs-agent/agent/wsclient/mock/utils"
+	"github.com/golang/mock/gomock"
 )
 
 const (
@@ -65,6 +68,33 @@ func TestFormatURL(t *testing.T) {
 	}
 }
 
+func TestTelemetryEndpointOverride(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	ecsClient := mock_api.NewMockECSClient(mockCtrl)
+
+	params := TelemetrySessionParams{
+		ContainerInstanceArn: testInstanceArn,
+		Cfg:                  &config.Config{TelemetryEndpoint: "https://tcs.vpce.example.com"},
+		EcsClient:            ecsClient,
+	}
+	endpoint, err := params.telemetryEndpoint()
+	if err != nil || endpoint != "https://tcs.vpce.example.com" {
+		t.Errorf("Expected the configured endpoint, got %s (%v)", endpoint, err)
+	}
+	// A configured endpoint is never rediscovered
+	params.invalidateTelemetryEndpoint()
+
+	params.Cfg.TelemetryEndpoint = ""
+	ecsClient.EXPECT().DiscoverTelemetryEndpoint(testInstanceArn).Return("https://tcs.example.com", nil)
+	endpoint, err = params.telemetryEndpoint()
+	if err != nil || endpoint != "https://tcs.example.com" {
+		t.Errorf("Expected the discovered endpoint, got %s (%v)", endpoint, err)
+	}
+	ecsClient.EXPECT().InvalidatePollEndpoints(testInstanceArn)
+	params.invalidateTelemetryEndpoint()
+}
+
 func TestStartSession(t *testing.T) {
 	// Start test server.
 	closeWS := make(chan bool)
@@ -82,7 +112,7 @@ func TestStartSession(t *testing.T) {
 	}()
 
 	// Start a session with the test server.
-	go startSession(server.URL, "us-east-1", auth.TestCredentialProvider{}, true, &mockStatsEngine{}, testPublishMetricsInterval)
+	go startSession(server.URL, "us-east-1", auth.TestCredentialProvider{}, true, wsclient.ConnectionConfig{}, &mockStatsEngine{}, testPublishMetricsInterval, nil)
 
 	// startSession internally starts publishing metrics from the mockStatsEngine object.
 	time.Sleep(testPublishMetricsInterval)
@@ -125,7 +155,7 @@ func TestSessionConenctionClosedByRemote(t *testing.T) {
 	}()
 
 	// Start a session with the test server.
-	err = startSession(server.URL, "us-east-1", auth.TestCredentialProvider{}, true, &mockStatsEngine{}, testPublishMetricsInterval)
+	err = startSession(server.URL, "us-east-1", auth.TestCredentialProvider{}, true, wsclient.ConnectionConfig{}, &mockStatsEngine{}, testPublishMetricsInterval, nil)
 
 	if err == nil {
 		t.Error("Expected io.EOF on closed connection")
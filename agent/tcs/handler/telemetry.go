@@ -0,0 +1,59 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tcshandler
+
+import "sync"
+
+// TelemetrySwitch turns publishing metrics to the telemetry service on and
+// off while the agent runs, as ECS_DISABLE_METRICS does when it starts
+type TelemetrySwitch struct {
+	lock    sync.Mutex
+	enabled bool
+	// changed is closed, and replaced, when the switch is flipped
+	changed chan struct{}
+}
+
+// Telemetry is the agent's telemetry switch
+var Telemetry = NewTelemetrySwitch(true)
+
+// NewTelemetrySwitch returns a switch which starts enabled or not
+func NewTelemetrySwitch(enabled bool) *TelemetrySwitch {
+	return &TelemetrySwitch{enabled: enabled, changed: make(chan struct{})}
+}
+
+// Enabled returns whether metrics are published to the telemetry service
+func (telemetry *TelemetrySwitch) Enabled() bool {
+	enabled, _ := telemetry.state()
+	return enabled
+}
+
+// SetEnabled turns publishing metrics to the telemetry service on or off
+func (telemetry *TelemetrySwitch) SetEnabled(enabled bool) {
+	telemetry.lock.Lock()
+	defer telemetry.lock.Unlock()
+	if telemetry.enabled == enabled {
+		return
+	}
+	telemetry.enabled = enabled
+	close(telemetry.changed)
+	telemetry.changed = make(chan struct{})
+}
+
+// state returns whether the switch is enabled and a channel which is closed
+// when that changes
+func (telemetry *TelemetrySwitch) state() (bool, <-chan struct{}) {
+	telemetry.lock.Lock()
+	defer telemetry.lock.Unlock()
+	return telemetry.enabled, telemetry.changed
+}
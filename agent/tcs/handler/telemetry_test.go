@@ -0,0 +1,41 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tcshandler
+
+import "testing"
+
+func TestTelemetrySwitch(t *testing.T) {
+	telemetry := NewTelemetrySwitch(false)
+	enabled, changed := telemetry.state()
+	if enabled {
+		t.Fatal("Expected the switch to start disabled")
+	}
+
+	telemetry.SetEnabled(false)
+	select {
+	case <-changed:
+		t.Fatal("Expected no change when the switch is set to its state")
+	default:
+	}
+
+	telemetry.SetEnabled(true)
+	select {
+	case <-changed:
+	default:
+		t.Fatal("Expected a change when the switch is flipped")
+	}
+	if !telemetry.Enabled() {
+		t.Error("Expected the switch to be enabled")
+	}
+}
@@ -31,6 +31,24 @@ type TelemetrySessionParams struct {
 	TaskEngine           engine.TaskEngine
 }
 
+// telemetryEndpoint returns the configured telemetry endpoint, if any, and
+// otherwise discovers it
+func (params *TelemetrySessionParams) telemetryEndpoint() (string, error) {
+	if params.Cfg != nil && params.Cfg.TelemetryEndpoint != "" {
+		return params.Cfg.TelemetryEndpoint, nil
+	}
+	return params.EcsClient.DiscoverTelemetryEndpoint(params.ContainerInstanceArn)
+}
+
+// invalidateTelemetryEndpoint makes the next telemetryEndpoint discover the
+// endpoint again, unless it is configured
+func (params *TelemetrySessionParams) invalidateTelemetryEndpoint() {
+	if params.Cfg != nil && params.Cfg.TelemetryEndpoint != "" {
+		return
+	}
+	params.EcsClient.InvalidatePollEndpoints(params.ContainerInstanceArn)
+}
+
 func (params *TelemetrySessionParams) isTelemetryDisabled() (bool, error) {
 	if params.Cfg != nil {
 		return params.Cfg.DisableMetrics, nil
@@ -0,0 +1,121 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package tlsconfig holds the TLS settings of the connections the agent
+// makes: its websocket connections to ACS and TCS, and its http clients,
+// including those calling AWS APIs. Hosts behind a TLS-intercepting proxy can
+// trust the proxy's CA, and hosts with compliance requirements can refuse
+// older versions of TLS.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+)
+
+var log = logger.ForModule("tlsconfig")
+
+// minVersions are the TLS versions which may be configured as the minimum
+var minVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+}
+
+// systemCertFiles are where go looks for the system's CA certificates, in
+// the order it looks. The agent's image has its bundled certificates at the
+// first.
+var systemCertFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+	"/usr/local/share/certs/ca-root-nss.crt",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/certs/ca-certificates.crt",
+}
+
+var (
+	lock sync.RWMutex
+	// rootCAs are the certificates trusted by the agent's connections, or
+	// nil to trust the system's
+	rootCAs *x509.CertPool
+	// minVersion is the lowest TLS version the agent's connections accept,
+	// or 0 for go's default
+	minVersion uint16
+)
+
+// Configure sets the TLS settings of the agent's connections from cfg. The
+// CA bundle is a file of PEM encoded certificates trusted in addition to the
+// system's. An error is returned if the bundle can't be loaded or the minimum
+// version is not supported, since connecting with weaker settings than
+// configured would be unsafe.
+func Configure(cfg *config.Config) error {
+	var version uint16
+	if cfg.TLSMinVersion != "" {
+		var ok bool
+		version, ok = minVersions[cfg.TLSMinVersion]
+		if !ok {
+			return errors.New("unsupported minimum TLS version " + cfg.TLSMinVersion + "; expected 1.2")
+		}
+	}
+
+	var pool *x509.CertPool
+	if cfg.TLSCABundle != "" {
+		bundle, err := ioutil.ReadFile(cfg.TLSCABundle)
+		if err != nil {
+			return errors.New("unable to read CA bundle: " + err.Error())
+		}
+		pool = systemCertPool()
+		if !pool.AppendCertsFromPEM(bundle) {
+			return errors.New("no certificates found in CA bundle " + cfg.TLSCABundle)
+		}
+		log.Info("Trusting CA bundle", "file", cfg.TLSCABundle)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	rootCAs, minVersion = pool, version
+	return nil
+}
+
+// systemCertPool returns a pool of the system's CA certificates, read from
+// the first of systemCertFiles found, as go does when it is not given a pool
+func systemCertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, file := range systemCertFiles {
+		certs, err := ioutil.ReadFile(file)
+		if err == nil && pool.AppendCertsFromPEM(certs) {
+			return pool
+		}
+	}
+	log.Warn("Unable to load the system's CA certificates; trusting only the CA bundle")
+	return pool
+}
+
+// New returns the TLS settings for a connection to serverName, or to the
+// host being dialed if serverName is empty
+func New(serverName string, insecureSkipVerify bool) *tls.Config {
+	lock.RLock()
+	defer lock.RUnlock()
+	return &tls.Config{
+		ServerName:         serverName,
+		RootCAs:            rootCAs,
+		MinVersion:         minVersion,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+)
+
+func TestConfigureMinVersion(t *testing.T) {
+	defer Configure(&config.Config{})
+
+	if err := Configure(&config.Config{TLSMinVersion: "1.2"}); err != nil {
+		t.Fatal(err)
+	}
+	if New("", false).MinVersion != tls.VersionTLS12 {
+		t.Error("Expected TLS 1.2 to be the minimum")
+	}
+	for _, version := range []string{"1.0", "1.3"} {
+		if err := Configure(&config.Config{TLSMinVersion: version}); err == nil {
+			t.Error("Expected an error for an unsupported minimum version", version)
+		}
+	}
+}
+
+func TestConfigureCABundle(t *testing.T) {
+	defer Configure(&config.Config{})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	bundle, err := ioutil.TempFile("", "ca-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(bundle.Name())
+	pem.Encode(bundle, &pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]})
+	bundle.Close()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: New("", false)}}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Error("Expected the server's certificate not to be trusted without the bundle")
+	}
+
+	if err := Configure(&config.Config{TLSCABundle: bundle.Name()}); err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: New("", false)}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal("Expected the bundle to be trusted", err)
+	}
+	resp.Body.Close()
+
+	if err := Configure(&config.Config{TLSCABundle: os.DevNull}); err == nil {
+		t.Error("Expected an error for a bundle without certificates")
+	}
+}
+
+func TestSystemCertPool(t *testing.T) {
+	defer func(files []string) { systemCertFiles = files }(systemCertFiles)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	system, err := ioutil.TempFile("", "system-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(system.Name())
+	pem.Encode(system, &pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]})
+	system.Close()
+
+	systemCertFiles = []string{"/nonexistent/ca-certificates.crt", os.DevNull, system.Name()}
+	if subjects := systemCertPool().Subjects(); len(subjects) != 1 {
+		t.Error("Expected the first file with certificates to be loaded", len(subjects))
+	}
+	systemCertFiles = []string{"/nonexistent/ca-certificates.crt"}
+	if subjects := systemCertPool().Subjects(); len(subjects) != 0 {
+		t.Error("Expected an empty pool without system certificates", len(subjects))
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/otlp"
+)
+
+const (
+	// exportInterval is how often ended spans are sent to the collector
+	exportInterval = 5 * time.Second
+
+	// maxQueuedSpans bounds the spans held while the collector is slow or
+	// unavailable; further spans are dropped
+	maxQueuedSpans = 1000
+)
+
+// spanExporter exports spans to an OpenTelemetry collector, as otlp.Exporter
+// does
+type spanExporter interface {
+	ExportSpans(resource []otlp.Attribute, spans []otlp.Span) error
+}
+
+// exporter queues ended spans and sends them to an OpenTelemetry collector
+// periodically
+type exporter struct {
+	client     spanExporter
+	attributes []otlp.Attribute
+
+	lock    sync.Mutex
+	queue   []*Span
+	started sync.Once
+}
+
+func newExporter(client spanExporter, attributes map[string]string) *exporter {
+	return &exporter{
+		client:     client,
+		attributes: otlp.Attributes(attributes),
+	}
+}
+
+func (exporter *exporter) add(span *Span) {
+	exporter.started.Do(func() { go exporter.exportPeriodically() })
+
+	exporter.lock.Lock()
+	defer exporter.lock.Unlock()
+	if len(exporter.queue) >= maxQueuedSpans {
+		log.Debug("Dropping span; too many queued", "span", span.name)
+		return
+	}
+	exporter.queue = append(exporter.queue, span)
+}
+
+func (exporter *exporter) exportPeriodically() {
+	for range time.Tick(exportInterval) {
+		if err := exporter.export(); err != nil {
+			log.Warn("Error exporting spans", "err", err)
+		}
+	}
+}
+
+// export sends every queued span in one request
+func (exporter *exporter) export() error {
+	exporter.lock.Lock()
+	spans := exporter.queue
+	exporter.queue = nil
+	exporter.lock.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	return exporter.client.ExportSpans(exporter.attributes, exportedSpans(spans))
+}
+
+func exportedSpans(spans []*Span) []otlp.Span {
+	exported := make([]otlp.Span, 0, len(spans))
+	for _, span := range spans {
+		span.lock.Lock()
+		s := otlp.Span{
+			TraceID:      span.traceID,
+			SpanID:       span.spanID,
+			ParentSpanID: span.parentID,
+			Name:         span.name,
+			Kind:         otlp.SpanKindInternal,
+			Start:        span.start,
+			End:          span.end,
+			Attributes:   otlp.Attributes(span.attributes),
+			Status:       otlp.Status{Code: otlp.StatusCodeOk},
+		}
+		if span.err != nil {
+			s.Status = otlp.Status{Code: otlp.StatusCodeError, Message: span.err.Error()}
+		}
+		span.lock.Unlock()
+		exported = append(exported, s)
+	}
+	return exported
+}
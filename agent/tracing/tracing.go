@@ -0,0 +1,123 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package tracing records spans for slow operations, such as launching a
+// task, and exports them to an OpenTelemetry collector.
+//
+// A nil *Tracer and a nil *Span are valid and record nothing, so callers do
+// not need to check whether tracing is enabled.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/otlp"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+var log = logger.ForModule("tracing")
+
+// Tracer starts traces and exports their spans once they end
+type Tracer struct {
+	exporter *exporter
+}
+
+// New returns a Tracer which exports to the OpenTelemetry collector at
+// endpoint. The given attributes describe the resource, e.g. the cluster,
+// being traced.
+func New(endpoint string, attributes map[string]string) *Tracer {
+	return &Tracer{exporter: newExporter(otlp.NewExporter(endpoint), attributes)}
+}
+
+// StartSpan starts the root span of a new trace
+func (tracer *Tracer) StartSpan(name string) *Span {
+	if tracer == nil {
+		return nil
+	}
+	return newSpan(tracer, name, newID(16), "")
+}
+
+// Span is a timed operation within a trace
+type Span struct {
+	tracer   *Tracer
+	name     string
+	traceID  string
+	spanID   string
+	parentID string
+	start    time.Time
+
+	lock       sync.Mutex
+	end        time.Time
+	attributes map[string]string
+	err        error
+	ended      bool
+}
+
+func newSpan(tracer *Tracer, name, traceID, parentID string) *Span {
+	return &Span{
+		tracer:     tracer,
+		name:       name,
+		traceID:    traceID,
+		spanID:     newID(8),
+		parentID:   parentID,
+		start:      ttime.Now(),
+		attributes: make(map[string]string),
+	}
+}
+
+// StartChild starts a span for an operation which is part of this one
+func (span *Span) StartChild(name string) *Span {
+	if span == nil {
+		return nil
+	}
+	return newSpan(span.tracer, name, span.traceID, span.spanID)
+}
+
+// SetAttribute records a key and value describing the operation
+func (span *Span) SetAttribute(key, value string) {
+	if span == nil {
+		return
+	}
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	span.attributes[key] = value
+}
+
+// End marks the operation complete, having failed with err if it is not nil,
+// and queues the span for export. Only the first call has any effect.
+func (span *Span) End(err error) {
+	if span == nil {
+		return
+	}
+	span.lock.Lock()
+	if span.ended {
+		span.lock.Unlock()
+		return
+	}
+	span.ended = true
+	span.end = ttime.Now()
+	span.err = err
+	span.lock.Unlock()
+
+	span.tracer.exporter.add(span)
+}
+
+func newID(bytes int) string {
+	id := make([]byte, bytes)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,86 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/otlp"
+)
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.StartSpan("root")
+	child := span.StartChild("child")
+	child.SetAttribute("key", "value")
+	child.End(nil)
+	span.End(nil)
+	if span != nil || child != nil {
+		t.Error("Expected a nil tracer to create no spans")
+	}
+}
+
+type fakeSpanExporter struct {
+	resource []otlp.Attribute
+	exported [][]otlp.Span
+}
+
+func (exporter *fakeSpanExporter) ExportSpans(resource []otlp.Attribute, spans []otlp.Span) error {
+	exporter.resource = resource
+	exporter.exported = append(exporter.exported, spans)
+	return nil
+}
+
+func TestExport(t *testing.T) {
+	client := &fakeSpanExporter{}
+	tracer := &Tracer{exporter: newExporter(client, map[string]string{"aws.ecs.cluster.arn": "default"})}
+	root := tracer.StartSpan("task launch")
+	root.SetAttribute("aws.ecs.task.arn", "arn")
+	child := root.StartChild("docker pull")
+	child.End(errors.New("pull failed"))
+	child.End(nil)
+	root.End(nil)
+
+	if err := tracer.exporter.export(); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.exported) != 1 {
+		t.Fatal("Expected one export, got", len(client.exported))
+	}
+	if len(client.resource) != 1 || client.resource[0].Value != "default" {
+		t.Error("Wrong resource attributes", client.resource)
+	}
+	spans := client.exported[0]
+	if len(spans) != 2 {
+		t.Fatal("Expected each span to be exported once, got", len(spans))
+	}
+	exportedChild, exportedRoot := spans[0], spans[1]
+	if exportedChild.TraceID != exportedRoot.TraceID || exportedChild.ParentSpanID != exportedRoot.SpanID {
+		t.Error("Expected the child to belong to the root span's trace")
+	}
+	if exportedRoot.ParentSpanID != "" || len(exportedRoot.TraceID) != 32 || len(exportedRoot.SpanID) != 16 {
+		t.Error("Wrong root span ids", exportedRoot)
+	}
+	if exportedChild.Status.Code != otlp.StatusCodeError || exportedChild.Status.Message != "pull failed" {
+		t.Error("Expected the first End to set the child's status", exportedChild.Status)
+	}
+	if exportedRoot.Status.Code != otlp.StatusCodeOk || len(exportedRoot.Attributes) != 1 {
+		t.Error("Wrong root span", exportedRoot)
+	}
+
+	if err := tracer.exporter.export(); err != nil || len(client.exported) != 1 {
+		t.Error("Expected nothing to export", err)
+	}
+}
@@ -73,3 +73,50 @@ func CompleteJsonUnmarshal(b []byte, iface interface{}) error {
 	}
 	return nil
 }
+
+// RedactedValue replaces the values removed by Redact
+const RedactedValue = "[REDACTED]"
+
+// Redact replaces the values of the given fields, at any depth of a decoded
+// json value, with RedactedValue. The names within a redacted object and the
+// names of "NAME=value" strings in a redacted list are kept, since they are
+// useful and rarely sensitive.
+func Redact(value interface{}, fields map[string]bool) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, fieldValue := range typed {
+			if fields[key] {
+				typed[key] = redactField(fieldValue)
+			} else {
+				typed[key] = Redact(fieldValue, fields)
+			}
+		}
+	case []interface{}:
+		for i, elem := range typed {
+			typed[i] = Redact(elem, fields)
+		}
+	}
+	return value
+}
+
+func redactField(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for name := range typed {
+			typed[name] = RedactedValue
+		}
+		return typed
+	case []interface{}:
+		for i, elem := range typed {
+			if s, ok := elem.(string); ok && strings.Contains(s, "=") {
+				typed[i] = s[:strings.Index(s, "=")+1] + RedactedValue
+			} else {
+				typed[i] = RedactedValue
+			}
+		}
+		return typed
+	case nil:
+		return nil
+	}
+	return RedactedValue
+}
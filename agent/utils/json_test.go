@@ -100,3 +100,14 @@ func TestCompleteJsonUnmarshal(t *testing.T) {
 		t.Error("The non-empty object can't unmarshal into the empty struct losslessly")
 	}
 }
+
+func TestRedact(t *testing.T) {
+	var value interface{}
+	json.Unmarshal([]byte(`{"name":"c1","environment":{"SECRET":"hunter2"},"Config":{"Env":["PASSWORD=hunter2","PATH"]},"nested":[{"environment":"hunter2"}]}`), &value)
+
+	redacted, _ := json.Marshal(Redact(value, map[string]bool{"environment": true, "Env": true}))
+	expected := `{"Config":{"Env":["PASSWORD=[REDACTED]","[REDACTED]"]},"environment":{"SECRET":"[REDACTED]"},"name":"c1","nested":[{"environment":"[REDACTED]"}]}`
+	if string(redacted) != expected {
+		t.Error("Unexpected redaction", string(redacted))
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package retry
+
+import "sync"
+
+// budget is a token bucket shared by every caller of a policy. Retries
+// withdraw tokens and successful calls put a few back.
+type budget struct {
+	lock      sync.Mutex
+	capacity  int
+	available int
+}
+
+func newBudget(capacity int) *budget {
+	return &budget{capacity: capacity, available: capacity}
+}
+
+// withdraw takes cost tokens from the budget and returns true, or returns
+// false if there are not enough tokens available
+func (b *budget) withdraw(cost int) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.available < cost {
+		return false
+	}
+	b.available -= cost
+	return true
+}
+
+func (b *budget) refund(amount int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.available += amount
+	if b.available > b.capacity {
+		b.available = b.capacity
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package retry provides retry policies for the AWS APIs the agent calls.
+// Each policy backs off more aggressively when the API reports throttling,
+// and shares a retry budget between all callers of that API so that a
+// struggling endpoint is not overwhelmed by retries.
+package retry
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+)
+
+var (
+	// ECS is the policy for submitting task and container events to the ECS
+	// API. Its backoff grows slowly, as events are retried until they are
+	// sent and a long wait delays every event queued behind them.
+	ECS = NewPolicy(time.Second, 30*time.Second, 5*time.Second, 1.3, 0)
+
+	// ACSSession and TCSSession are the policies for reconnecting the ACS and
+	// TCS websocket sessions, including discovering their endpoints
+	ACSSession = NewPolicy(time.Second, 2*time.Minute, 5*time.Second, 2, 0)
+	TCSSession = NewPolicy(time.Second, time.Minute, 5*time.Second, 2, 0)
+)
+
+const (
+	defaultJitterMultiple = 0.2
+
+	// Retry budget accounting: a retry costs retryCost tokens (or
+	// throttleRetryCost for a throttled call) and a success refunds
+	// successRefund, up to budgetCapacity.
+	budgetCapacity    = 500
+	retryCost         = 5
+	throttleRetryCost = 10
+	successRefund     = 1
+)
+
+// Policy describes how calls to an API are retried
+type Policy struct {
+	// MinBackoff and MaxBackoff bound the wait between attempts
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// ThrottleMinBackoff is the least time waited after a throttling error
+	ThrottleMinBackoff time.Duration
+	// Multiple is how much the wait grows after each failed attempt
+	Multiple float64
+	// MaxAttempts is the number of times a call is made before giving up. If
+	// it is 0, the call is retried until it succeeds or is not retriable.
+	MaxAttempts int
+
+	budget *budget
+}
+
+// NewPolicy creates a Policy with its own retry budget
+func NewPolicy(minBackoff, maxBackoff, throttleMinBackoff time.Duration, multiple float64, maxAttempts int) *Policy {
+	return &Policy{
+		MinBackoff:         minBackoff,
+		MaxBackoff:         maxBackoff,
+		ThrottleMinBackoff: throttleMinBackoff,
+		Multiple:           multiple,
+		MaxAttempts:        maxAttempts,
+		budget:             newBudget(budgetCapacity),
+	}
+}
+
+// NewBackoff returns a Backoff following this policy
+func (p *Policy) NewBackoff() *Backoff {
+	return &Backoff{
+		policy:   p,
+		normal:   utils.NewSimpleBackoff(p.MinBackoff, p.MaxBackoff, defaultJitterMultiple, p.Multiple),
+		throttle: utils.NewSimpleBackoff(p.ThrottleMinBackoff, p.MaxBackoff, defaultJitterMultiple, p.Multiple),
+	}
+}
+
+// Do calls fn until it succeeds, returns an error which is not retriable, or
+// the policy's MaxAttempts is reached. The last error is returned.
+func (p *Policy) Do(fn func() error) error {
+	return p.NewBackoff().Retry(fn)
+}
+
+// Backoff is a utils.Backoff which also adapts to throttling errors and
+// draws from its policy's retry budget
+type Backoff struct {
+	policy   *Policy
+	normal   *utils.SimpleBackoff
+	throttle *utils.SimpleBackoff
+}
+
+// Reset resets the backoff to its initial duration
+func (b *Backoff) Reset() {
+	b.normal.Reset()
+	b.throttle.Reset()
+}
+
+// Duration returns the time to wait after a non-throttling error
+func (b *Backoff) Duration() time.Duration {
+	return b.DurationFor(nil)
+}
+
+// DurationFor returns the time to wait before retrying after err. Throttling
+// errors wait at least the policy's ThrottleMinBackoff and grow from there.
+// Once the policy's retry budget is exhausted, every retry waits the policy's
+// MaxBackoff until successful calls replenish it.
+func (b *Backoff) DurationFor(err error) time.Duration {
+	throttled := IsThrottle(err)
+	cost := retryCost
+	if throttled {
+		cost = throttleRetryCost
+	}
+	if !b.policy.budget.withdraw(cost) {
+		log.Warn("Retry budget exhausted; backing off to maximum", "maxBackoff", b.policy.MaxBackoff)
+		return utils.AddJitter(b.policy.MaxBackoff, time.Duration(float64(b.policy.MaxBackoff)*defaultJitterMultiple))
+	}
+	if throttled {
+		// Keep the normal backoff moving so it doesn't snap back once the
+		// throttling stops
+		b.normal.Duration()
+		return b.throttle.Duration()
+	}
+	return b.normal.Duration()
+}
+
+// Retry calls fn, waiting between attempts, until it succeeds, returns an
+// error which is not retriable, or the policy's MaxAttempts is reached. The
+// last error is returned.
+func (b *Backoff) Retry(fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			b.policy.budget.refund(successRefund)
+			return nil
+		}
+		if retriable, ok := err.(utils.Retriable); ok && !retriable.Retry() {
+			return err
+		}
+		if b.policy.MaxAttempts > 0 && attempt >= b.policy.MaxAttempts {
+			return err
+		}
+		ttime.Sleep(b.DurationFor(err))
+	}
+}
+
+// Succeeded records a successful call made outside of Retry, returning some
+// of the policy's retry budget
+func (b *Backoff) Succeeded() {
+	b.policy.budget.refund(successRefund)
+}
@@ -0,0 +1,118 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/awslabs/aws-sdk-go/aws"
+)
+
+var throttleErr = aws.APIError{StatusCode: 400, Code: "ThrottlingException"}
+
+func TestIsThrottle(t *testing.T) {
+	if IsThrottle(nil) {
+		t.Error("nil is not a throttle")
+	}
+	if IsThrottle(errors.New("Throttling")) {
+		t.Error("A plain error is not a throttle")
+	}
+	if !IsThrottle(throttleErr) {
+		t.Error("ThrottlingException should be a throttle")
+	}
+	if !IsThrottle(&aws.APIError{StatusCode: 429}) {
+		t.Error("429 should be a throttle")
+	}
+	if IsThrottle(aws.APIError{StatusCode: 400, Code: "ClientException"}) {
+		t.Error("ClientException is not a throttle")
+	}
+}
+
+func TestDoRespectsMaxAttempts(t *testing.T) {
+	testTime := ttime.NewTestTime()
+	testTime.LudicrousSpeed(true)
+	ttime.SetTime(testTime)
+
+	policy := NewPolicy(time.Second, time.Minute, 5*time.Second, 2, 3)
+	calls := 0
+	err := policy.Do(func() error {
+		calls++
+		return errors.New("err")
+	})
+	if err == nil {
+		t.Error("Expected the last error to be returned")
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %v", calls)
+	}
+}
+
+func TestDoStopsOnUnretriable(t *testing.T) {
+	testTime := ttime.NewTestTime()
+	testTime.LudicrousSpeed(true)
+	ttime.SetTime(testTime)
+
+	policy := NewPolicy(time.Second, time.Minute, 5*time.Second, 2, 0)
+	calls := 0
+	err := policy.Do(func() error {
+		calls++
+		if calls == 1 {
+			return errors.New("retriable")
+		}
+		return utils.NewRetriableError(utils.NewRetriable(false), errors.New("unretriable"))
+	})
+	if err == nil || calls != 2 {
+		t.Errorf("Expected to stop after an unretriable error; calls: %v, err: %v", calls, err)
+	}
+}
+
+func TestThrottleBacksOffFurther(t *testing.T) {
+	policy := NewPolicy(time.Second, time.Minute, 10*time.Second, 2, 0)
+	backoff := policy.NewBackoff()
+
+	if d := backoff.DurationFor(errors.New("err")); d > 2*time.Second {
+		t.Errorf("Expected a short backoff for a normal error, got %v", d)
+	}
+	if d := backoff.DurationFor(throttleErr); d < 8*time.Second {
+		t.Errorf("Expected at least the throttle minimum for a throttle, got %v", d)
+	}
+	backoff.Reset()
+	if d := backoff.Duration(); d > 2*time.Second {
+		t.Errorf("Expected reset backoff to be short, got %v", d)
+	}
+}
+
+func TestExhaustedBudgetBacksOffToMax(t *testing.T) {
+	policy := NewPolicy(time.Second, time.Minute, 5*time.Second, 2, 0)
+	policy.budget = newBudget(retryCost)
+
+	if d := policy.NewBackoff().Duration(); d > 2*time.Second {
+		t.Errorf("Expected first retry to draw from the budget, got %v", d)
+	}
+	if d := policy.NewBackoff().Duration(); d < 48*time.Second {
+		t.Errorf("Expected exhausted budget to back off to max, got %v", d)
+	}
+
+	// Successes replenish the budget
+	for i := 0; i < retryCost; i++ {
+		policy.NewBackoff().Succeeded()
+	}
+	if d := policy.NewBackoff().Duration(); d > 2*time.Second {
+		t.Errorf("Expected replenished budget to allow a short backoff, got %v", d)
+	}
+}
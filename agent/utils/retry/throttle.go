@@ -0,0 +1,56 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package retry
+
+import (
+	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/awslabs/aws-sdk-go/aws"
+)
+
+var log = logger.ForModule("retry")
+
+// statusTooManyRequests is the http status some AWS APIs throttle with
+const statusTooManyRequests = 429
+
+// throttleCodes are the error codes AWS APIs use to indicate throttling
+var throttleCodes = map[string]bool{
+	"Throttling":                             true,
+	"ThrottlingException":                    true,
+	"ThrottledException":                     true,
+	"RequestThrottledException":              true,
+	"TooManyRequestsException":               true,
+	"ProvisionedThroughputExceededException": true,
+	"RequestLimitExceeded":                   true,
+	"SlowDown":                               true,
+}
+
+// ThrottlingError may be implemented by errors which wrap an AWS error to
+// report whether it was caused by throttling
+type ThrottlingError interface {
+	Throttled() bool
+}
+
+// IsThrottle returns true if err indicates the call was throttled
+func IsThrottle(err error) bool {
+	if err == nil {
+		return false
+	}
+	if throttlingErr, ok := err.(ThrottlingError); ok {
+		return throttlingErr.Throttled()
+	}
+	if apiErr := aws.Error(err); apiErr != nil {
+		return throttleCodes[apiErr.Code] || apiErr.StatusCode == statusTooManyRequests
+	}
+	return false
+}
@@ -19,6 +19,9 @@ type empty struct{}
 type Semaphore interface {
 	Post()
 	Wait()
+	// WaitUntil waits for a resource unless done is closed first. It returns
+	// whether it acquired the resource.
+	WaitUntil(done <-chan struct{}) bool
 }
 
 // Implements semaphore
@@ -46,3 +49,12 @@ func (s *ChanSemaphore) Post() {
 func (s *ChanSemaphore) Wait() {
 	<-s.semaphore
 }
+
+func (s *ChanSemaphore) WaitUntil(done <-chan struct{}) bool {
+	select {
+	case <-s.semaphore:
+		return true
+	case <-done:
+		return false
+	}
+}
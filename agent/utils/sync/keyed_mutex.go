@@ -0,0 +1,64 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sync
+
+import stdsync "sync"
+
+// A KeyedMutex is a mutex for each of a set of keys, such as paths or
+// digests, so that work on one key waits only for other work on the same key.
+// A key's mutex is forgotten once nothing holds or waits for it.
+type KeyedMutex struct {
+	mutex stdsync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	stdsync.Mutex
+	// refs is how many callers hold or wait for the lock
+	refs int
+}
+
+func NewKeyedMutex() *KeyedMutex {
+	return &KeyedMutex{locks: make(map[string]*keyedLock)}
+}
+
+// Lock locks the mutex for key, waiting until it is available
+func (k *KeyedMutex) Lock(key string) {
+	k.mutex.Lock()
+	lock, ok := k.locks[key]
+	if !ok {
+		lock = &keyedLock{}
+		k.locks[key] = lock
+	}
+	lock.refs++
+	k.mutex.Unlock()
+
+	lock.Lock()
+}
+
+// Unlock unlocks the mutex for key. It is a run-time error if it is not
+// locked.
+func (k *KeyedMutex) Unlock(key string) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	lock, ok := k.locks[key]
+	if !ok {
+		panic("sync: unlock of unlocked KeyedMutex key " + key)
+	}
+	lock.refs--
+	if lock.refs == 0 {
+		delete(k.locks, key)
+	}
+	lock.Unlock()
+}
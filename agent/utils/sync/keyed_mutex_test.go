@@ -0,0 +1,60 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	mutex := NewKeyedMutex()
+	mutex.Lock("a")
+
+	// Other keys aren't held up
+	otherLocked := make(chan struct{})
+	go func() {
+		mutex.Lock("b")
+		close(otherLocked)
+	}()
+	select {
+	case <-otherLocked:
+	case <-time.After(time.Second):
+		t.Fatal("Expected another key to be locked at once")
+	}
+	mutex.Unlock("b")
+
+	// The same key waits
+	sameLocked := make(chan struct{})
+	go func() {
+		mutex.Lock("a")
+		close(sameLocked)
+	}()
+	select {
+	case <-sameLocked:
+		t.Fatal("Expected the same key to wait")
+	case <-time.After(10 * time.Millisecond):
+	}
+	mutex.Unlock("a")
+	select {
+	case <-sameLocked:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the same key to be locked once unlocked")
+	}
+	mutex.Unlock("a")
+
+	if len(mutex.locks) != 0 {
+		t.Error("Expected unused keys to be forgotten", mutex.locks)
+	}
+}
@@ -77,6 +77,13 @@ func TestZeroOrNil(t *testing.T) {
 		t.Error("[] is Zero")
 	}
 
+	if !ZeroOrNil(map[string]string{}) {
+		t.Error("An empty map is zero")
+	}
+	if ZeroOrNil(map[string]string{"a": "b"}) {
+		t.Error("A populated map is not zero")
+	}
+
 }
 
 func TestSlicesDeepEqual(t *testing.T) {
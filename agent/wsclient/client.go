@@ -31,9 +31,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/dnscache"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/authv4"
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/authv4/credentials"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/tlsconfig"
 	"github.com/awslabs/aws-sdk-go/internal/protocol/json/jsonutil"
 	"github.com/gorilla/websocket"
 )
@@ -48,13 +51,47 @@ const (
 	// wsConnectTimeout specifies the default connection timeout to the backend.
 	wsConnectTimeout = 3 * time.Second
 
-	// readBufSize is the size of the read buffer for the ws connection.
+	// readBufSize is the default size of the read buffer for the ws
+	// connection.
 	readBufSize = 4096
 
-	// writeBufSize is the size of the write buffer for the ws connection.
+	// writeBufSize is the default size of the write buffer for the ws
+	// connection.
 	writeBufSize = 32768
 )
 
+// ConnectionConfig sizes a websocket connection's buffers and the messages it
+// accepts. Zero values use the defaults.
+type ConnectionConfig struct {
+	ReadBufferSize int
+	// WriteBufferSize is also the largest frame sent; longer messages are
+	// fragmented
+	WriteBufferSize int
+	// MaxMessageSize is the largest message accepted from the backend, or 0
+	// for no limit
+	MaxMessageSize int64
+}
+
+// NewConnectionConfig returns the websocket connection settings in cfg
+func NewConnectionConfig(cfg *config.Config) ConnectionConfig {
+	return ConnectionConfig{
+		ReadBufferSize:  cfg.WebsocketReadBufferSize,
+		WriteBufferSize: cfg.WebsocketWriteBufferSize,
+		MaxMessageSize:  cfg.WebsocketMaxMessageSize,
+	}
+}
+
+func (conf ConnectionConfig) bufferSizes() (int, int) {
+	readSize, writeSize := conf.ReadBufferSize, conf.WriteBufferSize
+	if readSize <= 0 {
+		readSize = readBufSize
+	}
+	if writeSize <= 0 {
+		writeSize = writeBufSize
+	}
+	return readSize, writeSize
+}
+
 // ReceivedMessage is the intermediate message used to unmarshal a
 // message from backend
 type ReceivedMessage struct {
@@ -95,6 +132,7 @@ type ClientServer interface {
 type ClientServerImpl struct {
 	AcceptInvalidCert  bool
 	Conn               WebsocketConn
+	ConnectionConfig   ConnectionConfig
 	CredentialProvider credentials.AWSCredentialProvider
 	Region             string
 	// RequestHandlers is a map from message types to handler functions of the
@@ -132,12 +170,22 @@ func (cs *ClientServerImpl) Connect() error {
 
 	timeoutDialer := &net.Dialer{Timeout: wsConnectTimeout}
 	log.Info("Creating poll dialer", "host", parsedURL.Host)
-	wsConn, err := tls.DialWithDialer(timeoutDialer, "tcp", dialHost, &tls.Config{InsecureSkipVerify: cs.AcceptInvalidCert})
+	rawConn, err := dnscache.Default.Dial(timeoutDialer)("tcp", dialHost)
+	if err != nil {
+		return err
+	}
+	serverName, _, _ := net.SplitHostPort(dialHost)
+	wsConn := tls.Client(rawConn, tlsconfig.New(serverName, cs.AcceptInvalidCert))
+	rawConn.SetDeadline(time.Now().Add(wsConnectTimeout))
+	err = wsConn.Handshake()
+	rawConn.SetDeadline(time.Time{})
 	if err != nil {
+		rawConn.Close()
 		return err
 	}
 
-	websocketConn, httpResponse, err := websocket.NewClient(wsConn, parsedURL, request.Header, readBufSize, writeBufSize)
+	readSize, writeSize := cs.ConnectionConfig.bufferSizes()
+	websocketConn, httpResponse, err := websocket.NewClient(wsConn, parsedURL, request.Header, readSize, writeSize)
 	if httpResponse != nil {
 		defer httpResponse.Body.Close()
 	}
@@ -159,6 +207,9 @@ func (cs *ClientServerImpl) Connect() error {
 		log.Warn("Error creating a websocket client", "err", err)
 		return errors.New(string(resp) + ", " + err.Error())
 	}
+	if cs.ConnectionConfig.MaxMessageSize > 0 {
+		websocketConn.SetReadLimit(cs.ConnectionConfig.MaxMessageSize)
+	}
 	cs.Conn = websocketConn
 	return nil
 }
@@ -205,7 +256,9 @@ func (cs *ClientServerImpl) ConsumeMessages() error {
 		messageType, message, cerr := cs.Conn.ReadMessage()
 		err = cerr
 		if err != nil {
-			if err != io.EOF {
+			if err == websocket.ErrReadLimit {
+				log.Error("Message from ws backend exceeds the maximum message size; closing connection", "maxMessageSize", cs.ConnectionConfig.MaxMessageSize)
+			} else if err != io.EOF {
 				if message != nil {
 					log.Error("Error getting message from ws backend", "err", err, "message", message)
 				} else {
@@ -261,6 +314,12 @@ func (cs *ClientServerImpl) handleMessage(data []byte) {
 		log.Warn("Unable to handle message from backend", "err", err)
 		return
 	}
+	raw := &ReceivedMessage{}
+	if json.Unmarshal(data, raw) == nil && raw.Type == typeStr {
+		if typ, ok := cs.GetRecognizedTypes()[typeStr]; ok {
+			logUnknownFields(typeStr, raw.Message, typ)
+		}
+	}
 
 	if handler, ok := cs.RequestHandlers[typeStr]; ok {
 		reflect.ValueOf(handler).Call([]reflect.Value{reflect.ValueOf(typedMessage)})
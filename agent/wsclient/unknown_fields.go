@@ -0,0 +1,110 @@
+// Copyright 2014-2015 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package wsclient
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// reportedUnknownFields records the unknown fields which have been logged so
+// that each is logged once rather than for every message it appears in
+var (
+	reportedUnknownFieldsLock sync.Mutex
+	reportedUnknownFields     = make(map[string]bool)
+)
+
+// UnknownFields returns the paths of the fields in message, a JSON object,
+// which have no corresponding field in typ. Newer backends may send fields
+// this agent does not know about; they are ignored when decoding. Paths use
+// the backend's field names, with "[]" marking list elements, e.g.
+// "tasks[].containers[].newField".
+func UnknownFields(message []byte, typ reflect.Type) ([]string, error) {
+	var value interface{}
+	if err := json.Unmarshal(message, &value); err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool)
+	unknownFields("", value, typ, found)
+	unknown := make([]string, 0, len(found))
+	for path := range found {
+		unknown = append(unknown, path)
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+func unknownFields(path string, value interface{}, typ reflect.Type, found map[string]bool) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if field.PkgPath != "" {
+				// Unexported, e.g. generated metadata
+				continue
+			}
+			name := field.Tag.Get("locationName")
+			if name == "" {
+				name = field.Name
+			}
+			fields[name] = field.Type
+		}
+		for key, fieldValue := range object {
+			fieldType, ok := fields[key]
+			if !ok {
+				found[path+key] = true
+				continue
+			}
+			unknownFields(path+key+".", fieldValue, fieldType, found)
+		}
+	case reflect.Slice:
+		list, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		elemPath := path[:len(path)-1] + "[]."
+		for _, elem := range list {
+			unknownFields(elemPath, elem, typ.Elem(), found)
+		}
+	}
+}
+
+// logUnknownFields logs any fields of the message which have not been logged
+// before
+func logUnknownFields(typeStr string, message []byte, typ reflect.Type) {
+	unknown, err := UnknownFields(message, typ)
+	if err != nil {
+		return
+	}
+	reportedUnknownFieldsLock.Lock()
+	defer reportedUnknownFieldsLock.Unlock()
+	for _, field := range unknown {
+		key := typeStr + "." + field
+		if reportedUnknownFields[key] {
+			continue
+		}
+		reportedUnknownFields[key] = true
+		log.Warn("Ignoring unrecognized field in message from backend; this agent may be out of date", "type", typeStr, "field", field)
+	}
+}